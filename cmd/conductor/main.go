@@ -0,0 +1,18 @@
+// Command conductor is a compatibility alias for agency, under the tool's
+// former name. It migrates a legacy conductor data dir on first use.
+package main
+
+import (
+	"os"
+
+	"github.com/NielsdaWheelz/agency/internal/cli"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+)
+
+func main() {
+	err := cli.RunConductor(os.Args[1:], os.Stdout, os.Stderr)
+	if err != nil {
+		errors.Print(os.Stderr, err)
+		os.Exit(errors.ExitCode(err))
+	}
+}
@@ -0,0 +1,227 @@
+// Package archive implements run archival: archive script, tmux teardown,
+// worktree removal, and the meta.json archive record.
+// Run metadata and logs are always retained under the run dir.
+package archive
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// ScriptTimeout is the archive script timeout (per constitution).
+const ScriptTimeout = 5 * time.Minute
+
+// Opts contains inputs for archiving a single run.
+type Opts struct {
+	// RepoRoot is the repo checkout the worktree belongs to (empty if unknown).
+	// When empty, the worktree directory is removed directly without git.
+	RepoRoot string
+
+	// Script is scripts.archive from agency.json (empty to skip).
+	Script string
+
+	// Timeout overrides ScriptTimeout when non-zero.
+	Timeout time.Duration
+}
+
+// Result describes what archival did. Warnings are non-fatal problems
+// (archive script failure, tmux errors) that did not stop cleanup.
+type Result struct {
+	ScriptRan      bool
+	ScriptExitCode int
+	ScriptLogPath  string
+	SessionKilled  bool
+	ArchivedAt     string
+	Warnings       []string
+}
+
+// Archive archives a run best-effort, in order:
+//  1. run scripts.archive in the worktree (timeout 5m; failure is a warning)
+//  2. kill the tmux session if it exists (failure is a warning)
+//  3. remove the worktree (git worktree remove --force, falling back to rm -rf
+//     for worktrees inside the data dir; see removeWorktree)
+//  4. record archive.archived_at in meta.json
+//
+// Returns E_ARCHIVE_FAILED if the worktree could not be removed, or the
+// meta.json update error. The run dir (meta, logs, events) is never removed.
+func Archive(ctx context.Context, cr exec.CommandRunner, st *store.Store, meta *store.RunMeta, opts Opts) (Result, error) {
+	var res Result
+	worktreePresent := dirExists(meta.WorktreePath)
+
+	// 1. Archive script (best-effort)
+	if opts.Script != "" && worktreePresent {
+		res.ScriptRan = true
+		res.ScriptLogPath = filepath.Join(st.RunLogsDir(meta.RepoID, meta.RunID), "archive.log")
+		exitCode, err := runScript(ctx, st, meta, opts, res.ScriptLogPath)
+		res.ScriptExitCode = exitCode
+		if err != nil {
+			res.Warnings = append(res.Warnings, "archive script failed to run: "+err.Error())
+		} else if exitCode != 0 {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("archive script exited %d (see %s)", exitCode, res.ScriptLogPath))
+		}
+	}
+
+	// 2. Tmux session (best-effort)
+	sessionName := meta.TmuxSessionName
+	if sessionName == "" {
+		sessionName = "agency_" + meta.RunID
+	}
+	if has, err := cr.Run(ctx, "tmux", []string{"has-session", "-t", sessionName}, exec.RunOpts{}); err == nil && has.ExitCode == 0 {
+		kill, err := cr.Run(ctx, "tmux", []string{"kill-session", "-t", sessionName}, exec.RunOpts{})
+		if err != nil || kill.ExitCode != 0 {
+			res.Warnings = append(res.Warnings, "failed to kill tmux session "+sessionName)
+		} else {
+			res.SessionKilled = true
+		}
+	}
+
+	// 3. Worktree removal
+	if worktreePresent {
+		if err := removeWorktree(ctx, cr, opts.RepoRoot, st.DataDir, meta); err != nil {
+			return res, err
+		}
+	}
+
+	// 4. Record archive in meta.json
+	res.ArchivedAt = st.Now().UTC().Format(time.RFC3339)
+	err := st.UpdateMeta(meta.RepoID, meta.RunID, func(m *store.RunMeta) {
+		if m.Archive == nil {
+			m.Archive = &store.RunMetaArchive{}
+		}
+		m.Archive.ArchivedAt = res.ArchivedAt
+	})
+	return res, err
+}
+
+// removeWorktree removes the worktree via git when the repo root is known,
+// falling back to deleting the directory (and pruning stale worktree entries).
+// meta.worktree_path comes from disk, so the fallback only deletes a
+// directory inside <data_dir>/repos/<repo_id>/worktrees/; other paths (a
+// hand-edited meta.json, an adopted worktree git would not remove) fail
+// with E_ARCHIVE_FAILED.
+func removeWorktree(ctx context.Context, cr exec.CommandRunner, repoRoot, dataDir string, meta *store.RunMeta) error {
+	worktreePath := meta.WorktreePath
+	if repoRoot != "" {
+		result, err := cr.Run(ctx, "git", []string{"-C", repoRoot, "worktree", "remove", "--force", worktreePath}, exec.RunOpts{})
+		if err == nil && result.ExitCode == 0 {
+			return nil
+		}
+	}
+
+	if err := checkRemovable(dataDir, meta.RepoID, worktreePath); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(worktreePath); err != nil {
+		return errors.WrapWithDetails(
+			errors.EArchiveFailed,
+			"failed to remove worktree",
+			err,
+			map[string]string{"worktree_path": worktreePath},
+		)
+	}
+
+	if repoRoot != "" {
+		_, _ = cr.Run(ctx, "git", []string{"-C", repoRoot, "worktree", "prune"}, exec.RunOpts{})
+	}
+	return nil
+}
+
+// checkRemovable returns E_ARCHIVE_FAILED unless worktreePath, with symlinks
+// resolved, is strictly inside the repo's worktrees dir under dataDir.
+func checkRemovable(dataDir, repoID, worktreePath string) error {
+	worktreesDir := filepath.Join(dataDir, "repos", repoID, "worktrees")
+	refuse := func() error {
+		return errors.WithHints(
+			errors.NewWithDetails(errors.EArchiveFailed,
+				"refusing to delete worktree outside the data dir's worktrees",
+				map[string]string{"worktree_path": worktreePath, "worktrees_dir": worktreesDir}),
+			"remove the worktree with git worktree remove, or check meta.json worktree_path",
+		)
+	}
+	root, err := filepath.EvalSymlinks(worktreesDir)
+	if err != nil {
+		return refuse()
+	}
+	path, err := filepath.EvalSymlinks(filepath.Clean(worktreePath))
+	if err != nil {
+		return refuse()
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return refuse()
+	}
+	return nil
+}
+
+// runScript runs scripts.archive via sh -lc in the worktree, writing output to logPath.
+func runScript(ctx context.Context, st *store.Store, meta *store.RunMeta, opts Opts, logPath string) (int, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = ScriptTimeout
+	}
+
+	logsDir := filepath.Dir(logPath)
+	if err := os.MkdirAll(logsDir, 0o700); err != nil {
+		return -1, err
+	}
+
+	dotAgencyDir := filepath.Join(meta.WorktreePath, ".agency")
+	prNumber := ""
+	if meta.PRNumber != 0 {
+		prNumber = strconv.Itoa(meta.PRNumber)
+	}
+	env := map[string]string{
+		"AGENCY_RUN_ID":         meta.RunID,
+		"AGENCY_TITLE":          meta.Title,
+		"AGENCY_REPO_ROOT":      opts.RepoRoot,
+		"AGENCY_WORKSPACE_ROOT": meta.WorktreePath,
+		"AGENCY_BRANCH":         meta.Branch,
+		"AGENCY_PARENT_BRANCH":  meta.ParentBranch,
+		"AGENCY_RUNNER":         meta.Runner,
+		"AGENCY_PR_URL":         meta.PRURL,
+		"AGENCY_PR_NUMBER":      prNumber,
+		"AGENCY_DOTAGENCY_DIR":  dotAgencyDir,
+		"AGENCY_OUTPUT_DIR":     filepath.Join(dotAgencyDir, "out"),
+		"AGENCY_LOG_DIR":        logsDir,
+		"AGENCY_NONINTERACTIVE": "1",
+		"CI":                    "1",
+	}
+
+	start := time.Now()
+	result, runErr := exec.RunScript(ctx, "sh", []string{"-lc", opts.Script}, exec.ScriptOpts{
+		Dir:     meta.WorktreePath,
+		Env:     env,
+		Timeout: timeout,
+	})
+
+	var log strings.Builder
+	fmt.Fprintf(&log, "# agency archive log\n")
+	fmt.Fprintf(&log, "# timestamp: %s\n", start.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&log, "# command: sh -lc %s\n", opts.Script)
+	fmt.Fprintf(&log, "# cwd: %s\n", meta.WorktreePath)
+	fmt.Fprintf(&log, "# ---\n\n")
+	log.WriteString(result.Stdout)
+	log.WriteString(result.Stderr)
+	fmt.Fprintf(&log, "\n# exit_code: %d\n", result.ExitCode)
+	_ = os.WriteFile(logPath, []byte(log.String()), 0o644)
+
+	return result.ExitCode, runErr
+}
+
+// dirExists returns true if path exists and is a directory.
+func dirExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,136 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// newFakeRunner returns a testkit runner where unstubbed commands exit 1.
+func newFakeRunner() *testkit.FakeRunner {
+	cr := testkit.NewFakeRunner()
+	cr.Fallback = &testkit.Response{Result: exec.CmdResult{ExitCode: 1}}
+	return cr
+}
+
+func setupRun(t *testing.T) (*store.Store, *store.RunMeta) {
+	t.Helper()
+	dataDir := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	st := store.NewStore(fs.NewRealFS(), dataDir, func() time.Time { return now })
+
+	if _, err := st.EnsureRunDir("repo1", "run1"); err != nil {
+		t.Fatal(err)
+	}
+	worktree := filepath.Join(dataDir, "repos", "repo1", "worktrees", "run1")
+	if err := os.MkdirAll(filepath.Join(worktree, ".agency"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	meta := store.NewRunMeta("run1", "repo1", "t", "claude", "claude", "main", "agency/t-run1", worktree, now)
+	meta.TmuxSessionName = "agency_run1"
+	if err := st.WriteInitialMeta("repo1", "run1", meta); err != nil {
+		t.Fatal(err)
+	}
+	return st, meta
+}
+
+func TestArchive_RemovesWorktreeAndRecordsMeta(t *testing.T) {
+	st, meta := setupRun(t)
+	cr := newFakeRunner()
+	cr.TmuxSessions("agency_run1")
+
+	res, err := Archive(context.Background(), cr, st, meta, Opts{Script: "echo archiving; exit 3"})
+	if err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+
+	if _, err := os.Stat(meta.WorktreePath); !os.IsNotExist(err) {
+		t.Error("worktree should be removed")
+	}
+	if !res.SessionKilled {
+		t.Error("SessionKilled should be true")
+	}
+	if !res.ScriptRan || res.ScriptExitCode != 3 {
+		t.Errorf("script ran=%v exit=%d, want ran with exit 3", res.ScriptRan, res.ScriptExitCode)
+	}
+	if len(res.Warnings) != 1 {
+		t.Errorf("Warnings = %v, want one script warning", res.Warnings)
+	}
+
+	logData, err := os.ReadFile(res.ScriptLogPath)
+	if err != nil {
+		t.Fatalf("archive log not written: %v", err)
+	}
+	if !strings.Contains(string(logData), "archiving") {
+		t.Errorf("archive log missing script output: %q", string(logData))
+	}
+
+	got, err := st.ReadMeta("repo1", "run1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Archive == nil || got.Archive.ArchivedAt != "2026-03-01T12:00:00Z" {
+		t.Errorf("Archive = %+v, want archived_at recorded", got.Archive)
+	}
+}
+
+func TestArchive_RefusesToDeleteOutsideWorktrees(t *testing.T) {
+	outside := t.TempDir()
+	keep := filepath.Join(outside, "keep.txt")
+	if err := os.WriteFile(keep, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, path := range map[string]func(st *store.Store) string{
+		"outside the data dir": func(*store.Store) string { return outside },
+		"the worktrees dir":    func(st *store.Store) string { return filepath.Join(st.DataDir, "repos", "repo1", "worktrees") },
+		"escaping with ..": func(st *store.Store) string {
+			return filepath.Join(st.DataDir, "repos", "repo1", "worktrees", "..", "runs")
+		},
+		"another repo's worktree": func(st *store.Store) string {
+			dir := filepath.Join(st.DataDir, "repos", "repo2", "worktrees", "run9")
+			_ = os.MkdirAll(dir, 0o755)
+			return dir
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			st, meta := setupRun(t)
+			meta.WorktreePath = path(st)
+
+			_, err := Archive(context.Background(), newFakeRunner(), st, meta, Opts{})
+			if errors.GetCode(err) != errors.EArchiveFailed {
+				t.Errorf("Archive() code = %q, want %q", errors.GetCode(err), errors.EArchiveFailed)
+			}
+			if _, err := os.Stat(meta.WorktreePath); err != nil {
+				t.Errorf("%s was deleted: %v", meta.WorktreePath, err)
+			}
+		})
+	}
+	if _, err := os.Stat(keep); err != nil {
+		t.Errorf("file outside the data dir deleted: %v", err)
+	}
+}
+
+func TestArchive_UsesGitWhenRepoRootKnown(t *testing.T) {
+	st, meta := setupRun(t)
+	removeArgs := []string{"-C", "/repo", "worktree", "remove", "--force", meta.WorktreePath}
+	cr := newFakeRunner()
+	cr.On("git", removeArgs...)
+
+	if _, err := Archive(context.Background(), cr, st, meta, Opts{RepoRoot: "/repo"}); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+
+	if !cr.Called("git", removeArgs...) {
+		t.Errorf("expected git %v in calls: %v", removeArgs, cr.Calls())
+	}
+}
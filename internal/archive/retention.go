@@ -0,0 +1,95 @@
+package archive
+
+import (
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// Candidate reasons for retention-based archival.
+const (
+	ReasonMerged    = "merged"
+	ReasonAbandoned = "abandoned"
+)
+
+// RetentionCandidate describes why a run qualifies for auto-archive.
+type RetentionCandidate struct {
+	Reason  string    // "merged" or "abandoned"
+	Since   time.Time // when the run entered that state (best-effort)
+	AgeDays int       // whole days since Since
+}
+
+// CheckRetention reports whether meta qualifies for auto-archive under a
+// retention of afterDays (0 disables). Only merged or abandoned runs that are
+// not yet archived qualify. Merged runs age from archive.merged_at; abandoned
+// runs have no recorded timestamp, so they age from the later of created_at
+// and last_push_at.
+func CheckRetention(meta *store.RunMeta, afterDays int, now time.Time) (RetentionCandidate, bool) {
+	if meta == nil || afterDays <= 0 {
+		return RetentionCandidate{}, false
+	}
+	if meta.Archive != nil && meta.Archive.ArchivedAt != "" {
+		return RetentionCandidate{}, false
+	}
+
+	var c RetentionCandidate
+	switch {
+	case meta.Archive != nil && meta.Archive.MergedAt != "":
+		t, err := time.Parse(time.RFC3339, meta.Archive.MergedAt)
+		if err != nil {
+			return RetentionCandidate{}, false
+		}
+		c = RetentionCandidate{Reason: ReasonMerged, Since: t}
+	case meta.Flags != nil && meta.Flags.Abandoned:
+		since, ok := latestTime(meta.CreatedAt, meta.LastPushAt)
+		if !ok {
+			return RetentionCandidate{}, false
+		}
+		c = RetentionCandidate{Reason: ReasonAbandoned, Since: since}
+	default:
+		return RetentionCandidate{}, false
+	}
+
+	age := now.Sub(c.Since)
+	c.AgeDays = int(age / (24 * time.Hour))
+	if age < time.Duration(afterDays)*24*time.Hour {
+		return RetentionCandidate{}, false
+	}
+	return c, true
+}
+
+// EligibleReason returns ReasonMerged or ReasonAbandoned if meta is a run that
+// retention would archive once old enough (merged or abandoned, not yet
+// archived), or "" otherwise. Unlike CheckRetention it ignores age.
+func EligibleReason(meta *store.RunMeta) string {
+	if meta == nil || (meta.Archive != nil && meta.Archive.ArchivedAt != "") {
+		return ""
+	}
+	switch {
+	case meta.Archive != nil && meta.Archive.MergedAt != "":
+		return ReasonMerged
+	case meta.Flags != nil && meta.Flags.Abandoned:
+		return ReasonAbandoned
+	}
+	return ""
+}
+
+// latestTime returns the latest parseable RFC3339 timestamp among values.
+func latestTime(values ...string) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			continue
+		}
+		if !found || t.After(latest) {
+			latest = t
+			found = true
+		}
+	}
+	return latest, found
+}
@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+func TestCheckRetention(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		meta       *store.RunMeta
+		days       int
+		wantOK     bool
+		wantReason string
+		wantAge    int
+	}{
+		{
+			name:       "merged past retention",
+			meta:       &store.RunMeta{Archive: &store.RunMetaArchive{MergedAt: "2026-01-01T12:00:00Z"}},
+			days:       30,
+			wantOK:     true,
+			wantReason: ReasonMerged,
+			wantAge:    59,
+		},
+		{
+			name:   "merged within retention",
+			meta:   &store.RunMeta{Archive: &store.RunMetaArchive{MergedAt: "2026-02-20T12:00:00Z"}},
+			days:   30,
+			wantOK: false,
+		},
+		{
+			name: "abandoned ages from latest push",
+			meta: &store.RunMeta{
+				CreatedAt:  "2026-01-01T12:00:00Z",
+				LastPushAt: "2026-02-25T12:00:00Z",
+				Flags:      &store.RunMetaFlags{Abandoned: true},
+			},
+			days:   7,
+			wantOK: false,
+		},
+		{
+			name: "abandoned past retention",
+			meta: &store.RunMeta{
+				CreatedAt: "2026-01-01T12:00:00Z",
+				Flags:     &store.RunMetaFlags{Abandoned: true},
+			},
+			days:       7,
+			wantOK:     true,
+			wantReason: ReasonAbandoned,
+			wantAge:    59,
+		},
+		{
+			name:   "already archived",
+			meta:   &store.RunMeta{Archive: &store.RunMetaArchive{MergedAt: "2026-01-01T12:00:00Z", ArchivedAt: "2026-01-02T12:00:00Z"}},
+			days:   30,
+			wantOK: false,
+		},
+		{
+			name:   "active run never qualifies",
+			meta:   &store.RunMeta{CreatedAt: "2025-01-01T12:00:00Z"},
+			days:   1,
+			wantOK: false,
+		},
+		{
+			name:   "retention disabled",
+			meta:   &store.RunMeta{Archive: &store.RunMetaArchive{MergedAt: "2025-01-01T12:00:00Z"}},
+			days:   0,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CheckRetention(tt.meta, tt.days, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.Reason != tt.wantReason {
+				t.Errorf("Reason = %q, want %q", got.Reason, tt.wantReason)
+			}
+			if got.AgeDays != tt.wantAge {
+				t.Errorf("AgeDays = %d, want %d", got.AgeDays, tt.wantAge)
+			}
+		})
+	}
+}
+
+func TestEligibleReason(t *testing.T) {
+	tests := []struct {
+		name string
+		meta *store.RunMeta
+		want string
+	}{
+		{"nil", nil, ""},
+		{"active", &store.RunMeta{}, ""},
+		{"merged", &store.RunMeta{Archive: &store.RunMetaArchive{MergedAt: "2026-02-28T12:00:00Z"}}, ReasonMerged},
+		{"abandoned", &store.RunMeta{Flags: &store.RunMetaFlags{Abandoned: true}}, ReasonAbandoned},
+		{"already archived", &store.RunMeta{Archive: &store.RunMetaArchive{MergedAt: "2026-02-28T12:00:00Z", ArchivedAt: "2026-03-01T12:00:00Z"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := EligibleReason(tt.meta); got != tt.want {
+			t.Errorf("%s: EligibleReason() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package audit provides the append-only audit.jsonl log of commands that
+// changed agency state, kept in the data dir next to repos/.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// SchemaVersion is the audit.jsonl line schema version.
+const SchemaVersion = "1.0"
+
+// FileName is the audit log's name in the data dir.
+const FileName = "audit.jsonl"
+
+// MaxSize is the size at which audit.jsonl is rotated to audit.jsonl.1
+// (a var so tests can lower it).
+var MaxSize int64 = 10 << 20
+
+// Keep is the number of rotated files kept (audit.jsonl.1 .. audit.jsonl.Keep).
+const Keep = 3
+
+// Outcomes recorded in Entry.Outcome.
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// Run identifies a run a command resolved, created, or changed.
+type Run struct {
+	RepoID string `json:"repo_id"`
+	RunID  string `json:"run_id"`
+}
+
+// Entry is a single line in audit.jsonl.
+type Entry struct {
+	SchemaVersion string   `json:"schema_version"`
+	Timestamp     string   `json:"timestamp"`
+	Command       string   `json:"command"`
+	Argv          []string `json:"argv"`
+	User          string   `json:"user,omitempty"`
+	Host          string   `json:"host,omitempty"`
+	Cwd           string   `json:"cwd"`
+	Runs          []Run    `json:"runs,omitempty"`
+	Outcome       string   `json:"outcome"`
+	ErrorCode     string   `json:"error_code,omitempty"`
+	Error         string   `json:"error,omitempty"`
+	DurationMS    int64    `json:"duration_ms"`
+}
+
+// Path returns the audit log path for a data dir.
+func Path(dataDir string) string {
+	return filepath.Join(dataDir, FileName)
+}
+
+// rotatedPath returns the path of the n-th rotated file (n >= 1).
+func rotatedPath(dataDir string, n int) string {
+	return fmt.Sprintf("%s.%d", Path(dataDir), n)
+}
+
+// Append appends e as a single JSON line to the data dir's audit log,
+// rotating it first if the line would take it past MaxSize.
+func Append(dataDir string, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return err
+	}
+	path := Path(dataDir)
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > MaxSize {
+		if err := rotate(dataDir); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fs.ShareFile(f, dataDir)
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rotate shifts audit.jsonl.N to .N+1, dropping the oldest, and moves
+// audit.jsonl to audit.jsonl.1.
+func rotate(dataDir string) error {
+	if err := os.Remove(rotatedPath(dataDir, Keep)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := Keep - 1; n >= 1; n-- {
+		if err := os.Rename(rotatedPath(dataDir, n), rotatedPath(dataDir, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(Path(dataDir), rotatedPath(dataDir, 1))
+}
+
+// Read returns the entries of the audit log and its rotated files, oldest
+// first. Missing files are skipped, as are lines that do not parse (a write
+// cut short by a crash).
+func Read(dataDir string) ([]Entry, error) {
+	var entries []Entry
+	for n := Keep; n >= 0; n-- {
+		path := Path(dataDir)
+		if n > 0 {
+			path = rotatedPath(dataDir, n)
+		}
+		fileEntries, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+func readFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// touched collects the runs the current command has worked on, for its
+// audit entry.
+var (
+	touchedMu sync.Mutex
+	touched   = map[Run]bool{}
+)
+
+// Touch records that the current command resolved, created, or changed a run.
+func Touch(repoID, runID string) {
+	touchedMu.Lock()
+	defer touchedMu.Unlock()
+	touched[Run{RepoID: repoID, RunID: runID}] = true
+}
+
+// Touched returns the runs recorded by Touch, sorted by run_id, and resets
+// the set.
+func Touched() []Run {
+	touchedMu.Lock()
+	defer touchedMu.Unlock()
+	runs := make([]Run, 0, len(touched))
+	for r := range touched {
+		runs = append(runs, r)
+	}
+	touched = map[Run]bool{}
+	sort.Slice(runs, func(i, j int) bool {
+		if runs[i].RunID != runs[j].RunID {
+			return runs[i].RunID < runs[j].RunID
+		}
+		return runs[i].RepoID < runs[j].RepoID
+	})
+	return runs
+}
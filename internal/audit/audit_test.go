@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestAppendRead(t *testing.T) {
+	dataDir := t.TempDir()
+	for _, cmd := range []string{"run", "note", "kill"} {
+		if err := Append(dataDir, Entry{SchemaVersion: SchemaVersion, Command: cmd, Outcome: OutcomeOK}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A torn line is skipped
+	f, err := os.OpenFile(Path(dataDir), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"command":"m` + "\n")
+	f.Close()
+
+	entries, err := Read(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Command)
+	}
+	if want := []string{"run", "note", "kill"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() commands = %v, want %v", got, want)
+	}
+}
+
+func TestAppendRotates(t *testing.T) {
+	defer func(size int64) { MaxSize = size }(MaxSize)
+	MaxSize = 200
+
+	dataDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		if err := Append(dataDir, Entry{Command: strconv.Itoa(i), Cwd: "/src/app"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := os.Stat(rotatedPath(dataDir, Keep+1)); !os.IsNotExist(err) {
+		t.Errorf("audit.jsonl.%d exists, want at most %d rotated files", Keep+1, Keep)
+	}
+	for n := 1; n <= Keep; n++ {
+		info, err := os.Stat(rotatedPath(dataDir, n))
+		if err != nil {
+			t.Fatalf("rotated file %d: %v", n, err)
+		}
+		if info.Size() > MaxSize {
+			t.Errorf("rotated file %d size = %d, want <= %d", n, info.Size(), MaxSize)
+		}
+	}
+
+	entries, err := Read(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 || entries[len(entries)-1].Command != "19" {
+		t.Fatalf("Read() = %v, want the newest entry last", entries)
+	}
+	for i := 1; i < len(entries); i++ {
+		prev, _ := strconv.Atoi(entries[i-1].Command)
+		cur, _ := strconv.Atoi(entries[i].Command)
+		if cur != prev+1 {
+			t.Errorf("entries out of order: %s after %s", entries[i].Command, entries[i-1].Command)
+		}
+	}
+}
+
+func TestTouched(t *testing.T) {
+	Touched()
+	Touch("r1", "20260110120000-bbbb")
+	Touch("r1", "20260110120000-aaaa")
+	Touch("r1", "20260110120000-bbbb")
+
+	want := []Run{{RepoID: "r1", RunID: "20260110120000-aaaa"}, {RepoID: "r1", RunID: "20260110120000-bbbb"}}
+	if got := Touched(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Touched() = %v, want %v", got, want)
+	}
+	if got := Touched(); len(got) != 0 {
+		t.Errorf("Touched() after reset = %v, want none", got)
+	}
+}
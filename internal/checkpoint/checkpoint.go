@@ -0,0 +1,388 @@
+// Package checkpoint snapshots a run worktree (tracked changes as a git
+// stash commit kept alive by a ref, untracked files as a tarball under the
+// run dir) and restores it later.
+package checkpoint
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// SchemaVersion is the checkpoint.json schema version.
+const SchemaVersion = "1.0"
+
+// Keep is how many checkpoints `agency gc --auto` keeps per active run.
+// Archived runs lose all of theirs, since there is no worktree to restore.
+const Keep = 10
+
+// untrackedArchive is the tarball of untracked files in a checkpoint dir.
+const untrackedArchive = "untracked.tar.gz"
+
+// Checkpoint is the checkpoint.json record of one snapshot.
+type Checkpoint struct {
+	SchemaVersion string `json:"schema_version"`
+
+	// Number identifies the checkpoint within its run (1, 2, ...).
+	Number int `json:"number"`
+
+	// CreatedAt is when the snapshot was taken (RFC3339, UTC).
+	CreatedAt string `json:"created_at"`
+
+	// Message is the optional description given with --message.
+	Message string `json:"message,omitempty"`
+
+	// Branch is the branch checked out in the worktree ("HEAD" if detached).
+	Branch string `json:"branch"`
+
+	// Head is the commit the worktree was on.
+	Head string `json:"head"`
+
+	// Stash is the `git stash create` commit holding staged and unstaged
+	// changes to tracked files ("" if there were none).
+	Stash string `json:"stash,omitempty"`
+
+	// Ref keeps Stash (or Head) from being garbage collected by git:
+	// refs/agency/checkpoints/<run_id>/<number>.
+	Ref string `json:"ref"`
+
+	// UntrackedFiles is how many untracked, non-ignored files were saved.
+	UntrackedFiles int `json:"untracked_files"`
+
+	// UntrackedBytes is the size of the untracked files tarball.
+	UntrackedBytes int64 `json:"untracked_bytes"`
+}
+
+// Dir returns the checkpoints dir of a run: <run_dir>/checkpoints/.
+func Dir(runDir string) string {
+	return filepath.Join(runDir, "checkpoints")
+}
+
+// checkpointDir returns the dir of checkpoint n.
+func checkpointDir(runDir string, n int) string {
+	return filepath.Join(Dir(runDir), strconv.Itoa(n))
+}
+
+// RefName returns the git ref that keeps checkpoint n of runID alive.
+func RefName(runID string, n int) string {
+	return "refs/agency/checkpoints/" + runID + "/" + strconv.Itoa(n)
+}
+
+// List returns a run's checkpoints, oldest first. Unreadable entries are
+// skipped; a run without checkpoints yields nil.
+func List(runDir string) ([]Checkpoint, error) {
+	entries, err := os.ReadDir(Dir(runDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var cps []Checkpoint
+	for _, e := range entries {
+		n, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(checkpointDir(runDir, n), "checkpoint.json"))
+		if err != nil {
+			continue
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil || cp.Number != n {
+			continue
+		}
+		cps = append(cps, cp)
+	}
+	sort.Slice(cps, func(i, j int) bool { return cps[i].Number < cps[j].Number })
+	return cps, nil
+}
+
+// Find returns checkpoint n of a run, or false if there is none.
+func Find(runDir string, n int) (Checkpoint, bool, error) {
+	cps, err := List(runDir)
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	for _, cp := range cps {
+		if cp.Number == n {
+			return cp, true, nil
+		}
+	}
+	return Checkpoint{}, false, nil
+}
+
+// Create snapshots the worktree as the run's next checkpoint. The worktree
+// itself is left untouched: tracked changes are captured with
+// `git stash create`, untracked non-ignored files outside .agency/ are tarred.
+func Create(ctx context.Context, cr exec.CommandRunner, runDir, runID, worktreePath, message string, now time.Time) (Checkpoint, error) {
+	existing, err := List(runDir)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	n := 1
+	if len(existing) > 0 {
+		n = existing[len(existing)-1].Number + 1
+	}
+	cp := Checkpoint{
+		SchemaVersion: SchemaVersion,
+		Number:        n,
+		CreatedAt:     now.UTC().Format(time.RFC3339),
+		Message:       message,
+		Ref:           RefName(runID, n),
+	}
+
+	if cp.Head, err = runGit(ctx, cr, worktreePath, "rev-parse", "HEAD"); err != nil {
+		return Checkpoint{}, err
+	}
+	if cp.Branch, err = runGit(ctx, cr, worktreePath, "rev-parse", "--abbrev-ref", "HEAD"); err != nil {
+		return Checkpoint{}, err
+	}
+	if cp.Stash, err = runGit(ctx, cr, worktreePath, "stash", "create"); err != nil {
+		return Checkpoint{}, err
+	}
+	untracked, err := runGit(ctx, cr, worktreePath, "ls-files", "--others", "--exclude-standard", "--exclude=/.agency/", "-z")
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	dir := checkpointDir(runDir, n)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Checkpoint{}, err
+	}
+	cp.UntrackedFiles, cp.UntrackedBytes, err = writeTarball(filepath.Join(dir, untrackedArchive), worktreePath, untrackedPaths(untracked))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return Checkpoint{}, err
+	}
+
+	target := cp.Head
+	if cp.Stash != "" {
+		target = cp.Stash
+	}
+	if _, err := runGit(ctx, cr, worktreePath, "update-ref", cp.Ref, target); err != nil {
+		_ = os.RemoveAll(dir)
+		return Checkpoint{}, err
+	}
+
+	if err := fs.WriteJSONAtomic(filepath.Join(dir, "checkpoint.json"), cp, 0o644); err != nil {
+		_ = os.RemoveAll(dir)
+		return Checkpoint{}, err
+	}
+	return cp, nil
+}
+
+// CheckBranch returns E_CHECKPOINT_FAILED unless the worktree has cp.Branch
+// checked out: restoring resets the checked-out branch to cp.Head, which
+// would rewind an unrelated branch and lose its tip.
+func CheckBranch(ctx context.Context, cr exec.CommandRunner, worktreePath string, cp Checkpoint) error {
+	branch, err := runGit(ctx, cr, worktreePath, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return errors.Wrap(errors.ECheckpointFailed, "failed to read the worktree's branch", err)
+	}
+	if branch == cp.Branch {
+		return nil
+	}
+	return errors.WithHints(errors.NewWithDetails(errors.ECheckpointFailed,
+		fmt.Sprintf("checkpoint %d was taken on %s, but the worktree has %s checked out", cp.Number, cp.Branch, branch),
+		map[string]string{"checkpoint_branch": cp.Branch, "worktree_branch": branch, "worktree_path": worktreePath}),
+		fmt.Sprintf("check out %s first: git -C %s checkout %s", cp.Branch, worktreePath, cp.Branch))
+}
+
+// Restore rolls the worktree back to cp: the branch is reset to cp.Head
+// (later commits stay reachable through the reflog), untracked non-ignored
+// files are removed, and the saved tracked changes and untracked files are
+// put back. Ignored files (build output, dependencies) and .agency/ are kept.
+// Returns the CheckBranch error, changing nothing, if another branch is
+// checked out.
+func Restore(ctx context.Context, cr exec.CommandRunner, runDir, worktreePath string, cp Checkpoint) error {
+	if err := CheckBranch(ctx, cr, worktreePath, cp); err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, cr, worktreePath, "reset", "--hard", cp.Head); err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, cr, worktreePath, "clean", "-fd", "-e", "/.agency/"); err != nil {
+		return err
+	}
+	if cp.Stash != "" {
+		if _, err := runGit(ctx, cr, worktreePath, "stash", "apply", "--index", cp.Stash); err != nil {
+			return err
+		}
+	}
+	if cp.UntrackedFiles > 0 {
+		return extractTarball(filepath.Join(checkpointDir(runDir, cp.Number), untrackedArchive), worktreePath)
+	}
+	return nil
+}
+
+// Delete removes checkpoint cp: its dir under the run dir and its ref.
+// gitDir is any checkout of the repo (refs are shared by all worktrees);
+// empty skips the ref, leaving it for git to keep.
+func Delete(ctx context.Context, cr exec.CommandRunner, gitDir, runDir string, cp Checkpoint) error {
+	if gitDir != "" {
+		if _, err := runGit(ctx, cr, gitDir, "update-ref", "-d", cp.Ref); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(checkpointDir(runDir, cp.Number))
+}
+
+// runGit runs git in dir and returns its trimmed stdout.
+func runGit(ctx context.Context, cr exec.CommandRunner, dir string, args ...string) (string, error) {
+	result, err := cr.Run(ctx, "git", args, exec.RunOpts{Dir: dir})
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(result.Stderr))
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// untrackedPaths splits `git ls-files -z` output. Directory entries (nested
+// git repos, which git does not descend into) are left out.
+func untrackedPaths(out string) []string {
+	var paths []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" && !strings.HasSuffix(p, "/") {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// writeTarball writes the files at paths (relative to root) to a gzipped
+// tarball, keeping modes and symlinks. Returns the file count and the
+// tarball size.
+func writeTarball(tarPath, root string, paths []string) (int, int64, error) {
+	f, err := os.OpenFile(tarPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	count := 0
+	for _, rel := range paths {
+		full := filepath.Join(root, rel)
+		info, err := os.Lstat(full)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // removed since ls-files
+			}
+			return 0, 0, err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(full); err != nil {
+				return 0, 0, err
+			}
+		} else if !info.Mode().IsRegular() {
+			continue
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return 0, 0, err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return 0, 0, err
+		}
+		if info.Mode().IsRegular() {
+			if err := copyFileTo(tw, full); err != nil {
+				return 0, 0, err
+			}
+		}
+		count++
+	}
+
+	if err := tw.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	return count, info.Size(), f.Close()
+}
+
+func copyFileTo(w io.Writer, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(w, src)
+	return err
+}
+
+// extractTarball unpacks a writeTarball tarball into root, overwriting
+// existing files. Entries escaping root are rejected.
+func extractTarball(tarPath, root string) error {
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("checkpoint archive entry escapes the worktree: %s", hdr.Name)
+		}
+		target := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		_ = os.Remove(target)
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package checkpoint
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// newWorktree returns a repo with one commit of a.txt and an ignored build/.
+func newWorktree(t *testing.T) string {
+	t.Helper()
+	return testkit.NewRepo(t, testkit.RepoOpts{
+		Git:          true,
+		NoAgencyJSON: true,
+		Scripts:      map[string]string{},
+		Files:        map[string]string{"a.txt": "one\n", ".gitignore": "build/\n.agency/\n"},
+	})
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	wt := newWorktree(t)
+	runDir := t.TempDir()
+	ctx := context.Background()
+	cr := agencyexec.NewRealRunner()
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	git := func(args ...string) string { return strings.TrimSpace(testkit.Git(t, wt, args...)) }
+	write := func(rel, content string) { testkit.WriteFile(t, filepath.Join(wt, rel), content) }
+	read := func(rel string) string {
+		data, err := os.ReadFile(filepath.Join(wt, rel))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	// Checkpoint 1: a staged edit, an unstaged edit, and untracked files
+	write("a.txt", "two\n")
+	git("add", "a.txt")
+	write("a.txt", "three\n")
+	write("notes/new.txt", "untracked\n")
+	write("build/out.bin", "ignored\n")
+	write(".agency/report.md", "report v1\n")
+
+	cp, err := Create(ctx, cr, runDir, "run1", wt, "before refactor", now)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if cp.Number != 1 || cp.Stash == "" || cp.UntrackedFiles != 1 || cp.Message != "before refactor" || cp.Ref != "refs/agency/checkpoints/run1/1" {
+		t.Errorf("checkpoint = %+v", cp)
+	}
+	if got := git("rev-parse", cp.Ref); got != cp.Stash {
+		t.Errorf("ref = %s, want stash %s", got, cp.Stash)
+	}
+	if read("a.txt") != "three\n" {
+		t.Error("Create() modified the worktree")
+	}
+
+	// Wreck the worktree: commit, delete, add files
+	git("commit", "-q", "-am", "later work")
+	write("a.txt", "broken\n")
+	write("stray.txt", "stray\n")
+	if err := os.RemoveAll(filepath.Join(wt, "notes")); err != nil {
+		t.Fatal(err)
+	}
+	write(".agency/report.md", "report v2\n")
+
+	if err := Restore(ctx, cr, runDir, wt, cp); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := git("rev-parse", "HEAD"); got != cp.Head {
+		t.Errorf("HEAD = %s, want %s", got, cp.Head)
+	}
+	if got := read("a.txt"); got != "three\n" {
+		t.Errorf("a.txt = %q, want unstaged edit", got)
+	}
+	if got := git("show", ":a.txt"); got != "two" {
+		t.Errorf("index a.txt = %q, want staged edit", got)
+	}
+	if got := read("notes/new.txt"); got != "untracked\n" {
+		t.Errorf("notes/new.txt = %q", got)
+	}
+	if got := read("stray.txt"); got != "<missing>" {
+		t.Errorf("stray.txt survived restore: %q", got)
+	}
+	if got := read("build/out.bin"); got != "ignored\n" {
+		t.Errorf("ignored file lost: %q", got)
+	}
+	if got := read(".agency/report.md"); got != "report v2\n" {
+		t.Errorf(".agency/report.md = %q, want it untouched", got)
+	}
+
+	// A clean worktree checkpoints just HEAD; numbers keep counting
+	git("stash", "-q", "-u")
+	cp2, err := Create(ctx, cr, runDir, "run1", wt, "", now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Create() clean error = %v", err)
+	}
+	if cp2.Number != 2 || cp2.Stash != "" || cp2.UntrackedFiles != 0 {
+		t.Errorf("clean checkpoint = %+v", cp2)
+	}
+
+	cps, err := List(runDir)
+	if err != nil || len(cps) != 2 || cps[0].Number != 1 || cps[1].Number != 2 {
+		t.Fatalf("List() = %+v, %v", cps, err)
+	}
+
+	if err := Delete(ctx, cr, wt, runDir, cps[0]); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, found, _ := Find(runDir, 1); found {
+		t.Error("checkpoint 1 still listed after Delete()")
+	}
+	if _, err := runGit(ctx, cr, wt, "rev-parse", "--verify", "-q", cp.Ref); err == nil {
+		t.Error("ref still present after Delete()")
+	}
+}
+
+func TestRestore_OtherBranchCheckedOut(t *testing.T) {
+	wt := newWorktree(t)
+	runDir := t.TempDir()
+	ctx := context.Background()
+	cr := agencyexec.NewRealRunner()
+
+	cp, err := Create(ctx, cr, runDir, "run1", wt, "", time.Now())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// The user switches branches and commits there
+	testkit.Git(t, wt, "checkout", "-q", "-b", "other")
+	testkit.WriteFile(t, filepath.Join(wt, "a.txt"), "other work\n")
+	testkit.Git(t, wt, "commit", "-q", "-am", "other work")
+	tip := strings.TrimSpace(testkit.Git(t, wt, "rev-parse", "HEAD"))
+
+	err = Restore(ctx, cr, runDir, wt, cp)
+	if errors.GetCode(err) != errors.ECheckpointFailed || !strings.Contains(err.Error(), "worktree has other checked out") {
+		t.Fatalf("Restore() error = %v, want E_CHECKPOINT_FAILED for the branch mismatch", err)
+	}
+	if got := strings.TrimSpace(testkit.Git(t, wt, "rev-parse", "other")); got != tip {
+		t.Errorf("branch other = %s, want its tip %s kept", got, tip)
+	}
+}
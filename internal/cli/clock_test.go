@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/commands"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// TestRun_InjectedClock runs adopt and note end to end under a fixed clock:
+// the run_id, created_at, and note timestamp are all deterministic.
+func TestRun_InjectedClock(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	repoRoot := testkit.NewRepo(t, testkit.RepoOpts{Git: true})
+	testkit.Git(t, repoRoot, "branch", "feature/manual")
+
+	clock := testkit.NewClock(time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	defer commands.SetClock(clock.Core())()
+
+	var stdout, stderr bytes.Buffer
+	if err := Run([]string{"-C", repoRoot, "adopt", "--no-worktree", "feature/manual"}, &stdout, &stderr); err != nil {
+		t.Fatalf("adopt: %v (stderr: %s)", err, stderr.String())
+	}
+	const runID = "20260110120000-0001"
+	if !strings.Contains(stdout.String(), "run_id: "+runID) {
+		t.Fatalf("adopt stdout = %q, want run_id %s", stdout.String(), runID)
+	}
+
+	clock.Advance(90 * time.Second)
+	stdout.Reset()
+	if err := Run([]string{"note", runID, "checked", "in"}, &stdout, &stderr); err != nil {
+		t.Fatalf("note: %v (stderr: %s)", err, stderr.String())
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dataDir, "repos", "*", "runs", runID))
+	if len(matches) != 1 {
+		t.Fatalf("run dir for %s not found", runID)
+	}
+	meta, err := os.ReadFile(filepath.Join(matches[0], "meta.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(meta), `"created_at": "2026-01-10T12:00:00Z"`) {
+		t.Errorf("meta.json = %s", meta)
+	}
+	notes, err := os.ReadFile(filepath.Join(matches[0], "notes.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(notes), "2026-01-10T12:01:30Z") {
+		t.Errorf("notes.jsonl = %s", notes)
+	}
+}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/NielsdaWheelz/agency/internal/commands"
+)
+
+// RunConductor is the entry point of the conductor compatibility binary
+// (the tool's former name). It offers once to migrate a legacy conductor
+// data dir, then runs args exactly like Run.
+func RunConductor(args []string, stdout, stderr io.Writer) error {
+	var confirmFn func(string) bool
+	if stdinIsTerminal() {
+		confirmFn = func(prompt string) bool {
+			return confirm(stdin, stderr, prompt)
+		}
+	}
+	if err := commands.MigrateLegacyDataDir(confirmFn, stderr); err != nil {
+		return err
+	}
+	return Run(args, stdout, stderr)
+}
@@ -2,16 +2,25 @@
 package cli
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/audit"
 	"github.com/NielsdaWheelz/agency/internal/commands"
+	"github.com/NielsdaWheelz/agency/internal/core"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	"github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/identity"
 	"github.com/NielsdaWheelz/agency/internal/version"
 )
 
@@ -23,11 +32,55 @@ commands:
   init        create agency.json template and stub scripts
   doctor      check prerequisites and show resolved paths
   run         create workspace, setup, and start tmux runner session
+  adopt       manage an existing branch as a run
   ls          list runs and their statuses
   show        show run details
   attach      attach to a tmux session for an existing run
+  note        append a timestamped note to a run
+  logs        list or print a run's script logs
+  watch-files print a live feed of file changes in a run's worktree
+  watch       run agency.json health probes for active runs
+  mv          change a run's title (and optionally its branch)
+  group       add runs to named groups and list groups with aggregate status
+  kill        kill the tmux session for one or more runs
+  verify      run scripts.verify for one or more runs, or all open runs
+  cleanup     delete the remote branch and archive runs whose PR was merged
+  checkpoint  snapshot a run's worktree before a risky step
+  restore     roll a run's worktree back to a checkpoint
+  unlock      remove a stale repo lock left by a crashed agency command
+  relocate    move the data dir and rewrite the paths stored in it
+  tmux        kill agency tmux sessions left by deleted or archived runs
+  repos       re-detect repo capabilities (GitHub origin, gh auth)
+  gc          apply retention policy (auto-archive old merged/abandoned runs)
+  lint        validate meta.json contents for one or all runs
+  diff-env    compare the setup environments captured for two runs
+  compare     show two runs side by side (setup, verify, diff size, report)
+  bundle      collect a run's logs, metadata, and diagnostics into a
+              redacted tarball for a bug report
+  report      write a Markdown/HTML digest of runs grouped by repo and status
+  digest      summarize recent run activity for a team channel
+  audit       show the log of commands that changed agency state
+  config      get, set, list, or edit user settings
+  schema      print the JSON schemas of agency's files and output, or
+              validate a file against one
+  branch-guard
+              warn or block checkouts of agency/* branches in the main repo
 
 options:
+  --plain         line-oriented "key: value" output (no tables or banners);
+                  also enabled by AGENCY_PLAIN=1, TERM=dumb, or "plain": true
+                  in ${AGENCY_CONFIG_DIR}/config.json
+  -C, --repo <path>
+                  run as if agency was started in <path> (like git -C)
+  --force-read-only
+                  inspect a data dir written in an unsupported format with
+                  read-only commands (ls, show, logs, group ls, report,
+                  diff-env, compare, lint, bundle)
+  --read-only     refuse every command that would modify agency state, repos,
+                  or worktrees (E_READ_ONLY); also enabled by
+                  AGENCY_READ_ONLY=1. for dashboards and cron jobs
+  --allow-root    run as root even when the data dir or cwd belongs to
+                  another user (refused with E_RUNNING_AS_ROOT otherwise)
   -h, --help      show this help
   -v, --version   show version
 
@@ -35,160 +88,2858 @@ run 'agency <command> --help' for command-specific help.
 `
 
 const initUsageText = `usage: agency init [options]
+       agency init --from-url <git-url> [--dir <path>] [--doctor] [options]
 
-create agency.json template and stub scripts in the current repo.
+create agency.json and stub scripts in the current repo.
+with --from-url, clone the repo first, initialize the clone, and register it
+in the repo index.
+
+when stdin is a terminal, asks for the parent branch, runner, and whether
+to enable the GitHub flow, checking tmux and gh as it goes; otherwise (or
+with --yes) writes the default template.
 
 options:
   --no-gitignore   do not modify .gitignore
   --force          overwrite existing agency.json
+  --preset <name>  setup/verify stubs for go, node, python, rust, or none
+                   (default: detected from go.mod, Cargo.toml, package.json,
+                   or pyproject.toml)
+  --yes            skip the questions and write the default template
+  --from-url <url> clone <url> and initialize the clone
+  --dir <path>     directory to clone into (default: the repo name from the URL)
+  --doctor         run agency doctor in the repo afterwards
   -h, --help       show this help
 `
 
-const doctorUsageText = `usage: agency doctor
+const doctorUsageText = `usage: agency doctor [options]
 
 check prerequisites and show resolved paths.
 verifies git, tmux, gh, runner command, and scripts are present and configured.
 
 options:
-  -h, --help    show this help
+  --repo <path>   check the repo at <path> instead of cwd (same as agency -C <path> doctor)
+  -h, --help      show this help
 `
 
 const runUsageText = `usage: agency run [options]
 
 create workspace, run setup, and start tmux runner session.
-requires cwd to be inside a git repo with agency.json.
+requires cwd (or -C <path>) to be inside a git repo with agency.json.
 
 options:
-  --title <string>    run title (default: untitled-<shortid>)
+  --title <string>    run title (default: untitled-<shortid>); may use {date}, {ticket}
+                      (the ticket label), {parent} and {runner}
   --runner <name>     runner name: claude or codex (default: agency.json defaults.runner)
+  --runners <a,b>     launch the same run once per runner, in parallel, linked by a shared
+                      task_id (meta.task_id); see 'agency ls --task' and 'agency compare
+                      --task'. cannot be used with --runner, --run-id, --attach, --dry-run
+                      or --json
   --parent <branch>   parent branch (default: agency.json defaults.parent_branch)
   --attach            attach to tmux session immediately after creation
+  --run-id <id>       use this run_id instead of generating one (default: $AGENCY_RUN_ID)
+                      lowercase letters, digits, '-' and '_'; max 64 chars; must be unused
+  --label <k=v>       attach a key=value label (repeatable); stored under meta.labels
+  --group <name>      add the run to a named group (see 'agency group'); stored under meta.group
+  --deadline <dur>    time-box the run (e.g. 2h, 90m, 1d); once past, ls/show report it
+                      as needs attention (default: agency.json defaults.deadline;
+                      "none" disables it)
+  --deadline-kill     also kill the tmux session when the deadline passes
+  --sparse <profile>  check out only agency.json worktrees.sparse_profiles.<profile>
+                      ("none" = full checkout; default: worktrees.sparse_checkout)
+  --no-setup          skip the setup script and its pre/post_run_setup hooks
+  --force-setup       run the setup script even if agency.json setup_cache has a hit
+  --no-tmux           skip starting the tmux session and its pre_start_tmux hook; the run
+                      stays idle until 'agency attach --start' (cannot be used with --attach)
+  --json              print the run summary as JSON (schema_version 1.0)
+  --dry-run           check the repo and agency.json, then print the title, slug,
+                      branch and worktree the run would get without creating it
+  --max-sessions <n>  queue the run while n agency tmux sessions are live, across all
+                      repos (default: config.json scheduling.max_sessions; 0 = no limit)
+  --min-free-disk-mb <n>
+                      queue the run while less than n MiB are free on the data dir's
+                      filesystem (default: scheduling.min_free_disk_mb; 0 = no limit)
+  --max-load <x>      queue the run while the 1-minute load average is x or more
+                      (default: scheduling.max_load; 0 = no limit)
+  --no-wait           fail with E_SCHEDULE_BLOCKED instead of queueing the run
   -h, --help          show this help
 
 examples:
   agency run --title "implement feature X" --runner claude
   agency run --attach
   agency run --parent develop
+  agency run --run-id ci-4821-a3f2 --title "nightly fix"
+  agency run --label ticket=JIRA-123 --label team=infra
+  agency run --title "split ledger writes" --group payments-refactor
+  agency run --title "JIRA-123 fix login" --dry-run
+  agency run --title "overnight refactor" --deadline 8h --deadline-kill
+  agency run --title "review only" --no-setup --no-tmux
+  agency run --title "api timeout fix" --sparse api
+  agency run --title "fix flaky login test" --runners claude,codex
+  agency run --title "nightly sweep" --max-sessions 6 --max-load 8
+`
+
+const adoptUsageText = `usage: agency adopt [options] <branch>
+
+create run metadata for an existing local branch so it shows up in ls and
+can be attached to, pushed and archived like any other run.
+requires cwd (or -C <path>) to be inside a git repo with agency.json.
+
+if the branch is checked out in a linked worktree, that worktree is reused;
+otherwise a worktree is created under the data dir. no setup script or tmux
+session is run (use 'agency attach --start <run_id>').
+
+arguments:
+  branch              existing local branch (must not be checked out in the main worktree)
+
+options:
+  --title <string>    run title (default: the branch name)
+  --runner <name>     runner name (default: agency.json defaults.runner)
+  --parent <branch>   parent branch (default: agency.json defaults.parent_branch)
+  --run-id <id>       use this run_id instead of generating one
+  --label <k=v>       attach a key=value label (repeatable); stored under meta.labels
+  --no-worktree       don't create a worktree (the run is listed as archived)
+  -h, --help          show this help
+
+examples:
+  agency adopt fix/login-redirect
+  agency adopt --title "login redirect fix" --parent develop fix/login-redirect
 `
 
-const attachUsageText = `usage: agency attach <run_id>
+const attachUsageText = `usage: agency attach [--start] [--window] [--refresh-env] [--repo <repo>] <run_id>
+       agency attach [--start] [--window] [--refresh-env] [--repo <repo>] (--branch <name> | --pr <number>)
 
 attach to the tmux session for an existing run.
-requires cwd to be inside the target repo.
+requires cwd to be inside the target repo unless --repo is given.
+
+if the run is idle (worktree present, no tmux session), attach offers to
+start the runner (meta.json runner_cmd) in a new session. on a terminal it
+asks first; otherwise it fails unless --start is given.
+
+before attaching, the session's tmux status-right is set to show the run
+(attach.status_format in config.json, default "{run_id} {title} [{status}]
+{pr}"); 'agency config set attach.status false' turns this off.
+
+with --window (or attach.window true in config.json), a shell is opened in
+the worktree next to the runner, with the run's AGENCY_* environment: a
+"tools" window, or a split pane with attach.layout split-right or
+split-below. an existing one from an earlier attach is reused.
+
+with --refresh-env (or attach.refresh_env true in config.json), a live
+session's environment is brought up to date: the run's current AGENCY_*
+variables and, for runs with credentials, a fresh GITHUB_TOKEN/GH_TOKEN are
+set with tmux set-environment. new panes and windows get them; the runner
+keeps its old environment until restarted (tmux respawn-pane -k).
 
 arguments:
   run_id        the run identifier (e.g., 20260110120000-a3f2)
 
 options:
-  -h, --help    show this help
+  --start         start a new session for an idle run without asking
+  --window        open a tools shell in the worktree next to the runner
+  --refresh-env   update the session's AGENCY_* variables and GitHub token
+  --repo <repo>   the run's repo (repo_id, repo_key, or path) instead of cwd
+  --branch <name> select the run by its branch instead of run_id
+  --pr <number>   select the run by its pull request number instead of run_id
+  -h, --help      show this help
 
 examples:
   agency attach 20260110120000-a3f2
+  agency attach --pr 123
+  agency attach --start 20260110120000-a3f2
+  agency attach --window 20260110120000-a3f2
+  agency attach --repo github:owner/repo 20260110120000-a3f2
 `
 
 const lsUsageText = `usage: agency ls [options]
 
 list runs and their statuses.
-by default, lists runs for the current repo (excludes archived).
+by default, lists runs for the current repo (excludes archived, includes broken).
 if not inside a git repo, lists runs across all repos.
+visibility defaults can be changed in ${AGENCY_CONFIG_DIR}/config.json
+("ls": {"archived": bool, "broken": bool}); flags override them.
 
 options:
-  --all           include archived runs
+  --archived      include archived runs (--archived=false to hide)
+  --broken        include broken runs (--broken=false to hide)
+  --all           alias for --archived
   --all-repos     list runs across all repos (ignores current repo scope)
+  --repo <repo>   only runs of the repos matching <repo>, from anywhere: a path
+                  (repo containing it, or last seen there), else an exact
+                  repo_id/repo_key or a substring of the origin URL
+  --json          output as JSON (stable format)
+  --format <tmpl> go template executed per run (fields match --json, Go names)
+  --label <sel>   only runs whose labels match key=value (or have key); repeatable, all must match
+  --group <name>  only runs in this group (see 'agency group')
+  --task <id>     only the runs of one 'agency run --runners' task (meta.task_id)
+  --mine          only runs you created (meta created_by; see AGENCY_USER)
+  --plain         one "key: value" block per run instead of a table
+  --commits       show commits ahead/behind the parent branch (+ahead -behind);
+                  fills ahead/behind in --json
+  --offset <n>    skip the first n runs (after sorting)
+  --limit <n>     output at most n runs (0 = no limit)
+  --stream        newline-delimited JSON: one run object per line, no envelope
+  -h, --help      show this help
+
+examples:
+  agency ls                    # list current repo runs
+  agency ls --archived         # include archived runs
+  agency ls --broken=false     # hide broken runs
+  agency ls --all-repos        # list all repos
+  agency ls --json             # machine-readable output
+  agency ls --format '{{.RunID}} {{.DerivedStatus}}'
+  agency ls --label ticket=JIRA-123
+  agency ls --all-repos --group payments-refactor
+  agency ls --task 20260110120000-c7d1
+  agency ls --all-repos --mine # your runs in a shared data dir
+  agency ls --repo github:acme/api
+  agency ls --repo acme/       # every repo whose origin URL contains acme/
+  agency ls --commits          # spot runs that never committed (+0)
+  agency ls --json --limit 50 --offset 100
+  agency ls --stream --all-repos | jq -r .run_id
+`
+
+const showUsageText = `usage: agency show <run_id> [options]
+       agency show (--branch <name> | --pr <number>) [options]
+
+show details for a single run.
+resolves run_id globally (works from anywhere, not just inside a repo).
+accepts exact run_id or unique prefix.
+if a prefix matches runs in several repos, runs in the current repo win;
+use --repo to pick a repo explicitly.
+--branch and --pr select the run by meta.json branch or pr_number instead,
+with the same preference for the current repo.
+
+arguments:
+  run_id        the run identifier or unique prefix
+
+options:
   --json          output as JSON (stable format)
+  --path          output only resolved filesystem paths
+  --format <tmpl> go template executed against the --json data (Go names)
+  --setup-env     output only the environment captured at setup time
+                  (AGENCY_* env, PATH, SHELL, tool versions); honors --json
+  --repo <repo>   resolve run_id only within this repo (repo_id, repo_key, or path)
+  --branch <name> select the run by its branch (e.g. agency/fix-auth-a3f2)
+  --pr <number>   select the run by its pull request number
+  --plain         omit "=== section ===" banners from human output
+  --explain       list the predicates behind derived_status
+                  (--json always includes them as derived.reasons)
+  -h, --help      show this help
+
+examples:
+  agency show 20260110120000-a3f2           # show run details
+  agency show 20260110                       # unique prefix resolution
+  agency show 20260110120000-a3f2 --json    # machine-readable output
+  agency show 20260110120000-a3f2 --path    # print paths only
+  agency show 20260110 --format '{{.Derived.DerivedStatus}}'
+  agency show 20260110120000-a3f2 --setup-env
+  agency show --branch agency/fix-auth-a3f2
+  agency show --pr 123
+`
+
+const logsUsageText = `usage: agency logs [--repo <repo>] <run_id> [<log>]
+
+list a run's script logs (setup, verify, archive, hooks, rotated copies),
+or print one. logs compressed by 'agency gc' (logs.compress_after_days)
+are decompressed transparently.
+resolves run_id globally (works from anywhere, not just inside a repo).
+
+arguments:
+  run_id        the run identifier or unique prefix
+  log           log to print: setup, verify, archive, hook_<hook>, or a
+                full name such as setup.log.1; omit to list the logs
+
+options:
+  --repo <repo>   resolve run_id only within this repo (repo_id, repo_key, or path)
+  -h, --help      show this help
+
+examples:
+  agency logs 20260110120000-a3f2          # list logs and their sizes
+  agency logs 20260110120000-a3f2 setup    # print setup.log
+  agency logs 20260110 verify | less
+`
+
+const noteUsageText = `usage: agency note [--repo <repo>] <run_id> <text>
+
+append a timestamped note to a run (stored in the run dir as notes.jsonl).
+notes are shown by 'agency show' and included in 'agency show --json'.
+resolves run_id globally (works from anywhere, not just inside a repo).
+if a prefix matches runs in several repos, runs in the current repo win;
+use --repo to pick a repo explicitly.
+
+arguments:
+  run_id        the run identifier or unique prefix
+  text          note text (multiple arguments are joined with spaces)
+
+options:
+  --repo <repo>   resolve run_id only within this repo (repo_id, repo_key, or path)
+  -h, --help      show this help
+
+examples:
+  agency note 20260110120000-a3f2 "review: missing error handling in parser"
+  agency note 20260110 follow-up: add integration test
+`
+
+const mvUsageText = `usage: agency mv [options] <run_id> <new title>
+
+change a run's title in meta.json. previous names are kept under
+meta.aliases. the tmux session name (agency_<run_id>) never changes.
+resolves run_id globally (works from anywhere, not just inside a repo).
+if a prefix matches runs in several repos, runs in the current repo win;
+use --repo to pick a repo explicitly.
+
+arguments:
+  run_id        the run identifier or unique prefix
+  new title     the new title (multiple arguments are joined with spaces)
+
+options:
+  --branch        also rename the branch to agency/<slug>-<shortid>
+  --force         rename the branch even if the run already has a PR
+  --repo <repo>   resolve run_id only within this repo (repo_id, repo_key, or path)
+  -h, --help      show this help
+
+examples:
+  agency mv 20260110120000-a3f2 "fix parser crash on empty input"
+  agency mv --branch 20260110 fix parser crash on empty input
+`
+
+const groupUsageText = `usage: agency group add [--repo <repo>] <run_id> <group>
+       agency group [ls] [--json]
+
+group runs into named projects or milestones. a run belongs to at most one
+group (meta.group); adding it to another group moves it. groups are indexed
+in ${AGENCY_DATA_DIR}/groups.json. runs can also be grouped at creation
+with 'agency run --group <name>', and listed with 'agency ls --group <name>'.
+
+group names: letters, digits, '.', '_' and '-'; must start and end with a
+letter or digit; max 63 chars.
+
+subcommands:
+  add           add a run to a group (creating the group if needed)
+  ls            list groups with their runs and aggregate status (default)
+
+options:
+  --repo <repo>   (add) resolve run_id only within this repo (repo_id, repo_key, or path)
+  --json          (ls) output as JSON (stable format)
+  -h, --help      show this help
+
+examples:
+  agency group add 20260110120000-a3f2 payments-refactor
+  agency group
+  agency group ls --json
+`
+
+const cleanupUsageText = `usage: agency cleanup [--force] <run_id>... | agency cleanup -
+       agency cleanup --merged [--repo <repo>]
+
+clean up runs whose PR was merged: record archive.merged_at, delete the
+run's branch on origin, run scripts.archive, kill the tmux session, remove
+the worktree, and record archive.archived_at. appends a cleanup event.
+the PR is checked with gh first; a run without a merged PR is refused
+(E_PR_NOT_MERGED) unless --force is given. run metadata and logs are kept.
+
+arguments:
+  run_id        the run identifier or unique prefix (repeatable)
+  -             read run ids from stdin (whitespace/newline separated)
+
+with multiple runs, each run is attempted; failures are reported per run
+and the command exits non-zero (E_BULK_FAILED) if any run failed.
+
+options:
+  --merged        clean up every unarchived run whose PR is merged
+  --force         clean up without checking the PR (e.g. merged outside GitHub)
+  --repo <repo>   resolve run_id (or limit --merged) to this repo (repo_id, repo_key, or path)
+  -h, --help      show this help
+
+agency.json "github": {"auto_cleanup": true} makes agency gc --auto do the
+same for the repo's merged runs.
+
+examples:
+  agency cleanup 20260110120000-a3f2
+  agency cleanup --merged
+`
+
+const relocateUsageText = `usage: agency relocate --to <new-dir> [--dry-run]
+
+move the data dir (see 'agency doctor') to <new-dir> and rewrite the
+absolute paths stored in meta.json that point into it: worktree_path, the
+setup log and output paths, and the token path. every repo is locked while
+the data dir moves; runs whose runner session is still running block the
+move (stop them with 'agency kill'). worktrees under the data dir are
+relinked with 'git worktree repair' and checked with 'git worktree list'.
+
+the move is a rename, or a copy followed by removing the old dir when
+<new-dir> is on another filesystem. <new-dir> must not exist or be empty.
+afterwards, point agency at <new-dir> with AGENCY_DATA_DIR (or the repo's
+agency.json data_dir), as the output says.
+
+options:
+  --to <dir>      the new data dir location
+  --dry-run       print the move and every path that would be rewritten
+  -h, --help      show this help
+
+examples:
+  agency relocate --to /mnt/fast/agency --dry-run
+  agency relocate --to /mnt/fast/agency
+`
+
+const killUsageText = `usage: agency kill <run_id>... | agency kill -
+
+kill the tmux session for one or more runs. the workspace persists.
+resolves run_id globally (works from anywhere, not just inside a repo).
+if a prefix matches runs in several repos, runs in the current repo win;
+use --repo to pick a repo explicitly.
+
+arguments:
+  run_id        the run identifier or unique prefix (repeatable)
+  -             read run ids from stdin (whitespace/newline separated)
+
+with multiple runs, each run is attempted; failures are reported per run
+and the command exits non-zero (E_BULK_FAILED) if any run failed.
+
+options:
+  --repo <repo>   resolve run_id only within this repo (repo_id, repo_key, or path)
+  -h, --help      show this help
+
+examples:
+  agency kill 20260110120000-a3f2
+  agency ls --json | jq -r '.data[] | select(.tmux_active) | .run_id' | agency kill -
+`
+
+const verifyUsageText = `usage: agency verify <run_id>... | agency verify - | agency verify --all [options]
+
+run scripts.verify from agency.json in the worktree of one or more runs and
+record the outcome in each run's verify history (verify.jsonl). output goes to
+the run's logs/verify.log; a .agency/out/verify.json written by the script
+decides pass/fail and the summary, otherwise the exit code does. the script
+times out after 30m.
+
+with --all, every unarchived run of the current repo is verified (all repos
+with --all-repos or when not inside a git repo). with several runs, up to
+--jobs run at a time and a summary table (result, duration, log) is printed
+when all are done; failures are reported per run and the command exits
+non-zero (E_BULK_FAILED) if any run failed or could not be verified.
+
+arguments:
+  run_id        the run identifier or unique prefix (repeatable)
+  -             read run ids from stdin (whitespace/newline separated)
+
+options:
+  --all           verify every unarchived run instead of the given runs
+  --all-repos     with --all, include runs from every repo
+  --jobs <n>      runs verified at a time (default 4)
+  --repo <repo>   resolve run_id only within this repo (repo_id, repo_key, or path)
+  -h, --help      show this help
+
+examples:
+  agency verify 20260110120000-a3f2
+  agency verify --all --all-repos --jobs 8
+`
+
+const unlockUsageText = `usage: agency unlock [--yes] <repo|run_id>
+
+remove a repo lock left behind by an agency command that crashed or was
+killed. locks are per repo, so a run_id unlocks the run's repo.
+ls and show report locks (holder pid, command, age) in their status.
+
+arguments:
+  repo          repo_id, repo_key (e.g. github:owner/repo), or path inside the repo
+  run_id        the run identifier or unique prefix
+
+options:
+  --yes         remove the lock without asking (required when stdin is not a terminal)
+  -h, --help    show this help
+
+examples:
+  agency unlock .
+  agency unlock --yes 20260110120000-a3f2
+`
+
+const checkpointUsageText = `usage: agency checkpoint [--repo <repo>] [--message <text>] <run_id>
+
+snapshot a run's worktree so it can be rolled back with 'agency restore':
+the commit it is on, staged and unstaged changes to tracked files (kept by
+git as refs/agency/checkpoints/<run_id>/<n>), and untracked, non-ignored
+files (a tarball under the run dir). the worktree is not modified; ignored
+files and .agency/ are not saved. checkpoints are numbered 1, 2, ... per
+run and listed by 'agency show'.
+
+arguments:
+  run_id        the run identifier or unique prefix
+
+options:
+  --message <text>  describe the checkpoint (shown by 'agency show')
+  --repo <repo>     resolve run_id only within this repo (repo_id, repo_key, or path)
+  -h, --help        show this help
+
+examples:
+  agency checkpoint --message "before dependency upgrade" 20260110120000-a3f2
+`
+
+const restoreUsageText = `usage: agency restore [--repo <repo>] --checkpoint <n> <run_id>
+
+roll a run's worktree back to checkpoint <n>: the branch is reset to the
+checkpoint's commit, untracked non-ignored files are removed, and the saved
+changes and untracked files are put back. ignored files and .agency/ are
+kept. the current state is checkpointed first, so a restore can be undone
+by restoring that checkpoint.
+
+arguments:
+  run_id        the run identifier or unique prefix
+
+options:
+  --checkpoint <n>  the checkpoint to roll back to (see 'agency show')
+  --repo <repo>     resolve run_id only within this repo (repo_id, repo_key, or path)
+  -h, --help        show this help
+
+examples:
+  agency restore --checkpoint 2 20260110120000-a3f2
+`
+
+const watchFilesUsageText = `usage: agency watch-files [options] <run_id>
+
+print a live feed of file changes in a run's worktree (time, change type,
+path) until interrupted, to see what the runner is touching without
+attaching. the worktree is polled: a file changed and changed back between
+two polls is not reported. .git is never watched.
+
+arguments:
+  run_id        the run identifier or unique prefix
+
+options:
+  --filter <glob>     only report matching paths (repeatable)
+  --exclude <glob>    skip matching paths and dirs (repeatable)
+  --interval <dur>    poll interval (default: 1s)
+  --count <n>         exit after n changes
+  --events            also append a file_changed event per change to the
+                      run's events.jsonl
+  --json              print one JSON object per change
+  --repo <repo>       resolve run_id only within this repo (repo_id, repo_key, or path)
+  -h, --help          show this help
+
+a glob without a slash matches any path element ("*.go", "node_modules");
+one with a slash matches from the worktree root ("src/*.ts", "docs/api").
+
+examples:
+  agency watch-files 20260110120000-a3f2
+  agency watch-files --filter '*.go' --exclude vendor --events 20260110
+`
+
+const watchUsageText = `usage: agency watch [options]
+
+run the health probes defined in agency.json "probes" for every active run
+(worktree present, tmux session alive) until interrupted. each probe runs in
+the run's worktree every interval_seconds; results go to stdout, the run's
+events.jsonl (probe events), and meta.json probes. after "failures"
+consecutive failures the run is flagged needs attention; the flag is cleared
+when the probe passes again.
+
+options:
+  --repo <repo>   only watch runs of this repo (repo_id, repo_key, or path)
+  --once          run the probes that are due once and exit (for cron)
+  --tick <dur>    how often to look for due probes (default: 5s)
+  -h, --help      show this help
+
+examples:
+  agency watch
+  agency watch --once --repo github:owner/repo
+`
+
+const reposUsageText = `usage: agency repos refresh [--all]
+
+detect repo capabilities again and rewrite repo.json: whether origin is on
+GitHub and whether gh is authenticated. run and adopt re-detect them once
+they are older than repos.capabilities_ttl_hours (user config, default 24);
+refresh does it now, e.g. right after gh auth login.
+
+options:
+  --all         refresh every repo in repo_index.json instead of the repo
+                containing the current directory
+  -h, --help    show this help
+
+examples:
+  agency repos refresh
+  agency repos refresh --all
+`
+
+const tmuxUsageText = `usage: agency tmux prune [--dry-run] [--yes]
+
+kill agency_* tmux sessions that no live run owns: sessions of deleted runs,
+of archived runs, and of runs whose worktree is gone. sessions of live or
+broken runs are kept, as are sessions started outside this data dir (another
+AGENCY_DATA_DIR on the same tmux server).
+
+safe to run while other agency commands are active: each repo's sessions are
+re-checked and killed under its repo lock, and repos locked by another
+command are skipped with a warning.
+
+options:
+  --dry-run     list the orphaned sessions without killing them
+  --yes         kill them without asking (required when stdin is not a terminal)
+  -h, --help    show this help
+
+examples:
+  agency tmux prune --dry-run
+  agency tmux prune --yes
+`
+
+const gcUsageText = `usage: agency gc [--auto]
+
+apply each repo's retention policy across all repos.
+merged/abandoned runs older than agency.json retention.auto_archive_after_days
+qualify for archive (archive script, tmux session killed, worktree deleted;
+meta, logs, and events are retained).
+logs of runs created more than logs.compress_after_days ago qualify for
+gzip compression ('agency logs' reads them transparently).
+checkpoints of archived runs, and all but the newest 10 of other runs,
+qualify for pruning.
+
+without --auto, lists qualifying runs only (dry run).
+
+options:
+  --auto        archive qualifying runs (prints a warning before each),
+                compress qualifying logs, and prune qualifying checkpoints
+  -h, --help    show this help
+
+examples:
+  agency gc            # list runs that would be archived
+  agency gc --auto     # archive them
+`
+
+const lintUsageText = `usage: agency lint [--fix] <run_id> | agency lint [--fix] --all
+
+validate meta.json contents beyond json parsing: schema_version, RFC3339
+timestamps, branch and worktree_path shape, and PR field consistency.
+prints one line per problem: <run_id> <error|warn> <field>: <message>.
+exits non-zero (E_META_INVALID) if any error remains.
+
+arguments:
+  run_id        the run identifier or unique prefix
+
+options:
+  --all         lint every run in the data dir (including broken ones)
+  --fix         repair trivially fixable problems (non-UTC timestamps,
+                unclean worktree_path, pr_number missing but pr_url set,
+                missing schema_version)
+  --repo <repo> only this repo (repo_id, repo_key, or path); with a run_id,
+                resolves it within the repo
+  -h, --help    show this help
+
+examples:
+  agency lint 20260110120000-a3f2
+  agency lint --all --fix
+`
+
+const diffEnvUsageText = `usage: agency diff-env [options] <run_a> <run_b>
+
+compare the environments two runs' setup scripts ran with (captured at setup
+time; see 'agency show --setup-env'). prints each differing key (os, arch,
+hostname, env.<NAME>, tools.<name>) with both values.
+keys that differ between any two runs (AGENCY_RUN_ID, AGENCY_BRANCH, worktree
+paths, ...) are hidden unless --all is given.
+
+arguments:
+  run_a, run_b  the run identifiers or unique prefixes
+
+options:
+  --all           include run-specific keys
+  --repo <repo>   resolve run_ids only within this repo (repo_id, repo_key, or path)
+  -h, --help      show this help
+
+examples:
+  agency diff-env 20260110120000-a3f2 20260111090000-b4c1
+`
+
+const compareUsageText = `usage: agency compare [options] <run_a> <run_b>
+       agency compare [options] --task <task_id>
+
+show two runs side by side, e.g. two runners that attempted the same task:
+title, runner, branch, setup result and duration, verify results (latest
+attempt and pass count), diff size vs the parent branch (lines added and
+removed, files, commits), and report.md size. archived runs have no diff or
+report size.
+
+arguments:
+  run_a, run_b  the run identifiers or unique prefixes
+
+options:
+  --task <id>     compare the two runs of an 'agency run --runners' task
+                  (meta.task_id; run_a is the older run_id)
+  --json          output as JSON (data[0] is run_a)
+  --plain         "<field>_a: value" / "<field>_b: value" lines instead of a table
+  --repo <repo>   resolve run_ids only within this repo (repo_id, repo_key, or path)
+  -h, --help      show this help
+
+examples:
+  agency compare 20260110120000-a3f2 20260110120000-b4c1
+  agency compare --json a3f2 b4c1
+  agency compare --task 20260110120000-c7d1
+`
+
+const bundleUsageText = `usage: agency bundle [options] <run_id>
+
+collect everything needed to debug a run into one gzipped tarball:
+meta.json, script logs, events.jsonl, transcript.txt, report.md, the setup
+env capture and .agency/out/*.json, an 'agency doctor' report (nothing is
+persisted), and the audit entries touching the run plus the newest 20.
+tokens, passwords, private keys, URL credentials, and the values of
+secret-looking env vars are replaced with [REDACTED]. redaction is best
+effort: review the bundle before sharing it.
+works for broken runs (whatever is readable is collected). large files keep
+their last 4 MiB. manifest.json lists each file, what was skipped, and the
+redaction count.
+
+arguments:
+  run_id        the run identifier (exact or unique prefix)
+
+options:
+  -o, --output <file>
+                  tarball path (default: agency-bundle-<run_id>.tar.gz in the
+                  current directory; - writes to stdout)
+  --repo <repo>   resolve run_id only within this repo (repo_id, repo_key, or path)
   -h, --help      show this help
 
-examples:
-  agency ls                    # list current repo runs
-  agency ls --all              # include archived runs
-  agency ls --all-repos        # list all repos
-  agency ls --json             # machine-readable output
-`
+examples:
+  agency bundle 20260110120000-a3f2
+  agency bundle 20260110 -o /tmp/failed-setup.tar.gz
+`
+
+const reportUsageText = `usage: agency report [options]
+
+write a digest of runs grouped by repo and status, with PR links, setup and
+verify outcomes, and the first paragraph of each run's report.md.
+by default, covers the current repo (all repos if not inside a git repo).
+archived runs are included; broken runs are skipped.
+
+options:
+  --all             include runs from every repo
+  --since <age>     only runs with activity (created, pushed, verified, merged,
+                    archived) within this window, e.g. 7d, 2w, 36h
+  --output <file>   write to file instead of stdout (.html/.htm selects HTML)
+  --html            render HTML instead of Markdown
+  -h, --help        show this help
+
+examples:
+  agency report --all --since 7d --output summary.md
+  agency report --since 2w --html > report.html
+`
+
+const digestUsageText = `usage: agency digest [options]
+
+summarize run activity for posting to a team channel: runs created, merged,
+and failed within the window, and runs awaiting review (ready for review,
+whatever their age). by default, covers the current repo (all repos if not
+inside a git repo). broken runs are skipped.
+
+options:
+  --all               include runs from every repo
+  --since <age>       the window, e.g. 24h, 7d, 2w (default 24h)
+  --format <format>   markdown (default) or slack
+  --post              post to digest.webhook_url in config.json (as {"text":
+                      ...}, e.g. a Slack incoming webhook) instead of printing
+  -h, --help          show this help
+
+examples:
+  agency digest --all
+  agency digest --since 7d --format slack --post
+`
+
+const auditUsageText = `usage: agency audit [options]
+
+print the audit log: every command that changed agency state (run, adopt,
+note, mv, kill, gc --auto, ...) with its arguments, user, working directory,
+the runs it touched, outcome, and duration, oldest first. the log is
+audit.jsonl in the data dir, rotated at 10 MiB (3 old files kept).
+
+options:
+  --run <id>        only commands that touched this run (run_id or prefix);
+                    works for runs that no longer exist
+  --since <age>     only commands within this window, e.g. 7d, 2w, 36h
+  --json            print the matching audit.jsonl lines
+  -h, --help        show this help
+
+examples:
+  agency audit --run 20260110120000-a3f2
+  agency audit --since 2d --json
+`
+
+const profileUsageText = `usage: agency profile report [<dir>] [options]
+
+summarize a profile recorded with the hidden global --profile flag (or
+AGENCY_PROFILE=1): wall time, time spent in git, tmux, gh, scripts, and data
+dir scans, and the slowest operations. profiles are kept in
+${AGENCY_CACHE_DIR}/profiles, one directory per invocation, with cpu.pprof
+and heap.pprof for go tool pprof.
+
+arguments:
+  <dir>         profile directory (default: the latest one)
+
+options:
+  --json        output as JSON
+  -h, --help    show this help
+
+examples:
+  agency --profile ls --all
+  agency profile report
+`
+
+const schemaUsageText = `usage: agency schema [<name>] [options]
+
+print the JSON Schema (draft 2020-12) of one of agency's files or --json
+outputs, or list them when no name is given. the schemas are embedded in
+the binary; setup.json is validated against its schema after every setup.
+
+schemas:
+  agency        agency.json (per-repo config)
+  meta          meta.json (run metadata)
+  setup         setup.json (setup script output)
+  verify        verify.json (verify script output)
+  status        status.json (per-run progress file for dashboards)
+  ls            ls --json envelope
+  show          show --json envelope
+
+options:
+  --validate <file>
+                check <file> against the schema instead of printing it;
+                prints <file>:<line>:<column>: <pointer>: <problem> for each
+                violation and fails with E_SCHEMA_VIOLATION
+  -h, --help    show this help
+
+examples:
+  agency schema setup > setup.schema.json
+  agency schema agency --validate agency.json
+  agency ls --json > runs.json && agency schema ls --validate runs.json
+`
+
+const configUsageText = `usage: agency config get <key>
+       agency config set <key> <value>
+       agency config [list] [--json]
+       agency config edit
+
+read and change user settings in ${AGENCY_CONFIG_DIR}/config.json without
+hand-editing JSON, like git config.
+
+subcommands:
+  get           print a setting's effective value
+  set           set a setting in config.json (booleans: true/false, yes/no,
+                on/off, 1/0; attach.status_format: any text)
+  list          print every setting with its value and origin (default)
+  edit          open config.json in $VISUAL/$EDITOR (default vi); it is only
+                saved if it is valid
+
+keys:
+  ls.archived   ls includes archived runs by default (default false)
+  ls.broken     ls includes broken runs by default (default true)
+  plain         line-oriented output for ls/show (default false)
+  attach.status show the run in its tmux session's status line on attach
+                (default true)
+  attach.status_format
+                that status line, with {run_id} {title} {status} {pr}
+                {branch} {runner} (default "{run_id} {title} [{status}] {pr}")
+  encryption.enabled
+                encrypt run files at rest with age (default false); meta.json
+                is encrypted as it is written, but script logs and the
+                transcript are written in plaintext and encrypted only after
+                the command ends (agency gc --auto encrypts leftovers)
+  encryption.identity
+                the age identity file to encrypt and decrypt with
+  data_dir      where agency keeps run state (read-only here)
+  config_dir    where config.json lives (read-only here)
+
+origins (list):
+  default       built-in default
+  user          set in config.json
+  repo          set in the repo's agency.json
+  env           set by an environment variable (AGENCY_PLAIN, TERM=dumb,
+                AGENCY_DATA_DIR, AGENCY_CONFIG_DIR)
+
+options:
+  --json        (list) output as JSON (stable format)
+  -h, --help    show this help
+
+examples:
+  agency config set ls.archived true
+  agency config get plain
+  agency config list
+`
+
+const branchGuardUsageText = `usage: agency branch-guard [options]
+
+install a post-checkout hook in the current repo that warns when an agency/*
+branch is checked out in the main working copy instead of its run's worktree.
+checkouts inside run worktrees are never affected. an existing post-checkout
+hook not installed by agency is left alone (E_GIT_HOOK_EXISTS).
+
+options:
+  --block       switch back to the previous HEAD instead of only warning
+  --uninstall   remove the hook
+  --status      report whether the hook is installed, and its mode
+  -h, --help    show this help
+
+set AGENCY_BRANCH_GUARD=off|warn|block to override the mode for one checkout.
+`
+
+// stdin is the reader used for "-" run id arguments and confirmation
+// prompts (replaceable in tests).
+var stdin io.Reader = os.Stdin
+
+// stdinIsTerminal reports whether prompts can be shown (replaceable in tests).
+var stdinIsTerminal = func() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm writes prompt to w and reads a y/N answer from r.
+func confirm(r io.Reader, w io.Writer, prompt string) bool {
+	fmt.Fprintf(w, "%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// forceReadOnly is set by Run from the global --force-read-only flag.
+var forceReadOnly bool
+
+// allowRoot is set by Run from the global --allow-root flag.
+var allowRoot bool
+
+// readOnly is set by Run from the global --read-only flag and AGENCY_READ_ONLY.
+var readOnly bool
+
+// commandName is the command Run dispatches to, for read-only mode errors.
+var commandName string
+
+// readOnlyFromEnv reports whether AGENCY_READ_ONLY is set to a true value.
+func readOnlyFromEnv(getenv func(string) string) bool {
+	switch strings.ToLower(getenv("AGENCY_READ_ONLY")) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// profileFromEnv reports whether AGENCY_PROFILE is set to a true value.
+func profileFromEnv(getenv func(string) string) bool {
+	switch strings.ToLower(getenv("AGENCY_PROFILE")) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// debugFromEnv reports whether AGENCY_DEBUG is set to a true value.
+func debugFromEnv(getenv func(string) string) bool {
+	switch strings.ToLower(getenv("AGENCY_DEBUG")) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// mutating is set once the command has declared write access, so Run
+// records it in the audit log.
+var mutating bool
+
+// checkReadOnly refuses write access in read-only mode with E_READ_ONLY.
+// Every command calls it, usually through guardDataDir, right after parsing
+// its flags: before it has looked at anything, so a refused command has no
+// effect.
+func checkReadOnly(access commands.DataDirAccess) error {
+	if access != commands.DataDirWrite {
+		return nil
+	}
+	if !readOnly {
+		mutating = true
+		return nil
+	}
+	return errors.WithHints(errors.NewWithDetails(errors.EReadOnly,
+		"agency "+commandName+" modifies state and is disabled in read-only mode",
+		map[string]string{"command": commandName}),
+		"read-only mode is set by --read-only or AGENCY_READ_ONLY; drop it to run this command")
+}
+
+// guardDataDir refuses writes in read-only mode, running as root on another
+// user's files, and writes from inside the data dir (see guardInvocation),
+// and checks the cwd's data dir for format skew (see commands.GuardDataDir).
+func guardDataDir(cwd string, access commands.DataDirAccess, stderr io.Writer) error {
+	if err := guardInvocation(cwd, access); err != nil {
+		return err
+	}
+	return commands.GuardDataDir(fs.NewRealFS(), cwd, access, forceReadOnly, stderr)
+}
+
+// guardInvocation is guardDataDir without the format check, for init and
+// doctor, which must work on a skewed data dir (doctor reports the skew).
+func guardInvocation(cwd string, access commands.DataDirAccess) error {
+	if err := checkReadOnly(access); err != nil {
+		return err
+	}
+	return commands.GuardInvocation(fs.NewRealFS(), cwd, access, allowRoot)
+}
+
+// dataDirAccess returns DataDirWrite for commands that write only when a flag is set.
+func dataDirAccess(writes bool) commands.DataDirAccess {
+	if writes {
+		return commands.DataDirWrite
+	}
+	return commands.DataDirRead
+}
+
+// workDir is set by Run from the global -C/--repo flag ("" = process cwd).
+var workDir string
+
+// setWorkDir validates a -C/--repo path and makes it the working directory
+// commands resolve the repo, config, and data dir from.
+func setWorkDir(path string) error {
+	if path == "" {
+		return errors.New(errors.EUsage, "-C/--repo requires a path")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return errors.Wrap(errors.EUsage, "invalid -C/--repo path", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil || !info.IsDir() {
+		return errors.NewWithDetails(errors.EUsage, "-C/--repo: not a directory: "+path,
+			map[string]string{"path": abs})
+	}
+	workDir = abs
+	return nil
+}
+
+// getwd returns the -C/--repo directory if set, otherwise the process cwd.
+func getwd() (string, error) {
+	if workDir != "" {
+		return workDir, nil
+	}
+	return os.Getwd()
+}
+
+// plainOutput is set by Run from the global --plain flag and the environment.
+var plainOutput bool
+
+// plainFromEnv reports whether the environment asks for plain output:
+// AGENCY_PLAIN set to a true value, or a dumb terminal.
+func plainFromEnv(getenv func(string) string) bool {
+	switch strings.ToLower(getenv("AGENCY_PLAIN")) {
+	case "1", "true", "yes":
+		return true
+	}
+	return getenv("TERM") == "dumb"
+}
+
+// Run parses arguments and dispatches to the appropriate subcommand.
+// Returns an error if the command fails; the caller should print the error and exit.
+func Run(args []string, stdout, stderr io.Writer) error {
+	argv := append([]string{"agency"}, args...)
+	if len(args) == 0 {
+		fmt.Fprint(stdout, usageText)
+		return errors.New(errors.EUsage, "no command specified")
+	}
+
+	// Global flags before the command
+	plainOutput = plainFromEnv(os.Getenv)
+	readOnly = readOnlyFromEnv(os.Getenv)
+	profiling := profileFromEnv(os.Getenv)
+	forceReadOnly = false
+	allowRoot = false
+	workDir = ""
+globalFlags:
+	for len(args) > 0 {
+		switch arg := args[0]; {
+		case arg == "--plain":
+			plainOutput = true
+		case arg == "--force-read-only":
+			forceReadOnly = true
+		case arg == "--read-only":
+			readOnly = true
+		case arg == "--allow-root":
+			allowRoot = true
+		case arg == "--profile":
+			// Hidden: record CPU/heap profiles and command latencies (see
+			// commands.StartProfile)
+			profiling = true
+		case arg == "-C" || arg == "--repo":
+			if len(args) < 2 {
+				return errors.New(errors.EUsage, arg+" requires a path")
+			}
+			if err := setWorkDir(args[1]); err != nil {
+				return err
+			}
+			args = args[1:]
+		case strings.HasPrefix(arg, "--repo="):
+			if err := setWorkDir(strings.TrimPrefix(arg, "--repo=")); err != nil {
+				return err
+			}
+		default:
+			break globalFlags
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprint(stdout, usageText)
+		return errors.New(errors.EUsage, "no command specified")
+	}
+
+	cmd := args[0]
+	cmdArgs := args[1:]
+	commandName = cmd
+
+	// Handle global flags
+	if cmd == "-h" || cmd == "--help" {
+		fmt.Fprint(stdout, usageText)
+		return nil
+	}
+	if cmd == "-v" || cmd == "--version" {
+		fmt.Fprintf(stdout, "agency %s\n", version.Version)
+		return nil
+	}
+
+	// Network command timeouts and retries; retries are logged to stderr
+	// with AGENCY_DEBUG
+	var debugLog io.Writer
+	if debugFromEnv(os.Getenv) {
+		debugLog = stderr
+	}
+	exec.Configure(commands.NetworkPolicy(fs.NewRealFS()), debugLog)
+
+	if profiling && cmd != "profile" {
+		session, err := commands.StartProfile(cmd, argv)
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: failed to start profiling: %v\n", err)
+		} else {
+			defer func() {
+				if err := session.Stop(); err != nil {
+					fmt.Fprintf(stderr, "warning: failed to write profile: %v\n", err)
+				}
+				fmt.Fprintf(stderr, "profile: %s (see agency profile report)\n", session.Dir)
+			}()
+		}
+	}
+
+	start := time.Now()
+	mutating = false
+	audit.Touched()
+	err := dispatch(cmd, cmdArgs, stdout, stderr)
+	if mutating {
+		runs := audit.Touched()
+		recordAudit(cmd, argv, start, runs, err, stderr)
+		refreshStatusFiles(runs)
+		sealRunFiles(runs, stderr)
+	}
+	return err
+}
+
+// sealRunFiles encrypts the files a mutating command left in plaintext in
+// the runs it touched, when encryption at rest is enabled (see
+// commands.SealRunFiles).
+func sealRunFiles(runs []audit.Run, stderr io.Writer) {
+	cwd, err := getwd()
+	if err != nil {
+		return
+	}
+	commands.SealRunFiles(exec.NewRealRunner(), fs.NewRealFS(), cwd, runs, stderr)
+}
+
+// refreshStatusFiles rewrites status.json of the runs a mutating command
+// touched, so dashboards see its effect (see commands.RefreshStatusFiles).
+func refreshStatusFiles(runs []audit.Run) {
+	cwd, err := getwd()
+	if err != nil {
+		return
+	}
+	commands.RefreshStatusFiles(context.Background(), exec.NewRealRunner(), fs.NewRealFS(), cwd, runs)
+}
+
+// recordAudit appends the command's entry to the audit log. Best-effort: a
+// failure is reported on stderr but does not fail the command.
+func recordAudit(cmd string, argv []string, start time.Time, runs []audit.Run, cmdErr error, stderr io.Writer) {
+	cwd, err := getwd()
+	if err != nil {
+		return
+	}
+	e := audit.Entry{
+		SchemaVersion: audit.SchemaVersion,
+		Timestamp:     start.UTC().Format(time.RFC3339),
+		Command:       cmd,
+		Argv:          argv,
+		User:          identity.CurrentUser(),
+		Host:          identity.Hostname(),
+		Cwd:           cwd,
+		Runs:          runs,
+		Outcome:       audit.OutcomeOK,
+		DurationMS:    time.Since(start).Milliseconds(),
+	}
+	if cmdErr != nil {
+		e.Outcome = audit.OutcomeError
+		e.ErrorCode = string(errors.GetCode(cmdErr))
+		e.Error = cmdErr.Error()
+	}
+	if err := commands.RecordAudit(fs.NewRealFS(), cwd, e); err != nil {
+		fmt.Fprintf(stderr, "warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// dispatch runs cmd with its arguments.
+func dispatch(cmd string, cmdArgs []string, stdout, stderr io.Writer) error {
+	switch cmd {
+	case "init":
+		return runInit(cmdArgs, stdout, stderr)
+	case "doctor":
+		return runDoctor(cmdArgs, stdout, stderr)
+	case "run":
+		return runRun(cmdArgs, stdout, stderr)
+	case "ls":
+		return runLS(cmdArgs, stdout, stderr)
+	case "show":
+		return runShow(cmdArgs, stdout, stderr)
+	case "adopt":
+		return runAdopt(cmdArgs, stdout, stderr)
+	case "attach":
+		return runAttach(cmdArgs, stdout, stderr)
+	case "note":
+		return runNote(cmdArgs, stdout, stderr)
+	case "logs":
+		return runLogs(cmdArgs, stdout, stderr)
+	case "group":
+		return runGroup(cmdArgs, stdout, stderr)
+	case "mv":
+		return runMv(cmdArgs, stdout, stderr)
+	case "cleanup":
+		return runCleanup(cmdArgs, stdout, stderr)
+	case "relocate":
+		return runRelocate(cmdArgs, stdout, stderr)
+	case "kill":
+		return runKill(cmdArgs, stdout, stderr)
+	case "verify":
+		return runVerify(cmdArgs, stdout, stderr)
+	case "unlock":
+		return runUnlock(cmdArgs, stdout, stderr)
+	case "tmux":
+		return runTmux(cmdArgs, stdout, stderr)
+	case "repos":
+		return runRepos(cmdArgs, stdout, stderr)
+	case "watch-files":
+		return runWatchFiles(cmdArgs, stdout, stderr)
+	case "watch":
+		return runWatch(cmdArgs, stdout, stderr)
+	case "checkpoint":
+		return runCheckpoint(cmdArgs, stdout, stderr)
+	case "restore":
+		return runRestore(cmdArgs, stdout, stderr)
+	case "gc":
+		return runGC(cmdArgs, stdout, stderr)
+	case "lint":
+		return runLint(cmdArgs, stdout, stderr)
+	case "branch-guard":
+		return runBranchGuard(cmdArgs, stdout, stderr)
+	case "diff-env":
+		return runDiffEnv(cmdArgs, stdout, stderr)
+	case "compare":
+		return runCompare(cmdArgs, stdout, stderr)
+	case "bundle":
+		return runBundle(cmdArgs, stdout, stderr)
+	case "report":
+		return runReport(cmdArgs, stdout, stderr)
+	case "digest":
+		return runDigest(cmdArgs, stdout, stderr)
+	case "audit":
+		return runAudit(cmdArgs, stdout, stderr)
+	case "config":
+		return runConfig(cmdArgs, stdout, stderr)
+	case "schema":
+		return runSchema(cmdArgs, stdout, stderr)
+	case "profile":
+		return runProfile(cmdArgs, stdout, stderr)
+	default:
+		fmt.Fprint(stdout, usageText)
+		return errors.New(errors.EUsage, fmt.Sprintf("unknown command: %s", cmd))
+	}
+}
+
+func runInit(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("init", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	noGitignore := flagSet.Bool("no-gitignore", false, "do not modify .gitignore")
+	force := flagSet.Bool("force", false, "overwrite existing agency.json")
+	preset := flagSet.String("preset", "", "setup/verify stub preset")
+	yes := flagSet.Bool("yes", false, "skip the questions and write the default template")
+	fromURL := flagSet.String("from-url", "", "clone this repo and initialize the clone")
+	dir := flagSet.String("dir", "", "directory to clone into")
+	doctorAfter := flagSet.Bool("doctor", false, "run agency doctor afterwards")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, initUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.ENoRepo, "failed to get working directory", err)
+	}
+
+	// init writes agency.json and scripts into the repo
+	if err := guardInvocation(cwd, commands.DataDirWrite); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.InitOpts{
+		NoGitignore: *noGitignore,
+		Force:       *force,
+		Preset:      *preset,
+		FromURL:     *fromURL,
+		Dir:         *dir,
+		Doctor:      *doctorAfter,
+	}
+	if !*yes && stdinIsTerminal() {
+		opts.Prompter = newLinePrompter(stdin, stderr)
+	}
+
+	return commands.Init(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runDoctor(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	repo := flagSet.String("repo", "", "check the repo at this path instead of cwd")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, doctorUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if *repo != "" {
+		if err := setWorkDir(*repo); err != nil {
+			return err
+		}
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.ENoRepo, "failed to get working directory", err)
+	}
+
+	// doctor probes the data dir and persists repo.json and the repo index
+	if err := guardInvocation(cwd, commands.DataDirWrite); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	return commands.Doctor(ctx, cr, fsys, cwd, stdout, stderr)
+}
+
+func runRun(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("run", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	title := flagSet.String("title", "", "run title")
+	runner := flagSet.String("runner", "", "runner name (claude or codex)")
+	runners := flagSet.String("runners", "", "comma-separated runners, one run each")
+	parent := flagSet.String("parent", "", "parent branch")
+	attach := flagSet.Bool("attach", false, "attach to tmux session immediately")
+	runID := flagSet.String("run-id", "", "externally supplied run_id")
+	var labels stringListFlag
+	flagSet.Var(&labels, "label", "key=value label (repeatable)")
+	group := flagSet.String("group", "", "run group name")
+	deadline := flagSet.String("deadline", "", "time box for the run (e.g. 2h), or none")
+	deadlineKill := flagSet.Bool("deadline-kill", false, "kill the tmux session at the deadline")
+	sparse := flagSet.String("sparse", "", "sparse-checkout profile name, or none")
+	noSetup := flagSet.Bool("no-setup", false, "skip the setup script")
+	forceSetup := flagSet.Bool("force-setup", false, "run setup even on a setup cache hit")
+	noTmux := flagSet.Bool("no-tmux", false, "skip starting the tmux session")
+	jsonOutput := flagSet.Bool("json", false, "output as JSON")
+	dryRun := flagSet.Bool("dry-run", false, "print the resolved names without creating anything")
+	maxSessions := flagSet.Int("max-sessions", 0, "wait while this many agency tmux sessions are live")
+	minFreeDiskMB := flagSet.Int("min-free-disk-mb", 0, "wait while less disk space (MiB) is free")
+	maxLoad := flagSet.Float64("max-load", 0, "wait while the load average is this high")
+	noWait := flagSet.Bool("no-wait", false, "fail instead of waiting for a scheduling limit")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, runUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if *dryRun && *attach {
+		return errors.New(errors.EUsage, "--dry-run cannot be combined with --attach")
+	}
+	if *noTmux && *attach {
+		return errors.New(errors.EUsage, "--no-tmux cannot be combined with --attach")
+	}
+	if *forceSetup && *noSetup {
+		return errors.New(errors.EUsage, "--force-setup cannot be combined with --no-setup")
+	}
+	var deadlineDur time.Duration
+	if *deadline != "" && *deadline != "none" {
+		d, err := core.ParseAge(*deadline)
+		if err != nil {
+			return errors.Wrap(errors.EUsage, "invalid --deadline", err)
+		}
+		deadlineDur = d
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.ENoRepo, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, dataDirAccess(!*dryRun), stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	// --run-id wins over AGENCY_RUN_ID
+	if *runID == "" {
+		*runID = os.Getenv("AGENCY_RUN_ID")
+	}
+
+	opts := commands.RunOpts{
+		Title:  *title,
+		Runner: *runner,
+		Parent: *parent,
+		Attach: *attach,
+		RunID:  *runID,
+		Labels: labels,
+		Group:  *group,
+		DryRun: *dryRun,
+
+		Runners: splitRunners(*runners),
+
+		Deadline:     deadlineDur,
+		NoDeadline:   *deadline == "none",
+		DeadlineKill: *deadlineKill,
+
+		SparseProfile: *sparse,
+		NoSetup:       *noSetup,
+		ForceSetup:    *forceSetup,
+		NoTmux:        *noTmux,
+		JSON:          *jsonOutput,
+
+		NoWait: *noWait,
+	}
+	// Limit flags override config.json scheduling.* only when given
+	flagSet.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-sessions":
+			opts.MaxSessions = maxSessions
+		case "min-free-disk-mb":
+			opts.MinFreeDiskMB = minFreeDiskMB
+		case "max-load":
+			opts.MaxLoad = maxLoad
+		}
+	})
+
+	return commands.Run(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runLS(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("ls", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	all := flagSet.Bool("all", false, "include archived runs (alias for --archived)")
+	archived := flagSet.Bool("archived", false, "include archived runs")
+	broken := flagSet.Bool("broken", false, "include broken runs")
+	allRepos := flagSet.Bool("all-repos", false, "list runs across all repos")
+	repo := flagSet.String("repo", "", "only runs of the matching repos")
+	jsonOutput := flagSet.Bool("json", false, "output as JSON")
+	format := flagSet.String("format", "", "go template executed per run")
+	var labels stringListFlag
+	flagSet.Var(&labels, "label", "label selector (repeatable)")
+	group := flagSet.String("group", "", "only runs in this group")
+	task := flagSet.String("task", "", "only runs of this task")
+	mine := flagSet.Bool("mine", false, "only runs created by the current user")
+	plain := flagSet.Bool("plain", false, "line-oriented key: value output")
+	commits := flagSet.Bool("commits", false, "show commits ahead/behind the parent branch")
+	offset := flagSet.Int("offset", 0, "skip this many runs")
+	limit := flagSet.Int("limit", 0, "output at most this many runs")
+	stream := flagSet.Bool("stream", false, "newline-delimited JSON, one run per line")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, lsUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirRead, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.LSOpts{
+		All:      *all,
+		AllRepos: *allRepos,
+		Repo:     *repo,
+		JSON:     *jsonOutput,
+		Format:   *format,
+		Labels:   labels,
+		Group:    *group,
+		Task:     *task,
+		Mine:     *mine,
+		Plain:    *plain || plainOutput,
+		Commits:  *commits,
+		Offset:   *offset,
+		Limit:    *limit,
+		Stream:   *stream,
+	}
+
+	// Only explicitly set visibility flags override user config defaults
+	flagSet.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "archived":
+			opts.Archived = archived
+		case "broken":
+			opts.Broken = broken
+		}
+	})
+
+	return commands.LS(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runShow(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("show", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	jsonOutput := flagSet.Bool("json", false, "output as JSON")
+	pathOutput := flagSet.Bool("path", false, "output only resolved paths")
+	format := flagSet.String("format", "", "go template executed against run detail")
+	setupEnv := flagSet.Bool("setup-env", false, "output the captured setup environment")
+	explain := flagSet.Bool("explain", false, "list the predicates behind the derived status")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+	branch := flagSet.String("branch", "", "select the run by branch name")
+	pr := flagSet.Int("pr", 0, "select the run by pull request number")
+	plain := flagSet.Bool("plain", false, "line-oriented output without section banners")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, showUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// run_id is a required positional argument
+	// (--branch or --pr can stand in for it)
+	positionalArgs := flagSet.Args()
+	var runID string
+	if len(positionalArgs) >= 1 {
+		runID = positionalArgs[0]
+	} else if *branch == "" && *pr == 0 {
+		fmt.Fprint(stderr, showUsageText)
+		return errors.New(errors.EUsage, "run_id is required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirRead, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.ShowOpts{
+		RunID:    runID,
+		Branch:   *branch,
+		PR:       *pr,
+		JSON:     *jsonOutput,
+		Path:     *pathOutput,
+		Format:   *format,
+		Repo:     *repo,
+		SetupEnv: *setupEnv,
+		Explain:  *explain,
+		Plain:    *plain || plainOutput,
+	}
+
+	return commands.Show(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runAdopt(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("adopt", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	title := flagSet.String("title", "", "run title")
+	runner := flagSet.String("runner", "", "runner name")
+	parent := flagSet.String("parent", "", "parent branch")
+	runID := flagSet.String("run-id", "", "externally supplied run_id")
+	noWorktree := flagSet.Bool("no-worktree", false, "don't create a worktree")
+	var labels stringListFlag
+	flagSet.Var(&labels, "label", "key=value label (repeatable)")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, adoptUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// branch is a required positional argument
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) != 1 {
+		fmt.Fprint(stderr, adoptUsageText)
+		return errors.New(errors.EUsage, "exactly one branch is required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.ENoRepo, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.AdoptOpts{
+		Branch:     positionalArgs[0],
+		Title:      *title,
+		Runner:     *runner,
+		Parent:     *parent,
+		RunID:      *runID,
+		Labels:     labels,
+		NoWorktree: *noWorktree,
+	}
+
+	return commands.Adopt(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runAttach(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("attach", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	start := flagSet.Bool("start", false, "start a new session for an idle run")
+	window := flagSet.Bool("window", false, "open a tools shell next to the runner")
+	refreshEnv := flagSet.Bool("refresh-env", false, "refresh the session environment")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+	branch := flagSet.String("branch", "", "select the run by branch name")
+	pr := flagSet.Int("pr", 0, "select the run by pull request number")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, attachUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// run_id is a required positional argument
+	// (--branch or --pr can stand in for it)
+	positionalArgs := flagSet.Args()
+	var runID string
+	if len(positionalArgs) >= 1 {
+		runID = positionalArgs[0]
+	} else if *branch == "" && *pr == 0 {
+		fmt.Fprint(stderr, attachUsageText)
+		return errors.New(errors.EUsage, "run_id is required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.ENoRepo, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.AttachOpts{
+		RunID:      runID,
+		Branch:     *branch,
+		PR:         *pr,
+		Start:      *start,
+		Window:     *window,
+		RefreshEnv: *refreshEnv,
+		Repo:       *repo,
+	}
+	if stdinIsTerminal() {
+		opts.Confirm = func(prompt string) bool {
+			return confirm(stdin, stderr, prompt)
+		}
+	}
+
+	return commands.Attach(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runNote(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("note", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, noteUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// run_id and text are required positional arguments
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) < 2 {
+		fmt.Fprint(stderr, noteUsageText)
+		return errors.New(errors.EUsage, "run_id and note text are required")
+	}
+	runID := positionalArgs[0]
+	text := strings.Join(positionalArgs[1:], " ")
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.NoteOpts{
+		RunID: runID,
+		Text:  text,
+		Repo:  *repo,
+	}
+
+	return commands.Note(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runLogs(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("logs", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, logsUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// run_id is a required positional argument; the log name is optional
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) < 1 || len(positionalArgs) > 2 {
+		fmt.Fprint(stderr, logsUsageText)
+		return errors.New(errors.EUsage, "run_id is required (and at most one log name)")
+	}
+	opts := commands.LogsOpts{
+		RunID: positionalArgs[0],
+		Repo:  *repo,
+	}
+	if len(positionalArgs) == 2 {
+		opts.Name = positionalArgs[1]
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirRead, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	return commands.Logs(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runMv(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("mv", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	branch := flagSet.Bool("branch", false, "also rename the branch")
+	force := flagSet.Bool("force", false, "rename the branch even if a PR exists")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, mvUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// run_id and title are required positional arguments
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) < 2 {
+		fmt.Fprint(stderr, mvUsageText)
+		return errors.New(errors.EUsage, "run_id and new title are required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.MvOpts{
+		RunID:  positionalArgs[0],
+		Title:  strings.Join(positionalArgs[1:], " "),
+		Branch: *branch,
+		Force:  *force,
+		Repo:   *repo,
+	}
+
+	return commands.Mv(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runGroup(args []string, stdout, stderr io.Writer) error {
+	sub := "ls"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	flagSet := flag.NewFlagSet("group "+sub, flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+	jsonOutput := flagSet.Bool("json", false, "output as JSON")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, groupUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	positionalArgs := flagSet.Args()
+	access := commands.DataDirRead
+	switch sub {
+	case "add":
+		if len(positionalArgs) != 2 {
+			fmt.Fprint(stderr, groupUsageText)
+			return errors.New(errors.EUsage, "run_id and group are required")
+		}
+		access = commands.DataDirWrite
+	case "ls":
+		if len(positionalArgs) > 0 {
+			fmt.Fprint(stderr, groupUsageText)
+			return errors.New(errors.EUsage, "group ls takes no arguments")
+		}
+	default:
+		fmt.Fprint(stderr, groupUsageText)
+		return errors.New(errors.EUsage, fmt.Sprintf("unknown group subcommand: %s", sub))
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, access, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	if sub == "add" {
+		opts := commands.GroupAddOpts{
+			RunID: positionalArgs[0],
+			Group: positionalArgs[1],
+			Repo:  *repo,
+		}
+		return commands.GroupAdd(ctx, cr, fsys, cwd, opts, stdout, stderr)
+	}
+	return commands.GroupLS(ctx, cr, fsys, cwd, commands.GroupLSOpts{JSON: *jsonOutput}, stdout, stderr)
+}
+
+func runRelocate(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("relocate", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	to := flagSet.String("to", "", "new data dir location")
+	dryRun := flagSet.Bool("dry-run", false, "print the plan without moving anything")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, relocateUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if *to == "" || flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, relocateUsageText)
+		return errors.New(errors.EUsage, "--to <new-dir> is required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, dataDirAccess(!*dryRun), stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.RelocateOpts{
+		To:     *to,
+		DryRun: *dryRun,
+	}
+
+	result, err := commands.Relocate(ctx, cr, fsys, cwd, opts, stdout, stderr)
+	if result != nil {
+		// The audit entry belongs in the moved data dir, not a recreated old one
+		os.Setenv("AGENCY_DATA_DIR", result.To)
+	}
+	return err
+}
+
+func runCleanup(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+	merged := flagSet.Bool("merged", false, "clean up every run whose PR is merged")
+	force := flagSet.Bool("force", false, "do not check that the PR is merged")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, cleanupUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// run_id(s) are required unless --merged ("-" reads from stdin)
+	if flagSet.NArg() < 1 && !*merged {
+		fmt.Fprint(stderr, cleanupUsageText)
+		return errors.New(errors.EUsage, "run_id (or --merged) is required")
+	}
+	var runIDs []string
+	if flagSet.NArg() > 0 {
+		var err error
+		if runIDs, err = commands.ExpandRunIDArgs(flagSet.Args(), stdin); err != nil {
+			return err
+		}
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.CleanupOpts{
+		RunIDs: runIDs,
+		Repo:   *repo,
+		Merged: *merged,
+		Force:  *force,
+	}
+
+	return commands.Cleanup(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runKill(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("kill", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, killUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// run_id(s) are required positional arguments ("-" reads from stdin)
+	if flagSet.NArg() < 1 {
+		fmt.Fprint(stderr, killUsageText)
+		return errors.New(errors.EUsage, "run_id is required")
+	}
+	runIDs, err := commands.ExpandRunIDArgs(flagSet.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.KillOpts{
+		RunIDs: runIDs,
+		Repo:   *repo,
+	}
+
+	return commands.Kill(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runVerify(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	all := flagSet.Bool("all", false, "verify every unarchived run")
+	allRepos := flagSet.Bool("all-repos", false, "with --all, include every repo")
+	jobs := flagSet.Int("jobs", commands.DefaultVerifyJobs, "runs verified at a time")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, verifyUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if *jobs < 1 {
+		return errors.New(errors.EUsage, "--jobs must be at least 1")
+	}
+
+	// run_id(s) are required positional arguments ("-" reads from stdin),
+	// unless --all is given
+	var runIDs []string
+	switch {
+	case *all && flagSet.NArg() > 0:
+		fmt.Fprint(stderr, verifyUsageText)
+		return errors.New(errors.EUsage, "--all cannot be combined with run ids")
+	case !*all && flagSet.NArg() < 1:
+		fmt.Fprint(stderr, verifyUsageText)
+		return errors.New(errors.EUsage, "run_id or --all is required")
+	case !*all:
+		var err error
+		runIDs, err = commands.ExpandRunIDArgs(flagSet.Args(), stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.VerifyOpts{
+		RunIDs:   runIDs,
+		All:      *all,
+		AllRepos: *allRepos,
+		Repo:     *repo,
+		Jobs:     *jobs,
+	}
+
+	return commands.Verify(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runRepos(args []string, stdout, stderr io.Writer) error {
+	sub := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	flagSet := flag.NewFlagSet("repos "+sub, flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	all := flagSet.Bool("all", false, "refresh every repo in repo_index.json")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, reposUsageText)
+			return nil
+		}
+	}
+	if sub != "refresh" {
+		fmt.Fprint(stderr, reposUsageText)
+		if sub == "" {
+			return errors.New(errors.EUsage, "repos subcommand is required")
+		}
+		return errors.New(errors.EUsage, fmt.Sprintf("unknown repos subcommand: %s", sub))
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, reposUsageText)
+		return errors.New(errors.EUsage, "repos refresh takes no arguments")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	return commands.ReposRefresh(ctx, cr, fsys, cwd, commands.ReposRefreshOpts{All: *all}, stdout, stderr)
+}
+
+func runTmux(args []string, stdout, stderr io.Writer) error {
+	sub := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	flagSet := flag.NewFlagSet("tmux "+sub, flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	dryRun := flagSet.Bool("dry-run", false, "list orphaned sessions without killing them")
+	yes := flagSet.Bool("yes", false, "kill orphaned sessions without asking")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, tmuxUsageText)
+			return nil
+		}
+	}
+	if sub != "prune" {
+		fmt.Fprint(stderr, tmuxUsageText)
+		if sub == "" {
+			return errors.New(errors.EUsage, "tmux subcommand is required")
+		}
+		return errors.New(errors.EUsage, fmt.Sprintf("unknown tmux subcommand: %s", sub))
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, tmuxUsageText)
+		return errors.New(errors.EUsage, "tmux prune takes no arguments")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, dataDirAccess(!*dryRun), stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.TmuxPruneOpts{
+		DryRun: *dryRun,
+		Yes:    *yes,
+	}
+	if stdinIsTerminal() {
+		opts.Confirm = func(prompt string) bool {
+			return confirm(stdin, stderr, prompt)
+		}
+	}
+
+	return commands.TmuxPrune(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runUnlock(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("unlock", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	yes := flagSet.Bool("yes", false, "remove the lock without asking")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, unlockUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// The target is a required positional argument
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) != 1 {
+		fmt.Fprint(stderr, unlockUsageText)
+		return errors.New(errors.EUsage, "exactly one repo or run_id is required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.UnlockOpts{
+		Target: positionalArgs[0],
+		Yes:    *yes,
+	}
+	if stdinIsTerminal() {
+		opts.Confirm = func(prompt string) bool {
+			return confirm(stdin, stderr, prompt)
+		}
+	}
+
+	return commands.Unlock(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runWatchFiles(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("watch-files", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	var filters, excludes stringListFlag
+	flagSet.Var(&filters, "filter", "only report matching paths (repeatable)")
+	flagSet.Var(&excludes, "exclude", "skip matching paths (repeatable)")
+	interval := flagSet.Duration("interval", commands.DefaultWatchInterval, "poll interval")
+	count := flagSet.Int("count", 0, "exit after n changes")
+	withEvents := flagSet.Bool("events", false, "append file_changed events")
+	jsonOutput := flagSet.Bool("json", false, "print one JSON object per change")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, watchFilesUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if *interval <= 0 {
+		return errors.New(errors.EUsage, "--interval must be positive")
+	}
+
+	// run_id is a required positional argument
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) != 1 {
+		fmt.Fprint(stderr, watchFilesUsageText)
+		return errors.New(errors.EUsage, "exactly one run_id is required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	// Appending events is a write
+	access := commands.DataDirRead
+	if *withEvents {
+		access = commands.DataDirWrite
+	}
+	if err := guardDataDir(cwd, access, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations; Ctrl-C ends the feed cleanly
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := commands.WatchFilesOpts{
+		RunID:    positionalArgs[0],
+		Repo:     *repo,
+		Filters:  filters,
+		Excludes: excludes,
+		Interval: *interval,
+		Events:   *withEvents,
+		JSON:     *jsonOutput,
+		Count:    *count,
+	}
+
+	return commands.WatchFiles(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runWatch(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	repo := flagSet.String("repo", "", "only watch runs of this repo")
+	once := flagSet.Bool("once", false, "run due probes once and exit")
+	tick := flagSet.Duration("tick", commands.DefaultWatchTick, "how often to look for due probes")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, watchUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, watchUsageText)
+		return errors.New(errors.EUsage, "watch takes no arguments")
+	}
+	if *tick <= 0 {
+		return errors.New(errors.EUsage, "--tick must be positive")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	// Probe results are written to meta.json and events.jsonl
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations; Ctrl-C stops watching cleanly
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := commands.WatchOpts{
+		Repo: *repo,
+		Once: *once,
+		Tick: *tick,
+	}
+
+	return commands.Watch(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runCheckpoint(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("checkpoint", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	message := flagSet.String("message", "", "describe the checkpoint")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, checkpointUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// run_id is a required positional argument
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) != 1 {
+		fmt.Fprint(stderr, checkpointUsageText)
+		return errors.New(errors.EUsage, "exactly one run_id is required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.CheckpointOpts{
+		RunID:   positionalArgs[0],
+		Message: *message,
+		Repo:    *repo,
+	}
+
+	return commands.Checkpoint(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runRestore(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	number := flagSet.Int("checkpoint", 0, "the checkpoint to roll back to")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, restoreUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// run_id is a required positional argument
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) != 1 {
+		fmt.Fprint(stderr, restoreUsageText)
+		return errors.New(errors.EUsage, "exactly one run_id is required")
+	}
+	if *number <= 0 {
+		fmt.Fprint(stderr, restoreUsageText)
+		return errors.New(errors.EUsage, "--checkpoint <n> is required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirWrite, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.RestoreOpts{
+		RunID:      positionalArgs[0],
+		Checkpoint: *number,
+		Repo:       *repo,
+	}
+
+	return commands.Restore(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runGC(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("gc", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	auto := flagSet.Bool("auto", false, "archive qualifying runs")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, gcUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, gcUsageText)
+		return errors.New(errors.EUsage, "gc takes no arguments")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, dataDirAccess(*auto), stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.GCOpts{
+		Auto: *auto,
+	}
+
+	return commands.GC(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runLint(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	all := flagSet.Bool("all", false, "lint every run")
+	fix := flagSet.Bool("fix", false, "repair fixable problems")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, lintUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) > 1 || (len(positionalArgs) == 1) == *all {
+		fmt.Fprint(stderr, lintUsageText)
+		return errors.New(errors.EUsage, "exactly one of <run_id> or --all is required")
+	}
+	runID := ""
+	if len(positionalArgs) == 1 {
+		runID = positionalArgs[0]
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, dataDirAccess(*fix), stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.LintOpts{
+		RunID: runID,
+		All:   *all,
+		Fix:   *fix,
+		Repo:  *repo,
+	}
+
+	return commands.Lint(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
+
+func runDiffEnv(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("diff-env", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	all := flagSet.Bool("all", false, "include run-specific keys")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, diffEnvUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+
+	// two run ids are required positional arguments
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) != 2 {
+		fmt.Fprint(stderr, diffEnvUsageText)
+		return errors.New(errors.EUsage, "exactly two run ids are required")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirRead, stderr); err != nil {
+		return err
+	}
+
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
 
-const showUsageText = `usage: agency show <run_id> [options]
+	opts := commands.DiffEnvOpts{
+		RunA: positionalArgs[0],
+		RunB: positionalArgs[1],
+		All:  *all,
+		Repo: *repo,
+	}
 
-show details for a single run.
-resolves run_id globally (works from anywhere, not just inside a repo).
-accepts exact run_id or unique prefix.
+	return commands.DiffEnv(ctx, cr, fsys, cwd, opts, stdout, stderr)
+}
 
-arguments:
-  run_id        the run identifier or unique prefix
+func runCompare(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("compare", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
 
-options:
-  --json          output as JSON (stable format)
-  --path          output only resolved filesystem paths
-  -h, --help      show this help
+	jsonOutput := flagSet.Bool("json", false, "output as JSON")
+	plain := flagSet.Bool("plain", false, "line-oriented key: value output")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
+	task := flagSet.String("task", "", "compare the runs of this task")
 
-examples:
-  agency show 20260110120000-a3f2           # show run details
-  agency show 20260110                       # unique prefix resolution
-  agency show 20260110120000-a3f2 --json    # machine-readable output
-  agency show 20260110120000-a3f2 --path    # print paths only
-`
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, compareUsageText)
+			return nil
+		}
+	}
 
-// Run parses arguments and dispatches to the appropriate subcommand.
-// Returns an error if the command fails; the caller should print the error and exit.
-func Run(args []string, stdout, stderr io.Writer) error {
-	if len(args) == 0 {
-		fmt.Fprint(stdout, usageText)
-		return errors.New(errors.EUsage, "no command specified")
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
 	}
 
-	cmd := args[0]
-	cmdArgs := args[1:]
+	// two run ids are required positional arguments, unless --task names them
+	positionalArgs := flagSet.Args()
+	if *task != "" {
+		if len(positionalArgs) != 0 {
+			fmt.Fprint(stderr, compareUsageText)
+			return errors.New(errors.EUsage, "--task cannot be combined with run ids")
+		}
+		positionalArgs = []string{"", ""}
+	} else if len(positionalArgs) != 2 {
+		fmt.Fprint(stderr, compareUsageText)
+		return errors.New(errors.EUsage, "exactly two run ids are required")
+	}
 
-	// Handle global flags
-	if cmd == "-h" || cmd == "--help" {
-		fmt.Fprint(stdout, usageText)
-		return nil
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
 	}
-	if cmd == "-v" || cmd == "--version" {
-		fmt.Fprintf(stdout, "agency %s\n", version.Version)
-		return nil
+
+	if err := guardDataDir(cwd, commands.DataDirRead, stderr); err != nil {
+		return err
 	}
 
-	switch cmd {
-	case "init":
-		return runInit(cmdArgs, stdout, stderr)
-	case "doctor":
-		return runDoctor(cmdArgs, stdout, stderr)
-	case "run":
-		return runRun(cmdArgs, stdout, stderr)
-	case "ls":
-		return runLS(cmdArgs, stdout, stderr)
-	case "show":
-		return runShow(cmdArgs, stdout, stderr)
-	case "attach":
-		return runAttach(cmdArgs, stdout, stderr)
-	default:
-		fmt.Fprint(stdout, usageText)
-		return errors.New(errors.EUsage, fmt.Sprintf("unknown command: %s", cmd))
+	// Create real implementations
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	opts := commands.CompareOpts{
+		RunA:  positionalArgs[0],
+		RunB:  positionalArgs[1],
+		Task:  *task,
+		Repo:  *repo,
+		JSON:  *jsonOutput,
+		Plain: *plain || plainOutput,
 	}
+
+	return commands.Compare(ctx, cr, fsys, cwd, opts, stdout, stderr)
 }
 
-func runInit(args []string, stdout, stderr io.Writer) error {
-	flagSet := flag.NewFlagSet("init", flag.ContinueOnError)
+func runBundle(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("bundle", flag.ContinueOnError)
 	flagSet.SetOutput(io.Discard)
 
-	noGitignore := flagSet.Bool("no-gitignore", false, "do not modify .gitignore")
-	force := flagSet.Bool("force", false, "overwrite existing agency.json")
+	var output string
+	flagSet.StringVar(&output, "output", "", "tarball path")
+	flagSet.StringVar(&output, "o", "", "tarball path")
+	repo := flagSet.String("repo", "", "restrict run_id resolution to a repo")
 
 	// Handle help manually to return nil (exit 0)
 	for _, arg := range args {
 		if arg == "-h" || arg == "--help" {
-			fmt.Fprint(stdout, initUsageText)
+			fmt.Fprint(stdout, bundleUsageText)
 			return nil
 		}
 	}
 
+	// Allow the run_id before flags (agency bundle <id> -o x.tar.gz)
+	var runID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		runID, args = args[0], args[1:]
+	}
 	if err := flagSet.Parse(args); err != nil {
 		return errors.Wrap(errors.EUsage, "invalid flags", err)
 	}
+	positionalArgs := flagSet.Args()
+	if runID == "" && len(positionalArgs) > 0 {
+		runID, positionalArgs = positionalArgs[0], positionalArgs[1:]
+	}
+	if runID == "" || len(positionalArgs) > 0 {
+		fmt.Fprint(stderr, bundleUsageText)
+		return errors.New(errors.EUsage, "exactly one run_id is required")
+	}
 
 	// Get current working directory
-	cwd, err := os.Getwd()
+	cwd, err := getwd()
 	if err != nil {
-		return errors.Wrap(errors.ENoRepo, "failed to get working directory", err)
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirRead, stderr); err != nil {
+		return err
 	}
 
 	// Create real implementations
@@ -196,22 +2947,28 @@ func runInit(args []string, stdout, stderr io.Writer) error {
 	fsys := fs.NewRealFS()
 	ctx := context.Background()
 
-	opts := commands.InitOpts{
-		NoGitignore: *noGitignore,
-		Force:       *force,
+	opts := commands.BundleOpts{
+		RunID:  runID,
+		Repo:   *repo,
+		Output: output,
 	}
 
-	return commands.Init(ctx, cr, fsys, cwd, opts, stdout, stderr)
+	return commands.Bundle(ctx, cr, fsys, cwd, opts, stdout, stderr)
 }
 
-func runDoctor(args []string, stdout, stderr io.Writer) error {
-	flagSet := flag.NewFlagSet("doctor", flag.ContinueOnError)
+func runReport(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("report", flag.ContinueOnError)
 	flagSet.SetOutput(io.Discard)
 
+	all := flagSet.Bool("all", false, "include runs from every repo")
+	since := flagSet.String("since", "", "only runs with activity within this window")
+	output := flagSet.String("output", "", "write to file instead of stdout")
+	html := flagSet.Bool("html", false, "render HTML instead of Markdown")
+
 	// Handle help manually to return nil (exit 0)
 	for _, arg := range args {
 		if arg == "-h" || arg == "--help" {
-			fmt.Fprint(stdout, doctorUsageText)
+			fmt.Fprint(stdout, reportUsageText)
 			return nil
 		}
 	}
@@ -219,11 +2976,32 @@ func runDoctor(args []string, stdout, stderr io.Writer) error {
 	if err := flagSet.Parse(args); err != nil {
 		return errors.Wrap(errors.EUsage, "invalid flags", err)
 	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, reportUsageText)
+		return errors.New(errors.EUsage, "report takes no arguments")
+	}
+
+	opts := commands.ReportOpts{
+		AllRepos: *all,
+		Output:   *output,
+		HTML:     *html,
+	}
+	if *since != "" {
+		d, err := core.ParseAge(*since)
+		if err != nil {
+			return errors.Wrap(errors.EUsage, "invalid --since", err)
+		}
+		opts.Since = d
+	}
 
 	// Get current working directory
-	cwd, err := os.Getwd()
+	cwd, err := getwd()
 	if err != nil {
-		return errors.Wrap(errors.ENoRepo, "failed to get working directory", err)
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirRead, stderr); err != nil {
+		return err
 	}
 
 	// Create real implementations
@@ -231,22 +3009,22 @@ func runDoctor(args []string, stdout, stderr io.Writer) error {
 	fsys := fs.NewRealFS()
 	ctx := context.Background()
 
-	return commands.Doctor(ctx, cr, fsys, cwd, stdout, stderr)
+	return commands.Report(ctx, cr, fsys, cwd, opts, stdout, stderr)
 }
 
-func runRun(args []string, stdout, stderr io.Writer) error {
-	flagSet := flag.NewFlagSet("run", flag.ContinueOnError)
+func runDigest(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("digest", flag.ContinueOnError)
 	flagSet.SetOutput(io.Discard)
 
-	title := flagSet.String("title", "", "run title")
-	runner := flagSet.String("runner", "", "runner name (claude or codex)")
-	parent := flagSet.String("parent", "", "parent branch")
-	attach := flagSet.Bool("attach", false, "attach to tmux session immediately")
+	all := flagSet.Bool("all", false, "include runs from every repo")
+	since := flagSet.String("since", "", "the digest window")
+	format := flagSet.String("format", "markdown", "markdown or slack")
+	post := flagSet.Bool("post", false, "post to digest.webhook_url")
 
 	// Handle help manually to return nil (exit 0)
 	for _, arg := range args {
 		if arg == "-h" || arg == "--help" {
-			fmt.Fprint(stdout, runUsageText)
+			fmt.Fprint(stdout, digestUsageText)
 			return nil
 		}
 	}
@@ -254,11 +3032,32 @@ func runRun(args []string, stdout, stderr io.Writer) error {
 	if err := flagSet.Parse(args); err != nil {
 		return errors.Wrap(errors.EUsage, "invalid flags", err)
 	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, digestUsageText)
+		return errors.New(errors.EUsage, "digest takes no arguments")
+	}
+
+	opts := commands.DigestOpts{
+		AllRepos: *all,
+		Format:   *format,
+		Post:     *post,
+	}
+	if *since != "" {
+		d, err := core.ParseAge(*since)
+		if err != nil {
+			return errors.Wrap(errors.EUsage, "invalid --since", err)
+		}
+		opts.Since = d
+	}
 
 	// Get current working directory
-	cwd, err := os.Getwd()
+	cwd, err := getwd()
 	if err != nil {
-		return errors.Wrap(errors.ENoRepo, "failed to get working directory", err)
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	if err := guardDataDir(cwd, commands.DataDirRead, stderr); err != nil {
+		return err
 	}
 
 	// Create real implementations
@@ -266,28 +3065,21 @@ func runRun(args []string, stdout, stderr io.Writer) error {
 	fsys := fs.NewRealFS()
 	ctx := context.Background()
 
-	opts := commands.RunOpts{
-		Title:  *title,
-		Runner: *runner,
-		Parent: *parent,
-		Attach: *attach,
-	}
-
-	return commands.Run(ctx, cr, fsys, cwd, opts, stdout, stderr)
+	return commands.Digest(ctx, cr, fsys, cwd, opts, stdout, stderr)
 }
 
-func runLS(args []string, stdout, stderr io.Writer) error {
-	flagSet := flag.NewFlagSet("ls", flag.ContinueOnError)
+func runAudit(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("audit", flag.ContinueOnError)
 	flagSet.SetOutput(io.Discard)
 
-	all := flagSet.Bool("all", false, "include archived runs")
-	allRepos := flagSet.Bool("all-repos", false, "list runs across all repos")
-	jsonOutput := flagSet.Bool("json", false, "output as JSON")
+	runID := flagSet.String("run", "", "only commands that touched this run")
+	since := flagSet.String("since", "", "only commands within this window")
+	jsonOutput := flagSet.Bool("json", false, "print audit.jsonl lines")
 
 	// Handle help manually to return nil (exit 0)
 	for _, arg := range args {
 		if arg == "-h" || arg == "--help" {
-			fmt.Fprint(stdout, lsUsageText)
+			fmt.Fprint(stdout, auditUsageText)
 			return nil
 		}
 	}
@@ -295,38 +3087,51 @@ func runLS(args []string, stdout, stderr io.Writer) error {
 	if err := flagSet.Parse(args); err != nil {
 		return errors.Wrap(errors.EUsage, "invalid flags", err)
 	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, auditUsageText)
+		return errors.New(errors.EUsage, "audit takes no arguments")
+	}
+
+	opts := commands.AuditOpts{
+		RunID: *runID,
+		JSON:  *jsonOutput,
+	}
+	if *since != "" {
+		d, err := core.ParseAge(*since)
+		if err != nil {
+			return errors.Wrap(errors.EUsage, "invalid --since", err)
+		}
+		opts.Since = d
+	}
 
 	// Get current working directory
-	cwd, err := os.Getwd()
+	cwd, err := getwd()
 	if err != nil {
 		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
 	}
 
-	// Create real implementations
-	cr := exec.NewRealRunner()
-	fsys := fs.NewRealFS()
-	ctx := context.Background()
-
-	opts := commands.LSOpts{
-		All:      *all,
-		AllRepos: *allRepos,
-		JSON:     *jsonOutput,
+	if err := guardDataDir(cwd, commands.DataDirRead, stderr); err != nil {
+		return err
 	}
 
-	return commands.LS(ctx, cr, fsys, cwd, opts, stdout, stderr)
+	return commands.Audit(fs.NewRealFS(), cwd, opts, stdout, stderr)
 }
 
-func runShow(args []string, stdout, stderr io.Writer) error {
-	flagSet := flag.NewFlagSet("show", flag.ContinueOnError)
+func runConfig(args []string, stdout, stderr io.Writer) error {
+	sub := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	flagSet := flag.NewFlagSet("config "+sub, flag.ContinueOnError)
 	flagSet.SetOutput(io.Discard)
 
 	jsonOutput := flagSet.Bool("json", false, "output as JSON")
-	pathOutput := flagSet.Bool("path", false, "output only resolved paths")
 
 	// Handle help manually to return nil (exit 0)
 	for _, arg := range args {
 		if arg == "-h" || arg == "--help" {
-			fmt.Fprint(stdout, showUsageText)
+			fmt.Fprint(stdout, configUsageText)
 			return nil
 		}
 	}
@@ -335,60 +3140,184 @@ func runShow(args []string, stdout, stderr io.Writer) error {
 		return errors.Wrap(errors.EUsage, "invalid flags", err)
 	}
 
-	// run_id is a required positional argument
 	positionalArgs := flagSet.Args()
-	if len(positionalArgs) < 1 {
-		fmt.Fprint(stderr, showUsageText)
-		return errors.New(errors.EUsage, "run_id is required")
+	want := map[string]int{"get": 1, "set": 2, "list": 0, "edit": 0}
+	n, ok := want[sub]
+	if !ok {
+		fmt.Fprint(stderr, configUsageText)
+		return errors.New(errors.EUsage, fmt.Sprintf("unknown config subcommand: %s", sub))
+	}
+	if len(positionalArgs) != n {
+		fmt.Fprint(stderr, configUsageText)
+		return errors.New(errors.EUsage, fmt.Sprintf("config %s takes %d argument(s)", sub, n))
+	}
+
+	// set and edit write config.json
+	if err := checkReadOnly(dataDirAccess(sub == "set" || sub == "edit")); err != nil {
+		return err
 	}
-	runID := positionalArgs[0]
 
 	// Get current working directory
-	cwd, err := os.Getwd()
+	cwd, err := getwd()
 	if err != nil {
 		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
 	}
 
-	// Create real implementations
-	cr := exec.NewRealRunner()
 	fsys := fs.NewRealFS()
-	ctx := context.Background()
+	opts := commands.ConfigOpts{
+		JSON:     *jsonOutput,
+		PlainEnv: plainFromEnv(os.Getenv),
+	}
+	switch sub {
+	case "get":
+		opts.Key = positionalArgs[0]
+		return commands.ConfigGet(fsys, cwd, opts, stdout, stderr)
+	case "set":
+		opts.Key, opts.Value = positionalArgs[0], positionalArgs[1]
+		return commands.ConfigSet(fsys, cwd, opts, stdout, stderr)
+	case "edit":
+		if stdinIsTerminal() {
+			opts.Confirm = func(prompt string) bool {
+				return confirm(stdin, stderr, prompt)
+			}
+		}
+		return commands.ConfigEdit(fsys, cwd, opts, stdout, stderr)
+	}
+	return commands.ConfigList(fsys, cwd, opts, stdout, stderr)
+}
 
-	opts := commands.ShowOpts{
-		RunID: runID,
-		JSON:  *jsonOutput,
-		Path:  *pathOutput,
+func runSchema(args []string, stdout, stderr io.Writer) error {
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	return commands.Show(ctx, cr, fsys, cwd, opts, stdout, stderr)
+	flagSet := flag.NewFlagSet("schema", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	validate := flagSet.String("validate", "", "file to check against the schema")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, schemaUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, schemaUsageText)
+		return errors.New(errors.EUsage, "schema takes at most one name")
+	}
+
+	// Get current working directory
+	cwd, err := getwd()
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to get working directory", err)
+	}
+
+	opts := commands.SchemaOpts{Name: name, Validate: *validate}
+	return commands.Schema(fs.NewRealFS(), cwd, opts, stdout)
 }
 
-func runAttach(args []string, stdout, stderr io.Writer) error {
-	flagSet := flag.NewFlagSet("attach", flag.ContinueOnError)
+func runProfile(args []string, stdout, stderr io.Writer) error {
+	sub := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	flagSet := flag.NewFlagSet("profile "+sub, flag.ContinueOnError)
 	flagSet.SetOutput(io.Discard)
 
+	jsonOutput := flagSet.Bool("json", false, "output as JSON")
+
 	// Handle help manually to return nil (exit 0)
 	for _, arg := range args {
 		if arg == "-h" || arg == "--help" {
-			fmt.Fprint(stdout, attachUsageText)
+			fmt.Fprint(stdout, profileUsageText)
 			return nil
 		}
 	}
 
+	if sub != "report" {
+		fmt.Fprint(stderr, profileUsageText)
+		if sub == "" {
+			return errors.New(errors.EUsage, "profile requires a subcommand")
+		}
+		return errors.New(errors.EUsage, fmt.Sprintf("unknown profile subcommand: %s", sub))
+	}
 	if err := flagSet.Parse(args); err != nil {
 		return errors.Wrap(errors.EUsage, "invalid flags", err)
 	}
+	if flagSet.NArg() > 1 {
+		fmt.Fprint(stderr, profileUsageText)
+		return errors.New(errors.EUsage, "profile report takes at most one directory")
+	}
 
-	// run_id is a required positional argument
-	positionalArgs := flagSet.Args()
-	if len(positionalArgs) < 1 {
-		fmt.Fprint(stderr, attachUsageText)
-		return errors.New(errors.EUsage, "run_id is required")
+	opts := commands.ProfileReportOpts{Dir: flagSet.Arg(0), JSON: *jsonOutput}
+	return commands.ProfileReport(opts, stdout)
+}
+
+// stringListFlag is a repeatable string flag (e.g. --label a=1 --label b=2).
+type stringListFlag []string
+
+func (f *stringListFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringListFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// splitRunners splits a --runners value ("claude,codex") into runner names.
+// Empty entries are kept so commands.Run can reject them.
+func splitRunners(s string) []string {
+	if s == "" {
+		return nil
+	}
+	runners := strings.Split(s, ",")
+	for i, r := range runners {
+		runners[i] = strings.TrimSpace(r)
+	}
+	return runners
+}
+
+func runBranchGuard(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("branch-guard", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	block := flagSet.Bool("block", false, "switch back instead of warning")
+	uninstall := flagSet.Bool("uninstall", false, "remove the hook")
+	status := flagSet.Bool("status", false, "report whether the hook is installed")
+
+	// Handle help manually to return nil (exit 0)
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Fprint(stdout, branchGuardUsageText)
+			return nil
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid flags", err)
+	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprint(stderr, branchGuardUsageText)
+		return errors.New(errors.EUsage, "branch-guard takes no arguments")
+	}
+	if (*block && *uninstall) || (*status && (*block || *uninstall)) {
+		return errors.New(errors.EUsage, "--block, --uninstall, and --status are mutually exclusive")
+	}
+
+	// Installing or removing the hook writes the repo's .git/hooks
+	if err := checkReadOnly(dataDirAccess(!*status)); err != nil {
+		return err
 	}
-	runID := positionalArgs[0]
 
 	// Get current working directory
-	cwd, err := os.Getwd()
+	cwd, err := getwd()
 	if err != nil {
 		return errors.Wrap(errors.ENoRepo, "failed to get working directory", err)
 	}
@@ -398,28 +3327,11 @@ func runAttach(args []string, stdout, stderr io.Writer) error {
 	fsys := fs.NewRealFS()
 	ctx := context.Background()
 
-	opts := commands.AttachOpts{
-		RunID: runID,
+	opts := commands.BranchGuardOpts{
+		Block:     *block,
+		Uninstall: *uninstall,
+		Status:    *status,
 	}
 
-	err = commands.Attach(ctx, cr, fsys, cwd, opts, stdout, stderr)
-	if err != nil {
-		// Print helpful details for E_TMUX_SESSION_MISSING
-		if ae, ok := errors.AsAgencyError(err); ok && ae.Code == errors.ETmuxSessionMissing {
-			if ae.Details != nil {
-				fmt.Fprintln(stderr)
-				if wp := ae.Details["worktree_path"]; wp != "" {
-					fmt.Fprintf(stderr, "worktree_path: %s\n", wp)
-				}
-				if rc := ae.Details["runner_cmd"]; rc != "" {
-					fmt.Fprintf(stderr, "runner_cmd: %s\n", rc)
-				}
-				if hint := ae.Details["hint"]; hint != "" {
-					fmt.Fprintf(stderr, "\nto start the runner manually:\n  %s\n", hint)
-				}
-				fmt.Fprintln(stderr, "\nnote: resume is not yet implemented (coming in later slice)")
-			}
-		}
-	}
-	return err
+	return commands.BranchGuard(ctx, cr, fsys, cwd, opts, stdout, stderr)
 }
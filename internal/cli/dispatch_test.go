@@ -2,13 +2,29 @@ package cli
 
 import (
 	"bytes"
+	"flag"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/NielsdaWheelz/agency/internal/audit"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 )
 
+// TestMain points AGENCY_DATA_DIR at a temp dir so commands that get past
+// argument parsing never touch the real data dir.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "agency-cli-test-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("AGENCY_DATA_DIR", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestRun_NoArgs(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	err := Run([]string{}, &stdout, &stderr)
@@ -253,3 +269,323 @@ func TestRun_AttachMissingRunID(t *testing.T) {
 		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
 	}
 }
+
+func TestRun_NoteHelp(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"note", "--help"}, &stdout, &stderr)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "agency note") {
+		t.Error("expected note usage in stdout")
+	}
+}
+
+func TestRun_NoteMissingText(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"note", "20260110120000-a3f2"}, &stdout, &stderr)
+
+	if err == nil {
+		t.Fatal("expected error when note text is missing")
+	}
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
+
+func TestRun_MvMissingTitle(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"mv", "--branch", "20260110120000-a3f2"}, &stdout, &stderr)
+
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+	if !strings.Contains(stderr.String(), "agency mv") {
+		t.Error("expected mv usage in stderr")
+	}
+}
+
+func TestRun_LintRequiresRunIDOrAll(t *testing.T) {
+	for _, args := range [][]string{{"lint"}, {"lint", "--all", "20260110120000-a3f2"}} {
+		var stdout, stderr bytes.Buffer
+		err := Run(args, &stdout, &stderr)
+
+		if errors.GetCode(err) != errors.EUsage {
+			t.Errorf("%v: code = %q, want %q", args, errors.GetCode(err), errors.EUsage)
+		}
+		if !strings.Contains(stderr.String(), "agency lint") {
+			t.Errorf("%v: expected lint usage in stderr", args)
+		}
+	}
+}
+
+func TestRun_DiffEnvRequiresTwoRunIDs(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"diff-env", "20260110120000-a3f2"}, &stdout, &stderr)
+
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+	if !strings.Contains(stderr.String(), "agency diff-env") {
+		t.Error("expected diff-env usage in stderr")
+	}
+}
+
+func TestRun_ReportInvalidSince(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"report", "--since", "soon"}, &stdout, &stderr)
+
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
+
+func TestPlainFromEnv(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want bool
+	}{
+		{map[string]string{}, false},
+		{map[string]string{"AGENCY_PLAIN": "1"}, true},
+		{map[string]string{"AGENCY_PLAIN": "TRUE"}, true},
+		{map[string]string{"AGENCY_PLAIN": "0"}, false},
+		{map[string]string{"TERM": "dumb"}, true},
+		{map[string]string{"TERM": "xterm-256color"}, false},
+	}
+	for _, tt := range tests {
+		getenv := func(k string) string { return tt.env[k] }
+		if got := plainFromEnv(getenv); got != tt.want {
+			t.Errorf("plainFromEnv(%v) = %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestRun_GlobalPlainFlag(t *testing.T) {
+	t.Cleanup(func() { plainOutput = false })
+
+	var stdout, stderr bytes.Buffer
+	if err := Run([]string{"--plain", "--version"}, &stdout, &stderr); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !plainOutput {
+		t.Error("--plain before the command should enable plain output")
+	}
+}
+
+func TestRun_AdoptMissingBranch(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"adopt", "--title", "x"}, &stdout, &stderr)
+
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+	if !strings.Contains(stderr.String(), "agency adopt") {
+		t.Error("expected adopt usage in stderr")
+	}
+}
+
+func TestRun_KillEmptyStdin(t *testing.T) {
+	old := stdin
+	stdin = strings.NewReader("\n")
+	defer func() { stdin = old }()
+
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"kill", "-"}, &stdout, &stderr)
+
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
+
+func TestRun_ForceReadOnlyRefusesWrites(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"--force-read-only", "note", "20260110120000-a3f2", "text"}, &stdout, &stderr)
+
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q (err=%v)", errors.GetCode(err), errors.EUsage, err)
+	}
+}
+
+func TestRun_ReadOnlyRefusesWrites(t *testing.T) {
+	t.Cleanup(func() { readOnly = false })
+	t.Setenv("AGENCY_DATA_DIR", t.TempDir())
+	t.Setenv("AGENCY_READ_ONLY", "")
+	var stdout, stderr bytes.Buffer
+	for _, args := range [][]string{
+		{"--read-only", "note", "20260110120000-a3f2", "text"},
+		{"--read-only", "init"},
+		{"--read-only", "doctor"},
+		{"--read-only", "gc", "--auto"},
+		{"--read-only", "branch-guard"},
+		{"--read-only", "config", "set", "plain", "true"},
+	} {
+		err := Run(args, &stdout, &stderr)
+		if errors.GetCode(err) != errors.EReadOnly {
+			t.Errorf("Run(%v) code = %q, want %q (err=%v)", args, errors.GetCode(err), errors.EReadOnly, err)
+		}
+	}
+
+	t.Setenv("AGENCY_READ_ONLY", "1")
+	err := Run([]string{"kill", "20260110120000-a3f2"}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EReadOnly {
+		t.Errorf("AGENCY_READ_ONLY=1 kill code = %q, want %q (err=%v)", errors.GetCode(err), errors.EReadOnly, err)
+	}
+
+	// Read-only commands still run
+	if err := Run([]string{"--read-only", "ls", "--all-repos"}, &stdout, &stderr); errors.GetCode(err) == errors.EReadOnly {
+		t.Errorf("ls under --read-only refused: %v", err)
+	}
+}
+
+func TestRun_InitAndDoctorRefuseCwdInDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	inside := filepath.Join(dataDir, "repos")
+	if err := os.MkdirAll(inside, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	for _, cmd := range []string{"init", "doctor"} {
+		err := Run([]string{"-C", inside, cmd}, &stdout, &stderr)
+		if errors.GetCode(err) != errors.ECwdInDataDir {
+			t.Errorf("%s from the data dir: code = %q, want %q (err=%v)", cmd, errors.GetCode(err), errors.ECwdInDataDir, err)
+		}
+	}
+}
+
+func TestRun_RecordsMutatingCommandsInAuditLog(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	var stdout, stderr bytes.Buffer
+
+	err := Run([]string{"note", "20260110120000-a3f2", "text"}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.ERunNotFound {
+		t.Fatalf("note code = %q, want %q (err=%v)", errors.GetCode(err), errors.ERunNotFound, err)
+	}
+	_ = Run([]string{"ls"}, &stdout, &stderr)
+
+	entries, err := audit.Read(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %+v, want only the note", entries)
+	}
+	e := entries[0]
+	if e.Command != "note" || e.Outcome != audit.OutcomeError || e.ErrorCode != string(errors.ERunNotFound) {
+		t.Errorf("entry = %+v, want a failed note", e)
+	}
+	if want := []string{"agency", "note", "20260110120000-a3f2", "text"}; strings.Join(e.Argv, " ") != strings.Join(want, " ") {
+		t.Errorf("argv = %v, want %v", e.Argv, want)
+	}
+}
+
+func TestReadOnlyFromEnv(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "1": true, "yes": true, "TRUE": true, "0": false, "no": false} {
+		getenv := func(string) string { return value }
+		if got := readOnlyFromEnv(getenv); got != want {
+			t.Errorf("readOnlyFromEnv(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestRun_GlobalRepoFlag(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	missing := filepath.Join(t.TempDir(), "missing")
+	for _, args := range [][]string{
+		{"-C", missing, "ls"},
+		{"--repo=" + missing, "ls"},
+		{"-C"},
+	} {
+		if err := Run(args, &stdout, &stderr); errors.GetCode(err) != errors.EUsage {
+			t.Errorf("Run(%v) code = %q, want %q (err=%v)", args, errors.GetCode(err), errors.EUsage, err)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := Run([]string{"-C", dir, "--help"}, &stdout, &stderr); err != nil {
+		t.Fatalf("Run(-C dir --help) error = %v", err)
+	}
+	if got, err := getwd(); err != nil || got != dir {
+		t.Errorf("getwd() = %q, %v; want %q", got, err, dir)
+	}
+}
+
+func TestStringListFlag_Repeatable(t *testing.T) {
+	var labels stringListFlag
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	fs.Var(&labels, "label", "")
+	if err := fs.Parse([]string{"--label", "a=1", "--label", "b=2"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 2 || labels[0] != "a=1" || labels[1] != "b=2" {
+		t.Errorf("labels = %v, want [a=1 b=2]", labels)
+	}
+}
+
+func TestRun_RunInvalidLabel(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"run", "--label", "no-equals"}, &stdout, &stderr)
+
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q (err=%v)", errors.GetCode(err), errors.EUsage, err)
+	}
+}
+
+func TestRun_RunDryRunWithAttach(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Run([]string{"run", "--dry-run", "--attach"}, &stdout, &stderr)
+
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q (err=%v)", errors.GetCode(err), errors.EUsage, err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if got := confirm(strings.NewReader(tt.input), &out, "start?"); got != tt.want {
+			t.Errorf("confirm(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+		if out.String() != "start? [y/N] " {
+			t.Errorf("prompt = %q", out.String())
+		}
+	}
+}
+
+func TestRun_Profile(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("AGENCY_CACHE_DIR", cacheDir)
+	t.Setenv("AGENCY_DATA_DIR", t.TempDir())
+	t.Setenv("AGENCY_PROFILE", "")
+	var stdout, stderr bytes.Buffer
+	if err := Run([]string{"--profile", "ls", "--all-repos"}, &stdout, &stderr); err != nil {
+		t.Fatalf("Run(--profile ls) error = %v (stderr %q)", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "profile: "+filepath.Join(cacheDir, "profiles")) {
+		t.Errorf("stderr = %q, want the profile dir", stderr.String())
+	}
+
+	stdout.Reset()
+	if err := Run([]string{"profile", "report"}, &stdout, &stderr); err != nil {
+		t.Fatalf("Run(profile report) error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "command:   agency --profile ls --all-repos") {
+		t.Errorf("report = %q", stdout.String())
+	}
+
+	if err := Run([]string{"profile"}, &stdout, &stderr); errors.GetCode(err) != errors.EUsage {
+		t.Errorf("profile without subcommand code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
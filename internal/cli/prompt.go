@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// linePrompter asks questions on w and reads one answer per line from r.
+// It implements commands.InitPrompter. A single buffered reader is shared
+// by all questions so piped answers are not lost between prompts; at EOF
+// every question takes its default.
+type linePrompter struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+func newLinePrompter(r io.Reader, w io.Writer) *linePrompter {
+	return &linePrompter{r: bufio.NewReader(r), w: w}
+}
+
+// Ask writes "question [def]: " and returns the trimmed answer, or def if empty.
+func (p *linePrompter) Ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.w, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.w, "%s: ", question)
+	}
+	if answer, _ := p.readLine(); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// Confirm writes "question [Y/n]" (or "[y/N]") and returns the answer,
+// re-asking until it is empty, yes, or no.
+func (p *linePrompter) Confirm(question string, def bool) bool {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	for {
+		fmt.Fprintf(p.w, "%s %s ", question, hint)
+		answer, eof := p.readLine()
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		case "":
+			return def
+		}
+		if eof {
+			return def
+		}
+		fmt.Fprintln(p.w, "please answer y or n")
+	}
+}
+
+// readLine returns the next trimmed line and whether input is exhausted.
+func (p *linePrompter) readLine() (string, bool) {
+	line, err := p.r.ReadString('\n')
+	return strings.TrimSpace(line), err != nil
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLinePrompter(t *testing.T) {
+	var out bytes.Buffer
+	p := newLinePrompter(strings.NewReader("develop\n\nmaybe\nn\n"), &out)
+
+	if got := p.Ask("parent branch", "main"); got != "develop" {
+		t.Errorf("Ask() = %q, want develop", got)
+	}
+	if got := p.Ask("runner", "claude"); got != "claude" {
+		t.Errorf("Ask() with empty answer = %q, want the default", got)
+	}
+	if p.Confirm("enable GitHub flow?", true) {
+		t.Error("Confirm() = true after \"maybe\" then \"n\"")
+	}
+	// Input exhausted: defaults
+	if got := p.Ask("again", "x"); got != "x" {
+		t.Errorf("Ask() at EOF = %q, want x", got)
+	}
+	if !p.Confirm("again?", true) {
+		t.Error("Confirm() at EOF = false, want the default")
+	}
+
+	want := "parent branch [main]: runner [claude]: enable GitHub flow? [Y/n] please answer y or n\n" +
+		"enable GitHub flow? [Y/n] again [x]: again? [Y/n] "
+	if out.String() != want {
+		t.Errorf("prompts = %q, want %q", out.String(), want)
+	}
+}
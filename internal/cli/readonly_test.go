@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/commands"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// treeSnapshot records every entry under a directory: type, mode, size,
+// mtime and, for files, a content hash. Directory mtimes catch files that
+// were created and removed again (a lock taken and released).
+func treeSnapshot(t *testing.T, root string) map[string]string {
+	t.Helper()
+	snap := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		entry := fmt.Sprintf("%s %d %s", info.Mode(), info.Size(), info.ModTime().Format(time.RFC3339Nano))
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, _ := os.Readlink(path)
+			entry += " -> " + target
+		case info.Mode().IsRegular():
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			entry += fmt.Sprintf(" %x", sha256.Sum256(data))
+		}
+		snap[path] = entry
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("snapshot %s: %v", root, err)
+	}
+	return snap
+}
+
+// assertUnchanged reports every entry under root that differs from before.
+func assertUnchanged(t *testing.T, label, root string, before map[string]string) {
+	t.Helper()
+	after := treeSnapshot(t, root)
+	for path, was := range before {
+		if now, ok := after[path]; !ok {
+			t.Errorf("%s removed %s", label, path)
+		} else if now != was {
+			t.Errorf("%s modified %s:\n  before %s\n  after  %s", label, path, was, now)
+		}
+	}
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			t.Errorf("%s created %s", label, path)
+		}
+	}
+}
+
+// holdRepoLocks writes a lock held by this (live) process for every repo in
+// dataDir, so a command that takes a repo lock fails with E_REPO_LOCKED.
+func holdRepoLocks(t *testing.T, dataDir string) {
+	t.Helper()
+	repos, err := filepath.Glob(filepath.Join(dataDir, "repos", "*"))
+	if err != nil || len(repos) == 0 {
+		t.Fatalf("no repos in %s: %v", dataDir, err)
+	}
+	data, err := json.Marshal(lock.LockInfo{PID: os.Getpid(), CreatedAt: time.Now(), Cmd: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, repo := range repos {
+		if err := os.WriteFile(filepath.Join(repo, ".lock"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestReadCommands_LeaveDataDirUntouched is the read path guarantee that
+// shared read-only mounts of the data dir rely on: read commands never
+// create directories, write files, or take repo locks, including for runs
+// whose logs dir is missing. Any change to the data dir fails the test, and
+// held locks make a command that takes one fail.
+func TestReadCommands_LeaveDataDirUntouched(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	repoRoot := testkit.NewRepo(t, testkit.RepoOpts{Git: true})
+	testkit.Git(t, repoRoot, "branch", "feature/one")
+	testkit.Git(t, repoRoot, "branch", "feature/two")
+
+	clock := testkit.NewClock(time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	defer commands.SetClock(clock.Core())()
+
+	var stdout, stderr bytes.Buffer
+	if err := Run([]string{"-C", repoRoot, "adopt", "feature/one"}, &stdout, &stderr); err != nil {
+		t.Fatalf("adopt: %v (stderr: %s)", err, stderr.String())
+	}
+	clock.Advance(time.Minute)
+	if err := Run([]string{"-C", repoRoot, "adopt", "--no-worktree", "feature/two"}, &stdout, &stderr); err != nil {
+		t.Fatalf("adopt: %v (stderr: %s)", err, stderr.String())
+	}
+	const withWorktree, withoutWorktree = "20260110120000-0001", "20260110120100-0002"
+
+	// A run whose logs dir is gone must not get it back from a read
+	logsDirs, _ := filepath.Glob(filepath.Join(dataDir, "repos", "*", "runs", withoutWorktree, "logs"))
+	for _, dir := range logsDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	holdRepoLocks(t, dataDir)
+
+	reads := [][]string{
+		{"ls"},
+		{"ls", "--all-repos"},
+		{"ls", "--all"},
+		{"ls", "--all", "--json"},
+		{"show", withWorktree},
+		{"show", "--json", withWorktree},
+		{"show", withoutWorktree},
+		{"show", "--json", withoutWorktree},
+		{"logs", withWorktree},
+		{"logs", withoutWorktree},
+	}
+	for _, args := range reads {
+		before := treeSnapshot(t, dataDir)
+		stdout.Reset()
+		stderr.Reset()
+		if err := Run(append([]string{"-C", repoRoot}, args...), &stdout, &stderr); err != nil {
+			t.Errorf("%s: %v (stderr: %s)", strings.Join(args, " "), err, stderr.String())
+		}
+		assertUnchanged(t, strings.Join(args, " "), dataDir, before)
+		if strings.Join(args, " ") == "ls --all --json" && (!strings.Contains(stdout.String(), withWorktree) || !strings.Contains(stdout.String(), withoutWorktree)) {
+			t.Errorf("%s does not list both runs:\n%s", strings.Join(args, " "), stdout.String())
+		}
+	}
+}
+
+// TestReadCommands_MissingDataDir checks that read commands against a data
+// dir that does not exist yet, from AGENCY_DATA_DIR or an agency.json
+// data_dir override, report nothing rather than creating it.
+func TestReadCommands_MissingDataDir(t *testing.T) {
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	override := filepath.Join(t.TempDir(), "shared")
+	agencyJSON := strings.Replace(testkit.DefaultAgencyJSON, `"version": 1,`, `"version": 1, "data_dir": "`+override+`",`, 1)
+
+	for _, tc := range []struct {
+		name       string
+		envDataDir string
+		dataDir    string
+	}{
+		{"AGENCY_DATA_DIR", filepath.Join(t.TempDir(), "data"), ""},
+		{"data_dir override", "", override},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AGENCY_DATA_DIR", tc.envDataDir)
+			dataDir := tc.dataDir
+			if dataDir == "" {
+				dataDir = tc.envDataDir
+			}
+			repoRoot := testkit.NewRepo(t, testkit.RepoOpts{Git: true, AgencyJSON: agencyJSON})
+
+			for _, args := range [][]string{
+				{"ls"},
+				{"ls", "--all-repos"},
+				{"show", "20260110120000-a3f2"},
+				{"logs", "20260110120000-a3f2"},
+			} {
+				var stdout, stderr bytes.Buffer
+				Run(append([]string{"-C", repoRoot}, args...), &stdout, &stderr)
+				if _, err := os.Stat(dataDir); !os.IsNotExist(err) {
+					t.Fatalf("%s created the data dir %s", strings.Join(args, " "), dataDir)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/git"
+	"github.com/NielsdaWheelz/agency/internal/identity"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/repo"
+	"github.com/NielsdaWheelz/agency/internal/runservice"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/worktree"
+)
+
+// AdoptOpts holds options for the adopt command.
+type AdoptOpts struct {
+	// Branch is the existing local branch to adopt.
+	Branch string
+
+	// Title is the run title (empty = branch name).
+	Title string
+
+	// Runner is the runner name (empty = use agency.json default).
+	Runner string
+
+	// Parent is the parent branch (empty = use agency.json default).
+	Parent string
+
+	// RunID is an externally supplied run_id (empty = generate one).
+	RunID string
+
+	// Labels are raw key=value arguments (repeatable --label).
+	Labels []string
+
+	// NoWorktree records the run without creating a worktree when the branch
+	// is not checked out anywhere. The run shows up as archived.
+	NoWorktree bool
+}
+
+// Adopt creates run metadata for an existing branch so it is managed like a
+// run created by `agency run`. An existing linked worktree for the branch is
+// reused; otherwise a worktree is created under the data dir (unless
+// NoWorktree). No setup script or tmux session is run.
+// Requires cwd to be inside the target repo.
+func Adopt(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts AdoptOpts, stdout, stderr io.Writer) error {
+	if opts.Branch == "" {
+		return errors.New(errors.EUsage, "branch is required")
+	}
+	labels, err := core.ParseLabels(opts.Labels)
+	if err != nil {
+		return errors.Wrap(errors.EUsage, "invalid --label", err)
+	}
+
+	rc, err := repo.ResolveRepo(ctx, cr, fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	cfg, err := config.LoadAndValidateForS1(fsys, rc.RepoRoot)
+	if err != nil {
+		return err
+	}
+	runnerName := opts.Runner
+	if runnerName == "" {
+		runnerName = cfg.Defaults.Runner
+	}
+	runnerCmd, err := config.ResolveRunnerCmd(cfg, runnerName)
+	if err != nil {
+		return err
+	}
+	parent := opts.Parent
+	if parent == "" {
+		parent = cfg.Defaults.ParentBranch
+	}
+	title := strings.TrimSpace(opts.Title)
+	if title == "" {
+		title = opts.Branch
+	}
+
+	exists, err := git.BranchExists(ctx, cr, rc.RepoRoot, opts.Branch)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.NewWithDetails(errors.EBranchNotFound, "local branch '"+opts.Branch+"' not found",
+			map[string]string{"branch": opts.Branch})
+	}
+
+	runID := opts.RunID
+	if runID != "" {
+		if err := core.ValidateRunID(runID); err != nil {
+			return errors.Wrap(errors.EUsage, "invalid --run-id", err)
+		}
+	} else {
+		runID, err = clock.NewRunID(clock.Now())
+		if err != nil {
+			return errors.Wrap(errors.EInternal, "failed to generate run_id", err)
+		}
+	}
+
+	unlock, err := lock.NewRepoLock(rc.DataDir).Lock(rc.RepoID, "adopt")
+	if err != nil {
+		return repoLockError(err, rc.DataDir, rc.RepoID)
+	}
+	defer func() { _ = unlock() }()
+
+	if err := checkBranchUnmanaged(rc.DataDir, rc.RepoID, opts.Branch, crypter); err != nil {
+		return err
+	}
+	if err := runservice.CheckRunIDAvailable(rc.DataDir, runID); err != nil {
+		return err
+	}
+
+	worktreePath, created, err := adoptWorktree(ctx, cr, rc, opts.Branch, runID, opts.NoWorktree, cfg.Worktrees.MaxPathLength)
+	if err != nil {
+		return err
+	}
+	_, statErr := fsys.Stat(worktreePath)
+	present := statErr == nil
+	if present {
+		if err := worktree.ScaffoldWorkspaceOnly(fsys, worktreePath, title); err != nil {
+			return errors.WrapWithDetails(errors.EInternal, "failed to scaffold workspace", err,
+				map[string]string{"worktree_path": worktreePath})
+		}
+	}
+
+	st := store.NewStore(fsys, rc.DataDir, clock.Now).WithCrypt(crypter)
+	runDir, err := st.EnsureRunDir(rc.RepoID, runID)
+	if err != nil {
+		return err
+	}
+	meta := store.NewRunMeta(runID, rc.RepoID, title, runnerName, runnerCmd, parent, opts.Branch, worktreePath, st.Now())
+	meta.CreatedBy = identity.CurrentUser()
+	meta.Labels = labels
+	if err := st.WriteInitialMeta(rc.RepoID, runID, meta); err != nil {
+		return err
+	}
+	audit.Touch(rc.RepoID, runID)
+
+	_ = events.AppendEvent(events.EventsPath(runDir), events.New(st.Now(), rc.RepoID, runID, "adopted", map[string]any{
+		"branch":           opts.Branch,
+		"worktree_path":    worktreePath,
+		"worktree_created": created,
+	}))
+
+	fmt.Fprintf(stdout, "run_id: %s\n", runID)
+	fmt.Fprintf(stdout, "title: %s\n", title)
+	fmt.Fprintf(stdout, "runner: %s\n", runnerName)
+	fmt.Fprintf(stdout, "parent: %s\n", parent)
+	fmt.Fprintf(stdout, "branch: %s\n", opts.Branch)
+	fmt.Fprintf(stdout, "worktree: %s\n", worktreePath)
+	if !present {
+		fmt.Fprintf(stderr, "warning: no worktree for %s; the run is listed as archived\n", opts.Branch)
+		return nil
+	}
+	fmt.Fprintf(stdout, "next: agency attach --start %s\n", runID)
+	return nil
+}
+
+// checkBranchUnmanaged fails with E_BRANCH_MANAGED if a run in repoID already
+// uses branch.
+func checkBranchUnmanaged(dataDir, repoID, branch string, crypter *crypt.Crypt) error {
+	records, err := store.ScanAllRuns(dataDir, crypter)
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+	for _, rec := range records {
+		if rec.Broken || rec.RepoID != repoID || rec.Meta.Branch != branch {
+			continue
+		}
+		return errors.NewWithDetails(errors.EBranchManaged,
+			"branch "+branch+" already belongs to run "+rec.RunID,
+			map[string]string{"branch": branch, "run_id": rec.RunID})
+	}
+	return nil
+}
+
+// adoptWorktree returns the worktree path for branch: its existing linked
+// worktree, a new one under the data dir, or (noWorktree) the path a worktree
+// would have. created reports whether a worktree was added; a new one is
+// checked with worktree.CheckPath first (maxPathLength 0 = platform default).
+func adoptWorktree(ctx context.Context, cr agencyexec.CommandRunner, rc *repo.RepoContext, branch, runID string, noWorktree bool, maxPathLength int) (path string, created bool, err error) {
+	worktrees, err := git.ListWorktrees(ctx, cr, rc.RepoRoot)
+	if err != nil {
+		return "", false, err
+	}
+	for i, wt := range worktrees {
+		if wt.Branch != branch {
+			continue
+		}
+		// The first entry is the main worktree; archiving would remove it
+		if i == 0 || filepath.Clean(wt.Path) == filepath.Clean(rc.RepoRoot) {
+			return "", false, errors.NewWithDetails(errors.EUsage,
+				"branch "+branch+" is checked out in the main worktree; switch to another branch first",
+				map[string]string{"branch": branch, "repo_root": rc.RepoRoot})
+		}
+		return wt.Path, false, nil
+	}
+
+	path = worktree.WorktreePath(rc.DataDir, rc.RepoID, runID)
+	if noWorktree {
+		return path, false, nil
+	}
+	if err := worktree.CheckPath(ctx, cr, rc.RepoRoot, branch, path, maxPathLength); err != nil {
+		return "", false, err
+	}
+
+	args := []string{"-C", rc.RepoRoot, "worktree", "add", path, branch}
+	res, err := cr.Run(ctx, "git", args, agencyexec.RunOpts{})
+	if err != nil {
+		return "", false, errors.Wrap(errors.EWorktreeCreateFailed, "failed to execute git worktree add", err)
+	}
+	if res.ExitCode != 0 {
+		return "", false, errors.NewWithDetails(errors.EWorktreeCreateFailed,
+			"git worktree add failed: "+strings.TrimSpace(res.Stderr),
+			map[string]string{"command": "git " + strings.Join(args, " "), "stderr": res.Stderr})
+	}
+	return path, true, nil
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestAdopt(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	repoRoot := testkit.NewRepo(t, testkit.RepoOpts{Git: true})
+	testkit.Git(t, repoRoot, "branch", "feature/manual")
+	testkit.Git(t, repoRoot, "branch", "feature/linked")
+	linked := filepath.Join(t.TempDir(), "linked")
+	testkit.Git(t, repoRoot, "worktree", "add", "-q", linked, "feature/linked")
+
+	cr := agencyexec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+
+	adopt := func(opts AdoptOpts) (string, error) {
+		var stdout bytes.Buffer
+		err := Adopt(ctx, cr, fsys, repoRoot, opts, &stdout, io.Discard)
+		return stdout.String(), err
+	}
+
+	// New worktree under the data dir
+	out, err := adopt(AdoptOpts{Branch: "feature/manual", Labels: []string{"origin=manual"}})
+	if err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+	if !strings.Contains(out, "next: agency attach --start ") {
+		t.Errorf("stdout = %q", out)
+	}
+
+	// Existing linked worktree is reused
+	if _, err := adopt(AdoptOpts{Branch: "feature/linked", Title: "linked work", RunID: "linked-1"}); err != nil {
+		t.Fatalf("Adopt linked: %v", err)
+	}
+
+	records, err := store.ScanAllRuns(dataDir, nil)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("ScanAllRuns = %d records, %v", len(records), err)
+	}
+	byBranch := map[string]*store.RunMeta{}
+	for _, rec := range records {
+		byBranch[rec.Meta.Branch] = rec.Meta
+	}
+
+	manual := byBranch["feature/manual"]
+	if manual == nil || manual.Title != "feature/manual" || manual.Runner != "claude" || manual.ParentBranch != "main" || manual.Labels["origin"] != "manual" {
+		t.Fatalf("manual meta = %+v", manual)
+	}
+	if !strings.HasPrefix(manual.WorktreePath, dataDir) {
+		t.Errorf("worktree %q should be under the data dir", manual.WorktreePath)
+	}
+	if _, err := os.Stat(filepath.Join(manual.WorktreePath, ".agency", "report.md")); err != nil {
+		t.Errorf("workspace not scaffolded: %v", err)
+	}
+	if got := strings.TrimSpace(testkit.Git(t, manual.WorktreePath, "rev-parse", "--abbrev-ref", "HEAD")); got != "feature/manual" {
+		t.Errorf("worktree HEAD = %q", got)
+	}
+
+	if l := byBranch["feature/linked"]; l == nil || l.RunID != "linked-1" || l.WorktreePath != linked || l.Title != "linked work" {
+		t.Errorf("linked meta = %+v", l)
+	}
+
+	// Failure modes
+	tests := []struct {
+		opts AdoptOpts
+		want errors.Code
+	}{
+		{AdoptOpts{Branch: "feature/manual"}, errors.EBranchManaged},
+		{AdoptOpts{Branch: "main"}, errors.EUsage},
+		{AdoptOpts{Branch: "does-not-exist"}, errors.EBranchNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := adopt(tt.opts); errors.GetCode(err) != tt.want {
+			t.Errorf("Adopt(%s) = %v, want %s", tt.opts.Branch, err, tt.want)
+		}
+	}
+}
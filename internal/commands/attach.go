@@ -7,14 +7,23 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/credentials"
 	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
 	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/git"
 	"github.com/NielsdaWheelz/agency/internal/identity"
 	"github.com/NielsdaWheelz/agency/internal/paths"
+	"github.com/NielsdaWheelz/agency/internal/render"
 	"github.com/NielsdaWheelz/agency/internal/runservice"
+	"github.com/NielsdaWheelz/agency/internal/status"
 	"github.com/NielsdaWheelz/agency/internal/store"
 )
 
@@ -22,85 +31,520 @@ import (
 type AttachOpts struct {
 	// RunID is the run identifier to attach to.
 	RunID string
+
+	// Branch selects the run by its branch instead of RunID.
+	Branch string
+
+	// PR selects the run by its pull request number instead of RunID.
+	PR int
+
+	// Start creates a new tmux session running meta.runner_cmd when the run
+	// is idle (worktree present, no session), without asking.
+	Start bool
+
+	// Repo selects the run's repo (repo_id, repo_key, or path) instead of cwd.
+	Repo string
+
+	// Window opens a tools shell in the worktree next to the runner (see
+	// openToolsShell), as attach.window in the user config does.
+	Window bool
+
+	// RefreshEnv re-injects the run's current AGENCY_* variables and GitHub
+	// token into a live session (see refreshSessionEnv), as
+	// attach.refresh_env in the user config does.
+	RefreshEnv bool
+
+	// Confirm asks the user whether to start a session for an idle run.
+	// Nil means non-interactive: idle runs fail with E_TMUX_SESSION_MISSING.
+	Confirm func(prompt string) bool
 }
 
+// attachSession is the interactive attach (replaceable in tests).
+var attachSession = attachToTmuxSession
+
 // Attach attaches to an existing tmux session for a run.
-// Requires cwd to be inside the target repo.
+// If the run is idle, a new session is started first when opts.Start is set
+// or the user confirms. Before attaching, the session's status line is set
+// to show the run (see setSessionStatusLine), and with opts.Window or
+// attach.window a tools shell is opened next to the runner. With
+// opts.RefreshEnv or attach.refresh_env, a live session's environment is
+// brought up to date first.
+// Requires cwd to be inside the target repo unless opts.Repo is set.
 func Attach(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts AttachOpts, stdout, stderr io.Writer) error {
-	// Validate run_id provided
-	if opts.RunID == "" {
-		return errors.New(errors.EUsage, "run_id is required")
+	// Validate that exactly one run reference is provided
+	selector := RunSelector{Branch: opts.Branch, PR: opts.PR}
+	if err := checkRunSelector(opts.RunID, selector); err != nil {
+		return err
 	}
 
-	// Find repo root
-	repoRoot, err := git.GetRepoRoot(ctx, cr, cwd)
+	dirs, err := resolveDirs(fsys, cwd)
 	if err != nil {
 		return err
 	}
+	crypter := LoadCrypt(cr, fsys)
+	dataDir := dirs.DataDir
+
+	// Compute repo identity: --repo if given, else the repo containing cwd
+	var repoID, repoRootPath string
+	if opts.Repo != "" {
+		repoID, err = resolveRepoFlag(ctx, cr, dataDir, opts.Repo)
+		if err != nil {
+			return err
+		}
+	} else {
+		repoRoot, err := git.GetRepoRoot(ctx, cr, cwd)
+		if err != nil {
+			return err
+		}
+		originInfo := git.GetOriginInfo(ctx, cr, repoRoot.Path)
+		repoID = identity.DeriveRepoIdentity(repoRoot.Path, originInfo.URL).RepoID
+		repoRootPath = repoRoot.Path
+	}
+
+	// Create store and look up the run
+	st := store.NewStore(fsys, dataDir, clock.Now).WithCrypt(crypter)
+	var meta *store.RunMeta
+	if !selector.IsZero() {
+		records, err := store.ScanRunsForRepo(dataDir, repoID, crypter)
+		if err != nil {
+			return errors.Wrap(errors.EInternal, "failed to scan runs", err)
+		}
+		record, err := resolveRunSelector(records, selector, runScope{RepoID: repoID})
+		if err != nil {
+			return err
+		}
+		meta = record.Meta
+	} else {
+		meta, err = st.ReadMeta(repoID, opts.RunID)
+		if err != nil {
+			// E_RUN_NOT_FOUND is already the right error code from ReadMeta
+			return err
+		}
+	}
 
-	// Get origin info for repo identity
-	originInfo := git.GetOriginInfo(ctx, cr, repoRoot.Path)
+	// Check if the tmux session actually exists (if one was ever started)
+	if meta.TmuxSessionName != "" {
+		hasSessionResult, err := cr.Run(ctx, "tmux", []string{"has-session", "-t", meta.TmuxSessionName}, agencyexec.RunOpts{})
+		if err != nil {
+			return errors.Wrap(errors.ETmuxNotInstalled, "failed to check tmux session", err)
+		}
+		if hasSessionResult.ExitCode == 0 {
+			if wantRefreshEnv(fsys, dirs, opts) {
+				refreshSessionEnv(ctx, cr, fsys, st, meta, repoRootPath, stderr)
+			} else {
+				refreshSessionToken(ctx, cr, fsys, st, meta, stderr)
+			}
+			setSessionStatusLine(ctx, cr, fsys, dirs, meta, meta.TmuxSessionName)
+			openToolsShellIfWanted(ctx, cr, fsys, dirs, opts, meta, meta.TmuxSessionName, repoRootPath, stderr)
+			return attachSession(meta.TmuxSessionName, stdout, stderr)
+		}
+	}
 
-	// Get home directory for path resolution
-	homeDir, err := os.UserHomeDir()
+	// No session: only idle runs (worktree still present) can be restarted
+	missing := sessionMissingError(meta)
+	if _, err := fsys.Stat(meta.WorktreePath); err != nil {
+		return missing
+	}
+	if !opts.Start {
+		if opts.Confirm == nil || !opts.Confirm(fmt.Sprintf("run %s has no tmux session; start %s in a new one?", meta.RunID, meta.RunnerCmd)) {
+			return missing
+		}
+	}
+
+	sessionName, err := startIdleSession(ctx, cr, st, meta)
 	if err != nil {
-		return errors.Wrap(errors.EInternal, "failed to get home directory", err)
+		return err
 	}
+	fmt.Fprintf(stderr, "started tmux session %s (runner: %s)\n", sessionName, meta.RunnerCmd)
 
-	// Resolve data directory
-	dirs := paths.ResolveDirs(osEnv{}, homeDir)
-	dataDir := dirs.DataDir
+	setSessionStatusLine(ctx, cr, fsys, dirs, meta, sessionName)
+	openToolsShellIfWanted(ctx, cr, fsys, dirs, opts, meta, sessionName, repoRootPath, stderr)
+	return attachSession(sessionName, stdout, stderr)
+}
 
-	// Compute repo identity
-	repoIdentity := identity.DeriveRepoIdentity(repoRoot.Path, originInfo.URL)
-	repoID := repoIdentity.RepoID
+// toolsWindowName is the name of the tmux window opened by attach --window.
+const toolsWindowName = "tools"
 
-	// Create store and look up the run
-	st := store.NewStore(fsys, dataDir, nil)
-	meta, err := st.ReadMeta(repoID, opts.RunID)
+// openToolsShellIfWanted opens the tools shell when opts.Window or
+// attach.window asks for one, in the attach.layout of the user config.
+func openToolsShellIfWanted(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, dirs paths.Dirs, opts AttachOpts, meta *store.RunMeta, session, repoRoot string, stderr io.Writer) {
+	cfg, err := config.LoadUserConfig(fsys, dirs.ConfigDir)
+	if err != nil {
+		cfg = config.DefaultUserConfig()
+	}
+	if !opts.Window && !cfg.Attach.Window {
+		return
+	}
+	if err := openToolsShell(ctx, cr, meta, session, cfg.Attach.Layout, repoRoot); err != nil {
+		fmt.Fprintf(stderr, "warning: failed to open tools shell: %v\n", err)
+	}
+}
+
+// openToolsShell opens a shell in the run's worktree with the run's AGENCY_*
+// environment, for manual commands alongside the runner: a "tools" window
+// (layout "window"), or a pane split off the runner's window to its right
+// or below it. The runner stays selected. Nothing is opened if an earlier
+// attach left one: a "tools" window, or a runner window already split.
+func openToolsShell(ctx context.Context, cr agencyexec.CommandRunner, meta *store.RunMeta, session, layout, repoRoot string) error {
+	var args []string
+	switch layout {
+	case "split-right", "split-below":
+		// The runner's window is the session's first one
+		res, err := cr.Run(ctx, "tmux", []string{"list-windows", "-t", session, "-F", "#{window_index} #{window_panes}"}, agencyexec.RunOpts{})
+		if err != nil || res.ExitCode != 0 {
+			return tmuxCommandError("list-windows", res, err)
+		}
+		first, _, _ := strings.Cut(strings.TrimSpace(res.Stdout), "\n")
+		index, panes, _ := strings.Cut(first, " ")
+		if index == "" || panes != "1" {
+			return nil
+		}
+		direction := "-h"
+		if layout == "split-below" {
+			direction = "-v"
+		}
+		args = []string{"split-window", "-d", direction, "-t", session + ":" + index}
+	default:
+		res, err := cr.Run(ctx, "tmux", []string{"list-windows", "-t", session, "-F", "#{window_name}"}, agencyexec.RunOpts{})
+		if err != nil || res.ExitCode != 0 {
+			return tmuxCommandError("list-windows", res, err)
+		}
+		for _, name := range strings.Split(res.Stdout, "\n") {
+			if strings.TrimSpace(name) == toolsWindowName {
+				return nil
+			}
+		}
+		args = []string{"new-window", "-d", "-t", session + ":", "-n", toolsWindowName}
+	}
+
+	args = append(args, "-c", meta.WorktreePath)
+	for _, kv := range toolsShellEnv(meta, repoRoot) {
+		args = append(args, "-e", kv)
+	}
+	res, err := cr.Run(ctx, "tmux", args, agencyexec.RunOpts{})
+	if err != nil || res.ExitCode != 0 {
+		return tmuxCommandError(args[0], res, err)
+	}
+	return nil
+}
+
+// toolsShellEnv returns the AGENCY_* variables of the tools shell as
+// sorted KEY=value pairs; unknown values (e.g. the repo root with --repo,
+// or the PR before one is opened) are left out.
+func toolsShellEnv(meta *store.RunMeta, repoRoot string) []string {
+	env := map[string]string{
+		"AGENCY_RUN_ID":         meta.RunID,
+		"AGENCY_TITLE":          meta.Title,
+		"AGENCY_REPO_ROOT":      repoRoot,
+		"AGENCY_WORKSPACE_ROOT": meta.WorktreePath,
+		"AGENCY_BRANCH":         meta.Branch,
+		"AGENCY_PARENT_BRANCH":  meta.ParentBranch,
+		"AGENCY_RUNNER":         meta.Runner,
+		"AGENCY_PR_URL":         meta.PRURL,
+	}
+	if meta.PRNumber > 0 {
+		env["AGENCY_PR_NUMBER"] = strconv.Itoa(meta.PRNumber)
+	}
+	pairs := make([]string, 0, len(env))
+	for k, v := range env {
+		if v != "" {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// tmuxCommandError describes a failed tmux command.
+func tmuxCommandError(command string, res agencyexec.CmdResult, err error) error {
 	if err != nil {
-		// E_RUN_NOT_FOUND is already the right error code from ReadMeta
 		return err
 	}
+	return fmt.Errorf("tmux %s exited %d: %s", command, res.ExitCode, strings.TrimSpace(res.Stderr))
+}
+
+// setSessionStatusLine shows the run (attach.status_format in the user
+// config) in status-right of its tmux session, so it is obvious which run a
+// window belongs to. The option is set on the session only: it never leaks
+// into other sessions and goes away when the session ends. With
+// attach.status off, a segment left by an earlier attach is removed.
+// Failures are ignored; the status line is cosmetic.
+func setSessionStatusLine(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, dirs paths.Dirs, meta *store.RunMeta, session string) {
+	cfg, err := config.LoadUserConfig(fsys, dirs.ConfigDir)
+	if err != nil {
+		return
+	}
+	if !cfg.Attach.Status {
+		for _, option := range []string{"status-right", "status-right-length"} {
+			cr.Run(ctx, "tmux", []string{"set-option", "-u", "-t", session, option}, agencyexec.RunOpts{})
+		}
+		return
+	}
+
+	format := cfg.Attach.StatusFormat
+	if format == "" {
+		format = core.DefaultStatusLineFormat
+	}
+	vars := map[string]string{
+		"run_id": meta.RunID,
+		"title":  meta.Title,
+		"status": render.StatusVocabulary{Statuses: cfg.Statuses}.Label(attachDerivedStatus(ctx, cr, fsys, dirs, meta)),
+		"branch": meta.Branch,
+		"runner": meta.Runner,
+	}
+	if meta.PRNumber > 0 {
+		vars["pr"] = "#" + strconv.Itoa(meta.PRNumber)
+	}
+	text, err := core.FormatStatusLine(format, vars)
+	if err != nil {
+		return
+	}
+
+	// "#" starts a tmux format; the run's title must be shown as typed
+	text = strings.ReplaceAll(text, "#", "##")
+	length := len(text) + 1
+	if length < 40 {
+		length = 40 // the tmux default
+	}
+	cr.Run(ctx, "tmux", []string{"set-option", "-t", session, "status-right", text}, agencyexec.RunOpts{})
+	cr.Run(ctx, "tmux", []string{"set-option", "-t", session, "status-right-length", strconv.Itoa(length)}, agencyexec.RunOpts{})
+}
+
+// attachDerivedStatus derives the status of a run with a live session, as
+// agency show would.
+func attachDerivedStatus(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, dirs paths.Dirs, meta *store.RunMeta) string {
+	rec := store.RunRecord{RepoID: meta.RepoID, RunID: meta.RunID, Meta: meta}
+	report := readReportSnapshot(ctx, cr, meta, true)
+	snapshot := status.Snapshot{
+		TmuxActive:       true,
+		WorktreePresent:  true,
+		ReportBytes:      report.Bytes,
+		ReportStale:      report.Stale,
+		ReportReadyFlag:  report.ReadyFlag,
+		Policy:           newReviewPolicySet(fsys, dirs.DataDir).Get(rec),
+		DeadlineExceeded: meta.DeadlineExceeded(clock.Now()),
+	}
+	snapshot.NoCommits = noCommits(newCommitCountSet(ctx, cr, fsys, dirs.CacheDir), rec, report.Bytes, snapshot.Policy)
+	return status.Derive(meta, snapshot).DerivedStatus
+}
+
+// sessionMissingError is returned when a run has no live tmux session.
+func sessionMissingError(meta *store.RunMeta) error {
+	msg := "tmux session not found for this run"
+	details := map[string]string{
+		"run_id":        meta.RunID,
+		"worktree_path": meta.WorktreePath,
+		"runner_cmd":    meta.RunnerCmd,
+	}
+	if meta.TmuxSessionName != "" {
+		// Session was killed, system restarted, etc.
+		msg = "tmux session '" + meta.TmuxSessionName + "' does not exist"
+		details["session"] = meta.TmuxSessionName
+	}
+	return errors.WithHints(errors.NewWithDetails(errors.ETmuxSessionMissing, msg, details),
+		"start it in a new tmux session: agency attach --start "+meta.RunID,
+		fmt.Sprintf("or start the runner manually: cd %q && %s", meta.WorktreePath, meta.RunnerCmd))
+}
+
+// startIdleSession starts meta.runner_cmd in a new detached tmux session,
+// records the session name in meta.json and appends a session_started event.
+// Returns the session name.
+func startIdleSession(ctx context.Context, cr agencyexec.CommandRunner, st *store.Store, meta *store.RunMeta) (string, error) {
+	if meta.RunnerCmd == "" {
+		return "", errors.NewWithDetails(errors.ERunnerNotConfigured, "run has no runner_cmd in meta.json",
+			map[string]string{"run_id": meta.RunID})
+	}
+
+	sessionName := meta.TmuxSessionName
+	if sessionName == "" {
+		sessionName = TmuxSessionPrefix + meta.RunID
+	}
+
+	tokenPath, err := runservice.RefreshGitHubToken(ctx, cr, st.FS, st, meta, st.Now())
+	if err != nil {
+		return "", err
+	}
+
+	if err := runservice.StartRunnerSession(ctx, cr, sessionName, meta.WorktreePath, meta.RunnerCmd, tokenPath); err != nil {
+		return "", err
+	}
+
+	err = st.UpdateMeta(meta.RepoID, meta.RunID, func(m *store.RunMeta) {
+		m.TmuxSessionName = sessionName
+		if m.Flags != nil {
+			m.Flags.TmuxFailed = false
+		}
+	})
+	if err != nil {
+		// Best effort: don't leave an untracked session behind
+		cr.Run(ctx, "tmux", []string{"kill-session", "-t", sessionName}, agencyexec.RunOpts{})
+		return "", err
+	}
+
+	_ = events.AppendEvent(events.EventsPath(st.RunDir(meta.RepoID, meta.RunID)), events.New(st.Now(), meta.RepoID, meta.RunID, "session_started", map[string]any{
+		"session":    sessionName,
+		"runner_cmd": meta.RunnerCmd,
+		"source":     "attach",
+	}))
+	runservice.ScheduleDeadlineKill(ctx, cr, sessionName, meta, st.Now())
+
+	return sessionName, nil
+}
+
+// refreshSessionToken replaces an expiring GitHub token of a live session
+// (meta.credentials): the token file is rewritten and GITHUB_TOKEN/GH_TOKEN
+// are updated in the session environment, which new panes and windows pick
+// up. The running runner keeps its old environment. Failures are warnings.
+func refreshSessionToken(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, st *store.Store, meta *store.RunMeta, stderr io.Writer) {
+	if meta.Credentials == nil || !credentials.NeedsRefresh(fsys, meta.Credentials, st.Now()) {
+		return
+	}
+	tokenPath, err := runservice.RefreshGitHubToken(ctx, cr, fsys, st, meta, st.Now())
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: failed to refresh GITHUB_TOKEN: %v\n", err)
+		return
+	}
+	token, err := fsys.ReadFile(tokenPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: failed to read GITHUB_TOKEN: %v\n", err)
+		return
+	}
+	value := strings.TrimSpace(string(token))
+	if err := setSessionEnv(ctx, cr, meta.TmuxSessionName, map[string]string{"GITHUB_TOKEN": value, "GH_TOKEN": value}); err != nil {
+		fmt.Fprintf(stderr, "warning: failed to set GITHUB_TOKEN, GH_TOKEN in session %s: %v\n", meta.TmuxSessionName, err)
+		return
+	}
+	msg := "refreshed GITHUB_TOKEN (" + meta.Credentials.Mode
+	if meta.Credentials.ExpiresAt != "" {
+		msg += ", expires " + meta.Credentials.ExpiresAt
+	}
+	fmt.Fprintln(stderr, msg+")")
+}
 
-	// Verify tmux_session_name is set
-	if meta.TmuxSessionName == "" {
-		// Run exists but no tmux session was ever started (setup failed or tmux failed)
-		return errors.NewWithDetails(
-			errors.ETmuxSessionMissing,
-			"tmux session not found for this run",
-			map[string]string{
-				"run_id":        opts.RunID,
-				"worktree_path": meta.WorktreePath,
-				"runner_cmd":    meta.RunnerCmd,
-				"hint":          fmt.Sprintf("cd %q && %s", meta.WorktreePath, meta.RunnerCmd),
-			},
-		)
+// wantRefreshEnv reports whether opts.RefreshEnv or attach.refresh_env asks
+// for the session environment to be refreshed.
+func wantRefreshEnv(fsys fs.FS, dirs paths.Dirs, opts AttachOpts) bool {
+	if opts.RefreshEnv {
+		return true
 	}
+	cfg, err := config.LoadUserConfig(fsys, dirs.ConfigDir)
+	return err == nil && cfg.Attach.RefreshEnv
+}
 
-	// Check if tmux session actually exists
-	hasSessionResult, err := cr.Run(ctx, "tmux", []string{"has-session", "-t", meta.TmuxSessionName}, agencyexec.RunOpts{})
+// refreshSessionEnv brings a live session's environment up to date: the
+// run's AGENCY_* variables (as in the tools shell, which may have changed
+// since the session started, e.g. the PR) and, with meta.credentials, the
+// GitHub token as GITHUB_TOKEN/GH_TOKEN, minted anew first if it expires
+// soon. Only variables that differ from `tmux show-environment` are set.
+// New panes and windows pick them up; the running runner keeps its old
+// environment until its pane is respawned, which is printed as a hint.
+// Failures are warnings.
+func refreshSessionEnv(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, st *store.Store, meta *store.RunMeta, repoRoot string, stderr io.Writer) {
+	session := meta.TmuxSessionName
+	want := map[string]string{}
+	for _, kv := range toolsShellEnv(meta, repoRoot) {
+		k, v, _ := strings.Cut(kv, "=")
+		want[k] = v
+	}
+	if meta.Credentials != nil {
+		tokenPath, err := runservice.RefreshGitHubToken(ctx, cr, fsys, st, meta, st.Now())
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: failed to refresh GITHUB_TOKEN: %v\n", err)
+		} else if token, err := fsys.ReadFile(tokenPath); err == nil {
+			want["GITHUB_TOKEN"] = strings.TrimSpace(string(token))
+			want["GH_TOKEN"] = want["GITHUB_TOKEN"]
+		}
+	}
+
+	current := sessionEnvironment(ctx, cr, session)
+	set := map[string]string{}
+	var changed []string
+	for name, value := range want {
+		if have, ok := current[name]; ok && have == value {
+			continue
+		}
+		set[name] = value
+		changed = append(changed, name)
+	}
+	if len(changed) == 0 {
+		fmt.Fprintln(stderr, "session environment is up to date")
+		return
+	}
+	sort.Strings(changed)
+	if err := setSessionEnv(ctx, cr, session, set); err != nil {
+		fmt.Fprintf(stderr, "warning: failed to set %s in session %s: %v\n", strings.Join(changed, ", "), session, err)
+		return
+	}
+
+	// Values are left out: they include the token
+	fmt.Fprintf(stderr, "refreshed session environment: %s\n", strings.Join(changed, ", "))
+	fmt.Fprintf(stderr, "hint: new panes and windows get it; the runner keeps its old environment until restarted (tmux respawn-pane -k -t %s)\n", session)
+	_ = events.AppendEvent(events.EventsPath(st.RunDir(meta.RepoID, meta.RunID)), events.New(st.Now(), meta.RepoID, meta.RunID, "session_env_refreshed", map[string]any{
+		"session": session,
+		"vars":    changed,
+	}))
+}
+
+// setSessionEnv sets vars in a tmux session's environment. The values
+// (the GitHub token among them) are written to a private temp file of tmux
+// commands that `tmux source-file` reads, so they never appear in argv.
+func setSessionEnv(ctx context.Context, cr agencyexec.CommandRunner, session string, vars map[string]string) error {
+	f, err := os.CreateTemp("", "agency-env-*.tmux")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = io.WriteString(f, sessionEnvCommands(session, vars))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return errors.Wrap(errors.ETmuxNotInstalled, "failed to check tmux session", err)
-	}
-	if hasSessionResult.ExitCode != 0 {
-		// Session doesn't exist (was killed, system restarted, etc.)
-		return errors.NewWithDetails(
-			errors.ETmuxSessionMissing,
-			"tmux session '"+meta.TmuxSessionName+"' does not exist",
-			map[string]string{
-				"run_id":        opts.RunID,
-				"session":       meta.TmuxSessionName,
-				"worktree_path": meta.WorktreePath,
-				"runner_cmd":    meta.RunnerCmd,
-				"hint":          fmt.Sprintf("cd %q && %s", meta.WorktreePath, meta.RunnerCmd),
-			},
-		)
-	}
-
-	// Attach to the tmux session
-	// We need to use exec.Command directly for interactive attach
-	return attachToTmuxSession(meta.TmuxSessionName, stdout, stderr)
+		return err
+	}
+	res, err := cr.Run(ctx, "tmux", []string{"source-file", f.Name()}, agencyexec.RunOpts{})
+	if err != nil || res.ExitCode != 0 {
+		return tmuxCommandError("source-file", res, err)
+	}
+	return nil
+}
+
+// sessionEnvCommands returns the tmux commands setting vars in session, one
+// set-environment per line, sorted by name.
+func sessionEnvCommands(session string, vars map[string]string) string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "set-environment -t %s %s %s\n", tmuxQuote(session), tmuxQuote(name), tmuxQuote(vars[name]))
+	}
+	return b.String()
+}
+
+// tmuxQuote double-quotes s for a tmux command file, escaping the
+// characters tmux interprets inside double quotes.
+func tmuxQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, `~`, `\~`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// sessionEnvironment returns the variables set in a tmux session's
+// environment (`tmux show-environment`); removed ("-NAME") entries are left
+// out. Returns an empty map if tmux fails.
+func sessionEnvironment(ctx context.Context, cr agencyexec.CommandRunner, session string) map[string]string {
+	env := map[string]string{}
+	res, err := cr.Run(ctx, "tmux", []string{"show-environment", "-t", session}, agencyexec.RunOpts{})
+	if err != nil || res.ExitCode != 0 {
+		return env
+	}
+	for _, line := range strings.Split(res.Stdout, "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(k, "-") {
+			env[k] = v
+		}
+	}
+	return env
 }
 
 // attachToTmuxSession attaches to a tmux session interactively.
@@ -0,0 +1,345 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/identity"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// setupAttachTest writes an idle run (worktree present, no live session) and
+// stubs the interactive attach. Returns the runner, store, meta and a pointer
+// to the attached session name.
+func setupAttachTest(t *testing.T) (*testkit.FakeRunner, *store.Store, *store.RunMeta, *string) {
+	t.Helper()
+	dataDir := testkit.DataDir(t)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	repoRoot := testkit.NewRepo(t, testkit.RepoOpts{})
+	repoID := identity.DeriveRepoIdentity(repoRoot, "").RepoID
+
+	runID := "20260110120000-a3f2"
+	worktree := filepath.Join(dataDir, "repos", repoID, "worktrees", runID)
+	if err := os.MkdirAll(worktree, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	meta := testkit.NewRunMeta(repoID, runID, worktree, time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	meta.Flags = &store.RunMetaFlags{TmuxFailed: true}
+	testkit.WriteRun(t, dataDir, meta)
+
+	cr := testkit.NewFakeRunner()
+	cr.AllToolsOK(repoRoot, "")
+	cr.TmuxNoServer()
+	cr.OnPrefix("tmux", "new-session")
+
+	attached := new(string)
+	orig := attachSession
+	attachSession = func(name string, _, _ io.Writer) error {
+		*attached = name
+		return nil
+	}
+	t.Cleanup(func() { attachSession = orig })
+
+	return cr, store.NewStore(fs.NewRealFS(), dataDir, time.Now), meta, attached
+}
+
+func TestAttach_IdleWithoutStart(t *testing.T) {
+	cr, _, meta, attached := setupAttachTest(t)
+
+	err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, AttachOpts{RunID: meta.RunID}, io.Discard, io.Discard)
+	if errors.GetCode(err) != errors.ETmuxSessionMissing {
+		t.Fatalf("expected E_TMUX_SESSION_MISSING, got %v", err)
+	}
+	if cr.Called("tmux", "new-session") || len(cr.CallsTo("tmux")) > 1 || *attached != "" {
+		t.Errorf("non-interactive attach without --start must not start a session: %v", cr.CallsTo("tmux"))
+	}
+}
+
+func TestAttach_IdleDeclined(t *testing.T) {
+	cr, _, meta, _ := setupAttachTest(t)
+
+	var prompt string
+	opts := AttachOpts{RunID: meta.RunID, Confirm: func(p string) bool { prompt = p; return false }}
+	err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, opts, io.Discard, io.Discard)
+	if errors.GetCode(err) != errors.ETmuxSessionMissing {
+		t.Fatalf("expected E_TMUX_SESSION_MISSING, got %v", err)
+	}
+	if !strings.Contains(prompt, meta.RunnerCmd) {
+		t.Errorf("prompt should name the runner: %q", prompt)
+	}
+}
+
+func TestAttach_IdleStart(t *testing.T) {
+	cr, st, meta, attached := setupAttachTest(t)
+
+	opts := AttachOpts{RunID: meta.RunID, Start: true}
+	if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, opts, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+
+	var newSession testkit.Call
+	for _, c := range cr.CallsTo("tmux") {
+		if c.Args[0] == "new-session" {
+			newSession = c
+		}
+	}
+	if newSession.Args == nil || newSession.Args[3] != meta.TmuxSessionName {
+		t.Fatalf("expected new-session for %s, got %v", meta.TmuxSessionName, cr.CallsTo("tmux"))
+	}
+	if !strings.Contains(newSession.Args[len(newSession.Args)-1], meta.RunnerCmd) {
+		t.Errorf("pane command should run %q: %v", meta.RunnerCmd, newSession.Args)
+	}
+	if *attached != meta.TmuxSessionName {
+		t.Errorf("attached to %q, want %q", *attached, meta.TmuxSessionName)
+	}
+
+	got, err := st.ReadMeta(meta.RepoID, meta.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TmuxSessionName != meta.TmuxSessionName || got.Flags.TmuxFailed {
+		t.Errorf("meta not updated: session=%q flags=%+v", got.TmuxSessionName, got.Flags)
+	}
+	evs, err := os.ReadFile(filepath.Join(st.RunDir(meta.RepoID, meta.RunID), "events.jsonl"))
+	if err != nil || !strings.Contains(string(evs), `"event":"session_started"`) {
+		t.Errorf("expected session_started event, got %q (%v)", evs, err)
+	}
+}
+
+func TestAttach_ArchivedNeverStarts(t *testing.T) {
+	cr, _, meta, _ := setupAttachTest(t)
+	if err := os.RemoveAll(meta.WorktreePath); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := AttachOpts{RunID: meta.RunID, Start: true}
+	err := Attach(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, io.Discard, io.Discard)
+	if errors.GetCode(err) != errors.ETmuxSessionMissing {
+		t.Fatalf("expected E_TMUX_SESSION_MISSING, got %v", err)
+	}
+	if cr.Called("tmux", "new-session") {
+		t.Error("archived run must not get a new session")
+	}
+}
+
+func TestAttach_LiveSession(t *testing.T) {
+	cr, _, meta, attached := setupAttachTest(t)
+	cr.TmuxSessions(meta.TmuxSessionName)
+
+	if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, AttachOpts{RunID: meta.RunID}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if *attached != meta.TmuxSessionName {
+		t.Errorf("attached to %q", *attached)
+	}
+}
+
+func TestAttach_StatusLine(t *testing.T) {
+	cr, st, meta, _ := setupAttachTest(t)
+	cr.TmuxSessions(meta.TmuxSessionName)
+	if err := st.UpdateMeta(meta.RepoID, meta.RunID, func(m *store.RunMeta) { m.PRNumber = 42; m.Title = "fix #1" }); err != nil {
+		t.Fatal(err)
+	}
+
+	statusRight := func() []string {
+		var got []string
+		for _, c := range cr.CallsTo("tmux") {
+			if c.Args[0] == "set-option" && c.Args[len(c.Args)-2] == "status-right" {
+				got = append(got, strings.Join(c.Args, " "))
+			}
+		}
+		return got
+	}
+
+	if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, AttachOpts{RunID: meta.RunID}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	want := "set-option -t " + meta.TmuxSessionName + " status-right " + meta.RunID + " fix ##1 [active (pr)] ##42"
+	if got := statusRight(); len(got) != 1 || got[0] != want {
+		t.Errorf("status-right calls = %q, want [%q]", got, want)
+	}
+
+	configDir := os.Getenv("AGENCY_CONFIG_DIR")
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"attach": {"status": false}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, AttachOpts{RunID: meta.RunID}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if !cr.Called("tmux", "set-option", "-u", "-t", meta.TmuxSessionName, "status-right") {
+		t.Errorf("attach.status=false should unset status-right: %v", cr.CallsTo("tmux"))
+	}
+}
+
+func TestAttach_ToolsWindow(t *testing.T) {
+	cr, _, meta, attached := setupAttachTest(t)
+	cr.TmuxSessions(meta.TmuxSessionName)
+	cr.On("tmux", "list-windows", "-t", meta.TmuxSessionName, "-F", "#{window_name}").Stdout("runner\n")
+	cr.On("tmux", "list-windows", "-t", meta.TmuxSessionName, "-F", "#{window_name}").Stdout("runner\ntools\n")
+	cr.OnPrefix("tmux", "new-window")
+
+	opts := AttachOpts{RunID: meta.RunID, Window: true}
+	for i := 0; i < 2; i++ {
+		if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, opts, io.Discard, io.Discard); err != nil {
+			t.Fatalf("Attach: %v", err)
+		}
+	}
+	if *attached != meta.TmuxSessionName {
+		t.Errorf("attached to %q", *attached)
+	}
+
+	var newWindows []string
+	for _, c := range cr.CallsTo("tmux") {
+		if c.Args[0] == "new-window" {
+			newWindows = append(newWindows, strings.Join(c.Args, " "))
+		}
+	}
+	if len(newWindows) != 1 {
+		t.Fatalf("new-window calls = %q, want one (the second attach reuses it)", newWindows)
+	}
+	for _, want := range []string{
+		"new-window -d -t " + meta.TmuxSessionName + ": -n tools -c " + meta.WorktreePath,
+		"-e AGENCY_BRANCH=" + meta.Branch,
+		"-e AGENCY_RUN_ID=" + meta.RunID,
+		"-e AGENCY_WORKSPACE_ROOT=" + meta.WorktreePath,
+	} {
+		if !strings.Contains(newWindows[0], want) {
+			t.Errorf("new-window = %q, missing %q", newWindows[0], want)
+		}
+	}
+}
+
+func TestAttach_ToolsSplitFromConfig(t *testing.T) {
+	cr, _, meta, _ := setupAttachTest(t)
+	cr.TmuxSessions(meta.TmuxSessionName)
+	cr.On("tmux", "list-windows", "-t", meta.TmuxSessionName, "-F", "#{window_index} #{window_panes}").Stdout("1 1\n2 1\n")
+	cr.OnPrefix("tmux", "split-window")
+
+	configDir := os.Getenv("AGENCY_CONFIG_DIR")
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"attach": {"window": true, "layout": "split-below"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, AttachOpts{RunID: meta.RunID}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	want := "tmux split-window -d -v -t " + meta.TmuxSessionName + ":1 -c " + meta.WorktreePath + " -e "
+	var calls []string
+	for _, c := range cr.CallsTo("tmux") {
+		calls = append(calls, c.String())
+		if strings.HasPrefix(c.String(), want) {
+			return
+		}
+	}
+	t.Errorf("expected a split below the runner window, got %q", calls)
+}
+
+func TestAttach_RefreshEnv(t *testing.T) {
+	cr, _, meta, _ := setupAttachTest(t)
+	cr.TmuxSessions(meta.TmuxSessionName)
+	cr.On("tmux", "show-environment", "-t", meta.TmuxSessionName).
+		Stdout("-AGENCY_PR_URL\nAGENCY_RUN_ID=" + meta.RunID + "\nAGENCY_BRANCH=old-branch\nHOME=/home/u\n")
+	cr.OnPrefix("tmux", "source-file")
+
+	setEnv := func() []string {
+		var got []string
+		for _, c := range cr.CallsTo("tmux") {
+			if c.Args[0] == "source-file" {
+				got = append(got, c.String())
+			}
+			if c.Args[0] == "set-environment" {
+				t.Errorf("values passed in argv: %s", c.String())
+			}
+		}
+		return got
+	}
+
+	// Off by default
+	if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, AttachOpts{RunID: meta.RunID}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if got := setEnv(); len(got) != 0 {
+		t.Fatalf("environment set without --refresh-env: %q", got)
+	}
+
+	var stderr strings.Builder
+	if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, AttachOpts{RunID: meta.RunID, RefreshEnv: true}, io.Discard, &stderr); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if got := setEnv(); len(got) != 1 {
+		t.Errorf("source-file calls = %q, want 1", got)
+	}
+	if got := stderr.String(); !strings.Contains(got, "refreshed session environment: AGENCY_BRANCH, ") || !strings.Contains(got, "AGENCY_WORKSPACE_ROOT") || strings.Contains(got, "AGENCY_RUN_ID") {
+		t.Errorf("stderr = %q, want changed AGENCY_* only", got)
+	}
+	if !strings.Contains(stderr.String(), "tmux respawn-pane -k -t "+meta.TmuxSessionName) {
+		t.Errorf("stderr missing respawn hint:\n%s", stderr.String())
+	}
+
+	configDir := os.Getenv("AGENCY_CONFIG_DIR")
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"attach": {"refresh_env": true}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	before := len(setEnv())
+	if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, AttachOpts{RunID: meta.RunID}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if len(setEnv()) == before {
+		t.Error("attach.refresh_env true should refresh the environment")
+	}
+}
+
+func TestAttach_ByBranchAndPR(t *testing.T) {
+	cr, st, meta, attached := setupAttachTest(t)
+	cr.TmuxSessions(meta.TmuxSessionName)
+	if err := st.UpdateMeta(meta.RepoID, meta.RunID, func(m *store.RunMeta) { m.PRNumber = 42 }); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []AttachOpts{{Branch: meta.Branch}, {PR: 42}} {
+		*attached = ""
+		if err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, opts, io.Discard, io.Discard); err != nil {
+			t.Fatalf("Attach(%+v): %v", opts, err)
+		}
+		if *attached != meta.TmuxSessionName {
+			t.Errorf("Attach(%+v) attached to %q", opts, *attached)
+		}
+	}
+
+	err := Attach(context.Background(), cr, fs.NewRealFS(), meta.WorktreePath, AttachOpts{PR: 43}, io.Discard, io.Discard)
+	if errors.GetCode(err) != errors.ERunNotFound {
+		t.Errorf("--pr 43 code = %q, want %q", errors.GetCode(err), errors.ERunNotFound)
+	}
+}
+
+func TestSetSessionEnv(t *testing.T) {
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	session := "agency_envtest"
+	if err := exec.Command("tmux", "new-session", "-d", "-s", session, "sleep", "60").Run(); err != nil {
+		t.Skip("tmux not available, skipping test")
+	}
+	defer exec.Command("tmux", "kill-server").Run()
+
+	vars := map[string]string{
+		"GITHUB_TOKEN": `ghs_"quoted" $HOME \back`,
+		"AGENCY_TITLE": "~/title #{session_name};\tx",
+	}
+	if err := setSessionEnv(context.Background(), agencyexec.NewRealRunner(), session, vars); err != nil {
+		t.Fatalf("setSessionEnv() error = %v", err)
+	}
+	got := sessionEnvironment(context.Background(), agencyexec.NewRealRunner(), session)
+	for name, want := range vars {
+		if got[name] != want {
+			t.Errorf("%s = %q, want %q", name, got[name], want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// AuditOpts holds options for the audit command.
+type AuditOpts struct {
+	// RunID limits the output to commands that touched a run with this
+	// run_id or run_id prefix. The run may since have been deleted.
+	RunID string
+
+	// Since limits the output to commands in this window (0 = no limit).
+	Since time.Duration
+
+	// JSON prints the matching audit.jsonl lines instead of a table.
+	JSON bool
+}
+
+// RecordAudit appends e to the audit log of the data dir cwd resolves to.
+// The cli calls it after each command that changed state.
+func RecordAudit(fsys fs.FS, cwd string, e audit.Entry) error {
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	return audit.Append(dirs.DataDir, e)
+}
+
+// Audit prints the audit log of state-changing commands, oldest first.
+// It is read-only and does not take the repo lock.
+func Audit(fsys fs.FS, cwd string, opts AuditOpts, stdout, stderr io.Writer) error {
+	if opts.Since < 0 {
+		return errors.New(errors.EUsage, "--since must not be negative")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+
+	entries, err := audit.Read(dirs.DataDir)
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to read audit log", err)
+	}
+
+	var since time.Time
+	if opts.Since > 0 {
+		since = clock.Now().Add(-opts.Since)
+	}
+	for _, e := range entries {
+		if !since.IsZero() {
+			ts, err := time.Parse(time.RFC3339, e.Timestamp)
+			if err != nil || ts.Before(since) {
+				continue
+			}
+		}
+		if opts.RunID != "" && !touchesRun(e, opts.RunID) {
+			continue
+		}
+
+		if opts.JSON {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return errors.Wrap(errors.EInternal, "failed to encode audit entry", err)
+			}
+			fmt.Fprintln(stdout, string(data))
+			continue
+		}
+		fmt.Fprintln(stdout, formatAuditEntry(e))
+	}
+	return nil
+}
+
+// touchesRun reports whether e touched a run whose run_id starts with prefix.
+func touchesRun(e audit.Entry, prefix string) bool {
+	for _, r := range e.Runs {
+		if strings.HasPrefix(r.RunID, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatAuditEntry renders one entry as a text line:
+// time, user@host, outcome (or error code), duration, argv, touched runs.
+func formatAuditEntry(e audit.Entry) string {
+	who := e.User
+	if who == "" {
+		who = "-"
+	}
+	if e.Host != "" {
+		who += "@" + e.Host
+	}
+	outcome := e.Outcome
+	if e.ErrorCode != "" {
+		outcome = e.ErrorCode
+	}
+	duration := (time.Duration(e.DurationMS) * time.Millisecond).Round(100 * time.Millisecond)
+	line := fmt.Sprintf("%s  %s  %s  %s  %s", e.Timestamp, who, outcome, duration, strings.Join(e.Argv, " "))
+	if len(e.Runs) > 0 {
+		ids := make([]string, len(e.Runs))
+		for i, r := range e.Runs {
+			ids[i] = r.RunID
+		}
+		line += "  runs=" + strings.Join(ids, ",")
+	}
+	return line
+}
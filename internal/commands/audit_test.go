@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestAudit(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	defer SetClock(testkit.NewClock(time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)).Core())()
+
+	cwd := t.TempDir()
+	for _, e := range []audit.Entry{
+		{Timestamp: "2026-01-01T09:00:00Z", Command: "run", Argv: []string{"agency", "run", "--title", "old"},
+			User: "alice", Host: "devbox", Outcome: audit.OutcomeOK, DurationMS: 2340,
+			Runs: []audit.Run{{RepoID: "r1", RunID: "20260101090000-aaaa"}}},
+		{Timestamp: "2026-01-10T11:00:00Z", Command: "gc", Argv: []string{"agency", "gc", "--auto"},
+			User: "bob", Outcome: audit.OutcomeOK, DurationMS: 120,
+			Runs: []audit.Run{{RepoID: "r1", RunID: "20260101090000-aaaa"}, {RepoID: "r1", RunID: "20260102090000-bbbb"}}},
+		{Timestamp: "2026-01-10T11:30:00Z", Command: "note", Argv: []string{"agency", "note", "nope", "x"},
+			User: "alice", Outcome: audit.OutcomeError, ErrorCode: "E_RUN_NOT_FOUND", DurationMS: 10},
+	} {
+		if err := RecordAudit(fs.NewRealFS(), cwd, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout bytes.Buffer
+	if err := Audit(fs.NewRealFS(), cwd, AuditOpts{}, &stdout, io.Discard); err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Audit() lines = %q, want 3", lines)
+	}
+	if want := "2026-01-01T09:00:00Z  alice@devbox  ok  2.3s  agency run --title old  runs=20260101090000-aaaa"; lines[0] != want {
+		t.Errorf("line 0 = %q, want %q", lines[0], want)
+	}
+	if !strings.Contains(lines[2], "alice  E_RUN_NOT_FOUND") {
+		t.Errorf("line 2 = %q, want the error code", lines[2])
+	}
+
+	// --run matches by prefix, --since by timestamp
+	stdout.Reset()
+	if err := Audit(fs.NewRealFS(), cwd, AuditOpts{RunID: "20260102", Since: 2 * time.Hour, JSON: true}, &stdout, io.Discard); err != nil {
+		t.Fatalf("Audit(--run --since) error = %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"command":"gc"`) {
+		t.Errorf("Audit(--run --since --json) = %q, want the gc entry", lines)
+	}
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/git"
+	"github.com/NielsdaWheelz/agency/internal/scaffold"
+)
+
+// BranchGuardOpts holds options for the branch-guard command.
+type BranchGuardOpts struct {
+	// Block installs the hook in block mode instead of warn mode.
+	Block bool
+	// Uninstall removes the hook instead of installing it.
+	Uninstall bool
+	// Status only reports whether the hook is installed.
+	Status bool
+}
+
+// BranchGuard implements the `agency branch-guard` command.
+// Installs (or removes) a post-checkout hook in the repo that warns, or with
+// --block switches back, when an agency/* branch is checked out in the main
+// working copy instead of its run's worktree. Never overwrites a
+// post-checkout hook agency did not install.
+func BranchGuard(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string, opts BranchGuardOpts, stdout, stderr io.Writer) error {
+	repoRoot, err := git.GetRepoRoot(ctx, cr, cwd)
+	if err != nil {
+		return err
+	}
+	hooksDir, err := git.HooksDir(ctx, cr, repoRoot.Path)
+	if err != nil {
+		return err
+	}
+	hookPath := filepath.Join(hooksDir, "post-checkout")
+
+	installed, foreign, err := readBranchGuardHook(fsys, hookPath)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case opts.Status:
+		mode := installed
+		if mode == "" {
+			mode = "not_installed"
+		}
+		fmt.Fprintf(stdout, "branch_guard: %s\n", mode)
+		fmt.Fprintf(stdout, "hook: %s\n", hookPath)
+		return nil
+
+	case opts.Uninstall:
+		if foreign {
+			return foreignHookError(hookPath)
+		}
+		if installed == "" {
+			fmt.Fprintln(stdout, "branch_guard: not_installed")
+			return nil
+		}
+		if err := fsys.Remove(hookPath); err != nil {
+			return errors.Wrap(errors.EInternal, "failed to remove post-checkout hook", err)
+		}
+		fmt.Fprintln(stdout, "branch_guard: removed")
+		fmt.Fprintf(stdout, "hook: %s\n", hookPath)
+		return nil
+	}
+
+	if foreign {
+		return foreignHookError(hookPath)
+	}
+	mode := scaffold.BranchGuardWarn
+	if opts.Block {
+		mode = scaffold.BranchGuardBlock
+	}
+	if err := fsys.MkdirAll(hooksDir, 0o755); err != nil {
+		return errors.Wrap(errors.EInternal, "failed to create hooks dir", err)
+	}
+	if err := fs.WriteFileAtomic(fsys, hookPath, []byte(scaffold.BranchGuardHook(mode)), 0o755); err != nil {
+		return errors.Wrap(errors.EInternal, "failed to write post-checkout hook", err)
+	}
+	fmt.Fprintf(stdout, "branch_guard: %s\n", mode)
+	fmt.Fprintf(stdout, "hook: %s\n", hookPath)
+	return nil
+}
+
+// readBranchGuardHook returns the mode of an agency-installed hook at path
+// ("" if absent), and whether a hook agency did not install is there instead.
+func readBranchGuardHook(fsys fs.FS, path string) (string, bool, error) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, errors.Wrap(errors.EInternal, "failed to read post-checkout hook", err)
+	}
+	mode := scaffold.BranchGuardMode(string(data))
+	return mode, mode == "", nil
+}
+
+// foreignHookError reports a post-checkout hook agency must not touch.
+func foreignHookError(path string) error {
+	return errors.NewWithDetails(errors.EGitHookExists,
+		"a post-checkout hook not installed by agency exists at "+path+"; move it aside first",
+		map[string]string{"hook": path})
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// checkout runs git checkout in dir, returning its combined output and error.
+func checkout(dir, branch string) (string, error) {
+	cmd := osexec.Command("git", "checkout", "-q", branch)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1", "AGENCY_BRANCH_GUARD=")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestBranchGuard(t *testing.T) {
+	repoRoot := testkit.NewRepo(t, testkit.RepoOpts{Git: true})
+	testkit.Git(t, repoRoot, "branch", "agency/fix-login-a3f2")
+	testkit.Git(t, repoRoot, "branch", "feature")
+
+	ctx := context.Background()
+	cr := exec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	guard := func(opts BranchGuardOpts) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := BranchGuard(ctx, cr, fsys, repoRoot, opts, &stdout, &stderr)
+		return stdout.String(), err
+	}
+	head := func() string {
+		return strings.TrimSpace(testkit.Git(t, repoRoot, "branch", "--show-current"))
+	}
+
+	// warn mode: checkout succeeds with a warning
+	if out, err := guard(BranchGuardOpts{}); err != nil || !strings.Contains(out, "branch_guard: warn") {
+		t.Fatalf("install warn: %q, %v", out, err)
+	}
+	out, err := checkout(repoRoot, "agency/fix-login-a3f2")
+	if err != nil || !strings.Contains(out, "belongs to an agency run") {
+		t.Fatalf("warn checkout: err=%v out=%q", err, out)
+	}
+	if head() != "agency/fix-login-a3f2" {
+		t.Errorf("warn mode should leave HEAD on the agency branch, got %q", head())
+	}
+	testkit.Git(t, repoRoot, "checkout", "-q", "main")
+
+	// block mode: checkout fails and HEAD returns to the previous branch
+	if out, err := guard(BranchGuardOpts{Block: true}); err != nil || !strings.Contains(out, "branch_guard: block") {
+		t.Fatalf("install block: %q, %v", out, err)
+	}
+	testkit.Git(t, repoRoot, "checkout", "-q", "feature")
+	if out, err := checkout(repoRoot, "agency/fix-login-a3f2"); err == nil || !strings.Contains(out, "refusing to check out") {
+		t.Fatalf("block checkout: err=%v out=%q", err, out)
+	}
+	if head() != "feature" {
+		t.Errorf("HEAD = %q after blocked checkout, want feature", head())
+	}
+
+	// run worktrees are never guarded
+	wt := filepath.Join(t.TempDir(), "wt")
+	testkit.Git(t, repoRoot, "worktree", "add", "-q", wt, "agency/fix-login-a3f2")
+	testkit.Git(t, wt, "checkout", "-q", "-b", "agency/other-b4c1")
+
+	if out, _ := guard(BranchGuardOpts{Status: true}); !strings.Contains(out, "branch_guard: block") {
+		t.Errorf("status = %q, want block", out)
+	}
+	if out, err := guard(BranchGuardOpts{Uninstall: true}); err != nil || !strings.Contains(out, "branch_guard: removed") {
+		t.Fatalf("uninstall: %q, %v", out, err)
+	}
+	if out, _ := guard(BranchGuardOpts{Status: true}); !strings.Contains(out, "branch_guard: not_installed") {
+		t.Errorf("status after uninstall = %q", out)
+	}
+}
+
+func TestBranchGuard_ForeignHook(t *testing.T) {
+	repoRoot := testkit.NewRepo(t, testkit.RepoOpts{Git: true})
+	hookPath := filepath.Join(repoRoot, ".git", "hooks", "post-checkout")
+	if err := os.MkdirAll(filepath.Dir(hookPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	const foreign = "#!/bin/sh\necho mine\n"
+	if err := os.WriteFile(hookPath, []byte(foreign), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []BranchGuardOpts{{}, {Uninstall: true}} {
+		var stdout, stderr bytes.Buffer
+		err := BranchGuard(context.Background(), exec.NewRealRunner(), fs.NewRealFS(), repoRoot, opts, &stdout, &stderr)
+		if errors.GetCode(err) != errors.EGitHookExists {
+			t.Errorf("%+v: code = %q, want %q", opts, errors.GetCode(err), errors.EGitHookExists)
+		}
+	}
+	if data, _ := os.ReadFile(hookPath); string(data) != foreign {
+		t.Errorf("foreign hook modified: %q", data)
+	}
+}
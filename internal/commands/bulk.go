@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+)
+
+// StdinRunIDArg is the run_id argument that means "read run ids from stdin".
+// Shared by all commands that accept one or more run ids.
+const StdinRunIDArg = "-"
+
+// ReadRunIDs reads whitespace-separated run ids from r.
+// Blank lines and lines starting with '#' are ignored; duplicates are dropped
+// (first occurrence wins) so piped `ls --json | jq` output can be fed directly.
+func ReadRunIDs(r io.Reader) ([]string, error) {
+	var ids []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			// Tolerate JSON string output (jq without -r)
+			if unquoted, err := strconv.Unquote(field); err == nil {
+				field = unquoted
+			}
+			if field == "" || seen[field] {
+				continue
+			}
+			seen[field] = true
+			ids = append(ids, field)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.EUsage, "failed to read run ids from stdin", err)
+	}
+
+	return ids, nil
+}
+
+// ExpandRunIDArgs expands a "-" argument into run ids read from stdin.
+// Returns E_USAGE if no run ids are given, "-" appears more than once,
+// or stdin yields no run ids.
+func ExpandRunIDArgs(args []string, stdin io.Reader) ([]string, error) {
+	var ids []string
+	usedStdin := false
+
+	for _, arg := range args {
+		if arg != StdinRunIDArg {
+			ids = append(ids, arg)
+			continue
+		}
+		if usedStdin {
+			return nil, errors.New(errors.EUsage, "'-' (read run ids from stdin) may only be given once")
+		}
+		usedStdin = true
+
+		fromStdin, err := ReadRunIDs(stdin)
+		if err != nil {
+			return nil, err
+		}
+		if len(fromStdin) == 0 {
+			return nil, errors.New(errors.EUsage, "no run ids read from stdin")
+		}
+		ids = append(ids, fromStdin...)
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New(errors.EUsage, "run_id is required")
+	}
+
+	return ids, nil
+}
+
+// RunBulk applies fn to each run id in order.
+// With a single run id, fn's error is returned unchanged so single-run behavior
+// (error codes, details) matches the non-bulk command.
+// With multiple run ids, every run is attempted; failures are reported per run on
+// stderr, a summary line is printed, and E_BULK_FAILED is returned if any failed.
+func RunBulk(runIDs []string, stderr io.Writer, fn func(runID string) error) error {
+	if len(runIDs) == 1 {
+		return fn(runIDs[0])
+	}
+
+	errs := make([]error, len(runIDs))
+	for i, runID := range runIDs {
+		errs[i] = fn(runID)
+	}
+	return reportBulk(runIDs, errs, stderr)
+}
+
+// RunBulkParallel is RunBulk with up to jobs runs processed at a time
+// (jobs < 1 means 1). fn must be safe to call concurrently. Failures are
+// reported in run id order once every run is done.
+func RunBulkParallel(runIDs []string, jobs int, stderr io.Writer, fn func(runID string) error) error {
+	if len(runIDs) == 1 {
+		return fn(runIDs[0])
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	errs := make([]error, len(runIDs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < len(runIDs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = fn(runIDs[i])
+			}
+		}()
+	}
+	for i := range runIDs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return reportBulk(runIDs, errs, stderr)
+}
+
+// reportBulk prints the per-run failures and the summary line of a bulk
+// operation; errs[i] is the outcome of runIDs[i].
+func reportBulk(runIDs []string, errs []error, stderr io.Writer) error {
+	var failed []string
+	for i, runID := range runIDs {
+		if err := errs[i]; err != nil {
+			failed = append(failed, runID)
+			if ae, ok := errors.AsAgencyError(err); ok {
+				fmt.Fprintf(stderr, "failed: %s: %s: %s\n", runID, ae.Code, ae.Msg)
+			} else {
+				fmt.Fprintf(stderr, "failed: %s: %v\n", runID, err)
+			}
+		}
+	}
+
+	fmt.Fprintf(stderr, "bulk: %d ok, %d failed\n", len(runIDs)-len(failed), len(failed))
+
+	if len(failed) > 0 {
+		return errors.NewWithDetails(
+			errors.EBulkFailed,
+			fmt.Sprintf("%d of %d runs failed: %s", len(failed), len(runIDs), strings.Join(failed, ", ")),
+			map[string]string{"failed": strings.Join(failed, ",")},
+		)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+)
+
+func TestReadRunIDs(t *testing.T) {
+	input := `
+# runs to clean up
+20260110120000-a3f2
+"20260110130000-b4c3" 20260110140000-c5d4
+20260110120000-a3f2
+`
+	got, err := ReadRunIDs(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadRunIDs() error = %v", err)
+	}
+	want := []string{"20260110120000-a3f2", "20260110130000-b4c3", "20260110140000-c5d4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadRunIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestExpandRunIDArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		want     []string
+		wantCode errors.Code
+	}{
+		{"positional only", []string{"a", "b"}, "", []string{"a", "b"}, ""},
+		{"stdin only", []string{"-"}, "a\nb\n", []string{"a", "b"}, ""},
+		{"mixed", []string{"x", "-"}, "a\n", []string{"x", "a"}, ""},
+		{"empty stdin", []string{"-"}, "\n# nothing\n", nil, errors.EUsage},
+		{"stdin twice", []string{"-", "-"}, "a\n", nil, errors.EUsage},
+		{"no args", nil, "", nil, errors.EUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandRunIDArgs(tt.args, strings.NewReader(tt.stdin))
+			if errors.GetCode(err) != tt.wantCode {
+				t.Fatalf("code = %q, want %q (err=%v)", errors.GetCode(err), tt.wantCode, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandRunIDArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunBulk_SingleReturnsOriginalError(t *testing.T) {
+	var stderr bytes.Buffer
+	err := RunBulk([]string{"a"}, &stderr, func(string) error {
+		return errors.New(errors.ERunNotFound, "run not found: a")
+	})
+	if errors.GetCode(err) != errors.ERunNotFound {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.ERunNotFound)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("expected no bulk output for single run, got %q", stderr.String())
+	}
+}
+
+func TestRunBulk_ContinuesAndReportsFailures(t *testing.T) {
+	var stderr bytes.Buffer
+	var attempted []string
+	err := RunBulk([]string{"a", "b", "c"}, &stderr, func(runID string) error {
+		attempted = append(attempted, runID)
+		if runID == "b" {
+			return errors.New(errors.ERunNotFound, "run not found: b")
+		}
+		return nil
+	})
+
+	if !reflect.DeepEqual(attempted, []string{"a", "b", "c"}) {
+		t.Errorf("attempted = %v, want all runs", attempted)
+	}
+	if errors.GetCode(err) != errors.EBulkFailed {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EBulkFailed)
+	}
+	out := stderr.String()
+	if !strings.Contains(out, "failed: b: E_RUN_NOT_FOUND") {
+		t.Errorf("stderr missing per-run failure: %q", out)
+	}
+	if !strings.Contains(out, "bulk: 2 ok, 1 failed") {
+		t.Errorf("stderr missing summary: %q", out)
+	}
+}
+
+func TestRunBulk_AllSucceed(t *testing.T) {
+	var stderr bytes.Buffer
+	err := RunBulk([]string{"a", "b"}, &stderr, func(string) error { return nil })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "bulk: 2 ok, 0 failed") {
+		t.Errorf("stderr missing summary: %q", stderr.String())
+	}
+}
+
+func TestRunBulkParallel_BoundsConcurrency(t *testing.T) {
+	var stderr bytes.Buffer
+	var mu sync.Mutex
+	running, peak := 0, 0
+	release := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	err := RunBulkParallel([]string{"a", "b", "c", "d", "e"}, 2, &stderr, func(runID string) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if runID == "b" || runID == "d" {
+			return errors.New(errors.EVerifyFailed, "verify failed")
+		}
+		return nil
+	})
+
+	if peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak)
+	}
+	if errors.GetCode(err) != errors.EBulkFailed || !strings.Contains(err.Error(), "2 of 5 runs failed: b, d") {
+		t.Errorf("err = %v", err)
+	}
+	if !strings.Contains(stderr.String(), "failed: b: E_VERIFY_FAILED: verify failed\nfailed: d: E_VERIFY_FAILED") {
+		t.Errorf("failures not reported in order: %q", stderr.String())
+	}
+}
@@ -0,0 +1,379 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/redact"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/version"
+	"github.com/NielsdaWheelz/agency/internal/worktree"
+)
+
+const (
+	// bundleMaxFileBytes caps each file in a bundle; larger files keep
+	// their tail, where failures usually are.
+	bundleMaxFileBytes = 4 << 20
+
+	// bundleRecentAudit is how many of the newest audit entries a bundle
+	// includes besides those touching the run.
+	bundleRecentAudit = 20
+)
+
+// BundleOpts holds options for the bundle command.
+type BundleOpts struct {
+	// RunID is the run identifier (exact or unique prefix).
+	RunID string
+
+	// Repo narrows run_id resolution to one repo (--repo).
+	Repo string
+
+	// Output is the tarball path (relative to cwd). Empty writes
+	// agency-bundle-<run_id>.tar.gz in cwd; "-" writes to stdout.
+	Output string
+}
+
+// BundleManifest is manifest.json, the first file in a bundle.
+type BundleManifest struct {
+	SchemaVersion string               `json:"schema_version"`
+	AgencyVersion string               `json:"agency_version"`
+	CreatedAt     string               `json:"created_at"`
+	RepoID        string               `json:"repo_id"`
+	RunID         string               `json:"run_id"`
+	Broken        bool                 `json:"broken"`
+	Redactions    int                  `json:"redactions"`
+	Files         []BundleManifestFile `json:"files"`
+	Skipped       []BundleManifestFile `json:"skipped,omitempty"`
+}
+
+// BundleManifestFile is one file collected (or skipped) by a bundle.
+type BundleManifestFile struct {
+	Name       string `json:"name"`
+	Source     string `json:"source,omitempty"`
+	Bytes      int    `json:"bytes,omitempty"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	Redactions int    `json:"redactions,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// bundleFile is one file to write into the tarball.
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// bundleBuilder collects redacted files and records them in the manifest.
+type bundleBuilder struct {
+	files    []bundleFile
+	manifest BundleManifest
+	crypter  *crypt.Crypt
+}
+
+// add redacts data and adds it as name.
+func (b *bundleBuilder) add(name, source string, data []byte, truncated bool) {
+	data, n := redact.Bytes(data)
+	b.files = append(b.files, bundleFile{name: name, data: data})
+	b.manifest.Redactions += n
+	b.manifest.Files = append(b.manifest.Files, BundleManifestFile{
+		Name:       name,
+		Source:     source,
+		Bytes:      len(data),
+		Truncated:  truncated,
+		Redactions: n,
+	})
+}
+
+// addFile adds the file at path as name, or records why it was skipped.
+func (b *bundleBuilder) addFile(name, path string) {
+	data, truncated, err := readTail(path, bundleMaxFileBytes, b.crypter)
+	if err != nil {
+		b.skip(name, path, err)
+		return
+	}
+	b.add(name, path, data, truncated)
+}
+
+// skip records a file the bundle could not include.
+func (b *bundleBuilder) skip(name, source string, err error) {
+	reason := "missing"
+	if !os.IsNotExist(err) {
+		reason = err.Error()
+	}
+	b.manifest.Skipped = append(b.manifest.Skipped, BundleManifestFile{Name: name, Source: source, Reason: reason})
+}
+
+// Bundle implements `agency bundle`: it collects a run's meta.json, logs,
+// events, transcript, report, setup env capture and output, a doctor report,
+// and the relevant audit entries into a gzipped tarball for a bug report.
+// Every file is passed through the redact package first. Broken runs are
+// bundled with whatever is still readable. Nothing in the data dir changes.
+//
+// Error codes:
+//   - E_RUN_NOT_FOUND: no run matches the run_id
+//   - E_RUN_ID_AMBIGUOUS: the prefix matches several runs
+//   - E_INTERNAL: the tarball could not be written
+func Bundle(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts BundleOpts, stdout, stderr io.Writer) error {
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+	dataDir := dirs.DataDir
+
+	scope, err := newRunScope(ctx, cr, dataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+	record, err := findRun(dataDir, opts.RunID, scope, crypter)
+	if err != nil {
+		return err
+	}
+
+	st := store.NewStore(fsys, dataDir, clock.Now).WithCrypt(crypter)
+	runDir := st.RunDir(record.RepoID, record.RunID)
+	b := &bundleBuilder{crypter: crypter, manifest: BundleManifest{
+		SchemaVersion: "1.0",
+		AgencyVersion: version.Version,
+		CreatedAt:     clock.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		RepoID:        record.RepoID,
+		RunID:         record.RunID,
+		Broken:        record.Broken,
+	}}
+
+	b.addFile("meta.json", st.RunMetaPath(record.RepoID, record.RunID))
+	b.addFile("events.jsonl", filepath.Join(runDir, "events.jsonl"))
+	b.addFile("transcript.txt", filepath.Join(runDir, store.TranscriptFileName))
+	b.addFile("setup_env.json", st.RunSetupEnvPath(record.RepoID, record.RunID))
+
+	logs, err := store.ListLogs(st.RunLogsDir(record.RepoID, record.RunID))
+	if err != nil {
+		b.skip("logs/", st.RunLogsDir(record.RepoID, record.RunID), err)
+	}
+	for _, l := range logs {
+		data, truncated, err := readLogTail(l.Path, bundleMaxFileBytes, crypter)
+		if err != nil {
+			b.skip("logs/"+l.Name, l.Path, err)
+			continue
+		}
+		b.add("logs/"+l.Name, l.Path, data, truncated)
+	}
+
+	if record.Meta != nil {
+		wt := record.Meta.WorktreePath
+		if dirExists(wt) {
+			b.addFile("report.md", filepath.Join(wt, ".agency", "report.md"))
+			outDir := worktree.OutputDir(wt)
+			if entries, err := os.ReadDir(outDir); err == nil {
+				for _, e := range entries {
+					if e.Type().IsRegular() && strings.HasSuffix(e.Name(), ".json") {
+						b.addFile("out/"+e.Name(), filepath.Join(outDir, e.Name()))
+					}
+				}
+			}
+		} else {
+			b.skip("report.md", wt, fmt.Errorf("worktree not present"))
+		}
+	}
+
+	b.add("doctor.txt", "agency doctor", bundleDoctor(ctx, cr, fsys, cwd, record, dataDir), false)
+
+	if data, err := bundleAudit(dataDir, record); err != nil {
+		b.skip("audit.jsonl", audit.Path(dataDir), err)
+	} else {
+		b.add("audit.jsonl", audit.Path(dataDir), data, false)
+	}
+
+	prefix := "agency-bundle-" + record.RunID
+	tarball, err := writeBundleTarball(prefix, b)
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to build bundle", err)
+	}
+
+	summary := stdout
+	if opts.Output == "-" {
+		if _, err := stdout.Write(tarball); err != nil {
+			return errors.Wrap(errors.EInternal, "failed to write bundle", err)
+		}
+		summary = stderr
+	} else {
+		path := opts.Output
+		if path == "" {
+			path = prefix + ".tar.gz"
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cwd, path)
+		}
+		if err := fs.WriteFileAtomic(fsys, path, tarball, 0o600); err != nil {
+			return errors.Wrap(errors.EInternal, "failed to write bundle", err)
+		}
+		fmt.Fprintf(summary, "bundle: %s\n", path)
+	}
+	fmt.Fprintf(summary, "files: %d (%d redaction(s))\n", len(b.files), b.manifest.Redactions)
+	for _, s := range b.manifest.Skipped {
+		fmt.Fprintf(summary, "skipped: %s (%s)\n", s.Name, s.Reason)
+	}
+	fmt.Fprintln(summary, "redaction is best effort: review the bundle before sharing it")
+	return nil
+}
+
+// bundleDoctor runs the doctor checks without persisting anything, from the
+// run's repo if it can be found (else cwd). A failure is reported in the
+// output rather than returned.
+func bundleDoctor(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, record *store.RunRecord, dataDir string) []byte {
+	dir := cwd
+	if root := resolveRepoRootForShow(ctx, cr, cwd, record, dataDir); root != nil {
+		dir = *root
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "# agency doctor (from %s)\n", dir)
+	if err := doctor(ctx, cr, fsys, dir, false, &out); err != nil {
+		fmt.Fprintf(&out, "\ndoctor failed: %v\n", err)
+	}
+	return out.Bytes()
+}
+
+// bundleAudit returns the audit entries touching the run plus the newest
+// bundleRecentAudit entries, oldest first, as JSON lines.
+func bundleAudit(dataDir string, record *store.RunRecord) ([]byte, error) {
+	entries, err := audit.Read(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i, e := range entries {
+		if i < len(entries)-bundleRecentAudit && !touchesRecord(e, record) {
+			continue
+		}
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// touchesRecord reports whether an audit entry touched the run.
+func touchesRecord(e audit.Entry, record *store.RunRecord) bool {
+	for _, r := range e.Runs {
+		if r.RepoID == record.RepoID && r.RunID == record.RunID {
+			return true
+		}
+	}
+	return false
+}
+
+// writeBundleTarball writes manifest.json and the collected files under
+// prefix/ into a gzipped tarball.
+func writeBundleTarball(prefix string, b *bundleBuilder) ([]byte, error) {
+	sort.SliceStable(b.manifest.Skipped, func(i, j int) bool { return b.manifest.Skipped[i].Name < b.manifest.Skipped[j].Name })
+	manifest, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files := append([]bundleFile{{name: "manifest.json", data: append(manifest, '\n')}}, b.files...)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	modTime := clock.Now()
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    prefix + "/" + f.name,
+			Mode:    0o600,
+			Size:    int64(len(f.data)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readTail reads the last max bytes of the file at path, decrypting it if
+// it is encrypted at rest.
+func readTail(path string, max int64, crypter *crypt.Crypt) ([]byte, bool, error) {
+	if crypt.IsEncryptedFile(path) {
+		data, err := crypter.ReadFile(path)
+		if err != nil {
+			return nil, false, err
+		}
+		return tailOf(bytes.NewReader(data), max)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	return tailOf(f, max)
+}
+
+// readLogTail is readTail for a script log, decompressing it if gc
+// compressed it.
+func readLogTail(path string, max int64, crypter *crypt.Crypt) ([]byte, bool, error) {
+	rc, err := store.OpenLog(strings.TrimSuffix(path, store.CompressedLogExt), crypter)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rc.Close()
+	return tailOf(rc, max)
+}
+
+// tailOf returns the last max bytes of r, prefixed with a marker if earlier
+// bytes were dropped.
+func tailOf(r io.Reader, max int64) ([]byte, bool, error) {
+	var buf []byte
+	var dropped int64
+	chunk := make([]byte, 64<<10)
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if over := int64(len(buf)) - max; over > max {
+			dropped += over
+			buf = append(buf[:0], buf[over:]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if over := int64(len(buf)) - max; over > 0 {
+		dropped += over
+		buf = buf[over:]
+	}
+	if dropped == 0 {
+		return buf, false, nil
+	}
+	// Start at a line boundary so a redaction pattern is never cut in half
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		dropped += int64(i + 1)
+		buf = buf[i+1:]
+	}
+	marker := fmt.Sprintf("[agency bundle: %d earlier bytes dropped]\n", dropped)
+	return append([]byte(marker), buf...), true, nil
+}
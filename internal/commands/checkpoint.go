@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/NielsdaWheelz/agency/internal/checkpoint"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// CheckpointOpts holds options for the checkpoint command.
+type CheckpointOpts struct {
+	// RunID is the run identifier (exact or unique prefix).
+	RunID string
+
+	// Message describes the checkpoint (optional).
+	Message string
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+}
+
+// RestoreOpts holds options for the restore command.
+type RestoreOpts struct {
+	// RunID is the run identifier (exact or unique prefix).
+	RunID string
+
+	// Checkpoint is the number of the checkpoint to roll back to.
+	Checkpoint int
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+}
+
+// Checkpoint snapshots a run's worktree (tracked changes, untracked files,
+// and the commit it is on) so it can be rolled back with `agency restore`.
+// The worktree is not modified.
+//
+// Error codes:
+//   - E_WORKTREE_MISSING: the run is archived
+//   - E_REPO_LOCKED: another agency command holds the repo lock
+//   - E_CHECKPOINT_FAILED: git or the run dir refused the snapshot
+func Checkpoint(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts CheckpointOpts, stdout, stderr io.Writer) error {
+	record, unlock, err := lockRunWorktree(ctx, cr, fsys, cwd, opts.RunID, opts.Repo, "checkpoint")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unlock() }()
+
+	cp, err := checkpoint.Create(ctx, cr, record.RunDir, record.RunID, record.Meta.WorktreePath, opts.Message, clock.Now())
+	if err != nil {
+		return errors.WrapWithDetails(errors.ECheckpointFailed, "failed to create checkpoint", err,
+			map[string]string{"run_id": record.RunID, "worktree_path": record.Meta.WorktreePath})
+	}
+	_ = events.AppendEvent(events.EventsPath(record.RunDir), events.New(clock.Now(), record.RepoID, record.RunID, "checkpoint", map[string]any{
+		"checkpoint": cp.Number,
+		"head":       cp.Head,
+	}))
+
+	fmt.Fprintf(stdout, "run_id: %s\n", record.RunID)
+	fmt.Fprintf(stdout, "checkpoint: %d\n", cp.Number)
+	fmt.Fprintf(stdout, "head: %s\n", cp.Head)
+	fmt.Fprintf(stdout, "tracked_changes: %s\n", boolStr(cp.Stash != ""))
+	fmt.Fprintf(stdout, "untracked_files: %d\n", cp.UntrackedFiles)
+	return nil
+}
+
+// Restore rolls a run's worktree back to one of its checkpoints. The current
+// state is checkpointed first, so a restore can itself be undone.
+//
+// Error codes:
+//   - E_WORKTREE_MISSING: the run is archived
+//   - E_CHECKPOINT_NOT_FOUND: the run has no such checkpoint
+//   - E_REPO_LOCKED: another agency command holds the repo lock
+//   - E_CHECKPOINT_FAILED: the safety checkpoint or the rollback failed
+func Restore(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts RestoreOpts, stdout, stderr io.Writer) error {
+	if opts.Checkpoint <= 0 {
+		return errors.New(errors.EUsage, "--checkpoint <n> is required")
+	}
+
+	record, unlock, err := lockRunWorktree(ctx, cr, fsys, cwd, opts.RunID, opts.Repo, "restore")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unlock() }()
+
+	target, found, err := checkpoint.Find(record.RunDir, opts.Checkpoint)
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to read checkpoints", err)
+	}
+	if !found {
+		return errors.NewWithDetails(errors.ECheckpointNotFound,
+			"run "+record.RunID+" has no checkpoint "+strconv.Itoa(opts.Checkpoint),
+			map[string]string{"run_id": record.RunID, "checkpoints_dir": checkpoint.Dir(record.RunDir)})
+	}
+
+	sessionName := record.Meta.TmuxSessionName
+	if sessionName == "" {
+		sessionName = "agency_" + record.RunID
+	}
+	if newTmuxSessionSet(ctx, cr).Active(sessionName) {
+		fmt.Fprintf(stderr, "warning: runner session %s is active; files change underneath it\n", sessionName)
+	}
+
+	worktreePath := record.Meta.WorktreePath
+	if err := checkpoint.CheckBranch(ctx, cr, worktreePath, target); err != nil {
+		return err
+	}
+	safety, err := checkpoint.Create(ctx, cr, record.RunDir, record.RunID, worktreePath,
+		fmt.Sprintf("before restore to %d", target.Number), clock.Now())
+	if err != nil {
+		return errors.WrapWithDetails(errors.ECheckpointFailed, "failed to checkpoint the current state; nothing was restored", err,
+			map[string]string{"run_id": record.RunID, "worktree_path": worktreePath})
+	}
+
+	if err := checkpoint.Restore(ctx, cr, record.RunDir, worktreePath, target); err != nil {
+		return errors.WithHints(errors.WrapWithDetails(errors.ECheckpointFailed, "failed to restore checkpoint "+strconv.Itoa(target.Number), err,
+			map[string]string{"run_id": record.RunID, "worktree_path": worktreePath}),
+			fmt.Sprintf("the state before the restore is checkpoint %d: agency restore --checkpoint %d %s", safety.Number, safety.Number, record.RunID))
+	}
+	_ = events.AppendEvent(events.EventsPath(record.RunDir), events.New(clock.Now(), record.RepoID, record.RunID, "restore", map[string]any{
+		"checkpoint": target.Number,
+		"saved_as":   safety.Number,
+	}))
+
+	fmt.Fprintf(stdout, "run_id: %s\n", record.RunID)
+	fmt.Fprintf(stdout, "restored: %d\n", target.Number)
+	fmt.Fprintf(stdout, "head: %s\n", target.Head)
+	fmt.Fprintf(stdout, "previous_state: checkpoint %d\n", safety.Number)
+	return nil
+}
+
+// lockRunWorktree resolves a run that still has its worktree and takes its
+// repo lock for cmd. The caller must call unlock.
+func lockRunWorktree(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd, runID, repo, cmd string) (*store.RunRecord, func() error, error) {
+	if runID == "" {
+		return nil, nil, errors.New(errors.EUsage, "run_id is required")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	record, err := resolveRun(dirs.DataDir, runID, scope, LoadCrypt(cr, fsys))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !dirExists(record.Meta.WorktreePath) {
+		return nil, nil, errors.NewWithDetails(errors.EWorktreeMissing, "cannot "+cmd+": run is archived",
+			map[string]string{"run_id": record.RunID, "worktree_path": record.Meta.WorktreePath})
+	}
+
+	unlock, err := lock.NewRepoLock(dirs.DataDir).Lock(record.RepoID, cmd)
+	if err != nil {
+		return nil, nil, repoLockError(err, dirs.DataDir, record.RepoID)
+	}
+	return record, unlock, nil
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestCheckpointRestoreAndGC(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	defer SetClock(testkit.NewClock(created.Add(time.Hour)).Core())()
+
+	// A git worktree for the run
+	wt := filepath.Join(t.TempDir(), "wt")
+	gitCmd := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", wt}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := os.MkdirAll(wt, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd("init", "-q")
+	gitCmd("config", "user.email", "test@example.com")
+	gitCmd("config", "user.name", "Test User")
+	if err := os.WriteFile(filepath.Join(wt, "a.txt"), []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "initial")
+
+	runID := "20260110120000-a3f2"
+	createValidMetaForShow(t, dataDir, "r1", runID, wt, created)
+	ctx := context.Background()
+	cr := agencyexec.NewRealRunner()
+	cwd := t.TempDir()
+
+	// checkpoint 1 holds the edit
+	if err := os.WriteFile(filepath.Join(wt, "a.txt"), []byte("edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	if err := Checkpoint(ctx, cr, fs.NewRealFS(), cwd, CheckpointOpts{RunID: "20260110", Message: "risky"}, &stdout, io.Discard); err != nil {
+		t.Fatalf("Checkpoint() error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "checkpoint: 1\n") || !strings.Contains(stdout.String(), "tracked_changes: true\n") {
+		t.Errorf("checkpoint output:\n%s", stdout.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(wt, "a.txt"), []byte("broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	restore := func(n int) (string, error) {
+		var stdout bytes.Buffer
+		err := Restore(ctx, cr, fs.NewRealFS(), cwd, RestoreOpts{RunID: runID, Checkpoint: n}, &stdout, io.Discard)
+		return stdout.String(), err
+	}
+	if _, err := restore(7); errors.GetCode(err) != errors.ECheckpointNotFound {
+		t.Errorf("restore 7: err = %v, want E_CHECKPOINT_NOT_FOUND", err)
+	}
+	out, err := restore(1)
+	if err != nil || !strings.Contains(out, "previous_state: checkpoint 2\n") {
+		t.Fatalf("restore 1: out = %q, err = %v", out, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(wt, "a.txt")); string(data) != "edited\n" {
+		t.Errorf("a.txt = %q after restore, want edited", data)
+	}
+
+	// show lists both checkpoints, the safety one included
+	stdout.Reset()
+	if err := Show(ctx, cr, fs.NewRealFS(), cwd, ShowOpts{RunID: runID, JSON: true}, &stdout, io.Discard); err != nil {
+		t.Fatalf("Show() error = %v", err)
+	}
+	var env render.ShowJSONEnvelope
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if cps := env.Data.Checkpoints; len(cps) != 2 || cps[0].Message != "risky" || cps[1].Message != "before restore to 1" {
+		t.Errorf("show checkpoints = %+v", cps)
+	}
+
+	// Once the run is archived, gc prunes all of its checkpoints
+	if err := os.RemoveAll(wt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restore(1); errors.GetCode(err) != errors.EWorktreeMissing {
+		t.Errorf("restore archived: err = %v, want E_WORKTREE_MISSING", err)
+	}
+	stdout.Reset()
+	if err := GC(ctx, cr, fs.NewRealFS(), cwd, GCOpts{}, &stdout, io.Discard); err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if want := "would prune 2 checkpoint(s) of " + runID + " (run archived)"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("gc dry run missing %q:\n%s", want, stdout.String())
+	}
+	stdout.Reset()
+	if err := GC(ctx, cr, fs.NewRealFS(), cwd, GCOpts{Auto: true}, &stdout, io.Discard); err != nil {
+		t.Fatalf("GC(--auto) error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "pruned 2 checkpoint(s) of "+runID) {
+		t.Errorf("gc --auto output:\n%s", stdout.String())
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dataDir, "repos", "r1", "runs", runID, "checkpoints")); len(entries) != 0 {
+		t.Errorf("checkpoints left after gc: %v", entries)
+	}
+}
@@ -0,0 +1,338 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/archive"
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/gh"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// CleanupOpts holds options for the cleanup command.
+type CleanupOpts struct {
+	// RunIDs are the run identifiers (exact or unique prefix), already expanded from stdin.
+	RunIDs []string
+
+	// Repo restricts run_id resolution (and --merged) to one repo
+	// (repo_id, repo_key, or path).
+	Repo string
+
+	// Merged cleans up every unarchived run whose PR is merged, instead of RunIDs.
+	Merged bool
+
+	// Force cleans up RunIDs without checking that their PR is merged.
+	Force bool
+}
+
+// cleanupTarget is a run to clean up, with what its cleanup needs.
+type cleanupTarget struct {
+	record   store.RunRecord
+	repoRoot string // "" if no checkout of the repo is known
+	script   string // scripts.archive ("" if unknown)
+	mergedAt string // the PR's merge time, or the cleanup time with --force
+	forced   bool
+}
+
+// Cleanup cleans up runs whose PR was merged: it records archive.merged_at,
+// deletes the run's branch on origin, and archives the run (archive script,
+// tmux session, worktree; see archive.Archive), appending a cleanup event.
+// Each run's PR is checked with gh first; runs without a merged PR fail with
+// E_PR_NOT_MERGED unless Force is set. With Merged, all unarchived runs with
+// a PR are checked in one GitHub query and the merged ones cleaned up.
+func Cleanup(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts CleanupOpts, stdout, stderr io.Writer) error {
+	switch {
+	case opts.Merged && len(opts.RunIDs) > 0:
+		return errors.New(errors.EUsage, "--merged takes no run_id")
+	case opts.Merged && opts.Force:
+		return errors.New(errors.EUsage, "--force cannot be combined with --merged")
+	case !opts.Merged && len(opts.RunIDs) == 0:
+		return errors.New(errors.EUsage, "run_id (or --merged) is required")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+	repoLock := lock.NewRepoLock(dirs.DataDir)
+	client := gh.NewClient(cr, fsys, dirs.CacheDir)
+
+	if opts.Merged {
+		targets, err := findMergedRuns(ctx, client, fsys, dirs.DataDir, scope.RepoID, false, crypter, stderr)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			fmt.Fprintln(stdout, "no unarchived runs with a merged PR")
+			return nil
+		}
+		byID := make(map[string]cleanupTarget, len(targets))
+		runIDs := make([]string, 0, len(targets))
+		for _, t := range targets {
+			byID[t.record.RunID] = t
+			runIDs = append(runIDs, t.record.RunID)
+		}
+		return RunBulk(runIDs, stderr, func(runID string) error {
+			return cleanupRun(ctx, cr, st, repoLock, byID[runID], stdout, stderr)
+		})
+	}
+
+	idx, _ := store.LoadRepoIndexForScan(dirs.DataDir)
+	return RunBulk(opts.RunIDs, stderr, func(input string) error {
+		record, err := resolveRun(dirs.DataDir, input, scope, crypter)
+		if err != nil {
+			return err
+		}
+		t := cleanupTarget{record: *record, forced: opts.Force}
+		t.repoRoot, t.script, _ = cleanupRepo(fsys, idx, *record)
+		if opts.Force {
+			t.mergedAt = st.Now().UTC().Format(time.RFC3339)
+		} else if t.mergedAt, err = prMergedAt(ctx, client, *record); err != nil {
+			return err
+		}
+		return cleanupRun(ctx, cr, st, repoLock, t, stdout, stderr)
+	})
+}
+
+// prRef returns the GitHub PR of rec, if it has one in a GitHub repo.
+func prRef(rec store.RunRecord) (gh.PRRef, bool) {
+	if rec.Meta == nil || rec.Meta.PRNumber <= 0 || rec.Repo == nil {
+		return gh.PRRef{}, false
+	}
+	ownerRepo, ok := strings.CutPrefix(rec.Repo.RepoKey, "github:")
+	if !ok {
+		return gh.PRRef{}, false
+	}
+	owner, repo, ok := strings.Cut(ownerRepo, "/")
+	if !ok {
+		return gh.PRRef{}, false
+	}
+	return gh.PRRef{Owner: owner, Repo: repo, Number: rec.Meta.PRNumber}, true
+}
+
+// prMergedAt returns when rec's PR was merged, or E_PR_NOT_MERGED if the run
+// has no PR or it is not merged.
+func prMergedAt(ctx context.Context, client *gh.Client, rec store.RunRecord) (string, error) {
+	ref, ok := prRef(rec)
+	if !ok {
+		return "", errors.WithHints(errors.New(errors.EPRNotMerged, rec.RunID+" has no GitHub PR"),
+			"clean it up anyway with: agency cleanup --force "+rec.RunID)
+	}
+	states, err := client.PRStates(ctx, []gh.PRRef{ref})
+	if err != nil {
+		return "", err
+	}
+	state, ok := states[ref]
+	if !ok {
+		return "", errors.New(errors.EPRNotMerged, fmt.Sprintf("PR #%d of %s was not found on GitHub", ref.Number, rec.RunID))
+	}
+	if state.State != "MERGED" {
+		return "", errors.WithHints(errors.NewWithDetails(errors.EPRNotMerged,
+			fmt.Sprintf("PR #%d of %s is %s, not merged", ref.Number, rec.RunID, strings.ToLower(state.State)),
+			map[string]string{"pr_url": state.URL}),
+			"clean it up anyway with: agency cleanup --force "+rec.RunID)
+	}
+	return state.MergedAt, nil
+}
+
+// cleanupRepo returns a checkout of rec's repo and its scripts.archive, and
+// whether the repo's agency.json sets github.auto_cleanup. An unreadable
+// agency.json leaves the script empty.
+func cleanupRepo(fsys fs.FS, idx *store.RepoIndex, rec store.RunRecord) (root, script string, auto bool) {
+	if rec.Repo == nil {
+		return "", "", false
+	}
+	picked := store.PickRepoRoot(rec.Repo.RepoKey, nil, idx)
+	if picked == nil {
+		return "", "", false
+	}
+	cfg, err := config.LoadAgencyConfig(fsys, *picked)
+	if err != nil {
+		return *picked, "", false
+	}
+	return *picked, cfg.Scripts.Archive, cfg.GitHub.AutoCleanup
+}
+
+// findMergedRuns returns the unarchived runs (in repoID, or all repos if
+// empty) whose PR is merged, sorted by run_id, querying GitHub once per
+// batch of PRs. With autoOnly, only repos whose agency.json sets
+// github.auto_cleanup are considered.
+func findMergedRuns(ctx context.Context, client *gh.Client, fsys fs.FS, dataDir, repoID string, autoOnly bool, crypter *crypt.Crypt, stderr io.Writer) ([]cleanupTarget, error) {
+	records, err := store.ScanAllRuns(dataDir, crypter)
+	if err != nil {
+		return nil, errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+	idx, _ := store.LoadRepoIndexForScan(dataDir)
+
+	type repoInfo struct {
+		root, script string
+		auto         bool
+	}
+	repos := make(map[string]repoInfo)
+	var pending []cleanupTarget
+	var refs []gh.PRRef
+	for _, rec := range records {
+		if rec.Broken || (repoID != "" && rec.RepoID != repoID) {
+			continue
+		}
+		if rec.Meta.Archive != nil && rec.Meta.Archive.ArchivedAt != "" {
+			continue
+		}
+		ref, ok := prRef(rec)
+		if !ok {
+			continue
+		}
+		info, seen := repos[rec.RepoID]
+		if !seen {
+			info.root, info.script, info.auto = cleanupRepo(fsys, idx, rec)
+			repos[rec.RepoID] = info
+		}
+		if autoOnly && !info.auto {
+			continue
+		}
+		pending = append(pending, cleanupTarget{record: rec, repoRoot: info.root, script: info.script})
+		refs = append(refs, ref)
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	states, err := client.PRStates(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	var merged []cleanupTarget
+	for _, t := range pending {
+		ref, _ := prRef(t.record)
+		if state, ok := states[ref]; ok && state.State == "MERGED" {
+			t.mergedAt = state.MergedAt
+			merged = append(merged, t)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].record.RunID < merged[j].record.RunID
+	})
+	return merged, nil
+}
+
+// cleanupRun cleans up one run under the repo lock: records merged_at,
+// deletes the remote branch (a failure is a warning), archives the run
+// unless it already is, and appends a cleanup event.
+func cleanupRun(ctx context.Context, cr agencyexec.CommandRunner, st *store.Store, repoLock lock.RepoLock, t cleanupTarget, stdout, stderr io.Writer) error {
+	meta := t.record.Meta
+
+	unlock, err := repoLock.Lock(meta.RepoID, "cleanup")
+	if err != nil {
+		return repoLockError(err, repoLock.DataDir, meta.RepoID)
+	}
+	defer func() { _ = unlock() }()
+	audit.Touch(meta.RepoID, meta.RunID)
+
+	// 1. Record the merge (an earlier merged_at is kept)
+	if err := st.UpdateMeta(meta.RepoID, meta.RunID, func(m *store.RunMeta) {
+		if m.Archive == nil {
+			m.Archive = &store.RunMetaArchive{}
+		}
+		if m.Archive.MergedAt == "" {
+			m.Archive.MergedAt = t.mergedAt
+		}
+	}); err != nil {
+		return err
+	}
+
+	// 2. Remote branch (best-effort; GitHub may have deleted it on merge)
+	var warnings []string
+	gitDir := t.repoRoot
+	if gitDir == "" && dirExists(meta.WorktreePath) {
+		gitDir = meta.WorktreePath
+	}
+	remoteBranch, warning := deleteRemoteBranch(ctx, cr, gitDir, meta.Branch)
+	if warning != "" {
+		warnings = append(warnings, warning)
+	}
+
+	// 3. Archive: script, tmux session, worktree, archived_at
+	var res archive.Result
+	alreadyArchived := meta.Archive != nil && meta.Archive.ArchivedAt != ""
+	if !alreadyArchived {
+		res, err = archive.Archive(ctx, cr, st, meta, archive.Opts{
+			RepoRoot: t.repoRoot,
+			Script:   t.script,
+		})
+		warnings = append(warnings, res.Warnings...)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: %s: %s\n", meta.RunID, w)
+	}
+
+	// Record the event (best-effort)
+	data := map[string]any{
+		"merged_at":      t.mergedAt,
+		"forced":         t.forced,
+		"remote_branch":  remoteBranch,
+		"session_killed": res.SessionKilled,
+		"archived":       !alreadyArchived && err == nil,
+		"warnings":       warnings,
+	}
+	if meta.PRNumber > 0 {
+		data["pr_number"] = meta.PRNumber
+	}
+	if res.ScriptRan {
+		data["script_exit_code"] = res.ScriptExitCode
+	}
+	if err != nil {
+		data["error_code"] = string(errors.GetCode(err))
+	}
+	_ = events.AppendEvent(events.EventsPath(t.record.RunDir), events.New(st.Now(), meta.RepoID, meta.RunID, "cleanup", data))
+
+	if err != nil {
+		return err
+	}
+
+	archived := "archived"
+	if alreadyArchived {
+		archived = "already archived"
+	}
+	fmt.Fprintf(stdout, "cleaned up %s (remote branch %s, %s)\n", meta.RunID, remoteBranch, archived)
+	return nil
+}
+
+// deleteRemoteBranch deletes branch on origin from the checkout at dir.
+// Returns "deleted", "already gone", "failed", or "skipped" (no checkout or
+// branch), and a warning for the last two.
+func deleteRemoteBranch(ctx context.Context, cr agencyexec.CommandRunner, dir, branch string) (string, string) {
+	if dir == "" || branch == "" {
+		return "skipped", "no checkout of the repo to delete the remote branch from"
+	}
+	result, err := cr.Run(ctx, "git", []string{"-C", dir, "push", "origin", "--delete", branch}, agencyexec.RunOpts{})
+	if err != nil {
+		return "failed", "failed to delete remote branch " + branch + ": " + err.Error()
+	}
+	if result.ExitCode != 0 {
+		if strings.Contains(result.Stderr, "remote ref does not exist") {
+			return "already gone", ""
+		}
+		return "failed", "failed to delete remote branch " + branch + ": " + strings.TrimSpace(result.Stderr)
+	}
+	return "deleted", ""
+}
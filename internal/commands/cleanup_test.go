@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// setupCleanupRuns creates a GitHub repo with two runs that have PRs #41
+// (run "...-a3f2") and #42 (run "...-b4c1"), each with a worktree.
+func setupCleanupRuns(t *testing.T, agencyJSON string) (dataDir, repoRoot string, runs [2]*store.RunMeta) {
+	t.Helper()
+	dataDir = testkit.DataDir(t)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	repoRoot = setupGCRepo(t, dataDir, "abc123", "github:owner/repo", agencyJSON)
+	t0 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	for i, runID := range []string{"20260110120000-a3f2", "20260110120000-b4c1"} {
+		wt := filepath.Join(dataDir, "repos", "abc123", "worktrees", runID)
+		if err := os.MkdirAll(wt, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		meta := testkit.NewRunMeta("abc123", runID, wt, t0)
+		meta.PRNumber = 41 + i
+		testkit.WriteRun(t, dataDir, meta)
+		runs[i] = meta
+	}
+	return dataDir, repoRoot, runs
+}
+
+// prStatesStub answers the PR state query: #41 open, #42 merged.
+const prStatesStub = `{"data": {"r0": {
+	"p41": {"number": 41, "state": "OPEN", "url": "https://github.com/owner/repo/pull/41"},
+	"p42": {"number": 42, "state": "MERGED", "mergedAt": "2026-01-11T09:00:00Z", "url": "https://github.com/owner/repo/pull/42"}
+}}}`
+
+func TestCleanup_Merged(t *testing.T) {
+	dataDir, repoRoot, runs := setupCleanupRuns(t, testkit.DefaultAgencyJSON)
+	open, merged := runs[0], runs[1]
+
+	cr := testkit.NewFakeRunner()
+	cr.OnPrefix("gh", "api", "graphql").Stdout(prStatesStub)
+	cr.On("git", "-C", repoRoot, "push", "origin", "--delete", merged.Branch).
+		Exit(1).Stderr("error: unable to delete '" + merged.Branch + "': remote ref does not exist\n")
+	cr.TmuxSessions(merged.TmuxSessionName)
+	cr.On("git", "-C", repoRoot, "worktree", "remove", "--force", merged.WorktreePath).Exit(128)
+	cr.On("git", "-C", repoRoot, "worktree", "prune")
+	cr.OnPrefix("sh", "-lc").Exit(0)
+
+	var stdout, stderr bytes.Buffer
+	if err := Cleanup(context.Background(), cr, fs.NewRealFS(), t.TempDir(), CleanupOpts{Merged: true}, &stdout, &stderr); err != nil {
+		t.Fatalf("Cleanup() error = %v (stderr %q)", err, stderr.String())
+	}
+	if want := "cleaned up " + merged.RunID + " (remote branch already gone, archived)\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	got, err := st.ReadMeta("abc123", merged.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Archive == nil || got.Archive.MergedAt != "2026-01-11T09:00:00Z" || got.Archive.ArchivedAt == "" {
+		t.Errorf("archive = %+v, want merged_at from GitHub and archived_at", got.Archive)
+	}
+	if dirExists(merged.WorktreePath) {
+		t.Error("worktree of the merged run still exists")
+	}
+	if !cr.Called("tmux", "kill-session", "-t", merged.TmuxSessionName) {
+		t.Error("tmux session not killed")
+	}
+	if !dirExists(open.WorktreePath) {
+		t.Error("worktree of the open run was removed")
+	}
+
+	evs, err := os.ReadFile(filepath.Join(st.RunDir("abc123", merged.RunID), "events.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(evs), `"event":"cleanup"`) || !strings.Contains(string(evs), `"remote_branch":"already gone"`) || !strings.Contains(string(evs), `"session_killed":true`) {
+		t.Errorf("events.jsonl = %s", evs)
+	}
+}
+
+func TestCleanup_RunIDs(t *testing.T) {
+	_, repoRoot, runs := setupCleanupRuns(t, testkit.DefaultAgencyJSON)
+	open := runs[0]
+
+	cr := testkit.NewFakeRunner()
+	cr.OnPrefix("gh", "api", "graphql").Stdout(prStatesStub)
+	var stdout, stderr bytes.Buffer
+
+	// The open PR is refused
+	err := Cleanup(context.Background(), cr, fs.NewRealFS(), t.TempDir(), CleanupOpts{RunIDs: []string{open.RunID}}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EPRNotMerged || !strings.Contains(err.Error(), "PR #41") {
+		t.Fatalf("Cleanup(open PR) error = %v, want E_PR_NOT_MERGED", err)
+	}
+	if !dirExists(open.WorktreePath) {
+		t.Fatal("refused cleanup removed the worktree")
+	}
+
+	// --force skips the check and deletes the branch
+	cr = testkit.NewFakeRunner()
+	cr.On("git", "-C", repoRoot, "push", "origin", "--delete", open.Branch)
+	cr.TmuxNoServer()
+	cr.On("git", "-C", repoRoot, "worktree", "remove", "--force", open.WorktreePath).Exit(128)
+	cr.On("git", "-C", repoRoot, "worktree", "prune")
+	cr.OnPrefix("sh", "-lc").Exit(0)
+	stdout.Reset()
+	if err := Cleanup(context.Background(), cr, fs.NewRealFS(), t.TempDir(), CleanupOpts{RunIDs: []string{open.RunID}, Force: true}, &stdout, &stderr); err != nil {
+		t.Fatalf("Cleanup(--force) error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "remote branch deleted, archived") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	if len(cr.CallsTo("gh")) != 0 {
+		t.Errorf("--force queried GitHub: %v", cr.CallsTo("gh"))
+	}
+
+	if err := Cleanup(context.Background(), cr, fs.NewRealFS(), t.TempDir(), CleanupOpts{Merged: true, Force: true}, &stdout, &stderr); errors.GetCode(err) != errors.EUsage {
+		t.Errorf("Cleanup(--merged --force) error = %v, want E_USAGE", err)
+	}
+}
+
+func TestGC_AutoCleanup(t *testing.T) {
+	dataDir, repoRoot, runs := setupCleanupRuns(t, `{"version": 1, "github": {"auto_cleanup": true}}`)
+	merged := runs[1]
+
+	cr := testkit.NewFakeRunner()
+	cr.OnPrefix("gh", "api", "graphql").Stdout(prStatesStub)
+	var stdout, stderr bytes.Buffer
+	if err := GC(context.Background(), cr, fs.NewRealFS(), t.TempDir(), GCOpts{}, &stdout, &stderr); err != nil {
+		t.Fatalf("GC() dry run error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "would clean up "+merged.RunID+" (PR #42 merged 2026-01-11T09:00:00Z)") {
+		t.Errorf("dry run stdout = %q", stdout.String())
+	}
+
+	cr.On("git", "-C", repoRoot, "push", "origin", "--delete", merged.Branch)
+	cr.TmuxNoServer()
+	cr.On("git", "-C", repoRoot, "worktree", "remove", "--force", merged.WorktreePath).Exit(128)
+	cr.On("git", "-C", repoRoot, "worktree", "prune")
+	stdout.Reset()
+	if err := GC(context.Background(), cr, fs.NewRealFS(), t.TempDir(), GCOpts{Auto: true}, &stdout, &stderr); err != nil {
+		t.Fatalf("GC() error = %v (stderr %q)", err, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "cleaned up "+merged.RunID) || !strings.Contains(stderr.String(), "warning: cleaning up "+merged.RunID) {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+
+	// Without github.auto_cleanup, gc leaves merged runs to retention
+	setupGCRepo(t, dataDir, "abc123", "github:owner/repo", `{"version": 1}`)
+	stdout.Reset()
+	cr = testkit.NewFakeRunner()
+	if err := GC(context.Background(), cr, fs.NewRealFS(), t.TempDir(), GCOpts{}, &stdout, &stderr); err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if len(cr.CallsTo("gh")) != 0 {
+		t.Errorf("gc queried GitHub without auto_cleanup: %v", cr.CallsTo("gh"))
+	}
+}
@@ -0,0 +1,16 @@
+package commands
+
+import "github.com/NielsdaWheelz/agency/internal/core"
+
+// clock is the time source and run_id generator every command uses, including
+// the run pipeline it builds. Tests replace it with SetClock.
+var clock = core.SystemClock()
+
+// SetClock replaces the clock used by commands and returns a function that
+// restores the previous one. Meant for tests; it must not be called while a
+// command is running.
+func SetClock(c core.Clock) (restore func()) {
+	prev := clock
+	clock = c
+	return func() { clock = prev }
+}
@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/git"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// CompareOpts holds options for the compare command.
+type CompareOpts struct {
+	// RunA and RunB are the run identifiers (exact or unique prefix) to compare.
+	RunA string
+	RunB string
+
+	// Task compares the two runs of an agency run --runners task
+	// (meta.task_id) instead of RunA and RunB.
+	Task string
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+
+	// JSON outputs machine-readable JSON.
+	JSON bool
+
+	// Plain selects line-oriented human output; the user config "plain"
+	// setting also enables it.
+	Plain bool
+}
+
+// Compare prints two runs side by side: title, runner, setup result and
+// duration, verify results, diff size vs the parent branch, and report
+// size. It helps pick between runs that attempted the same task.
+// This is a read-only command: no state files are mutated.
+func Compare(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts CompareOpts, stdout, stderr io.Writer) error {
+	if opts.Task != "" && (opts.RunA != "" || opts.RunB != "") {
+		return errors.New(errors.EUsage, "--task cannot be combined with run ids")
+	}
+	if opts.Task == "" && (opts.RunA == "" || opts.RunB == "") {
+		return errors.New(errors.EUsage, "two run ids are required")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+	plain := opts.Plain
+	if !opts.JSON {
+		if plain, _, err = resolveHumanOutput(fsys, dirs.ConfigDir, opts.Plain, stdout); err != nil {
+			return err
+		}
+	}
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+	commits := newCommitCountSet(ctx, cr, fsys, dirs.CacheDir)
+	var records [2]store.RunRecord
+	if opts.Task != "" {
+		if records, err = taskRuns(dirs.DataDir, opts.Task, scope, crypter); err != nil {
+			return err
+		}
+	} else {
+		for i, input := range []string{opts.RunA, opts.RunB} {
+			record, err := resolveRun(dirs.DataDir, input, scope, crypter)
+			if err != nil {
+				return err
+			}
+			records[i] = *record
+		}
+	}
+	var runs [2]render.CompareRun
+	for i, record := range records {
+		runs[i] = compareRun(ctx, cr, st, commits, record)
+	}
+	commits.Save()
+
+	if opts.JSON {
+		return render.WriteCompareJSON(stdout, runs[0], runs[1])
+	}
+	return render.WriteCompareHuman(stdout, runs[0], runs[1], plain)
+}
+
+// taskRuns returns the two runs of a task (meta.task_id), oldest run_id
+// first. Returns E_RUN_NOT_FOUND if the task has no runs, and E_USAGE if it
+// does not have exactly two (e.g. one failed, or three runners).
+func taskRuns(dataDir, taskID string, scope runScope, crypter *crypt.Crypt) ([2]store.RunRecord, error) {
+	var all []store.RunRecord
+	var err error
+	if scope.RepoID != "" {
+		all, err = store.ScanRunsForRepo(dataDir, scope.RepoID, crypter)
+	} else {
+		all, err = store.ScanAllRuns(dataDir, crypter)
+	}
+	if err != nil {
+		return [2]store.RunRecord{}, err
+	}
+	var matched []store.RunRecord
+	var runIDs []string
+	for _, rec := range all {
+		if !rec.Broken && rec.Meta != nil && rec.Meta.TaskID == taskID {
+			matched = append(matched, rec)
+		}
+	}
+	slices.SortFunc(matched, func(a, b store.RunRecord) int { return strings.Compare(a.RunID, b.RunID) })
+	for _, rec := range matched {
+		runIDs = append(runIDs, rec.RunID)
+	}
+	switch len(matched) {
+	case 0:
+		return [2]store.RunRecord{}, errors.NewWithDetails(errors.ERunNotFound,
+			"no runs found for task "+taskID, map[string]string{"task_id": taskID})
+	case 2:
+		for _, rec := range matched {
+			audit.Touch(rec.RepoID, rec.RunID)
+		}
+		return [2]store.RunRecord{matched[0], matched[1]}, nil
+	}
+	return [2]store.RunRecord{}, errors.WithHints(errors.NewWithDetails(errors.EUsage,
+		fmt.Sprintf("task %s has %d runs, not 2: %s", taskID, len(matched), strings.Join(runIDs, ", ")),
+		map[string]string{"task_id": taskID}), "compare two of them with: agency compare <run_a> <run_b>")
+}
+
+// compareRun gathers one run's side of the comparison. Values that cannot
+// be read (verify history, git) are left null.
+func compareRun(ctx context.Context, cr agencyexec.CommandRunner, st *store.Store, commits *commitCountSet, rec store.RunRecord) render.CompareRun {
+	meta := rec.Meta
+	worktreePresent := dirExists(meta.WorktreePath)
+	run := render.CompareRun{
+		RunID:        rec.RunID,
+		RepoID:       rec.RepoID,
+		Title:        meta.Title,
+		Runner:       meta.Runner,
+		Branch:       meta.Branch,
+		ParentBranch: meta.ParentBranch,
+		CreatedAt:    meta.CreatedAt,
+		Archived:     !worktreePresent,
+		Setup:        compareSetup(meta),
+	}
+
+	if history, _ := st.ReadVerifyHistory(rec.RepoID, rec.RunID); len(history) > 0 {
+		last := history[len(history)-1]
+		v := &render.CompareVerify{
+			Attempts:       len(history),
+			LastOK:         last.OK,
+			LastAt:         last.Timestamp,
+			LastDurationMs: last.DurationMs,
+			LastSummary:    last.Summary,
+		}
+		for _, a := range history {
+			if a.OK {
+				v.Passed++
+			}
+		}
+		run.Verify = v
+	}
+
+	if !worktreePresent {
+		return run
+	}
+	run.ReportBytes = readReportSnapshot(ctx, cr, meta, true).Bytes
+	if counts, ok := commits.Counts(rec); ok {
+		stat, err := git.DiffShortStat(ctx, cr, meta.WorktreePath, meta.ParentBranch, meta.Branch)
+		if err == nil {
+			run.Diff = &render.CompareDiff{
+				Commits:      counts.Ahead,
+				FilesChanged: stat.FilesChanged,
+				Insertions:   stat.Insertions,
+				Deletions:    stat.Deletions,
+			}
+		}
+	}
+	return run
+}
+
+// compareSetup returns meta's setup result (nil if setup never ran).
+func compareSetup(meta *store.RunMeta) *render.CompareSetup {
+	if slices.Contains(meta.SkippedSteps, "setup") {
+		return &render.CompareSetup{Status: "skipped"}
+	}
+	if meta.Setup == nil {
+		return nil
+	}
+	s := &render.CompareSetup{ExitCode: meta.Setup.ExitCode, DurationMs: meta.Setup.DurationMs}
+	switch {
+	case meta.Setup.TimedOut:
+		s.Status = "timed_out"
+	case meta.Setup.ExitCode == 0:
+		s.Status = "ok"
+	default:
+		s.Status = "failed"
+	}
+	return s
+}
@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestCompare(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	repoID := "abc123"
+	t0 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	clk := testkit.NewClock(t0)
+	defer SetClock(clk.Core())()
+
+	runA, runB := "20260110120000-a3f2", "20260110120000-b4c1"
+	worktree := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(worktree, ".agency"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, ".agency", "report.md"), []byte("# fixed the login bug\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a := testkit.NewRunMeta(repoID, runA, worktree, t0)
+	a.Setup = &store.RunMetaSetup{ExitCode: 0, DurationMs: 12300}
+	testkit.WriteRun(t, dataDir, a)
+	b := testkit.NewRunMeta(repoID, runB, filepath.Join(dataDir, "gone"), t0)
+	b.Runner = "codex"
+	b.Setup = &store.RunMetaSetup{ExitCode: 2, DurationMs: 4100}
+	testkit.WriteRun(t, dataDir, b)
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, clk.Now)
+	for _, ok := range []bool{false, true} {
+		if _, err := st.AppendVerifyAttempt(repoID, runA, store.VerifyAttempt{Timestamp: t0.Format(time.RFC3339), OK: ok, DurationMs: 40000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cr := testkit.NewFakeRunner()
+	cr.On("git", "for-each-ref", "--format=%(objectname) %(refname)", "refs/heads").Stdout(
+		"1111 refs/heads/main\n2222 refs/heads/" + a.Branch + "\n")
+	cr.On("git", "rev-list", "--left-right", "--count", "1111...2222").Stdout("0\t2\n")
+	cr.On("git", "diff", "--shortstat", "main..."+a.Branch).Stdout(" 4 files changed, 120 insertions(+), 30 deletions(-)\n")
+
+	var stdout, stderr bytes.Buffer
+	opts := CompareOpts{RunA: runA, RunB: "20260110120000-b", JSON: true}
+	if err := Compare(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Compare() error = %v (stderr %q)", err, stderr.String())
+	}
+	var env render.CompareJSONEnvelope
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	got := env.Data
+	if got[0].RunID != runA || got[1].RunID != runB || got[1].Runner != "codex" {
+		t.Errorf("runs = %s/%s, %s/%s", got[0].RunID, got[0].Runner, got[1].RunID, got[1].Runner)
+	}
+	if got[0].Setup == nil || got[0].Setup.Status != "ok" || got[1].Setup == nil || got[1].Setup.Status != "failed" {
+		t.Errorf("setup = %+v, %+v", got[0].Setup, got[1].Setup)
+	}
+	if v := got[0].Verify; v == nil || v.Attempts != 2 || v.Passed != 1 || !v.LastOK {
+		t.Errorf("verify a = %+v", v)
+	}
+	if got[1].Verify != nil {
+		t.Errorf("verify b = %+v, want null", got[1].Verify)
+	}
+	if d := got[0].Diff; d == nil || *d != (render.CompareDiff{Commits: 2, FilesChanged: 4, Insertions: 120, Deletions: 30}) {
+		t.Errorf("diff a = %+v", d)
+	}
+	if !got[1].Archived || got[1].Diff != nil || got[1].ReportBytes != 0 {
+		t.Errorf("archived run b = %+v", got[1])
+	}
+	if got[0].ReportBytes != len("# fixed the login bug\n") {
+		t.Errorf("report_bytes a = %d", got[0].ReportBytes)
+	}
+
+	stdout.Reset()
+	opts.JSON = false
+	if err := Compare(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Compare() human error = %v", err)
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"runner   claude",
+		"setup    ok 12.3s",
+		"FAIL exit 2 4.1s",
+		"verify   ok 40s (1/2 passed)",
+		"diff     +120 -30, 4 file(s), 2 commit(s)  -",
+		"report   22 bytes",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("human output missing %q:\n%s", want, out)
+		}
+	}
+
+	stdout.Reset()
+	opts.Plain = true
+	if err := Compare(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Compare() plain error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "runner_a: claude\nrunner_b: codex\n") {
+		t.Errorf("plain output:\n%s", stdout.String())
+	}
+}
+
+func TestCompare_Task(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	t0 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	clk := testkit.NewClock(t0)
+	defer SetClock(clk.Core())()
+
+	task := "20260110120000-c7d1"
+	for runID, taskID := range map[string]string{
+		"20260110120000-b4c1": task,
+		"20260110120000-a3f2": task,
+		"20260110120000-e9f3": "",
+	} {
+		m := testkit.NewRunMeta("abc123", runID, filepath.Join(dataDir, "gone"), t0)
+		m.TaskID = taskID
+		testkit.WriteRun(t, dataDir, m)
+	}
+
+	var stdout, stderr bytes.Buffer
+	opts := CompareOpts{Task: task, JSON: true}
+	if err := Compare(context.Background(), testkit.NewFakeRunner(), fs.NewRealFS(), t.TempDir(), opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	var env render.CompareJSONEnvelope
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if env.Data[0].RunID != "20260110120000-a3f2" || env.Data[1].RunID != "20260110120000-b4c1" {
+		t.Errorf("runs = %s, %s", env.Data[0].RunID, env.Data[1].RunID)
+	}
+
+	// A third run makes the pair ambiguous
+	m := testkit.NewRunMeta("abc123", "20260110120000-f0a4", filepath.Join(dataDir, "gone"), t0)
+	m.TaskID = task
+	testkit.WriteRun(t, dataDir, m)
+	err := Compare(context.Background(), testkit.NewFakeRunner(), fs.NewRealFS(), t.TempDir(), opts, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("three runs: code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+
+	opts.Task = "20260110120000-0000"
+	err = Compare(context.Background(), testkit.NewFakeRunner(), fs.NewRealFS(), t.TempDir(), opts, &stdout, &stderr)
+	if errors.GetCode(err) != errors.ERunNotFound {
+		t.Errorf("unknown task: code = %q, want %q", errors.GetCode(err), errors.ERunNotFound)
+	}
+}
+
+func TestCompare_RequiresTwoRuns(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Compare(context.Background(), testkit.NewFakeRunner(), fs.NewRealFS(), t.TempDir(), CompareOpts{RunA: "a3f2"}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
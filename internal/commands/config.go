@@ -0,0 +1,309 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/paths"
+	"github.com/NielsdaWheelz/agency/internal/render"
+)
+
+// Setting origins shown by agency config list.
+const (
+	originDefault = "default"
+	originUser    = "user"
+	originRepo    = "repo"
+	originEnv     = "env"
+)
+
+// pathConfigKeys are the keys agency config shows but cannot set: they are
+// resolved from the environment and agency.json, not the user config.
+var pathConfigKeys = []string{"data_dir", "config_dir"}
+
+// ConfigOpts holds options for the config subcommands.
+type ConfigOpts struct {
+	// Key is the setting for get and set.
+	Key string
+
+	// Value is the new value for set.
+	Value string
+
+	// JSON prints list output as JSON (stable format).
+	JSON bool
+
+	// PlainEnv reports that AGENCY_PLAIN or TERM=dumb selects plain output
+	// (resolved by the cli), which list shows as plain's origin.
+	PlainEnv bool
+
+	// EditFile opens a file in the user's editor and returns once it is
+	// closed (default: $VISUAL, $EDITOR, or vi).
+	EditFile func(path string) error
+
+	// Confirm asks whether to edit again after a failed validation.
+	// Nil means non-interactive: an invalid edit is discarded.
+	Confirm func(prompt string) bool
+}
+
+// ConfigList prints every setting with its effective value and origin.
+//
+// Error codes:
+//   - E_INVALID_USER_CONFIG: the user config file is invalid
+func ConfigList(fsys fs.FS, cwd string, opts ConfigOpts, stdout, stderr io.Writer) error {
+	settings, err := configSettings(fsys, cwd, opts.PlainEnv)
+	if err != nil {
+		return err
+	}
+	if opts.JSON {
+		return render.WriteConfigJSON(stdout, settings)
+	}
+	return render.WriteConfigHuman(stdout, settings)
+}
+
+// ConfigGet prints the effective value of one setting.
+//
+// Error codes:
+//   - E_USAGE: unknown key
+//   - E_INVALID_USER_CONFIG: the user config file is invalid
+func ConfigGet(fsys fs.FS, cwd string, opts ConfigOpts, stdout, stderr io.Writer) error {
+	settings, err := configSettings(fsys, cwd, opts.PlainEnv)
+	if err != nil {
+		return err
+	}
+	for _, s := range settings {
+		if s.Key == opts.Key {
+			fmt.Fprintln(stdout, s.Value)
+			return nil
+		}
+	}
+	return unknownConfigKey(opts.Key)
+}
+
+// ConfigSet sets one user config key in ${AGENCY_CONFIG_DIR}/config.json.
+//
+// Error codes:
+//   - E_USAGE: unknown or read-only key, or an invalid value
+//   - E_INVALID_USER_CONFIG: the existing user config file is invalid
+func ConfigSet(fsys fs.FS, cwd string, opts ConfigOpts, stdout, stderr io.Writer) error {
+	switch opts.Key {
+	case "data_dir":
+		return errors.WithHints(errors.New(errors.EUsage, "data_dir cannot be set with agency config"),
+			"set AGENCY_DATA_DIR, or data_dir in the repo's agency.json")
+	case "config_dir":
+		return errors.WithHints(errors.New(errors.EUsage, "config_dir cannot be set with agency config"),
+			"set AGENCY_CONFIG_DIR")
+	}
+	if !config.IsUserConfigKey(opts.Key) {
+		return unknownConfigKey(opts.Key)
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	if err := config.SetUserConfigValue(fsys, dirs.ConfigDir, opts.Key, opts.Value); err != nil {
+		return err
+	}
+	if opts.Key == "plain" && opts.PlainEnv {
+		fmt.Fprintln(stderr, "note: AGENCY_PLAIN or TERM=dumb selects plain output regardless of this setting")
+	}
+	return nil
+}
+
+// ConfigEdit opens the user config in an editor and saves it only if it is
+// valid. Edits happen on a copy, so an invalid config is never written; with
+// Confirm the user can fix it and try again.
+//
+// Error codes:
+//   - E_INVALID_USER_CONFIG: the edited config is invalid (and was discarded)
+func ConfigEdit(fsys fs.FS, cwd string, opts ConfigOpts, stdout, stderr io.Writer) error {
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	path := config.UserConfigPath(dirs.ConfigDir)
+
+	original, err := fsys.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.WrapWithDetails(errors.EInvalidUserConfig, "failed to read user config", err,
+			map[string]string{"path": path})
+	}
+	content := original
+	if content == nil {
+		content = []byte(defaultUserConfigJSON)
+	}
+
+	if err := fsys.MkdirAll(dirs.ConfigDir, 0o755); err != nil {
+		return errors.WrapWithDetails(errors.EInternal, "failed to create config dir", err,
+			map[string]string{"path": dirs.ConfigDir})
+	}
+	tmpPath, w, err := fsys.CreateTemp(dirs.ConfigDir, "config-*.json")
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to create temp file", err)
+	}
+	defer fsys.Remove(tmpPath)
+	_, werr := w.Write(content)
+	if cerr := w.Close(); werr == nil {
+		werr = cerr
+	}
+	if werr != nil {
+		return errors.Wrap(errors.EInternal, "failed to write temp file", werr)
+	}
+
+	editFile := opts.EditFile
+	if editFile == nil {
+		editFile = runEditor
+	}
+	for {
+		if err := editFile(tmpPath); err != nil {
+			return errors.Wrap(errors.EInternal, "editor failed; config.json was not changed", err)
+		}
+		edited, err := fsys.ReadFile(tmpPath)
+		if err != nil {
+			return errors.Wrap(errors.EInternal, "failed to read edited config", err)
+		}
+		if original != nil && bytes.Equal(edited, original) {
+			fmt.Fprintln(stdout, "config unchanged")
+			return nil
+		}
+
+		if _, err := config.ParseUserConfig(edited, path); err != nil {
+			msg := err.Error()
+			if ae, ok := errors.AsAgencyError(err); ok {
+				msg = ae.Msg
+			}
+			fmt.Fprintf(stderr, "invalid config: %s\n", msg)
+			if opts.Confirm != nil && opts.Confirm("edit again?") {
+				continue
+			}
+			return errors.WithHints(err, "config.json was not changed")
+		}
+
+		if err := fs.WriteFileAtomic(fsys, path, edited, 0o644); err != nil {
+			return errors.WrapWithDetails(errors.EInternal, "failed to write user config", err,
+				map[string]string{"path": path})
+		}
+		fmt.Fprintf(stdout, "wrote %s\n", path)
+		return nil
+	}
+}
+
+// defaultUserConfigJSON is what config edit starts from when there is no
+// user config yet: every key at its default.
+const defaultUserConfigJSON = `{
+  "version": 1,
+  "ls": {
+    "archived": false,
+    "broken": true
+  },
+  "plain": false,
+  "attach": {
+    "status": true,
+    "status_format": "",
+    "window": false,
+    "layout": "window",
+    "refresh_env": false
+  },
+  "repos": {
+    "capabilities_ttl_hours": 24
+  },
+  "network": {
+    "timeout_seconds": 120,
+    "retries": 2
+  },
+  "encryption": {
+    "enabled": false,
+    "identity": ""
+  },
+  "digest": {
+    "webhook_url": ""
+  },
+  "scheduling": {
+    "max_sessions": 0,
+    "min_free_disk_mb": 0,
+    "max_load": 0
+  }
+}
+`
+
+// configSettings returns every setting in list order: the user config keys,
+// then the resolved paths.
+func configSettings(fsys fs.FS, cwd string, plainEnv bool) ([]render.ConfigSetting, error) {
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadUserConfig(fsys, dirs.ConfigDir)
+	if err != nil {
+		return nil, err
+	}
+	inFile, err := config.UserConfigFileKeys(fsys, dirs.ConfigDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []render.ConfigSetting
+	for _, key := range config.UserConfigKeys() {
+		value, _ := config.UserConfigValue(cfg, key)
+		origin := originDefault
+		if inFile[key] {
+			origin = originUser
+		}
+		if key == "plain" && plainEnv {
+			value, origin = "true", originEnv
+		}
+		settings = append(settings, render.ConfigSetting{Key: key, Value: value, Origin: origin})
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, errors.Wrap(errors.EInternal, "failed to get home directory", err)
+	}
+	base := paths.ResolveDirs(osEnv{}, homeDir)
+	dataOrigin := originDefault
+	switch {
+	case os.Getenv("AGENCY_DATA_DIR") != "":
+		dataOrigin = originEnv
+	case dirs.DataDir != base.DataDir:
+		dataOrigin = originRepo
+	}
+	configOrigin := originDefault
+	if os.Getenv("AGENCY_CONFIG_DIR") != "" {
+		configOrigin = originEnv
+	}
+	settings = append(settings,
+		render.ConfigSetting{Key: "data_dir", Value: dirs.DataDir, Origin: dataOrigin},
+		render.ConfigSetting{Key: "config_dir", Value: dirs.ConfigDir, Origin: configOrigin},
+	)
+	return settings, nil
+}
+
+// unknownConfigKey is the E_USAGE error for a key agency config does not know.
+func unknownConfigKey(key string) error {
+	known := append(config.UserConfigKeys(), pathConfigKeys...)
+	return errors.WithHints(errors.New(errors.EUsage, "unknown config key: "+key),
+		"known keys: "+strings.Join(known, ", "))
+}
+
+// runEditor opens path in $VISUAL, $EDITOR, or vi, attached to the terminal.
+// The editor value goes through sh so it may carry arguments ("code -w").
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "agency-editor", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
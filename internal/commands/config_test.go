@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestConfigSetGetList(t *testing.T) {
+	dataDir := t.TempDir()
+	configDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", configDir)
+	fsys := fs.NewRealFS()
+	cwd := t.TempDir()
+
+	if err := ConfigSet(fsys, cwd, ConfigOpts{Key: "ls.archived", Value: "true"}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("ConfigSet() error = %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if err := ConfigGet(fsys, cwd, ConfigOpts{Key: "ls.archived"}, &stdout, io.Discard); err != nil {
+		t.Fatalf("ConfigGet() error = %v", err)
+	}
+	if got := stdout.String(); got != "true\n" {
+		t.Errorf("ConfigGet(ls.archived) = %q, want %q", got, "true\n")
+	}
+
+	stdout.Reset()
+	if err := ConfigList(fsys, cwd, ConfigOpts{PlainEnv: true}, &stdout, io.Discard); err != nil {
+		t.Fatalf("ConfigList() error = %v", err)
+	}
+	for _, want := range []string{
+		"user     ls.archived=true\n",
+		"default  ls.broken=true\n",
+		"env      plain=true\n",
+		"env      data_dir=" + dataDir + "\n",
+		"env      config_dir=" + configDir + "\n",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("ConfigList() = %q, want line %q", stdout.String(), want)
+		}
+	}
+
+	for _, tt := range []struct{ key, value string }{{"nope", "1"}, {"data_dir", "/tmp/x"}, {"plain", "maybe"}} {
+		err := ConfigSet(fsys, cwd, ConfigOpts{Key: tt.key, Value: tt.value}, io.Discard, io.Discard)
+		if errors.GetCode(err) != errors.EUsage {
+			t.Errorf("ConfigSet(%s=%s) code = %q, want %q", tt.key, tt.value, errors.GetCode(err), errors.EUsage)
+		}
+	}
+	if err := ConfigGet(fsys, cwd, ConfigOpts{Key: "nope"}, io.Discard, io.Discard); errors.GetCode(err) != errors.EUsage {
+		t.Errorf("ConfigGet(nope) code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
+
+func TestConfigEdit(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", t.TempDir())
+	t.Setenv("AGENCY_CONFIG_DIR", configDir)
+	fsys := fs.NewRealFS()
+	path := filepath.Join(configDir, "config.json")
+
+	// An invalid edit is discarded unless the user edits again
+	edits := []string{`{"plain": "yes"}`, `{"version": 1, "plain": true}`}
+	var asked int
+	opts := ConfigOpts{
+		EditFile: func(p string) error {
+			content := edits[0]
+			edits = edits[1:]
+			return os.WriteFile(p, []byte(content), 0o644)
+		},
+		Confirm: func(string) bool { asked++; return true },
+	}
+	var stderr bytes.Buffer
+	if err := ConfigEdit(fsys, t.TempDir(), opts, io.Discard, &stderr); err != nil {
+		t.Fatalf("ConfigEdit() error = %v", err)
+	}
+	if asked != 1 || !strings.Contains(stderr.String(), "plain must be a boolean") {
+		t.Errorf("asked = %d, stderr = %q; want one retry after the validation error", asked, stderr.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != `{"version": 1, "plain": true}` {
+		t.Errorf("config.json = %q, %v; want the second edit", data, err)
+	}
+
+	// Without Confirm an invalid edit fails and leaves the file alone
+	opts = ConfigOpts{EditFile: func(p string) error { return os.WriteFile(p, []byte(`{`), 0o644) }}
+	if err := ConfigEdit(fsys, t.TempDir(), opts, io.Discard, io.Discard); errors.GetCode(err) != errors.EInvalidUserConfig {
+		t.Errorf("ConfigEdit(invalid) code = %q, want %q", errors.GetCode(err), errors.EInvalidUserConfig)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(data) {
+		t.Errorf("config.json changed to %q after an invalid edit", after)
+	}
+	entries, _ := os.ReadDir(configDir)
+	if len(entries) != 1 {
+		t.Errorf("config dir has %d entries, want only config.json (temp copy removed)", len(entries))
+	}
+}
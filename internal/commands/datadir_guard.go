@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/version"
+)
+
+// DataDirAccess is how a command uses the data dir, for GuardDataDir.
+type DataDirAccess int
+
+const (
+	// DataDirRead commands only read the data dir (ls, show, report, ...).
+	DataDirRead DataDirAccess = iota
+
+	// DataDirWrite commands may write the data dir (run, kill, note, ...).
+	DataDirWrite
+)
+
+// GuardDataDir checks the data dir resolved from cwd against this build's
+// supported data formats before a command runs, so an older agency never
+// writes a newer layout (or the reverse across breaking changes).
+//
+// With forceReadOnly, read commands proceed on a skewed data dir after a
+// warning on stderr, and write commands are refused with E_USAGE.
+// Write commands create an agency.json data_dir override if missing and
+// record this build in state.json (best-effort); read commands never create
+// or write anything.
+//
+// Returns E_DATA_DIR_VERSION_SKEW on skew, E_STORE_CORRUPT if state.json is
+// unreadable, or E_INVALID_DATA_DIR if a write command's data_dir override
+// is not writable. Directory resolution errors are left for the command to
+// report.
+func GuardDataDir(fsys fs.FS, cwd string, access DataDirAccess, forceReadOnly bool, stderr io.Writer) error {
+	if forceReadOnly && access == DataDirWrite {
+		return errors.New(errors.EUsage, "--force-read-only only allows read-only commands (ls, show, report, diff-env, lint without --fix)")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return nil
+	}
+	if access == DataDirWrite {
+		if err := ensureDataDirOverride(fsys, cwd, dirs.DataDir); err != nil {
+			return err
+		}
+	}
+
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now)
+	state, err := st.ReadDataDirState()
+	if err == nil {
+		err = store.CheckDataDirState(dirs.DataDir, state, version.Version)
+	}
+	if err != nil {
+		if forceReadOnly {
+			fmt.Fprintf(stderr, "warning: %s\nwarning: continuing read-only (--force-read-only)\n", err)
+			return nil
+		}
+		return err
+	}
+
+	if access == DataDirWrite {
+		_ = st.StampDataDirState(version.Version)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestGuardDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	statePath := filepath.Join(dataDir, store.DataDirStateFileName)
+	cwd := t.TempDir()
+	fsys := fs.NewRealFS()
+
+	// No state.json: reads pass without writing; writes stamp the data dir
+	var stderr bytes.Buffer
+	if err := GuardDataDir(fsys, cwd, DataDirRead, false, &stderr); err != nil {
+		t.Fatalf("read guard on fresh data dir: %v", err)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Fatalf("read guard should not write state.json (stat err = %v)", err)
+	}
+	if err := GuardDataDir(fsys, cwd, DataDirWrite, false, &stderr); err != nil {
+		t.Fatalf("write guard on fresh data dir: %v", err)
+	}
+	state, err := store.NewStore(fsys, dataDir, nil).ReadDataDirState()
+	if err != nil || state == nil || state.DataFormat != store.DataFormat {
+		t.Fatalf("state after write guard = %+v, %v", state, err)
+	}
+
+	// Newer data format: everything fails unless --force-read-only on a read
+	newer := `{"schema_version": "1.0", "data_format": 99, "last_written_by": "v9.0.0"}`
+	if err := os.WriteFile(statePath, []byte(newer), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, access := range []DataDirAccess{DataDirRead, DataDirWrite} {
+		err := GuardDataDir(fsys, cwd, access, false, &stderr)
+		if errors.GetCode(err) != errors.EDataDirVersionSkew {
+			t.Errorf("access %d: code = %q, want %q", access, errors.GetCode(err), errors.EDataDirVersionSkew)
+		}
+	}
+	if err := GuardDataDir(fsys, cwd, DataDirRead, true, &stderr); err != nil {
+		t.Errorf("forced read guard: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "v9.0.0") || !strings.Contains(stderr.String(), "continuing read-only") {
+		t.Errorf("stderr = %q, want skew warning", stderr.String())
+	}
+	if err := GuardDataDir(fsys, cwd, DataDirWrite, true, &stderr); errors.GetCode(err) != errors.EUsage {
+		t.Errorf("forced write guard: code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+
+	// The skewed state.json is left untouched
+	if data, _ := os.ReadFile(statePath); string(data) != newer {
+		t.Errorf("state.json was modified: %s", data)
+	}
+}
+
+func TestGuardDataDir_Override(t *testing.T) {
+	t.Setenv("AGENCY_DATA_DIR", "")
+	shared := filepath.Join(t.TempDir(), "shared")
+	repoRoot := testkit.NewRepo(t, testkit.RepoOpts{
+		AgencyJSON: strings.Replace(testkit.DefaultAgencyJSON, `"version": 1,`, `"version": 1, "data_dir": "`+shared+`",`, 1),
+	})
+	fsys := fs.NewRealFS()
+
+	// Reads never create the override; writes do
+	if err := GuardDataDir(fsys, repoRoot, DataDirRead, false, io.Discard); err != nil {
+		t.Fatalf("read guard: %v", err)
+	}
+	if _, err := os.Stat(shared); !os.IsNotExist(err) {
+		t.Fatalf("read guard created %s (stat err = %v)", shared, err)
+	}
+	if err := GuardDataDir(fsys, repoRoot, DataDirWrite, false, io.Discard); err != nil {
+		t.Fatalf("write guard: %v", err)
+	}
+	if info, err := os.Stat(shared); err != nil || !info.IsDir() {
+		t.Errorf("write guard did not create %s: %v", shared, err)
+	}
+}
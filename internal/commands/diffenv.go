@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"io"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// DiffEnvOpts holds options for the diff-env command.
+type DiffEnvOpts struct {
+	// RunA and RunB are the run identifiers (exact or unique prefix) to compare.
+	RunA string
+	RunB string
+
+	// All includes keys that differ between any two runs by construction
+	// (AGENCY_RUN_ID, AGENCY_BRANCH, worktree paths, ...).
+	All bool
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+}
+
+// runSpecificSetupEnvKeys always differ between runs and hide real differences.
+var runSpecificSetupEnvKeys = map[string]bool{
+	"env.AGENCY_RUN_ID":         true,
+	"env.AGENCY_TITLE":          true,
+	"env.AGENCY_BRANCH":         true,
+	"env.AGENCY_WORKSPACE_ROOT": true,
+	"env.AGENCY_DOTAGENCY_DIR":  true,
+	"env.AGENCY_OUTPUT_DIR":     true,
+	"env.AGENCY_LOG_DIR":        true,
+}
+
+// DiffEnv compares the setup environments captured for two runs (see
+// `agency show --setup-env`) and prints the keys that differ.
+// Returns E_SETUP_ENV_NOT_FOUND if either run has no capture.
+func DiffEnv(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts DiffEnvOpts, stdout, stderr io.Writer) error {
+	if opts.RunA == "" || opts.RunB == "" {
+		return errors.New(errors.EUsage, "two run ids are required")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+
+	var records [2]*store.RunRecord
+	var envs [2]*store.SetupEnv
+	for i, input := range []string{opts.RunA, opts.RunB} {
+		records[i], err = resolveRun(dirs.DataDir, input, scope, crypter)
+		if err != nil {
+			return err
+		}
+		envs[i], err = readSetupEnv(fsys, dirs.DataDir, records[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	var diffs []store.SetupEnvDiff
+	hidden := 0
+	for _, d := range store.DiffSetupEnv(envs[0], envs[1]) {
+		if !opts.All && runSpecificSetupEnvKeys[d.Key] {
+			hidden++
+			continue
+		}
+		diffs = append(diffs, d)
+	}
+
+	return render.WriteSetupEnvDiff(stdout, records[0].RunID, records[1].RunID, envs[0], envs[1], diffs, hidden)
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+func writeTestSetupEnv(t *testing.T, dataDir, repoID, runID string, mutate func(*store.SetupEnv)) {
+	t.Helper()
+	env := store.SetupEnv{
+		SchemaVersion: "1.0",
+		CapturedAt:    "2026-01-10T12:00:00Z",
+		Hostname:      "build-1",
+		OS:            "linux",
+		Arch:          "amd64",
+		Env:           map[string]string{"AGENCY_RUN_ID": runID, "AGENCY_RUNNER": "claude", "PATH": "/usr/bin"},
+		Tools:         map[string]string{"git": "git version 2.43.0", "gh": "gh version 2.40.0"},
+	}
+	if mutate != nil {
+		mutate(&env)
+	}
+	if err := store.NewStore(fs.NewRealFS(), dataDir, time.Now).WriteSetupEnv(repoID, runID, store.SetupEnvScript, env); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffEnv(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	repoID := "abc123"
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	runA, runB := "20260110120000-a3f2", "20260111090000-b4c1"
+	for _, runID := range []string{runA, runB} {
+		createValidMetaForShow(t, dataDir, repoID, runID, filepath.Join(dataDir, "wt", runID), created)
+	}
+	writeTestSetupEnv(t, dataDir, repoID, runA, nil)
+	writeTestSetupEnv(t, dataDir, repoID, runB, func(env *store.SetupEnv) {
+		env.Hostname = "laptop"
+		env.Env["PATH"] = "/opt/homebrew/bin:/usr/bin"
+		env.Tools["gh"] = ""
+	})
+
+	var stdout, stderr bytes.Buffer
+	opts := DiffEnvOpts{RunA: runA, RunB: "20260111"}
+	if err := DiffEnv(context.Background(), nil, fs.NewRealFS(), dataDir, opts, &stdout, &stderr); err != nil {
+		t.Fatalf("DiffEnv() error = %v", err)
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"env.PATH\n  a: /usr/bin\n  b: /opt/homebrew/bin:/usr/bin\n",
+		"tools.gh\n  a: gh version 2.40.0\n  b: (unavailable)\n",
+		"hostname\n",
+		"3 difference(s) (1 run-specific key(s) hidden; use --all)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "AGENCY_RUN_ID") {
+		t.Errorf("run-specific key shown without --all:\n%s", out)
+	}
+
+	stdout.Reset()
+	opts.All = true
+	if err := DiffEnv(context.Background(), nil, fs.NewRealFS(), dataDir, opts, &stdout, &stderr); err != nil {
+		t.Fatalf("DiffEnv(--all) error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "env.AGENCY_RUN_ID") {
+		t.Errorf("--all should include run-specific keys:\n%s", stdout.String())
+	}
+}
+
+func TestDiffEnv_MissingCapture(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	repoID := "abc123"
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	runA, runB := "20260110120000-a3f2", "20260111090000-b4c1"
+	for _, runID := range []string{runA, runB} {
+		createValidMetaForShow(t, dataDir, repoID, runID, filepath.Join(dataDir, "wt", runID), created)
+	}
+	writeTestSetupEnv(t, dataDir, repoID, runA, nil)
+
+	var stdout, stderr bytes.Buffer
+	err := DiffEnv(context.Background(), nil, fs.NewRealFS(), dataDir, DiffEnvOpts{RunA: runA, RunB: runB}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.ESetupEnvNotFound {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.ESetupEnvNotFound)
+	}
+}
+
+func TestShow_SetupEnv(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	repoID, runID := "abc123", "20260110120000-a3f2"
+	createValidMetaForShow(t, dataDir, repoID, runID, filepath.Join(dataDir, "wt", runID), time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	writeTestSetupEnv(t, dataDir, repoID, runID, nil)
+
+	var stdout, stderr bytes.Buffer
+	opts := ShowOpts{RunID: runID, SetupEnv: true}
+	if err := Show(context.Background(), nil, fs.NewRealFS(), dataDir, opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Show(--setup-env) error = %v", err)
+	}
+	for _, want := range []string{"os: linux/amd64", "git: git version 2.43.0", "AGENCY_RUNNER=claude"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/status"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// DigestFormats lists the digest output formats.
+var DigestFormats = []string{"markdown", "slack"}
+
+// DefaultDigestSince is the digest window when none is given.
+const DefaultDigestSince = 24 * time.Hour
+
+// DigestOpts holds options for the digest command.
+type DigestOpts struct {
+	// AllRepos includes runs from every repo (default: current repo, or all
+	// repos when cwd is not inside a repo, as for report).
+	AllRepos bool
+
+	// Since is the digest window (0 = DefaultDigestSince).
+	Since time.Duration
+
+	// Format is one of DigestFormats ("" = markdown).
+	Format string
+
+	// Post sends the digest to digest.webhook_url instead of printing it.
+	Post bool
+
+	// Now overrides the current time (for tests).
+	Now func() time.Time
+}
+
+// Digest executes the agency digest command: the run activity of the last
+// opts.Since, for posting to a team channel. Runs are listed as created or
+// merged in the window, failed (setup failed, with activity in the window),
+// and awaiting review (currently ready for review, whatever their age).
+// Broken runs are skipped.
+//
+// With opts.Post the digest is posted to the digest.webhook_url of the user
+// config as {"text": ...}, the incoming webhook payload of Slack and
+// compatible chat tools. This is a read-only command.
+//
+// Returns E_USAGE for an unknown format or --post without a webhook URL,
+// and E_WEBHOOK_FAILED if the post fails.
+func Digest(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts DigestOpts, stdout, stderr io.Writer) error {
+	format := opts.Format
+	if format == "" {
+		format = "markdown"
+	}
+	if format != "markdown" && format != "slack" {
+		return errors.New(errors.EUsage, "invalid --format "+strconv.Quote(opts.Format)+": must be one of "+strings.Join(DigestFormats, ", "))
+	}
+	now := clock.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	window := opts.Since
+	if window <= 0 {
+		window = DefaultDigestSince
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	var webhookURL string
+	if opts.Post {
+		cfg, err := config.LoadUserConfig(fsys, dirs.ConfigDir)
+		if err != nil {
+			return err
+		}
+		if cfg.Digest.WebhookURL == "" {
+			return errors.WithHints(errors.New(errors.EUsage, "--post needs digest.webhook_url in config.json"),
+				"set it with: agency config set digest.webhook_url <url>")
+		}
+		webhookURL = cfg.Digest.WebhookURL
+	}
+
+	records, err := scanReportRuns(ctx, cr, dirs.DataDir, cwd, opts.AllRepos, LoadCrypt(cr, fsys))
+	if err != nil {
+		return err
+	}
+	generatedAt := now()
+	digest := newDigest(ctx, cr, fsys, dirs.DataDir, records, generatedAt, generatedAt.Add(-window))
+
+	var buf bytes.Buffer
+	if format == "slack" {
+		err = render.WriteDigestSlack(&buf, digest)
+	} else {
+		err = render.WriteDigestMarkdown(&buf, digest)
+	}
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to render digest", err)
+	}
+
+	if !opts.Post {
+		_, err = stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := postDigest(ctx, webhookURL, NetworkPolicy(fsys).Timeout, buf.String()); err != nil {
+		return err
+	}
+	// The webhook URL is a secret; only its host is shown
+	host := webhookURL
+	if u, err := url.Parse(webhookURL); err == nil {
+		host = u.Host
+	}
+	fmt.Fprintf(stdout, "posted digest to %s (%d run(s))\n", host, digest.RunCount())
+	return nil
+}
+
+// newDigest sorts records into the digest sections for the window
+// [since, generatedAt].
+func newDigest(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, dataDir string, records []store.RunRecord, generatedAt, since time.Time) render.Digest {
+	tmuxSessions := newTmuxSessionSet(ctx, cr)
+	policies := newReviewPolicySet(fsys, dataDir)
+
+	var summaries []render.RunSummary
+	metas := make(map[string]*store.RunMeta)
+	for _, rec := range records {
+		if rec.Broken || rec.Meta == nil {
+			continue
+		}
+		summaries = append(summaries, recordToSummary(ctx, cr, rec, tmuxSessions, policies, nil, fsys))
+		metas[rec.RepoID+"/"+rec.RunID] = rec.Meta
+	}
+	sortSummaries(summaries)
+
+	inWindow := func(stamp string) bool {
+		t, err := time.Parse(time.RFC3339, stamp)
+		return err == nil && !t.Before(since)
+	}
+
+	digest := render.Digest{GeneratedAt: generatedAt, Since: since}
+	for _, s := range summaries {
+		meta := metas[s.RepoID+"/"+s.RunID]
+		run := render.DigestRun{RunID: s.RunID, Repo: s.RepoID, Title: meta.Title}
+		if s.RepoKey != nil && *s.RepoKey != "" {
+			run.Repo = *s.RepoKey
+		}
+		if run.Title == "" {
+			run.Title = meta.Branch
+		}
+		if s.PRNumber != nil {
+			run.PRNumber = *s.PRNumber
+		}
+		if s.PRURL != nil {
+			run.PRURL = *s.PRURL
+		}
+
+		if inWindow(meta.CreatedAt) {
+			digest.Created = append(digest.Created, run)
+		}
+		if meta.Archive != nil && inWindow(meta.Archive.MergedAt) {
+			digest.Merged = append(digest.Merged, run)
+		}
+		switch s.DerivedStatus {
+		case status.StatusFailed:
+			if !lastActivity(meta).Before(since) {
+				failed := run
+				failed.Detail = "setup " + setupOutcome(meta)
+				digest.Failed = append(digest.Failed, failed)
+			}
+		case status.StatusReadyForReview:
+			digest.AwaitingReview = append(digest.AwaitingReview, run)
+		}
+	}
+	return digest
+}
+
+// webhookClient posts digests (replaceable in tests).
+var webhookClient = &http.Client{}
+
+// postDigest posts text to a chat webhook as {"text": text}, giving up
+// after timeout (0 = no limit).
+// Returns E_WEBHOOK_FAILED on errors and non-2xx responses.
+func postDigest(ctx context.Context, webhookURL string, timeout time.Duration, text string) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to encode digest", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(errors.EWebhookFailed, "invalid digest.webhook_url", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		// The error names the URL; keep the secret path out of it
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return errors.Wrap(errors.EWebhookFailed, "failed to post digest", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.NewWithDetails(errors.EWebhookFailed,
+			fmt.Sprintf("failed to post digest: HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(body))),
+			map[string]string{"status": strconv.Itoa(resp.StatusCode)})
+	}
+	return nil
+}
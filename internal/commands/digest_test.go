@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// writeDigestRuns writes runs covering each digest section, relative to now.
+func writeDigestRuns(t *testing.T, dataDir string, now time.Time) {
+	t.Helper()
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	if err := st.SaveRepoRecord(store.RepoRecord{SchemaVersion: "1.0", RepoKey: "github:owner/repo", RepoID: "abc123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	created := testkit.NewRunMeta("abc123", "20260120080000-a3f2", "/gone", now.Add(-4*time.Hour))
+	created.Title = "new <feature>"
+	testkit.WriteRun(t, dataDir, created)
+
+	merged := testkit.NewRunMeta("abc123", "20260115100000-b4c1", "/gone", now.AddDate(0, 0, -5))
+	merged.Title = "login fix"
+	merged.PRNumber = 12
+	merged.PRURL = "https://github.com/owner/repo/pull/12"
+	merged.Archive = &store.RunMetaArchive{MergedAt: now.Add(-2 * time.Hour).Format(time.RFC3339)}
+	testkit.WriteRun(t, dataDir, merged)
+
+	failed := testkit.NewRunMeta("abc123", "20260120060000-c5d2", "/gone", now.Add(-6*time.Hour))
+	failed.Title = "flaky setup"
+	failed.Setup = &store.RunMetaSetup{ExitCode: 2}
+	failed.Flags = &store.RunMetaFlags{SetupFailed: true}
+	testkit.WriteRun(t, dataDir, failed)
+
+	// Ready for review for days: still awaiting review
+	worktree := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(worktree, ".agency"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, ".agency", "report.md"), []byte(strings.Repeat("done. ", 20)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	review := testkit.NewRunMeta("abc123", "20260110100000-d6e3", worktree, now.AddDate(0, 0, -10))
+	review.Title = "refactor"
+	review.PRNumber = 9
+	review.PRURL = "https://github.com/owner/repo/pull/9"
+	review.LastPushAt = now.AddDate(0, 0, -9).Format(time.RFC3339)
+	testkit.WriteRun(t, dataDir, review)
+
+	old := testkit.NewRunMeta("abc123", "20260101100000-e7f4", "/gone", now.AddDate(0, 0, -19))
+	old.Title = "ancient"
+	testkit.WriteRun(t, dataDir, old)
+}
+
+func TestDigest(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	now := time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC)
+	writeDigestRuns(t, dataDir, now)
+
+	cr := testkit.NewFakeRunner()
+	cr.Fallback = &testkit.Response{Result: agencyexec.CmdResult{ExitCode: 1}}
+
+	var stdout bytes.Buffer
+	opts := DigestOpts{AllRepos: true, Now: func() time.Time { return now }}
+	if err := Digest(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, &stdout, io.Discard); err != nil {
+		t.Fatalf("Digest() error = %v", err)
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"_since 2026-01-19 12:00 UTC · 2 created · 1 merged · 1 failed · 1 awaiting review_",
+		"## created (2)\n\n- **new \\<feature\\>** (`20260120080000-a3f2`, github:owner/repo)\n",
+		"## merged (1)\n\n- **login fix** (`20260115100000-b4c1`, github:owner/repo) — [#12](https://github.com/owner/repo/pull/12)\n",
+		"## failed (1)\n\n- **flaky setup** (`20260120060000-c5d2`, github:owner/repo) — setup failed (exit 2)\n",
+		"## awaiting review (1)\n\n- **refactor** (`20260110100000-d6e3`, github:owner/repo) — [#9](https://github.com/owner/repo/pull/9)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "ancient") {
+		t.Errorf("run outside the window included:\n%s", out)
+	}
+
+	stdout.Reset()
+	opts.Format = "slack"
+	if err := Digest(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, &stdout, io.Discard); err != nil {
+		t.Fatalf("Digest(slack) error = %v", err)
+	}
+	for _, want := range []string{
+		"*agency digest* since 2026-01-19 12:00 UTC\n",
+		"*merged (1)*\n• login fix (`20260115100000-b4c1`, github:owner/repo) — <https://github.com/owner/repo/pull/12|#12>\n",
+		"• new &lt;feature&gt; (",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("slack output missing %q:\n%s", want, stdout.String())
+		}
+	}
+
+	opts.Format = "html"
+	if err := Digest(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, io.Discard, io.Discard); errors.GetCode(err) != errors.EUsage {
+		t.Errorf("--format html: code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
+
+func TestDigest_Post(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	configDir := t.TempDir()
+	t.Setenv("AGENCY_CONFIG_DIR", configDir)
+	now := time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC)
+	writeDigestRuns(t, dataDir, now)
+
+	cr := testkit.NewFakeRunner()
+	cr.Fallback = &testkit.Response{Result: agencyexec.CmdResult{ExitCode: 1}}
+	opts := DigestOpts{AllRepos: true, Format: "slack", Post: true, Now: func() time.Time { return now }}
+
+	// No webhook configured
+	err := Digest(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, io.Discard, io.Discard)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Fatalf("--post without webhook: code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+
+	var posted map[string]string
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "invalid_token", http.StatusForbidden)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer server.Close()
+	config := `{"digest": {"webhook_url": "` + server.URL + `/services/T0/B0/secret"}}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if err := Digest(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, &stdout, io.Discard); err != nil {
+		t.Fatalf("Digest(--post) error = %v", err)
+	}
+	if !strings.HasPrefix(posted["text"], "*agency digest*") {
+		t.Errorf("posted text = %q", posted["text"])
+	}
+	if out := stdout.String(); !strings.Contains(out, "posted digest to 127.0.0.1") || strings.Contains(out, "secret") {
+		t.Errorf("stdout = %q", out)
+	}
+
+	fail = true
+	err = Digest(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, io.Discard, io.Discard)
+	if errors.GetCode(err) != errors.EWebhookFailed || !strings.Contains(err.Error(), "HTTP 403") {
+		t.Errorf("failed post: %v (code %q)", err, errors.GetCode(err))
+	}
+}
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/paths"
+	"github.com/NielsdaWheelz/agency/internal/render"
+)
+
+// resolveDirs resolves the agency directories for a command run from dir.
+// DataDir honors the data_dir override in the agency.json enclosing dir
+// (see config.RepoDataDir); outside a repo it is the global data dir.
+func resolveDirs(fsys fs.FS, dir string) (paths.Dirs, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return paths.Dirs{}, errors.Wrap(errors.EInternal, "failed to get home directory", err)
+	}
+	dirs := paths.ResolveDirs(osEnv{}, homeDir)
+
+	if root := config.FindRepoRoot(fsys, dir); root != "" {
+		dataDir, err := config.RepoDataDir(fsys, osEnv{}, dirs, root)
+		if err != nil {
+			return paths.Dirs{}, err
+		}
+		dirs.DataDir = dataDir
+	}
+	return dirs, nil
+}
+
+// ensureDataDirOverride creates the agency.json data_dir override that
+// resolveDirs resolved dataDir from, if there is one, and checks that it is
+// writable (see paths.EnsureDataDir). Resolution itself never writes, so read
+// commands leave a read-only mount alone.
+func ensureDataDirOverride(fsys fs.FS, dir, dataDir string) error {
+	if os.Getenv("AGENCY_DATA_DIR") != "" {
+		return nil
+	}
+	root := config.FindRepoRoot(fsys, dir)
+	if root == "" {
+		return nil
+	}
+	cfg, err := config.LoadAgencyConfig(fsys, root)
+	if err != nil || cfg.DataDir == "" {
+		return nil
+	}
+	return paths.EnsureDataDir(dataDir)
+}
+
+// resolveHumanOutput returns the output settings for human output from the
+// user config: whether plain output is selected, either by flag (--plain,
+// AGENCY_PLAIN, or TERM=dumb, resolved by the cli) or by the "plain" setting,
+// and the status vocabulary (see statusVocabulary).
+// Returns E_INVALID_USER_CONFIG if the user config cannot be read.
+func resolveHumanOutput(fsys fs.FS, configDir string, flag bool, stdout io.Writer) (bool, render.StatusVocabulary, error) {
+	cfg, err := config.LoadUserConfig(fsys, configDir)
+	if err != nil {
+		return false, render.StatusVocabulary{}, err
+	}
+	plain := flag || cfg.Plain
+	return plain, statusVocabulary(cfg, plain, stdout), nil
+}
+
+// statusVocabulary returns the user config "statuses" mapping for human
+// output written to stdout, in color only when stdout is a terminal and
+// NO_COLOR is unset.
+func statusVocabulary(cfg config.UserConfig, plain bool, stdout io.Writer) render.StatusVocabulary {
+	return render.StatusVocabulary{
+		Statuses: cfg.Statuses,
+		Plain:    plain,
+		Color:    isColorTerminal(stdout),
+	}
+}
+
+// isColorTerminal reports whether w is a terminal that takes ANSI colors.
+func isColorTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth returns the columns available to human output written to
+// w: $COLUMNS if set to a positive number, else the width of the terminal w
+// is, or 0 (no limit) when w is not a terminal.
+func terminalWidth(w io.Writer) int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+	var size struct{ rows, cols, xpixel, ypixel uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0
+	}
+	return int(size.cols)
+}
+
+// LoadCrypt returns the encryption at rest of run files set in the user
+// config, running age with cr. Encryption is off if the user config cannot
+// be read. Commands load it once and pass it to the store, scans, and
+// helpers that read or write run files.
+func LoadCrypt(cr agencyexec.CommandRunner, fsys fs.FS) *crypt.Crypt {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return crypt.New(cr, crypt.Config{})
+	}
+	cfg, err := config.LoadUserConfig(fsys, paths.ResolveDirs(osEnv{}, homeDir).ConfigDir)
+	if err != nil {
+		return crypt.New(cr, crypt.Config{})
+	}
+	return crypt.New(cr, crypt.Config{Enabled: cfg.Encryption.Enabled, Identity: cfg.Encryption.Identity})
+}
+
+// NetworkPolicy returns the timeouts and retries for git and gh network
+// commands from the user config's network settings, or the defaults if the
+// user config cannot be read (commands that use it report that themselves).
+func NetworkPolicy(fsys fs.FS) agencyexec.NetworkPolicy {
+	policy := agencyexec.DefaultNetworkPolicy()
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return policy
+	}
+	cfg, err := config.LoadUserConfig(fsys, paths.ResolveDirs(osEnv{}, homeDir).ConfigDir)
+	if err != nil {
+		return policy
+	}
+	policy.Timeout = time.Duration(cfg.Network.TimeoutSeconds) * time.Second
+	policy.Retries = cfg.Network.Retries
+	return policy
+}
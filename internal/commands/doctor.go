@@ -17,8 +17,9 @@ import (
 	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/git"
 	"github.com/NielsdaWheelz/agency/internal/identity"
-	"github.com/NielsdaWheelz/agency/internal/paths"
+	"github.com/NielsdaWheelz/agency/internal/runservice"
 	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/version"
 )
 
 // DoctorReport holds all the data for doctor output.
@@ -43,6 +44,9 @@ type DoctorReport struct {
 	GhVersion      string
 	GhAuthenticated bool
 
+	// TmuxChecks are the tmux version gate and server reachability
+	TmuxChecks []DoctorCheck
+
 	// Config resolution
 	DefaultsParentBranch string
 	DefaultsRunner       string
@@ -50,6 +54,15 @@ type DoctorReport struct {
 	ScriptSetup          string
 	ScriptVerify         string
 	ScriptArchive        string
+
+	// HookScripts maps configured hook points to resolved script paths
+	HookScripts map[string]string
+
+	// Submodules and nested repos (warnings only)
+	RepoChecks []DoctorCheck
+
+	// Data dir health
+	DataDirChecks []DoctorCheck
 }
 
 // osEnv implements paths.Env using os.Getenv.
@@ -62,18 +75,23 @@ func (osEnv) Get(key string) string {
 // Doctor implements the `agency doctor` command.
 // Validates repo, tools, config, scripts, and persists repo identity on success.
 func Doctor(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, stdout, stderr io.Writer) error {
+	return doctor(ctx, cr, fsys, cwd, true, stdout)
+}
+
+// doctor runs the doctor checks from cwd and writes the report to stdout.
+// With persist false it leaves repo.json, the repo index, and the data dir
+// stamp untouched (used by agency bundle).
+func doctor(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, persist bool, stdout io.Writer) error {
 	// 1. Discover repo root
 	repoRoot, err := git.GetRepoRoot(ctx, cr, cwd)
 	if err != nil {
 		return err
 	}
 
-	// 2. Resolve directories
-	homeDir, err := os.UserHomeDir()
+	dirs, err := resolveDirs(fsys, repoRoot.Path)
 	if err != nil {
-		return errors.Wrap(errors.EInternal, "failed to get home directory", err)
+		return err
 	}
-	dirs := paths.ResolveDirs(osEnv{}, homeDir)
 
 	// 3. Load and validate agency.json
 	cfg, err := config.LoadAndValidate(fsys, repoRoot.Path)
@@ -98,14 +116,17 @@ func Doctor(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd st
 		return err
 	}
 
-	ghVersion, err := checkGh(ctx, cr)
-	if err != nil {
-		return err
-	}
-
-	// 7. Check gh auth status
-	if err := checkGhAuth(ctx, cr); err != nil {
-		return err
+	// 7. Check gh and its auth status, unless the repo opted out of the GitHub flow
+	ghFlow := cfg.GitHub.FlowEnabled()
+	var ghVersion string
+	if ghFlow {
+		ghVersion, err = checkGh(ctx, cr)
+		if err != nil {
+			return err
+		}
+		if err := checkGhAuth(ctx, cr); err != nil {
+			return err
+		}
 	}
 
 	// 8. Verify runner command exists
@@ -126,6 +147,17 @@ func Doctor(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd st
 	if err != nil {
 		return err
 	}
+	var hookScripts map[string]string
+	for hook, script := range cfg.Hooks {
+		path, err := checkScript(fsys, script, repoRoot.Path, "hook "+hook)
+		if err != nil {
+			return err
+		}
+		if hookScripts == nil {
+			hookScripts = make(map[string]string)
+		}
+		hookScripts[hook] = path
+	}
 
 	// Build report
 	report := DoctorReport{
@@ -138,25 +170,45 @@ func Doctor(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd st
 		OriginPresent:        originInfo.Present,
 		OriginURL:            originInfo.URL,
 		OriginHost:           originInfo.Host,
-		GitHubFlowAvailable:  repoIdentity.GitHubFlowAvailable,
+		GitHubFlowAvailable:  repoIdentity.GitHubFlowAvailable && ghFlow,
 		GitVersion:           gitVersion,
 		TmuxVersion:          tmuxVersion,
+		TmuxChecks:           checkTmuxEnv(ctx, cr, tmuxVersion),
 		GhVersion:            ghVersion,
-		GhAuthenticated:      true,
+		GhAuthenticated:      ghFlow,
 		DefaultsParentBranch: cfg.Defaults.ParentBranch,
 		DefaultsRunner:       cfg.Defaults.Runner,
 		RunnerCmd:            cfg.ResolvedRunnerCmd,
 		ScriptSetup:          scriptSetup,
 		ScriptVerify:         scriptVerify,
 		ScriptArchive:        scriptArchive,
+		HookScripts:          hookScripts,
+		RepoChecks:           checkRepoLayout(ctx, cr, repoRoot.Path, cfg.Worktrees.Submodules),
+		DataDirChecks:        checkDataDir(ctx, dirs.DataDir, clock.Now(), LoadCrypt(cr, fsys)),
 	}
 
-	// 10. Persist repo index and repo record (only on success)
-	if err := persistOnSuccess(fsys, dirs.DataDir, repoRoot.Path, repoIdentity, originInfo, cfg); err != nil {
+	// 10. tmux and data dir health: failing checks abort before persistence
+	if err := tmuxCheckError(report.TmuxChecks, tmuxVersion); err != nil {
+		writeDoctorOutput(stdout, report)
 		return err
 	}
+	if failed := failedChecks(report.DataDirChecks); len(failed) > 0 {
+		writeDoctorOutput(stdout, report)
+		return errors.New(errors.EDataDirUnhealthy, "data dir health check failed: "+strings.Join(failed, ", "))
+	}
+
+	// 11. Persist repo index and repo record (only on success), with the
+	// script environment show compares runs' setup_env.json against
+	if persist {
+		doctorEnv := runservice.CaptureSetupEnv(ctx, cr, clock.Now(), repoRoot.Path, nil, "")
+		if err := persistOnSuccess(fsys, dirs.DataDir, repoRoot.Path, repoIdentity, originInfo, cfg, &doctorEnv); err != nil {
+			return err
+		}
+		// data_dir_format passed above, so record this build as the last writer
+		_ = store.NewStore(fsys, dirs.DataDir, clock.Now).StampDataDirState(version.Version)
+	}
 
-	// 11. Write output
+	// 12. Write output
 	writeDoctorOutput(stdout, report)
 
 	return nil
@@ -271,8 +323,9 @@ func checkScript(fsys fs.FS, scriptPath, repoRoot, scriptName string) (string, e
 }
 
 // persistOnSuccess writes repo_index.json and repo.json atomically.
-func persistOnSuccess(fsys fs.FS, dataDir, repoRoot string, repoIdentity identity.RepoIdentity, originInfo git.OriginInfo, cfg config.AgencyConfig) error {
-	st := store.NewStore(fsys, dataDir, time.Now)
+// doctorEnv replaces the doctor environment in repo.json (nil = keep it).
+func persistOnSuccess(fsys fs.FS, dataDir, repoRoot string, repoIdentity identity.RepoIdentity, originInfo git.OriginInfo, cfg config.AgencyConfig, doctorEnv *store.SetupEnv) error {
+	st := store.NewStore(fsys, dataDir, clock.Now)
 
 	// Load existing repo index (or empty if missing)
 	idx, err := st.LoadRepoIndex()
@@ -307,8 +360,10 @@ func persistOnSuccess(fsys fs.FS, dataDir, repoRoot string, repoIdentity identit
 		Capabilities: store.Capabilities{
 			GitHubOrigin: repoIdentity.GitHubFlowAvailable,
 			OriginHost:   originInfo.Host,
-			GhAuthed:     true,
+			GhAuthed:     cfg.GitHub.FlowEnabled(),
+			CheckedAt:    clock.Now().UTC().Format(time.RFC3339),
 		},
+		DoctorEnv: doctorEnv,
 	})
 
 	// Save repo record first (so repo dir exists for repo_index to reference)
@@ -343,6 +398,7 @@ func writeDoctorOutput(w io.Writer, r DoctorReport) {
 	// Tooling
 	fmt.Fprintf(w, "git_version: %s\n", r.GitVersion)
 	fmt.Fprintf(w, "tmux_version: %s\n", r.TmuxVersion)
+	writeDoctorChecks(w, r.TmuxChecks)
 	fmt.Fprintf(w, "gh_version: %s\n", r.GhVersion)
 	fmt.Fprintf(w, "gh_authenticated: %s\n", boolStr(r.GhAuthenticated))
 
@@ -353,9 +409,37 @@ func writeDoctorOutput(w io.Writer, r DoctorReport) {
 	fmt.Fprintf(w, "script_setup: %s\n", r.ScriptSetup)
 	fmt.Fprintf(w, "script_verify: %s\n", r.ScriptVerify)
 	fmt.Fprintf(w, "script_archive: %s\n", r.ScriptArchive)
+	for _, hook := range config.HookPoints {
+		if path, ok := r.HookScripts[hook]; ok {
+			fmt.Fprintf(w, "hook_%s: %s\n", hook, path)
+		}
+	}
+
+	// Repo layout
+	writeDoctorChecks(w, r.RepoChecks)
+
+	// Data dir health
+	writeDoctorChecks(w, r.DataDirChecks)
+
+	status := "ok"
+	if len(failedChecks(r.TmuxChecks)) > 0 || len(failedChecks(r.DataDirChecks)) > 0 {
+		status = "fail"
+	}
 
 	// Final
-	fmt.Fprintln(w, "status: ok")
+	fmt.Fprintf(w, "status: %s\n", status)
+}
+
+// writeDoctorChecks writes one "name: status" line per check, with the
+// detail in parentheses when present.
+func writeDoctorChecks(w io.Writer, checks []DoctorCheck) {
+	for _, c := range checks {
+		if c.Detail != "" {
+			fmt.Fprintf(w, "%s: %s (%s)\n", c.Name, c.Status, c.Detail)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", c.Name, c.Status)
+		}
+	}
 }
 
 func boolStr(b bool) string {
@@ -0,0 +1,364 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	agencyfs "github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/version"
+)
+
+// Doctor check statuses.
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Free space thresholds for the data dir.
+const (
+	freeSpaceFailBytes = 64 << 20 // 64 MiB: atomic writes are likely to fail
+	freeSpaceWarnBytes = 1 << 30  // 1 GiB
+)
+
+// orphanTempMinAge is how old an atomic-write temp file must be before it is
+// considered orphaned (younger files may belong to an in-flight write).
+const orphanTempMinAge = 10 * time.Minute
+
+// atomicTempPrefixes are the temp prefixes used by fs.WriteFileAtomic and
+// fs.WriteJSONAtomic. FS.ReplaceDir backups are recovered by the next
+// ReplaceDir instead (see fs.RecoverReplacedDir).
+var atomicTempPrefixes = []string{".agency-tmp-", ".agency-atomic-"}
+
+// DoctorCheck is a single named doctor check result (data dir health, tmux).
+type DoctorCheck struct {
+	Name   string
+	Status string // ok | warn | fail
+	Detail string
+}
+
+// statFreeBytes returns the bytes available to unprivileged users on the
+// filesystem holding path. Overridable in tests.
+var statFreeBytes = func(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
+
+// checkDataDir runs all data dir health checks in a stable order.
+// The data dir is created if missing (doctor persists into it on success).
+func checkDataDir(ctx context.Context, dataDir string, now time.Time, crypter *crypt.Crypt) []DoctorCheck {
+	return []DoctorCheck{
+		checkDataDirWritable(dataDir),
+		checkDataDirFreeSpace(dataDir),
+		checkDataDirTempFiles(dataDir, now),
+		checkDataDirRepoIndex(dataDir),
+		checkDataDirStaleLocks(dataDir, now),
+		checkDataDirFormat(dataDir),
+		checkDataDirShared(dataDir),
+		checkDataDirOwnership(dataDir),
+		checkDataDirEncryption(ctx, crypter),
+	}
+}
+
+func checkDataDirWritable(dataDir string) DoctorCheck {
+	c := DoctorCheck{Name: "data_dir_writable"}
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		c.Status, c.Detail = CheckFail, err.Error()
+		return c
+	}
+	f, err := os.CreateTemp(dataDir, ".agency-doctor-probe-*")
+	if err != nil {
+		c.Status, c.Detail = CheckFail, err.Error()
+		return c
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	c.Status = CheckOK
+	return c
+}
+
+func checkDataDirFreeSpace(dataDir string) DoctorCheck {
+	c := DoctorCheck{Name: "data_dir_free_space"}
+	free, err := statFreeBytes(dataDir)
+	if err != nil {
+		c.Status, c.Detail = CheckWarn, "unable to stat filesystem: "+err.Error()
+		return c
+	}
+	c.Detail = core.FormatBytes(free) + " free"
+	switch {
+	case free < freeSpaceFailBytes:
+		c.Status = CheckFail
+	case free < freeSpaceWarnBytes:
+		c.Status = CheckWarn
+	default:
+		c.Status = CheckOK
+	}
+	return c
+}
+
+func checkDataDirTempFiles(dataDir string, now time.Time) DoctorCheck {
+	c := DoctorCheck{Name: "data_dir_temp_files"}
+	var orphans []string
+	_ = filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !hasAtomicTempPrefix(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || now.Sub(info.ModTime()) < orphanTempMinAge {
+			return nil
+		}
+		orphans = append(orphans, path)
+		return nil
+	})
+	if len(orphans) == 0 {
+		c.Status = CheckOK
+		return c
+	}
+	sort.Strings(orphans)
+	c.Status = CheckWarn
+	c.Detail = fmt.Sprintf("%d orphaned temp file(s), e.g. %s", len(orphans), orphans[0])
+	return c
+}
+
+func checkDataDirRepoIndex(dataDir string) DoctorCheck {
+	c := DoctorCheck{Name: "data_dir_repo_index"}
+	idx, err := store.LoadRepoIndexForScan(dataDir)
+	if err != nil {
+		c.Status, c.Detail = CheckFail, "repo_index.json is unreadable: "+err.Error()
+		return c
+	}
+	if idx == nil {
+		c.Status = CheckOK
+		return c
+	}
+	var missing []string
+	for _, entry := range idx.Repos {
+		for _, p := range entry.Paths {
+			if _, err := os.Stat(p); os.IsNotExist(err) {
+				missing = append(missing, p)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		c.Status = CheckOK
+		return c
+	}
+	sort.Strings(missing)
+	c.Status = CheckWarn
+	c.Detail = fmt.Sprintf("%d indexed path(s) missing, e.g. %s", len(missing), missing[0])
+	return c
+}
+
+func checkDataDirStaleLocks(dataDir string, now time.Time) DoctorCheck {
+	c := DoctorCheck{Name: "data_dir_stale_locks"}
+	matches, _ := filepath.Glob(filepath.Join(dataDir, "repos", "*", ".lock"))
+	var stale []string
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if now.Sub(info.ModTime()) > lock.DefaultStaleAfter {
+			stale = append(stale, path)
+		}
+	}
+	if len(stale) == 0 {
+		c.Status = CheckOK
+		return c
+	}
+	sort.Strings(stale)
+	c.Status = CheckWarn
+	c.Detail = fmt.Sprintf("%d lock file(s) older than %s, e.g. %s", len(stale), lock.DefaultStaleAfter, stale[0])
+	return c
+}
+
+func checkDataDirFormat(dataDir string) DoctorCheck {
+	c := DoctorCheck{Name: "data_dir_format"}
+	state, err := store.NewStore(agencyfs.NewRealFS(), dataDir, clock.Now).ReadDataDirState()
+	if err != nil {
+		c.Status, c.Detail = CheckFail, "state.json is unreadable: "+err.Error()
+		return c
+	}
+	if err := store.CheckDataDirState(dataDir, state, version.Version); err != nil {
+		c.Status, c.Detail = CheckFail, err.Error()
+		return c
+	}
+	c.Status = CheckOK
+	if state == nil {
+		c.Detail = fmt.Sprintf("format %d (no state.json yet)", store.DataFormat)
+	} else {
+		c.Detail = fmt.Sprintf("format %d, last written by agency %s", state.DataFormat, state.LastWrittenBy)
+	}
+	return c
+}
+
+// sharedDirGlobs are the data dir dirs other users of a shared data dir
+// create files in (locks, repo.json, meta.json, events, logs).
+var sharedDirGlobs = []string{"repos", "repos/*", "repos/*/runs", "repos/*/runs/*", "repos/*/runs/*/logs"}
+
+// checkDataDirShared warns, for a shared (group-writable) data dir, about
+// repo and run dirs the group cannot write: other users get
+// E_PERMISSION_DENIED on those repos and runs.
+func checkDataDirShared(dataDir string) DoctorCheck {
+	c := DoctorCheck{Name: "data_dir_shared", Status: CheckOK}
+	if !agencyfs.IsShared(dataDir) {
+		c.Detail = "not shared: data dir is not group-writable"
+		return c
+	}
+	var private []string
+	for _, pattern := range sharedDirGlobs {
+		matches, _ := filepath.Glob(filepath.Join(dataDir, filepath.FromSlash(pattern)))
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err == nil && info.IsDir() && info.Mode().Perm()&0o070 != 0o070 {
+				private = append(private, path)
+			}
+		}
+	}
+	if len(private) == 0 {
+		c.Detail = "shared"
+		return c
+	}
+	sort.Strings(private)
+	c.Status = CheckWarn
+	c.Detail = fmt.Sprintf("%d dir(s) other users cannot write, e.g. %s; their owner can run chmod -R g+rwX,g+s on them",
+		len(private), private[0])
+	return c
+}
+
+// checkDataDirEncryption verifies that run files can be encrypted and
+// decrypted when encryption at rest is configured (see Crypt.Check): a
+// missing age command or an unreadable identity fails, as encrypted runs
+// cannot be read and new ones not written.
+func checkDataDirEncryption(ctx context.Context, crypter *crypt.Crypt) DoctorCheck {
+	c := DoctorCheck{Name: "data_dir_encryption", Status: CheckOK}
+	cfg := crypter.Config()
+	if !cfg.Enabled && cfg.Identity == "" {
+		c.Detail = "disabled"
+		return c
+	}
+	version, err := crypter.Check(ctx)
+	if err != nil {
+		c.Status, c.Detail = CheckFail, err.Error()
+		return c
+	}
+	mode := "enabled"
+	if !cfg.Enabled {
+		mode = "disabled (decrypting existing files only)"
+	}
+	c.Detail = fmt.Sprintf("%s, identity %s, age %s", mode, cfg.Identity, version)
+	return c
+}
+
+// checkDataDirOwnership warns about files in a private data dir owned by
+// someone other than the data dir's owner, typically left by running agency
+// with sudo: the owner cannot rewrite or remove them. Worktree contents are
+// not walked (their root dirs are checked). In a shared data dir, files of
+// several users are expected; data_dir_shared checks their permissions.
+func checkDataDirOwnership(dataDir string) DoctorCheck {
+	c := DoctorCheck{Name: "data_dir_ownership", Status: CheckOK}
+	info, err := os.Stat(dataDir)
+	if err != nil {
+		c.Detail = "data dir does not exist"
+		return c
+	}
+	owner, ok := agencyfs.OwnerOf(info)
+	if !ok {
+		c.Detail = "file owners are not available on this platform"
+		return c
+	}
+	if agencyfs.IsShared(dataDir) {
+		c.Detail = "shared: files of several users are expected"
+		return c
+	}
+
+	others := map[int]int{}
+	var example string
+	_ = filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if uid, ok := agencyfs.OwnerOf(info); ok && uid != owner {
+			others[uid]++
+			if example == "" {
+				example = path
+			}
+		}
+		if d.IsDir() && path != dataDir {
+			if rel, _ := filepath.Rel(dataDir, path); inRunWorktree(rel) {
+				return fs.SkipDir
+			}
+		}
+		return nil
+	})
+	if len(others) == 0 {
+		c.Detail = "all files owned by " + agencyfs.UserName(owner)
+		return c
+	}
+
+	uids := make([]int, 0, len(others))
+	total := 0
+	for uid, n := range others {
+		uids = append(uids, uid)
+		total += n
+	}
+	sort.Ints(uids)
+	var users []string
+	for _, uid := range uids {
+		users = append(users, fmt.Sprintf("%s: %d", agencyfs.UserName(uid), others[uid]))
+	}
+	name := agencyfs.UserName(owner)
+	chownTo := name
+	if strings.HasPrefix(chownTo, "uid ") {
+		chownTo = strconv.Itoa(owner)
+	}
+	c.Status = CheckWarn
+	c.Detail = fmt.Sprintf("%d file(s) not owned by %s (%s), e.g. %s; fix with sudo chown -R %s %s",
+		total, name, strings.Join(users, ", "), example, chownTo, dataDir)
+	return c
+}
+
+func hasAtomicTempPrefix(name string) bool {
+	for _, prefix := range atomicTempPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// failedChecks returns the names of checks with status fail.
+func failedChecks(checks []DoctorCheck) []string {
+	var names []string
+	for _, c := range checks {
+		if c.Status == CheckFail {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,254 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func stubFreeBytes(t *testing.T, free uint64) {
+	t.Helper()
+	old := statFreeBytes
+	statFreeBytes = func(string) (uint64, error) { return free, nil }
+	t.Cleanup(func() { statFreeBytes = old })
+}
+
+func findCheck(t *testing.T, checks []DoctorCheck, name string) DoctorCheck {
+	t.Helper()
+	for _, c := range checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("check %q not found in %+v", name, checks)
+	return DoctorCheck{}
+}
+
+func TestCheckDataDir_HealthyEmptyDir(t *testing.T) {
+	stubFreeBytes(t, 10<<30)
+	dataDir := filepath.Join(t.TempDir(), "data")
+
+	checks := checkDataDir(context.Background(), dataDir, time.Now(), nil)
+
+	wantNames := []string{
+		"data_dir_writable",
+		"data_dir_free_space",
+		"data_dir_temp_files",
+		"data_dir_repo_index",
+		"data_dir_stale_locks",
+		"data_dir_format",
+		"data_dir_shared",
+		"data_dir_ownership",
+		"data_dir_encryption",
+	}
+	if len(checks) != len(wantNames) {
+		t.Fatalf("got %d checks, want %d", len(checks), len(wantNames))
+	}
+	for i, c := range checks {
+		if c.Name != wantNames[i] {
+			t.Errorf("checks[%d].Name = %q, want %q", i, c.Name, wantNames[i])
+		}
+		if c.Status != CheckOK {
+			t.Errorf("%s status = %q (%s), want ok", c.Name, c.Status, c.Detail)
+		}
+	}
+	if c := findCheck(t, checks, "data_dir_free_space"); c.Detail != "10.0 GiB free" {
+		t.Errorf("free space detail = %q", c.Detail)
+	}
+}
+
+func TestCheckDataDirFreeSpace_Thresholds(t *testing.T) {
+	tests := []struct {
+		free uint64
+		want string
+	}{
+		{10 << 30, CheckOK},
+		{512 << 20, CheckWarn},
+		{1 << 20, CheckFail},
+	}
+	for _, tt := range tests {
+		stubFreeBytes(t, tt.free)
+		if got := checkDataDirFreeSpace(t.TempDir()); got.Status != tt.want {
+			t.Errorf("free=%d: status = %q, want %q", tt.free, got.Status, tt.want)
+		}
+	}
+}
+
+func TestCheckDataDirTempFiles_OrphansOnlyWhenOld(t *testing.T) {
+	dataDir := t.TempDir()
+	runDir := filepath.Join(dataDir, "repos", "r1", "runs", "x")
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	orphan := filepath.Join(runDir, ".agency-tmp-123")
+	if err := os.WriteFile(orphan, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	if c := checkDataDirTempFiles(dataDir, now); c.Status != CheckOK {
+		t.Errorf("fresh temp file: status = %q, want ok", c.Status)
+	}
+
+	old := now.Add(-time.Hour)
+	if err := os.Chtimes(orphan, old, old); err != nil {
+		t.Fatal(err)
+	}
+	c := checkDataDirTempFiles(dataDir, now)
+	if c.Status != CheckWarn || !strings.Contains(c.Detail, orphan) {
+		t.Errorf("old temp file: got %+v, want warn mentioning %s", c, orphan)
+	}
+}
+
+func TestCheckDataDirRepoIndex(t *testing.T) {
+	dataDir := t.TempDir()
+	present := t.TempDir()
+	missing := filepath.Join(t.TempDir(), "gone")
+	idx := `{"schema_version":"1.0","repos":{"github:o/r":{"repo_id":"abc","paths":["` + present + `","` + missing + `"],"last_seen_at":""}}}`
+	if err := os.WriteFile(filepath.Join(dataDir, "repo_index.json"), []byte(idx), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := checkDataDirRepoIndex(dataDir)
+	if c.Status != CheckWarn || !strings.Contains(c.Detail, missing) {
+		t.Errorf("got %+v, want warn mentioning %s", c, missing)
+	}
+
+	if err := os.WriteFile(filepath.Join(dataDir, "repo_index.json"), []byte("{bad"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if c := checkDataDirRepoIndex(dataDir); c.Status != CheckFail {
+		t.Errorf("invalid index: status = %q, want fail", c.Status)
+	}
+}
+
+func TestCheckDataDirStaleLocks(t *testing.T) {
+	dataDir := t.TempDir()
+	lockPath := filepath.Join(dataDir, "repos", "abc", ".lock")
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath, []byte(`{"pid":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	if c := checkDataDirStaleLocks(dataDir, now); c.Status != CheckOK {
+		t.Errorf("fresh lock: status = %q, want ok", c.Status)
+	}
+	if c := checkDataDirStaleLocks(dataDir, now.Add(3*time.Hour)); c.Status != CheckWarn {
+		t.Errorf("old lock: status = %q, want warn", c.Status)
+	}
+}
+
+func TestDoctor_DataDirFailAbortsPersistence(t *testing.T) {
+	repoRoot, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	stubFreeBytes(t, 1<<20)
+
+	m := newMockRunner()
+	setupMockRunnerAllOK(m, repoRoot)
+
+	var stdout, stderr bytes.Buffer
+	err := Doctor(context.Background(), m, fs.NewRealFS(), repoRoot, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EDataDirUnhealthy {
+		t.Fatalf("error code = %q, want %q (err=%v)", errors.GetCode(err), errors.EDataDirUnhealthy, err)
+	}
+
+	out := stdout.String()
+	if !strings.Contains(out, "data_dir_free_space: fail (1.0 MiB free)") {
+		t.Errorf("output missing failing check:\n%s", out)
+	}
+	if !strings.Contains(out, "status: fail") {
+		t.Errorf("output missing status: fail:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "repo_index.json")); !os.IsNotExist(err) {
+		t.Error("repo_index.json should not be written when a data dir check fails")
+	}
+}
+
+func TestCheckDataDirShared(t *testing.T) {
+	dataDir := t.TempDir()
+	if err := os.Chmod(dataDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if c := checkDataDirShared(dataDir); c.Status != CheckOK || c.Detail != "not shared: data dir is not group-writable" {
+		t.Errorf("private data dir = %+v, want ok (not shared)", c)
+	}
+
+	// A shared data dir with one run dir created before it was shared
+	if err := os.Chmod(dataDir, 0o2770); err != nil {
+		t.Fatal(err)
+	}
+	runDir := filepath.Join(dataDir, "repos", "r1", "runs", "20260110120000-a3f2")
+	if err := os.MkdirAll(runDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{filepath.Join(dataDir, "repos"), filepath.Join(dataDir, "repos", "r1"), filepath.Join(dataDir, "repos", "r1", "runs")} {
+		if err := os.Chmod(dir, 0o2770); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := checkDataDirShared(dataDir)
+	if c.Status != CheckWarn || !strings.Contains(c.Detail, "1 dir(s)") || !strings.Contains(c.Detail, runDir) {
+		t.Errorf("shared data dir = %+v, want warn naming %s", c, runDir)
+	}
+
+	if err := os.Chmod(runDir, 0o2770); err != nil {
+		t.Fatal(err)
+	}
+	if c := checkDataDirShared(dataDir); c.Status != CheckOK || c.Detail != "shared" {
+		t.Errorf("fixed data dir = %+v, want ok (shared)", c)
+	}
+}
+
+func TestCheckDataDirOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("needs root to create files owned by another user")
+	}
+	dataDir := t.TempDir()
+	worktree := filepath.Join(dataDir, "repos", "r1", "worktrees", "20260110120000-a3f2")
+	runDir := filepath.Join(dataDir, "repos", "r1", "runs", "20260110120000-a3f2")
+	for _, dir := range []string{worktree, runDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c := checkDataDirOwnership(dataDir); c.Status != CheckOK {
+		t.Errorf("single owner = %+v, want ok", c)
+	}
+
+	// Files inside worktrees are not walked; run files are
+	inWorktree := filepath.Join(worktree, "main.go")
+	meta := filepath.Join(runDir, "meta.json")
+	for _, path := range []string{inWorktree, meta} {
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chown(path, 4242, 4242); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := checkDataDirOwnership(dataDir)
+	if c.Status != CheckWarn || !strings.HasPrefix(c.Detail, "1 file(s) not owned by root") || !strings.Contains(c.Detail, meta) || !strings.Contains(c.Detail, "chown -R root "+dataDir) {
+		t.Errorf("mixed owners = %+v, want warn naming %s", c, meta)
+	}
+
+	// In a shared data dir several owners are expected
+	if err := os.Chmod(dataDir, 0o2770); err != nil {
+		t.Fatal(err)
+	}
+	if c := checkDataDirOwnership(dataDir); c.Status != CheckOK {
+		t.Errorf("shared data dir = %+v, want ok", c)
+	}
+}
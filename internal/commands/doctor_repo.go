@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/worktree"
+)
+
+// Repo layout check names, in output order.
+const (
+	checkRepoSubmodules  = "repo_submodules"
+	checkRepoNestedRepos = "repo_nested_repos"
+)
+
+// maxListedPaths caps how many paths a check detail lists.
+const maxListedPaths = 5
+
+// checkRepoLayout warns about submodules and nested git repos, which run
+// worktrees do not contain unless worktrees.submodules is set. Both checks
+// only warn: agency works, but the runner sees less than the repo holds.
+func checkRepoLayout(ctx context.Context, cr agencyexec.CommandRunner, repoRoot string, initSubmodules bool) []DoctorCheck {
+	submodules := DoctorCheck{Name: checkRepoSubmodules, Status: CheckOK}
+	nested := DoctorCheck{Name: checkRepoNestedRepos, Status: CheckOK}
+
+	repos, err := worktree.FindNestedRepos(ctx, cr, repoRoot)
+	if err != nil {
+		detail := "unable to list repo files: " + err.Error()
+		submodules.Status, submodules.Detail = CheckWarn, detail
+		nested.Status, nested.Detail = CheckWarn, detail
+		return []DoctorCheck{submodules, nested}
+	}
+
+	switch {
+	case len(repos.Submodules) == 0 && repos.Gitmodules:
+		submodules.Detail = ".gitmodules present but no submodules are tracked"
+	case len(repos.Submodules) == 0:
+	case initSubmodules:
+		submodules.Detail = fmt.Sprintf("%s; initialized in run worktrees (worktrees.submodules)", listPaths(repos.Submodules))
+	default:
+		submodules.Status = CheckWarn
+		submodules.Detail = fmt.Sprintf("%s; run worktrees get empty dirs for them unless worktrees.submodules is set", listPaths(repos.Submodules))
+	}
+
+	if len(repos.Nested) > 0 {
+		nested.Status = CheckWarn
+		nested.Detail = fmt.Sprintf("%s; untracked repos are not in run worktrees and their changes are invisible to dirty checks", listPaths(repos.Nested))
+	}
+
+	return []DoctorCheck{submodules, nested}
+}
+
+// listPaths renders "3: a, b, c", listing at most maxListedPaths paths.
+func listPaths(paths []string) string {
+	listed := paths
+	if len(listed) > maxListedPaths {
+		listed = listed[:maxListedPaths]
+	}
+	s := fmt.Sprintf("%d: %s", len(paths), strings.Join(listed, ", "))
+	if len(paths) > len(listed) {
+		s += ", ..."
+	}
+	return s
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+)
+
+func TestCheckRepoLayout(t *testing.T) {
+	m := newMockRunner()
+	m.SetResponse("git", []string{"-C", "/repo", "ls-files", "--stage", "-z"}, agencyexec.CmdResult{
+		Stdout: "100644 aaaa 0\tREADME.md\x00160000 bbbb 0\tvendor/lib\x00",
+	}, nil)
+	m.SetResponse("git", []string{"-C", "/repo", "ls-files", "--others", "--exclude-standard", "-z"}, agencyexec.CmdResult{
+		Stdout: "notes.txt\x00tools/scratch/\x00",
+	}, nil)
+
+	checks := checkRepoLayout(context.Background(), m, "/repo", false)
+	if len(checks) != 2 {
+		t.Fatalf("checks = %+v", checks)
+	}
+	if c := checks[0]; c.Name != checkRepoSubmodules || c.Status != CheckWarn || !strings.HasPrefix(c.Detail, "1: vendor/lib; ") {
+		t.Errorf("submodules = %+v", c)
+	}
+	if c := checks[1]; c.Name != checkRepoNestedRepos || c.Status != CheckWarn || !strings.HasPrefix(c.Detail, "1: tools/scratch; ") {
+		t.Errorf("nested repos = %+v", c)
+	}
+
+	// worktrees.submodules turns the submodule warning into ok
+	if c := checkRepoLayout(context.Background(), m, "/repo", true)[0]; c.Status != CheckOK {
+		t.Errorf("submodules with worktrees.submodules = %+v, want ok", c)
+	}
+
+	// git failures only warn
+	for _, c := range checkRepoLayout(context.Background(), newMockRunner(), "/repo", false) {
+		if c.Status != CheckWarn || !strings.HasPrefix(c.Detail, "unable to list repo files") {
+			t.Errorf("git failure: %+v", c)
+		}
+	}
+}
+
+func TestListPaths(t *testing.T) {
+	if got := listPaths([]string{"a", "b"}); got != "2: a, b" {
+		t.Errorf("listPaths() = %q", got)
+	}
+	if got := listPaths([]string{"a", "b", "c", "d", "e", "f"}); got != "6: a, b, c, d, e, ..." {
+		t.Errorf("listPaths() = %q", got)
+	}
+}
@@ -10,9 +10,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/errors"
 	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/scaffold"
 	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
 )
 
 // mockRunner implements exec.CommandRunner for testing.
@@ -140,6 +143,9 @@ func setupMockRunnerAllOK(m *mockRunner, repoRoot string) {
 		ExitCode: 0,
 	}, nil)
 
+	// tmux start-server \; list-sessions
+	m.SetResponse("tmux", []string{"start-server", ";", "list-sessions"}, agencyexec.CmdResult{}, nil)
+
 	// gh --version
 	m.SetResponse("gh", []string{"--version"}, agencyexec.CmdResult{
 		Stdout:   "gh version 2.40.0 (2024-01-15)\nhttps://github.com/cli/cli/releases/tag/v2.40.0\n",
@@ -150,6 +156,10 @@ func setupMockRunnerAllOK(m *mockRunner, repoRoot string) {
 	m.SetResponse("gh", []string{"auth", "status"}, agencyexec.CmdResult{
 		ExitCode: 0,
 	}, nil)
+
+	// git ls-files (no submodules or nested repos)
+	m.SetResponse("git", []string{"-C", repoRoot, "ls-files", "--stage", "-z"}, agencyexec.CmdResult{}, nil)
+	m.SetResponse("git", []string{"-C", repoRoot, "ls-files", "--others", "--exclude-standard", "-z"}, agencyexec.CmdResult{}, nil)
 }
 
 func TestDoctor_Success(t *testing.T) {
@@ -193,6 +203,8 @@ func TestDoctor_Success(t *testing.T) {
 		"github_flow_available: true",
 		"git_version: git version 2.40.0",
 		"tmux_version: tmux 3.3a",
+		"tmux_version_supported: ok (3.3 >= 3.0)",
+		"tmux_server_reachable: ok",
 		"gh_version: gh version 2.40.0 (2024-01-15)",
 		"gh_authenticated: true",
 		"defaults_parent_branch: main",
@@ -261,6 +273,37 @@ func TestDoctor_GhNotAuthenticated(t *testing.T) {
 	}
 }
 
+func TestDoctor_GitHubFlowDisabled(t *testing.T) {
+	repoRoot, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	dataDir := t.TempDir()
+	oldDataDir := os.Getenv("AGENCY_DATA_DIR")
+	os.Setenv("AGENCY_DATA_DIR", dataDir)
+	defer os.Setenv("AGENCY_DATA_DIR", oldDataDir)
+
+	agencyJSON := scaffold.RenderAgencyJSON(scaffold.AgencyJSONOptions{ParentBranch: "main", Runner: "claude"})
+	if err := os.WriteFile(filepath.Join(repoRoot, "agency.json"), []byte(agencyJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newMockRunner()
+	setupMockRunnerAllOK(m, repoRoot)
+	// gh is neither installed nor needed
+	m.SetResponse("gh", []string{"--version"}, agencyexec.CmdResult{}, os.ErrNotExist)
+	m.SetResponse("gh", []string{"auth", "status"}, agencyexec.CmdResult{}, os.ErrNotExist)
+
+	var stdout, stderr bytes.Buffer
+	if err := Doctor(context.Background(), m, fs.NewRealFS(), repoRoot, &stdout, &stderr); err != nil {
+		t.Fatalf("doctor failed with github.flow false: %v", err)
+	}
+	for _, line := range []string{"github_flow_available: false", "gh_version: \n", "gh_authenticated: false", "status: ok"} {
+		if !strings.Contains(stdout.String(), line) {
+			t.Errorf("output missing %q:\n%s", line, stdout.String())
+		}
+	}
+}
+
 func TestDoctor_ScriptNotExecutable(t *testing.T) {
 	repoRoot, cleanup := setupTestRepo(t)
 	defer cleanup()
@@ -500,6 +543,8 @@ func TestDoctor_OutputOrder(t *testing.T) {
 		"github_flow_available:",
 		"git_version:",
 		"tmux_version:",
+		"tmux_version_supported:",
+		"tmux_server_reachable:",
 		"gh_version:",
 		"gh_authenticated:",
 		"defaults_parent_branch:",
@@ -508,6 +553,17 @@ func TestDoctor_OutputOrder(t *testing.T) {
 		"script_setup:",
 		"script_verify:",
 		"script_archive:",
+		"repo_submodules:",
+		"repo_nested_repos:",
+		"data_dir_writable:",
+		"data_dir_free_space:",
+		"data_dir_temp_files:",
+		"data_dir_repo_index:",
+		"data_dir_stale_locks:",
+		"data_dir_format:",
+		"data_dir_shared:",
+		"data_dir_ownership:",
+		"data_dir_encryption:",
 		"status:",
 	}
 
@@ -530,3 +586,36 @@ func TestDoctor_OutputOrder(t *testing.T) {
 		t.Errorf("expected %d lines, got %d", len(expectedKeyOrder), keyIndex)
 	}
 }
+
+func TestDoctor_HookScripts(t *testing.T) {
+	repoRoot, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	cfg := strings.Replace(testkit.DefaultAgencyJSON, `"runners"`, `"hooks": {"post_create_worktree": "scripts/copy_env.sh"},
+  "runners"`, 1)
+	if err := os.WriteFile(filepath.Join(repoRoot, "agency.json"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGENCY_DATA_DIR", t.TempDir())
+
+	m := newMockRunner()
+	setupMockRunnerAllOK(m, repoRoot)
+	var stdout, stderr bytes.Buffer
+
+	err := Doctor(context.Background(), m, fs.NewRealFS(), repoRoot, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EScriptNotFound {
+		t.Fatalf("expected E_SCRIPT_NOT_FOUND for missing hook script, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoRoot, "scripts", "copy_env.sh"), []byte(testkit.DefaultScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if err := Doctor(context.Background(), m, fs.NewRealFS(), repoRoot, &stdout, &stderr); err != nil {
+		t.Fatalf("doctor failed: %v", err)
+	}
+	want := "hook_post_create_worktree: " + filepath.Join(repoRoot, "scripts", "copy_env.sh") + "\n"
+	if !strings.Contains(stdout.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, stdout.String())
+	}
+}
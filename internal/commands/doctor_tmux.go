@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+)
+
+// minTmuxMajor and minTmuxMinor are the oldest supported tmux (3.0).
+// Older releases take different attach flags and lack options agency sets.
+const (
+	minTmuxMajor = 3
+	minTmuxMinor = 0
+)
+
+// Tmux check names, in output order.
+const (
+	checkTmuxVersionSupported = "tmux_version_supported"
+	checkTmuxServerReachable  = "tmux_server_reachable"
+)
+
+// parseTmuxVersion parses `tmux -V` output such as "tmux 3.3a" or
+// "tmux next-3.4" into major and minor. Builds without a numeric version
+// (e.g. "tmux master", "tmux openbsd-7.4") are not parsed.
+func parseTmuxVersion(out string) (major, minor int, ok bool) {
+	v := strings.TrimPrefix(strings.TrimSpace(out), "tmux ")
+	v = strings.TrimPrefix(v, "next-")
+	majorStr, rest, found := strings.Cut(v, ".")
+	if !found {
+		return 0, 0, false
+	}
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	major, err1 := strconv.Atoi(majorStr)
+	minor, err2 := strconv.Atoi(rest[:end])
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// checkTmuxEnv runs the tmux checks for an installed tmux whose `tmux -V`
+// output is version: the minimum version gate, and whether a tmux server
+// can be started and queried (`tmux start-server \; list-sessions`).
+func checkTmuxEnv(ctx context.Context, cr agencyexec.CommandRunner, version string) []DoctorCheck {
+	return []DoctorCheck{
+		checkTmuxVersion(version),
+		checkTmuxServer(ctx, cr),
+	}
+}
+
+func checkTmuxVersion(version string) DoctorCheck {
+	c := DoctorCheck{Name: checkTmuxVersionSupported}
+	minimum := fmt.Sprintf("%d.%d", minTmuxMajor, minTmuxMinor)
+	major, minor, ok := parseTmuxVersion(version)
+	switch {
+	case !ok:
+		c.Status = CheckWarn
+		c.Detail = fmt.Sprintf("cannot parse %q; agency needs tmux >= %s", version, minimum)
+	case major < minTmuxMajor || (major == minTmuxMajor && minor < minTmuxMinor):
+		c.Status = CheckFail
+		c.Detail = fmt.Sprintf("%d.%d < %s", major, minor, minimum)
+	default:
+		c.Status = CheckOK
+		c.Detail = fmt.Sprintf("%d.%d >= %s", major, minor, minimum)
+	}
+	return c
+}
+
+func checkTmuxServer(ctx context.Context, cr agencyexec.CommandRunner) DoctorCheck {
+	c := DoctorCheck{Name: checkTmuxServerReachable, Status: CheckOK}
+	result, err := cr.Run(ctx, "tmux", []string{"start-server", ";", "list-sessions"}, agencyexec.RunOpts{})
+	switch {
+	case err != nil:
+		c.Status = CheckFail
+		c.Detail = err.Error()
+	case result.ExitCode != 0:
+		c.Status = CheckFail
+		c.Detail = strings.TrimSpace(result.Stderr)
+		if c.Detail == "" {
+			c.Detail = fmt.Sprintf("exit %d", result.ExitCode)
+		}
+	}
+	return c
+}
+
+// tmuxCheckError returns the error for the first failed tmux check, or nil:
+// E_TMUX_UNSUPPORTED for a too-old tmux, E_TMUX_FAILED for an unreachable server.
+func tmuxCheckError(checks []DoctorCheck, version string) error {
+	for _, c := range checks {
+		if c.Status != CheckFail {
+			continue
+		}
+		if c.Name == checkTmuxVersionSupported {
+			return errors.New(errors.ETmuxUnsupported, fmt.Sprintf(
+				"tmux %s is too old; agency needs tmux >= %d.%d (older versions use different attach flags and lack options agency sets)",
+				strings.TrimPrefix(version, "tmux "), minTmuxMajor, minTmuxMinor))
+		}
+		return errors.New(errors.ETmuxFailed, "tmux server is not reachable: "+c.Detail)
+	}
+	return nil
+}
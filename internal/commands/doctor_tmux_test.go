@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestParseTmuxVersion(t *testing.T) {
+	tests := []struct {
+		out          string
+		major, minor int
+		ok           bool
+	}{
+		{"tmux 3.3a\n", 3, 3, true},
+		{"tmux 3.0", 3, 0, true},
+		{"tmux 2.9a", 2, 9, true},
+		{"tmux next-3.4", 3, 4, true},
+		{"tmux 10.12", 10, 12, true},
+		{"tmux master", 0, 0, false},
+		{"tmux openbsd-7.4", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		major, minor, ok := parseTmuxVersion(tt.out)
+		if major != tt.major || minor != tt.minor || ok != tt.ok {
+			t.Errorf("parseTmuxVersion(%q) = %d, %d, %v; want %d, %d, %v", tt.out, major, minor, ok, tt.major, tt.minor, tt.ok)
+		}
+	}
+}
+
+func TestCheckTmuxVersion(t *testing.T) {
+	if c := checkTmuxVersion("tmux 3.0"); c.Status != CheckOK {
+		t.Errorf("3.0: %+v, want ok", c)
+	}
+	if c := checkTmuxVersion("tmux 2.9a"); c.Status != CheckFail || c.Detail != "2.9 < 3.0" {
+		t.Errorf("2.9a: %+v, want fail", c)
+	}
+	if c := checkTmuxVersion("tmux master"); c.Status != CheckWarn {
+		t.Errorf("master: %+v, want warn", c)
+	}
+}
+
+func TestDoctor_TmuxChecksFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(m *mockRunner)
+		wantCode errors.Code
+		wantLine string
+	}{
+		{
+			name: "old tmux",
+			setup: func(m *mockRunner) {
+				m.SetResponse("tmux", []string{"-V"}, agencyexec.CmdResult{Stdout: "tmux 2.9a\n"}, nil)
+			},
+			wantCode: errors.ETmuxUnsupported,
+			wantLine: "tmux_version_supported: fail (2.9 < 3.0)",
+		},
+		{
+			name: "server unreachable",
+			setup: func(m *mockRunner) {
+				m.SetResponse("tmux", []string{"start-server", ";", "list-sessions"}, agencyexec.CmdResult{
+					Stderr:   "error connecting to /tmp/tmux-1000/default (Permission denied)\n",
+					ExitCode: 1,
+				}, nil)
+			},
+			wantCode: errors.ETmuxFailed,
+			wantLine: "tmux_server_reachable: fail (error connecting to /tmp/tmux-1000/default (Permission denied))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoRoot, cleanup := setupTestRepo(t)
+			defer cleanup()
+			dataDir := t.TempDir()
+			t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+			m := newMockRunner()
+			setupMockRunnerAllOK(m, repoRoot)
+			tt.setup(m)
+
+			var stdout, stderr bytes.Buffer
+			err := Doctor(context.Background(), m, fs.NewRealFS(), repoRoot, &stdout, &stderr)
+			if errors.GetCode(err) != tt.wantCode {
+				t.Fatalf("error code = %q, want %q (err=%v)", errors.GetCode(err), tt.wantCode, err)
+			}
+			out := stdout.String()
+			if !strings.Contains(out, tt.wantLine) || !strings.Contains(out, "status: fail") {
+				t.Errorf("output missing %q or status: fail:\n%s", tt.wantLine, out)
+			}
+			if _, err := os.Stat(filepath.Join(dataDir, "repo_index.json")); !os.IsNotExist(err) {
+				t.Error("repo_index.json should not be written when a tmux check fails")
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// Exported helpers for the embedding API (pkg/agency). They share the CLI's
+// resolution and status derivation so both report the same results.
+
+// ResolveDataDir returns the data dir for dir: AGENCY_DATA_DIR or the
+// platform default, overridden by the agency.json data_dir of the repo
+// containing dir.
+func ResolveDataDir(fsys fs.FS, dir string) (string, error) {
+	dirs, err := resolveDirs(fsys, dir)
+	if err != nil {
+		return "", err
+	}
+	return dirs.DataDir, nil
+}
+
+// SummarizeRuns derives the ls summary of each record, in order. tmux is
+// queried at most once and review policies once per repo; commit counts
+// are not computed.
+func SummarizeRuns(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, dataDir string, records []store.RunRecord) []render.RunSummary {
+	tmuxSessions := newTmuxSessionSet(ctx, cr)
+	policies := newReviewPolicySet(fsys, dataDir)
+	summaries := make([]render.RunSummary, len(records))
+	for i, rec := range records {
+		summaries[i] = recordToSummary(ctx, cr, rec, tmuxSessions, policies, nil, fsys)
+	}
+	return summaries
+}
+
+// ResolveRun resolves an exact run_id or unique prefix, across all repos or
+// only within repoID, decrypting meta.json with crypter (see LoadCrypt). Errors
+// are E_RUN_NOT_FOUND, E_RUN_ID_AMBIGUOUS, or E_RUN_BROKEN, as for agency
+// show.
+func ResolveRun(dataDir, input, repoID string, crypter *crypt.Crypt) (*store.RunRecord, error) {
+	return lookupRun(dataDir, input, runScope{RepoID: repoID}, crypter)
+}
@@ -0,0 +1,485 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/archive"
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/checkpoint"
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/gh"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// GCOpts holds options for the gc command.
+type GCOpts struct {
+	// Auto archives runs that qualify under each repo's agency.json retention policy.
+	// Without Auto, gc only lists qualifying runs (dry run).
+	Auto bool
+}
+
+// gcCandidate is a run that qualifies for retention-based archival.
+type gcCandidate struct {
+	record        store.RunRecord
+	repoRoot      string
+	script        string
+	retentionDays int
+	retention     archive.RetentionCandidate
+}
+
+// gcLogCandidate is a run whose uncompressed logs qualify for compression.
+type gcLogCandidate struct {
+	record  store.RunRecord
+	days    int
+	ageDays int
+}
+
+// gcCheckpointCandidate is a run whose checkpoints qualify for pruning: all of
+// them once the run is archived, otherwise all but the newest checkpoint.Keep.
+type gcCheckpointCandidate struct {
+	record   store.RunRecord
+	archived bool
+	prune    []checkpoint.Checkpoint
+}
+
+// gcSealCandidate is a run with plaintext left behind while encryption at
+// rest is enabled: files to encrypt, and orphaned temp files to remove.
+type gcSealCandidate struct {
+	record    store.RunRecord
+	plaintext []string
+	temps     []string
+}
+
+// GC applies retention policies (agency.json retention.auto_archive_after_days)
+// across all repos. Qualifying merged/abandoned runs are listed, and with --auto
+// archived: a warning is printed before each destructive archive, the repo lock
+// is taken, and an auto_archive event is appended to the run's events.jsonl.
+// Logs of runs older than logs.compress_after_days are likewise listed, and
+// with --auto gzipped in place. Checkpoints of archived runs, and all but the
+// newest checkpoint.Keep of other runs, are listed and with --auto deleted.
+// With encryption at rest enabled, plaintext run files are listed and with
+// --auto encrypted, and orphaned temp files in run dirs removed. In repos with
+// github.auto_cleanup, runs whose PR was merged are listed and with --auto
+// cleaned up (see Cleanup) instead of waiting for retention.
+func GC(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts GCOpts, stdout, stderr io.Writer) error {
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+	dataDir := dirs.DataDir
+
+	now := clock.Now()
+	candidates, logCandidates, err := findGCCandidates(fsys, dataDir, now, crypter, stderr)
+	if err != nil {
+		return err
+	}
+
+	checkpointCandidates, err := findCheckpointCandidates(dataDir, crypter)
+	if err != nil {
+		return err
+	}
+
+	sealCandidates, err := findSealCandidates(dataDir, now, crypter)
+	if err != nil {
+		return err
+	}
+
+	// Merged-PR cleanup needs GitHub; without it, the rest of gc still runs
+	cleanupCandidates, err := findMergedRuns(ctx, gh.NewClient(cr, fsys, dirs.CacheDir), fsys, dataDir, "", true, crypter, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: skipping merged-PR cleanup: %v\n", err)
+	}
+	cleanupIDs := make(map[string]bool, len(cleanupCandidates))
+	for _, c := range cleanupCandidates {
+		cleanupIDs[c.record.RunID] = true
+	}
+	candidates = slices.DeleteFunc(candidates, func(c gcCandidate) bool { return cleanupIDs[c.record.RunID] })
+
+	if len(candidates) == 0 && len(logCandidates) == 0 && len(checkpointCandidates) == 0 && len(sealCandidates) == 0 && len(cleanupCandidates) == 0 {
+		fmt.Fprintln(stdout, "no runs qualify for cleanup, auto-archive, log compression, checkpoint pruning, or encryption")
+		return nil
+	}
+
+	if !opts.Auto {
+		for _, c := range sealCandidates {
+			fmt.Fprintf(stdout, "would encrypt %d plaintext file(s) and remove %d temp file(s) of %s\n",
+				len(c.plaintext), len(c.temps), c.record.RunID)
+		}
+		for _, c := range logCandidates {
+			fmt.Fprintf(stdout, "would compress logs of %s (created %dd ago; compress after %dd)\n",
+				c.record.RunID, c.ageDays, c.days)
+		}
+		for _, c := range checkpointCandidates {
+			fmt.Fprintf(stdout, "would prune %d checkpoint(s) of %s (%s)\n",
+				len(c.prune), c.record.RunID, c.reason())
+		}
+		for _, c := range cleanupCandidates {
+			fmt.Fprintf(stdout, "would clean up %s (PR #%d merged %s)\n",
+				c.record.RunID, c.record.Meta.PRNumber, c.mergedAt)
+		}
+		for _, c := range candidates {
+			fmt.Fprintf(stdout, "would archive %s (%s %dd ago; retention %dd)\n",
+				c.record.RunID, c.retention.Reason, c.retention.AgeDays, c.retentionDays)
+		}
+		fmt.Fprintln(stdout, "run 'agency gc --auto' to apply")
+		return nil
+	}
+
+	// Encrypting is not destructive either; a failure is left for next time
+	for _, c := range sealCandidates {
+		if err := sealLeftovers(c, crypter); err != nil {
+			fmt.Fprintf(stderr, "warning: %s: failed to encrypt run files: %v\n", c.record.RunID, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "encrypted %d plaintext file(s) and removed %d temp file(s) of %s\n",
+			len(c.plaintext), len(c.temps), c.record.RunID)
+	}
+
+	// Compressing logs is not destructive; a failure leaves the log as is
+	for _, c := range logCandidates {
+		before, after, err := store.CompressLogs(filepath.Join(c.record.RunDir, "logs"))
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: %s: failed to compress logs: %v\n", c.record.RunID, err)
+		}
+		if err == nil || before > 0 {
+			fmt.Fprintf(stdout, "compressed logs of %s (%d -> %d bytes)\n", c.record.RunID, before, after)
+		}
+	}
+
+	repoLock := lock.NewRepoLock(dataDir)
+
+	// Checkpoint pruning failures are warnings; the rest are kept for next time
+	if len(checkpointCandidates) > 0 {
+		idx, _ := store.LoadRepoIndexForScan(dataDir)
+		for _, c := range checkpointCandidates {
+			if err := pruneCheckpoints(ctx, cr, repoLock, idx, c, stdout); err != nil {
+				fmt.Fprintf(stderr, "warning: %s: failed to prune checkpoints: %v\n", c.record.RunID, err)
+			}
+		}
+	}
+	if len(candidates) == 0 && len(cleanupCandidates) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]gcCandidate, len(candidates))
+	cleanupByID := make(map[string]cleanupTarget, len(cleanupCandidates))
+	runIDs := make([]string, 0, len(cleanupCandidates)+len(candidates))
+	for _, c := range cleanupCandidates {
+		cleanupByID[c.record.RunID] = c
+		runIDs = append(runIDs, c.record.RunID)
+	}
+	for _, c := range candidates {
+		byID[c.record.RunID] = c
+		runIDs = append(runIDs, c.record.RunID)
+	}
+
+	st := store.NewStore(fsys, dataDir, clock.Now).WithCrypt(crypter)
+
+	return RunBulk(runIDs, stderr, func(runID string) error {
+		if c, ok := cleanupByID[runID]; ok {
+			fmt.Fprintf(stderr, "warning: cleaning up %s (PR #%d merged); deleting worktree %s\n",
+				runID, c.record.Meta.PRNumber, c.record.Meta.WorktreePath)
+			return cleanupRun(ctx, cr, st, repoLock, c, stdout, stderr)
+		}
+		return autoArchiveRun(ctx, cr, st, repoLock, byID[runID], stdout, stderr)
+	})
+}
+
+// findGCCandidates scans all runs and returns those qualifying for auto-archive
+// and those whose logs qualify for compression, each sorted by run_id. Repos
+// without a reachable agency.json or policy are skipped.
+func findGCCandidates(fsys fs.FS, dataDir string, now time.Time, crypter *crypt.Crypt, stderr io.Writer) ([]gcCandidate, []gcLogCandidate, error) {
+	records, err := store.ScanAllRuns(dataDir, crypter)
+	if err != nil {
+		return nil, nil, errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+
+	idx, _ := store.LoadRepoIndexForScan(dataDir)
+
+	type repoPolicy struct {
+		root         string
+		script       string
+		days         int
+		compressDays int
+	}
+	policies := make(map[string]*repoPolicy)
+
+	var candidates []gcCandidate
+	var logCandidates []gcLogCandidate
+	for _, rec := range records {
+		if rec.Broken || rec.Repo == nil {
+			continue
+		}
+
+		policy, seen := policies[rec.RepoID]
+		if !seen {
+			policy = nil
+			if root := store.PickRepoRoot(rec.Repo.RepoKey, nil, idx); root != nil {
+				cfg, err := config.LoadAgencyConfig(fsys, *root)
+				if err != nil {
+					fmt.Fprintf(stderr, "warning: skipping repo %s: %s\n", rec.Repo.RepoKey, err.Error())
+				} else if cfg.Retention.AutoArchiveAfterDays > 0 || cfg.Logs.CompressAfterDays > 0 {
+					policy = &repoPolicy{
+						root:         *root,
+						script:       cfg.Scripts.Archive,
+						days:         cfg.Retention.AutoArchiveAfterDays,
+						compressDays: cfg.Logs.CompressAfterDays,
+					}
+				}
+			}
+			policies[rec.RepoID] = policy
+		}
+		if policy == nil {
+			continue
+		}
+
+		if c, ok := checkLogCompression(rec, policy.compressDays, now); ok {
+			logCandidates = append(logCandidates, c)
+		}
+
+		if policy.days == 0 {
+			continue
+		}
+		retention, ok := archive.CheckRetention(rec.Meta, policy.days, now)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, gcCandidate{
+			record:        rec,
+			repoRoot:      policy.root,
+			script:        policy.script,
+			retentionDays: policy.days,
+			retention:     retention,
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].record.RunID < candidates[j].record.RunID
+	})
+	sort.Slice(logCandidates, func(i, j int) bool {
+		return logCandidates[i].record.RunID < logCandidates[j].record.RunID
+	})
+	return candidates, logCandidates, nil
+}
+
+// checkLogCompression reports whether rec was created at least days ago
+// (days > 0) and still has uncompressed logs.
+func checkLogCompression(rec store.RunRecord, days int, now time.Time) (gcLogCandidate, bool) {
+	if days <= 0 {
+		return gcLogCandidate{}, false
+	}
+	created, err := time.Parse(time.RFC3339, rec.Meta.CreatedAt)
+	if err != nil {
+		return gcLogCandidate{}, false
+	}
+	ageDays := int(now.Sub(created).Hours() / 24)
+	if ageDays < days {
+		return gcLogCandidate{}, false
+	}
+	logs, _ := store.ListLogs(filepath.Join(rec.RunDir, "logs"))
+	for _, l := range logs {
+		if !l.Compressed && !l.Sealed {
+			return gcLogCandidate{record: rec, days: days, ageDays: ageDays}, true
+		}
+	}
+	return gcLogCandidate{}, false
+}
+
+// findCheckpointCandidates scans all runs for checkpoints to prune, sorted
+// by run_id.
+func findCheckpointCandidates(dataDir string, crypter *crypt.Crypt) ([]gcCheckpointCandidate, error) {
+	records, err := store.ScanAllRuns(dataDir, crypter)
+	if err != nil {
+		return nil, errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+
+	var candidates []gcCheckpointCandidate
+	for _, rec := range records {
+		if rec.Broken || rec.Meta == nil {
+			continue
+		}
+		cps, _ := checkpoint.List(rec.RunDir)
+		archived := !dirExists(rec.Meta.WorktreePath)
+		switch {
+		case archived && len(cps) > 0:
+			candidates = append(candidates, gcCheckpointCandidate{record: rec, archived: true, prune: cps})
+		case len(cps) > checkpoint.Keep:
+			candidates = append(candidates, gcCheckpointCandidate{record: rec, prune: cps[:len(cps)-checkpoint.Keep]})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].record.RunID < candidates[j].record.RunID
+	})
+	return candidates, nil
+}
+
+// findSealCandidates scans all runs for plaintext left behind while
+// encryption at rest is enabled, sorted by run_id. Files modified within
+// orphanTempMinAge are skipped: a script may still be writing its log, or
+// an atomic write may be in flight. Without encryption there are none.
+func findSealCandidates(dataDir string, now time.Time, crypter *crypt.Crypt) ([]gcSealCandidate, error) {
+	if !crypter.Enabled() {
+		return nil, nil
+	}
+	records, err := store.ScanAllRuns(dataDir, crypter)
+	if err != nil {
+		return nil, errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+
+	settled := func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && now.Sub(info.ModTime()) >= orphanTempMinAge
+	}
+	var candidates []gcSealCandidate
+	for _, rec := range records {
+		c := gcSealCandidate{record: rec}
+		for _, path := range store.PlaintextRunFiles(rec.RunDir) {
+			if settled(path) {
+				c.plaintext = append(c.plaintext, path)
+			}
+		}
+		for _, dir := range []string{rec.RunDir, filepath.Join(rec.RunDir, "logs")} {
+			entries, _ := os.ReadDir(dir)
+			for _, e := range entries {
+				path := filepath.Join(dir, e.Name())
+				if e.Type().IsRegular() && hasAtomicTempPrefix(e.Name()) && settled(path) {
+					c.temps = append(c.temps, path)
+				}
+			}
+		}
+		if len(c.plaintext) > 0 || len(c.temps) > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].record.RunID < candidates[j].record.RunID
+	})
+	return candidates, nil
+}
+
+// sealLeftovers encrypts a candidate's plaintext files and removes its
+// orphaned temp files.
+func sealLeftovers(c gcSealCandidate, crypter *crypt.Crypt) error {
+	for _, path := range c.plaintext {
+		if _, err := crypter.Seal(path); err != nil {
+			return err
+		}
+	}
+	for _, path := range c.temps {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// reason explains why c's checkpoints are pruned.
+func (c gcCheckpointCandidate) reason() string {
+	if c.archived {
+		return "run archived"
+	}
+	return fmt.Sprintf("keeping the newest %d", checkpoint.Keep)
+}
+
+// pruneCheckpoints deletes a candidate's checkpoints under the repo lock.
+// Refs are deleted through the worktree, or the repo root once the run is
+// archived; without either the refs are left for git to keep.
+func pruneCheckpoints(ctx context.Context, cr agencyexec.CommandRunner, repoLock lock.RepoLock, idx *store.RepoIndex, c gcCheckpointCandidate, stdout io.Writer) error {
+	unlock, err := repoLock.Lock(c.record.RepoID, "gc --auto")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unlock() }()
+
+	gitDir := c.record.Meta.WorktreePath
+	if c.archived {
+		gitDir = ""
+		if c.record.Repo != nil {
+			if root := store.PickRepoRoot(c.record.Repo.RepoKey, nil, idx); root != nil {
+				gitDir = *root
+			}
+		}
+	}
+	for _, cp := range c.prune {
+		if err := checkpoint.Delete(ctx, cr, gitDir, c.record.RunDir, cp); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(stdout, "pruned %d checkpoint(s) of %s (%s)\n", len(c.prune), c.record.RunID, c.reason())
+	return nil
+}
+
+// autoArchiveRun archives a single candidate under the repo lock. The
+// retention decision is made again on meta.json read under the lock, so a
+// run pushed, reopened or archived since the scan is left alone.
+func autoArchiveRun(ctx context.Context, cr agencyexec.CommandRunner, st *store.Store, repoLock lock.RepoLock, c gcCandidate, stdout, stderr io.Writer) error {
+	repoID, runID := c.record.RepoID, c.record.RunID
+
+	unlock, err := repoLock.Lock(repoID, "gc --auto")
+	if err != nil {
+		return repoLockError(err, repoLock.DataDir, repoID)
+	}
+	defer func() { _ = unlock() }()
+
+	meta, err := st.ReadMeta(repoID, runID)
+	if err != nil {
+		return err
+	}
+	retention, ok := archive.CheckRetention(meta, c.retentionDays, st.Now())
+	if !ok {
+		fmt.Fprintf(stderr, "skipping %s: no longer due for auto-archive\n", runID)
+		return nil
+	}
+	c.retention = retention
+
+	// Warn before destruction
+	fmt.Fprintf(stderr, "warning: auto-archiving %s (%s %dd ago; retention %dd); deleting worktree %s\n",
+		meta.RunID, c.retention.Reason, c.retention.AgeDays, c.retentionDays, meta.WorktreePath)
+
+	res, err := archive.Archive(ctx, cr, st, meta, archive.Opts{
+		RepoRoot: c.repoRoot,
+		Script:   c.script,
+	})
+	for _, w := range res.Warnings {
+		fmt.Fprintf(stderr, "warning: %s: %s\n", meta.RunID, w)
+	}
+
+	// Record the event (best-effort)
+	data := map[string]any{
+		"reason":         c.retention.Reason,
+		"age_days":       c.retention.AgeDays,
+		"retention_days": c.retentionDays,
+		"warnings":       res.Warnings,
+	}
+	if res.ScriptRan {
+		data["script_exit_code"] = res.ScriptExitCode
+	}
+	if err != nil {
+		data["error_code"] = string(errors.GetCode(err))
+	}
+	_ = events.AppendEvent(events.EventsPath(c.record.RunDir), events.New(st.Now(), meta.RepoID, meta.RunID, "auto_archive", data))
+	audit.Touch(meta.RepoID, meta.RunID)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "archived %s\n", meta.RunID)
+	return nil
+}
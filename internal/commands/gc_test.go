@@ -0,0 +1,289 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// setupGCRepo creates a repo root with agency.json plus repo.json/repo_index.json linkage.
+func setupGCRepo(t *testing.T, dataDir, repoID, repoKey, agencyJSON string) string {
+	t.Helper()
+	repoRoot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoRoot, "agency.json"), []byte(agencyJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	if err := st.SaveRepoRecord(store.RepoRecord{SchemaVersion: "1.0", RepoKey: repoKey, RepoID: repoID}); err != nil {
+		t.Fatal(err)
+	}
+	idx := store.RepoIndex{SchemaVersion: "1.0", Repos: map[string]store.RepoIndexEntry{
+		repoKey: {RepoID: repoID, Paths: []string{repoRoot}},
+	}}
+	data, _ := json.Marshal(idx)
+	if err := os.WriteFile(filepath.Join(dataDir, "repo_index.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return repoRoot
+}
+
+func TestFindGCCandidates(t *testing.T) {
+	dataDir := t.TempDir()
+	repoID := "abc123"
+	repoRoot := setupGCRepo(t, dataDir, repoID, "github:owner/repo", `{
+		"version": 1,
+		"scripts": {"archive": "scripts/agency_archive.sh"},
+		"retention": {"auto_archive_after_days": 30}
+	}`)
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, runID := range []string{"run-old-merged", "run-new-merged", "run-active"} {
+		createValidMetaForShow(t, dataDir, repoID, runID, filepath.Join(dataDir, "wt", runID), created)
+	}
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	setMerged := func(runID, mergedAt string) {
+		if err := st.UpdateMeta(repoID, runID, func(m *store.RunMeta) {
+			m.Archive = &store.RunMetaArchive{MergedAt: mergedAt}
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setMerged("run-old-merged", "2026-01-15T12:00:00Z")
+	setMerged("run-new-merged", "2026-02-25T12:00:00Z")
+
+	var stderr bytes.Buffer
+	candidates, _, err := findGCCandidates(fs.NewRealFS(), dataDir, now, nil, &stderr)
+	if err != nil {
+		t.Fatalf("findGCCandidates() error = %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("len(candidates) = %d, want 1", len(candidates))
+	}
+
+	c := candidates[0]
+	if c.record.RunID != "run-old-merged" {
+		t.Errorf("RunID = %q, want run-old-merged", c.record.RunID)
+	}
+	if c.repoRoot != repoRoot || c.script != "scripts/agency_archive.sh" || c.retentionDays != 30 {
+		t.Errorf("candidate policy = %+v", c)
+	}
+}
+
+func TestFindGCCandidates_NoRetentionPolicy(t *testing.T) {
+	dataDir := t.TempDir()
+	repoID := "abc123"
+	setupGCRepo(t, dataDir, repoID, "github:owner/repo", `{"version": 1}`)
+
+	createValidMetaForShow(t, dataDir, repoID, "run1", filepath.Join(dataDir, "wt", "run1"), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	if err := st.UpdateMeta(repoID, "run1", func(m *store.RunMeta) {
+		m.Archive = &store.RunMetaArchive{MergedAt: "2025-01-02T00:00:00Z"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	candidates, _, err := findGCCandidates(fs.NewRealFS(), dataDir, time.Now(), nil, &stderr)
+	if err != nil {
+		t.Fatalf("findGCCandidates() error = %v", err)
+	}
+	if len(candidates) != 0 {
+		t.Errorf("len(candidates) = %d, want 0 without retention policy", len(candidates))
+	}
+}
+
+func TestGC_EncryptsLeftoverPlaintext(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	repoID := "abc123"
+	setupGCRepo(t, dataDir, repoID, "github:owner/repo", `{"version": 1}`)
+
+	// Files written before encryption was enabled, plus a crashed atomic write
+	now := time.Now()
+	createValidMetaForShow(t, dataDir, repoID, "run-old", filepath.Join(dataDir, "wt"), now)
+	runDir := filepath.Join(dataDir, "repos", repoID, "runs", "run-old")
+	logPath := filepath.Join(runDir, "logs", "setup.log")
+	tempPath := filepath.Join(runDir, ".agency-tmp-meta.json")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{logPath, tempPath} {
+		if err := os.WriteFile(path, []byte("setup output\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := now.Add(-time.Hour)
+	for _, path := range []string{filepath.Join(runDir, "meta.json"), logPath, tempPath} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without encryption, nothing qualifies
+	var stdout, stderr bytes.Buffer
+	if got, err := findSealCandidates(dataDir, now, nil); err != nil || len(got) != 0 {
+		t.Fatalf("findSealCandidates() while disabled = %+v, %v", got, err)
+	}
+
+	testkit.FakeAge(t)
+	if err := GC(context.Background(), agencyexec.NewRealRunner(), fs.NewRealFS(), t.TempDir(), GCOpts{}, &stdout, &stderr); err != nil {
+		t.Fatalf("GC() dry run error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "would encrypt 2 plaintext file(s) and remove 1 temp file(s) of run-old") {
+		t.Errorf("dry run stdout = %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if err := GC(context.Background(), agencyexec.NewRealRunner(), fs.NewRealFS(), t.TempDir(), GCOpts{Auto: true}, &stdout, &stderr); err != nil {
+		t.Fatalf("GC() error = %v (stderr: %s)", err, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "encrypted 2 plaintext file(s) and removed 1 temp file(s) of run-old") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	if got := store.PlaintextRunFiles(runDir); len(got) != 0 {
+		t.Errorf("plaintext left after gc: %v", got)
+	}
+	if _, err := os.Stat(tempPath); !os.IsNotExist(err) {
+		t.Errorf("temp file still exists (err=%v)", err)
+	}
+
+	// agency logs decrypts transparently
+	stdout.Reset()
+	if err := Logs(context.Background(), agencyexec.NewRealRunner(), fs.NewRealFS(), t.TempDir(), LogsOpts{RunID: "run-old", Name: "setup"}, &stdout, &stderr); err != nil {
+		t.Fatalf("Logs() error = %v", err)
+	}
+	if stdout.String() != "setup output\n" {
+		t.Errorf("Logs() output = %q", stdout.String())
+	}
+}
+
+func TestGC_CompressLogs(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	repoID := "abc123"
+	setupGCRepo(t, dataDir, repoID, "github:owner/repo", `{
+		"version": 1,
+		"logs": {"compress_after_days": 7}
+	}`)
+
+	now := time.Now()
+	for runID, created := range map[string]time.Time{
+		"run-old": now.AddDate(0, 0, -10),
+		"run-new": now.AddDate(0, 0, -1),
+	} {
+		createValidMetaForShow(t, dataDir, repoID, runID, filepath.Join(dataDir, "wt", runID), created)
+		logsDir := filepath.Join(dataDir, "repos", repoID, "runs", runID, "logs")
+		if err := os.MkdirAll(logsDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(logsDir, "setup.log"), []byte("setup output\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	_, logCandidates, err := findGCCandidates(fs.NewRealFS(), dataDir, now, nil, &stderr)
+	if err != nil {
+		t.Fatalf("findGCCandidates() error = %v", err)
+	}
+	if len(logCandidates) != 1 || logCandidates[0].record.RunID != "run-old" {
+		t.Fatalf("logCandidates = %+v, want run-old only", logCandidates)
+	}
+
+	if err := GC(context.Background(), nil, fs.NewRealFS(), t.TempDir(), GCOpts{Auto: true}, &stdout, &stderr); err != nil {
+		t.Fatalf("GC() error = %v (stderr: %s)", err, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "compressed logs of run-old") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	oldLogs := filepath.Join(dataDir, "repos", repoID, "runs", "run-old", "logs")
+	if _, err := os.Stat(filepath.Join(oldLogs, "setup.log.gz")); err != nil {
+		t.Errorf("setup.log.gz missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "repos", repoID, "runs", "run-new", "logs", "setup.log")); err != nil {
+		t.Errorf("recent run's log was touched: %v", err)
+	}
+
+	// agency logs reads the compressed log transparently
+	stdout.Reset()
+	if err := Logs(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), LogsOpts{RunID: "run-old", Name: "setup"}, &stdout, &stderr); err != nil {
+		t.Fatalf("Logs() error = %v", err)
+	}
+	if stdout.String() != "setup output\n" {
+		t.Errorf("Logs() output = %q", stdout.String())
+	}
+	stdout.Reset()
+	if err := Logs(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), LogsOpts{RunID: "run-old"}, &stdout, &stderr); err != nil {
+		t.Fatalf("Logs() list error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "setup.log: ") || !strings.Contains(stdout.String(), "(compressed)") {
+		t.Errorf("Logs() list = %q", stdout.String())
+	}
+	err = Logs(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), LogsOpts{RunID: "run-old", Name: "verify"}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.ELogNotFound {
+		t.Errorf("missing log code = %q, want %q", errors.GetCode(err), errors.ELogNotFound)
+	}
+}
+
+func TestAutoArchiveRun_RechecksRetentionUnderLock(t *testing.T) {
+	dataDir := t.TempDir()
+	repoID := "abc123"
+	setupGCRepo(t, dataDir, repoID, "github:owner/repo", `{"version": 1, "retention": {"auto_archive_after_days": 30}}`)
+
+	worktree := filepath.Join(dataDir, "repos", repoID, "worktrees", "run1")
+	if err := os.MkdirAll(worktree, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	createValidMetaForShow(t, dataDir, repoID, "run1", worktree, time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	if err := st.UpdateMeta(repoID, "run1", func(m *store.RunMeta) {
+		m.Archive = &store.RunMetaArchive{MergedAt: "2026-01-15T12:00:00Z"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	candidates, _, err := findGCCandidates(fs.NewRealFS(), dataDir, time.Now(), nil, &stderr)
+	if err != nil || len(candidates) != 1 {
+		t.Fatalf("findGCCandidates() = %d candidates, %v", len(candidates), err)
+	}
+
+	// The run is reopened and merged again after the scan
+	if err := st.UpdateMeta(repoID, "run1", func(m *store.RunMeta) {
+		m.Archive = &store.RunMetaArchive{MergedAt: time.Now().UTC().Format(time.RFC3339)}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cr := testkit.NewFakeRunner()
+	if err := autoArchiveRun(context.Background(), cr, st, lock.NewRepoLock(dataDir), candidates[0], &stdout, &stderr); err != nil {
+		t.Fatalf("autoArchiveRun() error = %v", err)
+	}
+	if !strings.Contains(stderr.String(), "skipping run1: no longer due for auto-archive") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+	if _, err := os.Stat(worktree); err != nil {
+		t.Errorf("worktree deleted: %v", err)
+	}
+	if meta, _ := st.ReadMeta(repoID, "run1"); meta.Archive.ArchivedAt != "" {
+		t.Errorf("run archived: %+v", meta.Archive)
+	}
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// GroupAddOpts holds options for the group add command.
+type GroupAddOpts struct {
+	// RunID is the run identifier (exact or unique prefix).
+	RunID string
+
+	// Group is the group to add the run to.
+	Group string
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+}
+
+// GroupAdd assigns a run to a named group: meta.json group is set under the
+// repo lock and the run is indexed in groups.json. A run belongs to at most
+// one group; adding it to another moves it.
+func GroupAdd(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts GroupAddOpts, stdout, stderr io.Writer) error {
+	if opts.RunID == "" {
+		return errors.New(errors.EUsage, "run_id is required")
+	}
+	if err := core.ValidateGroupName(opts.Group); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid group", err)
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+	record, err := resolveRun(dirs.DataDir, opts.RunID, scope, crypter)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock.NewRepoLock(dirs.DataDir).Lock(record.RepoID, "group add")
+	if err != nil {
+		return repoLockError(err, dirs.DataDir, record.RepoID)
+	}
+	defer func() { _ = unlock() }()
+
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+	if err := st.UpdateMeta(record.RepoID, record.RunID, func(m *store.RunMeta) {
+		m.Group = opts.Group
+	}); err != nil {
+		return err
+	}
+	if err := st.AddRunToGroup(opts.Group, record.RepoID, record.RunID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "run_id: %s\n", record.RunID)
+	fmt.Fprintf(stdout, "group: %s\n", opts.Group)
+	return nil
+}
+
+// GroupLSOpts holds options for the group ls command.
+type GroupLSOpts struct {
+	// JSON outputs machine-readable JSON.
+	JSON bool
+}
+
+// GroupLS lists run groups across all repos with their member runs and
+// aggregate derived status. Membership comes from each run's meta.json;
+// groups.json contributes creation times and groups whose runs are gone.
+// This is a read-only command: no state files are mutated.
+func GroupLS(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts GroupLSOpts, stdout, stderr io.Writer) error {
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+	index, err := st.LoadGroups()
+	if err != nil {
+		return err
+	}
+	records, warnings := store.ScanAllRunsWithWarnings(dirs.DataDir, crypter)
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: skipped %s: %s\n", w.Path, w.Message)
+	}
+
+	byName := make(map[string]*render.GroupSummary)
+	group := func(name string) *render.GroupSummary {
+		g, ok := byName[name]
+		if !ok {
+			g = &render.GroupSummary{Group: name, RunIDs: []string{}, Statuses: map[string]int{}}
+			if entry, ok := index.Groups[name]; ok {
+				createdAt := entry.CreatedAt
+				g.CreatedAt = &createdAt
+			}
+			byName[name] = g
+		}
+		return g
+	}
+	for name := range index.Groups {
+		group(name)
+	}
+
+	tmuxSessions := newTmuxSessionSet(ctx, cr)
+	policies := newReviewPolicySet(fsys, dirs.DataDir)
+	for _, rec := range records {
+		if rec.Meta == nil || rec.Meta.Group == "" {
+			continue
+		}
+		g := group(rec.Meta.Group)
+		summary := recordToSummary(ctx, cr, rec, tmuxSessions, policies, nil, fsys)
+		g.RunIDs = append(g.RunIDs, rec.RunID)
+		g.Statuses[summary.DerivedStatus]++
+	}
+
+	groups := make([]render.GroupSummary, 0, len(byName))
+	for _, g := range byName {
+		sort.Strings(g.RunIDs)
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Group < groups[j].Group })
+
+	if opts.JSON {
+		return render.WriteGroupsJSON(stdout, groups)
+	}
+	return render.WriteGroupsHuman(stdout, groups)
+}
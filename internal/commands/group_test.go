@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestGroupAddAndLS(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	for _, runID := range []string{"20260110120000-a3f2", "20260110130000-b4c5", "20260110140000-c6d7"} {
+		worktree := filepath.Join(dataDir, "repos", "repo1", "worktrees", runID)
+		if err := os.MkdirAll(worktree, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		testkit.WriteRun(t, dataDir, testkit.NewRunMeta("repo1", runID, worktree, created))
+	}
+	ctx := context.Background()
+	fsys := fs.NewRealFS()
+	cwd := t.TempDir()
+
+	var stdout bytes.Buffer
+	opts := GroupAddOpts{RunID: "20260110120000", Group: "payments-refactor"}
+	if err := GroupAdd(ctx, testkit.NewFakeRunner(), fsys, cwd, opts, &stdout, io.Discard); err != nil {
+		t.Fatalf("GroupAdd: %v", err)
+	}
+	if got := stdout.String(); got != "run_id: 20260110120000-a3f2\ngroup: payments-refactor\n" {
+		t.Errorf("stdout = %q", got)
+	}
+	opts = GroupAddOpts{RunID: "20260110130000", Group: "payments-refactor"}
+	if err := GroupAdd(ctx, testkit.NewFakeRunner(), fsys, cwd, opts, io.Discard, io.Discard); err != nil {
+		t.Fatalf("GroupAdd: %v", err)
+	}
+
+	st := store.NewStore(fsys, dataDir, time.Now)
+	meta, err := st.ReadMeta("repo1", "20260110120000-a3f2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Group != "payments-refactor" {
+		t.Errorf("meta.group = %q", meta.Group)
+	}
+
+	// Invalid group names are usage errors
+	opts = GroupAddOpts{RunID: "20260110140000", Group: "-bad name"}
+	err = GroupAdd(ctx, testkit.NewFakeRunner(), fsys, cwd, opts, io.Discard, io.Discard)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("invalid group: got %v, want E_USAGE", err)
+	}
+
+	stdout.Reset()
+	if err := GroupLS(ctx, testkit.NewFakeRunner(), fsys, cwd, GroupLSOpts{JSON: true}, &stdout, io.Discard); err != nil {
+		t.Fatalf("GroupLS: %v", err)
+	}
+	var env render.GroupsJSONEnvelope
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, stdout.String())
+	}
+	if len(env.Data) != 1 {
+		t.Fatalf("groups = %+v", env.Data)
+	}
+	g := env.Data[0]
+	if g.Group != "payments-refactor" || len(g.RunIDs) != 2 || g.CreatedAt == nil || g.CreatedAtUnix == nil {
+		t.Errorf("group = %+v", g)
+	}
+	total := 0
+	for _, n := range g.Statuses {
+		total += n
+	}
+	if total != 2 {
+		t.Errorf("statuses = %v, want 2 runs counted", g.Statuses)
+	}
+
+	stdout.Reset()
+	if err := GroupLS(ctx, testkit.NewFakeRunner(), fsys, cwd, GroupLSOpts{}, &stdout, io.Discard); err != nil {
+		t.Fatalf("GroupLS: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "payments-refactor  2") {
+		t.Errorf("human output = %q", stdout.String())
+	}
+
+	// ls --group only lists members
+	stdout.Reset()
+	lsOpts := LSOpts{AllRepos: true, JSON: true, Group: "payments-refactor"}
+	if err := LS(ctx, newMockRunner(), fsys, cwd, lsOpts, &stdout, io.Discard); err != nil {
+		t.Fatalf("LS: %v", err)
+	}
+	var lsEnv render.LSJSONEnvelope
+	if err := json.Unmarshal(stdout.Bytes(), &lsEnv); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(lsEnv.Data) != 2 {
+		t.Errorf("ls --group listed %d runs, want 2", len(lsEnv.Data))
+	}
+}
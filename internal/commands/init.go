@@ -9,10 +9,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/NielsdaWheelz/agency/internal/config"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	"github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/git"
+	"github.com/NielsdaWheelz/agency/internal/identity"
 	"github.com/NielsdaWheelz/agency/internal/scaffold"
 )
 
@@ -20,19 +22,66 @@ import (
 type InitOpts struct {
 	NoGitignore bool
 	Force       bool
+
+	// Preset selects the setup/verify stubs (see scaffold.Presets, or
+	// scaffold.PresetNone); "" detects it from the repo's files.
+	Preset string
+
+	// Prompter, when set, runs the interactive wizard (parent branch,
+	// runner, GitHub flow) instead of writing the default template.
+	Prompter InitPrompter
+
+	// FromURL, when set, clones this git URL first and initializes the
+	// clone, then registers it in repo_index.json.
+	FromURL string
+
+	// Dir is the directory FromURL is cloned into (default: the URL's last
+	// path component without .git, relative to cwd).
+	Dir string
+
+	// Doctor runs agency doctor in the repo after a successful init.
+	Doctor bool
+}
+
+// InitPrompter asks the init wizard's questions.
+type InitPrompter interface {
+	// Ask returns the answer to question, or def if the answer is empty.
+	Ask(question, def string) string
+
+	// Confirm returns a yes/no answer to question, or def if the answer is empty.
+	Confirm(question string, def bool) bool
 }
 
 // InitResult holds the result of the init command for output formatting.
 type InitResult struct {
+	ClonedFrom      string // --from-url, "" otherwise
+	RepoID          string // set when the repo was registered (--from-url)
 	RepoRoot        string
 	AgencyJSONState string // "created" or "overwritten"
 	ScriptsCreated  []string
+	Preset          string
 	GitignoreState  scaffold.GitignoreResult
 }
 
 // Init implements the `agency init` command.
 // Creates agency.json, stub scripts (if missing), and updates .gitignore (by default).
+// With FromURL, it clones the repo first and registers the clone.
 func Init(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string, opts InitOpts, stdout, stderr io.Writer) error {
+	if opts.Preset != "" && !scaffold.IsPreset(opts.Preset) {
+		return errors.New(errors.EUsage, "unknown --preset "+opts.Preset+"; valid: "+presetChoices())
+	}
+	if opts.Dir != "" && opts.FromURL == "" {
+		return errors.New(errors.EUsage, "--dir requires --from-url")
+	}
+
+	if opts.FromURL != "" {
+		dir, err := cloneForInit(ctx, cr, fsys, cwd, opts, stderr)
+		if err != nil {
+			return err
+		}
+		cwd = dir
+	}
+
 	// Discover repo root
 	repoRoot, err := git.GetRepoRoot(ctx, cr, cwd)
 	if err != nil {
@@ -59,13 +108,32 @@ func Init(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string, op
 		agencyJSONState = "overwritten"
 	}
 
+	// Ask the wizard's questions (before anything is written)
+	agencyJSON := scaffold.AgencyJSONTemplate
+	if opts.Prompter != nil {
+		agencyJSON = scaffold.RenderAgencyJSON(initWizard(ctx, cr, repoRoot.Path, opts.Prompter, stderr))
+	}
+
+	// Pick the stub preset: --preset, else detected (and confirmed in the wizard)
+	preset := opts.Preset
+	if preset == "" {
+		preset, _ = scaffold.DetectPreset(fsys, repoRoot.Path)
+		for opts.Prompter != nil {
+			preset = opts.Prompter.Ask("setup/verify preset ("+presetChoices()+")", preset)
+			if scaffold.IsPreset(preset) {
+				break
+			}
+			fmt.Fprintln(stderr, "unknown preset "+preset)
+		}
+	}
+
 	// Write agency.json atomically
-	if err := fs.WriteFileAtomic(fsys, agencyJSONPath, []byte(scaffold.AgencyJSONTemplate), 0644); err != nil {
+	if err := fs.WriteFileAtomic(fsys, agencyJSONPath, []byte(agencyJSON), 0644); err != nil {
 		return errors.Wrap(errors.ENoRepo, "failed to write agency.json", err)
 	}
 
 	// Create stub scripts (never overwrite existing)
-	stubsResult, err := scaffold.CreateStubs(fsys, repoRoot.Path)
+	stubsResult, err := scaffold.CreateStubs(fsys, repoRoot.Path, scaffold.PresetStubs(preset))
 	if err != nil {
 		return errors.Wrap(errors.ENoRepo, "failed to create stub scripts", err)
 	}
@@ -84,12 +152,22 @@ func Init(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string, op
 
 	// Build result
 	result := InitResult{
+		ClonedFrom:      opts.FromURL,
 		RepoRoot:        repoRoot.Path,
 		AgencyJSONState: agencyJSONState,
 		ScriptsCreated:  stubsResult.Created,
+		Preset:          preset,
 		GitignoreState:  gitignoreState,
 	}
 
+	// Register a fresh clone so that commands run from elsewhere (ls, gc,
+	// --repo) know it before its first run
+	if opts.FromURL != "" {
+		if result.RepoID, err = registerRepo(ctx, cr, fsys, repoRoot.Path); err != nil {
+			return err
+		}
+	}
+
 	// Output result
 	writeInitOutput(stdout, result)
 
@@ -98,12 +176,143 @@ func Init(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string, op
 		fmt.Fprintln(stdout, "warning: gitignore_skipped")
 	}
 
+	if opts.Doctor {
+		fmt.Fprintln(stdout)
+		return Doctor(ctx, cr, fsys, repoRoot.Path, stdout, stderr)
+	}
 	return nil
 }
 
+// cloneForInit clones opts.FromURL into opts.Dir (or a directory named
+// after the URL) relative to cwd and returns the clone's absolute path.
+// The directory must not exist or be empty, as for git clone.
+func cloneForInit(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string, opts InitOpts, stderr io.Writer) (string, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = cloneDirName(opts.FromURL)
+		if dir == "" {
+			return "", errors.New(errors.EUsage, "cannot derive a directory name from "+opts.FromURL+"; use --dir")
+		}
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cwd, dir)
+	}
+	dir = filepath.Clean(dir)
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return "", errors.WithHints(errors.NewWithDetails(errors.ECloneFailed, "destination is not empty: "+dir,
+			map[string]string{"url": opts.FromURL, "dir": dir}),
+			"pick another directory with --dir, or run agency init inside the existing clone")
+	}
+	if err := fsys.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return "", errors.Wrap(errors.ECloneFailed, "failed to create "+filepath.Dir(dir), err)
+	}
+	fmt.Fprintf(stderr, "cloning %s into %s...\n", opts.FromURL, dir)
+	if err := git.Clone(ctx, cr, opts.FromURL, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// cloneDirName returns the directory git clone would pick for url: its last
+// path component without a trailing .git (e.g. "repo" for
+// git@github.com:owner/repo.git). Returns "" if there is none.
+func cloneDirName(url string) string {
+	name := strings.TrimRight(url, "/")
+	name = strings.TrimSuffix(name, ".git")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// registerRepo records the freshly initialized repo in repo_index.json and
+// its repo.json, as agency doctor does, and returns its repo_id.
+func registerRepo(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, repoRoot string) (string, error) {
+	dirs, err := resolveDirs(fsys, repoRoot)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := config.LoadAndValidate(fsys, repoRoot)
+	if err != nil {
+		return "", err
+	}
+	originInfo := git.GetOriginInfo(ctx, cr, repoRoot)
+	repoIdentity := identity.DeriveRepoIdentity(repoRoot, originInfo.URL)
+	if err := persistOnSuccess(fsys, dirs.DataDir, repoRoot, repoIdentity, originInfo, cfg, nil); err != nil {
+		return "", err
+	}
+	return repoIdentity.RepoID, nil
+}
+
+// initWizard asks for the agency.json choices, checking each against the
+// environment as it goes. Problems (a missing branch, tmux, or gh) are
+// warnings on stderr: the repo may be set up before the tools are.
+func initWizard(ctx context.Context, cr exec.CommandRunner, repoRoot string, p InitPrompter, stderr io.Writer) scaffold.AgencyJSONOptions {
+	opts := scaffold.DefaultAgencyJSONOptions
+
+	// Parent branch: default to main, or master if that is all the repo has
+	if exists, _ := git.BranchExists(ctx, cr, repoRoot, "main"); !exists {
+		if exists, _ := git.BranchExists(ctx, cr, repoRoot, "master"); exists {
+			opts.ParentBranch = "master"
+		}
+	}
+	for {
+		opts.ParentBranch = p.Ask("parent branch", opts.ParentBranch)
+		if !strings.ContainsAny(opts.ParentBranch, " \t") {
+			break
+		}
+		fmt.Fprintln(stderr, "branch names cannot contain whitespace")
+	}
+	if exists, _ := git.BranchExists(ctx, cr, repoRoot, opts.ParentBranch); !exists {
+		fmt.Fprintf(stderr, "warning: branch %s does not exist yet\n", opts.ParentBranch)
+	}
+
+	// Runner
+	for {
+		opts.Runner = p.Ask("runner (claude, codex, or a command on PATH)", opts.Runner)
+		if !strings.ContainsAny(opts.Runner, " \t") {
+			break
+		}
+		fmt.Fprintln(stderr, "runner must be a single executable (no args); use a wrapper script")
+	}
+
+	// agency run always needs tmux
+	if _, err := checkTmux(ctx, cr); err != nil {
+		fmt.Fprintf(stderr, "warning: %s; agency run needs tmux\n", err)
+	}
+
+	// GitHub flow: default on for github.com origins
+	origin := git.GetOriginInfo(ctx, cr, repoRoot)
+	opts.GitHubFlow = p.Confirm("enable GitHub flow (pull requests via gh)?", origin.Host == "github.com")
+	if opts.GitHubFlow {
+		if _, err := checkGh(ctx, cr); err != nil {
+			fmt.Fprintf(stderr, "warning: %s\n", err)
+		} else if err := checkGhAuth(ctx, cr); err != nil {
+			fmt.Fprintf(stderr, "warning: %s\n", err)
+		}
+	}
+
+	return opts
+}
+
+// presetChoices lists the valid --preset values, e.g. "go, node, none".
+func presetChoices() string {
+	return strings.Join(append(scaffold.Presets(), scaffold.PresetNone), ", ")
+}
+
 // writeInitOutput writes the stable key: value output for init.
 func writeInitOutput(w io.Writer, r InitResult) {
+	if r.ClonedFrom != "" {
+		fmt.Fprintf(w, "cloned_from: %s\n", r.ClonedFrom)
+	}
 	fmt.Fprintf(w, "repo_root: %s\n", r.RepoRoot)
+	if r.RepoID != "" {
+		fmt.Fprintf(w, "repo_id: %s\n", r.RepoID)
+	}
 	fmt.Fprintf(w, "agency_json: %s\n", r.AgencyJSONState)
 
 	scriptsCreated := "none"
@@ -111,6 +320,7 @@ func writeInitOutput(w io.Writer, r InitResult) {
 		scriptsCreated = strings.Join(r.ScriptsCreated, ", ")
 	}
 	fmt.Fprintf(w, "scripts_created: %s\n", scriptsCreated)
+	fmt.Fprintf(w, "preset: %s\n", r.Preset)
 
 	fmt.Fprintf(w, "gitignore: %s\n", r.GitignoreState)
 }
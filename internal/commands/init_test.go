@@ -5,13 +5,16 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/NielsdaWheelz/agency/internal/config"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	"github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/scaffold"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
 )
 
 // stubRunner is a CommandRunner that returns a fixed repo root.
@@ -476,3 +479,191 @@ func TestInit_ScriptsNotCreatedIfExist(t *testing.T) {
 		t.Errorf("output should say 'scripts_created: none': %s", output)
 	}
 }
+
+// scriptedPrompter answers the init wizard's questions from a map,
+// taking the default for anything unlisted.
+type scriptedPrompter struct {
+	answers  map[string]string
+	defaults map[string]string
+}
+
+func (p *scriptedPrompter) Ask(question, def string) string {
+	p.defaults[question] = def
+	if answer, ok := p.answers[question]; ok {
+		return answer
+	}
+	return def
+}
+
+func (p *scriptedPrompter) Confirm(question string, def bool) bool {
+	p.defaults[question] = strconv.FormatBool(def)
+	if answer, ok := p.answers[question]; ok {
+		return answer == "y"
+	}
+	return def
+}
+
+func TestInit_Wizard(t *testing.T) {
+	repoRoot := setupTempGitRepo(t)
+
+	cr := testkit.NewFakeRunner()
+	cr.On("git", "rev-parse", "--show-toplevel").Stdout(repoRoot + "\n")
+	cr.On("git", "config", "--get", "remote.origin.url").Stdout("git@github.com:owner/repo.git\n")
+	cr.On("git", "show-ref", "--verify", "refs/heads/main").Exit(1)
+	cr.On("git", "show-ref", "--verify", "refs/heads/master").Exit(0)
+	cr.On("git", "show-ref", "--verify", "refs/heads/develop").Exit(1)
+	cr.On("tmux", "-V").Fail(os.ErrNotExist)
+	cr.On("gh", "--version").Stdout(testkit.GhVersion + "\n")
+	cr.GhAuthenticated(false)
+
+	p := &scriptedPrompter{
+		answers: map[string]string{
+			"parent branch": "develop",
+			"runner (claude, codex, or a command on PATH)": "aider",
+		},
+		defaults: make(map[string]string),
+	}
+	var stdout, stderr bytes.Buffer
+	if err := Init(context.Background(), cr, fs.NewRealFS(), repoRoot, InitOpts{Prompter: p}, &stdout, &stderr); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	if got := p.defaults["parent branch"]; got != "master" {
+		t.Errorf("parent branch default = %q, want master (main is missing)", got)
+	}
+	if got := p.defaults["enable GitHub flow (pull requests via gh)?"]; got != "true" {
+		t.Errorf("GitHub flow default = %s, want true for a github.com origin", got)
+	}
+	for _, warning := range []string{"branch develop does not exist yet", "E_TMUX_NOT_INSTALLED", "E_GH_NOT_AUTHENTICATED"} {
+		if !strings.Contains(stderr.String(), warning) {
+			t.Errorf("stderr missing %q:\n%s", warning, stderr.String())
+		}
+	}
+
+	cfg, err := config.LoadAndValidate(fs.NewRealFS(), repoRoot)
+	if err != nil {
+		t.Fatalf("generated agency.json invalid: %v", err)
+	}
+	if cfg.Defaults.ParentBranch != "develop" || cfg.Defaults.Runner != "aider" || cfg.ResolvedRunnerCmd != "aider" {
+		t.Errorf("defaults = %+v, runner_cmd = %q", cfg.Defaults, cfg.ResolvedRunnerCmd)
+	}
+	if !cfg.GitHub.FlowEnabled() {
+		t.Error("GitHub flow disabled, want enabled")
+	}
+
+	// Declining the GitHub flow writes github.flow false and skips the gh checks
+	cr = testkit.NewFakeRunner()
+	cr.AllToolsOK(repoRoot, "")
+	cr.On("git", "show-ref", "--verify", "refs/heads/main").Exit(0)
+	p.answers = map[string]string{"enable GitHub flow (pull requests via gh)?": "n"}
+	if err := Init(context.Background(), cr, fs.NewRealFS(), repoRoot, InitOpts{Force: true, Prompter: p}, &stdout, &stderr); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if len(cr.CallsTo("gh")) != 0 {
+		t.Errorf("gh checked with the GitHub flow declined: %v", cr.CallsTo("gh"))
+	}
+	content, _ := os.ReadFile(filepath.Join(repoRoot, "agency.json"))
+	want := scaffold.RenderAgencyJSON(scaffold.AgencyJSONOptions{ParentBranch: "main", Runner: "claude"})
+	if string(content) != want {
+		t.Errorf("agency.json:\n%s\nwant:\n%s", content, want)
+	}
+	if scaffold.RenderAgencyJSON(scaffold.DefaultAgencyJSONOptions) != scaffold.AgencyJSONTemplate {
+		t.Error("default options do not render the template")
+	}
+}
+
+func TestInit_Preset(t *testing.T) {
+	repoRoot := setupTempGitRepo(t)
+	if err := os.WriteFile(filepath.Join(repoRoot, "go.mod"), []byte("module example.com/x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cr := &stubRunner{repoRoot: repoRoot}
+	var stdout, stderr bytes.Buffer
+
+	// Detected from go.mod
+	if err := Init(context.Background(), cr, fs.NewRealFS(), repoRoot, InitOpts{}, &stdout, &stderr); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "preset: go\n") {
+		t.Errorf("output missing preset: go:\n%s", stdout.String())
+	}
+	verify, _ := os.ReadFile(filepath.Join(repoRoot, "scripts", "agency_verify.sh"))
+	if !strings.Contains(string(verify), "go test ./...") {
+		t.Errorf("verify script not from the go preset:\n%s", verify)
+	}
+	archive, _ := os.ReadFile(filepath.Join(repoRoot, "scripts", "agency_archive.sh"))
+	if string(archive) != scaffold.ArchiveStub {
+		t.Errorf("archive script = %q, want the default stub", archive)
+	}
+
+	// --preset wins over detection
+	for _, name := range []string{"agency_setup.sh", "agency_verify.sh"} {
+		os.Remove(filepath.Join(repoRoot, "scripts", name))
+	}
+	stdout.Reset()
+	if err := Init(context.Background(), cr, fs.NewRealFS(), repoRoot, InitOpts{Force: true, Preset: "node"}, &stdout, &stderr); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	setup, _ := os.ReadFile(filepath.Join(repoRoot, "scripts", "agency_setup.sh"))
+	if !strings.Contains(string(setup), "npm ci") {
+		t.Errorf("setup script not from the node preset:\n%s", setup)
+	}
+
+	err := Init(context.Background(), cr, fs.NewRealFS(), repoRoot, InitOpts{Force: true, Preset: "cobol"}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EUsage || !strings.Contains(err.Error(), "go, node, python, rust, none") {
+		t.Errorf("unknown preset: err = %v, want E_USAGE listing the presets", err)
+	}
+}
+
+func TestInit_FromURL(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	src := testkit.NewRepo(t, testkit.RepoOpts{NoAgencyJSON: true, Scripts: map[string]string{}, Files: map[string]string{"go.mod": "module example.com/x\n"}, Git: true})
+	cwd := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	opts := InitOpts{FromURL: src, Dir: "work/x"}
+	if err := Init(context.Background(), exec.NewRealRunner(), fs.NewRealFS(), cwd, opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Init() error = %v (stderr %q)", err, stderr.String())
+	}
+	clone := filepath.Join(cwd, "work", "x")
+	for _, want := range []string{"cloned_from: " + src + "\n", "repo_root: " + clone + "\n", "repo_id: ", "preset: go\n"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout.String())
+		}
+	}
+	if _, err := config.LoadAndValidate(fs.NewRealFS(), clone); err != nil {
+		t.Errorf("clone's agency.json invalid: %v", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(dataDir, "repo_index.json"))
+	if err != nil || !strings.Contains(string(index), clone) {
+		t.Errorf("repo_index.json = %s, %v; want the clone registered", index, err)
+	}
+
+	// A non-empty destination is refused before cloning
+	err = Init(context.Background(), exec.NewRealRunner(), fs.NewRealFS(), cwd, opts, &stdout, &stderr)
+	if errors.GetCode(err) != errors.ECloneFailed {
+		t.Errorf("Init() into a non-empty dir error = %v, want E_CLONE_FAILED", err)
+	}
+	err = Init(context.Background(), exec.NewRealRunner(), fs.NewRealFS(), cwd, InitOpts{Dir: "y"}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("Init() with --dir alone error = %v, want E_USAGE", err)
+	}
+}
+
+func TestCloneDirName(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:owner/repo.git":   "repo",
+		"https://github.com/owner/repo/":  "repo",
+		"ssh://host/srv/git/project.git/": "project",
+		"/srv/repos/local":                "local",
+		"host:repo":                       "repo",
+		"https://github.com/":             "github.com",
+		"..":                              "",
+	}
+	for url, want := range tests {
+		if got := cloneDirName(url); got != want {
+			t.Errorf("cloneDirName(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// geteuid and pathOwner are replaceable in tests.
+var (
+	geteuid   = os.Geteuid
+	pathOwner = fs.ExistingOwner
+)
+
+// GuardInvocation refuses invocations that leave the data dir, or a repo,
+// with files their owner cannot change or remove:
+//   - running as root when the data dir or cwd belongs to another user
+//     (E_RUNNING_AS_ROOT), unless allowRoot. Root on its own data dir, as
+//     in a container, is fine
+//   - write commands run with cwd inside the data dir (E_CWD_IN_DATA_DIR),
+//     except inside a run's worktree, where agency is meant to be used
+//
+// Directory resolution errors are left for the command to report.
+func GuardInvocation(fsys fs.FS, cwd string, access DataDirAccess, allowRoot bool) error {
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return nil
+	}
+
+	if !allowRoot && geteuid() == 0 {
+		for _, path := range []string{dirs.DataDir, cwd} {
+			uid, ok := pathOwner(path)
+			if !ok || uid == 0 {
+				continue
+			}
+			owner := fs.UserName(uid)
+			return errors.WithHints(errors.NewWithDetails(errors.ERunningAsRoot,
+				"refusing to run as root: "+path+" belongs to "+owner+", who could not change the files root would create",
+				map[string]string{"path": path, "owner": owner, "data_dir": dirs.DataDir}),
+				"run agency as "+owner+" (without sudo)",
+				"or pass --allow-root if root should own these files")
+		}
+	}
+
+	if access == DataDirWrite {
+		if rel, ok := pathWithin(dirs.DataDir, cwd); ok && !inRunWorktree(rel) {
+			return errors.WithHints(errors.NewWithDetails(errors.ECwdInDataDir,
+				"refusing to run from inside the data dir: "+cwd,
+				map[string]string{"cwd": cwd, "data_dir": dirs.DataDir}),
+				"cd to your repo (or a run's worktree) and run the command again")
+		}
+	}
+	return nil
+}
+
+// pathWithin returns path relative to dir if path is dir or inside it,
+// comparing the paths with symlinks resolved where they exist.
+func pathWithin(dir, path string) (string, bool) {
+	dir, path = resolvePath(dir), resolvePath(path)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// resolvePath returns path cleaned and with symlinks resolved (as is if it
+// cannot be resolved).
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// inRunWorktree reports whether rel (relative to the data dir) is inside a
+// run's worktree: repos/<repo_id>/worktrees/<run_id>/...
+func inRunWorktree(rel string) bool {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	return len(parts) >= 4 && parts[0] == "repos" && parts[2] == "worktrees"
+}
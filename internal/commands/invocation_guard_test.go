@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestGuardInvocation_Root(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	cwd := t.TempDir()
+	fsys := fs.NewRealFS()
+
+	owners := map[string]int{}
+	euid := 0
+	origEuid, origOwner := geteuid, pathOwner
+	geteuid = func() int { return euid }
+	pathOwner = func(path string) (int, bool) { return owners[path], true }
+	t.Cleanup(func() { geteuid, pathOwner = origEuid, origOwner })
+
+	// Root on root's own files (e.g. in a container) is fine
+	if err := GuardInvocation(fsys, cwd, DataDirWrite, false); err != nil {
+		t.Fatalf("root on root-owned dirs: %v", err)
+	}
+
+	// Root on another user's data dir or repo is refused unless allowed
+	for _, path := range []string{dataDir, cwd} {
+		owners = map[string]int{path: 4242}
+		err := GuardInvocation(fsys, cwd, DataDirRead, false)
+		if errors.GetCode(err) != errors.ERunningAsRoot {
+			t.Errorf("%s owned by another user: code = %q, want %q", path, errors.GetCode(err), errors.ERunningAsRoot)
+		}
+		if details := errors.Details(err); details["path"] != path {
+			t.Errorf("details = %v, want path %s", details, path)
+		}
+		if err := GuardInvocation(fsys, cwd, DataDirRead, true); err != nil {
+			t.Errorf("--allow-root: %v", err)
+		}
+	}
+
+	// Not root: owners do not matter
+	euid = 1000
+	if err := GuardInvocation(fsys, cwd, DataDirWrite, false); err != nil {
+		t.Errorf("non-root user: %v", err)
+	}
+}
+
+func TestGuardInvocation_CwdInDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	fsys := fs.NewRealFS()
+
+	runsDir := filepath.Join(dataDir, "repos", "r1", "runs")
+	worktree := filepath.Join(dataDir, "repos", "r1", "worktrees", "20260110120000-a3f2", "src")
+	for _, dir := range []string{runsDir, worktree} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, cwd := range []string{dataDir, runsDir} {
+		if err := GuardInvocation(fsys, cwd, DataDirWrite, true); errors.GetCode(err) != errors.ECwdInDataDir {
+			t.Errorf("write from %s: code = %q, want %q", cwd, errors.GetCode(err), errors.ECwdInDataDir)
+		}
+		if err := GuardInvocation(fsys, cwd, DataDirRead, true); err != nil {
+			t.Errorf("read from %s: %v", cwd, err)
+		}
+	}
+
+	// A run's worktree is where agency is meant to be used
+	if err := GuardInvocation(fsys, worktree, DataDirWrite, true); err != nil {
+		t.Errorf("write from a worktree: %v", err)
+	}
+
+	// A symlink into the data dir is seen through
+	link := filepath.Join(t.TempDir(), "data")
+	if err := os.Symlink(runsDir, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := GuardInvocation(fsys, link, DataDirWrite, true); errors.GetCode(err) != errors.ECwdInDataDir {
+		t.Errorf("write via symlink: code = %q, want %q", errors.GetCode(err), errors.ECwdInDataDir)
+	}
+}
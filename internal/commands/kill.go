@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// KillOpts holds options for the kill command.
+type KillOpts struct {
+	// RunIDs are the run identifiers (exact or unique prefix), already expanded from stdin.
+	RunIDs []string
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+}
+
+// Kill kills the tmux session for one or more runs.
+// The workspace persists. Kill is best-effort and bypasses the repo lock.
+// A run whose session is already gone is reported and treated as success.
+func Kill(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts KillOpts, stdout, stderr io.Writer) error {
+	if len(opts.RunIDs) == 0 {
+		return errors.New(errors.EUsage, "run_id is required")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	return RunBulk(opts.RunIDs, stderr, func(runID string) error {
+		return killOne(ctx, cr, dirs.DataDir, runID, scope, crypter, stdout)
+	})
+}
+
+// killOne resolves a single run and kills its tmux session.
+func killOne(ctx context.Context, cr agencyexec.CommandRunner, dataDir, input string, scope runScope, crypter *crypt.Crypt, stdout io.Writer) error {
+	record, err := resolveRun(dataDir, input, scope, crypter)
+	if err != nil {
+		return err
+	}
+
+	sessionName := record.Meta.TmuxSessionName
+	if sessionName == "" {
+		sessionName = "agency_" + record.RunID
+	}
+
+	hasSession, err := cr.Run(ctx, "tmux", []string{"has-session", "-t", sessionName}, agencyexec.RunOpts{})
+	if err != nil {
+		return errors.Wrap(errors.ETmuxNotInstalled, "failed to check tmux session", err)
+	}
+	if hasSession.ExitCode != 0 {
+		fmt.Fprintf(stdout, "%s: no tmux session (%s)\n", record.RunID, sessionName)
+		return nil
+	}
+
+	result, err := cr.Run(ctx, "tmux", []string{"kill-session", "-t", sessionName}, agencyexec.RunOpts{})
+	if err != nil {
+		return errors.Wrap(errors.ETmuxNotInstalled, "failed to kill tmux session", err)
+	}
+	if result.ExitCode != 0 {
+		return errors.NewWithDetails(
+			errors.ETmuxFailed,
+			"tmux kill-session failed",
+			map[string]string{"session": sessionName, "stderr": result.Stderr},
+		)
+	}
+
+	fmt.Fprintf(stdout, "%s: killed tmux session %s\n", record.RunID, sessionName)
+	return nil
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestKill_BulkMixedResults(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	repoID := "abc123"
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	for _, runID := range []string{"20260110120000-a3f2", "20260110130000-b4c3"} {
+		createValidMetaForShow(t, dataDir, repoID, runID, filepath.Join(dataDir, "wt", runID), created)
+	}
+
+	cr := testkit.NewFakeRunner()
+	cr.TmuxSessions("agency_20260110120000-a3f2")
+
+	var stdout, stderr bytes.Buffer
+	opts := KillOpts{RunIDs: []string{"20260110120000", "20260110130000-b4c3", "missing"}}
+	err := Kill(context.Background(), cr, fs.NewRealFS(), dataDir, opts, &stdout, &stderr)
+
+	if errors.GetCode(err) != errors.EBulkFailed {
+		t.Fatalf("code = %q, want %q (err=%v)", errors.GetCode(err), errors.EBulkFailed, err)
+	}
+	if !strings.Contains(stdout.String(), "20260110120000-a3f2: killed tmux session") {
+		t.Errorf("stdout missing kill line: %q", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "20260110130000-b4c3: no tmux session") {
+		t.Errorf("stdout missing no-session line: %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "failed: missing: E_RUN_NOT_FOUND") {
+		t.Errorf("stderr missing failure line: %q", stderr.String())
+	}
+}
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/paths"
+)
+
+// legacyMigrationMarker is written into a legacy data dir the user chose not
+// to migrate (or that could not be), so the conductor binary asks only once.
+const legacyMigrationMarker = "agency_migration"
+
+// MigrateLegacyDataDir offers, once, to move a data dir left by the tool's
+// former name (conductor, see paths.ResolveLegacyDataDir) to the agency data
+// dir. It is run by the conductor alias binary before each command.
+//
+// confirm asks the user; nil (no terminal) only prints a notice, so the
+// question waits for an interactive run. On yes the legacy dir is renamed to
+// the data dir and a symlink is left at the old path, so worktree paths
+// recorded in meta.json and git keep resolving; if the rename fails (e.g.
+// across filesystems) the data dir becomes a symlink to the legacy dir
+// instead. On no, or when the data dir already holds data, a marker file in
+// the legacy dir records the outcome and the question is not asked again.
+//
+// Error codes:
+//   - E_LEGACY_MIGRATION: the confirmed move or symlink failed
+func MigrateLegacyDataDir(confirm func(prompt string) bool, stderr io.Writer) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	legacy := paths.ResolveLegacyDataDir(osEnv{}, homeDir)
+	dataDir := paths.ResolveDirs(osEnv{}, homeDir).DataDir
+	return migrateLegacyDataDir(legacy, dataDir, confirm, stderr)
+}
+
+// migrateLegacyDataDir implements MigrateLegacyDataDir for explicit dirs.
+func migrateLegacyDataDir(legacy, dataDir string, confirm func(prompt string) bool, stderr io.Writer) error {
+	if !isLegacyDataDir(legacy) || filepath.Clean(legacy) == filepath.Clean(dataDir) {
+		return nil
+	}
+
+	if entries, err := os.ReadDir(dataDir); err == nil && len(entries) > 0 {
+		fmt.Fprintf(stderr, "warning: found conductor data in %s, but %s already has agency data; move runs over by hand\n", legacy, dataDir)
+		writeLegacyMarker(legacy, "skipped: "+dataDir+" is not empty")
+		return nil
+	}
+
+	if confirm == nil {
+		fmt.Fprintf(stderr, "note: found conductor data in %s; run conductor in a terminal to migrate it to %s\n", legacy, dataDir)
+		return nil
+	}
+	if !confirm(fmt.Sprintf("migrate conductor data from %s to %s?", legacy, dataDir)) {
+		writeLegacyMarker(legacy, "declined")
+		fmt.Fprintf(stderr, "not migrating; remove %s to be asked again\n", filepath.Join(legacy, legacyMigrationMarker))
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dataDir), 0o700); err != nil {
+		return legacyMigrationError("failed to create data dir parent", err, legacy, dataDir)
+	}
+	// An empty data dir (e.g. created by an earlier agency command) is replaced
+	if err := os.Remove(dataDir); err != nil && !os.IsNotExist(err) {
+		return legacyMigrationError("failed to remove empty data dir", err, legacy, dataDir)
+	}
+
+	if err := os.Rename(legacy, dataDir); err != nil {
+		if err := os.Symlink(legacy, dataDir); err != nil {
+			return legacyMigrationError("failed to link data dir to legacy dir", err, legacy, dataDir)
+		}
+		fmt.Fprintf(stderr, "linked %s -> %s\n", dataDir, legacy)
+		return nil
+	}
+	if err := os.Symlink(dataDir, legacy); err != nil {
+		fmt.Fprintf(stderr, "warning: moved data but could not link %s -> %s: %v\n", legacy, dataDir, err)
+	}
+	fmt.Fprintf(stderr, "migrated %s -> %s\n", legacy, dataDir)
+	return nil
+}
+
+// isLegacyDataDir reports whether path is a real (not symlinked) directory
+// with agency's data layout that has not been dealt with yet.
+func isLegacyDataDir(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(path, legacyMigrationMarker)); err == nil {
+		return false
+	}
+	for _, name := range []string{"repos", "repo_index.json"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// writeLegacyMarker records outcome in the legacy dir (best-effort).
+func writeLegacyMarker(legacy, outcome string) {
+	_ = os.WriteFile(filepath.Join(legacy, legacyMigrationMarker), []byte(outcome+"\n"), 0o600)
+}
+
+func legacyMigrationError(msg string, err error, legacy, dataDir string) error {
+	return errors.WrapWithDetails(errors.ELegacyMigration, msg, err, map[string]string{
+		"legacy_dir": legacy,
+		"data_dir":   dataDir,
+	})
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newLegacyDataDir creates a conductor data dir with one repo under root.
+func newLegacyDataDir(t *testing.T, root string) string {
+	t.Helper()
+	legacy := filepath.Join(root, "conductor")
+	if err := os.MkdirAll(filepath.Join(legacy, "repos", "abc123"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "repo_index.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return legacy
+}
+
+func TestMigrateLegacyDataDir_Confirmed(t *testing.T) {
+	root := t.TempDir()
+	legacy := newLegacyDataDir(t, root)
+	dataDir := filepath.Join(root, "share", "agency")
+
+	asked := 0
+	var stderr bytes.Buffer
+	err := migrateLegacyDataDir(legacy, dataDir, func(string) bool { asked++; return true }, &stderr)
+	if err != nil {
+		t.Fatalf("migrateLegacyDataDir() error = %v", err)
+	}
+	if asked != 1 {
+		t.Errorf("asked %d times, want 1", asked)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "repos", "abc123")); err != nil {
+		t.Errorf("data not moved: %v", err)
+	}
+	if target, err := os.Readlink(legacy); err != nil || target != dataDir {
+		t.Errorf("legacy path = %q, %v; want a symlink to %s", target, err, dataDir)
+	}
+	if !strings.Contains(stderr.String(), "migrated") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+
+	// The legacy path is now a symlink: nothing left to migrate
+	err = migrateLegacyDataDir(legacy, dataDir, func(string) bool { asked++; return true }, &stderr)
+	if err != nil || asked != 1 {
+		t.Errorf("second run: err = %v, asked = %d; want no prompt", err, asked)
+	}
+}
+
+func TestMigrateLegacyDataDir_DeclinedOnce(t *testing.T) {
+	root := t.TempDir()
+	legacy := newLegacyDataDir(t, root)
+	dataDir := filepath.Join(root, "agency")
+
+	asked := 0
+	decline := func(string) bool { asked++; return false }
+	var stderr bytes.Buffer
+	for i := 0; i < 2; i++ {
+		if err := migrateLegacyDataDir(legacy, dataDir, decline, &stderr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if asked != 1 {
+		t.Errorf("asked %d times, want 1", asked)
+	}
+	if _, err := os.Stat(dataDir); !os.IsNotExist(err) {
+		t.Errorf("data dir should not exist after declining, stat err = %v", err)
+	}
+}
+
+func TestMigrateLegacyDataDir_NotInteractiveOrDataDirInUse(t *testing.T) {
+	root := t.TempDir()
+	legacy := newLegacyDataDir(t, root)
+	dataDir := filepath.Join(root, "agency")
+
+	var stderr bytes.Buffer
+	if err := migrateLegacyDataDir(legacy, dataDir, nil, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr.String(), "run conductor in a terminal") {
+		t.Errorf("stderr = %q, want a notice", stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(legacy, legacyMigrationMarker)); !os.IsNotExist(err) {
+		t.Error("a notice must not record an answer")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dataDir, "repos"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	err := migrateLegacyDataDir(legacy, dataDir, func(string) bool { t.Error("should not ask"); return true }, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr.String(), "already has agency data") {
+		t.Errorf("stderr = %q, want a warning", stderr.String())
+	}
+	if isLegacyDataDir(legacy) {
+		t.Error("legacy dir should be marked as handled")
+	}
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// LintOpts holds options for the lint command.
+type LintOpts struct {
+	// RunID is the run identifier (exact or unique prefix). Mutually exclusive with All.
+	RunID string
+
+	// All lints every run in the data dir, including broken ones.
+	All bool
+
+	// Fix applies fixes for trivially fixable problems (e.g. non-UTC timestamps).
+	Fix bool
+
+	// Repo restricts linting (and run_id resolution) to one repo
+	// (repo_id, repo_key, or path).
+	Repo string
+}
+
+// Lint validates meta.json contents beyond JSON parsing (see store.LintMeta)
+// and prints one line per problem. With Fix, fixable problems are repaired
+// under the repo lock. Returns E_META_INVALID if any error-severity problem
+// remains.
+func Lint(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts LintOpts, stdout, stderr io.Writer) error {
+	if opts.All == (opts.RunID != "") {
+		return errors.New(errors.EUsage, "exactly one of <run_id> or --all is required")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+
+	var records []store.RunRecord
+	if opts.All {
+		all, err := store.ScanAllRuns(dirs.DataDir, crypter)
+		if err != nil {
+			return errors.Wrap(errors.EInternal, "failed to scan runs", err)
+		}
+		for _, rec := range all {
+			if scope.RepoID == "" || rec.RepoID == scope.RepoID {
+				records = append(records, rec)
+			}
+		}
+	} else {
+		record, err := resolveRun(dirs.DataDir, opts.RunID, scope, crypter)
+		if err != nil {
+			return err
+		}
+		records = []store.RunRecord{*record}
+	}
+
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+	repoLock := lock.NewRepoLock(dirs.DataDir)
+
+	var nErrors, nWarnings, nFixed int
+	for _, rec := range records {
+		if rec.Broken {
+			nErrors++
+			fmt.Fprintf(stdout, "%s %s meta.json: unreadable or invalid json\n", rec.RunID, store.LintError)
+			continue
+		}
+
+		issues := store.LintMeta(rec.Meta, dirs.DataDir, rec.RepoID, rec.RunID)
+		fixed := false
+		if opts.Fix && hasFixable(issues) {
+			if err := applyLintFixes(st, repoLock, rec, issues); err != nil {
+				fmt.Fprintf(stderr, "warning: %s: fix failed: %s\n", rec.RunID, err.Error())
+			} else {
+				fixed = true
+			}
+		}
+
+		for _, issue := range issues {
+			suffix := ""
+			switch {
+			case issue.Fix != nil && fixed:
+				suffix = " (fixed)"
+				nFixed++
+			case issue.Fix != nil:
+				suffix = " (fixable with --fix)"
+			}
+			fmt.Fprintf(stdout, "%s %s %s: %s%s\n", rec.RunID, issue.Severity, issue.Field, issue.Message, suffix)
+			if issue.Fix != nil && fixed {
+				continue
+			}
+			if issue.Severity == store.LintError {
+				nErrors++
+			} else {
+				nWarnings++
+			}
+		}
+	}
+
+	if nErrors+nWarnings+nFixed == 0 {
+		fmt.Fprintf(stdout, "checked %d run(s): no problems\n", len(records))
+		return nil
+	}
+	fmt.Fprintf(stdout, "checked %d run(s): %d error(s), %d warning(s), %d fixed\n", len(records), nErrors, nWarnings, nFixed)
+	if nErrors > 0 {
+		return errors.NewWithDetails(errors.EMetaInvalid, fmt.Sprintf("%d meta.json error(s)", nErrors),
+			map[string]string{"errors": fmt.Sprintf("%d", nErrors)})
+	}
+	return nil
+}
+
+func hasFixable(issues []store.LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Fix != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLintFixes applies all fixable issues to the run's meta.json under the repo lock.
+func applyLintFixes(st *store.Store, repoLock lock.RepoLock, rec store.RunRecord, issues []store.LintIssue) error {
+	unlock, err := repoLock.Lock(rec.RepoID, "lint")
+	if err != nil {
+		return repoLockError(err, repoLock.DataDir, rec.RepoID)
+	}
+	defer func() { _ = unlock() }()
+
+	return st.UpdateMeta(rec.RepoID, rec.RunID, func(m *store.RunMeta) {
+		for _, issue := range issues {
+			if issue.Fix != nil {
+				issue.Fix(m)
+			}
+		}
+	})
+}
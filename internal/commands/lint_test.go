@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestLint(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	repoID := "abcd1234ef567890"
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	worktree := func(runID string) string {
+		return filepath.Join(dataDir, "repos", repoID, "worktrees", runID)
+	}
+
+	clean := testkit.NewRunMeta(repoID, "20260110120000-aaaa", worktree("20260110120000-aaaa"), created)
+	testkit.WriteRun(t, dataDir, clean)
+
+	fixable := testkit.NewRunMeta(repoID, "20260110120000-bbbb", worktree("20260110120000-bbbb"), created)
+	fixable.LastPushAt = "2026-01-10T13:00:00+01:00"
+	fixable.PRURL = "https://github.com/o/r/pull/12"
+	testkit.WriteRun(t, dataDir, fixable)
+
+	bad := testkit.NewRunMeta(repoID, "20260110120000-cccc", worktree("20260110120000-cccc"), created)
+	bad.SchemaVersion = "9.9"
+	testkit.WriteRun(t, dataDir, bad)
+
+	brokenDir := filepath.Join(dataDir, "repos", repoID, "runs", "20260110120000-dddd")
+	if err := os.MkdirAll(brokenDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(brokenDir, "meta.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	fsys := fs.NewRealFS()
+	lint := func(opts LintOpts) (string, error) {
+		var stdout bytes.Buffer
+		err := Lint(ctx, testkit.NewFakeRunner(), fsys, t.TempDir(), opts, &stdout, io.Discard)
+		return stdout.String(), err
+	}
+
+	out, err := lint(LintOpts{RunID: clean.RunID})
+	if err != nil || !strings.Contains(out, "checked 1 run(s): no problems") {
+		t.Fatalf("clean run: %v\n%s", err, out)
+	}
+
+	out, err = lint(LintOpts{All: true, Fix: true})
+	if errors.GetCode(err) != errors.EMetaInvalid {
+		t.Fatalf("expected E_META_INVALID, got %v", err)
+	}
+	for _, want := range []string{
+		"20260110120000-bbbb warn last_push_at: not in UTC: 2026-01-10T13:00:00+01:00 (fixed)",
+		"20260110120000-bbbb warn pr_number: missing; pr_url points at #12 (fixed)",
+		"20260110120000-cccc error schema_version: unknown schema_version \"9.9\"",
+		"20260110120000-dddd error meta.json: unreadable or invalid json",
+		"checked 4 run(s): 2 error(s), 0 warning(s), 2 fixed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	st := store.NewStore(fsys, dataDir, time.Now)
+	got, err := st.ReadMeta(repoID, fixable.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LastPushAt != "2026-01-10T12:00:00Z" || got.PRNumber != 12 {
+		t.Errorf("fixes not written: last_push_at=%q pr_number=%d", got.LastPushAt, got.PRNumber)
+	}
+
+	if _, err := lint(LintOpts{}); errors.GetCode(err) != errors.EUsage {
+		t.Errorf("no run_id and no --all: got %v, want E_USAGE", err)
+	}
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// LogsOpts holds options for the logs command.
+type LogsOpts struct {
+	// RunID is the run identifier (exact or unique prefix).
+	RunID string
+
+	// Name selects the log to print ("setup", "setup.log", "setup.log.1",
+	// "hook_post_run_setup", ...). Empty lists the run's logs instead.
+	Name string
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+}
+
+// Logs lists a run's script logs or prints one, decompressing logs that
+// gc compressed. This is a read-only command: no state files are mutated.
+func Logs(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts LogsOpts, stdout, stderr io.Writer) error {
+	if opts.RunID == "" {
+		return errors.New(errors.EUsage, "run_id is required")
+	}
+	if strings.ContainsAny(opts.Name, `/\`) {
+		return errors.New(errors.EUsage, "log name must not contain a path separator: "+opts.Name)
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+	record, err := resolveRun(dirs.DataDir, opts.RunID, scope, crypter)
+	if err != nil {
+		return err
+	}
+	logsDir := filepath.Join(record.RunDir, "logs")
+
+	if opts.Name == "" {
+		logs, err := store.ListLogs(logsDir)
+		if err != nil {
+			return errors.Wrap(errors.EInternal, "failed to list logs", err)
+		}
+		for _, l := range logs {
+			line := fmt.Sprintf("%s: %d bytes", l.Name, l.Bytes)
+			if l.Compressed {
+				line += " (compressed)"
+			}
+			if l.Sealed {
+				line += " (encrypted)"
+			}
+			fmt.Fprintln(stdout, line)
+		}
+		return nil
+	}
+
+	name := opts.Name
+	if !strings.Contains(name, ".log") {
+		name += ".log"
+	}
+	r, err := store.OpenLog(filepath.Join(logsDir, name), crypter)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.WithHints(errors.NewWithDetails(errors.ELogNotFound,
+				"run "+record.RunID+" has no log "+name,
+				map[string]string{"run_id": record.RunID, "logs_dir": logsDir}),
+				"list the run's logs: agency logs "+record.RunID)
+		}
+		return errors.Wrap(errors.EInternal, "failed to open log "+name, err)
+	}
+	defer r.Close()
+
+	if _, err := io.Copy(stdout, r); err != nil {
+		return errors.Wrap(errors.EInternal, "failed to read log "+name, err)
+	}
+	return nil
+}
@@ -2,18 +2,25 @@ package commands
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/errors"
 	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/git"
 	"github.com/NielsdaWheelz/agency/internal/identity"
-	"github.com/NielsdaWheelz/agency/internal/paths"
+	"github.com/NielsdaWheelz/agency/internal/ids"
+	"github.com/NielsdaWheelz/agency/internal/lock"
 	"github.com/NielsdaWheelz/agency/internal/render"
 	"github.com/NielsdaWheelz/agency/internal/status"
 	"github.com/NielsdaWheelz/agency/internal/store"
@@ -21,28 +28,117 @@ import (
 
 // LSOpts holds options for the ls command.
 type LSOpts struct {
-	// All includes archived runs in the output.
+	// All includes archived runs in the output (alias for Archived=true).
 	All bool
 
+	// Archived includes archived runs; nil uses the user config default (ls.archived).
+	Archived *bool
+
+	// Broken includes broken runs; nil uses the user config default (ls.broken).
+	Broken *bool
+
 	// AllRepos lists runs across all repos (ignores current repo scope).
 	AllRepos bool
 
+	// Repo lists only runs in the repos it matches, from anywhere (see
+	// matchLSRepos); it overrides the current repo scope.
+	Repo string
+
 	// JSON outputs machine-readable JSON.
 	JSON bool
+
+	// Format is a go-template executed per run against the ls --json data (RunSummary).
+	Format string
+
+	// Labels are label selectors ("key=value" or "key"); all must match.
+	Labels []string
+
+	// Group only lists runs in this group (meta.group).
+	Group string
+
+	// Task only lists the runs of one agency run --runners task (meta.task_id).
+	Task string
+
+	// Mine only lists runs created by the current user (meta.created_by).
+	Mine bool
+
+	// Plain writes one "key: value" block per run instead of a table;
+	// the user config "plain" setting also enables it.
+	Plain bool
+
+	// Commits computes how many commits each run's branch is ahead of and
+	// behind its parent branch (costs git calls; cached by head SHAs).
+	Commits bool
+
+	// Offset skips this many runs (after sorting) before output.
+	Offset int
+
+	// Limit caps the number of runs output; 0 means unlimited.
+	Limit int
+
+	// Stream writes newline-delimited JSON, one run per line, instead of
+	// the --json envelope.
+	Stream bool
 }
 
 // LS executes the agency ls command.
 // Lists runs with sane defaults and stable JSON output.
 // This is a read-only command: no state files are mutated.
 func LS(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts LSOpts, stdout, stderr io.Writer) error {
-	// Resolve data directory
-	homeDir, err := os.UserHomeDir()
+	// Parse --format up front so template errors fail fast
+	var formatTmpl *template.Template
+	if opts.Format != "" {
+		if opts.JSON {
+			return errors.New(errors.EUsage, "--format cannot be combined with --json")
+		}
+		if opts.Stream {
+			return errors.New(errors.EUsage, "--format cannot be combined with --stream")
+		}
+		tmpl, err := render.ParseFormat(opts.Format)
+		if err != nil {
+			return err
+		}
+		formatTmpl = tmpl
+	}
+
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return errors.New(errors.EUsage, "--offset and --limit must not be negative")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
 	if err != nil {
 		return err
 	}
-	dirs := paths.ResolveDirs(osEnv{}, homeDir)
+	crypter := LoadCrypt(cr, fsys)
 	dataDir := dirs.DataDir
 
+	// Resolve visibility filter (flags override user config defaults)
+	userCfg, err := config.LoadUserConfig(fsys, dirs.ConfigDir)
+	if err != nil {
+		return err
+	}
+	filter := resolveLSFilter(opts, userCfg.LS)
+	for _, raw := range opts.Labels {
+		sel, err := core.ParseLabelSelector(raw)
+		if err != nil {
+			return errors.Wrap(errors.EUsage, "invalid --label", err)
+		}
+		filter.Labels = append(filter.Labels, sel)
+	}
+	if opts.Group != "" {
+		if err := core.ValidateGroupName(opts.Group); err != nil {
+			return errors.Wrap(errors.EUsage, "invalid --group", err)
+		}
+		filter.Group = opts.Group
+	}
+	filter.Task = opts.Task
+	if opts.Mine {
+		filter.CreatedBy = identity.CurrentUser()
+		if filter.CreatedBy == "" {
+			return errors.New(errors.EUsage, "--mine: cannot determine the current user; set AGENCY_USER")
+		}
+	}
+
 	// Determine scope: in-repo vs not-in-repo
 	var repoID string
 	var inRepo bool
@@ -60,51 +156,273 @@ func LS(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string
 	useAllRepos := opts.AllRepos || !inRepo
 
 	// Scan runs based on scope
+	// (unreadable directories are skipped and reported, never fatal)
 	var records []store.RunRecord
-	if useAllRepos {
-		records, err = store.ScanAllRuns(dataDir)
-	} else {
-		records, err = store.ScanRunsForRepo(dataDir, repoID)
-	}
-	if err != nil {
-		return err
+	var warnings []store.ScanWarning
+	switch {
+	case opts.Repo != "":
+		// Only the matched repos are scanned, so other runs cost nothing
+		repoIDs, err := matchLSRepos(ctx, cr, fsys, dataDir, opts.Repo)
+		if err != nil {
+			return err
+		}
+		for _, id := range repoIDs {
+			recs, warns := store.ScanRunsForRepoWithWarnings(dataDir, id, crypter)
+			records = append(records, recs...)
+			warnings = append(warnings, warns...)
+		}
+	case useAllRepos:
+		records, warnings = store.ScanAllRunsWithWarnings(dataDir, crypter)
+	default:
+		records, warnings = store.ScanRunsForRepoWithWarnings(dataDir, repoID, crypter)
 	}
 
-	// Get tmux session set (single call)
-	tmuxSessions := getTmuxSessions(ctx, cr)
+	// Tmux session set: queried at most once, and only if a run needs it
+	tmuxSessions := newTmuxSessionSet(ctx, cr)
+	policies := newReviewPolicySet(fsys, dataDir)
+	commits := newCommitCountSet(ctx, cr, fsys, dirs.CacheDir)
+	locks := newRepoLockSet(dataDir)
 
 	// Convert records to summaries with snapshot data
 	summaries := make([]render.RunSummary, 0, len(records))
 	for _, rec := range records {
-		summary := recordToSummary(rec, tmuxSessions, fsys)
+		if !filter.includeRecord(rec) {
+			continue
+		}
 
-		// Filter archived unless --all
-		if summary.Archived && !opts.All {
+		summary := recordToSummary(ctx, cr, rec, tmuxSessions, policies, commits, fsys)
+		if !filter.includeSummary(summary) {
 			continue
 		}
+		if opts.Commits {
+			commits.Fill(&summary, rec)
+		}
+		summary.Lock = locks.Get(rec.RepoID)
 
 		summaries = append(summaries, summary)
 	}
 
+	commits.Save()
+
 	// Sort: created_at descending (newest first), broken runs last
 	sortSummaries(summaries)
+	page := render.LSPage{Total: len(summaries), Offset: opts.Offset, Limit: opts.Limit}
+	summaries = paginate(summaries, opts.Offset, opts.Limit)
 
 	// Output
-	if opts.JSON {
-		return render.WriteLSJSON(stdout, summaries)
+	if opts.JSON && !opts.Stream {
+		return render.WriteLSJSON(stdout, summaries, page, warnings)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: skipped %s: %s\n", w.Path, w.Message)
+	}
+	if opts.Stream {
+		return render.WriteLSStream(stdout, summaries)
+	}
+	if formatTmpl != nil {
+		return render.WriteLSFormat(stdout, formatTmpl, summaries)
 	}
 
 	// Human output
-	now := time.Now()
-	rows := render.FormatHumanRows(summaries, now)
-	return render.WriteLSHuman(stdout, rows)
+	now := clock.Now()
+	plain := opts.Plain || userCfg.Plain
+	vocab := statusVocabulary(userCfg, plain, stdout)
+	if plain {
+		return render.WriteLSPlain(stdout, summaries, now, vocab)
+	}
+	rows := render.FormatHumanRows(summaries, now, vocab)
+	if len(rows) > 0 {
+		if !useAllRepos || opts.Repo != "" {
+			// Ids resolve across all repos, so prefixes must be unique there
+			records, _ = store.ScanAllRunsWithWarnings(dataDir, crypter)
+		}
+		short := shortRunIDs(records)
+		for i := range rows {
+			if id, ok := short[rows[i].RunID]; ok {
+				rows[i].RunID = id
+			}
+		}
+	}
+	return render.WriteLSHumanFit(stdout, rows, terminalWidth(stdout))
+}
+
+// matchLSRepos returns the repo_ids (sorted) of the repos in the data dir
+// that an ls --repo value matches:
+//   - a path (absolute, starting with ./ ../ or ~/, or an existing dir)
+//     matches the repo containing it, and repos last seen at that path
+//   - anything else matches a repo_id or repo_key exactly, or a substring of
+//     the origin URL (case-insensitive)
+//
+// Returns E_REPO_NOT_FOUND if no repo matches.
+func matchLSRepos(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, dataDir, value string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dataDir, "repos"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(errors.EInternal, "failed to read repos dir", err)
+	}
+
+	isPath := filepath.IsAbs(value) || value == "." || value == ".." ||
+		strings.HasPrefix(value, "./") || strings.HasPrefix(value, "../") || strings.HasPrefix(value, "~/") ||
+		dirExists(value)
+	var absPath, pathRepoID string
+	var indexPaths map[string]bool
+	if isPath {
+		absPath = value
+		if strings.HasPrefix(value, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				absPath = filepath.Join(home, value[2:])
+			}
+		}
+		if abs, err := filepath.Abs(absPath); err == nil {
+			absPath = abs
+		}
+		if dirExists(absPath) {
+			pathRepoID = repoIDForDir(ctx, cr, absPath)
+		}
+		// Checkouts that no longer exist still match by their recorded path
+		indexPaths = map[string]bool{}
+		if idx, _ := store.LoadRepoIndexForScan(dataDir); idx != nil {
+			for _, entry := range idx.Repos {
+				for _, p := range entry.Paths {
+					if filepath.Clean(p) == absPath {
+						indexPaths[entry.RepoID] = true
+					}
+				}
+			}
+		}
+	}
+
+	st := store.NewStore(fsys, dataDir, clock.Now)
+	var matched []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		id := e.Name()
+		rec, _, _ := st.LoadRepoRecord(id)
+		var ok bool
+		if isPath {
+			ok = id == pathRepoID || indexPaths[id] ||
+				(rec.RepoRootLastSeen != "" && filepath.Clean(rec.RepoRootLastSeen) == absPath)
+		} else {
+			ok = id == value || (rec.RepoKey != "" && rec.RepoKey == value) ||
+				(rec.OriginURL != "" && strings.Contains(strings.ToLower(rec.OriginURL), strings.ToLower(value)))
+		}
+		if ok {
+			matched = append(matched, id)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, errors.WithHints(errors.NewWithDetails(errors.ERepoNotFound,
+			"no agency repo matches --repo "+value+" (expected a repo_id, repo_key, origin URL substring, or path)",
+			map[string]string{"repo": value}),
+			"agency ls --all-repos --json lists each run's repo_key and origin_url")
+	}
+	return matched, nil
+}
+
+// shortRunIDs maps each run_id in records to its display id: the shortest
+// unique prefix that run id resolution accepts (see ids.ShortIDs).
+func shortRunIDs(records []store.RunRecord) map[string]string {
+	runIDs := make([]string, len(records))
+	for i, rec := range records {
+		runIDs[i] = rec.RunID
+	}
+	return ids.ShortIDs(runIDs)
+}
+
+// paginate returns the page of summaries after skipping offset runs and
+// keeping at most limit (0 = unlimited).
+func paginate(summaries []render.RunSummary, offset, limit int) []render.RunSummary {
+	if offset >= len(summaries) {
+		return summaries[:0]
+	}
+	summaries = summaries[offset:]
+	if limit > 0 && limit < len(summaries) {
+		summaries = summaries[:limit]
+	}
+	return summaries
+}
+
+// lsFilter controls which runs are visible in ls output.
+type lsFilter struct {
+	IncludeArchived bool
+	IncludeBroken   bool
+
+	// Labels must all match meta.labels; broken runs never match a selector.
+	Labels []core.LabelSelector
+
+	// Group must equal meta.group; broken runs never match.
+	Group string
+
+	// Task must equal meta.task_id; broken runs never match.
+	Task string
+
+	// CreatedBy must equal meta.created_by; broken runs and runs without a
+	// recorded creator never match.
+	CreatedBy string
+}
+
+// resolveLSFilter merges explicit flags over user config defaults.
+// --all is kept as an alias for --archived.
+func resolveLSFilter(opts LSOpts, defaults config.UserLSConfig) lsFilter {
+	filter := lsFilter{
+		IncludeArchived: defaults.Archived,
+		IncludeBroken:   defaults.Broken,
+	}
+	if opts.Archived != nil {
+		filter.IncludeArchived = *opts.Archived
+	}
+	if opts.All {
+		filter.IncludeArchived = true
+	}
+	if opts.Broken != nil {
+		filter.IncludeBroken = *opts.Broken
+	}
+	return filter
+}
+
+// includeRecord applies filters that only need the scanned record.
+// Checked before summary conversion to skip tmux/git work for hidden runs.
+func (f lsFilter) includeRecord(rec store.RunRecord) bool {
+	if f.CreatedBy != "" && (rec.Broken || rec.Meta == nil || rec.Meta.CreatedBy != f.CreatedBy) {
+		return false
+	}
+	if f.Group != "" && (rec.Broken || rec.Meta == nil || rec.Meta.Group != f.Group) {
+		return false
+	}
+	if f.Task != "" && (rec.Broken || rec.Meta == nil || rec.Meta.TaskID != f.Task) {
+		return false
+	}
+	if len(f.Labels) > 0 {
+		if rec.Broken || rec.Meta == nil {
+			return false
+		}
+		for _, sel := range f.Labels {
+			if !sel.Matches(rec.Meta.Labels) {
+				return false
+			}
+		}
+	}
+	return !rec.Broken || f.IncludeBroken
+}
+
+// includeSummary applies filters that need derived snapshot data.
+// Broken runs are governed solely by IncludeBroken.
+func (f lsFilter) includeSummary(summary render.RunSummary) bool {
+	if summary.Broken {
+		return f.IncludeBroken
+	}
+	return !summary.Archived || f.IncludeArchived
 }
 
 // recordToSummary converts a RunRecord to a RunSummary with snapshot data.
-func recordToSummary(rec store.RunRecord, tmuxSessions map[string]bool, fsys fs.FS) render.RunSummary {
+// Archived runs never consult tmux; report files and review policies are only
+// read for present worktrees.
+func recordToSummary(ctx context.Context, cr agencyexec.CommandRunner, rec store.RunRecord, tmuxSessions *tmuxSessionSet, policies *reviewPolicySet, commits *commitCountSet, fsys fs.FS) render.RunSummary {
 	summary := render.RunSummary{
 		RunID:  rec.RunID,
 		RepoID: rec.RepoID,
+		Labels: map[string]string{},
 		Broken: rec.Broken,
 	}
 
@@ -119,9 +437,9 @@ func recordToSummary(rec store.RunRecord, tmuxSessions map[string]bool, fsys fs.
 		summary.Title = render.TitleBroken
 		summary.DerivedStatus = status.StatusBroken
 
-		// Check tmux even for broken runs
+		// Check tmux even for broken runs (a live session aids recovery)
 		sessionName := "agency_" + rec.RunID
-		summary.TmuxActive = tmuxSessions[sessionName]
+		summary.TmuxActive = tmuxSessions.Active(sessionName)
 
 		// Worktree can't be checked without meta; assume absent
 		summary.WorktreePresent = false
@@ -134,6 +452,12 @@ func recordToSummary(rec store.RunRecord, tmuxSessions map[string]bool, fsys fs.
 	meta := rec.Meta
 	summary.Title = meta.Title
 	summary.Runner = &meta.Runner
+	if meta.CreatedBy != "" {
+		summary.CreatedBy = &meta.CreatedBy
+	}
+	for k, v := range meta.Labels {
+		summary.Labels[k] = v
+	}
 
 	// Parse created_at
 	if t, err := time.Parse(time.RFC3339, meta.CreatedAt); err == nil {
@@ -155,42 +479,219 @@ func recordToSummary(rec store.RunRecord, tmuxSessions map[string]bool, fsys fs.
 		summary.PRURL = &meta.PRURL
 	}
 
-	// Check tmux session existence
-	sessionName := meta.TmuxSessionName
-	if sessionName == "" {
-		// Fallback to constructed name if not set in meta
-		sessionName = "agency_" + rec.RunID
-	}
-	summary.TmuxActive = tmuxSessions[sessionName]
-
 	// Check worktree presence
 	summary.WorktreePresent = dirExists(meta.WorktreePath)
 	summary.Archived = !summary.WorktreePresent
 
-	// Get report bytes (0 if missing or worktree absent)
-	reportBytes := 0
+	// Check tmux session existence (archived runs are treated as inactive)
 	if summary.WorktreePresent {
-		reportPath := filepath.Join(meta.WorktreePath, ".agency", "report.md")
-		if info, err := os.Stat(reportPath); err == nil {
-			reportBytes = int(info.Size())
+		sessionName := meta.TmuxSessionName
+		if sessionName == "" {
+			// Fallback to constructed name if not set in meta
+			sessionName = "agency_" + rec.RunID
 		}
+		summary.TmuxActive = tmuxSessions.Active(sessionName)
 	}
 
+	// Get report info (zero values if missing or worktree absent)
+	report := readReportSnapshot(ctx, cr, meta, summary.WorktreePresent)
+	summary.ReportStale = report.Stale
+
 	// Derive status
 	snapshot := status.Snapshot{
 		TmuxActive:      summary.TmuxActive,
 		WorktreePresent: summary.WorktreePresent,
-		ReportBytes:     reportBytes,
+		ReportBytes:     report.Bytes,
+		ReportStale:     report.Stale,
+		ReportReadyFlag: report.ReadyFlag,
+	}
+	if summary.WorktreePresent {
+		snapshot.Policy = policies.Get(rec)
+		snapshot.DeadlineExceeded = meta.DeadlineExceeded(clock.Now())
+		snapshot.NoCommits = noCommits(commits, rec, report.Bytes, snapshot.Policy)
 	}
 	derived := status.Derive(meta, snapshot)
 	summary.DerivedStatus = derived.DerivedStatus
+	summary.NoChanges = derived.NoChanges
+	if derived.AttentionReason != "" {
+		summary.AttentionReason = &derived.AttentionReason
+	}
 
 	return summary
 }
 
+// reportSnapshot holds the report facts gathered for a single run.
+type reportSnapshot struct {
+	// Path is the absolute path to .agency/report.md.
+	Path string
+
+	// Exists is true iff the report is a regular file.
+	Exists bool
+
+	// Bytes is the report size (0 if missing).
+	Bytes int
+
+	// Commit is the commit covered by the report ("" if not recorded).
+	Commit string
+
+	// Stale is true iff the worktree HEAD has commits newer than Commit.
+	Stale bool
+
+	// ReadyFlag is true iff the report front matter sets ready: true.
+	ReadyFlag bool
+}
+
+// readReportSnapshot reads .agency/report.md and computes freshness.
+// Freshness requires a recorded commit (report footer or meta.report_commit);
+// git failures (e.g. unknown sha) are treated as "not stale".
+func readReportSnapshot(ctx context.Context, cr agencyexec.CommandRunner, meta *store.RunMeta, worktreePresent bool) reportSnapshot {
+	snap := reportSnapshot{
+		Path: filepath.Join(meta.WorktreePath, ".agency", "report.md"),
+	}
+	if !worktreePresent {
+		return snap
+	}
+
+	info, err := os.Stat(snap.Path)
+	if err != nil || !info.Mode().IsRegular() {
+		return snap
+	}
+	snap.Exists = true
+	snap.Bytes = int(info.Size())
+
+	content, _ := os.ReadFile(snap.Path)
+	snap.ReadyFlag = status.ParseReportReady(string(content))
+	snap.Commit = status.ReportCommit(meta.ReportCommit, string(content))
+	if snap.Commit == "" {
+		return snap
+	}
+
+	n, err := git.CountCommitsSince(ctx, cr, meta.WorktreePath, snap.Commit, "HEAD")
+	snap.Stale = err == nil && n > 0
+	return snap
+}
+
+// reviewPolicySet lazily loads each repo's review policy from agency.json at
+// the repo root recorded in repo_index.json. Repos whose root or agency.json
+// cannot be read use the default policy. A nil set always returns the default.
+type reviewPolicySet struct {
+	fsys      fs.FS
+	dataDir   string
+	idx       *store.RepoIndex
+	idxLoaded bool
+	byRepo    map[string]status.ReviewPolicy
+}
+
+func newReviewPolicySet(fsys fs.FS, dataDir string) *reviewPolicySet {
+	return &reviewPolicySet{fsys: fsys, dataDir: dataDir, byRepo: make(map[string]status.ReviewPolicy)}
+}
+
+// Get returns the review policy for the record's repo.
+func (s *reviewPolicySet) Get(rec store.RunRecord) status.ReviewPolicy {
+	if s == nil || rec.Repo == nil {
+		return status.ReviewPolicy{}
+	}
+	if policy, ok := s.byRepo[rec.RepoID]; ok {
+		return policy
+	}
+
+	var policy status.ReviewPolicy
+	if !s.idxLoaded {
+		s.idx, _ = store.LoadRepoIndexForScan(s.dataDir)
+		s.idxLoaded = true
+	}
+	if root := store.PickRepoRoot(rec.Repo.RepoKey, nil, s.idx); root != nil {
+		if cfg, err := config.LoadAgencyConfig(s.fsys, *root); err == nil {
+			policy = reviewPolicy(cfg.Review)
+		}
+	}
+	s.byRepo[rec.RepoID] = policy
+	return policy
+}
+
+// reviewPolicy converts agency.json review settings to a status policy.
+func reviewPolicy(r config.Review) status.ReviewPolicy {
+	return status.ReviewPolicy{
+		ReportMinBytes:   r.ReportMinBytes,
+		RequireReadyFlag: r.Readiness == config.ReviewReadinessFrontMatter,
+	}
+}
+
+// repoLockSet reads each repo's lock file at most once per invocation.
+type repoLockSet struct {
+	repoLock lock.RepoLock
+	byRepo   map[string]*render.LockJSON
+}
+
+func newRepoLockSet(dataDir string) *repoLockSet {
+	repoLock := lock.NewRepoLock(dataDir)
+	repoLock.Now = clock.Now
+	return &repoLockSet{repoLock: repoLock, byRepo: make(map[string]*render.LockJSON)}
+}
+
+// Get returns the repo's lock, or nil if it is not locked (or the lock file
+// cannot be inspected).
+func (s *repoLockSet) Get(repoID string) *render.LockJSON {
+	if l, ok := s.byRepo[repoID]; ok {
+		return l
+	}
+	var l *render.LockJSON
+	if state, err := s.repoLock.State(repoID); err == nil && state != nil {
+		l = lockJSON(state, s.repoLock.Now())
+	}
+	s.byRepo[repoID] = l
+	return l
+}
+
+// lockJSON converts a lock file state to its ls/show representation.
+func lockJSON(state *lock.LockState, now time.Time) *render.LockJSON {
+	l := &render.LockJSON{
+		CreatedAt:  state.Since.UTC(),
+		AgeSeconds: int64(now.Sub(state.Since) / time.Second),
+		Stale:      state.Stale,
+		Path:       state.Path,
+	}
+	if state.Info != nil {
+		pid := state.Info.PID
+		l.PID = &pid
+		if state.Info.Cmd != "" {
+			cmd := state.Info.Cmd
+			l.Cmd = &cmd
+		}
+		if state.Info.User != "" {
+			user := state.Info.User
+			l.User = &user
+		}
+		if state.Info.Host != "" {
+			host := state.Info.Host
+			l.Host = &host
+		}
+	}
+	return l
+}
+
+// tmuxSessionSet is a lazily loaded set of active tmux session names.
+// tmux is queried on the first Active call and never again, so invocations
+// where every run is archived make no tmux call at all.
+type tmuxSessionSet struct {
+	load     func() map[string]bool
+	once     sync.Once
+	sessions map[string]bool
+}
+
+// newTmuxSessionSet returns a set backed by a single `tmux list-sessions`.
+func newTmuxSessionSet(ctx context.Context, cr agencyexec.CommandRunner) *tmuxSessionSet {
+	return &tmuxSessionSet{load: func() map[string]bool { return getTmuxSessions(ctx, cr) }}
+}
+
+// Active reports whether the named session exists, loading the set on first use.
+func (t *tmuxSessionSet) Active(name string) bool {
+	t.once.Do(func() { t.sessions = t.load() })
+	return t.sessions[name]
+}
+
 // getTmuxSessions returns a set of active tmux session names.
 // Returns empty map if tmux is not available or server not running.
-// This is a single call per ls invocation.
 func getTmuxSessions(ctx context.Context, cr agencyexec.CommandRunner) map[string]bool {
 	sessions := make(map[string]bool)
 
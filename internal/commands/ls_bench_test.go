@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// seedRuns writes n runs across 5 repos. Every presentEvery-th run gets a
+// worktree with a report (presentEvery <= 0 means all runs are archived).
+func seedRuns(tb testing.TB, dataDir string, n, presentEvery int) {
+	tb.Helper()
+	base := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		repoID := fmt.Sprintf("repo%d", i%5)
+		runID := fmt.Sprintf("20260110%06d-%04x", i, i)
+		worktree := filepath.Join(dataDir, "repos", repoID, "worktrees", runID)
+		meta := testkit.NewRunMeta(repoID, runID, worktree, base.Add(time.Duration(i)*time.Minute))
+		testkit.WriteRun(tb, dataDir, meta)
+
+		if presentEvery > 0 && i%presentEvery == 0 {
+			if err := os.MkdirAll(filepath.Join(worktree, ".agency"), 0o755); err != nil {
+				tb.Fatal(err)
+			}
+			report := []byte("# report\n\nsummary of the change, long enough to count as non-empty.\n")
+			if err := os.WriteFile(filepath.Join(worktree, ".agency", "report.md"), report, 0o644); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+}
+
+// newLSRunner returns a runner where cwd is not a repo (all-repos mode).
+func newLSRunner(sessions ...string) *testkit.FakeRunner {
+	cr := testkit.NewFakeRunner()
+	cr.Fallback = &testkit.Response{Result: exec.CmdResult{ExitCode: 128}}
+	cr.TmuxSessions(sessions...)
+	return cr
+}
+
+func TestLS_TmuxNotQueriedWhenAllArchived(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	seedRuns(t, dataDir, 20, 0)
+
+	cr := newLSRunner()
+	opts := LSOpts{JSON: true, All: true}
+	if err := LS(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, io.Discard, io.Discard); err != nil {
+		t.Fatalf("LS: %v", err)
+	}
+	if calls := cr.CallsTo("tmux"); len(calls) != 0 {
+		t.Errorf("tmux called %d times for all-archived runs, want 0: %v", len(calls), calls)
+	}
+}
+
+func TestLS_TmuxQueriedOnce(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	seedRuns(t, dataDir, 20, 2)
+
+	cr := newLSRunner("agency_20260110000000-0000")
+	var stdout bytes.Buffer
+	if err := LS(context.Background(), cr, fs.NewRealFS(), t.TempDir(), LSOpts{JSON: true}, &stdout, io.Discard); err != nil {
+		t.Fatalf("LS: %v", err)
+	}
+	if calls := cr.CallsTo("tmux"); len(calls) != 1 {
+		t.Errorf("tmux called %d times, want 1: %v", len(calls), calls)
+	}
+
+	var env render.LSJSONEnvelope
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Data) != 10 {
+		t.Fatalf("len(Data) = %d, want 10 non-archived runs", len(env.Data))
+	}
+	active := 0
+	for _, s := range env.Data {
+		if s.TmuxActive {
+			active++
+		}
+	}
+	if active != 1 {
+		t.Errorf("active runs = %d, want 1", active)
+	}
+}
+
+// BenchmarkLS_JSON_500Runs measures `agency ls --json --all-repos --archived`
+// over 500 runs (half with worktrees). Target: well under 100ms/op.
+func BenchmarkLS_JSON_500Runs(b *testing.B) {
+	dataDir := b.TempDir()
+	b.Setenv("AGENCY_DATA_DIR", dataDir)
+	b.Setenv("AGENCY_CONFIG_DIR", b.TempDir())
+	seedRuns(b, dataDir, 500, 2)
+
+	cr := newLSRunner("agency_20260110000000-0000")
+	fsys := fs.NewRealFS()
+	cwd := b.TempDir()
+	opts := LSOpts{JSON: true, AllRepos: true, All: true}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := LS(context.Background(), cr, fsys, cwd, opts, io.Discard, io.Discard); err != nil {
+			b.Fatalf("LS: %v", err)
+		}
+	}
+}
+
+// BenchmarkShow_Path measures `agency show --path`, which skips tmux and report stats.
+func BenchmarkShow_Path(b *testing.B) {
+	dataDir := b.TempDir()
+	b.Setenv("AGENCY_DATA_DIR", dataDir)
+	seedRuns(b, dataDir, 500, 2)
+
+	cr := newLSRunner()
+	fsys := fs.NewRealFS()
+	cwd := b.TempDir()
+	opts := ShowOpts{RunID: "20260110000000-0000", Path: true}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Show(context.Background(), cr, fsys, cwd, opts, io.Discard, io.Discard); err != nil {
+			b.Fatalf("Show: %v", err)
+		}
+	}
+	if calls := cr.CallsTo("tmux"); len(calls) != 0 {
+		b.Errorf("show --path called tmux %d times, want 0", len(calls))
+	}
+}
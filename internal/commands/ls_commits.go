@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/git"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/status"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// commitCountsCacheFile is the ls --commits cache under the agency cache dir.
+const commitCountsCacheFile = "ls_commits.json"
+
+// maxCachedCommitCounts bounds the cache; when exceeded, only the entries
+// used by the current invocation are kept.
+const maxCachedCommitCounts = 2000
+
+// commitCounts is one cached ahead/behind result.
+type commitCounts struct {
+	Ahead  int `json:"ahead"`
+	Behind int `json:"behind"`
+}
+
+// commitCountsCache is the on-disk form of the ls --commits cache. Keys are
+// "<parent sha>...<branch sha>", so entries never go stale: a new commit on
+// either side changes the key.
+type commitCountsCache struct {
+	SchemaVersion string                  `json:"schema_version"`
+	Counts        map[string]commitCounts `json:"counts"`
+}
+
+// commitCountSet computes how far each run's branch is ahead of and behind
+// its parent branch (ls --commits and the "no changes" indicator). Branch
+// heads are read once per repo (git for-each-ref), and counts are cached by
+// head SHAs so unchanged runs cost no further git calls. A nil set reports
+// no counts.
+type commitCountSet struct {
+	ctx       context.Context
+	cr        agencyexec.CommandRunner
+	fsys      fs.FS
+	cachePath string
+
+	heads  map[string]map[string]string // repo_id -> branch -> sha (nil if unreadable)
+	cached map[string]commitCounts
+	used   map[string]commitCounts
+	dirty  bool
+}
+
+// newCommitCountSet returns a set caching counts under cacheDir
+// ("" disables the on-disk cache).
+func newCommitCountSet(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cacheDir string) *commitCountSet {
+	s := &commitCountSet{
+		ctx:    ctx,
+		cr:     cr,
+		fsys:   fsys,
+		heads:  make(map[string]map[string]string),
+		cached: make(map[string]commitCounts),
+		used:   make(map[string]commitCounts),
+	}
+	if cacheDir != "" {
+		s.cachePath = filepath.Join(cacheDir, commitCountsCacheFile)
+		if data, err := fsys.ReadFile(s.cachePath); err == nil {
+			var c commitCountsCache
+			if json.Unmarshal(data, &c) == nil && c.Counts != nil {
+				s.cached = c.Counts
+			}
+		}
+	}
+	return s
+}
+
+// Fill sets summary.Ahead and summary.Behind for rec. Runs without a
+// worktree, or whose branch or parent branch no longer exists, are left null.
+func (s *commitCountSet) Fill(summary *render.RunSummary, rec store.RunRecord) {
+	if !summary.WorktreePresent {
+		return
+	}
+	if counts, ok := s.Counts(rec); ok {
+		summary.Ahead = &counts.Ahead
+		summary.Behind = &counts.Behind
+	}
+}
+
+// Counts returns the ahead/behind counts of rec's branch vs its parent
+// branch. ok is false if either branch is gone or git fails.
+func (s *commitCountSet) Counts(rec store.RunRecord) (counts commitCounts, ok bool) {
+	if s == nil || rec.Meta == nil {
+		return commitCounts{}, false
+	}
+	heads := s.branchHeads(rec.RepoID, rec.Meta.WorktreePath)
+	parentSHA, branchSHA := heads[rec.Meta.ParentBranch], heads[rec.Meta.Branch]
+	if parentSHA == "" || branchSHA == "" {
+		return commitCounts{}, false
+	}
+
+	key := parentSHA + "..." + branchSHA
+	counts, ok = s.cached[key]
+	if !ok {
+		ahead, behind, err := git.AheadBehind(s.ctx, s.cr, rec.Meta.WorktreePath, parentSHA, branchSHA)
+		if err != nil {
+			return commitCounts{}, false
+		}
+		counts = commitCounts{Ahead: ahead, Behind: behind}
+		s.cached[key] = counts
+		s.dirty = true
+	}
+	s.used[key] = counts
+	return counts, true
+}
+
+// noCommits reports whether rec's branch has no commits beyond the parent
+// branch. Git is only consulted when the report is below the policy's
+// non-empty threshold, since "no changes" needs both.
+func noCommits(commits *commitCountSet, rec store.RunRecord, reportBytes int, policy status.ReviewPolicy) bool {
+	if reportBytes >= policy.ReportThreshold() {
+		return false
+	}
+	counts, ok := commits.Counts(rec)
+	return ok && counts.Ahead == 0
+}
+
+// branchHeads returns the repo's branch heads, reading them from dir
+// (any worktree of the repo) on first use.
+func (s *commitCountSet) branchHeads(repoID, dir string) map[string]string {
+	if heads, ok := s.heads[repoID]; ok {
+		return heads
+	}
+	heads, err := git.BranchHeads(s.ctx, s.cr, dir)
+	if err != nil {
+		heads = nil
+	}
+	s.heads[repoID] = heads
+	return heads
+}
+
+// Save writes new counts to the cache (best-effort; never fails ls).
+func (s *commitCountSet) Save() {
+	if s == nil || s.cachePath == "" || !s.dirty {
+		return
+	}
+	counts := s.cached
+	if len(counts) > maxCachedCommitCounts {
+		counts = s.used
+	}
+	data, err := json.Marshal(commitCountsCache{SchemaVersion: "1.0", Counts: counts})
+	if err != nil {
+		return
+	}
+	if err := s.fsys.MkdirAll(filepath.Dir(s.cachePath), 0o700); err != nil {
+		return
+	}
+	_ = fs.WriteFileAtomic(s.fsys, s.cachePath, data, 0o600)
+}
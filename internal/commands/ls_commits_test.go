@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/status"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestCommitCountSet(t *testing.T) {
+	cacheDir := t.TempDir()
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	committed := testkit.NewRunMeta("abc123", "20260110120000-a3f2", "/wt/a3f2", created)
+	empty := testkit.NewRunMeta("abc123", "20260110130000-b4c1", "/wt/b4c1", created)
+	gone := testkit.NewRunMeta("abc123", "20260110140000-c5d2", "/wt/c5d2", created)
+
+	newRunner := func() *testkit.FakeRunner {
+		cr := testkit.NewFakeRunner()
+		cr.On("git", "for-each-ref", "--format=%(objectname) %(refname)", "refs/heads").Stdout(
+			"1111 refs/heads/main\n" +
+				"2222 refs/heads/" + committed.Branch + "\n" +
+				"1111 refs/heads/" + empty.Branch + "\n")
+		cr.On("git", "rev-list", "--left-right", "--count", "1111...2222").Stdout("0\t3\n")
+		cr.On("git", "rev-list", "--left-right", "--count", "1111...1111").Stdout("0\t0\n")
+		return cr
+	}
+
+	fill := func(cr *testkit.FakeRunner) []render.RunSummary {
+		set := newCommitCountSet(context.Background(), cr, fs.NewRealFS(), cacheDir)
+		var summaries []render.RunSummary
+		for _, meta := range []*store.RunMeta{committed, empty, gone} {
+			summary := render.RunSummary{RunID: meta.RunID, WorktreePresent: true}
+			set.Fill(&summary, store.RunRecord{RepoID: meta.RepoID, RunID: meta.RunID, Meta: meta})
+			summaries = append(summaries, summary)
+		}
+		set.Save()
+		return summaries
+	}
+
+	cr := newRunner()
+	summaries := fill(cr)
+	if got := summaries[0]; got.Ahead == nil || *got.Ahead != 3 || *got.Behind != 0 {
+		t.Errorf("committed run: ahead=%v behind=%v, want 3, 0", got.Ahead, got.Behind)
+	}
+	if got := summaries[1]; got.Ahead == nil || *got.Ahead != 0 {
+		t.Errorf("empty run: ahead=%v, want 0", got.Ahead)
+	}
+	if got := summaries[2]; got.Ahead != nil || got.Behind != nil {
+		t.Errorf("run with deleted branch: ahead=%v behind=%v, want null", got.Ahead, got.Behind)
+	}
+	if n := len(cr.CallsTo("git")); n != 3 {
+		t.Errorf("first ls: %d git calls, want 3 (for-each-ref + 2 rev-list): %v", n, cr.Calls())
+	}
+
+	// Unchanged heads are served from the cache
+	cr = newRunner()
+	summaries = fill(cr)
+	if got := summaries[0]; got.Ahead == nil || *got.Ahead != 3 {
+		t.Errorf("cached: ahead=%v, want 3", got.Ahead)
+	}
+	if n := len(cr.CallsTo("git")); n != 1 {
+		t.Errorf("second ls: %d git calls, want 1 (for-each-ref): %v", n, cr.Calls())
+	}
+
+	var out bytes.Buffer
+	if err := render.WriteLSHuman(&out, render.FormatHumanRows(summaries, created, render.StatusVocabulary{})); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if !strings.Contains(lines[0], "COMMITS") || !strings.Contains(lines[1], "+3 -0") || !strings.Contains(lines[3], " - ") {
+		t.Errorf("human output:\n%s", out.String())
+	}
+}
+
+func TestNoCommits(t *testing.T) {
+	meta := testkit.NewRunMeta("abc123", "20260110120000-a3f2", "/wt/a3f2", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	rec := store.RunRecord{RepoID: meta.RepoID, RunID: meta.RunID, Meta: meta}
+
+	cr := testkit.NewFakeRunner()
+	cr.On("git", "for-each-ref", "--format=%(objectname) %(refname)", "refs/heads").Stdout(
+		"1111 refs/heads/main\n1111 refs/heads/" + meta.Branch + "\n")
+	cr.On("git", "rev-list", "--left-right", "--count", "1111...1111").Stdout("0\t0\n")
+	commits := newCommitCountSet(context.Background(), cr, fs.NewRealFS(), "")
+
+	if noCommits(commits, rec, 500, status.ReviewPolicy{}) {
+		t.Error("noCommits() = true with a non-empty report")
+	}
+	if len(cr.Calls()) != 0 {
+		t.Errorf("git consulted despite a non-empty report: %v", cr.Calls())
+	}
+	if !noCommits(commits, rec, 0, status.ReviewPolicy{}) {
+		t.Error("noCommits() = false for a branch at its parent's tip")
+	}
+	if noCommits(nil, rec, 0, status.ReviewPolicy{}) {
+		t.Error("noCommits() = true without a commit count set")
+	}
+}
@@ -2,12 +2,21 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/render"
 	"github.com/NielsdaWheelz/agency/internal/status"
 	"github.com/NielsdaWheelz/agency/internal/store"
@@ -107,7 +116,7 @@ func TestWriteLSJSON_SchemaVersion(t *testing.T) {
 	var buf bytes.Buffer
 	summaries := []render.RunSummary{}
 
-	if err := render.WriteLSJSON(&buf, summaries); err != nil {
+	if err := render.WriteLSJSON(&buf, summaries, render.LSPage{Total: len(summaries)}, nil); err != nil {
 		t.Fatalf("WriteLSJSON() error = %v", err)
 	}
 
@@ -116,8 +125,8 @@ func TestWriteLSJSON_SchemaVersion(t *testing.T) {
 		t.Fatalf("json.Unmarshal() error = %v", err)
 	}
 
-	if env.SchemaVersion != "1.0" {
-		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.0")
+	if env.SchemaVersion != "1.4" {
+		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.4")
 	}
 
 	if len(env.Data) != 0 {
@@ -155,7 +164,7 @@ func TestWriteLSJSON_AllFields(t *testing.T) {
 	}
 
 	var buf bytes.Buffer
-	if err := render.WriteLSJSON(&buf, summaries); err != nil {
+	if err := render.WriteLSJSON(&buf, summaries, render.LSPage{Total: len(summaries)}, nil); err != nil {
 		t.Fatalf("WriteLSJSON() error = %v", err)
 	}
 
@@ -219,6 +228,14 @@ func TestWriteLSJSON_AllFields(t *testing.T) {
 	if s.LastPushAt == nil {
 		t.Error("LastPushAt is nil")
 	}
+
+	// Epoch seconds are derived from the RFC3339 timestamps
+	if s.CreatedAtUnix == nil || *s.CreatedAtUnix != createdAt.Unix() {
+		t.Errorf("CreatedAtUnix = %v, want %d", s.CreatedAtUnix, createdAt.Unix())
+	}
+	if s.LastPushAtUnix == nil || *s.LastPushAtUnix != lastPushAt.Unix() {
+		t.Errorf("LastPushAtUnix = %v, want %d", s.LastPushAtUnix, lastPushAt.Unix())
+	}
 }
 
 func TestWriteLSJSON_BrokenRun(t *testing.T) {
@@ -243,7 +260,7 @@ func TestWriteLSJSON_BrokenRun(t *testing.T) {
 	}
 
 	var buf bytes.Buffer
-	if err := render.WriteLSJSON(&buf, summaries); err != nil {
+	if err := render.WriteLSJSON(&buf, summaries, render.LSPage{Total: len(summaries)}, nil); err != nil {
 		t.Fatalf("WriteLSJSON() error = %v", err)
 	}
 
@@ -269,11 +286,14 @@ func TestWriteLSJSON_BrokenRun(t *testing.T) {
 	if s.CreatedAt != nil {
 		t.Errorf("CreatedAt = %v, want nil", s.CreatedAt)
 	}
+	if s.CreatedAtUnix != nil {
+		t.Errorf("CreatedAtUnix = %v, want nil", s.CreatedAtUnix)
+	}
 }
 
 func TestWriteLSJSON_NilSummaries(t *testing.T) {
 	var buf bytes.Buffer
-	if err := render.WriteLSJSON(&buf, nil); err != nil {
+	if err := render.WriteLSJSON(&buf, nil, render.LSPage{}, nil); err != nil {
 		t.Fatalf("WriteLSJSON() error = %v", err)
 	}
 
@@ -282,10 +302,13 @@ func TestWriteLSJSON_NilSummaries(t *testing.T) {
 		t.Fatalf("json.Unmarshal() error = %v", err)
 	}
 
-	// Should output empty array, not null
+	// Should output empty arrays, not null
 	if env.Data == nil {
 		t.Error("Data is nil, want empty slice")
 	}
+	if env.Warnings == nil {
+		t.Error("Warnings is nil, want empty slice")
+	}
 }
 
 // ============================================================
@@ -348,6 +371,129 @@ func TestWriteLSHuman_WithRows(t *testing.T) {
 	}
 }
 
+func TestWriteLSHuman_WideTitlesAlign(t *testing.T) {
+	rows := []render.RunSummaryHumanRow{
+		{RunID: "20260110-a3f2", Title: "修复登录错误", Runner: "claude", CreatedAt: "2 hours ago", Status: "active"},
+		{RunID: "20260110-b4c1", Title: "fix 🐛 in login", Runner: "codex", CreatedAt: "1 day ago", Status: "idle"},
+		{RunID: "20260110-c5d2", Title: "plain ascii title", Runner: "claude", CreatedAt: "1 day ago", Status: "idle"},
+	}
+	var buf bytes.Buffer
+	if err := render.WriteLSHuman(&buf, rows); err != nil {
+		t.Fatalf("WriteLSHuman() error = %v", err)
+	}
+
+	// The runner column starts at the same terminal column on every line
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := -1
+	for i, line := range lines {
+		runner := "RUNNER"
+		if i > 0 {
+			runner = rows[i-1].Runner
+		}
+		col := render.DisplayWidth(line[:strings.Index(line, runner)])
+		if want == -1 {
+			want = col
+		}
+		if col != want {
+			t.Errorf("line %d: runner at column %d, want %d:\n%s", i, col, want, buf.String())
+		}
+	}
+}
+
+func TestWriteLSHumanFit_ShrinksTitle(t *testing.T) {
+	rows := []render.RunSummaryHumanRow{
+		{RunID: "a3f2", Title: "一个非常长的标题，描述了这次运行要完成的任务", Runner: "claude", CreatedAt: "just now", Status: "active"},
+	}
+	var buf bytes.Buffer
+	if err := render.WriteLSHumanFit(&buf, rows, 60); err != nil {
+		t.Fatalf("WriteLSHumanFit() error = %v", err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if w := render.DisplayWidth(strings.TrimRight(line, " ")); w > 60 {
+			t.Errorf("line is %d columns wide, want <= 60: %q", w, line)
+		}
+	}
+	if !strings.Contains(buf.String(), "…") {
+		t.Errorf("title not truncated:\n%s", buf.String())
+	}
+
+	// The title never shrinks below TitleMinLen
+	buf.Reset()
+	if err := render.WriteLSHumanFit(&buf, rows, 20); err != nil {
+		t.Fatal(err)
+	}
+	if w := render.DisplayWidth(rows[0].Title); w < render.TitleMinLen-1 || w > render.TitleMinLen {
+		t.Errorf("title %q is %d columns, want ~%d", rows[0].Title, w, render.TitleMinLen)
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly ten", 11, "exactly ten"},
+		{"a long title", 7, "a long…"},
+		{"日本語のタイトル", 16, "日本語のタイトル"},
+		{"日本語のタイトル", 7, "日本語…"},
+		{"日本語のタイトル", 8, "日本語…"}, // a wide char never straddles the limit
+		{"fix 🐛 bug", 6, "fix …"},
+		{"anything", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := render.TruncateWidth(tt.in, tt.width); got != tt.want {
+			t.Errorf("TruncateWidth(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestTerminalWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "")
+	if got := terminalWidth(&bytes.Buffer{}); got != 0 {
+		t.Errorf("terminalWidth(buffer) = %d, want 0", got)
+	}
+	t.Setenv("COLUMNS", "132")
+	if got := terminalWidth(&bytes.Buffer{}); got != 132 {
+		t.Errorf("terminalWidth with COLUMNS=132 = %d, want 132", got)
+	}
+	t.Setenv("COLUMNS", "wide")
+	if got := terminalWidth(&bytes.Buffer{}); got != 0 {
+		t.Errorf("terminalWidth with COLUMNS=wide = %d, want 0", got)
+	}
+}
+
+func TestWriteLSPlain(t *testing.T) {
+	now := time.Date(2026, 1, 10, 14, 0, 0, 0, time.UTC)
+	created := now.Add(-2 * time.Hour)
+	runner := "claude"
+	pr := 123
+	longTitle := strings.Repeat("long title ", 10)
+	summaries := []render.RunSummary{
+		{RunID: "20260110-a3f2", Title: longTitle, Runner: &runner, CreatedAt: &created, DerivedStatus: "active", WorktreePresent: true, PRNumber: &pr},
+		{RunID: "20260109-b4c1", Broken: true, DerivedStatus: "broken", Archived: true},
+	}
+
+	var buf bytes.Buffer
+	if err := render.WriteLSPlain(&buf, summaries, now, render.StatusVocabulary{}); err != nil {
+		t.Fatalf("WriteLSPlain() error = %v", err)
+	}
+	want := "run_id: 20260110-a3f2\n" +
+		"title: " + longTitle + "\n" +
+		"runner: claude\n" +
+		"created: 2 hours ago\n" +
+		"status: active\n" +
+		"pr: #123\n" +
+		"\n" +
+		"run_id: 20260109-b4c1\n" +
+		"title: <broken>\n" +
+		"status: broken (archived)\n"
+	if buf.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
 func TestFormatHumanRow_TitleTruncation(t *testing.T) {
 	longTitle := "this is a very long title that exceeds fifty characters limit"
 	createdAt := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
@@ -362,7 +508,7 @@ func TestFormatHumanRow_TitleTruncation(t *testing.T) {
 	}
 
 	now := time.Date(2026, 1, 10, 14, 0, 0, 0, time.UTC)
-	row := render.FormatHumanRow(summary, now)
+	row := render.FormatHumanRow(summary, now, render.StatusVocabulary{})
 
 	// Title should be truncated with ellipsis
 	if len([]rune(row.Title)) > render.TitleMaxLen {
@@ -384,7 +530,7 @@ func TestFormatHumanRow_BrokenRun(t *testing.T) {
 	}
 
 	now := time.Now()
-	row := render.FormatHumanRow(summary, now)
+	row := render.FormatHumanRow(summary, now, render.StatusVocabulary{})
 
 	if row.Title != render.TitleBroken {
 		t.Errorf("Title = %q, want %q", row.Title, render.TitleBroken)
@@ -409,7 +555,7 @@ func TestFormatHumanRow_UntitledRun(t *testing.T) {
 		DerivedStatus: "idle",
 	}
 
-	row := render.FormatHumanRow(summary, time.Now())
+	row := render.FormatHumanRow(summary, time.Now(), render.StatusVocabulary{})
 
 	if row.Title != render.TitleUntitled {
 		t.Errorf("Title = %q, want %q", row.Title, render.TitleUntitled)
@@ -428,13 +574,56 @@ func TestFormatHumanRow_ArchivedStatus(t *testing.T) {
 		Archived:      true,
 	}
 
-	row := render.FormatHumanRow(summary, time.Now())
+	row := render.FormatHumanRow(summary, time.Now(), render.StatusVocabulary{})
 
 	if row.Status != "idle (archived)" {
 		t.Errorf("Status = %q, want %q", row.Status, "idle (archived)")
 	}
 }
 
+func TestFormatHumanRows_StatusVocabulary(t *testing.T) {
+	now := time.Date(2026, 1, 10, 14, 0, 0, 0, time.UTC)
+	vocab := render.StatusVocabulary{
+		Statuses: map[string]config.StatusDisplay{
+			status.StatusActivePR: {Label: "in-progress", Emoji: "🔨", Color: "yellow"},
+			status.StatusMerged:   {Label: "landed"},
+		},
+		Color: true,
+	}
+	summaries := []render.RunSummary{
+		{RunID: "run1", Title: "a", DerivedStatus: status.StatusActivePR},
+		{RunID: "run2", Title: "b", DerivedStatus: status.StatusMerged, Archived: true},
+		{RunID: "run3", Title: "c", DerivedStatus: status.StatusIdle},
+	}
+
+	rows := render.FormatHumanRows(summaries, now, vocab)
+	if rows[0].Status != "🔨 in-progress" || rows[1].Status != "landed (archived)" || rows[2].Status != "idle" {
+		t.Errorf("statuses = %q, %q, %q", rows[0].Status, rows[1].Status, rows[2].Status)
+	}
+
+	var buf bytes.Buffer
+	if err := render.WriteLSHuman(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	// Padding counts display columns: the emoji takes two, colors none
+	want := "RUN_ID  TITLE  RUNNER  CREATED  STATUS             PR\n" +
+		"run1    a                       \x1b[33m🔨 in-progress\x1b[0m     \n" +
+		"run2    b                       landed (archived)  \n" +
+		"run3    c                       idle               \n"
+	if buf.String() != want {
+		t.Errorf("output =\n%q\nwant\n%q", buf.String(), want)
+	}
+
+	// Plain output keeps the labels but drops emoji and color
+	buf.Reset()
+	if err := render.WriteLSPlain(&buf, summaries[:1], now, vocab); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "status: in-progress\n") {
+		t.Errorf("plain output = %q", buf.String())
+	}
+}
+
 // ============================================================
 // Integration-ish test with fake data
 // ============================================================
@@ -461,7 +650,7 @@ func TestLS_IntegrationWithFakeData(t *testing.T) {
 	createRepoJSONForLS(t, dataDir, "r1", "github:owner/repo1", "git@github.com:owner/repo1.git")
 
 	// Scan all runs
-	records, err := store.ScanAllRuns(dataDir)
+	records, err := store.ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v", err)
 	}
@@ -471,10 +660,10 @@ func TestLS_IntegrationWithFakeData(t *testing.T) {
 	}
 
 	// Convert to summaries (without tmux - use empty session map)
-	tmuxSessions := make(map[string]bool)
+	tmuxSessions := staticTmuxSessions(nil)
 	summaries := make([]render.RunSummary, len(records))
 	for i, rec := range records {
-		summaries[i] = recordToSummary(rec, tmuxSessions, nil)
+		summaries[i] = recordToSummary(context.Background(), nil, rec, tmuxSessions, nil, nil, nil)
 	}
 
 	// Sort
@@ -520,7 +709,7 @@ func TestLS_IntegrationWithFakeData(t *testing.T) {
 
 	// Test JSON output
 	var buf bytes.Buffer
-	if err := render.WriteLSJSON(&buf, summaries); err != nil {
+	if err := render.WriteLSJSON(&buf, summaries, render.LSPage{Total: len(summaries)}, nil); err != nil {
 		t.Fatalf("WriteLSJSON() error = %v", err)
 	}
 
@@ -529,8 +718,8 @@ func TestLS_IntegrationWithFakeData(t *testing.T) {
 		t.Fatalf("json.Unmarshal() error = %v", err)
 	}
 
-	if env.SchemaVersion != "1.0" {
-		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.0")
+	if env.SchemaVersion != "1.4" {
+		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.4")
 	}
 	if len(env.Data) != 3 {
 		t.Errorf("len(Data) = %d, want 3", len(env.Data))
@@ -539,6 +728,11 @@ func TestLS_IntegrationWithFakeData(t *testing.T) {
 
 // Helper functions for tests
 
+// staticTmuxSessions returns a preloaded session set that never calls tmux.
+func staticTmuxSessions(active map[string]bool) *tmuxSessionSet {
+	return &tmuxSessionSet{load: func() map[string]bool { return active }}
+}
+
 func createValidMetaForLS(t *testing.T, dataDir, repoID, runID string, createdAt time.Time) {
 	t.Helper()
 	runDir := filepath.Join(dataDir, "repos", repoID, "runs", runID)
@@ -601,3 +795,427 @@ func createRepoJSONForLS(t *testing.T, dataDir, repoID, repoKey, originURL strin
 		t.Fatal(err)
 	}
 }
+
+// ============================================================
+// Visibility filter tests
+// ============================================================
+
+func TestResolveLSFilter(t *testing.T) {
+	yes, no := true, false
+	defaults := config.DefaultUserConfig().LS
+
+	tests := []struct {
+		name     string
+		opts     LSOpts
+		defaults config.UserLSConfig
+		want     lsFilter
+	}{
+		{"defaults", LSOpts{}, defaults, lsFilter{IncludeArchived: false, IncludeBroken: true}},
+		{"--all alias", LSOpts{All: true}, defaults, lsFilter{IncludeArchived: true, IncludeBroken: true}},
+		{"--archived", LSOpts{Archived: &yes}, defaults, lsFilter{IncludeArchived: true, IncludeBroken: true}},
+		{"--broken=false", LSOpts{Broken: &no}, defaults, lsFilter{IncludeArchived: false, IncludeBroken: false}},
+		{"config defaults", LSOpts{}, config.UserLSConfig{Archived: true, Broken: false}, lsFilter{IncludeArchived: true, IncludeBroken: false}},
+		{"flag overrides config", LSOpts{Archived: &no, Broken: &yes}, config.UserLSConfig{Archived: true, Broken: false}, lsFilter{IncludeArchived: false, IncludeBroken: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveLSFilter(tt.opts, tt.defaults)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveLSFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLSFilter_BrokenIndependentOfArchived(t *testing.T) {
+	broken := render.RunSummary{RunID: "broken", Broken: true, Archived: true}
+	archived := render.RunSummary{RunID: "archived", Archived: true}
+
+	f := lsFilter{IncludeArchived: false, IncludeBroken: true}
+	if !f.includeSummary(broken) {
+		t.Error("broken run should be visible when IncludeBroken is set, even though archived is hidden")
+	}
+	if f.includeSummary(archived) {
+		t.Error("archived run should be hidden when IncludeArchived is false")
+	}
+
+	f = lsFilter{IncludeArchived: true, IncludeBroken: false}
+	if f.includeRecord(store.RunRecord{RunID: "broken", Broken: true}) {
+		t.Error("broken record should be skipped when IncludeBroken is false")
+	}
+	if !f.includeSummary(archived) {
+		t.Error("archived run should be visible when IncludeArchived is true")
+	}
+}
+
+func TestLSFilter_Labels(t *testing.T) {
+	sel, err := core.ParseLabelSelector("ticket=JIRA-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := lsFilter{IncludeArchived: true, IncludeBroken: true, Labels: []core.LabelSelector{sel}}
+
+	tagged := store.RunRecord{RunID: "a", Meta: &store.RunMeta{Labels: map[string]string{"ticket": "JIRA-123", "team": "infra"}}}
+	other := store.RunRecord{RunID: "b", Meta: &store.RunMeta{Labels: map[string]string{"ticket": "JIRA-999"}}}
+	unlabeled := store.RunRecord{RunID: "c", Meta: &store.RunMeta{}}
+	broken := store.RunRecord{RunID: "d", Broken: true}
+
+	if !f.includeRecord(tagged) {
+		t.Error("run with matching label should be included")
+	}
+	for _, rec := range []store.RunRecord{other, unlabeled, broken} {
+		if f.includeRecord(rec) {
+			t.Errorf("run %s should be excluded by label selector", rec.RunID)
+		}
+	}
+
+	summary := recordToSummary(context.Background(), nil, tagged, staticTmuxSessions(nil), nil, nil, nil)
+	if summary.Labels["team"] != "infra" {
+		t.Errorf("summary.Labels = %v, want team=infra", summary.Labels)
+	}
+	if summary := recordToSummary(context.Background(), nil, broken, staticTmuxSessions(nil), nil, nil, nil); summary.Labels == nil {
+		t.Error("broken summary Labels should be an empty map (JSON {}), not nil")
+	}
+}
+
+func TestLSFilter_Task(t *testing.T) {
+	f := lsFilter{IncludeArchived: true, IncludeBroken: true, Task: "20260110120000-c7d1"}
+	for _, tc := range []struct {
+		rec  store.RunRecord
+		want bool
+	}{
+		{store.RunRecord{RunID: "a", Meta: &store.RunMeta{TaskID: "20260110120000-c7d1"}}, true},
+		{store.RunRecord{RunID: "b", Meta: &store.RunMeta{TaskID: "20260110120000-d8e2"}}, false},
+		{store.RunRecord{RunID: "c", Meta: &store.RunMeta{}}, false},
+		{store.RunRecord{RunID: "d", Broken: true}, false},
+	} {
+		if got := f.includeRecord(tc.rec); got != tc.want {
+			t.Errorf("includeRecord(%s) = %v, want %v", tc.rec.RunID, got, tc.want)
+		}
+	}
+}
+
+func TestLS_Mine(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	t.Setenv("AGENCY_USER", "alice")
+
+	created := time.Date(2026, 1, 10, 14, 0, 0, 0, time.UTC)
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	for runID, user := range map[string]string{"20260110-a3f2": "alice", "20260110-b4c1": "bob", "20260110-c5d2": ""} {
+		createValidMetaForLS(t, dataDir, "r1", runID, created)
+		if err := st.UpdateMeta("r1", runID, func(m *store.RunMeta) { m.CreatedBy = user }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout bytes.Buffer
+	if err := LS(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), LSOpts{All: true, JSON: true, Mine: true}, &stdout, io.Discard); err != nil {
+		t.Fatalf("LS() error = %v", err)
+	}
+	var env render.LSJSONEnvelope
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(env.Data) != 1 || env.Data[0].RunID != "20260110-a3f2" || env.Data[0].CreatedBy == nil || *env.Data[0].CreatedBy != "alice" {
+		t.Errorf("--mine data = %+v, want only alice's run", env.Data)
+	}
+}
+
+func TestLS_RepoFilter(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+
+	created := time.Date(2026, 1, 10, 14, 0, 0, 0, time.UTC)
+	createValidMetaForLS(t, dataDir, "r1", "20260110-a3f2", created)
+	createRepoJSONForLS(t, dataDir, "r1", "github:acme/api", "git@github.com:Acme/api.git")
+	createValidMetaForLS(t, dataDir, "r2", "20260110-b4c1", created)
+	createRepoJSONForLS(t, dataDir, "r2", "github:acme/web", "https://github.com/acme/web.git")
+	createValidMetaForLS(t, dataDir, "r3", "20260110-c5d2", created)
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	if err := st.SaveRepoRecord(store.RepoRecord{SchemaVersion: "1.0", RepoKey: "path:deadbeef", RepoID: "r3", RepoRootLastSeen: "/gone/checkout"}); err != nil {
+		t.Fatal(err)
+	}
+
+	runIDs := func(repo string) ([]string, error) {
+		var stdout bytes.Buffer
+		err := LS(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), LSOpts{All: true, JSON: true, Repo: repo}, &stdout, io.Discard)
+		if err != nil {
+			return nil, err
+		}
+		var env render.LSJSONEnvelope
+		if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		var got []string
+		for _, s := range env.Data {
+			got = append(got, s.RepoID)
+		}
+		sort.Strings(got)
+		return got, nil
+	}
+
+	for repo, want := range map[string]string{
+		"r2":              "r2",
+		"github:acme/api": "r1",
+		"acme/":           "r1 r2",
+		"ACME/API":        "r1",
+		"/gone/checkout/": "r3",
+	} {
+		got, err := runIDs(repo)
+		if err != nil {
+			t.Errorf("--repo %s: error = %v", repo, err)
+			continue
+		}
+		if strings.Join(got, " ") != want {
+			t.Errorf("--repo %s: repos = %v, want %s", repo, got, want)
+		}
+	}
+
+	if _, err := runIDs("gitlab.com"); errors.GetCode(err) != errors.ERepoNotFound {
+		t.Errorf("unmatched --repo error = %v, want E_REPO_NOT_FOUND", err)
+	}
+}
+
+func TestLS_InvalidLabelSelector(t *testing.T) {
+	t.Setenv("AGENCY_DATA_DIR", t.TempDir())
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+
+	var stdout, stderr bytes.Buffer
+	err := LS(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), LSOpts{Labels: []string{"bad key"}}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q (err=%v)", errors.GetCode(err), errors.EUsage, err)
+	}
+}
+
+func TestLS_UnreadableRepoDir(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+
+	createValidMetaForLS(t, dataDir, "r1", "20260110-a3f2", time.Date(2026, 1, 10, 14, 0, 0, 0, time.UTC))
+	badRuns := filepath.Join(dataDir, "repos", "r2", "runs")
+	if err := os.MkdirAll(filepath.Dir(badRuns), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(badRuns, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := LS(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), LSOpts{All: true, JSON: true}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("LS() error = %v", err)
+	}
+	var env render.LSJSONEnvelope
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(env.Data) != 1 || env.Data[0].RunID != "20260110-a3f2" {
+		t.Errorf("data = %+v, want the readable run", env.Data)
+	}
+	if len(env.Warnings) != 1 || env.Warnings[0].Path != badRuns {
+		t.Errorf("warnings = %+v, want one for %s", env.Warnings, badRuns)
+	}
+
+	stdout.Reset()
+	if err := LS(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), LSOpts{}, &stdout, &stderr); err != nil {
+		t.Fatalf("LS() error = %v", err)
+	}
+	if !strings.Contains(stderr.String(), "warning: skipped "+badRuns) {
+		t.Errorf("stderr = %q, want a skipped warning", stderr.String())
+	}
+}
+
+func TestLS_PaginationAndStream(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+
+	// Newest first: run-c, run-b, run-a
+	for i, runID := range []string{"run-a", "run-b", "run-c"} {
+		createValidMetaForLS(t, dataDir, "r1", runID, time.Date(2026, 1, 10, 12+i, 0, 0, 0, time.UTC))
+	}
+	ls := func(opts LSOpts) (string, error) {
+		opts.All = true
+		var stdout, stderr bytes.Buffer
+		err := LS(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), opts, &stdout, &stderr)
+		return stdout.String(), err
+	}
+
+	out, err := ls(LSOpts{JSON: true, Offset: 1, Limit: 1})
+	if err != nil {
+		t.Fatalf("LS() error = %v", err)
+	}
+	var env render.LSJSONEnvelope
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(env.Data) != 1 || env.Data[0].RunID != "run-b" {
+		t.Errorf("data = %+v, want [run-b]", env.Data)
+	}
+	if env.Total != 3 || env.Offset != 1 || env.Limit == nil || *env.Limit != 1 {
+		t.Errorf("total=%d offset=%d limit=%v, want 3, 1, 1", env.Total, env.Offset, env.Limit)
+	}
+
+	out, err = ls(LSOpts{Stream: true, Offset: 1})
+	if err != nil {
+		t.Fatalf("LS() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("stream output = %q, want 2 lines", out)
+	}
+	for i, want := range []string{"run-b", "run-a"} {
+		var summary render.RunSummary
+		if err := json.Unmarshal([]byte(lines[i]), &summary); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if summary.RunID != want {
+			t.Errorf("line %d run_id = %q, want %q", i, summary.RunID, want)
+		}
+	}
+
+	if out, err = ls(LSOpts{JSON: true, Offset: 10}); err != nil || !strings.Contains(out, `"data": []`) {
+		t.Errorf("offset past the end: out=%s err=%v, want empty data", out, err)
+	}
+	for _, opts := range []LSOpts{{Limit: -1}, {Offset: -1}, {Stream: true, Format: "{{.RunID}}"}} {
+		if _, err := ls(opts); errors.GetCode(err) != errors.EUsage {
+			t.Errorf("LS(%+v) code = %q, want %q", opts, errors.GetCode(err), errors.EUsage)
+		}
+	}
+}
+
+func TestLS_HumanShortIDs(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+
+	createValidMetaForLS(t, dataDir, "r1", "20260110120000-a3f2", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	createValidMetaForLS(t, dataDir, "r2", "20260110120000-a3ff", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	createValidMetaForLS(t, dataDir, "r1", "20260111090000-c5d2", time.Date(2026, 1, 11, 9, 0, 0, 0, time.UTC))
+	ls := func(opts LSOpts) string {
+		opts.All = true
+		var stdout bytes.Buffer
+		if err := LS(context.Background(), newMockRunner(), fs.NewRealFS(), t.TempDir(), opts, &stdout, io.Discard); err != nil {
+			t.Fatalf("LS(%+v) error = %v", opts, err)
+		}
+		return stdout.String()
+	}
+
+	// Human table: shortest unique prefix, full id where runs differ only at the end
+	out := ls(LSOpts{})
+	for _, want := range []string{"\n20260111 ", "\n20260110120000-a3f2 ", "\n20260110120000-a3ff "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("human output missing %q:\n%s", want, out)
+		}
+	}
+
+	// JSON and plain output keep full ids
+	if out := ls(LSOpts{JSON: true}); !strings.Contains(out, `"run_id": "20260111090000-c5d2"`) {
+		t.Errorf("json output lost the full run_id:\n%s", out)
+	}
+	if out := ls(LSOpts{Plain: true}); !strings.Contains(out, "run_id: 20260111090000-c5d2\n") {
+		t.Errorf("plain output lost the full run_id:\n%s", out)
+	}
+}
+
+// ============================================================
+// --format tests
+// ============================================================
+
+func TestWriteLSFormat(t *testing.T) {
+	runner := "claude"
+	summaries := []render.RunSummary{
+		{RunID: "run1", Runner: &runner, DerivedStatus: "idle"},
+		{RunID: "run2", DerivedStatus: status.StatusBroken, Broken: true},
+	}
+
+	tmpl, err := render.ParseFormat(`{{.RunID}} {{.DerivedStatus}} {{default "-" .Runner}}`)
+	if err != nil {
+		t.Fatalf("ParseFormat() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := render.WriteLSFormat(&buf, tmpl, summaries); err != nil {
+		t.Fatalf("WriteLSFormat() error = %v", err)
+	}
+
+	want := "run1 idle claude\nrun2 broken -\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestParseFormat_Invalid(t *testing.T) {
+	_, err := render.ParseFormat(`{{.RunID`)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
+
+func TestWriteLSFormat_UnknownField(t *testing.T) {
+	tmpl, err := render.ParseFormat(`{{.NoSuchField}}`)
+	if err != nil {
+		t.Fatalf("ParseFormat() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = render.WriteLSFormat(&buf, tmpl, []render.RunSummary{{RunID: "run1"}})
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no partial output, got %q", buf.String())
+	}
+}
+
+func TestRecordToSummary_ReviewPolicy(t *testing.T) {
+	dataDir := t.TempDir()
+	repoID := "abc123"
+	setupGCRepo(t, dataDir, repoID, "github:owner/repo", `{
+		"version": 1,
+		"review": {"readiness": "front_matter"}
+	}`)
+
+	worktree := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(worktree, ".agency"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	reportPath := filepath.Join(worktree, ".agency", "report.md")
+	longReport := "# summary\n\n" + strings.Repeat("a long report without the ready flag. ", 10) + "\n"
+	if err := os.WriteFile(reportPath, []byte(longReport), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	createValidMetaForShow(t, dataDir, repoID, "run-pr", worktree, time.Now())
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	if err := st.UpdateMeta(repoID, "run-pr", func(m *store.RunMeta) {
+		m.PRNumber = 7
+		m.LastPushAt = "2026-01-10T13:00:00Z"
+	}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := store.ScanAllRuns(dataDir, nil)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("ScanAllRuns = %d, %v", len(records), err)
+	}
+
+	summarize := func() string {
+		policies := newReviewPolicySet(fs.NewRealFS(), dataDir)
+		return recordToSummary(context.Background(), nil, records[0], staticTmuxSessions(nil), policies, nil, nil).DerivedStatus
+	}
+
+	if got := summarize(); got != status.StatusIdlePR {
+		t.Errorf("without ready flag: status = %q, want %q", got, status.StatusIdlePR)
+	}
+	if err := os.WriteFile(reportPath, []byte("---\nready: true\n---\nshort\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := summarize(); got != status.StatusReadyForReview {
+		t.Errorf("with ready flag: status = %q, want %q", got, status.StatusReadyForReview)
+	}
+}
@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// MvOpts holds options for the mv command.
+type MvOpts struct {
+	// RunID is the run identifier (exact or unique prefix).
+	RunID string
+
+	// Title is the new run title.
+	Title string
+
+	// Branch also renames the run branch to agency/<slug(title)>-<shortid>.
+	Branch bool
+
+	// Force allows renaming the branch of a run that already has a PR.
+	Force bool
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+}
+
+// Mv changes a run's title and optionally re-slugs its branch.
+// Previous names are kept under meta.aliases. The tmux session name is
+// derived from the run_id, so it never changes.
+// Resolves run_id globally (works from anywhere, not just inside a repo).
+func Mv(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts MvOpts, stdout, stderr io.Writer) error {
+	if opts.RunID == "" {
+		return errors.New(errors.EUsage, "run_id is required")
+	}
+	title := strings.TrimSpace(opts.Title)
+	if title == "" {
+		return errors.New(errors.EUsage, "new title is required")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+	record, err := resolveRun(dirs.DataDir, opts.RunID, scope, crypter)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock.NewRepoLock(dirs.DataDir).Lock(record.RepoID, "mv")
+	if err != nil {
+		return repoLockError(err, dirs.DataDir, record.RepoID)
+	}
+	defer func() { _ = unlock() }()
+
+	// Re-read under the lock
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+	meta, err := st.ReadMeta(record.RepoID, record.RunID)
+	if err != nil {
+		return err
+	}
+
+	oldTitle, oldBranch := meta.Title, meta.Branch
+	newBranch := oldBranch
+	if opts.Branch {
+		newBranch = slugRulesForRun(fsys, dirs.DataDir, record).BranchName(title, meta.RunID)
+		if newBranch != oldBranch {
+			if err := checkBranchRename(ctx, cr, fsys, meta, newBranch, opts.Force); err != nil {
+				return err
+			}
+		}
+	}
+
+	if title == oldTitle && newBranch == oldBranch {
+		fmt.Fprintf(stdout, "run_id: %s\n", meta.RunID)
+		fmt.Fprintf(stdout, "title: %s (unchanged)\n", title)
+		fmt.Fprintf(stdout, "branch: %s (unchanged)\n", oldBranch)
+		return nil
+	}
+
+	if newBranch != oldBranch {
+		res, err := cr.Run(ctx, "git", []string{"branch", "-m", oldBranch, newBranch}, agencyexec.RunOpts{Dir: meta.WorktreePath})
+		if err != nil {
+			return errors.Wrap(errors.EInternal, "failed to run git branch -m", err)
+		}
+		if res.ExitCode != 0 {
+			return errors.NewWithDetails(errors.EInternal, "git branch -m failed: "+strings.TrimSpace(res.Stderr),
+				map[string]string{"from": oldBranch, "to": newBranch})
+		}
+	}
+
+	err = st.UpdateMeta(meta.RepoID, meta.RunID, func(m *store.RunMeta) {
+		if m.Aliases == nil {
+			m.Aliases = &store.RunMetaAliases{}
+		}
+		if title != oldTitle {
+			m.Aliases.Titles = append(m.Aliases.Titles, oldTitle)
+			m.Title = title
+		}
+		if newBranch != oldBranch {
+			m.Aliases.Branches = append(m.Aliases.Branches, oldBranch)
+			m.Branch = newBranch
+		}
+	})
+	if err != nil {
+		if newBranch != oldBranch {
+			// Keep git and meta.json consistent (best-effort)
+			cr.Run(ctx, "git", []string{"branch", "-m", newBranch, oldBranch}, agencyexec.RunOpts{Dir: meta.WorktreePath})
+		}
+		return err
+	}
+
+	data := map[string]any{"from_title": oldTitle, "to_title": title}
+	if newBranch != oldBranch {
+		data["from_branch"] = oldBranch
+		data["to_branch"] = newBranch
+	}
+	_ = events.AppendEvent(events.EventsPath(record.RunDir), events.New(st.Now(), meta.RepoID, meta.RunID, "renamed", data))
+
+	if meta.PRNumber != 0 {
+		fmt.Fprintf(stderr, "warning: PR #%d title on GitHub is unchanged\n", meta.PRNumber)
+	}
+	if newBranch != oldBranch && meta.LastPushAt != "" {
+		fmt.Fprintf(stderr, "warning: remote branch %s keeps its old name; the next push creates %s\n", oldBranch, newBranch)
+	}
+
+	fmt.Fprintf(stdout, "run_id: %s\n", meta.RunID)
+	fmt.Fprintf(stdout, "title: %s (was %s)\n", title, oldTitle)
+	if newBranch != oldBranch {
+		fmt.Fprintf(stdout, "branch: %s (was %s)\n", newBranch, oldBranch)
+	} else {
+		fmt.Fprintf(stdout, "branch: %s (unchanged)\n", oldBranch)
+	}
+	return nil
+}
+
+// checkBranchRename verifies the branch of meta can be renamed to newBranch:
+// the worktree must exist, the run must not have a PR (unless force), and
+// newBranch must not already exist.
+func checkBranchRename(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, meta *store.RunMeta, newBranch string, force bool) error {
+	if _, err := fsys.Stat(meta.WorktreePath); err != nil {
+		return errors.NewWithDetails(errors.EWorktreeMissing, "cannot rename branch: run is archived",
+			map[string]string{"run_id": meta.RunID, "worktree_path": meta.WorktreePath})
+	}
+	if meta.PRNumber != 0 && !force {
+		return errors.NewWithDetails(errors.EPRExists,
+			"run has PR #"+strconv.Itoa(meta.PRNumber)+" on branch "+meta.Branch+"; renaming the branch detaches it from the PR (use --force)",
+			map[string]string{"run_id": meta.RunID, "pr_url": meta.PRURL, "branch": meta.Branch})
+	}
+
+	res, err := cr.Run(ctx, "git", []string{"show-ref", "--verify", "--quiet", "refs/heads/" + newBranch}, agencyexec.RunOpts{Dir: meta.WorktreePath})
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to check branch existence", err)
+	}
+	if res.ExitCode == 0 {
+		return errors.NewWithDetails(errors.EBranchExists, "branch "+newBranch+" already exists",
+			map[string]string{"branch": newBranch})
+	}
+	return nil
+}
+
+// slugRulesForRun returns the slug rules from the agency.json of the run's
+// repo, or the built-in rules if the repo or its config cannot be found.
+func slugRulesForRun(fsys fs.FS, dataDir string, record *store.RunRecord) core.SlugRules {
+	if record.Repo == nil {
+		return core.SlugRules{}
+	}
+	idx, _ := store.LoadRepoIndexForScan(dataDir)
+	root := store.PickRepoRoot(record.Repo.RepoKey, nil, idx)
+	if root == nil {
+		return core.SlugRules{}
+	}
+	cfg, err := config.LoadAgencyConfig(fsys, *root)
+	if err != nil {
+		return core.SlugRules{}
+	}
+	return cfg.Slug.Rules()
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// setupMvTest writes a run with a present worktree. mutate may adjust meta
+// before it is written.
+func setupMvTest(t *testing.T, mutate func(*store.RunMeta)) (*store.Store, *store.RunMeta) {
+	t.Helper()
+	dataDir := testkit.DataDir(t)
+	runID := "20260110120000-a3f2"
+	worktree := filepath.Join(dataDir, "repos", "repo1", "worktrees", runID)
+	if err := os.MkdirAll(worktree, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	meta := testkit.NewRunMeta("repo1", runID, worktree, time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	meta.Title = "fix parsr"
+	meta.Branch = "agency/fix-parsr-a3f2"
+	if mutate != nil {
+		mutate(meta)
+	}
+	testkit.WriteRun(t, dataDir, meta)
+	return store.NewStore(fs.NewRealFS(), dataDir, time.Now), meta
+}
+
+func TestMv_TitleOnly(t *testing.T) {
+	st, meta := setupMvTest(t, nil)
+	cr := testkit.NewFakeRunner()
+
+	var stdout bytes.Buffer
+	opts := MvOpts{RunID: "20260110", Title: "fix parser crash"}
+	if err := Mv(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, &stdout, io.Discard); err != nil {
+		t.Fatalf("Mv: %v", err)
+	}
+	if len(cr.Calls()) != 0 {
+		t.Errorf("title-only mv should not run commands: %v", cr.Calls())
+	}
+
+	got, err := st.ReadMeta(meta.RepoID, meta.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "fix parser crash" || got.Branch != meta.Branch {
+		t.Errorf("meta title=%q branch=%q", got.Title, got.Branch)
+	}
+	if got.Aliases == nil || len(got.Aliases.Titles) != 1 || got.Aliases.Titles[0] != "fix parsr" || len(got.Aliases.Branches) != 0 {
+		t.Errorf("aliases = %+v", got.Aliases)
+	}
+	if !strings.Contains(stdout.String(), "title: fix parser crash (was fix parsr)") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+}
+
+func TestMv_RenameBranch(t *testing.T) {
+	st, meta := setupMvTest(t, nil)
+	cr := testkit.NewFakeRunner()
+	cr.OnPrefix("git", "show-ref").Exit(1)
+	cr.OnPrefix("git", "branch", "-m")
+
+	opts := MvOpts{RunID: meta.RunID, Title: "fix parser crash", Branch: true}
+	if err := Mv(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Mv: %v", err)
+	}
+	if !cr.Called("git", "branch", "-m", "agency/fix-parsr-a3f2", "agency/fix-parser-crash-a3f2") {
+		t.Errorf("expected git branch -m, got %v", cr.Calls())
+	}
+
+	got, _ := st.ReadMeta(meta.RepoID, meta.RunID)
+	if got.Branch != "agency/fix-parser-crash-a3f2" || got.Aliases.Branches[0] != "agency/fix-parsr-a3f2" {
+		t.Errorf("branch=%q aliases=%+v", got.Branch, got.Aliases)
+	}
+	if got.TmuxSessionName != meta.TmuxSessionName {
+		t.Errorf("tmux session changed to %q", got.TmuxSessionName)
+	}
+	evs, err := os.ReadFile(filepath.Join(st.RunDir(meta.RepoID, meta.RunID), "events.jsonl"))
+	if err != nil || !strings.Contains(string(evs), `"to_branch":"agency/fix-parser-crash-a3f2"`) {
+		t.Errorf("expected renamed event, got %q (%v)", evs, err)
+	}
+}
+
+func TestMv_BranchSafetyChecks(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(*store.RunMeta)
+		exists   bool
+		force    bool
+		wantCode errors.Code
+	}{
+		{"pr exists", func(m *store.RunMeta) { m.PRNumber = 12 }, false, false, errors.EPRExists},
+		{"archived", func(m *store.RunMeta) { m.WorktreePath = "/nonexistent/wt" }, false, false, errors.EWorktreeMissing},
+		{"target exists", nil, true, false, errors.EBranchExists},
+		{"pr exists forced", func(m *store.RunMeta) { m.PRNumber = 12 }, false, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, meta := setupMvTest(t, tt.mutate)
+			cr := testkit.NewFakeRunner()
+			if tt.exists {
+				cr.OnPrefix("git", "show-ref")
+			} else {
+				cr.OnPrefix("git", "show-ref").Exit(1)
+			}
+			cr.OnPrefix("git", "branch", "-m")
+
+			var stderr bytes.Buffer
+			opts := MvOpts{RunID: meta.RunID, Title: "new title", Branch: true, Force: tt.force}
+			err := Mv(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, io.Discard, &stderr)
+			if errors.GetCode(err) != tt.wantCode {
+				t.Fatalf("code = %q, want %q (%v)", errors.GetCode(err), tt.wantCode, err)
+			}
+
+			got, _ := st.ReadMeta(meta.RepoID, meta.RunID)
+			if tt.wantCode != "" {
+				if got.Title != meta.Title || cr.Called("git", "branch", "-m", meta.Branch, "agency/new-title-a3f2") {
+					t.Error("failed safety check must not change anything")
+				}
+				return
+			}
+			if !strings.Contains(stderr.String(), "PR #12") {
+				t.Errorf("expected PR warning, got %q", stderr.String())
+			}
+		})
+	}
+}
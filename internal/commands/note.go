@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// NoteOpts holds options for the note command.
+type NoteOpts struct {
+	// RunID is the run identifier (exact or unique prefix).
+	RunID string
+
+	// Text is the note body.
+	Text string
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+}
+
+// Note appends a timestamped note to a run's notes.jsonl.
+// Resolves run_id globally (works from anywhere, not just inside a repo).
+func Note(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts NoteOpts, stdout, stderr io.Writer) error {
+	if opts.RunID == "" {
+		return errors.New(errors.EUsage, "run_id is required")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+	record, err := resolveRun(dirs.DataDir, opts.RunID, scope, crypter)
+	if err != nil {
+		return err
+	}
+
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+	note, err := st.AppendNote(record.RepoID, record.RunID, opts.Text)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "run_id: %s\n", record.RunID)
+	fmt.Fprintf(stdout, "noted_at: %s\n", note.Timestamp)
+	return nil
+}
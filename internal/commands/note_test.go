@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+func TestNote_AppendsToResolvedRun(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	runID := "20260110120000-a3f2"
+	repoID := "abc123"
+	worktreePath := filepath.Join(dataDir, "repos", repoID, "worktrees", runID)
+	createValidMetaForShow(t, dataDir, repoID, runID, worktreePath, time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+
+	var stdout, stderr bytes.Buffer
+	opts := NoteOpts{RunID: "20260110", Text: "review: check error paths"}
+	if err := Note(context.Background(), nil, fs.NewRealFS(), dataDir, opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Note() error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "run_id: "+runID) {
+		t.Errorf("stdout = %q, want run_id line", stdout.String())
+	}
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, nil)
+	notes, err := st.ReadNotes(repoID, runID)
+	if err != nil {
+		t.Fatalf("ReadNotes() error = %v", err)
+	}
+	if len(notes) != 1 || notes[0].Text != "review: check error paths" {
+		t.Errorf("notes = %+v, want single review note", notes)
+	}
+}
+
+func TestNote_RunNotFound(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	if err := os.MkdirAll(filepath.Join(dataDir, "repos"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	opts := NoteOpts{RunID: "nope", Text: "text"}
+	err := Note(context.Background(), nil, fs.NewRealFS(), dataDir, opts, &stdout, &stderr)
+	if errors.GetCode(err) != errors.ERunNotFound {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.ERunNotFound)
+	}
+}
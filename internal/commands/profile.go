@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/paths"
+	"github.com/NielsdaWheelz/agency/internal/profile"
+)
+
+// profileSlowest is how many of the slowest spans profile report lists.
+const profileSlowest = 10
+
+// ProfileReportOpts holds options for the profile report command.
+type ProfileReportOpts struct {
+	// Dir is the profile directory to summarize ("" = the latest one).
+	Dir string
+
+	// JSON outputs machine-readable JSON.
+	JSON bool
+}
+
+// profilesDir returns ${AGENCY_CACHE_DIR}/profiles.
+func profilesDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(errors.EInternal, "failed to get home directory", err)
+	}
+	return filepath.Join(paths.ResolveDirs(osEnv{}, homeDir).CacheDir, "profiles"), nil
+}
+
+// StartProfile starts profiling this invocation of command into a new
+// directory under ${AGENCY_CACHE_DIR}/profiles (see package profile). The
+// cli calls it for --profile and AGENCY_PROFILE, and stops the session
+// once the command returns.
+func StartProfile(command string, argv []string) (*profile.Session, error) {
+	dir, err := profilesDir()
+	if err != nil {
+		return nil, err
+	}
+	name := clock.Now().UTC().Format("20060102T150405.000") + "-" + command
+	return profile.Start(filepath.Join(dir, name), command, argv)
+}
+
+// ProfileReport summarizes a profile recorded with --profile: wall time,
+// time spent in git, tmux, gh, scripts, and data dir scans, and the
+// slowest operations. Returns E_NO_PROFILE if none was recorded.
+func ProfileReport(opts ProfileReportOpts, stdout io.Writer) error {
+	dir := opts.Dir
+	if dir == "" {
+		var err error
+		if dir, err = latestProfile(); err != nil {
+			return err
+		}
+	}
+	trace, err := profile.ReadTrace(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.WithHints(errors.NewWithDetails(errors.ENoProfile,
+				"no profile trace in "+dir, map[string]string{"dir": dir}),
+				"record one with: agency --profile <command>")
+		}
+		return errors.Wrap(errors.EInternal, "failed to read profile trace", err)
+	}
+	summary := profile.Summarize(trace, profileSlowest)
+
+	if opts.JSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			SchemaVersion string          `json:"schema_version"`
+			Dir           string          `json:"dir"`
+			Data          profile.Summary `json:"data"`
+		}{"1.0", dir, summary})
+	}
+	writeProfileSummary(stdout, dir, summary)
+	return nil
+}
+
+// latestProfile returns the most recent profile directory.
+func latestProfile() (string, error) {
+	root, err := profilesDir()
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrap(errors.EInternal, "failed to read profiles dir", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", errors.WithHints(errors.NewWithDetails(errors.ENoProfile,
+			"no profiles recorded", map[string]string{"dir": root}),
+			"record one with: agency --profile <command>")
+	}
+	sort.Strings(names)
+	return filepath.Join(root, names[len(names)-1]), nil
+}
+
+// writeProfileSummary prints a summary as a table of time by kind and a
+// list of the slowest operations.
+func writeProfileSummary(w io.Writer, dir string, s profile.Summary) {
+	fmt.Fprintf(w, "profile:   %s\n", dir)
+	fmt.Fprintf(w, "command:   %s\n", strings.Join(s.Argv, " "))
+	fmt.Fprintf(w, "started:   %s\n", s.StartedAt)
+	fmt.Fprintf(w, "wall time: %s\n\n", formatProfileMs(s.DurationMs))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tCOUNT\tTIME\tSHARE")
+	for _, k := range s.Kinds {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", k.Kind, k.Count, formatProfileMs(k.TotalMs), profileShare(k.TotalMs, s.DurationMs))
+	}
+	fmt.Fprintf(tw, "other\t-\t%s\t%s\n", formatProfileMs(s.OtherMs), profileShare(s.OtherMs, s.DurationMs))
+	tw.Flush()
+
+	if len(s.Slowest) > 0 {
+		fmt.Fprintln(w, "\nslowest:")
+		for _, span := range s.Slowest {
+			fmt.Fprintf(w, "  %8s  %s\n", formatProfileMs(span.DurationMs), span.Name)
+		}
+	}
+	fmt.Fprintf(w, "\n\"other\" is agency's own work; inspect it with: go tool pprof -top %s\n",
+		filepath.Join(dir, profile.CPUFileName))
+}
+
+// formatProfileMs renders milliseconds as a duration, e.g. "1.24s".
+func formatProfileMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// profileShare renders part as a percentage of total ("-" for a zero total).
+// Concurrent operations can add up to more than 100%.
+func profileShare(part, total int64) string {
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", part*100/total)
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/profile"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestProfileReport(t *testing.T) {
+	t.Setenv("AGENCY_CACHE_DIR", t.TempDir())
+	clk := testkit.NewClock(time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	defer SetClock(clk.Core())()
+
+	var stdout bytes.Buffer
+	err := ProfileReport(ProfileReportOpts{}, &stdout)
+	if errors.GetCode(err) != errors.ENoProfile {
+		t.Fatalf("code = %q, want %q", errors.GetCode(err), errors.ENoProfile)
+	}
+
+	session, err := StartProfile("ls", []string{"agency", "ls", "--all"})
+	if err != nil {
+		t.Fatalf("StartProfile() error = %v", err)
+	}
+	profile.Record(profile.KindGit, "git rev-list", time.Now().Add(-20*time.Millisecond))
+	profile.Track(profile.KindScan, "scan all runs")()
+	if err := session.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(session.Dir, "20260110T120000.000-ls") {
+		t.Errorf("dir = %s", session.Dir)
+	}
+
+	if err := ProfileReport(ProfileReportOpts{}, &stdout); err != nil {
+		t.Fatalf("ProfileReport() error = %v", err)
+	}
+	out := stdout.String()
+	for _, want := range []string{"command:   agency ls --all", "KIND", "git ", "scan ", "other", "slowest:", "git rev-list", "cpu.pprof"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	stdout.Reset()
+	if err := ProfileReport(ProfileReportOpts{Dir: session.Dir, JSON: true}, &stdout); err != nil {
+		t.Fatalf("ProfileReport(json) error = %v", err)
+	}
+	var env struct {
+		Dir  string          `json:"dir"`
+		Data profile.Summary `json:"data"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if env.Dir != session.Dir || env.Data.Command != "ls" || len(env.Data.Kinds) != 2 || env.Data.Kinds[0].Kind != profile.KindGit {
+		t.Errorf("json = %+v", env)
+	}
+}
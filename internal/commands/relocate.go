@@ -0,0 +1,393 @@
+package commands
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// RelocateOpts holds options for the relocate command.
+type RelocateOpts struct {
+	// To is the new data dir location; it must not exist or be empty.
+	To string
+
+	// DryRun prints the paths that would be rewritten without moving anything.
+	DryRun bool
+}
+
+// RelocateResult is where Relocate moved the data dir. The cli points
+// AGENCY_DATA_DIR at To for the rest of the invocation (e.g. its audit entry).
+type RelocateResult struct {
+	From string
+	To   string
+}
+
+// pathRewrite is one absolute path in a run's meta.json that moves with the
+// data dir.
+type pathRewrite struct {
+	RunID string
+	Field string
+	From  string
+	To    string
+}
+
+// Relocate moves the data dir to opts.To and rewrites the absolute paths
+// recorded in meta.json (worktree_path, setup log and output paths, token
+// path) that point into it. Every repo is locked for the move; runs with a
+// live tmux session block it, since their runner works in the old location.
+// Worktrees under the data dir are relinked with git worktree repair and
+// checked with git worktree list, so the main repo does not prune them.
+//
+// The move is a rename, or a copy when the target is on another filesystem;
+// a copy only removes the old data dir once every run was rewritten.
+//
+// Error codes:
+//   - E_USAGE: bad target (exists and is not empty, inside the data dir, ...)
+//   - E_SESSIONS_RUNNING: runner sessions still run in the data dir
+//   - E_REPO_LOCKED: another command holds a repo lock
+//   - E_RELOCATE_FAILED: the move or a meta.json rewrite failed
+func Relocate(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts RelocateOpts, stdout, stderr io.Writer) (*RelocateResult, error) {
+	if opts.To == "" {
+		return nil, errors.New(errors.EUsage, "--to is required")
+	}
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return nil, err
+	}
+	crypter := LoadCrypt(cr, fsys)
+	from := filepath.Clean(dirs.DataDir)
+	to, err := filepath.Abs(opts.To)
+	if err != nil {
+		return nil, errors.Wrap(errors.EUsage, "invalid --to", err)
+	}
+	if err := checkRelocateTarget(from, to, cwd); err != nil {
+		return nil, err
+	}
+
+	records, err := store.ScanAllRuns(from, crypter)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].RunID < records[j].RunID })
+	var rewrites []pathRewrite
+	var worktrees []string
+	broken := 0
+	sessions := newTmuxSessionSet(ctx, cr)
+	var running []string
+	for _, rec := range records {
+		if rec.Broken || rec.Meta == nil {
+			broken++
+			continue
+		}
+		if rec.Meta.TmuxSessionName != "" && sessions.Active(rec.Meta.TmuxSessionName) {
+			running = append(running, rec.RunID)
+		}
+		meta := *rec.Meta
+		rewrites = append(rewrites, relocateMetaPaths(&meta, from, to)...)
+		if meta.WorktreePath != rec.Meta.WorktreePath && dirExists(rec.Meta.WorktreePath) {
+			worktrees = append(worktrees, meta.WorktreePath)
+		}
+	}
+	if len(running) > 0 {
+		return nil, errors.WithHints(errors.NewWithDetails(errors.ESessionsRunning,
+			"runner sessions are running in the data dir: "+strings.Join(running, ", "),
+			map[string]string{"data_dir": from}),
+			"stop them first (agency kill <run_id>), then relocate")
+	}
+
+	move := "rename"
+	if !sameFilesystem(from, to) {
+		move = "copy"
+	}
+	fmt.Fprintf(stdout, "data_dir: %s -> %s\n", from, to)
+	if opts.DryRun {
+		fmt.Fprintln(stdout, "dry_run: true")
+		fmt.Fprintf(stdout, "move: %s\n", move)
+		writeRelocateCounts(stdout, len(records)-broken, broken, len(rewrites))
+		fmt.Fprintf(stdout, "worktrees: %d to relink\n", len(worktrees))
+		for _, r := range rewrites {
+			fmt.Fprintf(stdout, "  %s %s: %s -> %s\n", r.RunID, r.Field, r.From, r.To)
+		}
+		return nil, nil
+	}
+
+	repoIDs, err := lockAllRepos(from)
+	if err != nil {
+		return nil, err
+	}
+	// The lock files move with the data dir; release them there
+	unlockAt := from
+	defer func() {
+		repoLock := lock.NewRepoLock(unlockAt)
+		for _, id := range repoIDs {
+			_ = repoLock.ForceUnlock(id)
+		}
+	}()
+
+	if move, err = moveDataDir(fsys, from, to); err != nil {
+		return nil, err
+	}
+	unlockAt = to
+	fmt.Fprintf(stdout, "move: %s\n", move)
+
+	// Rewrite under the (moved) locks; a run that fails keeps its old paths
+	st := store.NewStore(fsys, to, clock.Now).WithCrypt(crypter)
+	var failed []string
+	for _, rec := range records {
+		if rec.Broken || rec.Meta == nil {
+			continue
+		}
+		err := st.UpdateMeta(rec.RepoID, rec.RunID, func(m *store.RunMeta) { relocateMetaPaths(m, from, to) })
+		if err != nil {
+			failed = append(failed, rec.RunID)
+			fmt.Fprintf(stderr, "warning: %s: failed to rewrite meta.json: %v\n", rec.RunID, err)
+		}
+	}
+	writeRelocateCounts(stdout, len(records)-broken-len(failed), broken, len(rewrites))
+
+	relinked := 0
+	for _, wt := range worktrees {
+		if err := relinkWorktree(ctx, cr, wt); err != nil {
+			fmt.Fprintf(stderr, "warning: %s\n", err)
+			continue
+		}
+		relinked++
+	}
+	fmt.Fprintf(stdout, "worktrees: %d relinked, %d failed\n", relinked, len(worktrees)-relinked)
+
+	if len(failed) > 0 {
+		msg := "failed to rewrite meta.json of " + strings.Join(failed, ", ")
+		if move == "copy" {
+			msg += "; the old data dir was kept at " + from
+		}
+		return nil, errors.NewWithDetails(errors.ERelocateFailed, msg,
+			map[string]string{"from": from, "to": to})
+	}
+	if move == "copy" {
+		if err := os.RemoveAll(from); err != nil {
+			fmt.Fprintf(stderr, "warning: failed to remove the old data dir %s: %v\n", from, err)
+		}
+	}
+
+	fmt.Fprintf(stdout, "next: %s\n", relocateNextStep(fsys, cwd, to))
+	return &RelocateResult{From: from, To: to}, nil
+}
+
+// checkRelocateTarget rejects targets that are not a fresh location for the
+// data dir at from.
+func checkRelocateTarget(from, to, cwd string) error {
+	if info, err := os.Stat(from); err != nil || !info.IsDir() {
+		return errors.NewWithDetails(errors.EUsage, "no data dir at "+from,
+			map[string]string{"data_dir": from})
+	}
+	if _, ok := pathWithin(from, to); ok {
+		return errors.NewWithDetails(errors.EUsage, "--to is inside the data dir: "+to,
+			map[string]string{"data_dir": from, "to": to})
+	}
+	if _, ok := pathWithin(to, from); ok {
+		return errors.NewWithDetails(errors.EUsage, "the data dir is inside --to: "+from,
+			map[string]string{"data_dir": from, "to": to})
+	}
+	if _, ok := pathWithin(from, cwd); ok {
+		return errors.WithHints(errors.NewWithDetails(errors.EUsage, "cannot relocate the data dir from inside it: "+cwd,
+			map[string]string{"data_dir": from, "cwd": cwd}),
+			"cd to your repo and run the command again")
+	}
+	if entries, err := os.ReadDir(to); err == nil && len(entries) > 0 {
+		return errors.NewWithDetails(errors.EUsage, "--to exists and is not empty: "+to,
+			map[string]string{"to": to})
+	} else if err != nil && !os.IsNotExist(err) {
+		return errors.WrapWithDetails(errors.EUsage, "--to is not a usable directory", err,
+			map[string]string{"to": to})
+	}
+	return nil
+}
+
+// relocateMetaPaths rewrites the absolute paths of m under from to the same
+// place under to, and returns the rewrites. Paths elsewhere (e.g. an adopted
+// worktree outside the data dir) are left alone.
+func relocateMetaPaths(m *store.RunMeta, from, to string) []pathRewrite {
+	type field struct {
+		name string
+		path *string
+	}
+	fields := []field{{"worktree_path", &m.WorktreePath}}
+	if m.Setup != nil {
+		// Copy the nested structs so a dry run never touches the scanned meta
+		setup := *m.Setup
+		m.Setup = &setup
+		fields = append(fields,
+			field{"setup.log_path", &setup.LogPath},
+			field{"setup.previous_output_path", &setup.PreviousOutputPath},
+			field{"setup.sandbox_report_path", &setup.SandboxReportPath})
+		if setup.OutputFile != nil {
+			file := *setup.OutputFile
+			setup.OutputFile = &file
+			fields = append(fields, field{"setup.output_file.path", &file.Path})
+		}
+	}
+	if m.Credentials != nil {
+		creds := *m.Credentials
+		m.Credentials = &creds
+		fields = append(fields, field{"credentials.token_path", &creds.TokenPath})
+	}
+
+	var rewrites []pathRewrite
+	for _, f := range fields {
+		rel, err := filepath.Rel(from, *f.path)
+		if *f.path == "" || !filepath.IsAbs(*f.path) || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		newPath := filepath.Join(to, rel)
+		rewrites = append(rewrites, pathRewrite{RunID: m.RunID, Field: f.name, From: *f.path, To: newPath})
+		*f.path = newPath
+	}
+	return rewrites
+}
+
+// writeRelocateCounts prints the runs and paths line of relocate's output.
+func writeRelocateCounts(w io.Writer, runs, broken, paths int) {
+	fmt.Fprintf(w, "runs: %d", runs)
+	if broken > 0 {
+		fmt.Fprintf(w, " (%d broken, not rewritten)", broken)
+	}
+	fmt.Fprintf(w, "\npaths: %d\n", paths)
+}
+
+// lockAllRepos takes the repo lock of every repo in the data dir and returns
+// their ids. On failure the locks already taken are released.
+func lockAllRepos(dataDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dataDir, "repos"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(errors.EInternal, "failed to read repos dir", err)
+	}
+	repoLock := lock.NewRepoLock(dataDir)
+	var locked []string
+	var unlocks []func() error
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		unlock, err := repoLock.Lock(e.Name(), "relocate")
+		if err != nil {
+			for _, u := range unlocks {
+				_ = u()
+			}
+			return nil, repoLockError(err, dataDir, e.Name())
+		}
+		locked = append(locked, e.Name())
+		unlocks = append(unlocks, unlock)
+	}
+	return locked, nil
+}
+
+// moveDataDir moves from to to and returns how: "rename", or "copy" across
+// filesystems (from is then still in place). An empty to is replaced.
+func moveDataDir(fsys fs.FS, from, to string) (string, error) {
+	fail := func(msg string, err error) (string, error) {
+		return "", errors.WrapWithDetails(errors.ERelocateFailed, msg, err,
+			map[string]string{"from": from, "to": to})
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0o700); err != nil {
+		return fail("failed to create the parent of --to", err)
+	}
+	if err := os.Remove(to); err != nil && !os.IsNotExist(err) {
+		return fail("failed to remove the empty --to dir", err)
+	}
+	err := fsys.Rename(from, to)
+	if err == nil {
+		return "rename", nil
+	}
+	if !stderrors.Is(err, syscall.EXDEV) {
+		return fail("failed to move the data dir", err)
+	}
+	if err := fsys.CopyDir(from, to); err != nil {
+		_ = os.RemoveAll(to)
+		return fail("failed to copy the data dir", err)
+	}
+	return "copy", nil
+}
+
+// sameFilesystem reports whether to (or its nearest existing ancestor) is on
+// the filesystem of from, i.e. whether the move can be a rename.
+func sameFilesystem(from, to string) bool {
+	var a, b syscall.Stat_t
+	if syscall.Stat(from, &a) != nil {
+		return true
+	}
+	for dir := to; ; dir = filepath.Dir(dir) {
+		if syscall.Stat(dir, &b) == nil {
+			return a.Dev == b.Dev
+		}
+		if filepath.Dir(dir) == dir {
+			return true
+		}
+	}
+}
+
+// relinkWorktree points the main repo's record of the moved worktree at its
+// new path (git worktree repair, run in the worktree) and checks that git
+// lists it there and does not consider it prunable.
+func relinkWorktree(ctx context.Context, cr agencyexec.CommandRunner, worktree string) error {
+	res, err := cr.Run(ctx, "git", []string{"worktree", "repair"}, agencyexec.RunOpts{Dir: worktree})
+	if err != nil || res.ExitCode != 0 {
+		return fmt.Errorf("git worktree repair failed in %s: %s", worktree, commandFailure(res, err))
+	}
+	res, err = cr.Run(ctx, "git", []string{"worktree", "list", "--porcelain"}, agencyexec.RunOpts{Dir: worktree})
+	if err != nil || res.ExitCode != 0 {
+		return fmt.Errorf("git worktree list failed in %s: %s", worktree, commandFailure(res, err))
+	}
+	want := resolvePath(worktree)
+	for _, block := range strings.Split(res.Stdout, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		path, ok := strings.CutPrefix(lines[0], "worktree ")
+		if !ok || resolvePath(path) != want {
+			continue
+		}
+		for _, line := range lines[1:] {
+			if strings.HasPrefix(line, "prunable") {
+				return fmt.Errorf("git still considers %s prunable; run: git -C %s worktree repair", worktree, worktree)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("git does not list the worktree %s; run: git -C %s worktree repair", worktree, worktree)
+}
+
+// commandFailure describes a failed command for a warning.
+func commandFailure(res agencyexec.CmdResult, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if msg := strings.TrimSpace(res.Stderr); msg != "" {
+		return msg
+	}
+	return fmt.Sprintf("exit %d", res.ExitCode)
+}
+
+// relocateNextStep tells the user how to point agency at the moved data
+// dir: the agency.json data_dir override if the repo sets one (and
+// AGENCY_DATA_DIR does not win over it), else AGENCY_DATA_DIR.
+func relocateNextStep(fsys fs.FS, cwd, to string) string {
+	if os.Getenv("AGENCY_DATA_DIR") == "" {
+		if root := config.FindRepoRoot(fsys, cwd); root != "" {
+			if cfg, err := config.LoadAgencyConfig(fsys, root); err == nil && cfg.DataDir != "" {
+				return fmt.Sprintf("set \"data_dir\": %q in %s", to, filepath.Join(root, "agency.json"))
+			}
+		}
+	}
+	return "export AGENCY_DATA_DIR=" + to + "  # e.g. in your shell profile"
+}
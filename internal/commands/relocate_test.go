@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestRelocate(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	repoRoot := testkit.NewRepo(t, testkit.RepoOpts{Git: true})
+	testkit.Git(t, repoRoot, "branch", "feature/moved")
+
+	cr := agencyexec.NewRealRunner()
+	fsys := fs.NewRealFS()
+	ctx := context.Background()
+	if err := Adopt(ctx, cr, fsys, repoRoot, AdoptOpts{Branch: "feature/moved", RunID: "relocate-1"}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+	records, err := store.ScanAllRuns(dataDir, nil)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("ScanAllRuns = %d records, %v", len(records), err)
+	}
+	rec := records[0]
+	oldLog := filepath.Join(rec.RunDir, "logs", "setup.log")
+	st := store.NewStore(fsys, dataDir, nil)
+	if err := st.UpdateMeta(rec.RepoID, rec.RunID, func(m *store.RunMeta) {
+		m.Setup = &store.RunMetaSetup{LogPath: oldLog}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	to := filepath.Join(t.TempDir(), "moved")
+
+	// Dry run: prints the rewrites, moves nothing
+	var stdout bytes.Buffer
+	result, err := Relocate(ctx, cr, fsys, repoRoot, RelocateOpts{To: to, DryRun: true}, &stdout, io.Discard)
+	if err != nil || result != nil {
+		t.Fatalf("Relocate(dry run) = %v, %v", result, err)
+	}
+	for _, want := range []string{"dry_run: true", "move: rename", "runs: 1", "paths: 2", "worktrees: 1 to relink", "relocate-1 setup.log_path: " + oldLog} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("dry run output missing %q:\n%s", want, stdout.String())
+		}
+	}
+	if _, err := os.Stat(to); !os.IsNotExist(err) {
+		t.Errorf("dry run created %s", to)
+	}
+
+	stdout.Reset()
+	result, err = Relocate(ctx, cr, fsys, repoRoot, RelocateOpts{To: to}, &stdout, io.Discard)
+	if err != nil {
+		t.Fatalf("Relocate: %v\n%s", err, stdout.String())
+	}
+	if result.To != to || !strings.Contains(stdout.String(), "worktrees: 1 relinked, 0 failed") ||
+		!strings.Contains(stdout.String(), "next: export AGENCY_DATA_DIR="+to) {
+		t.Errorf("result = %+v, output:\n%s", result, stdout.String())
+	}
+	if _, err := os.Stat(dataDir); !os.IsNotExist(err) {
+		t.Errorf("old data dir still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(to, "repos", rec.RepoID, ".lock")); !os.IsNotExist(err) {
+		t.Errorf("repo lock left behind: %v", err)
+	}
+
+	meta, err := store.NewStore(fsys, to, nil).ReadMeta(rec.RepoID, rec.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantWorktree := filepath.Join(to, "repos", rec.RepoID, "worktrees", rec.RunID)
+	if meta.WorktreePath != wantWorktree || meta.Setup.LogPath != filepath.Join(to, "repos", rec.RepoID, "runs", rec.RunID, "logs", "setup.log") {
+		t.Errorf("meta paths = %s, %s", meta.WorktreePath, meta.Setup.LogPath)
+	}
+	list := testkit.Git(t, repoRoot, "worktree", "list", "--porcelain")
+	if !strings.Contains(list, "worktree "+resolvePath(wantWorktree)+"\n") || strings.Contains(list, "prunable") {
+		t.Errorf("git worktree list:\n%s", list)
+	}
+}
+
+func TestRelocate_Target(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	nonEmpty := t.TempDir()
+	if err := os.WriteFile(filepath.Join(nonEmpty, "x"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, to := range []string{"", nonEmpty, filepath.Join(dataDir, "inner")} {
+		_, err := Relocate(context.Background(), testkit.NewFakeRunner(), fs.NewRealFS(), t.TempDir(), RelocateOpts{To: to}, io.Discard, io.Discard)
+		if errors.GetCode(err) != errors.EUsage {
+			t.Errorf("--to %q: code = %q, want %q", to, errors.GetCode(err), errors.EUsage)
+		}
+	}
+}
+
+func TestRelocate_SessionsRunning(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	testkit.WriteRun(t, dataDir, testkit.NewRunMeta("abc123", "20260110120000-a3f2", filepath.Join(dataDir, "gone"), time.Now()))
+	cr := testkit.NewFakeRunner()
+	cr.On("tmux", "list-sessions", "-F", "#{session_name}").Stdout("agency_20260110120000-a3f2\n")
+
+	to := filepath.Join(t.TempDir(), "moved")
+	_, err := Relocate(context.Background(), cr, fs.NewRealFS(), t.TempDir(), RelocateOpts{To: to}, io.Discard, io.Discard)
+	if errors.GetCode(err) != errors.ESessionsRunning {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.ESessionsRunning)
+	}
+	if _, err := os.Stat(dataDir); err != nil {
+		t.Errorf("data dir moved despite running sessions: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/git"
+	"github.com/NielsdaWheelz/agency/internal/identity"
+	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/status"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// reportSummaryMaxLen caps the report.md excerpt shown per run.
+const reportSummaryMaxLen = 280
+
+// ReportOpts holds options for the report command.
+type ReportOpts struct {
+	// AllRepos includes runs from every repo (default: current repo, or all
+	// repos when cwd is not inside a repo, as for ls).
+	AllRepos bool
+
+	// Since limits the digest to runs with activity in this window (0 = no limit).
+	Since time.Duration
+
+	// Output is the file to write; "" writes to stdout.
+	Output string
+
+	// HTML renders HTML instead of Markdown. Implied by an .html/.htm Output.
+	HTML bool
+
+	// Now overrides the current time (for tests).
+	Now func() time.Time
+}
+
+// Report executes the agency report command: a Markdown (or HTML) digest of
+// runs grouped by repo and status, with PR links, setup/verify outcomes, and
+// the first paragraph of each run's report.md.
+// Archived runs are included; broken runs are skipped.
+// This is a read-only command apart from writing Output.
+func Report(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts ReportOpts, stdout, stderr io.Writer) error {
+	now := clock.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	generatedAt := now()
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	dataDir := dirs.DataDir
+
+	records, err := scanReportRuns(ctx, cr, dataDir, cwd, opts.AllRepos, LoadCrypt(cr, fsys))
+	if err != nil {
+		return err
+	}
+
+	var since *time.Time
+	if opts.Since > 0 {
+		t := generatedAt.Add(-opts.Since)
+		since = &t
+	}
+
+	tmuxSessions := newTmuxSessionSet(ctx, cr)
+	policies := newReviewPolicySet(fsys, dataDir)
+	commits := newCommitCountSet(ctx, cr, fsys, "")
+
+	var summaries []render.RunSummary
+	metas := make(map[string]*store.RunMeta)
+	for _, rec := range records {
+		if rec.Broken || rec.Meta == nil {
+			continue
+		}
+		if since != nil && lastActivity(rec.Meta).Before(*since) {
+			continue
+		}
+		summaries = append(summaries, recordToSummary(ctx, cr, rec, tmuxSessions, policies, commits, fsys))
+		metas[rec.RepoID+"/"+rec.RunID] = rec.Meta
+	}
+	sortSummaries(summaries)
+
+	runs := make([]render.ReportRun, 0, len(summaries))
+	for _, s := range summaries {
+		meta := metas[s.RepoID+"/"+s.RunID]
+		run := render.ReportRun{
+			RunID:  s.RunID,
+			Repo:   s.RepoID,
+			Title:  meta.Title,
+			Branch: meta.Branch,
+			Status: s.DerivedStatus,
+			Setup:  setupOutcome(meta),
+			Verify: verifyOutcome(meta.LastVerifyAt),
+		}
+		if s.RepoKey != nil && *s.RepoKey != "" {
+			run.Repo = *s.RepoKey
+		}
+		if run.Title == "" {
+			run.Title = meta.Branch
+		}
+		if s.PRNumber != nil {
+			run.PRNumber = *s.PRNumber
+		}
+		if s.PRURL != nil {
+			run.PRURL = *s.PRURL
+		}
+		if s.WorktreePresent {
+			if content, err := os.ReadFile(filepath.Join(meta.WorktreePath, ".agency", "report.md")); err == nil {
+				run.Summary = status.ReportSummary(string(content), reportSummaryMaxLen)
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	digest := render.NewReportDigest(runs, generatedAt, since)
+	html := opts.HTML || isHTMLPath(opts.Output)
+
+	var buf bytes.Buffer
+	if html {
+		err = render.WriteReportHTML(&buf, digest)
+	} else {
+		err = render.WriteReportMarkdown(&buf, digest)
+	}
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to render report", err)
+	}
+
+	if opts.Output == "" {
+		_, err = stdout.Write(buf.Bytes())
+		return err
+	}
+
+	outPath := opts.Output
+	if !filepath.IsAbs(outPath) {
+		outPath = filepath.Join(cwd, outPath)
+	}
+	if err := fs.WriteFileAtomic(fsys, outPath, buf.Bytes(), 0o644); err != nil {
+		return errors.WrapWithDetails(errors.EPersistFailed, "failed to write report", err, map[string]string{"path": outPath})
+	}
+	fmt.Fprintf(stdout, "wrote %s (%d run(s))\n", opts.Output, len(runs))
+	return nil
+}
+
+// scanReportRuns returns the runs a digest of cwd covers: the current
+// repo's, or every repo's with allRepos or when cwd is not inside a repo.
+func scanReportRuns(ctx context.Context, cr agencyexec.CommandRunner, dataDir, cwd string, allRepos bool, crypter *crypt.Crypt) ([]store.RunRecord, error) {
+	repoRoot, err := git.GetRepoRoot(ctx, cr, cwd)
+	if allRepos || err != nil {
+		return store.ScanAllRuns(dataDir, crypter)
+	}
+	originInfo := git.GetOriginInfo(ctx, cr, repoRoot.Path)
+	return store.ScanRunsForRepo(dataDir, identity.DeriveRepoIdentity(repoRoot.Path, originInfo.URL).RepoID, crypter)
+}
+
+// lastActivity returns the latest recorded timestamp for a run: creation,
+// push, verify, merge, or archive. Unparseable timestamps are ignored.
+func lastActivity(meta *store.RunMeta) time.Time {
+	stamps := []string{meta.CreatedAt, meta.LastPushAt, meta.LastVerifyAt}
+	if meta.Archive != nil {
+		stamps = append(stamps, meta.Archive.MergedAt, meta.Archive.ArchivedAt)
+	}
+	var latest time.Time
+	for _, s := range stamps {
+		if t, err := time.Parse(time.RFC3339, s); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// setupOutcome describes the setup script result recorded in meta.json.
+func setupOutcome(meta *store.RunMeta) string {
+	setup := meta.Setup
+	if setup == nil && slices.Contains(meta.SkippedSteps, pipeline.SkipSetup) {
+		return "skipped"
+	}
+	if setup == nil {
+		return "not run"
+	}
+	var outcome string
+	switch {
+	case setup.CacheHit:
+		outcome = "skipped (cache hit)"
+	case setup.TimedOut:
+		outcome = "timed out"
+	case setup.ExitCode == 0:
+		outcome = "ok"
+	case setup.ExitCode < 0:
+		outcome = "failed to start"
+	default:
+		outcome = fmt.Sprintf("failed (exit %d)", setup.ExitCode)
+	}
+	if setup.OutputSummary != "" {
+		outcome += " — " + setup.OutputSummary
+	}
+	return outcome
+}
+
+// verifyOutcome describes the last verify recorded in meta.json.
+func verifyOutcome(lastVerifyAt string) string {
+	if lastVerifyAt == "" {
+		return "not run"
+	}
+	if t, err := time.Parse(time.RFC3339, lastVerifyAt); err == nil {
+		return "ran " + t.UTC().Format("2006-01-02")
+	}
+	return "ran"
+}
+
+// isHTMLPath reports whether an output path asks for HTML by extension.
+func isHTMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".html" || ext == ".htm"
+}
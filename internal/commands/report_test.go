@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestReport(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	now := time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC)
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	if err := st.SaveRepoRecord(store.RepoRecord{SchemaVersion: "1.0", RepoKey: "github:owner/repo", RepoID: "abc123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Merged run with a PR and a report
+	worktree := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(worktree, ".agency"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	report := "# report\n\nfixed the login redirect loop.\n\n## details\n"
+	if err := os.WriteFile(filepath.Join(worktree, ".agency", "report.md"), []byte(report), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	merged := testkit.NewRunMeta("abc123", "20260115100000-a3f2", worktree, now.AddDate(0, 0, -5))
+	merged.Title = "login fix"
+	merged.PRNumber = 12
+	merged.PRURL = "https://github.com/owner/repo/pull/12"
+	merged.Setup = &store.RunMetaSetup{ExitCode: 0}
+	merged.LastVerifyAt = "2026-01-16T09:00:00Z"
+	merged.Archive = &store.RunMetaArchive{MergedAt: "2026-01-17T09:00:00Z"}
+	testkit.WriteRun(t, dataDir, merged)
+
+	// Old run outside the --since window
+	old := testkit.NewRunMeta("abc123", "20251201100000-b4c1", "/gone", now.AddDate(0, 0, -50))
+	old.Title = "ancient"
+	testkit.WriteRun(t, dataDir, old)
+
+	// Run in a repo without repo.json, setup failed
+	failed := testkit.NewRunMeta("def456", "20260119100000-c5d2", "/gone", now.AddDate(0, 0, -1))
+	failed.Setup = &store.RunMetaSetup{ExitCode: 2, OutputSummary: "npm install failed"}
+	failed.Flags = &store.RunMetaFlags{SetupFailed: true}
+	testkit.WriteRun(t, dataDir, failed)
+
+	cr := testkit.NewFakeRunner()
+	cr.Fallback = &testkit.Response{Result: agencyexec.CmdResult{ExitCode: 1}}
+
+	var stdout, stderr bytes.Buffer
+	opts := ReportOpts{AllRepos: true, Since: 7 * 24 * time.Hour, Now: func() time.Time { return now }}
+	if err := Report(context.Background(), cr, fs.NewRealFS(), t.TempDir(), opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"2 run(s) with activity since 2026-01-13",
+		"## def456\n\n### failed (1)\n",
+		"- setup: failed (exit 2) — npm install failed · verify: not run\n",
+		"## github:owner/repo\n\n### merged (1)\n",
+		"- **login fix** (`20260115100000-a3f2`) — [#12](https://github.com/owner/repo/pull/12)\n",
+		"- setup: ok · verify: ran 2026-01-16\n  - fixed the login redirect loop.\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "ancient") {
+		t.Errorf("run outside --since window included:\n%s", out)
+	}
+
+	// HTML by output extension
+	cwd := t.TempDir()
+	stdout.Reset()
+	opts.Output = "summary.html"
+	if err := Report(context.Background(), cr, fs.NewRealFS(), cwd, opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Report(--output) error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "wrote summary.html (2 run(s))") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	html, err := os.ReadFile(filepath.Join(cwd, "summary.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(html), `<a href="https://github.com/owner/repo/pull/12">#12</a>`) {
+		t.Errorf("html missing PR link:\n%s", html)
+	}
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/repo"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// ReposRefreshOpts holds options for the repos refresh command.
+type ReposRefreshOpts struct {
+	// All refreshes every repo in repo_index.json instead of the repo
+	// containing cwd.
+	All bool
+}
+
+// ReposRefresh detects repo capabilities (GitHub origin, gh auth) again and
+// rewrites repo.json, whatever their age: for the repo containing cwd, or
+// with All for every repo in repo_index.json. Repos whose last seen root is
+// gone are skipped with a warning.
+//
+// Error codes:
+//   - E_NO_REPO: not inside a git repository (without All)
+//   - E_PERSIST_FAILED: repo.json could not be written
+func ReposRefresh(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts ReposRefreshOpts, stdout, stderr io.Writer) error {
+	if !opts.All {
+		rc, err := repo.RefreshRepo(ctx, cr, fsys, cwd)
+		if err != nil {
+			return err
+		}
+		writeRepoCapabilities(stdout, rc)
+		return nil
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	idx, err := store.LoadRepoIndexForScan(dirs.DataDir)
+	if err != nil {
+		return errors.Wrap(errors.EStoreCorrupt, "failed to read repo_index.json", err)
+	}
+	if idx == nil || len(idx.Repos) == 0 {
+		fmt.Fprintln(stderr, "no repos in repo_index.json; run agency doctor in a repo first")
+		return nil
+	}
+
+	repoKeys := make([]string, 0, len(idx.Repos))
+	for key := range idx.Repos {
+		repoKeys = append(repoKeys, key)
+	}
+	sort.Strings(repoKeys)
+
+	var failed error
+	for _, key := range repoKeys {
+		root := store.PickRepoRoot(key, nil, idx)
+		if root == nil {
+			fmt.Fprintf(stderr, "warning: skipping %s: no known repo root exists\n", key)
+			continue
+		}
+		rc, err := repo.RefreshRepo(ctx, cr, fsys, *root)
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: %s: %s\n", key, err.Error())
+			if errors.GetCode(err) == errors.EPersistFailed && failed == nil {
+				failed = err
+			}
+			continue
+		}
+		if rc.RepoKey != key {
+			fmt.Fprintf(stderr, "warning: %s now resolves to %s (origin changed?)\n", key, rc.RepoKey)
+		}
+		writeRepoCapabilities(stdout, rc)
+	}
+	return failed
+}
+
+// writeRepoCapabilities prints one line with a repo's refreshed capabilities.
+func writeRepoCapabilities(w io.Writer, rc *repo.RepoContext) {
+	c := rc.Capabilities
+	fmt.Fprintf(w, "%s: github_origin=%t gh_authed=%t checked_at=%s\n", rc.RepoKey, c.GitHubOrigin, c.GhAuthed, c.CheckedAt)
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/identity"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestReposRefresh_All(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+
+	root := t.TempDir()
+	origin := "git@github.com:owner/app.git"
+	live := identity.DeriveRepoIdentity(root, origin)
+	gone := filepath.Join(t.TempDir(), "deleted")
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	idx, err := st.LoadRepoIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx = st.UpsertRepoIndexEntry(idx, live.RepoKey, live.RepoID, root)
+	idx = st.UpsertRepoIndexEntry(idx, "path:0123abcd", "0123abcd", gone)
+	if err := st.SaveRepoIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+
+	cr := testkit.NewFakeRunner()
+	cr.On("git", "rev-parse", "--show-toplevel").Stdout(root + "\n")
+	cr.On("git", "remote", "get-url", "origin").Stdout(origin + "\n")
+	cr.GhAuthenticated(true)
+
+	var stdout, stderr bytes.Buffer
+	if err := ReposRefresh(context.Background(), cr, fs.NewRealFS(), t.TempDir(), ReposRefreshOpts{All: true}, &stdout, &stderr); err != nil {
+		t.Fatalf("ReposRefresh() error = %v", err)
+	}
+	if want := live.RepoKey + ": github_origin=true gh_authed=true checked_at="; !strings.HasPrefix(stdout.String(), want) {
+		t.Errorf("stdout = %q, want prefix %q", stdout.String(), want)
+	}
+	if !strings.Contains(stderr.String(), "skipping path:0123abcd") {
+		t.Errorf("stderr = %q, want the missing repo skipped", stderr.String())
+	}
+
+	rec, ok, err := st.LoadRepoRecord(live.RepoID)
+	if err != nil || !ok || !rec.Capabilities.GhAuthed || rec.Capabilities.CheckedAt == "" {
+		t.Errorf("repo.json = %+v (ok %v, err %v), want refreshed capabilities", rec, ok, err)
+	}
+}
@@ -0,0 +1,295 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/git"
+	"github.com/NielsdaWheelz/agency/internal/identity"
+	"github.com/NielsdaWheelz/agency/internal/ids"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// runScope narrows run_id resolution to a repo.
+type runScope struct {
+	// RepoID restricts resolution to a single repo (--repo). Empty = all repos.
+	RepoID string
+
+	// cwdRepoID returns the repo_id of the repo containing cwd ("" if none).
+	// It is only called to break prefix ambiguity; nil disables the preference.
+	cwdRepoID func() string
+}
+
+// newRunScope returns the scope for resolving run ids from cwd.
+// A non-empty repoFlag (--repo) forces the scope to that repo; otherwise runs
+// in the repo containing cwd win when a prefix matches runs in several repos.
+func newRunScope(ctx context.Context, cr agencyexec.CommandRunner, dataDir, cwd, repoFlag string) (runScope, error) {
+	if repoFlag != "" {
+		repoID, err := resolveRepoFlag(ctx, cr, dataDir, repoFlag)
+		if err != nil {
+			return runScope{}, err
+		}
+		return runScope{RepoID: repoID}, nil
+	}
+	// Looked up at most once, and only if some input is ambiguous
+	var cwdRepoID *string
+	return runScope{cwdRepoID: func() string {
+		if cwdRepoID == nil {
+			id := repoIDForDir(ctx, cr, cwd)
+			cwdRepoID = &id
+		}
+		return *cwdRepoID
+	}}, nil
+}
+
+// repoIDForDir returns the repo_id of the git repo containing dir, or "".
+func repoIDForDir(ctx context.Context, cr agencyexec.CommandRunner, dir string) string {
+	if dir == "" {
+		return ""
+	}
+	root, err := git.GetRepoRoot(ctx, cr, dir)
+	if err != nil {
+		return ""
+	}
+	origin := git.GetOriginInfo(ctx, cr, root.Path)
+	return identity.DeriveRepoIdentity(root.Path, origin.URL).RepoID
+}
+
+// resolveRepoFlag maps a --repo value to a repo_id. The value may be a repo_id,
+// a repo_key from repo_index.json (e.g. github:owner/repo), or a path inside a
+// repo. Returns E_REPO_NOT_FOUND if none match a repo with agency data.
+func resolveRepoFlag(ctx context.Context, cr agencyexec.CommandRunner, dataDir, value string) (string, error) {
+	hasRuns := func(repoID string) bool {
+		info, err := os.Stat(filepath.Join(dataDir, "repos", repoID))
+		return err == nil && info.IsDir()
+	}
+
+	if !strings.ContainsAny(value, `/\`) && value != "." && value != ".." && hasRuns(value) {
+		return value, nil
+	}
+	if idx, _ := store.LoadRepoIndexForScan(dataDir); idx != nil {
+		if entry, ok := idx.Repos[value]; ok && entry.RepoID != "" {
+			return entry.RepoID, nil
+		}
+	}
+	if info, err := os.Stat(value); err == nil && info.IsDir() {
+		if abs, err := filepath.Abs(value); err == nil {
+			if repoID := repoIDForDir(ctx, cr, abs); repoID != "" && hasRuns(repoID) {
+				return repoID, nil
+			}
+		}
+	}
+
+	return "", errors.NewWithDetails(errors.ERepoNotFound,
+		"no agency repo matches --repo "+value+" (expected a repo_id, repo_key, or path inside a repo)",
+		map[string]string{"repo": value})
+}
+
+// resolveRunRef resolves input (exact or unique prefix) among records within scope.
+// Returns the raw *ids.ErrNotFound / *ids.ErrAmbiguous errors.
+func resolveRunRef(records []store.RunRecord, input string, scope runScope) (ids.RunRef, error) {
+	refs := make([]ids.RunRef, 0, len(records))
+	for _, rec := range records {
+		if scope.RepoID != "" && rec.RepoID != scope.RepoID {
+			continue
+		}
+		refs = append(refs, ids.RunRef{
+			RepoID: rec.RepoID,
+			RunID:  rec.RunID,
+			Broken: rec.Broken,
+		})
+	}
+
+	ref, err := ids.ResolveRunRef(input, refs)
+	if _, ok := err.(*ids.ErrAmbiguous); ok && scope.cwdRepoID != nil {
+		return ids.ResolveRunRefPreferRepo(input, refs, scope.cwdRepoID())
+	}
+	return ref, err
+}
+
+// ambiguousRunIDError converts an ambiguity into E_RUN_ID_AMBIGUOUS.
+func ambiguousRunIDError(ambErr *ids.ErrAmbiguous) error {
+	candidates := make([]string, len(ambErr.Candidates))
+	for i, c := range ambErr.Candidates {
+		candidates[i] = c.RunID
+	}
+	return errors.WithHints(errors.NewWithDetails(
+		errors.ERunIDAmbiguous,
+		"ambiguous run id '"+ambErr.Input+"' matches multiple runs: "+strings.Join(candidates, ", "),
+		map[string]string{"input": ambErr.Input},
+	), "use a longer prefix or --repo <repo>")
+}
+
+// brokenRunError is E_RUN_BROKEN for a run whose meta.json cannot be read.
+func brokenRunError(runID, metaPath string) error {
+	return errors.WithHints(errors.NewWithDetails(
+		errors.ERunBroken,
+		"run exists but meta.json is unreadable or invalid",
+		map[string]string{"run_id": runID, "meta_path": metaPath},
+	), "delete this run dir or fix meta.json")
+}
+
+// resolveRun resolves a run reference (exact or unique prefix) across all repos,
+// narrowed by scope, decrypting meta.json encrypted at rest with crypter, and
+// records the run for the command's audit entry (see audit.Touch).
+// Returns E_RUN_NOT_FOUND, E_RUN_ID_AMBIGUOUS, or E_RUN_BROKEN as appropriate.
+func resolveRun(dataDir, input string, scope runScope, crypter *crypt.Crypt) (*store.RunRecord, error) {
+	rec, err := lookupRun(dataDir, input, scope, crypter)
+	if err != nil {
+		return nil, err
+	}
+	audit.Touch(rec.RepoID, rec.RunID)
+	return rec, nil
+}
+
+// lookupRun is resolveRun without the audit record, for callers outside a
+// CLI command (which never drain the touched set).
+func lookupRun(dataDir, input string, scope runScope, crypter *crypt.Crypt) (*store.RunRecord, error) {
+	rec, err := findRun(dataDir, input, scope, crypter)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Broken {
+		metaPath := filepath.Join(rec.RunDir, "meta.json")
+		if rec.Sealed {
+			// Report why it could not be decrypted rather than suggest deleting it
+			if _, err := crypter.ReadFile(metaPath); err != nil {
+				return nil, err
+			}
+		}
+		return nil, brokenRunError(rec.RunID, metaPath)
+	}
+	return rec, nil
+}
+
+// findRun is resolveRun for commands that also handle broken runs: it
+// returns the record even if meta.json is unreadable.
+func findRun(dataDir, input string, scope runScope, crypter *crypt.Crypt) (*store.RunRecord, error) {
+	records, err := store.ScanAllRuns(dataDir, crypter)
+	if err != nil {
+		return nil, errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+
+	resolved, err := resolveRunRef(records, input, scope)
+	if err != nil {
+		if ambErr, ok := err.(*ids.ErrAmbiguous); ok {
+			return nil, ambiguousRunIDError(ambErr)
+		}
+		if _, ok := err.(*ids.ErrNotFound); ok {
+			return nil, errors.New(errors.ERunNotFound, "run not found: "+input)
+		}
+		return nil, err
+	}
+
+	for i := range records {
+		if records[i].RunID == resolved.RunID && records[i].RepoID == resolved.RepoID {
+			return &records[i], nil
+		}
+	}
+
+	// Should not happen if resolver worked correctly
+	return nil, errors.New(errors.EInternal, "resolved run not found in records")
+}
+
+// RunSelector picks a run by its branch (meta.branch) or pull request
+// number (meta.pr_number) instead of its run_id. At most one is set.
+type RunSelector struct {
+	Branch string
+	PR     int
+}
+
+// IsZero reports whether no selector is set.
+func (s RunSelector) IsZero() bool {
+	return s.Branch == "" && s.PR == 0
+}
+
+// String returns the selector as given on the command line.
+func (s RunSelector) String() string {
+	if s.Branch != "" {
+		return "--branch " + s.Branch
+	}
+	return "--pr " + strconv.Itoa(s.PR)
+}
+
+func (s RunSelector) matches(meta *store.RunMeta) bool {
+	if s.Branch != "" {
+		return meta.Branch == strings.TrimPrefix(s.Branch, "refs/heads/")
+	}
+	return meta.PRNumber == s.PR
+}
+
+// checkRunSelector requires exactly one of runID, sel.Branch, and sel.PR.
+func checkRunSelector(runID string, sel RunSelector) error {
+	if sel.PR < 0 {
+		return errors.New(errors.EUsage, "--pr must be a positive number")
+	}
+	given := 0
+	for _, set := range []bool{runID != "", sel.Branch != "", sel.PR != 0} {
+		if set {
+			given++
+		}
+	}
+	switch given {
+	case 0:
+		return errors.New(errors.EUsage, "run_id is required (or --branch <name> / --pr <number>)")
+	case 1:
+		return nil
+	}
+	return errors.New(errors.EUsage, "run_id, --branch, and --pr are mutually exclusive")
+}
+
+// resolveRunSelector finds the run whose meta.json matches sel among records
+// within scope. Broken runs never match. Ambiguity is handled like run_id
+// prefixes: runs in the cwd repo win, otherwise E_RUN_ID_AMBIGUOUS.
+func resolveRunSelector(records []store.RunRecord, sel RunSelector, scope runScope) (*store.RunRecord, error) {
+	var matches []*store.RunRecord
+	for i := range records {
+		rec := &records[i]
+		if rec.Meta == nil || (scope.RepoID != "" && rec.RepoID != scope.RepoID) {
+			continue
+		}
+		if sel.matches(rec.Meta) {
+			matches = append(matches, rec)
+		}
+	}
+
+	if len(matches) > 1 && scope.cwdRepoID != nil {
+		if cwdRepoID := scope.cwdRepoID(); cwdRepoID != "" {
+			var local []*store.RunRecord
+			for _, rec := range matches {
+				if rec.RepoID == cwdRepoID {
+					local = append(local, rec)
+				}
+			}
+			if len(local) > 0 {
+				matches = local
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.NewWithDetails(errors.ERunNotFound,
+			"no run matches "+sel.String(),
+			map[string]string{"input": sel.String()})
+	case 1:
+		return matches[0], nil
+	}
+	candidates := make([]string, len(matches))
+	for i, rec := range matches {
+		candidates[i] = fmt.Sprintf("%s (repo %s)", rec.RunID, rec.RepoID)
+	}
+	return nil, errors.WithHints(errors.NewWithDetails(
+		errors.ERunIDAmbiguous,
+		sel.String()+" matches multiple runs: "+strings.Join(candidates, ", "),
+		map[string]string{"input": sel.String()},
+	), "use a run_id or --repo <repo>")
+}
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// setupTwoRepoRuns creates runs in two repos whose run_ids share the prefix "20260110".
+func setupTwoRepoRuns(t *testing.T, dataDir string) {
+	t.Helper()
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	for _, r := range []struct{ repoID, runID string }{
+		{"repoaaaa", "20260110120000-a3f2"},
+		{"repobbbb", "20260110130000-b4c1"},
+	} {
+		worktree := filepath.Join(dataDir, "repos", r.repoID, "worktrees", r.runID)
+		createValidMetaForShow(t, dataDir, r.repoID, r.runID, worktree, created)
+	}
+}
+
+func TestResolveRun_PrefersCwdRepo(t *testing.T) {
+	dataDir := t.TempDir()
+	setupTwoRepoRuns(t, dataDir)
+
+	// Outside any repo the prefix stays ambiguous
+	_, err := resolveRun(dataDir, "20260110", runScope{cwdRepoID: func() string { return "" }}, nil)
+	if errors.GetCode(err) != errors.ERunIDAmbiguous {
+		t.Fatalf("code = %q, want %q", errors.GetCode(err), errors.ERunIDAmbiguous)
+	}
+
+	rec, err := resolveRun(dataDir, "20260110", runScope{cwdRepoID: func() string { return "repobbbb" }}, nil)
+	if err != nil {
+		t.Fatalf("resolveRun() error = %v", err)
+	}
+	if rec.RunID != "20260110130000-b4c1" {
+		t.Errorf("run_id = %q, want the cwd repo's run", rec.RunID)
+	}
+
+	// An explicit repo scope never consults cwd
+	rec, err = resolveRun(dataDir, "20260110", runScope{RepoID: "repoaaaa"}, nil)
+	if err != nil {
+		t.Fatalf("resolveRun() error = %v", err)
+	}
+	if rec.RunID != "20260110120000-a3f2" {
+		t.Errorf("run_id = %q, want repoaaaa's run", rec.RunID)
+	}
+	_, err = resolveRun(dataDir, "20260110130000-b4c1", runScope{RepoID: "repoaaaa"}, nil)
+	if errors.GetCode(err) != errors.ERunNotFound {
+		t.Errorf("code = %q, want %q for a run outside the scope", errors.GetCode(err), errors.ERunNotFound)
+	}
+}
+
+func TestResolveRun_EmbedderDoesNotTouch(t *testing.T) {
+	dataDir := t.TempDir()
+	setupTwoRepoRuns(t, dataDir)
+	audit.Touched()
+
+	if _, err := ResolveRun(dataDir, "20260110120000", "", nil); err != nil {
+		t.Fatalf("ResolveRun() error = %v", err)
+	}
+	if got := audit.Touched(); len(got) != 0 {
+		t.Errorf("ResolveRun() touched %v; only CLI commands record runs", got)
+	}
+
+	if _, err := resolveRun(dataDir, "20260110120000", runScope{}, nil); err != nil {
+		t.Fatalf("resolveRun() error = %v", err)
+	}
+	if got := audit.Touched(); len(got) != 1 || got[0].RunID != "20260110120000-a3f2" {
+		t.Errorf("resolveRun() touched %v, want the resolved run", got)
+	}
+}
+
+func TestResolveRunSelector(t *testing.T) {
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	a := testkit.NewRunMeta("repoaaaa", "20260110120000-a3f2", "/wt/a3f2", created)
+	a.PRNumber = 7
+	b := testkit.NewRunMeta("repobbbb", "20260110130000-b4c1", "/wt/b4c1", created)
+	b.PRNumber = 7
+	records := []store.RunRecord{
+		{RepoID: a.RepoID, RunID: a.RunID, Meta: a},
+		{RepoID: b.RepoID, RunID: b.RunID, Meta: b},
+		{RepoID: "repoaaaa", RunID: "20260110140000-dead", Broken: true},
+	}
+	noCwd := runScope{cwdRepoID: func() string { return "" }}
+
+	rec, err := resolveRunSelector(records, RunSelector{Branch: a.Branch}, noCwd)
+	if err != nil || rec.RunID != a.RunID {
+		t.Fatalf("--branch: rec=%v err=%v, want %s", rec, err, a.RunID)
+	}
+	if rec, err = resolveRunSelector(records, RunSelector{Branch: "refs/heads/" + b.Branch}, noCwd); err != nil || rec.RunID != b.RunID {
+		t.Errorf("--branch refs/heads/...: rec=%v err=%v, want %s", rec, err, b.RunID)
+	}
+
+	// PR numbers are per repo: ambiguous unless cwd or --repo picks one
+	_, err = resolveRunSelector(records, RunSelector{PR: 7}, noCwd)
+	if errors.GetCode(err) != errors.ERunIDAmbiguous {
+		t.Errorf("--pr 7 code = %q, want %q", errors.GetCode(err), errors.ERunIDAmbiguous)
+	}
+	if rec, err = resolveRunSelector(records, RunSelector{PR: 7}, runScope{cwdRepoID: func() string { return "repobbbb" }}); err != nil || rec.RunID != b.RunID {
+		t.Errorf("--pr 7 from repobbbb: rec=%v err=%v", rec, err)
+	}
+	if rec, err = resolveRunSelector(records, RunSelector{PR: 7}, runScope{RepoID: "repoaaaa"}); err != nil || rec.RunID != a.RunID {
+		t.Errorf("--pr 7 --repo repoaaaa: rec=%v err=%v", rec, err)
+	}
+
+	_, err = resolveRunSelector(records, RunSelector{PR: 8}, noCwd)
+	if errors.GetCode(err) != errors.ERunNotFound {
+		t.Errorf("--pr 8 code = %q, want %q", errors.GetCode(err), errors.ERunNotFound)
+	}
+
+	for _, tt := range []struct {
+		runID string
+		sel   RunSelector
+	}{
+		{"", RunSelector{}},
+		{"abc", RunSelector{PR: 1}},
+		{"", RunSelector{Branch: "x", PR: 1}},
+		{"", RunSelector{PR: -1}},
+	} {
+		if err := checkRunSelector(tt.runID, tt.sel); errors.GetCode(err) != errors.EUsage {
+			t.Errorf("checkRunSelector(%q, %+v) = %v, want E_USAGE", tt.runID, tt.sel, err)
+		}
+	}
+}
+
+func TestResolveRepoFlag(t *testing.T) {
+	dataDir := t.TempDir()
+	setupTwoRepoRuns(t, dataDir)
+	setupGCRepo(t, dataDir, "repobbbb", "github:owner/repo", `{"version": 1}`)
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"repoaaaa", "repoaaaa"},
+		{"github:owner/repo", "repobbbb"},
+	}
+	for _, tt := range tests {
+		got, err := resolveRepoFlag(context.Background(), nil, dataDir, tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("resolveRepoFlag(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+		}
+	}
+
+	_, err := resolveRepoFlag(context.Background(), nil, dataDir, "github:owner/other")
+	if errors.GetCode(err) != errors.ERepoNotFound {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.ERepoNotFound)
+	}
+}
+
+func TestNote_RepoFlagDisambiguates(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	setupTwoRepoRuns(t, dataDir)
+
+	var stdout, stderr bytes.Buffer
+	opts := NoteOpts{RunID: "20260110", Text: "scoped", Repo: "repobbbb"}
+	if err := Note(context.Background(), nil, fs.NewRealFS(), dataDir, opts, &stdout, &stderr); err != nil {
+		t.Fatalf("Note() error = %v", err)
+	}
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, nil)
+	notes, err := st.ReadNotes("repobbbb", "20260110130000-b4c1")
+	if err != nil {
+		t.Fatalf("ReadNotes() error = %v", err)
+	}
+	if len(notes) != 1 || notes[0].Text != "scoped" {
+		t.Errorf("notes = %+v, want single scoped note", notes)
+	}
+}
@@ -7,21 +7,29 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/git"
 	"github.com/NielsdaWheelz/agency/internal/identity"
-	"github.com/NielsdaWheelz/agency/internal/paths"
 	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/render"
 	"github.com/NielsdaWheelz/agency/internal/runservice"
 	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/worktree"
 )
 
 // RunOpts holds options for the run command.
 type RunOpts struct {
-	// Title is the run title (empty = use default).
+	// Title is the run title (empty = use default). Placeholders such as
+	// {date} and {ticket} are expanded when the run is created.
 	Title string
 
 	// Runner is the runner name (empty = use agency.json default).
@@ -32,6 +40,61 @@ type RunOpts struct {
 
 	// Attach indicates whether to attach after tmux creation.
 	Attach bool
+
+	// RunID is an externally supplied run_id (empty = generate one).
+	RunID string
+
+	// Labels are raw key=value arguments (repeatable --label).
+	Labels []string
+
+	// Group adds the run to a named group (see agency group).
+	Group string
+
+	// Deadline time-boxes the run (0 = agency.json defaults.deadline).
+	Deadline time.Duration
+
+	// NoDeadline ignores defaults.deadline.
+	NoDeadline bool
+
+	// DeadlineKill kills the tmux session when the deadline passes.
+	DeadlineKill bool
+
+	// SparseProfile selects an agency.json worktrees.sparse_profiles entry
+	// ("none" = full checkout, "" = worktrees.sparse_checkout).
+	SparseProfile string
+
+	// NoSetup skips the setup script (and its hooks).
+	NoSetup bool
+
+	// ForceSetup runs the setup script even when agency.json setup_cache
+	// has an entry for the current inputs.
+	ForceSetup bool
+
+	// NoTmux skips starting the tmux session (and its hook); the run is
+	// left idle for 'agency attach --start'.
+	NoTmux bool
+
+	// JSON prints the success summary as a run --json envelope
+	// (warnings included) instead of key: value lines.
+	JSON bool
+
+	// DryRun resolves and prints the run's names (title, branch slug,
+	// worktree) after the repo and config checks, without creating anything.
+	DryRun bool
+
+	// Runners launches one run per runner, in parallel, linked by a shared
+	// meta.task_id (see runTask). Cannot be combined with Runner.
+	Runners []string
+
+	// MaxSessions, MinFreeDiskMB and MaxLoad override config.json
+	// scheduling.* for this run (nil = use config.json; 0 = no limit).
+	MaxSessions   *int
+	MinFreeDiskMB *int
+	MaxLoad       *float64
+
+	// NoWait fails with E_SCHEDULE_BLOCKED instead of queueing the run
+	// when a scheduling limit is reached.
+	NoWait bool
 }
 
 // RunResult holds the result of a successful run for output formatting.
@@ -43,41 +106,77 @@ type RunResult struct {
 	Branch          string
 	WorktreePath    string
 	TmuxSessionName string
+	SkippedSteps    []string
 	Warnings        []pipeline.Warning
 }
 
 // Run executes the agency run command.
-// Creates a workspace, runs setup, starts tmux session.
+// Creates a workspace, runs setup, starts tmux session. When a scheduling
+// limit is reached, the run is queued before its worktree is created (see
+// runScheduler).
 func Run(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts RunOpts, stdout, stderr io.Writer) error {
-	// Create the run service with production dependencies
-	svc := runservice.New()
+	labels, err := core.ParseLabels(opts.Labels)
+	if err != nil {
+		return errors.Wrap(errors.EUsage, "invalid --label", err)
+	}
+	if _, err := core.ParseTemplate(opts.Title); err != nil {
+		return errors.Wrap(errors.EUsage, "invalid --title", err)
+	}
+	if opts.Group != "" {
+		if err := core.ValidateGroupName(opts.Group); err != nil {
+			return errors.Wrap(errors.EUsage, "invalid --group", err)
+		}
+	}
+	if opts.Attach && opts.NoTmux {
+		return errors.New(errors.EUsage, "--attach cannot be combined with --no-tmux")
+	}
+	if opts.JSON && (opts.Attach || opts.DryRun) {
+		return errors.New(errors.EUsage, "--json cannot be combined with --attach or --dry-run")
+	}
+	pipelineOpts := runPipelineOpts(opts, labels, cwd)
+	if len(opts.Runners) > 0 {
+		return runTask(ctx, cr, fsys, cwd, opts, pipelineOpts, stdout, stderr)
+	}
 
-	// Create the pipeline
-	p := pipeline.NewPipeline(svc)
+	crypter := LoadCrypt(cr, fsys)
+	p := newRunPipeline(crypter)
 
-	// Execute the pipeline
-	pipelineOpts := pipeline.RunPipelineOpts{
-		Title:  opts.Title,
-		Runner: opts.Runner,
-		Parent: opts.Parent,
-		Attach: opts.Attach,
+	if opts.DryRun {
+		return runDryRun(ctx, p, pipelineOpts, cwd, fsys, crypter, stdout, stderr)
 	}
 
-	runID, err := p.Run(ctx, pipelineOpts)
+	sched, err := newRunScheduler(cr, fsys, opts, stderr)
+	if err != nil {
+		return err
+	}
+
+	st, err := p.Execute(ctx, pipelineOpts, sched.steps(pipeline.RunSteps(pipelineOpts)))
+	sched.release(st)
+	if st != nil && st.RunID != "" {
+		audit.Touch(st.RepoID, st.RunID)
+	}
 	if err != nil {
 		// Print error details for failures after worktree creation
-		printRunError(stderr, err, runID, cwd, fsys)
+		runID := ""
+		if st != nil {
+			runID = st.RunID
+		}
+		printRunError(stderr, err, runID, cwd, fsys, crypter)
 		return err
 	}
 
 	// Get final state from metadata
-	result, err := getRunResult(ctx, cr, fsys, cwd, runID)
+	result, err := getRunResult(ctx, cr, fsys, cwd, st.RunID, crypter)
 	if err != nil {
 		// Pipeline succeeded but couldn't read result - internal error
 		return errors.Wrap(errors.EInternal, "failed to read run result", err)
 	}
+	result.Warnings = st.Warnings
 
 	// Print success output
+	if opts.JSON {
+		return writeRunJSON(stdout, result)
+	}
 	printRunSuccess(stdout, result)
 
 	// Print warnings to stderr
@@ -93,8 +192,85 @@ func Run(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd strin
 	return nil
 }
 
+// runPipelineOpts maps run options onto the pipeline's.
+func runPipelineOpts(opts RunOpts, labels map[string]string, cwd string) pipeline.RunPipelineOpts {
+	return pipeline.RunPipelineOpts{
+		Title:  opts.Title,
+		Runner: opts.Runner,
+		Parent: opts.Parent,
+		Attach: opts.Attach,
+		RunID:  opts.RunID,
+		Labels: labels,
+		Group:  opts.Group,
+		Dir:    cwd,
+
+		Deadline:     opts.Deadline,
+		NoDeadline:   opts.NoDeadline,
+		DeadlineKill: opts.DeadlineKill,
+
+		SparseProfile: opts.SparseProfile,
+		NoSetup:       opts.NoSetup,
+		ForceSetup:    opts.ForceSetup,
+		NoTmux:        opts.NoTmux,
+	}
+}
+
+// newRunPipeline returns a run pipeline with production dependencies.
+func newRunPipeline(crypter *crypt.Crypt) *pipeline.Pipeline {
+	svc := runservice.New()
+	svc.SetCrypt(crypter)
+	svc.SetNowFunc(clock.Now)
+	p := pipeline.NewPipeline(svc)
+	p.SetClock(clock)
+	return p
+}
+
+// runDryRun runs the pipeline's side-effect-free steps and prints the names
+// the run would get, so slug rules can be checked before creating anything.
+func runDryRun(ctx context.Context, p *pipeline.Pipeline, opts pipeline.RunPipelineOpts, cwd string, fsys fs.FS, crypter *crypt.Crypt, stdout, stderr io.Writer) error {
+	st, err := p.Prepare(ctx, opts)
+	if err != nil {
+		printRunError(stderr, err, "", cwd, fsys, crypter)
+		return err
+	}
+
+	title, branch, worktreePath := worktree.Names(worktree.CreateOpts{
+		RunID:   st.RunID,
+		Title:   st.Title,
+		RepoID:  st.RepoID,
+		DataDir: st.DataDir,
+		Slug:    st.Slug,
+	})
+	fmt.Fprintln(stdout, "dry_run: true")
+	fmt.Fprintf(stdout, "run_id: %s\n", st.RunID)
+	fmt.Fprintf(stdout, "title: %s\n", title)
+	fmt.Fprintf(stdout, "runner: %s\n", st.Runner)
+	fmt.Fprintf(stdout, "parent: %s\n", st.ParentBranch)
+	fmt.Fprintf(stdout, "slug: %s\n", st.Slug.Slug(title))
+	fmt.Fprintf(stdout, "branch: %s\n", branch)
+	fmt.Fprintf(stdout, "worktree: %s\n", worktreePath)
+	if st.Deadline > 0 {
+		deadline := "deadline: " + st.Deadline.String()
+		if st.DeadlineKill {
+			deadline += " (kill)"
+		}
+		fmt.Fprintln(stdout, deadline)
+	}
+	if len(st.SparseCheckout) > 0 {
+		fmt.Fprintf(stdout, "sparse_checkout: %s\n", strings.Join(st.SparseCheckout, " "))
+	}
+	if len(st.SkippedSteps) > 0 {
+		fmt.Fprintf(stdout, "skipped: %s\n", strings.Join(st.SkippedSteps, ", "))
+	}
+
+	for _, w := range st.Warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w.Message)
+	}
+	return nil
+}
+
 // getRunResult reads the run metadata and constructs the result.
-func getRunResult(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, runID string) (*RunResult, error) {
+func getRunResult(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, runID string, crypter *crypt.Crypt) (*RunResult, error) {
 	// Resolve repo root
 	repoRoot, err := git.GetRepoRoot(ctx, cr, cwd)
 	if err != nil {
@@ -104,12 +280,10 @@ func getRunResult(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS,
 	// Get origin info for repo identity
 	originInfo := git.GetOriginInfo(ctx, cr, repoRoot.Path)
 
-	// Resolve data directory
-	homeDir, err := os.UserHomeDir()
+	dirs, err := resolveDirs(fsys, repoRoot.Path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
-	dirs := paths.ResolveDirs(osEnv{}, homeDir)
 	dataDir := dirs.DataDir
 
 	// Compute repo identity
@@ -117,7 +291,7 @@ func getRunResult(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS,
 	repoID := repoIdentity.RepoID
 
 	// Create store and read meta
-	st := store.NewStore(fsys, dataDir, nil)
+	st := store.NewStore(fsys, dataDir, nil).WithCrypt(crypter)
 	meta, err := st.ReadMeta(repoID, runID)
 	if err != nil {
 		return nil, err
@@ -131,10 +305,12 @@ func getRunResult(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS,
 		Branch:          meta.Branch,
 		WorktreePath:    meta.WorktreePath,
 		TmuxSessionName: meta.TmuxSessionName,
+		SkippedSteps:    meta.SkippedSteps,
 	}, nil
 }
 
-// printRunSuccess prints the success output in the required format.
+// printRunSuccess prints the success output in the required format:
+// key: value lines, then one "next:" line per suggested command.
 func printRunSuccess(w io.Writer, result *RunResult) {
 	fmt.Fprintf(w, "run_id: %s\n", result.RunID)
 	fmt.Fprintf(w, "title: %s\n", result.Title)
@@ -142,12 +318,78 @@ func printRunSuccess(w io.Writer, result *RunResult) {
 	fmt.Fprintf(w, "parent: %s\n", result.Parent)
 	fmt.Fprintf(w, "branch: %s\n", result.Branch)
 	fmt.Fprintf(w, "worktree: %s\n", result.WorktreePath)
-	fmt.Fprintf(w, "tmux: %s\n", result.TmuxSessionName)
-	fmt.Fprintf(w, "next: agency attach %s\n", result.RunID)
+	if len(result.SkippedSteps) > 0 {
+		fmt.Fprintf(w, "skipped: %s\n", strings.Join(result.SkippedSteps, ", "))
+	}
+	if result.TmuxSessionName == "" {
+		fmt.Fprintf(w, "tmux: none\n")
+	} else {
+		fmt.Fprintf(w, "tmux: %s\n", result.TmuxSessionName)
+		fmt.Fprintf(w, "attach: %s\n", tmuxAttachCommand(result.TmuxSessionName))
+	}
+	for _, step := range runNextSteps(result) {
+		fmt.Fprintf(w, "next: %s\n", step.Command)
+	}
+}
+
+// tmuxAttachCommand is the plain tmux command attaching to sessionName.
+func tmuxAttachCommand(sessionName string) string {
+	return "tmux attach -t " + sessionName
+}
+
+// runNextSteps suggests follow-up commands for a new run.
+func runNextSteps(result *RunResult) []render.NextStep {
+	var steps []render.NextStep
+	if result.TmuxSessionName == "" {
+		steps = append(steps, render.NextStep{
+			Command:     "agency attach --start " + result.RunID,
+			Description: "start the runner in a tmux session and attach",
+		})
+	} else {
+		steps = append(steps, render.NextStep{
+			Command:     "agency attach " + result.RunID,
+			Description: "attach to the runner session",
+		})
+	}
+	steps = append(steps, render.NextStep{
+		Command:     "agency show " + result.RunID,
+		Description: "show run details and status",
+	})
+	if !slices.Contains(result.SkippedSteps, pipeline.SkipSetup) {
+		steps = append(steps, render.NextStep{
+			Command:     "agency logs " + result.RunID + " setup",
+			Description: "print the setup script log",
+		})
+	}
+	return steps
+}
+
+// writeRunJSON writes the success output as a run --json envelope.
+func writeRunJSON(w io.Writer, result *RunResult) error {
+	out := render.RunCreated{
+		RunID:        result.RunID,
+		Title:        result.Title,
+		Runner:       result.Runner,
+		Parent:       result.Parent,
+		Branch:       result.Branch,
+		WorktreePath: result.WorktreePath,
+		SkippedSteps: result.SkippedSteps,
+		Next:         runNextSteps(result),
+	}
+	if result.TmuxSessionName != "" {
+		session := result.TmuxSessionName
+		attach := tmuxAttachCommand(session)
+		out.TmuxSession = &session
+		out.AttachCommand = &attach
+	}
+	for _, w := range result.Warnings {
+		out.Warnings = append(out.Warnings, render.RunWarning{Code: w.Code, Message: w.Message})
+	}
+	return render.WriteRunCreatedJSON(w, out)
 }
 
 // printRunError prints error details for run failures.
-func printRunError(w io.Writer, err error, runID string, cwd string, fsys fs.FS) {
+func printRunError(w io.Writer, err error, runID string, cwd string, fsys fs.FS, crypter *crypt.Crypt) {
 	ae, ok := errors.AsAgencyError(err)
 	if !ok {
 		fmt.Fprintf(w, "error: %s\n", err.Error())
@@ -174,14 +416,14 @@ func printRunError(w io.Writer, err error, runID string, cwd string, fsys fs.FS)
 
 	// Try to get worktree path from meta if we have a run_id
 	if runID != "" && ae.Details["worktree_path"] == "" {
-		if result, err := tryGetRunMeta(cwd, runID, fsys); err == nil {
+		if result, err := tryGetRunMeta(cwd, runID, fsys, crypter); err == nil {
 			fmt.Fprintf(w, "worktree: %s\n", result.WorktreePath)
 		}
 	}
 }
 
 // tryGetRunMeta attempts to read run metadata for error reporting.
-func tryGetRunMeta(cwd, runID string, fsys fs.FS) (*store.RunMeta, error) {
+func tryGetRunMeta(cwd, runID string, fsys fs.FS, crypter *crypt.Crypt) (*store.RunMeta, error) {
 	// Get repo root using direct git command (simpler path for error handling)
 	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
 	cmd.Dir = cwd
@@ -206,16 +448,14 @@ func tryGetRunMeta(cwd, runID string, fsys fs.FS) (*store.RunMeta, error) {
 	repoIdentity := identity.DeriveRepoIdentity(repoRoot, originURL)
 	repoID := repoIdentity.RepoID
 
-	// Resolve data directory
-	homeDir, err := os.UserHomeDir()
+	dirs, err := resolveDirs(fsys, repoRoot)
 	if err != nil {
 		return nil, err
 	}
-	dirs := paths.ResolveDirs(osEnv{}, homeDir)
 	dataDir := dirs.DataDir
 
 	// Read meta
-	st := store.NewStore(fsys, dataDir, nil)
+	st := store.NewStore(fsys, dataDir, nil).WithCrypt(crypter)
 	return st.ReadMeta(repoID, runID)
 }
 
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/paths"
+	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/schedule"
+)
+
+// stepWaitForSlot names the step holding a run in the queue, and
+// stepReleaseSlot the step giving its slot back once its session exists.
+const (
+	stepWaitForSlot = "WaitForSlot"
+	stepReleaseSlot = "ReleaseSlot"
+)
+
+// runScheduler holds new runs back until config.json scheduling.* (or the
+// run's --max-* flags) allow them to start; see schedule.Queue.
+type runScheduler struct {
+	limits schedule.Limits
+	noWait bool
+	probe  schedule.Probe
+	stderr io.Writer
+
+	mu       sync.Mutex
+	releases map[*pipeline.PipelineState]func()
+}
+
+// newRunScheduler returns the scheduler for opts: config.json scheduling.*
+// overridden by the flags opts sets.
+// Returns E_USAGE for a negative flag value, and E_INVALID_USER_CONFIG if
+// config.json is invalid.
+func newRunScheduler(cr agencyexec.CommandRunner, fsys fs.FS, opts RunOpts, stderr io.Writer) (*runScheduler, error) {
+	if (opts.MaxSessions != nil && *opts.MaxSessions < 0) || (opts.MinFreeDiskMB != nil && *opts.MinFreeDiskMB < 0) || (opts.MaxLoad != nil && *opts.MaxLoad < 0) {
+		return nil, errors.New(errors.EUsage, "--max-sessions, --min-free-disk-mb and --max-load must not be negative")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, errors.Wrap(errors.EInternal, "failed to get home directory", err)
+	}
+	cfg, err := config.LoadUserConfig(fsys, paths.ResolveDirs(osEnv{}, homeDir).ConfigDir)
+	if err != nil {
+		return nil, err
+	}
+	limits := schedule.Limits{
+		MaxSessions:   cfg.Scheduling.MaxSessions,
+		MinFreeDiskMB: cfg.Scheduling.MinFreeDiskMB,
+		MaxLoad:       cfg.Scheduling.MaxLoad,
+	}
+	if opts.MaxSessions != nil {
+		limits.MaxSessions = *opts.MaxSessions
+	}
+	if opts.MinFreeDiskMB != nil {
+		limits.MinFreeDiskMB = *opts.MinFreeDiskMB
+	}
+	if opts.MaxLoad != nil {
+		limits.MaxLoad = *opts.MaxLoad
+	}
+	return &runScheduler{
+		limits:   limits,
+		noWait:   opts.NoWait,
+		probe:    schedule.NewProbe(cr),
+		stderr:   stderr,
+		releases: map[*pipeline.PipelineState]func(){},
+	}, nil
+}
+
+// steps returns steps with a step waiting for the limits inserted before
+// CreateWorktree, once the run's data dir and title are known, and one
+// releasing the run's slot after StartTmux, when its session counts itself.
+// Without limits, steps are returned unchanged.
+func (s *runScheduler) steps(steps []pipeline.Step) []pipeline.Step {
+	if s.limits.IsZero() {
+		return steps
+	}
+	wait := pipeline.Step{Name: stepWaitForSlot, Run: func(ctx context.Context, _ pipeline.RunService, st *pipeline.PipelineState) error {
+		return s.wait(ctx, st)
+	}}
+	release := pipeline.Step{Name: stepReleaseSlot, Run: func(_ context.Context, _ pipeline.RunService, st *pipeline.PipelineState) error {
+		s.release(st)
+		return nil
+	}}
+	var out []pipeline.Step
+	for _, step := range steps {
+		if step.Name == pipeline.StepCreateWorktree {
+			out = append(out, wait)
+		}
+		out = append(out, step)
+		if step.Name == pipeline.StepStartTmux {
+			out = append(out, release)
+		}
+	}
+	return out
+}
+
+// wait holds st's run in the queue until it may start, printing
+// "queued: <run_id>: waiting for <reason>" to stderr while it waits.
+func (s *runScheduler) wait(ctx context.Context, st *pipeline.PipelineState) error {
+	queued := false
+	notify := func(reason string) {
+		queued = true
+		fmt.Fprintf(s.stderr, "queued: %s: waiting for %s\n", st.RunID, reason)
+	}
+	release, err := schedule.NewQueue(st.DataDir, s.probe).Wait(ctx,
+		schedule.Request{RunID: st.RunID, RepoID: st.RepoID, Title: st.Title}, s.limits, s.noWait, notify)
+	if err != nil {
+		return err
+	}
+	if queued {
+		fmt.Fprintf(s.stderr, "starting: %s\n", st.RunID)
+	}
+	s.mu.Lock()
+	s.releases[st] = release
+	s.mu.Unlock()
+	return nil
+}
+
+// release removes st's run from the queue: after StartTmux, or once its
+// pipeline has ended (without a session, or failed). Releasing twice is a
+// no-op.
+func (s *runScheduler) release(st *pipeline.PipelineState) {
+	s.mu.Lock()
+	release := s.releases[st]
+	delete(s.releases, st)
+	s.mu.Unlock()
+	if release != nil {
+		release()
+	}
+}
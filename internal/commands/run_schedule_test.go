@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/schedule"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestNewRunScheduler(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("AGENCY_CONFIG_DIR", configDir)
+	config := `{"scheduling": {"max_sessions": 8, "min_free_disk_mb": 2048}}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	noDisk, load := 0, 4.5
+	s, err := newRunScheduler(testkit.NewFakeRunner(), fs.NewRealFS(), RunOpts{MinFreeDiskMB: &noDisk, MaxLoad: &load}, io.Discard)
+	if err != nil {
+		t.Fatalf("newRunScheduler() error = %v", err)
+	}
+	want := schedule.Limits{MaxSessions: 8, MaxLoad: 4.5}
+	if s.limits != want {
+		t.Errorf("limits = %+v, want %+v", s.limits, want)
+	}
+
+	negative := -1
+	_, err = newRunScheduler(testkit.NewFakeRunner(), fs.NewRealFS(), RunOpts{MaxSessions: &negative}, io.Discard)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("negative --max-sessions: code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
+
+func TestRunScheduler(t *testing.T) {
+	steps := pipeline.RunSteps(pipeline.RunPipelineOpts{})
+	if got := (&runScheduler{}).steps(steps); len(got) != len(steps) {
+		t.Errorf("steps without limits = %d, want %d", len(got), len(steps))
+	}
+
+	var stderr bytes.Buffer
+	s := &runScheduler{
+		limits: schedule.Limits{MaxSessions: 1},
+		noWait: true,
+		probe: schedule.Probe{
+			Sessions: func(context.Context) (int, error) { return 1, nil },
+		},
+		stderr:   &stderr,
+		releases: map[*pipeline.PipelineState]func(){},
+	}
+	var names []string
+	var releaseStep pipeline.Step
+	for _, step := range s.steps(steps) {
+		names = append(names, step.Name)
+		if step.Name == stepReleaseSlot {
+			releaseStep = step
+		}
+	}
+	if i := strings.Index(strings.Join(names, " "), stepWaitForSlot+" "+pipeline.StepCreateWorktree); i < 0 {
+		t.Errorf("steps = %v, want %s before %s", names, stepWaitForSlot, pipeline.StepCreateWorktree)
+	}
+	if !strings.HasSuffix(strings.Join(names, " "), pipeline.StepStartTmux+" "+stepReleaseSlot) {
+		t.Errorf("steps = %v, want %s after %s", names, stepReleaseSlot, pipeline.StepStartTmux)
+	}
+
+	st := &pipeline.PipelineState{RunID: "r1", DataDir: t.TempDir()}
+	err := s.wait(context.Background(), st)
+	if errors.GetCode(err) != errors.EScheduleBlocked {
+		t.Fatalf("wait() code = %q, want %q", errors.GetCode(err), errors.EScheduleBlocked)
+	}
+
+	s.limits.MaxSessions = 2
+	if err := s.wait(context.Background(), st); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+	entry := filepath.Join(st.DataDir, "queue", "r1.json")
+	if _, err := os.Stat(entry); err != nil {
+		t.Errorf("queue entry missing while starting: %v", err)
+	}
+	if err := releaseStep.Run(context.Background(), nil, st); err != nil {
+		t.Fatalf("%s error = %v", stepReleaseSlot, err)
+	}
+	if _, err := os.Stat(entry); !os.IsNotExist(err) {
+		t.Errorf("queue entry kept after %s: %v", stepReleaseSlot, err)
+	}
+	s.release(st)  // again when the pipeline ends
+	s.release(nil) // a pipeline that failed before a run_id
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/pipeline"
+)
+
+// validateRunners checks the --runners list: at least two distinct, non-empty
+// runner names, and none of the single-run options.
+func validateRunners(opts RunOpts) error {
+	if opts.Runner != "" {
+		return errors.New(errors.EUsage, "--runners cannot be combined with --runner")
+	}
+	if opts.RunID != "" {
+		return errors.New(errors.EUsage, "--runners cannot be combined with --run-id")
+	}
+	if opts.Attach || opts.DryRun || opts.JSON {
+		return errors.New(errors.EUsage, "--runners cannot be combined with --attach, --dry-run or --json")
+	}
+	seen := map[string]bool{}
+	for _, r := range opts.Runners {
+		if r == "" {
+			return errors.New(errors.EUsage, "invalid --runners: empty runner name")
+		}
+		if seen[r] {
+			return errors.New(errors.EUsage, "invalid --runners: duplicate runner "+r)
+		}
+		seen[r] = true
+	}
+	if len(opts.Runners) < 2 {
+		return errors.New(errors.EUsage, "--runners needs at least two runners; use --runner for one")
+	}
+	return nil
+}
+
+// runOutcome is the result of one run of a task.
+type runOutcome struct {
+	st  *pipeline.PipelineState
+	err error
+}
+
+// runTask launches the same run once per runner in opts.Runners, linked by a
+// new task id stored under meta.task_id, so runners can be compared on one
+// task. Every runner is checked first (as with --dry-run), so a typo creates
+// nothing; the runs are then created in parallel, each queued on its own
+// when a scheduling limit is reached. A run that fails does not
+// stop the others; the first failure is returned after all have finished.
+func runTask(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts RunOpts, base pipeline.RunPipelineOpts, stdout, stderr io.Writer) error {
+	if err := validateRunners(opts); err != nil {
+		return err
+	}
+	sched, err := newRunScheduler(cr, fsys, opts, stderr)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	for _, runner := range opts.Runners {
+		runOpts := base
+		runOpts.Runner = runner
+		if _, err := newRunPipeline(crypter).Prepare(ctx, runOpts); err != nil {
+			fmt.Fprintf(stderr, "runner: %s\n", runner)
+			printRunError(stderr, err, "", cwd, fsys, crypter)
+			return err
+		}
+	}
+
+	taskID, err := core.NewRunID(clock.Now())
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to generate task id", err)
+	}
+	base.TaskID = taskID
+
+	outcomes := make([]runOutcome, len(opts.Runners))
+	var wg sync.WaitGroup
+	for i, runner := range opts.Runners {
+		runOpts := base
+		runOpts.Runner = runner
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			st, err := newRunPipeline(crypter).Execute(ctx, runOpts, sched.steps(pipeline.RunSteps(runOpts)))
+			sched.release(st)
+			outcomes[i] = runOutcome{st: st, err: err}
+		}(i)
+	}
+	wg.Wait()
+
+	fmt.Fprintf(stdout, "task_id: %s\n", taskID)
+	var firstErr error
+	succeeded := 0
+	for i, runner := range opts.Runners {
+		out := outcomes[i]
+		runID := ""
+		if out.st != nil {
+			runID = out.st.RunID
+		}
+		if runID != "" {
+			audit.Touch(out.st.RepoID, runID)
+		}
+		if out.err != nil {
+			fmt.Fprintf(stderr, "runner: %s\n", runner)
+			printRunError(stderr, out.err, runID, cwd, fsys, crypter)
+			if firstErr == nil {
+				firstErr = out.err
+			}
+			continue
+		}
+		result, err := getRunResult(ctx, cr, fsys, cwd, runID, crypter)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrap(errors.EInternal, "failed to read run result", err)
+			}
+			continue
+		}
+		succeeded++
+		fmt.Fprintln(stdout)
+		printRunSuccess(stdout, result)
+		for _, w := range out.st.Warnings {
+			fmt.Fprintf(stderr, "warning: %s: %s\n", runner, w.Message)
+		}
+	}
+
+	if succeeded > 0 {
+		fmt.Fprintln(stdout)
+		fmt.Fprintf(stdout, "next: agency ls --task %s\n", taskID)
+	}
+	if succeeded == 2 {
+		fmt.Fprintf(stdout, "next: agency compare --task %s\n", taskID)
+	}
+	return firstErr
+}
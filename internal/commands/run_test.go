@@ -2,9 +2,13 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/NielsdaWheelz/agency/internal/errors"
 	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/render"
 )
 
 func TestPrintRunSuccess(t *testing.T) {
@@ -31,7 +35,10 @@ parent: main
 branch: agency/test-run-a3f2
 worktree: /path/to/worktree
 tmux: agency_20260110120000-a3f2
+attach: tmux attach -t agency_20260110120000-a3f2
 next: agency attach 20260110120000-a3f2
+next: agency show 20260110120000-a3f2
+next: agency logs 20260110120000-a3f2 setup
 `,
 		},
 		{
@@ -52,7 +59,33 @@ parent: develop
 branch: agency/untitled-b4c5
 worktree: /tmp/worktree
 tmux: agency_20260110130000-b4c5
+attach: tmux attach -t agency_20260110130000-b4c5
 next: agency attach 20260110130000-b4c5
+next: agency show 20260110130000-b4c5
+next: agency logs 20260110130000-b4c5 setup
+`,
+		},
+		{
+			name: "no setup, no tmux",
+			result: &RunResult{
+				RunID:        "20260110140000-c6d7",
+				Title:        "review only",
+				Runner:       "claude",
+				Parent:       "main",
+				Branch:       "agency/review-only-c6d7",
+				WorktreePath: "/tmp/worktree",
+				SkippedSteps: []string{pipeline.SkipSetup, pipeline.SkipTmux},
+			},
+			expected: `run_id: 20260110140000-c6d7
+title: review only
+runner: claude
+parent: main
+branch: agency/review-only-c6d7
+worktree: /tmp/worktree
+skipped: setup, tmux
+tmux: none
+next: agency attach --start 20260110140000-c6d7
+next: agency show 20260110140000-c6d7
 `,
 		},
 	}
@@ -77,7 +110,8 @@ func TestPrintRunSuccessOrderAndKeys(t *testing.T) {
 	// 5. branch
 	// 6. worktree
 	// 7. tmux
-	// 8. next
+	// 8. attach
+	// 9. next
 
 	result := &RunResult{
 		RunID:           "id",
@@ -100,6 +134,7 @@ func TestPrintRunSuccessOrderAndKeys(t *testing.T) {
 		"branch:",
 		"worktree:",
 		"tmux:",
+		"attach:",
 		"next:",
 	}
 
@@ -138,6 +173,53 @@ func TestRunResultWarnings(t *testing.T) {
 	}
 }
 
+func TestWriteRunJSON(t *testing.T) {
+	result := &RunResult{
+		RunID:           "20260110120000-a3f2",
+		Title:           "test run",
+		Runner:          "claude",
+		Parent:          "main",
+		Branch:          "agency/test-run-a3f2",
+		WorktreePath:    "/path/to/worktree",
+		TmuxSessionName: "agency_20260110120000-a3f2",
+		Warnings:        []pipeline.Warning{{Code: "W_TEST", Message: "test warning"}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeRunJSON(&buf, result); err != nil {
+		t.Fatalf("writeRunJSON() error = %v", err)
+	}
+	var env render.RunCreatedJSONEnvelope
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if env.SchemaVersion != "1.0" || env.Data.RunID != result.RunID {
+		t.Errorf("envelope = %+v", env)
+	}
+	if env.Data.AttachCommand == nil || *env.Data.AttachCommand != "tmux attach -t agency_20260110120000-a3f2" {
+		t.Errorf("attach_command = %v", env.Data.AttachCommand)
+	}
+	if len(env.Data.Warnings) != 1 || env.Data.Warnings[0].Code != "W_TEST" {
+		t.Errorf("warnings = %+v", env.Data.Warnings)
+	}
+	if len(env.Data.Next) != 3 || env.Data.Next[0].Command != "agency attach 20260110120000-a3f2" {
+		t.Errorf("next = %+v", env.Data.Next)
+	}
+
+	// Without tmux: null session, attach --start hint, and [] rather than null
+	buf.Reset()
+	result.TmuxSessionName = ""
+	result.Warnings = nil
+	if err := writeRunJSON(&buf, result); err != nil {
+		t.Fatalf("writeRunJSON() error = %v", err)
+	}
+	for _, want := range []string{`"tmux_session": null`, `"warnings": []`, `"skipped_steps": []`, `"agency attach --start 20260110120000-a3f2"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %s:\n%s", want, buf.String())
+		}
+	}
+}
+
 func TestRunOptsDefaults(t *testing.T) {
 	// Test that empty opts are valid (defaults come from agency.json)
 	opts := RunOpts{}
@@ -177,3 +259,28 @@ func TestRunOptsWithValues(t *testing.T) {
 		t.Error("expected attach=true")
 	}
 }
+
+func TestValidateRunners(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RunOpts
+		ok   bool
+	}{
+		{"pair", RunOpts{Runners: []string{"claude", "codex"}}, true},
+		{"one runner", RunOpts{Runners: []string{"claude"}}, false},
+		{"duplicate", RunOpts{Runners: []string{"claude", "claude"}}, false},
+		{"empty name", RunOpts{Runners: []string{"claude", ""}}, false},
+		{"with --runner", RunOpts{Runners: []string{"claude", "codex"}, Runner: "claude"}, false},
+		{"with --run-id", RunOpts{Runners: []string{"claude", "codex"}, RunID: "ci-1"}, false},
+		{"with --json", RunOpts{Runners: []string{"claude", "codex"}, JSON: true}, false},
+	}
+	for _, tt := range tests {
+		err := validateRunners(tt.opts)
+		if tt.ok && err != nil {
+			t.Errorf("%s: error = %v", tt.name, err)
+		}
+		if !tt.ok && errors.GetCode(err) != errors.EUsage {
+			t.Errorf("%s: code = %q, want %q", tt.name, errors.GetCode(err), errors.EUsage)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/schema"
+)
+
+// schemaDescriptions is printed by `agency schema` without a name.
+var schemaDescriptions = map[string]string{
+	"agency": "agency.json, the per-repo config",
+	"meta":   "meta.json, a run's metadata",
+	"setup":  "setup.json, the setup script's structured output",
+	"verify": "verify.json, the verify script's structured output",
+	"status": "status.json, the per-run progress file for dashboards",
+	"ls":     "the ls --json envelope (and ls --stream lines: $defs/run)",
+	"show":   "the show --json envelope",
+}
+
+// SchemaOpts holds options for the schema command.
+type SchemaOpts struct {
+	// Name is the schema to print or validate against ("" lists them).
+	Name string
+
+	// Validate is a file to check against the schema instead of printing
+	// it (relative to cwd).
+	Validate string
+}
+
+// Schema prints an embedded JSON Schema, lists the schemas, or validates a
+// file against one, printing each violation as <file>:<line>:<column>.
+//
+// Error codes:
+//   - E_USAGE: unknown schema name, or --validate without a name
+//   - E_SCHEMA_VIOLATION: the validated file does not match the schema
+func Schema(fsys fs.FS, cwd string, opts SchemaOpts, stdout io.Writer) error {
+	if opts.Name == "" {
+		if opts.Validate != "" {
+			return errors.New(errors.EUsage, "--validate requires a schema name")
+		}
+		for _, name := range schema.Names() {
+			fmt.Fprintf(stdout, "%-8s %s\n", name, schemaDescriptions[name])
+		}
+		return nil
+	}
+
+	name := schema.Normalize(opts.Name)
+	if name == "" {
+		return errors.WithHints(errors.New(errors.EUsage, fmt.Sprintf("unknown schema %q", opts.Name)),
+			"known schemas: "+strings.Join(schema.Names(), ", "))
+	}
+
+	if opts.Validate == "" {
+		data, err := schema.Source(name)
+		if err != nil {
+			return errors.Wrap(errors.EInternal, "failed to read schema", err)
+		}
+		_, err = stdout.Write(data)
+		return err
+	}
+
+	path := opts.Validate
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(errors.EUsage, "failed to read "+opts.Validate, err)
+	}
+	violations, err := schema.Validate(name, data)
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to validate", err)
+	}
+	if len(violations) == 0 {
+		fmt.Fprintf(stdout, "%s: valid %s\n", opts.Validate, name)
+		return nil
+	}
+	for _, v := range violations {
+		ptr := v.Pointer
+		if ptr == "" {
+			ptr = "(root)"
+		}
+		fmt.Fprintf(stdout, "%s:%d:%d: %s: %s\n", opts.Validate, v.Line, v.Column, ptr, v.Message)
+	}
+	return errors.NewWithDetails(errors.ESchemaViolation,
+		fmt.Sprintf("%s does not match the %s schema (%d problem(s))", opts.Validate, name, len(violations)),
+		map[string]string{"path": path})
+}
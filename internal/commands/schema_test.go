@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestSchema_PrintAndList(t *testing.T) {
+	var stdout bytes.Buffer
+	if err := Schema(fs.NewRealFS(), t.TempDir(), SchemaOpts{}, &stdout); err != nil {
+		t.Fatalf("Schema() error = %v", err)
+	}
+	for _, name := range []string{"agency", "meta", "setup", "verify", "status", "ls", "show"} {
+		if !strings.Contains(stdout.String(), name+" ") {
+			t.Errorf("list missing %s:\n%s", name, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	if err := Schema(fs.NewRealFS(), t.TempDir(), SchemaOpts{Name: "agency.json"}, &stdout); err != nil {
+		t.Fatalf("Schema(agency.json) error = %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &doc); err != nil || doc["title"] != "agency.json" {
+		t.Errorf("printed schema = %v (%v), want the agency.json schema", doc["title"], err)
+	}
+
+	err := Schema(fs.NewRealFS(), t.TempDir(), SchemaOpts{Name: "nope"}, &stdout)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("unknown schema error = %v, want E_USAGE", err)
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "good.json"), []byte(`{"ok": true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{\n  \"ok\": \"yes\"\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if err := Schema(fs.NewRealFS(), dir, SchemaOpts{Name: "setup", Validate: "good.json"}, &stdout); err != nil {
+		t.Fatalf("valid file error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "good.json: valid setup") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+
+	stdout.Reset()
+	err := Schema(fs.NewRealFS(), dir, SchemaOpts{Name: "setup", Validate: "bad.json"}, &stdout)
+	if errors.GetCode(err) != errors.ESchemaViolation {
+		t.Fatalf("invalid file error = %v, want E_SCHEMA_VIOLATION", err)
+	}
+	if want := "bad.json:2:9: /ok: expected boolean, got string\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+}
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// SealRunFiles encrypts the plaintext meta.json, transcript, and script logs
+// of runs a mutating command touched, when encryption at rest is enabled:
+// meta.json is encrypted as it is written, but script logs are streamed in
+// plaintext while their script runs. The cli calls it after every mutating
+// command. Failures are warnings; agency gc --auto encrypts what is left.
+func SealRunFiles(cr agencyexec.CommandRunner, fsys fs.FS, cwd string, runs []audit.Run, stderr io.Writer) {
+	if len(runs) == 0 {
+		return
+	}
+	crypter := LoadCrypt(cr, fsys)
+	if !crypter.Enabled() {
+		return
+	}
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return
+	}
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now)
+	for _, r := range runs {
+		if _, err := store.SealRunFiles(st.RunDir(r.RepoID, r.RunID), crypter); err != nil {
+			fmt.Fprintf(stderr, "warning: %s: failed to encrypt run files: %v\n", r.RunID, err)
+		}
+	}
+}
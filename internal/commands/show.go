@@ -3,16 +3,15 @@ package commands
 import (
 	"context"
 	"io"
-	"os"
 	"path/filepath"
-	"strings"
+	"text/template"
 
+	"github.com/NielsdaWheelz/agency/internal/checkpoint"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/git"
 	"github.com/NielsdaWheelz/agency/internal/ids"
-	"github.com/NielsdaWheelz/agency/internal/paths"
 	"github.com/NielsdaWheelz/agency/internal/render"
 	"github.com/NielsdaWheelz/agency/internal/status"
 	"github.com/NielsdaWheelz/agency/internal/store"
@@ -23,111 +22,189 @@ type ShowOpts struct {
 	// RunID is the run identifier (exact or unique prefix).
 	RunID string
 
+	// Branch selects the run by its branch instead of RunID.
+	Branch string
+
+	// PR selects the run by its pull request number instead of RunID.
+	PR int
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+
 	// JSON outputs machine-readable JSON.
 	JSON bool
 
 	// Path outputs only resolved filesystem paths.
 	Path bool
+
+	// Format is a go-template executed against the show --json data (RunDetail).
+	Format string
+
+	// SetupEnv outputs only the environment captured at setup time (setup_env.json).
+	SetupEnv bool
+
+	// Explain lists the predicates behind the derived status in human output.
+	Explain bool
+
+	// Plain selects line-oriented human output (no section banners);
+	// the user config "plain" setting also enables it.
+	Plain bool
 }
 
 // Show executes the agency show command.
 // Inspects a single run by exact or unique-prefix ID resolution.
 // This is a read-only command: no state files are mutated.
 func Show(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts ShowOpts, stdout, stderr io.Writer) error {
-	// Validate run_id provided
-	if opts.RunID == "" {
-		return errors.New(errors.EUsage, "run_id is required")
+	// Validate that exactly one run reference is provided
+	selector := RunSelector{Branch: opts.Branch, PR: opts.PR}
+	if err := checkRunSelector(opts.RunID, selector); err != nil {
+		return err
 	}
 
-	// Resolve data directory
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return errors.Wrap(errors.EInternal, "failed to get home directory", err)
+	if opts.SetupEnv && (opts.Path || opts.Format != "") {
+		return errors.New(errors.EUsage, "--setup-env cannot be combined with --path or --format")
+	}
+	if opts.Explain && (opts.JSON || opts.Path || opts.Format != "" || opts.SetupEnv) {
+		return errors.New(errors.EUsage, "--explain cannot be combined with --json, --path, --format, or --setup-env (show --json always includes derived.reasons)")
 	}
-	dirs := paths.ResolveDirs(osEnv{}, homeDir)
-	dataDir := dirs.DataDir
 
-	// Scan all runs (global resolution works regardless of cwd)
-	records, err := store.ScanAllRuns(dataDir)
+	// Parse --format up front so template errors fail fast
+	var formatTmpl *template.Template
+	if opts.Format != "" {
+		if opts.JSON || opts.Path {
+			return errors.New(errors.EUsage, "--format cannot be combined with --json or --path")
+		}
+		tmpl, err := render.ParseFormat(opts.Format)
+		if err != nil {
+			return err
+		}
+		formatTmpl = tmpl
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
 	if err != nil {
-		return errors.Wrap(errors.EInternal, "failed to scan runs", err)
+		return err
 	}
+	crypter := LoadCrypt(cr, fsys)
+	dataDir := dirs.DataDir
 
-	// Convert records to RunRefs for resolution
-	refs := make([]ids.RunRef, len(records))
-	for i, rec := range records {
-		refs[i] = ids.RunRef{
-			RepoID: rec.RepoID,
-			RunID:  rec.RunID,
-			Broken: rec.Broken,
+	// Plain mode and status labels only affect human output; machine output
+	// never reads the user config
+	plain := opts.Plain
+	var vocab render.StatusVocabulary
+	if !opts.JSON && !opts.Path && formatTmpl == nil {
+		if plain, vocab, err = resolveHumanOutput(fsys, dirs.ConfigDir, opts.Plain, stdout); err != nil {
+			return err
 		}
 	}
 
-	// Resolve run ID (exact or unique prefix)
-	resolvedRef, err := ids.ResolveRunRef(opts.RunID, refs)
+	// Scan all runs (global resolution works regardless of cwd)
+	records, err := store.ScanAllRuns(dataDir, crypter)
 	if err != nil {
-		return handleResolveError(err, opts, stdout, stderr)
+		return errors.Wrap(errors.EInternal, "failed to scan runs", err)
 	}
 
-	// Find the matching record
-	var record *store.RunRecord
-	for i := range records {
-		if records[i].RunID == resolvedRef.RunID && records[i].RepoID == resolvedRef.RepoID {
-			record = &records[i]
-			break
+	// Resolve run ID (exact or unique prefix), preferring the cwd repo on ambiguity
+	scope, err := newRunScope(ctx, cr, dataDir, cwd, opts.Repo)
+	if err != nil {
+		if opts.JSON {
+			_ = render.WriteShowJSONError(stdout, err)
 		}
+		return err
 	}
-	if record == nil {
-		// Should not happen if resolver worked correctly
-		return errors.New(errors.EInternal, "resolved run not found in records")
+	var record *store.RunRecord
+	if !selector.IsZero() {
+		record, err = resolveRunSelector(records, selector, scope)
+		if err != nil {
+			if opts.JSON {
+				_ = render.WriteShowJSONError(stdout, err)
+			}
+			return err
+		}
+	} else {
+		resolvedRef, err := resolveRunRef(records, opts.RunID, scope)
+		if err != nil {
+			return handleResolveError(err, opts, stdout, stderr)
+		}
+
+		// Find the matching record
+		for i := range records {
+			if records[i].RunID == resolvedRef.RunID && records[i].RepoID == resolvedRef.RepoID {
+				record = &records[i]
+				break
+			}
+		}
+		if record == nil {
+			// Should not happen if resolver worked correctly
+			return errors.New(errors.EInternal, "resolved run not found in records")
+		}
 	}
 
 	// Compute paths
 	runDir := filepath.Join(dataDir, "repos", record.RepoID, "runs", record.RunID)
 	eventsPath := filepath.Join(runDir, "events.jsonl")
-	transcriptPath := filepath.Join(runDir, "transcript.txt")
+	transcriptPath := filepath.Join(runDir, store.TranscriptFileName)
 	logsDir := filepath.Join(runDir, "logs")
 	setupLogPath, verifyLogPath, archiveLogPath := render.ResolveScriptLogPaths(runDir)
 
+	// --setup-env reads only setup_env.json (works for broken runs too)
+	if opts.SetupEnv {
+		return outputShowSetupEnv(fsys, dataDir, record, opts.JSON, plain, stdout)
+	}
+
 	// Handle broken runs
 	if record.Broken {
 		return handleBrokenRun(record, runDir, logsDir, eventsPath, transcriptPath, setupLogPath, verifyLogPath, archiveLogPath, opts, stdout, stderr)
 	}
 
-	// Get tmux session set (single call for efficiency)
-	tmuxSessions := getTmuxSessions(ctx, cr)
-	tmuxUnavailable := false // we don't know if tmux is unavailable, just that no sessions exist
-
 	// Compute local snapshot for the run
 	worktreePath := record.Meta.WorktreePath
 	worktreePresent := dirExists(worktreePath)
 	archived := !worktreePresent
 
-	// Report info
-	reportPath := filepath.Join(worktreePath, ".agency", "report.md")
-	reportExists := false
-	reportBytes := 0
-	if worktreePresent {
-		if info, err := os.Stat(reportPath); err == nil && info.Mode().IsRegular() {
-			reportExists = true
-			reportBytes = int(info.Size())
-		}
+	// --path needs no tmux, report, or status data
+	if opts.Path {
+		repoRoot := resolveRepoRootForShow(ctx, cr, cwd, record, dataDir)
+		reportPath := filepath.Join(worktreePath, ".agency", "report.md")
+		return outputShowPaths(stdout, repoRoot, worktreePath, runDir, logsDir, eventsPath, transcriptPath, reportPath)
 	}
 
-	// Tmux session check
-	sessionName := record.Meta.TmuxSessionName
-	if sessionName == "" {
-		sessionName = "agency_" + record.RunID
+	tmuxUnavailable := false // we don't know if tmux is unavailable, just that no sessions exist
+
+	// Report info (size + freshness)
+	report := readReportSnapshot(ctx, cr, record.Meta, worktreePresent)
+
+	// Notes (best-effort; unreadable notes are omitted)
+	st := store.NewStore(fsys, dataDir, clock.Now)
+	notes, _ := st.ReadNotes(record.RepoID, record.RunID)
+	verifyHistory, _ := st.ReadVerifyHistory(record.RepoID, record.RunID)
+	checkpoints, _ := checkpoint.List(runDir)
+
+	// Tmux session check (skipped for archived runs)
+	tmuxActive := false
+	if worktreePresent {
+		sessionName := record.Meta.TmuxSessionName
+		if sessionName == "" {
+			sessionName = "agency_" + record.RunID
+		}
+		tmuxActive = newTmuxSessionSet(ctx, cr).Active(sessionName)
 	}
-	tmuxActive := tmuxSessions[sessionName]
 
 	// Derive status
 	snapshot := status.Snapshot{
 		TmuxActive:      tmuxActive,
 		WorktreePresent: worktreePresent,
-		ReportBytes:     reportBytes,
+		ReportBytes:     report.Bytes,
+		ReportStale:     report.Stale,
+		ReportReadyFlag: report.ReadyFlag,
+	}
+	if worktreePresent {
+		snapshot.Policy = newReviewPolicySet(fsys, dataDir).Get(*record)
+		snapshot.DeadlineExceeded = record.Meta.DeadlineExceeded(clock.Now())
+		snapshot.NoCommits = noCommits(newCommitCountSet(ctx, cr, fsys, ""), *record, report.Bytes, snapshot.Policy)
 	}
 	derived := status.Derive(record.Meta, snapshot)
+	repoLock := newRepoLockSet(dataDir).Get(record.RepoID)
 
 	// Best-effort repo root resolution
 	repoRoot := resolveRepoRootForShow(ctx, cr, cwd, record, dataDir)
@@ -137,50 +214,39 @@ func Show(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd stri
 	worktreeMissingWarning := !worktreePresent
 
 	// Build output based on mode
-	if opts.Path {
-		return outputShowPaths(stdout, repoRoot, worktreePath, runDir, logsDir, eventsPath, transcriptPath, reportPath)
+	if opts.JSON || formatTmpl != nil {
+		detail := buildShowDetail(record, repoRoot, runDir, eventsPath, transcriptPath, derived, report, notes, tmuxActive, worktreePresent, archived, setupLogPath, verifyLogPath, archiveLogPath)
+		detail.Derived.Lock = repoLock
+		detail.Checkpoints = checkpoints
+		detail.VerifyHistory = verifyHistory
+		if formatTmpl != nil {
+			return render.WriteShowFormat(stdout, formatTmpl, detail)
+		}
+		return render.WriteShowJSON(stdout, detail)
 	}
 
-	if opts.JSON {
-		return outputShowJSON(stdout, record, repoRoot, runDir, eventsPath, transcriptPath, derived, reportPath, reportExists, reportBytes, tmuxActive, worktreePresent, archived, setupLogPath, verifyLogPath, archiveLogPath)
+	// Human output (reasons only with --explain)
+	if !opts.Explain {
+		derived.Reasons = nil
 	}
-
-	// Human output
-	return outputShowHuman(stdout, record, repoRoot, runDir, derived, reportPath, reportExists, reportBytes, tmuxActive, worktreePresent, archived, setupLogPath, verifyLogPath, archiveLogPath, repoNotFoundWarning, worktreeMissingWarning, tmuxUnavailable)
+	return outputShowHuman(stdout, record, repoRoot, runDir, derived, report, notes, tmuxActive, worktreePresent, archived, setupLogPath, verifyLogPath, archiveLogPath, repoNotFoundWarning, worktreeMissingWarning, tmuxUnavailable, plain, vocab, repoLock, checkpoints, verifyHistory, scriptEnvWarnings(fsys, dataDir, record))
 }
 
 // handleResolveError handles ID resolution errors and outputs appropriate error.
 func handleResolveError(err error, opts ShowOpts, stdout, stderr io.Writer) error {
 	// Handle ambiguous error
 	if ambErr, ok := err.(*ids.ErrAmbiguous); ok {
-		// Build candidates list
-		candidates := make([]string, len(ambErr.Candidates))
-		for i, c := range ambErr.Candidates {
-			candidates[i] = c.RunID
-		}
-
-		// For --json mode, output JSON envelope with null data
-		if opts.JSON {
-			_ = render.WriteShowJSON(stdout, nil)
-		}
-
-		return errors.NewWithDetails(
-			errors.ERunIDAmbiguous,
-			"ambiguous run id '"+ambErr.Input+"' matches multiple runs: "+strings.Join(candidates, ", "),
-			map[string]string{"input": ambErr.Input},
-		)
+		err = ambiguousRunIDError(ambErr)
+	} else if _, ok := err.(*ids.ErrNotFound); ok {
+		err = errors.New(errors.ERunNotFound, "run not found: "+opts.RunID)
+	} else {
+		return err
 	}
 
-	// Handle not found error
-	if _, ok := err.(*ids.ErrNotFound); ok {
-		// For --json mode, output JSON envelope with null data
-		if opts.JSON {
-			_ = render.WriteShowJSON(stdout, nil)
-		}
-
-		return errors.New(errors.ERunNotFound, "run not found: "+opts.RunID)
+	// For --json mode, output JSON envelope with null data and the error
+	if opts.JSON {
+		_ = render.WriteShowJSONError(stdout, err)
 	}
-
 	return err
 }
 
@@ -198,15 +264,7 @@ func handleBrokenRun(record *store.RunRecord, runDir, logsDir, eventsPath, trans
 			ReportPath:     "",
 		}
 		_ = render.WriteShowPaths(stdout, data)
-		return errors.NewWithDetails(
-			errors.ERunBroken,
-			"run exists but meta.json is unreadable or invalid",
-			map[string]string{
-				"run_id":    record.RunID,
-				"meta_path": filepath.Join(runDir, "meta.json"),
-				"hint":      "delete this run dir or fix meta.json",
-			},
-		)
+		return brokenRunError(record.RunID, filepath.Join(runDir, "meta.json"))
 	}
 
 	// For --json mode, output broken=true envelope
@@ -218,6 +276,7 @@ func handleBrokenRun(record *store.RunRecord, runDir, logsDir, eventsPath, trans
 			Archived: true, // assume archived for broken runs
 			Derived: render.DerivedJSON{
 				DerivedStatus:   status.StatusBroken,
+				Reasons:         []string{"meta.json unreadable"},
 				TmuxActive:      false,
 				WorktreePresent: false,
 				Report: render.ReportJSON{
@@ -248,28 +307,11 @@ func handleBrokenRun(record *store.RunRecord, runDir, logsDir, eventsPath, trans
 		}
 
 		_ = render.WriteShowJSON(stdout, detail)
-		return errors.NewWithDetails(
-			errors.ERunBroken,
-			"run exists but meta.json is unreadable or invalid",
-			map[string]string{
-				"run_id":    record.RunID,
-				"meta_path": filepath.Join(runDir, "meta.json"),
-				"hint":      "delete this run dir or fix meta.json",
-			},
-		)
+		return brokenRunError(record.RunID, filepath.Join(runDir, "meta.json"))
 	}
 
 	// Human output for broken run
-	metaPath := filepath.Join(runDir, "meta.json")
-	return errors.NewWithDetails(
-		errors.ERunBroken,
-		"run exists but meta.json is unreadable or invalid",
-		map[string]string{
-			"run_id":    record.RunID,
-			"meta_path": metaPath,
-			"hint":      "delete this run dir or fix meta.json",
-		},
-	)
+	return brokenRunError(record.RunID, filepath.Join(runDir, "meta.json"))
 }
 
 // resolveRepoRootForShow attempts to resolve the repo root for display purposes.
@@ -316,25 +358,40 @@ func outputShowPaths(stdout io.Writer, repoRoot *string, worktreePath, runDir, l
 	return render.WriteShowPaths(stdout, data)
 }
 
-// outputShowJSON writes the --json output.
-func outputShowJSON(stdout io.Writer, record *store.RunRecord, repoRoot *string, runDir, eventsPath, transcriptPath string, derived status.Derived, reportPath string, reportExists bool, reportBytes int, tmuxActive, worktreePresent, archived bool, setupLogPath, verifyLogPath, archiveLogPath string) error {
+// buildShowDetail builds the run detail used for --json and --format output.
+func buildShowDetail(record *store.RunRecord, repoRoot *string, runDir, eventsPath, transcriptPath string, derived status.Derived, report reportSnapshot, notes []store.RunNote, tmuxActive, worktreePresent, archived bool, setupLogPath, verifyLogPath, archiveLogPath string) *render.RunDetail {
+	var reportCommit *string
+	if report.Commit != "" {
+		reportCommit = &report.Commit
+	}
+	var attentionReason *string
+	if derived.AttentionReason != "" {
+		attentionReason = &derived.AttentionReason
+	}
+
 	detail := &render.RunDetail{
 		Meta:     record.Meta,
 		RepoID:   record.RepoID,
 		Archived: archived,
 		Derived: render.DerivedJSON{
 			DerivedStatus:   derived.DerivedStatus,
+			AttentionReason: attentionReason,
+			Reasons:         derived.Reasons,
+			NoChanges:       derived.NoChanges,
 			TmuxActive:      tmuxActive,
 			WorktreePresent: worktreePresent,
 			Report: render.ReportJSON{
-				Exists: reportExists,
-				Bytes:  reportBytes,
-				Path:   reportPath,
+				Exists: report.Exists,
+				Bytes:  report.Bytes,
+				Path:   report.Path,
+				Commit: reportCommit,
+				Stale:  report.Stale,
 			},
 			Logs: render.LogsJSON{
 				SetupLogPath:   setupLogPath,
 				VerifyLogPath:  verifyLogPath,
 				ArchiveLogPath: archiveLogPath,
+				Bytes:          store.LogsBytes(filepath.Join(runDir, "logs")),
 			},
 		},
 		Paths: render.PathsJSON{
@@ -344,6 +401,7 @@ func outputShowJSON(stdout io.Writer, record *store.RunRecord, repoRoot *string,
 			EventsPath:     eventsPath,
 			TranscriptPath: transcriptPath,
 		},
+		Notes:  notes,
 		Broken: false,
 	}
 
@@ -353,11 +411,11 @@ func outputShowJSON(stdout io.Writer, record *store.RunRecord, repoRoot *string,
 		detail.OriginURL = record.Repo.OriginURL
 	}
 
-	return render.WriteShowJSON(stdout, detail)
+	return detail
 }
 
 // outputShowHuman writes the human-readable output.
-func outputShowHuman(stdout io.Writer, record *store.RunRecord, repoRoot *string, runDir string, derived status.Derived, reportPath string, reportExists bool, reportBytes int, tmuxActive, worktreePresent, archived bool, setupLogPath, verifyLogPath, archiveLogPath string, repoNotFoundWarning, worktreeMissingWarning, tmuxUnavailable bool) error {
+func outputShowHuman(stdout io.Writer, record *store.RunRecord, repoRoot *string, runDir string, derived status.Derived, report reportSnapshot, notes []store.RunNote, tmuxActive, worktreePresent, archived bool, setupLogPath, verifyLogPath, archiveLogPath string, repoNotFoundWarning, worktreeMissingWarning, tmuxUnavailable, plain bool, vocab render.StatusVocabulary, repoLock *render.LockJSON, checkpoints []checkpoint.Checkpoint, verifyHistory []store.VerifyAttempt, envWarnings []string) error {
 	meta := record.Meta
 
 	data := render.ShowHumanData{
@@ -366,7 +424,14 @@ func outputShowHuman(stdout io.Writer, record *store.RunRecord, repoRoot *string
 		Title:     meta.Title,
 		Runner:    meta.Runner,
 		CreatedAt: meta.CreatedAt,
+		CreatedBy: meta.CreatedBy,
 		RepoID:    record.RepoID,
+		Labels:    meta.Labels,
+		Group:     meta.Group,
+
+		SkippedSteps: meta.SkippedSteps,
+		Credentials:  meta.Credentials,
+		Probes:       meta.Probes,
 
 		// Git/workspace
 		ParentBranch:    meta.ParentBranch,
@@ -375,6 +440,7 @@ func outputShowHuman(stdout io.Writer, record *store.RunRecord, repoRoot *string
 		WorktreePresent: worktreePresent,
 		TmuxSessionName: meta.TmuxSessionName,
 		TmuxActive:      tmuxActive,
+		Worktree:        meta.Worktree,
 
 		// PR
 		PRNumber:   meta.PRNumber,
@@ -382,23 +448,48 @@ func outputShowHuman(stdout io.Writer, record *store.RunRecord, repoRoot *string
 		LastPushAt: meta.LastPushAt,
 
 		// Report
-		ReportPath:   reportPath,
-		ReportExists: reportExists,
-		ReportBytes:  reportBytes,
+		ReportPath:   report.Path,
+		ReportExists: report.Exists,
+		ReportBytes:  report.Bytes,
+		ReportCommit: report.Commit,
+		ReportStale:  report.Stale,
+
+		// Setup
+		SetupOutput: meta.Setup,
 
 		// Logs
 		SetupLogPath:   setupLogPath,
 		VerifyLogPath:  verifyLogPath,
 		ArchiveLogPath: archiveLogPath,
+		LogsBytes:      store.LogsBytes(filepath.Join(runDir, "logs")),
+
+		// Notes
+		Notes: notes,
+
+		// Verify
+		VerifyHistory: verifyHistory,
+
+		// Checkpoints
+		Checkpoints: checkpoints,
 
 		// Derived
-		DerivedStatus: derived.DerivedStatus,
-		Archived:      archived,
+		DerivedStatus:   derived.DerivedStatus,
+		AttentionReason: derived.AttentionReason,
+		Reasons:         derived.Reasons,
+		Vocabulary:      vocab,
+		NoChanges:       derived.NoChanges,
+		Archived:        archived,
+		Deadline:        meta.Deadline,
+		Lock:            repoLock,
+		Now:             clock.Now(),
 
 		// Warnings
 		RepoNotFoundWarning:    repoNotFoundWarning,
 		WorktreeMissingWarning: worktreeMissingWarning,
 		TmuxUnavailableWarning: tmuxUnavailable,
+		EnvWarnings:            envWarnings,
+
+		Plain: plain,
 	}
 
 	// Repo identity
@@ -411,3 +502,36 @@ func outputShowHuman(stdout io.Writer, record *store.RunRecord, repoRoot *string
 
 	return render.WriteShowHuman(stdout, data)
 }
+
+// outputShowSetupEnv writes the run's captured setup environment.
+// Returns E_SETUP_ENV_NOT_FOUND if setup never captured one.
+func outputShowSetupEnv(fsys fs.FS, dataDir string, record *store.RunRecord, jsonOutput, plain bool, stdout io.Writer) error {
+	env, err := readSetupEnv(fsys, dataDir, record)
+	if err != nil {
+		if jsonOutput {
+			_ = render.WriteSetupEnvJSON(stdout, nil)
+		}
+		return err
+	}
+	if jsonOutput {
+		return render.WriteSetupEnvJSON(stdout, env)
+	}
+	return render.WriteSetupEnvHuman(stdout, record.RunID, env, plain)
+}
+
+// readSetupEnv reads a run's setup_env.json, mapping "no capture" to E_SETUP_ENV_NOT_FOUND.
+func readSetupEnv(fsys fs.FS, dataDir string, record *store.RunRecord) (*store.SetupEnv, error) {
+	st := store.NewStore(fsys, dataDir, clock.Now)
+	env, err := st.ReadSetupEnv(record.RepoID, record.RunID)
+	if err != nil {
+		return nil, err
+	}
+	if env == nil || env.CapturedAt == "" {
+		return nil, errors.WithHints(errors.NewWithDetails(
+			errors.ESetupEnvNotFound,
+			"run "+record.RunID+" has no captured setup environment",
+			map[string]string{"run_id": record.RunID},
+		), "setup_env.json is written when setup runs; runs created before it was added have none")
+	}
+	return env, nil
+}
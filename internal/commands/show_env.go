@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// scriptEnvWarnings compares the environment each of a run's scripts last
+// ran with (setup_env.json) against the one the repo's last agency doctor
+// saw, and describes the differences that commonly make a script fail
+// under agency but not in the user's shell: PATH and the shell. Returns
+// nil when either side was never recorded.
+func scriptEnvWarnings(fsys fs.FS, dataDir string, record *store.RunRecord) []string {
+	st := store.NewStore(fsys, dataDir, clock.Now)
+	repo, ok, err := st.LoadRepoRecord(record.RepoID)
+	if err != nil || !ok || repo.DoctorEnv == nil {
+		return nil
+	}
+	captured, err := st.ReadSetupEnv(record.RepoID, record.RunID)
+	if err != nil || captured == nil {
+		return nil
+	}
+	doctor := repo.DoctorEnv
+
+	envs := make(map[string]store.SetupEnv, len(captured.Scripts)+1)
+	for name, env := range captured.Scripts {
+		envs[name] = env
+	}
+	if captured.CapturedAt != "" {
+		envs[store.SetupEnvScript] = *captured
+	}
+	scripts := make([]string, 0, len(envs))
+	for name := range envs {
+		scripts = append(scripts, name)
+	}
+	sort.Strings(scripts)
+
+	var warnings []string
+	for _, name := range scripts {
+		env := envs[name]
+		if w := pathWarning(name, env.ScriptPath, doctor.ScriptPath); w != "" {
+			warnings = append(warnings, w)
+		}
+		shell, doctorShell := env.Tools["sh"], doctor.Tools["sh"]
+		if shell != "" && doctorShell != "" && shell != doctorShell {
+			warnings = append(warnings, fmt.Sprintf("%s ran under %s, but agency doctor found %s", name, shell, doctorShell))
+		}
+	}
+	return warnings
+}
+
+// pathWarning describes how a script's PATH differs from doctor's: the
+// entries it lacks or adds, or only their order ("" if the same or unknown).
+func pathWarning(script, path, doctorPath string) string {
+	if path == "" || doctorPath == "" || path == doctorPath {
+		return ""
+	}
+	entries, doctorEntries := strings.Split(path, ":"), strings.Split(doctorPath, ":")
+	missing := pathEntriesNotIn(doctorEntries, entries)
+	extra := pathEntriesNotIn(entries, doctorEntries)
+	if len(missing) == 0 && len(extra) == 0 {
+		return script + " ran with the PATH entries of agency doctor in a different order"
+	}
+	var parts []string
+	if len(missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		parts = append(parts, "extra "+strings.Join(extra, ", "))
+	}
+	return fmt.Sprintf("%s ran with a different PATH than agency doctor (%s)", script, strings.Join(parts, "; "))
+}
+
+// pathEntriesNotIn returns the entries of a that are not in b, in order.
+func pathEntriesNotIn(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, e := range b {
+		in[e] = true
+	}
+	var out []string
+	for _, e := range a {
+		if !in[e] {
+			out = append(out, e)
+			in[e] = true
+		}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestShow_ScriptEnvWarnings(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	repoID, runID := "abc123", "20260110120000-a3f2"
+	t0 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	clk := testkit.NewClock(t0)
+	defer SetClock(clk.Core())()
+	testkit.WriteRun(t, dataDir, testkit.NewRunMeta(repoID, runID, t.TempDir(), t0))
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, clk.Now)
+	rec := st.UpsertRepoRecord(nil, store.BuildRepoRecordInput{
+		RepoKey:   "github:owner/repo",
+		RepoID:    repoID,
+		DoctorEnv: &store.SetupEnv{ScriptPath: "/opt/homebrew/bin:/usr/bin:/bin", Tools: map[string]string{"sh": "/bin/bash"}},
+	})
+	if err := st.SaveRepoRecord(rec); err != nil {
+		t.Fatal(err)
+	}
+	setup := store.SetupEnv{CapturedAt: "2026-01-10T12:00:00Z", ScriptPath: "/usr/bin:/bin:/usr/sbin", Tools: map[string]string{"sh": "/usr/bin/dash"}}
+	if err := st.WriteSetupEnv(repoID, runID, store.SetupEnvScript, setup); err != nil {
+		t.Fatal(err)
+	}
+	hook := store.SetupEnv{CapturedAt: "2026-01-10T12:05:00Z", ScriptPath: "/bin:/usr/bin:/opt/homebrew/bin", Tools: map[string]string{"sh": "/bin/bash"}}
+	if err := st.WriteSetupEnv(repoID, runID, "hook_post_setup", hook); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cr := testkit.NewFakeRunner()
+	cr.TmuxNoServer()
+	if err := Show(context.Background(), cr, fs.NewRealFS(), t.TempDir(), ShowOpts{RunID: runID}, &stdout, &stderr); err != nil {
+		t.Fatalf("Show() error = %v (stderr %q)", err, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"warning: hook_post_setup ran with the PATH entries of agency doctor in a different order",
+		"warning: setup ran with a different PATH than agency doctor (missing /opt/homebrew/bin; extra /usr/sbin)",
+		"warning: setup ran under /usr/bin/dash, but agency doctor found /bin/bash",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("show output missing %q:\n%s", want, out)
+		}
+	}
+}
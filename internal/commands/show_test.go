@@ -2,7 +2,9 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,10 +12,12 @@ import (
 	"time"
 
 	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/ids"
 	"github.com/NielsdaWheelz/agency/internal/render"
 	"github.com/NielsdaWheelz/agency/internal/status"
 	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
 )
 
 // ============================================================
@@ -36,8 +40,8 @@ func TestWriteShowJSON_SchemaVersion(t *testing.T) {
 		t.Fatalf("json.Unmarshal() error = %v", err)
 	}
 
-	if env.SchemaVersion != "1.0" {
-		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.0")
+	if env.SchemaVersion != "1.6" {
+		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.6")
 	}
 
 	if env.Data == nil {
@@ -56,8 +60,8 @@ func TestWriteShowJSON_NullData(t *testing.T) {
 		t.Fatalf("json.Unmarshal() error = %v", err)
 	}
 
-	if env.SchemaVersion != "1.0" {
-		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.0")
+	if env.SchemaVersion != "1.6" {
+		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.6")
 	}
 
 	if env.Data != nil {
@@ -133,6 +137,12 @@ func TestWriteShowJSON_AllFields(t *testing.T) {
 	if d.RepoID != "abc123" {
 		t.Errorf("RepoID = %q, want %q", d.RepoID, "abc123")
 	}
+	if d.CreatedAtUnix == nil || *d.CreatedAtUnix != 1768046400 {
+		t.Errorf("CreatedAtUnix = %v, want 1768046400", d.CreatedAtUnix)
+	}
+	if d.LastPushAtUnix != nil {
+		t.Errorf("LastPushAtUnix = %v, want nil (not pushed)", d.LastPushAtUnix)
+	}
 	if d.RepoKey == nil || *d.RepoKey != repoKey {
 		t.Errorf("RepoKey = %v, want %q", d.RepoKey, repoKey)
 	}
@@ -387,6 +397,99 @@ func TestWriteShowHuman_BasicOutput(t *testing.T) {
 	}
 }
 
+func TestWriteShowHuman_Labels(t *testing.T) {
+	data := render.ShowHumanData{
+		RunID:  "20260110-a3f2",
+		Labels: map[string]string{"ticket": "JIRA-123", "team": "infra"},
+	}
+
+	var buf bytes.Buffer
+	if err := render.WriteShowHuman(&buf, data); err != nil {
+		t.Fatalf("WriteShowHuman() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "labels: team=infra,ticket=JIRA-123\n") {
+		t.Errorf("missing sorted labels line:\n%s", buf.String())
+	}
+
+	data.Labels = nil
+	buf.Reset()
+	if err := render.WriteShowHuman(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "labels:") {
+		t.Error("labels line should be omitted when there are no labels")
+	}
+}
+
+func TestWriteShowHuman_Plain(t *testing.T) {
+	data := render.ShowHumanData{RunID: "20260110-a3f2", Title: "fix", Plain: true}
+
+	var buf bytes.Buffer
+	if err := render.WriteShowHuman(&buf, data); err != nil {
+		t.Fatalf("WriteShowHuman() error = %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "===") {
+		t.Errorf("plain output should have no section banners:\n%s", out)
+	}
+	if !strings.HasPrefix(out, "run_id: 20260110-a3f2\n") || !strings.Contains(out, "\n\nparent_branch: ") {
+		t.Errorf("plain output should be key: value lines with blank-line sections:\n%s", out)
+	}
+}
+
+func TestWriteShowHuman_SetupChecks(t *testing.T) {
+	ok := false
+	data := render.ShowHumanData{
+		RunID: "20260110-a3f2",
+		SetupOutput: &store.RunMetaSetup{
+			OutputOk:      &ok,
+			OutputSummary: "migrations failed",
+			OutputChecks: []store.RunMetaSetupCheck{
+				{Name: "deps", Ok: true, DurationMs: 5300, Details: "312 packages"},
+				{Name: "migrate", Ok: false, Details: "db unreachable"},
+			},
+			OutputArtifacts: []string{"node_modules/.bin"},
+			OutputWarnings:  []string{"lockfile out of date"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := render.WriteShowHuman(&buf, data); err != nil {
+		t.Fatalf("WriteShowHuman() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"=== setup ===\nsetup_ok: false\nsetup_summary: migrations failed\n",
+		"CHECK    RESULT  DURATION  DETAILS\n",
+		"deps     ok      5.3s      312 packages\n",
+		"migrate  FAIL    -         db unreachable\n",
+		"artifact: node_modules/.bin\n",
+		"setup_warning: lockfile out of date\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	data.Plain = true
+	if err := render.WriteShowHuman(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "check: deps ok 5.3s - 312 packages\ncheck: migrate FAIL - db unreachable\n") {
+		t.Errorf("plain output should list checks as lines:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	data.SetupOutput = &store.RunMetaSetup{ExitCode: 0}
+	if err := render.WriteShowHuman(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "setup_ok") || strings.Contains(buf.String(), "check:") {
+		t.Errorf("setup section should be omitted without setup.json output:\n%s", buf.String())
+	}
+}
+
 func TestWriteShowHuman_UntitledRun(t *testing.T) {
 	data := render.ShowHumanData{
 		RunID:           "20260110-a3f2",
@@ -568,7 +671,7 @@ func TestShow_IntegrationWithFakeData_ValidRun(t *testing.T) {
 	}
 
 	// Scan and verify
-	records, err := store.ScanAllRuns(dataDir)
+	records, err := store.ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v", err)
 	}
@@ -610,7 +713,7 @@ func TestShow_IntegrationWithFakeData_BrokenRun(t *testing.T) {
 	createCorruptMetaForShow(t, dataDir, repoID, runID)
 
 	// Scan and verify
-	records, err := store.ScanAllRuns(dataDir)
+	records, err := store.ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v", err)
 	}
@@ -651,7 +754,7 @@ func TestShow_IDResolutionErrors(t *testing.T) {
 	createValidMetaForShow(t, dataDir, "r2", "20260110-a3ff", "/path/wt2", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
 
 	// Test ambiguous resolution
-	records, err := store.ScanAllRuns(dataDir)
+	records, err := store.ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v", err)
 	}
@@ -766,12 +869,15 @@ func TestHandleResolveError_JSONMode(t *testing.T) {
 		t.Fatalf("json.Unmarshal() error = %v", err)
 	}
 
-	if env.SchemaVersion != "1.0" {
-		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.0")
+	if env.SchemaVersion != "1.6" {
+		t.Errorf("SchemaVersion = %q, want %q", env.SchemaVersion, "1.6")
 	}
 	if env.Data != nil {
 		t.Errorf("Data = %v, want nil", env.Data)
 	}
+	if env.Error == nil || env.Error.Code != errors.ERunNotFound || env.Error.Message != "run not found: nonexistent" {
+		t.Errorf("Error = %+v, want E_RUN_NOT_FOUND", env.Error)
+	}
 }
 
 // Helper functions
@@ -829,3 +935,235 @@ func createCorruptMetaForShow(t *testing.T, dataDir, repoID, runID string) {
 		t.Fatal(err)
 	}
 }
+
+func TestWriteShowHuman_ReportStale(t *testing.T) {
+	data := render.ShowHumanData{
+		RunID:         "20260110-a3f2",
+		ReportPath:    "/path/to/worktree/.agency/report.md",
+		ReportExists:  true,
+		ReportBytes:   256,
+		ReportCommit:  "abc1234",
+		ReportStale:   true,
+		DerivedStatus: "idle (pr)",
+	}
+
+	var buf bytes.Buffer
+	if err := render.WriteShowHuman(&buf, data); err != nil {
+		t.Fatalf("WriteShowHuman() error = %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "report_commit: abc1234") {
+		t.Error("missing report_commit")
+	}
+	if !strings.Contains(output, "report_stale: yes") {
+		t.Error("missing report_stale: yes")
+	}
+	if !strings.Contains(output, "warning: report stale") {
+		t.Error("missing report stale warning")
+	}
+}
+
+func TestWriteShowHuman_Notes(t *testing.T) {
+	data := render.ShowHumanData{
+		RunID:         "20260110120000-a3f2",
+		DerivedStatus: "idle",
+		Notes: []store.RunNote{
+			{Timestamp: "2026-01-10T12:00:00Z", Text: "first"},
+			{Timestamp: "2026-01-10T13:00:00Z", Text: "line one\nline two"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := render.WriteShowHuman(&buf, data); err != nil {
+		t.Fatalf("WriteShowHuman() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"=== notes ===",
+		"2026-01-10T12:00:00Z: first\n",
+		"2026-01-10T13:00:00Z: line one\n  line two\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\ngot:\n%s", want, output)
+		}
+	}
+}
+
+func TestWriteShowHuman_VerifyHistory(t *testing.T) {
+	history := []store.VerifyAttempt{
+		{Timestamp: "2026-01-10T12:00:00Z", OK: true, DurationMs: 4000},
+		{Timestamp: "2026-01-10T13:00:00Z", OK: false, DurationMs: 5300, Summary: "2 tests failed"},
+		{Timestamp: "2026-01-10T14:00:00Z", OK: true, DurationMs: 4100},
+		{Timestamp: "2026-01-10T15:00:00Z", OK: false, DurationMs: 1500, Summary: "lint failed"},
+	}
+
+	for _, tt := range []struct {
+		plain bool
+		trend string
+	}{
+		{false, "verify_trend: ✓✗✓✗ (last 4, oldest first)\n"},
+		{true, "verify_trend: PFPF (last 4, oldest first)\n"},
+	} {
+		data := render.ShowHumanData{
+			RunID:         "20260110120000-a3f2",
+			DerivedStatus: "idle",
+			VerifyHistory: history,
+			Plain:         tt.plain,
+		}
+		var buf bytes.Buffer
+		if err := render.WriteShowHuman(&buf, data); err != nil {
+			t.Fatalf("WriteShowHuman() error = %v", err)
+		}
+		output := buf.String()
+		for _, want := range []string{
+			"verify_attempts: 4 (2 passed, 2 failed)\n",
+			tt.trend,
+			"verify_flaky: yes\n",
+			"verify_last: FAIL 2026-01-10T15:00:00Z 1.5s - lint failed\n",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("plain=%t: output missing %q\ngot:\n%s", tt.plain, want, output)
+			}
+		}
+	}
+
+	// Consistent results are not flaky, and no attempts means no section
+	var buf bytes.Buffer
+	_ = render.WriteShowHuman(&buf, render.ShowHumanData{RunID: "r", VerifyHistory: history[:1]})
+	if strings.Contains(buf.String(), "verify_flaky") {
+		t.Errorf("a single passing attempt shown as flaky:\n%s", buf.String())
+	}
+	buf.Reset()
+	_ = render.WriteShowHuman(&buf, render.ShowHumanData{RunID: "r"})
+	if strings.Contains(buf.String(), "=== verify ===") {
+		t.Errorf("verify section without attempts:\n%s", buf.String())
+	}
+}
+
+func TestWriteShowJSON_NotesEmptyArray(t *testing.T) {
+	var buf bytes.Buffer
+	if err := render.WriteShowJSON(&buf, &render.RunDetail{RepoID: "abc123"}); err != nil {
+		t.Fatalf("WriteShowJSON() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"notes": []`) {
+		t.Errorf("expected empty notes array, got:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"verify_history": []`) {
+		t.Errorf("expected empty verify_history array, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteShowFormat(t *testing.T) {
+	detail := &render.RunDetail{
+		Meta:   &store.RunMeta{RunID: "20260110120000-a3f2", Branch: "agency/test-a3f2"},
+		RepoID: "abc123",
+		Derived: render.DerivedJSON{
+			DerivedStatus: "idle",
+		},
+	}
+
+	tmpl, err := render.ParseFormat(`{{.Meta.Branch}} {{.Derived.DerivedStatus}} {{.RepoID}}`)
+	if err != nil {
+		t.Fatalf("ParseFormat() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := render.WriteShowFormat(&buf, tmpl, detail); err != nil {
+		t.Fatalf("WriteShowFormat() error = %v", err)
+	}
+
+	want := "agency/test-a3f2 idle abc123\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestShow_Explain(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	repoID, runID := "abc123", "20260110120000-a3f2"
+	createValidMetaForShow(t, dataDir, repoID, runID, filepath.Join(dataDir, "wt", runID), time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+
+	cr := testkit.NewFakeRunner()
+	cr.TmuxSessions()
+	show := func(opts ShowOpts) (string, error) {
+		var stdout bytes.Buffer
+		opts.RunID = runID
+		err := Show(context.Background(), cr, fs.NewRealFS(), dataDir, opts, &stdout, io.Discard)
+		return stdout.String(), err
+	}
+
+	out, err := show(ShowOpts{JSON: true})
+	if err != nil {
+		t.Fatalf("Show(--json) error = %v", err)
+	}
+	var env render.ShowJSONEnvelope
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatal(err)
+	}
+	reasons := env.Data.Derived.Reasons
+	if len(reasons) == 0 || reasons[len(reasons)-1] != "worktree missing (archived)" {
+		t.Errorf("derived.reasons = %q", reasons)
+	}
+
+	out, err = show(ShowOpts{})
+	if err != nil || strings.Contains(out, "reasons:") {
+		t.Errorf("plain show listed reasons (err %v):\n%s", err, out)
+	}
+	out, err = show(ShowOpts{Explain: true})
+	if err != nil {
+		t.Fatalf("Show(--explain) error = %v", err)
+	}
+	for _, r := range reasons {
+		if !strings.Contains(out, "  - "+r+"\n") {
+			t.Errorf("--explain output missing %q:\n%s", r, out)
+		}
+	}
+
+	if _, err := show(ShowOpts{Explain: true, JSON: true}); errors.GetCode(err) != errors.EUsage {
+		t.Errorf("--explain --json: err = %v, want E_USAGE", err)
+	}
+}
+
+func TestShow_StatusVocabulary(t *testing.T) {
+	dataDir, configDir := t.TempDir(), t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", configDir)
+	cfg := `{"statuses": {"idle": {"label": "triage", "emoji": "📥", "color": "gray"}}}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	repoID, runID := "abc123", "20260110120000-a3f2"
+	worktree := filepath.Join(dataDir, "wt", runID)
+	if err := os.MkdirAll(worktree, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	createValidMetaForShow(t, dataDir, repoID, runID, worktree, time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+
+	cr := testkit.NewFakeRunner()
+	cr.TmuxSessions()
+	show := func(opts ShowOpts) string {
+		t.Helper()
+		var stdout bytes.Buffer
+		opts.RunID = runID
+		if err := Show(context.Background(), cr, fs.NewRealFS(), dataDir, opts, &stdout, io.Discard); err != nil {
+			t.Fatalf("Show(%+v) error = %v", opts, err)
+		}
+		return stdout.String()
+	}
+
+	// Not a terminal: label and emoji, no color
+	if out := show(ShowOpts{}); !strings.Contains(out, "derived_status: 📥 triage\n") {
+		t.Errorf("human output:\n%s", out)
+	}
+	if out := show(ShowOpts{Plain: true}); !strings.Contains(out, "derived_status: triage\n") {
+		t.Errorf("plain output:\n%s", out)
+	}
+	if out := show(ShowOpts{JSON: true}); !strings.Contains(out, `"derived_status": "idle"`) {
+		t.Errorf("JSON output must keep the derived status:\n%s", out)
+	}
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"os"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/status"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// RefreshStatusFiles rewrites status.json (see events.RunStatus) for runs a
+// mutating command touched, with their status derived as agency ls would.
+// The cli calls it after every mutating command. Best-effort: runs that are
+// gone or broken are skipped, and write errors are ignored.
+func RefreshStatusFiles(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, runs []audit.Run) {
+	if len(runs) == 0 {
+		return
+	}
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return
+	}
+	crypter := LoadCrypt(cr, fsys)
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+	sessions := newTmuxSessionSet(ctx, cr)
+	for _, r := range runs {
+		meta, err := st.ReadMeta(r.RepoID, r.RunID)
+		if err != nil {
+			continue
+		}
+		refreshStatusFile(ctx, cr, st, meta, sessions, dirs.CacheDir)
+	}
+}
+
+// refreshStatusFile derives a run's status and writes it to status.json.
+// cacheDir is where commit counts are cached ("" = not cached).
+func refreshStatusFile(ctx context.Context, cr agencyexec.CommandRunner, st *store.Store, meta *store.RunMeta, sessions *tmuxSessionSet, cacheDir string) {
+	runDir := st.RunDir(meta.RepoID, meta.RunID)
+	if _, err := os.Stat(runDir); err != nil {
+		return
+	}
+
+	worktreePresent := dirExists(meta.WorktreePath)
+	tmuxActive := false
+	if worktreePresent {
+		sessionName := meta.TmuxSessionName
+		if sessionName == "" {
+			sessionName = TmuxSessionPrefix + meta.RunID
+		}
+		tmuxActive = sessions.Active(sessionName)
+	}
+
+	rec := store.RunRecord{RepoID: meta.RepoID, RunID: meta.RunID, Meta: meta}
+	report := readReportSnapshot(ctx, cr, meta, worktreePresent)
+	snapshot := status.Snapshot{
+		TmuxActive:      tmuxActive,
+		WorktreePresent: worktreePresent,
+		ReportBytes:     report.Bytes,
+		ReportStale:     report.Stale,
+		ReportReadyFlag: report.ReadyFlag,
+	}
+	if worktreePresent {
+		snapshot.Policy = newReviewPolicySet(st.FS, st.DataDir).Get(rec)
+		snapshot.DeadlineExceeded = meta.DeadlineExceeded(clock.Now())
+		snapshot.NoCommits = noCommits(newCommitCountSet(ctx, cr, st.FS, cacheDir), rec, report.Bytes, snapshot.Policy)
+	}
+	derived := status.Derive(meta, snapshot)
+
+	_ = events.UpdateStatus(runDir, meta.RepoID, meta.RunID, clock.Now(), func(s *events.RunStatus) {
+		s.DerivedStatus = derived.DerivedStatus
+		s.Archived = !worktreePresent
+		s.CreatedAt = meta.CreatedAt
+		s.LastPushAt = meta.LastPushAt
+		s.PRNumber = meta.PRNumber
+	})
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestRefreshStatusFiles(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	t0 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	clk := testkit.NewClock(t0)
+	defer SetClock(clk.Core())()
+
+	repoID := "abc123"
+	active := "20260110120000-a3f2"
+	archived := "20260110120000-b4e3"
+	activeMeta := testkit.NewRunMeta(repoID, active, t.TempDir(), t0)
+	activeMeta.PRNumber = 12
+	testkit.WriteRun(t, dataDir, activeMeta)
+	testkit.WriteRun(t, dataDir, testkit.NewRunMeta(repoID, archived, filepath.Join(dataDir, "gone"), t0))
+
+	cr := testkit.NewFakeRunner()
+	cr.TmuxSessions("agency_" + active)
+	cr.Fallback = &testkit.Response{}
+
+	runs := []audit.Run{
+		{RepoID: repoID, RunID: active},
+		{RepoID: repoID, RunID: archived},
+		{RepoID: repoID, RunID: "20260110120000-none"},
+	}
+	clk.Advance(time.Minute)
+	RefreshStatusFiles(context.Background(), cr, fs.NewRealFS(), t.TempDir(), runs)
+
+	read := func(runID string) events.RunStatus {
+		t.Helper()
+		data, err := os.ReadFile(events.StatusPath(filepath.Join(dataDir, "repos", repoID, "runs", runID)))
+		if err != nil {
+			t.Fatalf("status.json of %s: %v", runID, err)
+		}
+		var s events.RunStatus
+		if err := json.Unmarshal(data, &s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := read(active)
+	if s.SchemaVersion != events.StatusSchemaVersion || s.DerivedStatus != "active (pr)" || s.Archived || s.PRNumber != 12 ||
+		s.CreatedAt != "2026-01-10T12:00:00Z" || s.UpdatedAt != "2026-01-10T12:01:00Z" {
+		t.Errorf("active run status.json = %+v", s)
+	}
+	if s := read(archived); !s.Archived || s.DerivedStatus == "" {
+		t.Errorf("archived run status.json = %+v", s)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "repos", repoID, "runs", "20260110120000-none")); !os.IsNotExist(err) {
+		t.Errorf("a missing run got a run dir (stat err = %v)", err)
+	}
+}
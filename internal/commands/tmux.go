@@ -0,0 +1,295 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/worktree"
+)
+
+// Reasons a tmux session is an orphan, as printed by tmux prune.
+const (
+	orphanRunDeleted      = "run deleted"
+	orphanRunArchived     = "run archived"
+	orphanWorktreeMissing = "worktree missing"
+)
+
+// TmuxPruneOpts holds options for the tmux prune command.
+type TmuxPruneOpts struct {
+	// DryRun lists the orphaned sessions without killing them.
+	DryRun bool
+
+	// Yes kills the orphaned sessions without asking.
+	Yes bool
+
+	// Confirm asks the user whether to kill the listed sessions.
+	// Nil means non-interactive: without Yes nothing is killed.
+	Confirm func(prompt string) bool
+}
+
+// tmuxSession is a tmux session with its start directory.
+type tmuxSession struct {
+	name string
+	path string
+}
+
+// orphanSession is an agency tmux session that no live run owns.
+type orphanSession struct {
+	name   string
+	runID  string
+	repoID string
+	reason string
+}
+
+// TmuxPrune kills agency_* tmux sessions that no live run of this data dir
+// owns: sessions of deleted runs, of archived runs, and of runs whose
+// worktree is gone. Sessions of live or broken runs, and sessions started in
+// another data dir, are kept.
+//
+// Sessions are listed before runs are scanned, and a run's meta.json is
+// written before its session starts, so a run being created concurrently is
+// never mistaken for an orphan. Each repo's sessions are re-checked and
+// killed under the repo lock; repos locked by another command are skipped
+// with a warning.
+//
+// Error codes:
+//   - E_TMUX_NOT_INSTALLED: tmux could not be run
+//   - E_USAGE: sessions would be killed but neither Yes nor Confirm was given
+func TmuxPrune(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts TmuxPruneOpts, stdout, stderr io.Writer) error {
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+	dataDir := dirs.DataDir
+
+	sessions, err := listAgencySessions(ctx, cr)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Fprintln(stdout, "no agency tmux sessions")
+		return nil
+	}
+
+	records, err := store.ScanAllRuns(dataDir, crypter)
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+	byRunID := recordsByRunID(records)
+
+	var orphans []orphanSession
+	for _, s := range sessions {
+		o, ok := classifySession(s, dataDir, byRunID)
+		if !ok {
+			continue
+		}
+		if o.repoID == "" {
+			fmt.Fprintf(stderr, "note: keeping %s: started in %s, outside this data dir\n", s.name, s.path)
+			continue
+		}
+		orphans = append(orphans, o)
+	}
+	if len(orphans) == 0 {
+		fmt.Fprintf(stdout, "no orphaned agency tmux sessions (%d checked)\n", len(sessions))
+		return nil
+	}
+
+	verb := "orphan"
+	if opts.DryRun {
+		verb = "would kill"
+	}
+	for _, o := range orphans {
+		fmt.Fprintf(stdout, "%s %s (%s)\n", verb, o.name, o.reason)
+	}
+	if opts.DryRun {
+		fmt.Fprintln(stdout, "run 'agency tmux prune' to kill them")
+		return nil
+	}
+
+	if !opts.Yes {
+		if opts.Confirm == nil {
+			return errors.WithHints(errors.New(errors.EUsage,
+				fmt.Sprintf("refusing to kill %d tmux session(s) without confirmation", len(orphans))),
+				"rerun with --yes to kill them without asking")
+		}
+		if !opts.Confirm(fmt.Sprintf("kill %d tmux session(s)?", len(orphans))) {
+			fmt.Fprintln(stdout, "no sessions killed")
+			return nil
+		}
+	}
+
+	byRepo := make(map[string][]orphanSession)
+	var repoIDs []string
+	for _, o := range orphans {
+		if _, ok := byRepo[o.repoID]; !ok {
+			repoIDs = append(repoIDs, o.repoID)
+		}
+		byRepo[o.repoID] = append(byRepo[o.repoID], o)
+	}
+	sort.Strings(repoIDs)
+
+	repoLock := lock.NewRepoLock(dataDir)
+	killed, skipped := 0, 0
+	for _, repoID := range repoIDs {
+		n, err := pruneRepoSessions(ctx, cr, repoLock, dataDir, repoID, byRepo[repoID], crypter, stdout, stderr)
+		killed += n
+		if err != nil {
+			skipped += len(byRepo[repoID]) - n
+			fmt.Fprintf(stderr, "warning: kept %d session(s) of repo %s: %s\n", len(byRepo[repoID])-n, repoID, err.Error())
+		}
+	}
+
+	if skipped > 0 {
+		fmt.Fprintf(stdout, "killed %d tmux session(s); %d kept, run 'agency tmux prune' again later\n", killed, skipped)
+		return nil
+	}
+	fmt.Fprintf(stdout, "killed %d tmux session(s)\n", killed)
+	return nil
+}
+
+// pruneRepoSessions kills the orphaned sessions of one repo under its repo
+// lock, re-checking each against the repo's runs first. Returns how many
+// sessions are gone.
+func pruneRepoSessions(ctx context.Context, cr agencyexec.CommandRunner, repoLock lock.RepoLock, dataDir, repoID string, orphans []orphanSession, crypter *crypt.Crypt, stdout, stderr io.Writer) (int, error) {
+	unlock, err := repoLock.Lock(repoID, "tmux prune")
+	if err != nil {
+		return 0, repoLockError(err, dataDir, repoID)
+	}
+	defer func() { _ = unlock() }()
+
+	records, err := store.ScanRunsForRepo(dataDir, repoID, crypter)
+	if err != nil {
+		return 0, errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+	byRunID := recordsByRunID(records)
+
+	gone := 0
+	for _, o := range orphans {
+		// The run may have changed since the first scan (e.g. restored)
+		if _, still := classifySession(tmuxSession{name: o.name, path: worktree.WorktreePath(dataDir, repoID, o.runID)}, dataDir, byRunID); !still {
+			fmt.Fprintf(stderr, "note: keeping %s: its run is live again\n", o.name)
+			continue
+		}
+
+		result, err := cr.Run(ctx, "tmux", []string{"kill-session", "-t", o.name}, agencyexec.RunOpts{})
+		if err != nil {
+			return gone, errors.Wrap(errors.ETmuxNotInstalled, "failed to kill tmux session", err)
+		}
+		if result.ExitCode != 0 {
+			// Most likely the session ended on its own in the meantime
+			fmt.Fprintf(stderr, "note: %s: %s\n", o.name, strings.TrimSpace(result.Stderr))
+			gone++
+			continue
+		}
+		audit.Touch(repoID, o.runID)
+		fmt.Fprintf(stdout, "killed %s (%s)\n", o.name, o.reason)
+		gone++
+	}
+	return gone, nil
+}
+
+// listAgencySessions returns the tmux sessions named with TmuxSessionPrefix,
+// sorted by name. No running tmux server means no sessions.
+func listAgencySessions(ctx context.Context, cr agencyexec.CommandRunner) ([]tmuxSession, error) {
+	result, err := cr.Run(ctx, "tmux", []string{"list-sessions", "-F", "#{session_name}\t#{session_path}"}, agencyexec.RunOpts{})
+	if err != nil {
+		return nil, errors.Wrap(errors.ETmuxNotInstalled, "failed to list tmux sessions", err)
+	}
+	if result.ExitCode != 0 {
+		return nil, nil
+	}
+
+	var sessions []tmuxSession
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		name, path, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		if !strings.HasPrefix(name, TmuxSessionPrefix) || name == TmuxSessionPrefix {
+			continue
+		}
+		sessions = append(sessions, tmuxSession{name: name, path: path})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].name < sessions[j].name })
+	return sessions, nil
+}
+
+// recordsByRunID indexes run records by run_id (a run_id may exist in
+// several repos).
+func recordsByRunID(records []store.RunRecord) map[string][]store.RunRecord {
+	byRunID := make(map[string][]store.RunRecord, len(records))
+	for _, rec := range records {
+		byRunID[rec.RunID] = append(byRunID[rec.RunID], rec)
+	}
+	return byRunID
+}
+
+// classifySession reports whether s is an orphan. A session whose run_id
+// matches a live or broken run is not. For a deleted run, the repo is taken
+// from the session's start directory; an empty repoID means the session was
+// started outside this data dir and must be kept.
+func classifySession(s tmuxSession, dataDir string, byRunID map[string][]store.RunRecord) (orphanSession, bool) {
+	runID := strings.TrimPrefix(s.name, TmuxSessionPrefix)
+	o := orphanSession{name: s.name, runID: runID}
+
+	recs := byRunID[runID]
+	if len(recs) == 0 {
+		o.reason = orphanRunDeleted
+		o.repoID = worktreeRepoID(dataDir, s.path, runID)
+		return o, true
+	}
+
+	// With several runs of that id, the session is kept if any of them is live
+	for _, rec := range recs {
+		if rec.Broken || rec.Meta == nil {
+			return orphanSession{}, false
+		}
+		switch {
+		case rec.Meta.Archive != nil && rec.Meta.Archive.ArchivedAt != "":
+			o.reason = orphanRunArchived
+		case !dirExists(rec.Meta.WorktreePath):
+			o.reason = orphanWorktreeMissing
+		default:
+			return orphanSession{}, false
+		}
+		o.repoID = rec.RepoID
+	}
+	return o, true
+}
+
+// worktreeRepoID returns the repo_id of path if it is the default worktree
+// path of runID in dataDir (repos/<repo_id>/worktrees/<run_id>), or "".
+func worktreeRepoID(dataDir, path, runID string) string {
+	if path == "" {
+		return ""
+	}
+	for _, base := range []string{dataDir, evalSymlinksOrSelf(dataDir)} {
+		rel, err := filepath.Rel(filepath.Join(base, "repos"), evalSymlinksOrSelf(path))
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) == 3 && parts[0] != ".." && parts[1] == "worktrees" && parts[2] == runID {
+			return parts[0]
+		}
+	}
+	return ""
+}
+
+// evalSymlinksOrSelf resolves symlinks in path, or returns it unchanged if
+// it cannot be resolved (e.g. it no longer exists).
+func evalSymlinksOrSelf(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// setupTmuxPrune seeds a live run, an archived run, and tmux sessions for
+// both plus a deleted run and a session from another data dir.
+func setupTmuxPrune(t *testing.T) (string, *testkit.FakeRunner) {
+	t.Helper()
+	dataDir := testkit.DataDir(t)
+	repoID := "abcd1234ef567890"
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	liveWT := filepath.Join(dataDir, "repos", repoID, "worktrees", "20260110120000-live")
+	if err := os.MkdirAll(liveWT, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	testkit.WriteRun(t, dataDir, testkit.NewRunMeta(repoID, "20260110120000-live", liveWT, created))
+
+	archived := testkit.NewRunMeta(repoID, "20260110120000-arch", filepath.Join(dataDir, "repos", repoID, "worktrees", "20260110120000-arch"), created)
+	archived.Archive = &store.RunMetaArchive{ArchivedAt: "2026-01-11T12:00:00Z"}
+	testkit.WriteRun(t, dataDir, archived)
+
+	cr := testkit.NewFakeRunner()
+	sessions := []string{
+		"agency_20260110120000-arch\t" + filepath.Join(dataDir, "repos", repoID, "worktrees", "20260110120000-arch"),
+		"agency_20260110120000-gone\t" + filepath.Join(dataDir, "repos", repoID, "worktrees", "20260110120000-gone"),
+		"agency_20260110120000-live\t" + liveWT,
+		"agency_20260110120000-othr\t/elsewhere/repos/r/worktrees/20260110120000-othr",
+		"scratch\t/home/u",
+	}
+	cr.On("tmux", "list-sessions", "-F", "#{session_name}\t#{session_path}").Stdout(strings.Join(sessions, "\n") + "\n")
+	cr.OnPrefix("tmux", "kill-session")
+	return dataDir, cr
+}
+
+func TestTmuxPrune_KillsOrphans(t *testing.T) {
+	dataDir, cr := setupTmuxPrune(t)
+
+	var stdout, stderr bytes.Buffer
+	err := TmuxPrune(context.Background(), cr, fs.NewRealFS(), dataDir, TmuxPruneOpts{Yes: true}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("TmuxPrune() error = %v", err)
+	}
+
+	for _, name := range []string{"agency_20260110120000-arch", "agency_20260110120000-gone"} {
+		if !cr.Called("tmux", "kill-session", "-t", name) {
+			t.Errorf("%s was not killed", name)
+		}
+	}
+	for _, name := range []string{"agency_20260110120000-live", "agency_20260110120000-othr", "scratch"} {
+		if cr.Called("tmux", "kill-session", "-t", name) {
+			t.Errorf("%s was killed", name)
+		}
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"killed agency_20260110120000-arch (run archived)",
+		"killed agency_20260110120000-gone (run deleted)",
+		"killed 2 tmux session(s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stdout missing %q:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(stderr.String(), "keeping agency_20260110120000-othr") {
+		t.Errorf("stderr = %q, want a note about the other data dir's session", stderr.String())
+	}
+}
+
+func TestTmuxPrune_DryRunAndConfirmation(t *testing.T) {
+	dataDir, cr := setupTmuxPrune(t)
+
+	var stdout, stderr bytes.Buffer
+	if err := TmuxPrune(context.Background(), cr, fs.NewRealFS(), dataDir, TmuxPruneOpts{DryRun: true}, &stdout, &stderr); err != nil {
+		t.Fatalf("dry run error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "would kill agency_20260110120000-gone (run deleted)") {
+		t.Errorf("dry run stdout = %q", stdout.String())
+	}
+
+	// Non-interactive without --yes refuses
+	err := TmuxPrune(context.Background(), cr, fs.NewRealFS(), dataDir, TmuxPruneOpts{}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("error = %v, want E_USAGE", err)
+	}
+
+	// Declining kills nothing
+	stdout.Reset()
+	declined := TmuxPruneOpts{Confirm: func(string) bool { return false }}
+	if err := TmuxPrune(context.Background(), cr, fs.NewRealFS(), dataDir, declined, &stdout, &stderr); err != nil {
+		t.Fatalf("declined error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "no sessions killed") {
+		t.Errorf("declined stdout = %q", stdout.String())
+	}
+
+	if len(cr.CallsTo("tmux")) == 0 || cr.Called("tmux", "kill-session", "-t", "agency_20260110120000-gone") {
+		t.Error("no session should be killed without confirmation")
+	}
+}
+
+func TestTmuxPrune_SkipsLockedRepo(t *testing.T) {
+	dataDir, cr := setupTmuxPrune(t)
+
+	unlock, err := lock.NewRepoLock(dataDir).Lock("abcd1234ef567890", "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = unlock() }()
+
+	var stdout, stderr bytes.Buffer
+	if err := TmuxPrune(context.Background(), cr, fs.NewRealFS(), dataDir, TmuxPruneOpts{Yes: true}, &stdout, &stderr); err != nil {
+		t.Fatalf("TmuxPrune() error = %v", err)
+	}
+	if cr.Called("tmux", "kill-session", "-t", "agency_20260110120000-gone") {
+		t.Error("sessions of a locked repo should be kept")
+	}
+	if !strings.Contains(stderr.String(), "kept 2 session(s) of repo abcd1234ef567890") {
+		t.Errorf("stderr = %q, want a locked-repo warning", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "killed 0 tmux session(s); 2 kept") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+}
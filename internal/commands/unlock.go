@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/ids"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// UnlockOpts holds options for the unlock command.
+type UnlockOpts struct {
+	// Target is a repo (repo_id, repo_key, or path inside the repo) or a
+	// run_id (exact or unique prefix) whose repo lock is removed.
+	Target string
+
+	// Yes removes the lock without asking.
+	Yes bool
+
+	// Confirm asks the user whether to remove the lock.
+	// Nil means non-interactive: without Yes the lock is kept.
+	Confirm func(prompt string) bool
+}
+
+// Unlock forcibly removes a repo lock left behind by an agency command that
+// crashed or was killed. Locks are per repo, so a run target unlocks its repo.
+//
+// Error codes:
+//   - E_REPO_NOT_FOUND: target matches no repo or run
+//   - E_RUN_ID_AMBIGUOUS: target is a run id prefix matching several runs
+//   - E_REPO_LOCKED: the user declined, or no confirmation was possible
+func Unlock(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts UnlockOpts, stdout, stderr io.Writer) error {
+	if opts.Target == "" {
+		return errors.New(errors.EUsage, "repo or run_id is required")
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+
+	repoID, err := resolveUnlockTarget(ctx, cr, dirs.DataDir, cwd, opts.Target, LoadCrypt(cr, fsys))
+	if err != nil {
+		return err
+	}
+
+	locks := newRepoLockSet(dirs.DataDir)
+	held := locks.Get(repoID)
+	if held == nil {
+		fmt.Fprintf(stdout, "repo %s is not locked\n", repoID)
+		return nil
+	}
+	holder := render.FormatLock(held, clock.Now())
+
+	if !opts.Yes {
+		if !held.Stale {
+			fmt.Fprintf(stderr, "warning: the lock holder is still running; removing its lock lets another agency command modify repo %s at the same time\n", repoID)
+		}
+		if opts.Confirm == nil || !opts.Confirm(fmt.Sprintf("remove lock on repo %s (%s)?", repoID, holder)) {
+			return errors.WithHints(errors.NewWithDetails(errors.ERepoLocked,
+				"lock on repo "+repoID+" kept ("+holder+")",
+				map[string]string{"repo_id": repoID, "lock_path": held.Path}),
+				"rerun with --yes to remove it without asking")
+		}
+	}
+
+	if err := locks.repoLock.ForceUnlock(repoID); err != nil {
+		return errors.WrapWithDetails(errors.EInternal, "failed to remove lock file", err,
+			map[string]string{"lock_path": held.Path})
+	}
+	fmt.Fprintf(stdout, "unlocked repo %s (was held by %s)\n", repoID, holder)
+	return nil
+}
+
+// resolveUnlockTarget maps an unlock target to a repo_id. Repo values are
+// tried first (as for --repo), then run ids; broken runs are accepted since
+// only their repo is needed.
+func resolveUnlockTarget(ctx context.Context, cr agencyexec.CommandRunner, dataDir, cwd, target string, crypter *crypt.Crypt) (string, error) {
+	if repoID, err := resolveRepoFlag(ctx, cr, dataDir, target); err == nil {
+		return repoID, nil
+	}
+
+	records, err := store.ScanAllRuns(dataDir, crypter)
+	if err != nil {
+		return "", errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+	scope, err := newRunScope(ctx, cr, dataDir, cwd, "")
+	if err != nil {
+		return "", err
+	}
+	ref, err := resolveRunRef(records, target, scope)
+	if err != nil {
+		if ambErr, ok := err.(*ids.ErrAmbiguous); ok {
+			return "", ambiguousRunIDError(ambErr)
+		}
+		return "", errors.NewWithDetails(errors.ERepoNotFound,
+			"no agency repo or run matches "+target+" (expected a repo_id, repo_key, path inside a repo, or run_id)",
+			map[string]string{"target": target})
+	}
+	return ref.RepoID, nil
+}
+
+// repoLockError maps a RepoLock.Lock failure to its error code:
+// E_REPO_LOCKED when another command holds the lock, E_PERMISSION_DENIED
+// when another user of a shared data dir owns the repo dir.
+func repoLockError(err error, dataDir, repoID string) error {
+	if _, ok := err.(*lock.ErrLocked); ok {
+		return errors.Wrap(errors.ERepoLocked, err.Error(), err)
+	}
+	if stderrors.Is(err, os.ErrPermission) {
+		return store.PermissionDenied(err, filepath.Join(dataDir, "repos", repoID), "")
+	}
+	return errors.Wrap(errors.EInternal, "failed to acquire repo lock", err)
+}
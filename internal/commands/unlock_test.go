@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/lock"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestLockVisibilityAndUnlock(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+
+	lockedAt := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	defer SetClock(testkit.NewClock(lockedAt.Add(3 * time.Minute)).Core())()
+
+	createValidMetaForLS(t, dataDir, "r1", "20260110120000-a3f2", lockedAt)
+	createValidMetaForLS(t, dataDir, "r2", "20260110130000-b4c1", lockedAt)
+	repoLock := lock.NewRepoLock(dataDir)
+	repoLock.Now = func() time.Time { return lockedAt }
+	repoLock.User, repoLock.Host = "alice", "devbox"
+	if _, err := repoLock.Lock("r1", "mv"); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	lockPath := filepath.Join(dataDir, "repos", "r1", ".lock")
+	ctx := context.Background()
+	cwd := t.TempDir()
+
+	// ls reports the lock for runs in the locked repo only
+	var stdout bytes.Buffer
+	if err := LS(ctx, newMockRunner(), fs.NewRealFS(), cwd, LSOpts{JSON: true, All: true}, &stdout, io.Discard); err != nil {
+		t.Fatalf("LS() error = %v", err)
+	}
+	var env render.LSJSONEnvelope
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(env.Data) != 2 {
+		t.Fatalf("ls data = %+v, want 2 runs", env.Data)
+	}
+	for _, s := range env.Data {
+		if s.RepoID == "r2" {
+			if s.Lock != nil {
+				t.Errorf("r2 lock = %+v, want nil", s.Lock)
+			}
+			continue
+		}
+		l := s.Lock
+		if l == nil || l.PID == nil || *l.PID != os.Getpid() || l.Cmd == nil || *l.Cmd != "mv" || l.User == nil || *l.User != "alice" || l.AgeSeconds != 180 || l.Stale || l.Path != lockPath {
+			t.Errorf("r1 lock = %+v", l)
+		}
+	}
+
+	stdout.Reset()
+	if err := LS(ctx, newMockRunner(), fs.NewRealFS(), cwd, LSOpts{All: true}, &stdout, io.Discard); err != nil {
+		t.Fatalf("LS() error = %v", err)
+	}
+	if want := "(locked: mv pid " + strconv.Itoa(os.Getpid()) + " (alice@devbox), 3 mins ago)"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("human ls missing %q:\n%s", want, stdout.String())
+	}
+
+	unlock := func(opts UnlockOpts) (string, error) {
+		var stdout bytes.Buffer
+		err := Unlock(ctx, newMockRunner(), fs.NewRealFS(), cwd, opts, &stdout, io.Discard)
+		return stdout.String(), err
+	}
+
+	// Declined or unconfirmable: the lock stays
+	var prompt string
+	_, err := unlock(UnlockOpts{Target: "20260110120000", Confirm: func(p string) bool { prompt = p; return false }})
+	if errors.GetCode(err) != errors.ERepoLocked || !strings.Contains(prompt, "remove lock on repo r1 (mv pid") {
+		t.Errorf("declined: err = %v, prompt = %q", err, prompt)
+	}
+	if _, err := unlock(UnlockOpts{Target: "r1"}); errors.GetCode(err) != errors.ERepoLocked {
+		t.Errorf("non-interactive: err = %v, want E_REPO_LOCKED", err)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("lock file removed without confirmation: %v", err)
+	}
+
+	// Unknown targets fail before touching any lock
+	if _, err := unlock(UnlockOpts{Target: "nope", Yes: true}); errors.GetCode(err) != errors.ERepoNotFound {
+		t.Errorf("unknown target: err = %v, want E_REPO_NOT_FOUND", err)
+	}
+
+	// A run id unlocks its repo
+	out, err := unlock(UnlockOpts{Target: "20260110120000-a3f2", Yes: true})
+	if err != nil || !strings.Contains(out, "unlocked repo r1") {
+		t.Errorf("unlock --yes: out = %q, err = %v", out, err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("lock file still present: %v", err)
+	}
+	if out, err := unlock(UnlockOpts{Target: "r1"}); err != nil || out != "repo r1 is not locked\n" {
+		t.Errorf("unlocked repo: out = %q, err = %v", out, err)
+	}
+}
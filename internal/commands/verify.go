@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/audit"
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/runservice"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// DefaultVerifyJobs is the number of runs verified at a time when --jobs is
+// not given.
+const DefaultVerifyJobs = 4
+
+// VerifyOpts holds options for the verify command.
+type VerifyOpts struct {
+	// RunIDs are the run identifiers (exact or unique prefix), already expanded from stdin.
+	RunIDs []string
+
+	// All verifies every unarchived run of the current repo (every repo's
+	// with AllRepos, or when cwd is not inside a repo) instead of RunIDs.
+	All bool
+
+	// AllRepos extends All to every repo.
+	AllRepos bool
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+
+	// Jobs is the number of runs verified at a time (0 = DefaultVerifyJobs).
+	Jobs int
+}
+
+// Verify executes the agency verify command: scripts.verify runs in the
+// worktree of each run (see runservice.RunVerify), recording the attempt in
+// the run's verify.jsonl and its output in logs/verify.log. Up to opts.Jobs
+// runs are verified at a time.
+//
+// With a single run, the outcome is printed as one line; a failed verify
+// returns E_VERIFY_FAILED. With several runs (or --all), a summary table is
+// printed once every run is done, and the bulk convention applies:
+// failures are listed on stderr and E_BULK_FAILED is returned if any run
+// failed or could not be verified.
+//
+// scripts.verify is read from the agency.json of the repo's checkout (of
+// the worktree if no checkout is known). Verify does not take the repo lock,
+// so long verify scripts do not block other commands.
+func Verify(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts VerifyOpts, stdout, stderr io.Writer) error {
+	switch {
+	case opts.All && len(opts.RunIDs) > 0:
+		return errors.New(errors.EUsage, "--all cannot be combined with run ids")
+	case !opts.All && len(opts.RunIDs) == 0:
+		return errors.New(errors.EUsage, "run_id or --all is required")
+	case opts.AllRepos && !opts.All:
+		return errors.New(errors.EUsage, "--all-repos requires --all")
+	case opts.Jobs < 0:
+		return errors.New(errors.EUsage, "--jobs must be at least 1")
+	}
+	jobs := opts.Jobs
+	if jobs == 0 {
+		jobs = DefaultVerifyJobs
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+	idx, _ := store.LoadRepoIndexForScan(dirs.DataDir)
+
+	// resolve maps an input to its run: --all inputs are run ids of records
+	// already scanned, others are resolved like any run id argument
+	var resolve func(input string) (*store.RunRecord, error)
+	runIDs := opts.RunIDs
+	if opts.All {
+		records, err := scanReportRuns(ctx, cr, dirs.DataDir, cwd, opts.AllRepos, crypter)
+		if err != nil {
+			return err
+		}
+		byID := make(map[string]*store.RunRecord)
+		runIDs = nil
+		for i := range records {
+			rec := &records[i]
+			if rec.Broken || rec.Meta == nil {
+				fmt.Fprintf(stderr, "warning: skipping broken run %s\n", rec.RunID)
+				continue
+			}
+			if rec.Meta.Archive != nil && rec.Meta.Archive.ArchivedAt != "" {
+				continue
+			}
+			byID[rec.RunID] = rec
+			runIDs = append(runIDs, rec.RunID)
+		}
+		if len(runIDs) == 0 {
+			fmt.Fprintln(stdout, "no runs to verify")
+			return nil
+		}
+		sort.Strings(runIDs)
+		resolve = func(runID string) (*store.RunRecord, error) {
+			rec := byID[runID]
+			audit.Touch(rec.RepoID, rec.RunID)
+			return rec, nil
+		}
+	} else {
+		scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+		if err != nil {
+			return err
+		}
+		resolve = func(input string) (*store.RunRecord, error) {
+			return resolveRun(dirs.DataDir, input, scope, crypter)
+		}
+	}
+
+	var mu sync.Mutex
+	rows := make(map[string]render.VerifyRow, len(runIDs))
+	verifyOne := func(input string) error {
+		row, err := verifyRun(ctx, cr, fsys, st, idx, input, resolve)
+		mu.Lock()
+		rows[input] = row
+		mu.Unlock()
+		return err
+	}
+
+	if !opts.All && len(runIDs) == 1 {
+		err := verifyOne(runIDs[0])
+		row := rows[runIDs[0]]
+		if row.Result == render.VerifyError {
+			return err
+		}
+		outcome := "passed"
+		if row.Result == render.VerifyFail {
+			outcome = "failed"
+		}
+		fmt.Fprintf(stdout, "%s: verify %s (%s)", row.RunID, outcome, formatVerifyDuration(row.DurationMs))
+		if row.Detail != "" {
+			fmt.Fprintf(stdout, ": %s", row.Detail)
+		}
+		fmt.Fprintf(stdout, "\nlog: %s\n", row.LogPath)
+		return err
+	}
+
+	// Failures and the bulk line go after the table
+	var bulkOut bytes.Buffer
+	bulkErr := RunBulkParallel(runIDs, jobs, &bulkOut, verifyOne)
+	ordered := make([]render.VerifyRow, len(runIDs))
+	for i, input := range runIDs {
+		ordered[i] = rows[input]
+	}
+	if err := render.WriteVerifySummary(stdout, ordered); err != nil {
+		return err
+	}
+	_, _ = stderr.Write(bulkOut.Bytes())
+	return bulkErr
+}
+
+// verifyRun verifies the run input resolves to and returns its summary row.
+// Returns E_VERIFY_FAILED if the verification failed, or the error that
+// kept it from running (the row's Result is then render.VerifyError).
+func verifyRun(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, st *store.Store, idx *store.RepoIndex, input string, resolve func(string) (*store.RunRecord, error)) (render.VerifyRow, error) {
+	row := render.VerifyRow{RunID: input, Result: render.VerifyError}
+	rec, err := resolve(input)
+	if err != nil {
+		row.Detail = errorDetail(err)
+		return row, err
+	}
+	row.RunID, row.Repo = rec.RunID, rec.RepoID
+	if rec.Repo != nil && rec.Repo.RepoKey != "" {
+		row.Repo = rec.Repo.RepoKey
+	}
+
+	repoRoot, cfg, err := verifyConfig(fsys, idx, rec)
+	if err != nil {
+		row.Detail = errorDetail(err)
+		return row, err
+	}
+	originURL := ""
+	if rec.Repo != nil && rec.Repo.OriginURL != nil {
+		originURL = *rec.Repo.OriginURL
+	}
+	res, err := runservice.RunVerify(ctx, cr, fsys, st, rec.Meta, runservice.VerifyOpts{
+		Script:    cfg.Scripts.Verify,
+		RepoRoot:  repoRoot,
+		OriginURL: originURL,
+		Logs:      cfg.Logs,
+	})
+	if err != nil {
+		row.Detail = errorDetail(err)
+		return row, err
+	}
+
+	row.DurationMs = res.Attempt.DurationMs
+	row.Detail = res.Attempt.Summary
+	row.LogPath = res.LogPath
+	if res.Attempt.OK {
+		row.Result = render.VerifyPass
+		return row, nil
+	}
+	row.Result = render.VerifyFail
+	msg := "verify failed"
+	if res.Attempt.Summary != "" {
+		msg += ": " + res.Attempt.Summary
+	}
+	return row, errors.NewWithDetails(errors.EVerifyFailed, msg, map[string]string{
+		"exit_code": fmt.Sprintf("%d", res.ExitCode),
+		"log_path":  res.LogPath,
+	})
+}
+
+// verifyConfig returns a checkout of rec's repo ("" if none is known) and
+// the agency.json scripts.verify is read from: the checkout's, or the
+// worktree's if there is no checkout.
+// Returns E_SCRIPT_NOT_FOUND if scripts.verify is not set.
+func verifyConfig(fsys fs.FS, idx *store.RepoIndex, rec *store.RunRecord) (string, config.AgencyConfig, error) {
+	var repoRoot string
+	if rec.Repo != nil {
+		if picked := store.PickRepoRoot(rec.Repo.RepoKey, nil, idx); picked != nil {
+			repoRoot = *picked
+		}
+	}
+	dir := repoRoot
+	if dir == "" {
+		dir = rec.Meta.WorktreePath
+	}
+	cfg, err := config.LoadAgencyConfig(fsys, dir)
+	if err != nil {
+		return repoRoot, cfg, err
+	}
+	if cfg.Scripts.Verify == "" {
+		return repoRoot, cfg, errors.WithHints(
+			errors.NewWithDetails(errors.EScriptNotFound, "scripts.verify is not set in agency.json", map[string]string{"agency_json_dir": dir}),
+			"set scripts.verify in agency.json to a script that checks the run's work",
+		)
+	}
+	return repoRoot, cfg, nil
+}
+
+// errorDetail is the one-line form of err for the summary table.
+func errorDetail(err error) string {
+	if ae, ok := errors.AsAgencyError(err); ok {
+		return string(ae.Code) + ": " + ae.Msg
+	}
+	return err.Error()
+}
+
+// formatVerifyDuration renders a verify duration, e.g. "1.5s".
+func formatVerifyDuration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// adoptForVerify adopts branch as runID and writes check.sh, the verify
+// script of these tests, into its worktree.
+func adoptForVerify(t *testing.T, repoRoot, branch, runID, check string) *store.RunMeta {
+	t.Helper()
+	testkit.Git(t, repoRoot, "branch", branch)
+	if err := Adopt(context.Background(), agencyexec.NewRealRunner(), fs.NewRealFS(), repoRoot, AdoptOpts{Branch: branch, RunID: runID}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Adopt(%s): %v", branch, err)
+	}
+	records, err := store.ScanAllRuns(os.Getenv("AGENCY_DATA_DIR"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range records {
+		if rec.RunID == runID {
+			if err := os.WriteFile(filepath.Join(rec.Meta.WorktreePath, "check.sh"), []byte(check), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			return rec.Meta
+		}
+	}
+	t.Fatalf("run %s not found", runID)
+	return nil
+}
+
+func TestVerify(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	agencyJSON := strings.Replace(testkit.DefaultAgencyJSON, `"scripts/agency_verify.sh"`, `"sh check.sh"`, 1)
+	repoRoot := testkit.NewRepo(t, testkit.RepoOpts{Git: true, AgencyJSON: agencyJSON})
+
+	pass := adoptForVerify(t, repoRoot, "feature/pass", "verify-pass",
+		`echo '{"ok": true, "summary": "12 tests passed"}' > "$AGENCY_OUTPUT_DIR/verify.json"`+"\n")
+	fail := adoptForVerify(t, repoRoot, "feature/fail", "verify-fail", "echo broken >&2\nexit 3\n")
+	archived := adoptForVerify(t, repoRoot, "feature/old", "verify-old", "exit 0\n")
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	if err := st.UpdateMeta(archived.RepoID, archived.RunID, func(m *store.RunMeta) {
+		m.Archive = &store.RunMetaArchive{ArchivedAt: "2026-01-10T12:00:00Z"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cr := agencyexec.NewRealRunner()
+	ctx := context.Background()
+
+	// Single run
+	var stdout bytes.Buffer
+	if err := Verify(ctx, cr, fs.NewRealFS(), repoRoot, VerifyOpts{RunIDs: []string{"verify-pass"}}, &stdout, io.Discard); err != nil {
+		t.Fatalf("Verify(pass): %v", err)
+	}
+	if !strings.HasPrefix(stdout.String(), "verify-pass: verify passed (") || !strings.Contains(stdout.String(), "): 12 tests passed\nlog: ") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	err := Verify(ctx, cr, fs.NewRealFS(), repoRoot, VerifyOpts{RunIDs: []string{"verify-fail"}}, io.Discard, io.Discard)
+	if errors.GetCode(err) != errors.EVerifyFailed {
+		t.Errorf("Verify(fail): code = %q, want %q", errors.GetCode(err), errors.EVerifyFailed)
+	}
+	logPath := filepath.Join(st.RunLogsDir(fail.RepoID, fail.RunID), "verify.log")
+	if data, err := os.ReadFile(logPath); err != nil || !strings.Contains(string(data), "broken") {
+		t.Errorf("verify.log = %q, %v", data, err)
+	}
+
+	// --all: every unarchived run of the repo, with a summary table
+	stdout.Reset()
+	var stderr bytes.Buffer
+	err = Verify(ctx, cr, fs.NewRealFS(), repoRoot, VerifyOpts{All: true, Jobs: 2}, &stdout, &stderr)
+	if errors.GetCode(err) != errors.EBulkFailed {
+		t.Errorf("Verify(--all): code = %q, want %q", errors.GetCode(err), errors.EBulkFailed)
+	}
+	out := stdout.String()
+	for _, want := range []string{"RUN_ID", "verify-fail", "fail", "exit 3", logPath, "verify-pass", "12 tests passed", "verified 2 run(s): 1 passed, 1 failed, 0 could not run"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "verify-old") {
+		t.Errorf("archived run verified:\n%s", out)
+	}
+	if !strings.Contains(stderr.String(), "failed: verify-fail: E_VERIFY_FAILED: verify failed: exit 3\nbulk: 1 ok, 1 failed") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+
+	history, err := st.ReadVerifyHistory(pass.RepoID, pass.RunID)
+	if err != nil || len(history) != 2 || !history[1].OK || history[1].Summary != "12 tests passed" {
+		t.Errorf("verify history = %+v, %v", history, err)
+	}
+	meta, err := st.ReadMeta(fail.RepoID, fail.RunID)
+	if err != nil || meta.LastVerifyAt == "" {
+		t.Errorf("last_verify_at not set: %+v, %v", meta, err)
+	}
+	if meta.ReportCommit != "" {
+		t.Errorf("report_commit set by a failed verify: %q", meta.ReportCommit)
+	}
+	meta, err = st.ReadMeta(pass.RepoID, pass.RunID)
+	head := strings.TrimSpace(testkit.Git(t, pass.WorktreePath, "rev-parse", "HEAD"))
+	if err != nil || meta.ReportCommit != head {
+		t.Errorf("report_commit = %q, want %q (%v)", meta.ReportCommit, head, err)
+	}
+}
+
+func TestVerify_Usage(t *testing.T) {
+	testkit.DataDir(t)
+	for _, opts := range []VerifyOpts{
+		{},
+		{All: true, RunIDs: []string{"a"}},
+		{AllRepos: true, RunIDs: []string{"a"}},
+		{RunIDs: []string{"a"}, Jobs: -1},
+	} {
+		err := Verify(context.Background(), testkit.NewFakeRunner(), fs.NewRealFS(), t.TempDir(), opts, io.Discard, io.Discard)
+		if errors.GetCode(err) != errors.EUsage {
+			t.Errorf("%+v: code = %q, want %q", opts, errors.GetCode(err), errors.EUsage)
+		}
+	}
+}
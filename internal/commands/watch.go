@@ -0,0 +1,330 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/redact"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// DefaultWatchTick is how often watch looks for probes that are due.
+const DefaultWatchTick = 5 * time.Second
+
+// probeOutputMaxBytes caps the output tail kept in a probe event.
+const probeOutputMaxBytes = 512
+
+// WatchOpts holds options for the watch command.
+type WatchOpts struct {
+	// Repo restricts watching to one repo (repo_id, repo_key, or path).
+	Repo string
+
+	// Once runs the probes that are due once and exits.
+	Once bool
+
+	// Tick is how often due probes are looked for (DefaultWatchTick if zero).
+	Tick time.Duration
+}
+
+// probeTarget is an active run with the probes of its repo's agency.json.
+type probeTarget struct {
+	record   store.RunRecord
+	repoRoot string
+	probes   []config.Probe
+}
+
+// probeResult is the outcome of one probe check.
+type probeResult struct {
+	ok         bool
+	exitCode   int
+	timedOut   bool
+	durationMs int64
+	output     string
+}
+
+// Watch evaluates agency.json probes for active runs (worktree present and
+// tmux session alive) until ctx is done, or once with Once. Each probe runs
+// when its interval has passed since the run's last check, as recorded in
+// meta.json probes, so intervals carry over between invocations. Every check
+// appends a probe event to the run's events.jsonl; after a probe's failures
+// threshold of consecutive failures the run is flagged needs_attention, and
+// the flag is cleared when the probes that set it pass again.
+//
+// agency.json is re-read on every pass, from each repo's last seen root.
+func Watch(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts WatchOpts, stdout, stderr io.Writer) error {
+	if opts.Tick < 0 {
+		return errors.New(errors.EUsage, "--tick must not be negative")
+	}
+	tick := opts.Tick
+	if tick == 0 {
+		tick = DefaultWatchTick
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+	repoID := ""
+	if opts.Repo != "" {
+		if repoID, err = resolveRepoFlag(ctx, cr, dirs.DataDir, opts.Repo); err != nil {
+			return err
+		}
+	}
+	st := store.NewStore(fsys, dirs.DataDir, clock.Now).WithCrypt(crypter)
+
+	for first := true; ; first = false {
+		targets, configured, err := findProbeTargets(ctx, cr, fsys, dirs.DataDir, repoID, crypter, stderr)
+		if err != nil {
+			return err
+		}
+		if first && !configured {
+			fmt.Fprintln(stderr, "no watched repo defines probes in agency.json")
+		}
+		for _, t := range targets {
+			for _, p := range t.probes {
+				if ctx.Err() != nil {
+					return nil
+				}
+				checkProbe(ctx, cr, st, t, p, stdout, stderr)
+			}
+		}
+		if opts.Once {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(tick):
+		}
+	}
+}
+
+// findProbeTargets returns the active runs (in repoID, or all repos if empty)
+// whose repo defines probes; configured reports whether any repo does.
+func findProbeTargets(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, dataDir, repoID string, crypter *crypt.Crypt, stderr io.Writer) (targets []probeTarget, configured bool, err error) {
+	records, err := store.ScanAllRuns(dataDir, crypter)
+	if err != nil {
+		return nil, false, errors.Wrap(errors.EInternal, "failed to scan runs", err)
+	}
+	idx, _ := store.LoadRepoIndexForScan(dataDir)
+	tmuxSessions := newTmuxSessionSet(ctx, cr)
+
+	type repoProbes struct {
+		root   string
+		probes []config.Probe
+	}
+	byRepo := make(map[string]*repoProbes)
+
+	for _, rec := range records {
+		if rec.Broken || rec.Repo == nil || (repoID != "" && rec.RepoID != repoID) {
+			continue
+		}
+		rp, seen := byRepo[rec.RepoID]
+		if !seen {
+			rp = nil
+			if root := store.PickRepoRoot(rec.Repo.RepoKey, nil, idx); root != nil {
+				cfg, err := config.LoadAgencyConfig(fsys, *root)
+				if err != nil {
+					fmt.Fprintf(stderr, "warning: skipping repo %s: %s\n", rec.Repo.RepoKey, err.Error())
+				} else if len(cfg.Probes) > 0 {
+					rp = &repoProbes{root: *root, probes: cfg.Probes}
+				}
+			}
+			byRepo[rec.RepoID] = rp
+		}
+		if rp == nil {
+			continue
+		}
+		configured = true
+
+		meta := rec.Meta
+		if meta.Archive != nil || (meta.Flags != nil && meta.Flags.Abandoned) || !dirExists(meta.WorktreePath) {
+			continue
+		}
+		sessionName := meta.TmuxSessionName
+		if sessionName == "" {
+			sessionName = "agency_" + rec.RunID
+		}
+		if !tmuxSessions.Active(sessionName) {
+			continue
+		}
+		targets = append(targets, probeTarget{record: rec, repoRoot: rp.root, probes: rp.probes})
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].record.RunID < targets[j].record.RunID
+	})
+	return targets, configured, nil
+}
+
+// checkProbe runs p for t if it is due, records the result in meta.json and
+// events.jsonl, and prints it. Failures to record are warnings.
+func checkProbe(ctx context.Context, cr agencyexec.CommandRunner, st *store.Store, t probeTarget, p config.Probe, stdout, stderr io.Writer) {
+	rec := t.record
+	if prev, ok := rec.Meta.Probes[p.Name]; ok {
+		if last, err := time.Parse(time.RFC3339, prev.LastCheckAt); err == nil && clock.Now().Before(last.Add(time.Duration(p.Interval())*time.Second)) {
+			return
+		}
+	}
+
+	result := runProbe(ctx, cr, rec.Meta, t.repoRoot, p)
+	if ctx.Err() != nil {
+		// Interrupted: the check says nothing about the run
+		return
+	}
+	now := clock.Now()
+
+	var state store.RunMetaProbe
+	var flagged, cleared bool
+	if err := st.UpdateMeta(rec.RepoID, rec.RunID, func(m *store.RunMeta) {
+		state, flagged, cleared = applyProbeResult(m, p, result, now)
+	}); err != nil {
+		fmt.Fprintf(stderr, "warning: %s: failed to record probe %s: %v\n", rec.RunID, p.Name, err)
+		return
+	}
+
+	eventsPath := events.EventsPath(rec.RunDir)
+	data := map[string]any{
+		"probe":                p.Name,
+		"ok":                   result.ok,
+		"exit_code":            result.exitCode,
+		"duration_ms":          result.durationMs,
+		"timed_out":            result.timedOut,
+		"consecutive_failures": state.ConsecutiveFailures,
+	}
+	if !result.ok && result.output != "" {
+		data["output"] = result.output
+	}
+	_ = events.AppendEvent(eventsPath, events.New(now, rec.RepoID, rec.RunID, "probe", data))
+
+	stamp := now.UTC().Format(time.RFC3339)
+	switch {
+	case result.ok:
+		fmt.Fprintf(stdout, "%s %s %s ok (%dms)\n", stamp, rec.RunID, p.Name, result.durationMs)
+	case result.timedOut:
+		fmt.Fprintf(stdout, "%s %s %s FAIL timed out after %ds (%d/%d)\n", stamp, rec.RunID, p.Name, p.Timeout(), state.ConsecutiveFailures, p.FailureThreshold())
+	default:
+		fmt.Fprintf(stdout, "%s %s %s FAIL exit %d (%d/%d)\n", stamp, rec.RunID, p.Name, result.exitCode, state.ConsecutiveFailures, p.FailureThreshold())
+	}
+
+	if flagged {
+		reason := fmt.Sprintf("probe %s failed %d times in a row", p.Name, state.ConsecutiveFailures)
+		_ = events.AppendEvent(eventsPath, events.New(now, rec.RepoID, rec.RunID, "needs_attention", map[string]any{
+			"probe":  p.Name,
+			"reason": reason,
+		}))
+		fmt.Fprintf(stdout, "%s %s needs attention: %s\n", stamp, rec.RunID, reason)
+	}
+	if cleared {
+		_ = events.AppendEvent(eventsPath, events.New(now, rec.RepoID, rec.RunID, "attention_cleared", map[string]any{
+			"probe": p.Name,
+		}))
+		fmt.Fprintf(stdout, "%s %s recovered: probe %s passed, needs attention cleared\n", stamp, rec.RunID, p.Name)
+	}
+	if flagged || cleared {
+		// The derived status changed: dashboards should not wait for watch to exit
+		if meta, err := st.ReadMeta(rec.RepoID, rec.RunID); err == nil {
+			refreshStatusFile(ctx, cr, st, meta, newTmuxSessionSet(ctx, cr), "")
+		}
+	}
+}
+
+// runProbe runs p's command with `sh -c` in the run's worktree.
+func runProbe(ctx context.Context, cr agencyexec.CommandRunner, meta *store.RunMeta, repoRoot string, p config.Probe) probeResult {
+	timeout := time.Duration(p.Timeout()) * time.Second
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	res, err := cr.Run(probeCtx, "sh", []string{"-c", p.Command}, agencyexec.RunOpts{
+		Dir: meta.WorktreePath,
+		Env: map[string]string{
+			"AGENCY_RUN_ID":         meta.RunID,
+			"AGENCY_TITLE":          meta.Title,
+			"AGENCY_REPO_ROOT":      repoRoot,
+			"AGENCY_WORKSPACE_ROOT": meta.WorktreePath,
+			"AGENCY_BRANCH":         meta.Branch,
+			"AGENCY_PROBE":          p.Name,
+			"AGENCY_NONINTERACTIVE": "1",
+		},
+	})
+	result := probeResult{exitCode: res.ExitCode, durationMs: time.Since(start).Milliseconds()}
+
+	output := res.Stdout + res.Stderr
+	switch {
+	case probeCtx.Err() == context.DeadlineExceeded:
+		result.exitCode = -1
+		result.timedOut = true
+	case err != nil:
+		result.exitCode = -1
+		output = err.Error()
+	default:
+		result.ok = res.ExitCode == 0
+	}
+	if len(output) > probeOutputMaxBytes {
+		output = output[len(output)-probeOutputMaxBytes:]
+	}
+	result.output, _ = redact.String(output)
+	return result
+}
+
+// applyProbeResult records r for p in m and updates flags.needs_attention:
+// the probe sets it when its consecutive failures reach the threshold (unless
+// something other than a probe already set it), and the success of the last
+// probe holding it clears it. It reports whether the flag was set or cleared.
+func applyProbeResult(m *store.RunMeta, p config.Probe, r probeResult, now time.Time) (state store.RunMetaProbe, flagged, cleared bool) {
+	if m.Probes == nil {
+		m.Probes = make(map[string]store.RunMetaProbe)
+	}
+	held := probesHoldAttention(m.Probes)
+	prev := m.Probes[p.Name]
+	state = store.RunMetaProbe{
+		LastCheckAt: now.UTC().Format(time.RFC3339),
+		OK:          r.ok,
+		ExitCode:    r.exitCode,
+		TimedOut:    r.timedOut,
+	}
+
+	if !r.ok {
+		state.ConsecutiveFailures = prev.ConsecutiveFailures + 1
+		state.Flagged = prev.Flagged
+		externallySet := m.Flags != nil && m.Flags.NeedsAttention && !held
+		if !prev.Flagged && !externallySet && state.ConsecutiveFailures >= p.FailureThreshold() {
+			state.Flagged = true
+			flagged = !held
+			if m.Flags == nil {
+				m.Flags = &store.RunMetaFlags{}
+			}
+			m.Flags.NeedsAttention = true
+		}
+		m.Probes[p.Name] = state
+		return state, flagged, false
+	}
+
+	m.Probes[p.Name] = state
+	if prev.Flagged && !probesHoldAttention(m.Probes) && m.Flags != nil {
+		m.Flags.NeedsAttention = false
+		cleared = true
+	}
+	return state, false, cleared
+}
+
+// probesHoldAttention reports whether any probe holds flags.needs_attention.
+func probesHoldAttention(probes map[string]store.RunMetaProbe) bool {
+	for _, s := range probes {
+		if s.Flagged {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestWatch_ProbesFlagAndClearNeedsAttention(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	repoID := "abc123"
+	setupGCRepo(t, dataDir, repoID, "github:owner/repo", `{
+		"version": 1,
+		"probes": [{"name": "dev", "command": "curl -fsS localhost:3000", "failures": 2}]
+	}`)
+
+	t0 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	clk := testkit.NewClock(t0)
+	defer SetClock(clk.Core())()
+
+	active := "20260110120000-a3f2"
+	idle := "20260110120000-b4e3"
+	archived := "20260110120000-c5d4"
+	testkit.WriteRun(t, dataDir, testkit.NewRunMeta(repoID, active, t.TempDir(), t0))
+	testkit.WriteRun(t, dataDir, testkit.NewRunMeta(repoID, idle, t.TempDir(), t0))
+	testkit.WriteRun(t, dataDir, testkit.NewRunMeta(repoID, archived, filepath.Join(dataDir, "gone"), t0))
+
+	cr := testkit.NewFakeRunner()
+	cr.TmuxSessions("agency_"+active, "agency_"+archived)
+	cr.On("sh", "-c", "curl -fsS localhost:3000").Exit(7).Stderr("connection refused token=s3cret\n")
+	cr.On("sh", "-c", "curl -fsS localhost:3000").Exit(7)
+	cr.On("sh", "-c", "curl -fsS localhost:3000")
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, clk.Now)
+	watchOnce := func() string {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		if err := Watch(context.Background(), cr, fs.NewRealFS(), t.TempDir(), WatchOpts{Once: true}, &stdout, &stderr); err != nil {
+			t.Fatalf("Watch() error = %v (stderr %q)", err, stderr.String())
+		}
+		return stdout.String()
+	}
+
+	out := watchOnce()
+	if !strings.Contains(out, active+" dev FAIL exit 7 (1/2)") {
+		t.Errorf("first pass output = %q", out)
+	}
+	calls := cr.CallsTo("sh")
+	if len(calls) != 1 || calls[0].Env["AGENCY_RUN_ID"] != active || calls[0].Env["AGENCY_PROBE"] != "dev" {
+		t.Fatalf("sh calls = %+v, want one for %s", calls, active)
+	}
+
+	// Not due again until the interval has passed
+	if out := watchOnce(); out != "" || len(cr.CallsTo("sh")) != 1 {
+		t.Errorf("probe ran before its interval: %q", out)
+	}
+
+	clk.Advance(61 * time.Second)
+	out = watchOnce()
+	if !strings.Contains(out, active+" needs attention: probe dev failed 2 times in a row") {
+		t.Errorf("second failure output = %q", out)
+	}
+	meta, err := st.ReadMeta(repoID, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Flags == nil || !meta.Flags.NeedsAttention || !meta.Probes["dev"].Flagged || meta.Probes["dev"].ConsecutiveFailures != 2 {
+		t.Errorf("meta after failures: flags %+v probes %+v", meta.Flags, meta.Probes)
+	}
+
+	clk.Advance(61 * time.Second)
+	if out := watchOnce(); !strings.Contains(out, "recovered: probe dev passed") {
+		t.Errorf("recovery output = %q", out)
+	}
+	meta, _ = st.ReadMeta(repoID, active)
+	if meta.Flags.NeedsAttention || meta.Probes["dev"].Flagged || !meta.Probes["dev"].OK {
+		t.Errorf("meta after recovery: flags %+v probes %+v", meta.Flags, meta.Probes)
+	}
+
+	eventsData, err := os.ReadFile(filepath.Join(dataDir, "repos", repoID, "runs", active, "events.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := string(eventsData)
+	for _, want := range []string{`"event":"probe"`, `"event":"needs_attention"`, `"event":"attention_cleared"`, "connection refused token=[REDACTED]"} {
+		if !strings.Contains(events, want) {
+			t.Errorf("events.jsonl missing %s:\n%s", want, events)
+		}
+	}
+}
+
+func TestApplyProbeResult_KeepsOtherAttention(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	p := config.Probe{Name: "build", Failures: 1}
+	m := &store.RunMeta{Flags: &store.RunMetaFlags{NeedsAttention: true}}
+
+	// needs_attention was set by something else: the probe does not take it over
+	if _, flagged, _ := applyProbeResult(m, p, probeResult{exitCode: 1}, now); flagged || m.Probes["build"].Flagged {
+		t.Errorf("probe took over an existing flag: %+v", m.Probes)
+	}
+	if _, _, cleared := applyProbeResult(m, p, probeResult{ok: true}, now); cleared || !m.Flags.NeedsAttention {
+		t.Error("a passing probe cleared a flag it did not set")
+	}
+}
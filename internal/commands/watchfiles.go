@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/watch"
+)
+
+// DefaultWatchInterval is how often watch-files polls the worktree.
+const DefaultWatchInterval = time.Second
+
+// WatchFilesOpts holds options for the watch-files command.
+type WatchFilesOpts struct {
+	// RunID is the run identifier (exact or unique prefix).
+	RunID string
+
+	// Repo restricts run_id resolution to one repo (repo_id, repo_key, or path).
+	Repo string
+
+	// Filters limit the feed to matching paths (see watch.Match).
+	Filters []string
+
+	// Excludes drop matching paths from the feed and skip matching dirs.
+	Excludes []string
+
+	// Interval is the poll interval (DefaultWatchInterval if zero).
+	Interval time.Duration
+
+	// Events also appends a file_changed event per change to events.jsonl.
+	Events bool
+
+	// JSON prints one JSON object per change instead of a text line.
+	JSON bool
+
+	// Count exits after this many changes (0 watches until ctx is done).
+	Count int
+}
+
+// watchedChange is the JSON form of one change.
+type watchedChange struct {
+	Time string `json:"time"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+// WatchFiles prints a live feed of file changes in a run's worktree until
+// ctx is done, Count changes were seen, or the worktree goes away. It does
+// not take the repo lock: it only reads the worktree (and appends to the
+// run's events.jsonl with Events).
+//
+// Error codes:
+//   - E_WORKTREE_MISSING: the run is archived
+func WatchFiles(ctx context.Context, cr agencyexec.CommandRunner, fsys fs.FS, cwd string, opts WatchFilesOpts, stdout, stderr io.Writer) error {
+	if opts.RunID == "" {
+		return errors.New(errors.EUsage, "run_id is required")
+	}
+	if opts.Interval < 0 || opts.Count < 0 {
+		return errors.New(errors.EUsage, "--interval and --count must not be negative")
+	}
+	interval := opts.Interval
+	if interval == 0 {
+		interval = DefaultWatchInterval
+	}
+
+	dirs, err := resolveDirs(fsys, cwd)
+	if err != nil {
+		return err
+	}
+	crypter := LoadCrypt(cr, fsys)
+
+	scope, err := newRunScope(ctx, cr, dirs.DataDir, cwd, opts.Repo)
+	if err != nil {
+		return err
+	}
+	record, err := resolveRun(dirs.DataDir, opts.RunID, scope, crypter)
+	if err != nil {
+		return err
+	}
+	worktreePath := record.Meta.WorktreePath
+	if !dirExists(worktreePath) {
+		return errors.NewWithDetails(errors.EWorktreeMissing, "cannot watch files: run is archived",
+			map[string]string{"run_id": record.RunID, "worktree_path": worktreePath})
+	}
+
+	w := watch.New(worktreePath, opts.Filters, opts.Excludes)
+	if err := w.Start(); err != nil {
+		return errors.Wrap(errors.EInternal, "failed to scan worktree", err)
+	}
+	fmt.Fprintf(stderr, "watching %s (every %s; Ctrl-C to stop)\n", worktreePath, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	seen := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		changes, err := w.Poll(clock.Now())
+		if err != nil {
+			if !dirExists(worktreePath) {
+				fmt.Fprintln(stderr, "worktree removed; stopping")
+				return nil
+			}
+			return errors.Wrap(errors.EInternal, "failed to scan worktree", err)
+		}
+		for _, c := range changes {
+			ts := c.Time.UTC().Format(time.RFC3339)
+			if opts.JSON {
+				data, err := json.Marshal(watchedChange{Time: ts, Type: c.Type, Path: c.Path})
+				if err != nil {
+					return errors.Wrap(errors.EInternal, "failed to encode change", err)
+				}
+				fmt.Fprintln(stdout, string(data))
+			} else {
+				fmt.Fprintf(stdout, "%s  %-8s  %s\n", ts, c.Type, c.Path)
+			}
+			if opts.Events {
+				_ = events.AppendEvent(events.EventsPath(record.RunDir), events.New(c.Time, record.RepoID, record.RunID, "file_changed", map[string]any{
+					"path":   c.Path,
+					"change": c.Type,
+				}))
+			}
+			seen++
+			if opts.Count > 0 && seen >= opts.Count {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestWatchFiles(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	defer SetClock(testkit.NewClock(created.Add(time.Hour)).Core())()
+
+	wt := t.TempDir()
+	if err := os.WriteFile(filepath.Join(wt, "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runID := "20260110120000-a3f2"
+	createValidMetaForShow(t, dataDir, "r1", runID, wt, created)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	opts := WatchFilesOpts{
+		RunID:    "20260110",
+		Filters:  []string{"*.go"},
+		Interval: 10 * time.Millisecond,
+		Events:   true,
+		JSON:     true,
+		Count:    1,
+	}
+	var stdout bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchFiles(ctx, agencyexec.NewRealRunner(), fs.NewRealFS(), t.TempDir(), opts, &stdout, io.Discard)
+	}()
+
+	// Keep changing files until the watcher has seen one (it may start after
+	// the first writes, which then belong to its baseline).
+	var err error
+	for i := 0; ; i++ {
+		_ = os.WriteFile(filepath.Join(wt, "notes.txt"), []byte(strconv.Itoa(i)), 0o644)
+		_ = os.WriteFile(filepath.Join(wt, "f"+strconv.Itoa(i)+".go"), nil, 0o644)
+		select {
+		case err = <-done:
+		case <-time.After(20 * time.Millisecond):
+			continue
+		}
+		break
+	}
+	if err != nil {
+		t.Fatalf("WatchFiles() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("output lines = %q, want 1", lines)
+	}
+	var change watchedChange
+	if err := json.Unmarshal([]byte(lines[0]), &change); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if change.Type != "created" || !strings.HasSuffix(change.Path, ".go") || change.Time != "2026-01-10T13:00:00Z" {
+		t.Errorf("change = %+v, want a created .go file at 13:00", change)
+	}
+
+	data, err := os.ReadFile(events.EventsPath(filepath.Join(dataDir, "repos", "r1", "runs", runID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"event":"file_changed"`) || !strings.Contains(string(data), change.Path) {
+		t.Errorf("events.jsonl = %s, want a file_changed event for %s", data, change.Path)
+	}
+}
+
+func TestWatchFilesArchived(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	createValidMetaForShow(t, dataDir, "r1", "20260110120000-a3f2", filepath.Join(t.TempDir(), "gone"), created)
+
+	err := WatchFiles(context.Background(), agencyexec.NewRealRunner(), fs.NewRealFS(), t.TempDir(),
+		WatchFilesOpts{RunID: "20260110120000-a3f2"}, io.Discard, io.Discard)
+	if code := errors.GetCode(err); code != errors.EWorktreeMissing {
+		t.Fatalf("WatchFiles() code = %v, want %v", code, errors.EWorktreeMissing)
+	}
+}
@@ -3,9 +3,13 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/NielsdaWheelz/agency/internal/core"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 )
@@ -17,6 +21,43 @@ type AgencyConfig struct {
 	Scripts  Scripts           `json:"scripts"`
 	Runners  map[string]string `json:"runners,omitempty"`
 
+	// Retention is optional; zero values disable automatic archival.
+	Retention Retention `json:"retention,omitempty"`
+
+	// Hooks maps hook points (see HookPoints) to repo-relative scripts.
+	Hooks map[string]string `json:"hooks,omitempty"`
+
+	// Logs is optional; zero values use DefaultLogMaxBytes and LogOverflowRotate.
+	Logs Logs `json:"logs,omitempty"`
+
+	// Review is optional; zero values keep the default ready-for-review predicate.
+	Review Review `json:"review,omitempty"`
+
+	// Worktrees is optional; zero values leave worktree disk usage unlimited.
+	Worktrees Worktrees `json:"worktrees,omitempty"`
+
+	// Slug is optional; zero values keep the built-in title slugging.
+	Slug Slug `json:"slug,omitempty"`
+
+	// GitHub is optional; zero values keep the GitHub flow (gh) enabled.
+	GitHub GitHub `json:"github,omitempty"`
+
+	// Sandbox is optional; zero values run setup with the caller's environment.
+	Sandbox Sandbox `json:"sandbox,omitempty"`
+
+	// SetupCache is optional; zero values run setup for every run.
+	SetupCache SetupCache `json:"setup_cache,omitempty"`
+
+	// Probes are health checks `agency watch` runs for active runs.
+	Probes []Probe `json:"probes,omitempty"`
+
+	// DataDir overrides the agency data dir for this repo (absolute path;
+	// "" = global data dir). Validated by paths.ValidateDataDir when used.
+	DataDir string `json:"data_dir,omitempty"`
+
+	// AllowDataDirInRepo permits DataDir inside the repo working tree.
+	AllowDataDirInRepo bool `json:"allow_data_dir_in_repo,omitempty"`
+
 	// Derived (not from JSON):
 	ResolvedRunnerCmd string `json:"-"`
 }
@@ -25,6 +66,12 @@ type AgencyConfig struct {
 type Defaults struct {
 	ParentBranch string `json:"parent_branch"`
 	Runner       string `json:"runner"`
+
+	// Deadline time-boxes new runs (core.ParseAge syntax, e.g. "2h"; "" = none).
+	Deadline string `json:"deadline,omitempty"`
+
+	// DeadlineKill kills the tmux session when the deadline passes.
+	DeadlineKill bool `json:"deadline_kill,omitempty"`
 }
 
 // Scripts contains paths to the required agency scripts.
@@ -34,6 +81,294 @@ type Scripts struct {
 	Archive string `json:"archive"`
 }
 
+// Retention contains the run retention policy.
+type Retention struct {
+	// AutoArchiveAfterDays archives merged/abandoned runs this many days after
+	// they reached that state (0 = disabled). Applied by `agency gc --auto`.
+	AutoArchiveAfterDays int `json:"auto_archive_after_days,omitempty"`
+}
+
+// Logs contains the size limit applied to setup and hook script logs.
+type Logs struct {
+	// MaxBytes caps the size of each script log (0 = DefaultLogMaxBytes).
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+
+	// Overflow is LogOverflowRotate or LogOverflowTruncate ("" = rotate).
+	Overflow string `json:"overflow,omitempty"`
+
+	// CompressAfterDays gzips the logs of runs created at least this many
+	// days ago (0 = never). Applied by `agency gc --auto`.
+	CompressAfterDays int `json:"compress_after_days,omitempty"`
+}
+
+// DefaultLogMaxBytes is the script log size limit when logs.max_bytes is unset.
+const DefaultLogMaxBytes int64 = 10 << 20
+
+// Log overflow modes.
+const (
+	// LogOverflowRotate moves a full log to <name>.1 and starts a new one.
+	LogOverflowRotate = "rotate"
+	// LogOverflowTruncate drops output from the head of the log, keeping the tail.
+	LogOverflowTruncate = "truncate"
+)
+
+// Limit returns the effective size limit and overflow mode.
+func (l Logs) Limit() (int64, string) {
+	maxBytes, overflow := l.MaxBytes, l.Overflow
+	if maxBytes == 0 {
+		maxBytes = DefaultLogMaxBytes
+	}
+	if overflow == "" {
+		overflow = LogOverflowRotate
+	}
+	return maxBytes, overflow
+}
+
+// Worktrees contains the disk quota for this repo's run worktrees.
+type Worktrees struct {
+	// MaxTotalBytes caps the combined size of all of the repo's worktrees
+	// (0 = unlimited). `agency run` refuses to create a worktree once it is reached.
+	MaxTotalBytes int64 `json:"max_total_bytes,omitempty"`
+
+	// MaxPathLength caps the length of file paths in a new worktree
+	// (0 = the platform's PATH_MAX). `agency run` refuses to create a
+	// worktree whose longest tracked file would exceed it.
+	MaxPathLength int `json:"max_path_length,omitempty"`
+
+	// SparseCheckout are gitignore-style patterns (e.g. "/*", "!/assets/")
+	// limiting what `agency run` checks out; empty = full checkout.
+	SparseCheckout []string `json:"sparse_checkout,omitempty"`
+
+	// SparseProfiles are named pattern lists selected per run with
+	// `agency run --sparse <name>`; they replace SparseCheckout.
+	SparseProfiles map[string][]string `json:"sparse_profiles,omitempty"`
+
+	// Submodules runs `git submodule update --init --recursive` in each new
+	// run worktree; otherwise submodules are left as empty dirs.
+	Submodules bool `json:"submodules,omitempty"`
+}
+
+// SparseProfileNone is the reserved --sparse value forcing a full checkout.
+const SparseProfileNone = "none"
+
+// Slug configures how run titles become branch slugs and default titles.
+type Slug struct {
+	// Prefix is prepended to every slug and default title, e.g. team initials.
+	Prefix string `json:"prefix,omitempty"`
+
+	// MaxLength caps the slug, prefix included (0 = core.DefaultSlugMaxLen).
+	MaxLength int `json:"max_length,omitempty"`
+
+	// Charset is SlugCharsetLower (default) or SlugCharsetMixed.
+	Charset string `json:"charset,omitempty"`
+
+	// ExtraChars are characters from core.SlugExtraChars allowed in slugs.
+	ExtraChars string `json:"extra_chars,omitempty"`
+}
+
+// Slug charsets.
+const (
+	// SlugCharsetLower lowercases titles: [a-z0-9-].
+	SlugCharsetLower = "lower"
+	// SlugCharsetMixed keeps the title's case: [A-Za-z0-9-].
+	SlugCharsetMixed = "mixed"
+)
+
+// Slug length bounds for slug.max_length.
+const (
+	MinSlugMaxLength = 8
+	MaxSlugMaxLength = 100
+)
+
+// Rules returns the slug rules for core.SlugRules-based naming.
+func (s Slug) Rules() core.SlugRules {
+	return core.SlugRules{
+		Prefix:       s.Prefix,
+		MaxLen:       s.MaxLength,
+		PreserveCase: s.Charset == SlugCharsetMixed,
+		ExtraChars:   s.ExtraChars,
+	}
+}
+
+// GitHub configures the GitHub flow (PRs via gh).
+type GitHub struct {
+	// Flow false marks the repo as not using GitHub PRs; doctor then skips
+	// the gh checks (nil = enabled).
+	Flow *bool `json:"flow,omitempty"`
+
+	// Credentials selects the GITHUB_TOKEN given to runner sessions:
+	// CredentialsInherit (default), CredentialsGh or CredentialsApp.
+	Credentials string `json:"credentials,omitempty"`
+
+	// App configures the GitHub App used with CredentialsApp.
+	App GitHubApp `json:"app,omitempty"`
+
+	// AutoCleanup makes gc --auto clean up runs whose PR was merged (see
+	// agency cleanup): delete the remote branch, archive the run.
+	AutoCleanup bool `json:"auto_cleanup,omitempty"`
+}
+
+// GitHub credential modes for runner sessions.
+const (
+	// CredentialsInherit leaves the runner with whatever the user's
+	// environment and gh login provide.
+	CredentialsInherit = "inherit"
+	// CredentialsGh exports `gh auth token` as GITHUB_TOKEN and GH_TOKEN.
+	CredentialsGh = "gh"
+	// CredentialsApp exports a GitHub App installation token scoped to the
+	// run's repo and github.app.permissions (valid for one hour).
+	CredentialsApp = "app"
+)
+
+// GitHubApp identifies a GitHub App installation that mints run tokens.
+type GitHubApp struct {
+	// AppID is the GitHub App's numeric id.
+	AppID int64 `json:"app_id,omitempty"`
+
+	// InstallationID is the installation on the repo's owner.
+	InstallationID int64 `json:"installation_id,omitempty"`
+
+	// PrivateKeyPath is the app's PEM private key, absolute or "~/"-relative.
+	PrivateKeyPath string `json:"private_key_path,omitempty"`
+
+	// Permissions requested for the token (nil = DefaultAppPermissions).
+	Permissions map[string]string `json:"permissions,omitempty"`
+
+	// APIURL is the REST API base URL ("" = https://api.github.com).
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// DefaultAppPermissions are requested when github.app.permissions is unset:
+// enough to push the run's branch and open its PR.
+var DefaultAppPermissions = map[string]string{
+	"contents":      "write",
+	"pull_requests": "write",
+}
+
+// CredentialMode returns the configured credential mode (CredentialsInherit if unset).
+func (g GitHub) CredentialMode() string {
+	if g.Credentials == "" {
+		return CredentialsInherit
+	}
+	return g.Credentials
+}
+
+// FlowEnabled reports whether the GitHub flow is enabled.
+func (g GitHub) FlowEnabled() bool {
+	return g.Flow == nil || *g.Flow
+}
+
+// Sandbox guards the setup script against writing outside the worktree.
+type Sandbox struct {
+	// Enforce runs setup with HOME and TMPDIR under .agency/tmp and fails
+	// the run if files outside the worktree changed.
+	Enforce bool `json:"enforce,omitempty"`
+
+	// Watch are extra paths checked for changes, absolute or "~/"-relative
+	// to the real HOME (the repo root is always checked).
+	Watch []string `json:"watch,omitempty"`
+}
+
+// Probe defaults, used when agency.json leaves a field unset or 0.
+const (
+	DefaultProbeIntervalSeconds = 60
+	DefaultProbeTimeoutSeconds  = 30
+	DefaultProbeFailures        = 3
+)
+
+// Probe is a health check run in an active run's worktree by `agency watch`
+// (e.g. "does the dev server respond", "does the test suite compile").
+type Probe struct {
+	// Name identifies the probe in events and meta.json ([A-Za-z0-9._-]).
+	Name string `json:"name"`
+
+	// Command is run with `sh -c` in the worktree; exit 0 means healthy.
+	Command string `json:"command"`
+
+	// IntervalSeconds is the time between checks of one run
+	// (0 = DefaultProbeIntervalSeconds).
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+
+	// TimeoutSeconds fails a check that runs longer
+	// (0 = DefaultProbeTimeoutSeconds).
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+
+	// Failures is how many consecutive failed checks flag the run as
+	// needing attention (0 = DefaultProbeFailures).
+	Failures int `json:"failures,omitempty"`
+}
+
+// Interval returns the probe's check interval in seconds.
+func (p Probe) Interval() int {
+	if p.IntervalSeconds > 0 {
+		return p.IntervalSeconds
+	}
+	return DefaultProbeIntervalSeconds
+}
+
+// Timeout returns the probe's timeout in seconds.
+func (p Probe) Timeout() int {
+	if p.TimeoutSeconds > 0 {
+		return p.TimeoutSeconds
+	}
+	return DefaultProbeTimeoutSeconds
+}
+
+// FailureThreshold returns the consecutive failures that flag a run.
+func (p Probe) FailureThreshold() int {
+	if p.Failures > 0 {
+		return p.Failures
+	}
+	return DefaultProbeFailures
+}
+
+// SetupCache lets a run skip setup when its inputs match an earlier run's.
+type SetupCache struct {
+	// Inputs are repo-relative files (e.g. package-lock.json, go.sum) whose
+	// contents, with the setup script, key the cache. Empty disables caching.
+	Inputs []string `json:"inputs,omitempty"`
+
+	// Paths are repo-relative files or dirs setup produces in the worktree
+	// (e.g. node_modules); they are saved after a successful setup and
+	// restored on a cache hit.
+	Paths []string `json:"paths,omitempty"`
+}
+
+// Review configures when a run with a PR counts as "ready for review".
+type Review struct {
+	// ReportMinBytes is the report.md size that counts as non-empty
+	// (0 = the built-in 64 bytes).
+	ReportMinBytes int `json:"report_min_bytes,omitempty"`
+
+	// Readiness selects the report predicate: ReviewReadinessSize (default)
+	// or ReviewReadinessFrontMatter.
+	Readiness string `json:"readiness,omitempty"`
+}
+
+// Review readiness predicates.
+const (
+	// ReviewReadinessSize requires report.md to be at least report_min_bytes.
+	ReviewReadinessSize = "size"
+	// ReviewReadinessFrontMatter requires "ready: true" in report.md front matter.
+	ReviewReadinessFrontMatter = "front_matter"
+)
+
+// Hook points, in the order the run pipeline fires them.
+const (
+	HookPostCreateWorktree = "post_create_worktree"
+	HookPreRunSetup        = "pre_run_setup"
+	HookPostRunSetup       = "post_run_setup"
+	HookPreStartTmux       = "pre_start_tmux"
+)
+
+// HookPoints lists every valid hooks.* key in firing order.
+var HookPoints = []string{
+	HookPostCreateWorktree,
+	HookPreRunSetup,
+	HookPostRunSetup,
+	HookPreStartTmux,
+}
+
 // LoadAgencyConfig reads and parses agency.json from the given repo root.
 // Returns E_NO_AGENCY_JSON if the file does not exist.
 // Returns E_INVALID_AGENCY_JSON if the file is not valid JSON.
@@ -113,6 +448,27 @@ func parseWithStrictTypes(raw map[string]json.RawMessage) (AgencyConfig, error)
 			}
 			cfg.Defaults.Runner = runner
 		}
+
+		// Parse defaults.deadline
+		if rawDeadline, ok := defaultsMap["deadline"]; ok {
+			var deadline string
+			if err := json.Unmarshal(rawDeadline, &deadline); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "defaults.deadline must be a string")
+			}
+			if _, err := core.ParseAge(deadline); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "defaults.deadline must be a duration such as 2h, 90m, or 1d")
+			}
+			cfg.Defaults.Deadline = deadline
+		}
+
+		// Parse defaults.deadline_kill
+		if rawKill, ok := defaultsMap["deadline_kill"]; ok {
+			var kill bool
+			if err := json.Unmarshal(rawKill, &kill); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "defaults.deadline_kill must be a boolean")
+			}
+			cfg.Defaults.DeadlineKill = kill
+		}
 	}
 
 	// Parse scripts - required, must be object
@@ -169,5 +525,487 @@ func parseWithStrictTypes(raw map[string]json.RawMessage) (AgencyConfig, error)
 		}
 	}
 
+	// Parse retention - optional, must be object if present
+	if rawRetention, ok := raw["retention"]; ok {
+		var retentionMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawRetention, &retentionMap); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "retention must be an object")
+		}
+
+		// Parse retention.auto_archive_after_days
+		if rawDays, ok := retentionMap["auto_archive_after_days"]; ok {
+			var days int
+			if err := json.Unmarshal(rawDays, &days); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "retention.auto_archive_after_days must be an integer")
+			}
+			if days < 0 {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "retention.auto_archive_after_days must be >= 0")
+			}
+			cfg.Retention.AutoArchiveAfterDays = days
+		}
+	}
+
+	// Parse hooks - optional, must be object of strings if present
+	if rawHooks, ok := raw["hooks"]; ok {
+		var hooksMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawHooks, &hooksMap); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "hooks must be an object")
+		}
+
+		cfg.Hooks = make(map[string]string)
+		for key, rawVal := range hooksMap {
+			var val string
+			if err := json.Unmarshal(rawVal, &val); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "hooks."+key+" must be a string")
+			}
+			cfg.Hooks[key] = val
+		}
+	}
+
+	// Parse logs - optional, must be object if present
+	if rawLogs, ok := raw["logs"]; ok {
+		var logsMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawLogs, &logsMap); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "logs must be an object")
+		}
+
+		if rawMax, ok := logsMap["max_bytes"]; ok {
+			var maxBytes int64
+			if err := json.Unmarshal(rawMax, &maxBytes); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "logs.max_bytes must be an integer")
+			}
+			if maxBytes < 1024 {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "logs.max_bytes must be >= 1024")
+			}
+			cfg.Logs.MaxBytes = maxBytes
+		}
+
+		if rawOverflow, ok := logsMap["overflow"]; ok {
+			var overflow string
+			if err := json.Unmarshal(rawOverflow, &overflow); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "logs.overflow must be a string")
+			}
+			if overflow != LogOverflowRotate && overflow != LogOverflowTruncate {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "logs.overflow must be \"rotate\" or \"truncate\"")
+			}
+			cfg.Logs.Overflow = overflow
+		}
+
+		if rawDays, ok := logsMap["compress_after_days"]; ok {
+			var days int
+			if err := json.Unmarshal(rawDays, &days); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "logs.compress_after_days must be an integer")
+			}
+			if days < 0 {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "logs.compress_after_days must be >= 0")
+			}
+			cfg.Logs.CompressAfterDays = days
+		}
+	}
+
+	// Parse worktrees - optional, must be object if present
+	if rawWorktrees, ok := raw["worktrees"]; ok {
+		var worktreesMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawWorktrees, &worktreesMap); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "worktrees must be an object")
+		}
+
+		if rawMax, ok := worktreesMap["max_total_bytes"]; ok {
+			var maxBytes int64
+			if err := json.Unmarshal(rawMax, &maxBytes); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "worktrees.max_total_bytes must be an integer")
+			}
+			if maxBytes < 0 {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "worktrees.max_total_bytes must be >= 0")
+			}
+			cfg.Worktrees.MaxTotalBytes = maxBytes
+		}
+
+		if rawMax, ok := worktreesMap["max_path_length"]; ok {
+			var maxLen int
+			if err := json.Unmarshal(rawMax, &maxLen); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "worktrees.max_path_length must be an integer")
+			}
+			if maxLen < 0 {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "worktrees.max_path_length must be >= 0")
+			}
+			cfg.Worktrees.MaxPathLength = maxLen
+		}
+
+		if rawSparse, ok := worktreesMap["sparse_checkout"]; ok {
+			patterns, err := parseSparsePatterns(rawSparse, "worktrees.sparse_checkout")
+			if err != nil {
+				return AgencyConfig{}, err
+			}
+			cfg.Worktrees.SparseCheckout = patterns
+		}
+
+		if rawProfiles, ok := worktreesMap["sparse_profiles"]; ok {
+			var profilesMap map[string]json.RawMessage
+			if err := json.Unmarshal(rawProfiles, &profilesMap); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "worktrees.sparse_profiles must be an object")
+			}
+			cfg.Worktrees.SparseProfiles = make(map[string][]string, len(profilesMap))
+			for name, rawPatterns := range profilesMap {
+				if name == "" || name == SparseProfileNone || strings.ContainsAny(name, " \t\r\n") {
+					return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, fmt.Sprintf("worktrees.sparse_profiles: invalid profile name %q", name))
+				}
+				patterns, err := parseSparsePatterns(rawPatterns, "worktrees.sparse_profiles."+name)
+				if err != nil {
+					return AgencyConfig{}, err
+				}
+				if len(patterns) == 0 {
+					return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "worktrees.sparse_profiles."+name+" must not be empty")
+				}
+				cfg.Worktrees.SparseProfiles[name] = patterns
+			}
+		}
+
+		if rawSubmodules, ok := worktreesMap["submodules"]; ok {
+			if err := json.Unmarshal(rawSubmodules, &cfg.Worktrees.Submodules); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "worktrees.submodules must be a boolean")
+			}
+		}
+	}
+
+	// Parse slug - optional, must be object if present
+	if rawSlug, ok := raw["slug"]; ok {
+		slug, err := parseSlug(rawSlug)
+		if err != nil {
+			return AgencyConfig{}, err
+		}
+		cfg.Slug = slug
+	}
+
+	// Parse review - optional, must be object if present
+	if rawReview, ok := raw["review"]; ok {
+		var reviewMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawReview, &reviewMap); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "review must be an object")
+		}
+
+		if rawMin, ok := reviewMap["report_min_bytes"]; ok {
+			var minBytes int
+			if err := json.Unmarshal(rawMin, &minBytes); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "review.report_min_bytes must be an integer")
+			}
+			if minBytes < 0 {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "review.report_min_bytes must be >= 0")
+			}
+			cfg.Review.ReportMinBytes = minBytes
+		}
+
+		if rawReadiness, ok := reviewMap["readiness"]; ok {
+			var readiness string
+			if err := json.Unmarshal(rawReadiness, &readiness); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "review.readiness must be a string")
+			}
+			if readiness != ReviewReadinessSize && readiness != ReviewReadinessFrontMatter {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "review.readiness must be \"size\" or \"front_matter\"")
+			}
+			cfg.Review.Readiness = readiness
+		}
+	}
+
+	// Parse github - optional, must be object if present
+	if rawGitHub, ok := raw["github"]; ok {
+		var githubMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawGitHub, &githubMap); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "github must be an object")
+		}
+
+		if rawFlow, ok := githubMap["flow"]; ok {
+			var flow bool
+			if err := json.Unmarshal(rawFlow, &flow); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "github.flow must be a boolean")
+			}
+			cfg.GitHub.Flow = &flow
+		}
+
+		if rawCreds, ok := githubMap["credentials"]; ok {
+			var creds string
+			if err := json.Unmarshal(rawCreds, &creds); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "github.credentials must be a string")
+			}
+			switch creds {
+			case CredentialsInherit, CredentialsGh, CredentialsApp:
+			default:
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "github.credentials must be one of: inherit, gh, app")
+			}
+			cfg.GitHub.Credentials = creds
+		}
+
+		if rawCleanup, ok := githubMap["auto_cleanup"]; ok {
+			if err := json.Unmarshal(rawCleanup, &cfg.GitHub.AutoCleanup); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "github.auto_cleanup must be a boolean")
+			}
+		}
+
+		if rawApp, ok := githubMap["app"]; ok {
+			if err := json.Unmarshal(rawApp, &cfg.GitHub.App); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "github.app must be an object with integer app_id/installation_id and string private_key_path/api_url")
+			}
+		}
+
+		if cfg.GitHub.Credentials == CredentialsApp {
+			app := cfg.GitHub.App
+			if app.AppID <= 0 || app.InstallationID <= 0 || app.PrivateKeyPath == "" {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "github.credentials \"app\" requires github.app.app_id, installation_id and private_key_path")
+			}
+			if !filepath.IsAbs(app.PrivateKeyPath) && !strings.HasPrefix(app.PrivateKeyPath, "~/") {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "github.app.private_key_path must be absolute or start with ~/")
+			}
+		}
+	}
+
+	// Parse sandbox - optional, must be object if present
+	if rawSandbox, ok := raw["sandbox"]; ok {
+		var sandboxMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawSandbox, &sandboxMap); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "sandbox must be an object")
+		}
+
+		if rawEnforce, ok := sandboxMap["enforce"]; ok {
+			var enforce bool
+			if err := json.Unmarshal(rawEnforce, &enforce); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "sandbox.enforce must be a boolean")
+			}
+			cfg.Sandbox.Enforce = enforce
+		}
+
+		if rawWatch, ok := sandboxMap["watch"]; ok {
+			var watch []string
+			if err := json.Unmarshal(rawWatch, &watch); err != nil {
+				return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "sandbox.watch must be an array of strings")
+			}
+			for _, p := range watch {
+				if !filepath.IsAbs(p) && !strings.HasPrefix(p, "~/") {
+					return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "sandbox.watch paths must be absolute or start with ~/")
+				}
+			}
+			cfg.Sandbox.Watch = watch
+		}
+	}
+
+	// Parse setup_cache - optional, must be object if present
+	if rawCache, ok := raw["setup_cache"]; ok {
+		var cacheMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawCache, &cacheMap); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "setup_cache must be an object")
+		}
+
+		rawInputs, ok := cacheMap["inputs"]
+		if !ok {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "setup_cache.inputs is required")
+		}
+		inputs, err := parseRelativePaths(rawInputs, "setup_cache.inputs")
+		if err != nil {
+			return AgencyConfig{}, err
+		}
+		if len(inputs) == 0 {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "setup_cache.inputs must not be empty")
+		}
+		cfg.SetupCache.Inputs = inputs
+
+		if rawPaths, ok := cacheMap["paths"]; ok {
+			paths, err := parseRelativePaths(rawPaths, "setup_cache.paths")
+			if err != nil {
+				return AgencyConfig{}, err
+			}
+			cfg.SetupCache.Paths = paths
+		}
+	}
+
+	// Parse probes - optional, must be an array of objects if present
+	if rawProbes, ok := raw["probes"]; ok {
+		probes, err := parseProbes(rawProbes)
+		if err != nil {
+			return AgencyConfig{}, err
+		}
+		cfg.Probes = probes
+	}
+
+	// Parse data_dir - optional, must be an absolute path if present
+	if rawDataDir, ok := raw["data_dir"]; ok {
+		var dataDir string
+		if err := json.Unmarshal(rawDataDir, &dataDir); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "data_dir must be a string")
+		}
+		if dataDir != "" && !filepath.IsAbs(dataDir) {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "data_dir must be an absolute path")
+		}
+		cfg.DataDir = dataDir
+	}
+
+	// Parse allow_data_dir_in_repo - optional, must be boolean if present
+	if rawAllow, ok := raw["allow_data_dir_in_repo"]; ok {
+		var allow bool
+		if err := json.Unmarshal(rawAllow, &allow); err != nil {
+			return AgencyConfig{}, errors.New(errors.EInvalidAgencyJSON, "allow_data_dir_in_repo must be a boolean")
+		}
+		cfg.AllowDataDirInRepo = allow
+	}
+
 	return cfg, nil
 }
+
+// parseSlug parses and validates the slug object. The prefix must be a
+// non-empty slug under the configured charset and extra chars, and must leave
+// at least 4 characters of max_length for the title.
+func parseSlug(rawSlug json.RawMessage) (Slug, error) {
+	var slugMap map[string]json.RawMessage
+	if err := json.Unmarshal(rawSlug, &slugMap); err != nil {
+		return Slug{}, errors.New(errors.EInvalidAgencyJSON, "slug must be an object")
+	}
+
+	var slug Slug
+	for _, field := range []struct {
+		key string
+		dst *string
+	}{
+		{"prefix", &slug.Prefix},
+		{"charset", &slug.Charset},
+		{"extra_chars", &slug.ExtraChars},
+	} {
+		if rawVal, ok := slugMap[field.key]; ok {
+			if err := json.Unmarshal(rawVal, field.dst); err != nil {
+				return Slug{}, errors.New(errors.EInvalidAgencyJSON, "slug."+field.key+" must be a string")
+			}
+		}
+	}
+	if rawMax, ok := slugMap["max_length"]; ok {
+		if err := json.Unmarshal(rawMax, &slug.MaxLength); err != nil {
+			return Slug{}, errors.New(errors.EInvalidAgencyJSON, "slug.max_length must be an integer")
+		}
+		if slug.MaxLength < MinSlugMaxLength || slug.MaxLength > MaxSlugMaxLength {
+			return Slug{}, errors.New(errors.EInvalidAgencyJSON,
+				fmt.Sprintf("slug.max_length must be between %d and %d", MinSlugMaxLength, MaxSlugMaxLength))
+		}
+	}
+
+	if slug.Charset != "" && slug.Charset != SlugCharsetLower && slug.Charset != SlugCharsetMixed {
+		return Slug{}, errors.New(errors.EInvalidAgencyJSON, "slug.charset must be \"lower\" or \"mixed\"")
+	}
+	for _, r := range slug.ExtraChars {
+		if !strings.ContainsRune(core.SlugExtraChars, r) {
+			return Slug{}, errors.New(errors.EInvalidAgencyJSON, "slug.extra_chars may only contain "+strconv.Quote(core.SlugExtraChars))
+		}
+	}
+
+	if slug.Prefix != "" {
+		rules := slug.Rules()
+		rules.Prefix = ""
+		rules.MaxLen = len(slug.Prefix)
+		if rules.Slug(slug.Prefix) != slug.Prefix {
+			return Slug{}, errors.New(errors.EInvalidAgencyJSON,
+				"slug.prefix must itself be a valid slug (e.g. "+strconv.Quote(rules.Slug(slug.Prefix))+")")
+		}
+		maxLen := slug.MaxLength
+		if maxLen == 0 {
+			maxLen = core.DefaultSlugMaxLen
+		}
+		if len(slug.Prefix)+1+4 > maxLen {
+			return Slug{}, errors.New(errors.EInvalidAgencyJSON, "slug.prefix is too long for slug.max_length")
+		}
+	}
+	return slug, nil
+}
+
+// parseProbes parses the probes array. Names must be unique and made of
+// [A-Za-z0-9._-]; commands must be non-empty; the numbers must be >= 0.
+func parseProbes(raw json.RawMessage) ([]Probe, error) {
+	var rawList []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &rawList); err != nil {
+		return nil, errors.New(errors.EInvalidAgencyJSON, "probes must be an array of objects")
+	}
+	probes := make([]Probe, 0, len(rawList))
+	seen := make(map[string]bool, len(rawList))
+	for i, fields := range rawList {
+		key := fmt.Sprintf("probes[%d]", i)
+		var p Probe
+		for _, f := range []struct {
+			name string
+			dst  *string
+		}{{"name", &p.Name}, {"command", &p.Command}} {
+			rawVal, ok := fields[f.name]
+			if !ok {
+				return nil, errors.New(errors.EInvalidAgencyJSON, key+"."+f.name+" is required")
+			}
+			if err := json.Unmarshal(rawVal, f.dst); err != nil {
+				return nil, errors.New(errors.EInvalidAgencyJSON, key+"."+f.name+" must be a string")
+			}
+		}
+		if !isProbeName(p.Name) {
+			return nil, errors.New(errors.EInvalidAgencyJSON, fmt.Sprintf("%s.name %q must be non-empty and use only letters, digits, '.', '_' and '-'", key, p.Name))
+		}
+		if seen[p.Name] {
+			return nil, errors.New(errors.EInvalidAgencyJSON, fmt.Sprintf("%s.name %q is already used", key, p.Name))
+		}
+		seen[p.Name] = true
+		if strings.TrimSpace(p.Command) == "" {
+			return nil, errors.New(errors.EInvalidAgencyJSON, key+".command must not be empty")
+		}
+		for _, f := range []struct {
+			name string
+			dst  *int
+		}{{"interval_seconds", &p.IntervalSeconds}, {"timeout_seconds", &p.TimeoutSeconds}, {"failures", &p.Failures}} {
+			rawVal, ok := fields[f.name]
+			if !ok {
+				continue
+			}
+			if err := json.Unmarshal(rawVal, f.dst); err != nil {
+				return nil, errors.New(errors.EInvalidAgencyJSON, key+"."+f.name+" must be an integer")
+			}
+			if *f.dst < 0 {
+				return nil, errors.New(errors.EInvalidAgencyJSON, key+"."+f.name+" must be >= 0")
+			}
+		}
+		probes = append(probes, p)
+	}
+	return probes, nil
+}
+
+// isProbeName reports whether name is a valid probe name.
+func isProbeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSparsePatterns parses a sparse-checkout pattern list at key.
+// parseRelativePaths parses an array of repo-relative paths. Paths must stay
+// inside the worktree and must not point into .git or .agency.
+func parseRelativePaths(raw json.RawMessage, key string) ([]string, error) {
+	var paths []string
+	if err := json.Unmarshal(raw, &paths); err != nil {
+		return nil, errors.New(errors.EInvalidAgencyJSON, key+" must be an array of strings")
+	}
+	for i, p := range paths {
+		clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(p)))
+		first := strings.SplitN(clean, "/", 2)[0]
+		if strings.TrimSpace(p) == "" || filepath.IsAbs(p) || clean == "." || first == ".." || first == ".git" || first == ".agency" {
+			return nil, errors.New(errors.EInvalidAgencyJSON, fmt.Sprintf("%s: %q must be a relative path inside the worktree (not .git or .agency)", key, p))
+		}
+		paths[i] = clean
+	}
+	return paths, nil
+}
+
+func parseSparsePatterns(raw json.RawMessage, key string) ([]string, error) {
+	var patterns []string
+	if err := json.Unmarshal(raw, &patterns); err != nil {
+		return nil, errors.New(errors.EInvalidAgencyJSON, key+" must be an array of strings")
+	}
+	for _, p := range patterns {
+		if strings.TrimSpace(p) == "" || strings.ContainsAny(p, "\r\n") || strings.HasPrefix(p, "-") {
+			return nil, errors.New(errors.EInvalidAgencyJSON, key+" patterns must be non-empty single lines not starting with '-'")
+		}
+	}
+	return patterns, nil
+}
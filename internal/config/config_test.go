@@ -5,6 +5,7 @@ import (
 	iofs "io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -38,6 +39,9 @@ func (s *stubFS) Chmod(path string, perm os.FileMode) error            { return
 func (s *stubFS) CreateTemp(dir, pattern string) (string, io.WriteCloser, error) {
 	return "", nil, nil
 }
+func (s *stubFS) CopyDir(src, dst string) error      { return nil }
+func (s *stubFS) DirSize(path string) (int64, error) { return 0, nil }
+func (s *stubFS) ReplaceDir(src, dst string) error   { return nil }
 
 // Verify stubFS implements fs.FS interface (compile-time check)
 var _ fs.FS = (*stubFS)(nil)
@@ -104,6 +108,25 @@ func TestLoadAgencyConfig_WrongTypes(t *testing.T) {
 		{"runner value as number", "wrong_types_runner_value.json", "runners.claude must be a string"},
 		{"version as string", "wrong_version_string.json", "version must be an integer"},
 		{"version as float", "wrong_version_float.json", "version must be an integer"},
+		{"retention days as string", "wrong_types_retention.json", "retention.auto_archive_after_days must be an integer"},
+		{"hook value as array", "wrong_types_hooks.json", "hooks.pre_run_setup must be a string"},
+		{"logs max_bytes as string", "wrong_types_logs.json", "logs.max_bytes must be an integer"},
+		{"logs compress_after_days as string", "wrong_types_logs_compress.json", "logs.compress_after_days must be an integer"},
+		{"worktrees max_total_bytes as string", "wrong_types_worktrees.json", "worktrees.max_total_bytes must be an integer"},
+		{"worktrees sparse_checkout as string", "wrong_types_worktrees_sparse.json", "worktrees.sparse_checkout must be an array of strings"},
+		{"worktrees sparse profile as string", "wrong_types_worktrees_profiles.json", "worktrees.sparse_profiles.api must be an array of strings"},
+		{"worktrees submodules as string", "wrong_types_worktrees_submodules.json", "worktrees.submodules must be a boolean"},
+		{"defaults deadline not a duration", "invalid_deadline.json", "defaults.deadline must be a duration such as 2h, 90m, or 1d"},
+		{"slug max_length as string", "wrong_types_slug.json", "slug.max_length must be an integer"},
+		{"review readiness as bool", "wrong_types_review.json", "review.readiness must be a string"},
+		{"github flow as string", "wrong_types_github.json", "github.flow must be a boolean"},
+		{"github credentials unknown mode", "wrong_types_github_credentials.json", "github.credentials must be one of: inherit, gh, app"},
+		{"github auto_cleanup as string", "wrong_types_github_cleanup.json", "github.auto_cleanup must be a boolean"},
+		{"github app credentials incomplete", "github_app_incomplete.json", "github.credentials \"app\" requires github.app.app_id, installation_id and private_key_path"},
+		{"relative data_dir", "wrong_types_data_dir.json", "data_dir must be an absolute path"},
+		{"sandbox enforce as string", "wrong_types_sandbox.json", "sandbox.enforce must be a boolean"},
+		{"setup_cache inputs as string", "wrong_types_setup_cache.json", "setup_cache.inputs must be an array of strings"},
+		{"probe interval as string", "wrong_types_probes.json", "probes[0].interval_seconds must be an integer"},
 	}
 
 	for _, tt := range tests {
@@ -753,3 +776,299 @@ func TestLoadAgencyConfig_RealFS(t *testing.T) {
 		t.Errorf("ParentBranch = %q, want %q", cfg.Defaults.ParentBranch, "main")
 	}
 }
+
+func TestLoadAgencyConfig_Retention(t *testing.T) {
+	data, err := os.ReadFile("testdata/retention.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if cfg.Retention.AutoArchiveAfterDays != 30 {
+		t.Errorf("AutoArchiveAfterDays = %d, want 30", cfg.Retention.AutoArchiveAfterDays)
+	}
+}
+
+func TestLoadAgencyConfig_Logs(t *testing.T) {
+	data, err := os.ReadFile("testdata/logs.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if maxBytes, overflow := cfg.Logs.Limit(); maxBytes != 1048576 || overflow != LogOverflowTruncate {
+		t.Errorf("Limit() = %d, %q", maxBytes, overflow)
+	}
+	if maxBytes, overflow := (Logs{}).Limit(); maxBytes != DefaultLogMaxBytes || overflow != LogOverflowRotate {
+		t.Errorf("default Limit() = %d, %q", maxBytes, overflow)
+	}
+	if cfg.Logs.CompressAfterDays != 14 {
+		t.Errorf("CompressAfterDays = %d, want 14", cfg.Logs.CompressAfterDays)
+	}
+}
+
+func TestLoadAgencyConfig_Worktrees(t *testing.T) {
+	data, err := os.ReadFile("testdata/worktrees.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if cfg.Worktrees.MaxTotalBytes != 20<<30 {
+		t.Errorf("MaxTotalBytes = %d, want %d", cfg.Worktrees.MaxTotalBytes, int64(20<<30))
+	}
+	if cfg.Worktrees.MaxPathLength != 900 {
+		t.Errorf("MaxPathLength = %d, want 900", cfg.Worktrees.MaxPathLength)
+	}
+	if want := []string{"/*", "!/assets/"}; !reflect.DeepEqual(cfg.Worktrees.SparseCheckout, want) {
+		t.Errorf("SparseCheckout = %v, want %v", cfg.Worktrees.SparseCheckout, want)
+	}
+	if want := []string{"/services/api/", "/libs/"}; !reflect.DeepEqual(cfg.Worktrees.SparseProfiles["api"], want) {
+		t.Errorf("SparseProfiles[api] = %v, want %v", cfg.Worktrees.SparseProfiles["api"], want)
+	}
+	if !cfg.Worktrees.Submodules {
+		t.Error("Submodules = false, want true")
+	}
+
+	stub.files["/repo/agency.json"] = []byte(strings.Replace(string(data), `"max_path_length": 900`, `"max_path_length": -1`, 1))
+	if _, err := LoadAgencyConfig(stub, "/repo"); err == nil || !strings.Contains(err.Error(), "worktrees.max_path_length must be >= 0") {
+		t.Errorf("negative max_path_length: got %v", err)
+	}
+}
+
+func TestLoadAgencyConfig_Sandbox(t *testing.T) {
+	data, err := os.ReadFile("testdata/sandbox.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if !cfg.Sandbox.Enforce {
+		t.Error("Sandbox.Enforce = false, want true")
+	}
+	if len(cfg.Sandbox.Watch) != 2 || cfg.Sandbox.Watch[0] != "~/.npmrc" || cfg.Sandbox.Watch[1] != "/opt/shared" {
+		t.Errorf("Sandbox.Watch = %v", cfg.Sandbox.Watch)
+	}
+
+	stub.files["/repo/agency.json"] = []byte(strings.Replace(string(data), "/opt/shared", "opt/shared", 1))
+	if _, err := LoadAgencyConfig(stub, "/repo"); err == nil || !strings.Contains(err.Error(), "sandbox.watch paths must be absolute") {
+		t.Errorf("relative watch path: got %v", err)
+	}
+}
+
+func TestLoadAgencyConfig_SetupCache(t *testing.T) {
+	data, err := os.ReadFile("testdata/setup_cache.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if got := strings.Join(cfg.SetupCache.Inputs, ","); got != "package-lock.json,tools/go.sum" {
+		t.Errorf("SetupCache.Inputs = %v", cfg.SetupCache.Inputs)
+	}
+	if len(cfg.SetupCache.Paths) != 1 || cfg.SetupCache.Paths[0] != "node_modules" {
+		t.Errorf("SetupCache.Paths = %v", cfg.SetupCache.Paths)
+	}
+
+	for _, bad := range []string{"../go.sum", "/abs/go.sum", ".agency/out", ".git/HEAD", "."} {
+		stub.files["/repo/agency.json"] = []byte(strings.Replace(string(data), "./tools/go.sum", bad, 1))
+		if _, err := LoadAgencyConfig(stub, "/repo"); err == nil || !strings.Contains(err.Error(), "must be a relative path inside the worktree") {
+			t.Errorf("input %q: got %v", bad, err)
+		}
+	}
+}
+
+func TestLoadAgencyConfig_Probes(t *testing.T) {
+	data, err := os.ReadFile("testdata/probes.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if len(cfg.Probes) != 2 {
+		t.Fatalf("Probes = %+v, want 2", cfg.Probes)
+	}
+	dev, build := cfg.Probes[0], cfg.Probes[1]
+	if dev.Name != "dev-server" || dev.Interval() != 30 || dev.Timeout() != DefaultProbeTimeoutSeconds || dev.FailureThreshold() != 2 {
+		t.Errorf("dev-server probe = %+v", dev)
+	}
+	if build.Command != "go build ./..." || build.Interval() != DefaultProbeIntervalSeconds || build.Timeout() != 120 || build.FailureThreshold() != DefaultProbeFailures {
+		t.Errorf("build probe = %+v", build)
+	}
+
+	for _, tt := range []struct{ old, new, want string }{
+		{`"name": "build"`, `"name": "dev-server"`, `probes[1].name "dev-server" is already used`},
+		{`"name": "dev-server"`, `"name": "dev server"`, `probes[0].name "dev server" must be non-empty`},
+		{`"command": "go build ./..."`, `"command": " "`, "probes[1].command must not be empty"},
+		{`"failures": 2`, `"failures": -1`, "probes[0].failures must be >= 0"},
+	} {
+		stub.files["/repo/agency.json"] = []byte(strings.Replace(string(data), tt.old, tt.new, 1))
+		if _, err := LoadAgencyConfig(stub, "/repo"); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.new, err, tt.want)
+		}
+	}
+}
+
+func TestLoadAgencyConfig_Deadline(t *testing.T) {
+	data, err := os.ReadFile("testdata/deadline.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if cfg.Defaults.Deadline != "2h" || !cfg.Defaults.DeadlineKill {
+		t.Errorf("Defaults = %+v, want deadline 2h with kill", cfg.Defaults)
+	}
+}
+
+func TestLoadAgencyConfig_Slug(t *testing.T) {
+	data, err := os.ReadFile("testdata/slug.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	rules := cfg.Slug.Rules()
+	if rules.Prefix != "NW" || rules.MaxLen != 40 || !rules.PreserveCase || rules.ExtraChars != "." {
+		t.Errorf("Rules() = %+v", rules)
+	}
+
+	invalid := []struct {
+		slug    string
+		wantMsg string
+	}{
+		{`{"max_length": 4}`, "slug.max_length must be between 8 and 100"},
+		{`{"charset": "upper"}`, "slug.charset must be"},
+		{`{"extra_chars": "/"}`, "slug.extra_chars may only contain"},
+		{`{"prefix": "Team NW"}`, "slug.prefix must itself be a valid slug"},
+		{`{"prefix": "NW"}`, "slug.prefix must itself be a valid slug (e.g. \"nw\")"},
+		{`{"prefix": "abcdefgh", "max_length": 10}`, "slug.prefix is too long"},
+	}
+	for _, tt := range invalid {
+		stub.files["/repo/agency.json"] = []byte(`{"version": 1, "slug": ` + tt.slug + `}`)
+		_, err := LoadAgencyConfig(stub, "/repo")
+		if errors.GetCode(err) != errors.EInvalidAgencyJSON || !strings.Contains(err.Error(), tt.wantMsg) {
+			t.Errorf("slug %s: err = %v, want %q", tt.slug, err, tt.wantMsg)
+		}
+	}
+}
+
+func TestLoadAgencyConfig_Review(t *testing.T) {
+	data, err := os.ReadFile("testdata/review.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if cfg.Review.ReportMinBytes != 256 || cfg.Review.Readiness != ReviewReadinessFrontMatter {
+		t.Errorf("Review = %+v", cfg.Review)
+	}
+}
+
+func TestLoadAgencyConfig_DataDir(t *testing.T) {
+	data, err := os.ReadFile("testdata/data_dir.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAgencyConfig(stub, "/repo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if cfg.DataDir != "/mnt/shared/agency" || cfg.AllowDataDirInRepo {
+		t.Errorf("DataDir = %q, AllowDataDirInRepo = %v", cfg.DataDir, cfg.AllowDataDirInRepo)
+	}
+}
+
+func TestLoadAndValidate_Hooks(t *testing.T) {
+	data, err := os.ReadFile("testdata/hooks.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	cfg, err := LoadAndValidateForS1(stub, "/repo")
+	if err != nil {
+		t.Fatalf("validate error: %v", err)
+	}
+	if cfg.Hooks[HookPostCreateWorktree] != "scripts/copy_env.sh" {
+		t.Errorf("hooks.post_create_worktree = %q", cfg.Hooks[HookPostCreateWorktree])
+	}
+	if cfg.Hooks[HookPreStartTmux] != "scripts/warm_cache.sh" {
+		t.Errorf("hooks.pre_start_tmux = %q", cfg.Hooks[HookPreStartTmux])
+	}
+	if _, ok := cfg.Hooks[HookPreRunSetup]; ok {
+		t.Error("unset hook should be absent")
+	}
+}
+
+func TestValidate_UnknownHook(t *testing.T) {
+	data, err := os.ReadFile("testdata/hooks_unknown.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	stub := newStubFS()
+	stub.files["/repo/agency.json"] = data
+
+	for name, load := range map[string]func(fs.FS, string) (AgencyConfig, error){
+		"full": LoadAndValidate,
+		"s1":   LoadAndValidateForS1,
+	} {
+		_, err := load(stub, "/repo")
+		if errors.GetCode(err) != errors.EInvalidAgencyJSON {
+			t.Errorf("%s: expected E_INVALID_AGENCY_JSON, got %v", name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), "unknown hook hooks.post_create_worktre") {
+			t.Errorf("%s: unexpected message: %s", name, err.Error())
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/paths"
+)
+
+// RepoDataDir returns the data dir for the repo at repoRoot, honoring the
+// data_dir override in its agency.json (see paths.RepoDataDir).
+// A missing or unparseable agency.json falls back to dirs.DataDir; commands
+// that need the config report those errors themselves.
+// Returns E_INVALID_DATA_DIR if the override fails validation.
+func RepoDataDir(filesystem fs.FS, env paths.Env, dirs paths.Dirs, repoRoot string) (string, error) {
+	cfg, err := LoadAgencyConfig(filesystem, repoRoot)
+	if err != nil {
+		return dirs.DataDir, nil
+	}
+	return paths.RepoDataDir(env, dirs, repoRoot, cfg.DataDir, cfg.AllowDataDirInRepo)
+}
+
+// FindRepoRoot returns the nearest ancestor of dir (including dir) that
+// contains agency.json, or "" if there is none. Unlike git.GetRepoRoot it
+// needs no git, so commands that work from anywhere can use it cheaply.
+func FindRepoRoot(filesystem fs.FS, dir string) string {
+	if dir == "" {
+		return ""
+	}
+	dir = filepath.Clean(dir)
+	for {
+		if info, err := filesystem.Stat(filepath.Join(dir, "agency.json")); err == nil && !info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/paths"
+)
+
+type mapEnv map[string]string
+
+func (m mapEnv) Get(key string) string { return m[key] }
+
+func TestRepoDataDir(t *testing.T) {
+	repoRoot := t.TempDir()
+	sub := filepath.Join(repoRoot, "services", "api")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fs.NewRealFS()
+	dirs := paths.Dirs{DataDir: "/global/agency"}
+
+	if got := FindRepoRoot(fsys, sub); got != "" {
+		t.Fatalf("FindRepoRoot() = %q without agency.json, want \"\"", got)
+	}
+
+	writeConfig := func(extra string) {
+		t.Helper()
+		data := `{"version": 1, "defaults": {"parent_branch": "main", "runner": "claude"}` + extra + `}`
+		if err := os.WriteFile(filepath.Join(repoRoot, "agency.json"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig("")
+	if got := FindRepoRoot(fsys, sub); got != repoRoot {
+		t.Fatalf("FindRepoRoot() = %q, want %q", got, repoRoot)
+	}
+	if got, err := RepoDataDir(fsys, mapEnv{}, dirs, repoRoot); err != nil || got != dirs.DataDir {
+		t.Errorf("no data_dir: got %q, %v; want %q", got, err, dirs.DataDir)
+	}
+
+	shared := filepath.Join(t.TempDir(), "agency")
+	writeConfig(`, "data_dir": "` + shared + `"`)
+	if got, err := RepoDataDir(fsys, mapEnv{}, dirs, repoRoot); err != nil || got != shared {
+		t.Errorf("data_dir: got %q, %v; want %q", got, err, shared)
+	}
+
+	inRepo := filepath.Join(repoRoot, ".agency")
+	writeConfig(`, "data_dir": "` + inRepo + `"`)
+	if _, err := RepoDataDir(fsys, mapEnv{}, dirs, repoRoot); errors.GetCode(err) != errors.EInvalidDataDir {
+		t.Errorf("in-repo data_dir: code = %q, want %q", errors.GetCode(err), errors.EInvalidDataDir)
+	}
+	writeConfig(`, "data_dir": "` + inRepo + `", "allow_data_dir_in_repo": true`)
+	if got, err := RepoDataDir(fsys, mapEnv{}, dirs, repoRoot); err != nil || got != inRepo {
+		t.Errorf("allowed in-repo data_dir: got %q, %v; want %q", got, err, inRepo)
+	}
+}
@@ -0,0 +1,493 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/status"
+)
+
+// UserConfigFileName is the name of the per-user config file in AGENCY_CONFIG_DIR.
+const UserConfigFileName = "config.json"
+
+// UserConfig represents the per-user configuration at ${AGENCY_CONFIG_DIR}/config.json.
+// All fields are optional; a missing file yields DefaultUserConfig().
+type UserConfig struct {
+	Version int          `json:"version"`
+	LS      UserLSConfig `json:"ls"`
+
+	// Plain selects line-oriented "key: value" output for ls/show (default false).
+	Plain bool `json:"plain"`
+
+	Attach UserAttachConfig `json:"attach"`
+
+	Repos UserReposConfig `json:"repos"`
+
+	Network UserNetworkConfig `json:"network"`
+
+	Encryption UserEncryptionConfig `json:"encryption"`
+
+	Digest UserDigestConfig `json:"digest"`
+
+	Scheduling UserSchedulingConfig `json:"scheduling"`
+
+	// Statuses maps derived statuses (e.g. "active (pr)") to how human
+	// output shows them; statuses not listed keep their names. JSON output
+	// always uses the derived status names.
+	Statuses map[string]StatusDisplay `json:"statuses"`
+}
+
+// StatusDisplay is how human output shows one derived status.
+type StatusDisplay struct {
+	// Label replaces the status name (empty keeps it).
+	Label string `json:"label"`
+
+	// Emoji is shown before the label, except in plain output.
+	Emoji string `json:"emoji"`
+
+	// Color is one of StatusColors, used on color terminals (empty = none).
+	Color string `json:"color"`
+}
+
+// StatusColors lists the colors a StatusDisplay may use.
+var StatusColors = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white", "gray"}
+
+// UserAttachConfig contains settings for `agency attach`.
+type UserAttachConfig struct {
+	// Status shows the run in its tmux session's status line (default true).
+	Status bool `json:"status"`
+
+	// StatusFormat is the status line text (see core.FormatStatusLine);
+	// empty uses core.DefaultStatusLineFormat.
+	StatusFormat string `json:"status_format"`
+
+	// Window opens a tools shell in the worktree next to the runner on
+	// every attach, as `agency attach --window` does (default false).
+	Window bool `json:"window"`
+
+	// Layout is where the tools shell goes: one of AttachLayouts
+	// (default "window").
+	Layout string `json:"layout"`
+
+	// RefreshEnv re-injects the run's current AGENCY_* variables and GitHub
+	// token into a live session on every attach, as `agency attach
+	// --refresh-env` does (default false).
+	RefreshEnv bool `json:"refresh_env"`
+}
+
+// AttachLayouts lists the attach.layout values: a separate tmux window, or
+// a pane split off the runner's window to its right or below it.
+var AttachLayouts = []string{"window", "split-right", "split-below"}
+
+// UserReposConfig contains settings for repo records (repo.json).
+type UserReposConfig struct {
+	// CapabilitiesTTLHours is how long repo.json capabilities (GitHub
+	// origin, gh auth) are trusted before run and adopt detect them again
+	// (default 24; 0 = only doctor and `agency repos refresh` update them).
+	CapabilitiesTTLHours int `json:"capabilities_ttl_hours"`
+}
+
+// UserNetworkConfig bounds git and gh commands that talk to a remote
+// (see exec.NetworkPolicy).
+type UserNetworkConfig struct {
+	// TimeoutSeconds limits each attempt (default 120; 0 = no limit).
+	TimeoutSeconds int `json:"timeout_seconds"`
+
+	// Retries is how many times idempotent commands (fetch, gh api GETs)
+	// are retried after transient failures (default 2; 0 = never).
+	Retries int `json:"retries"`
+}
+
+// UserEncryptionConfig configures encryption at rest of run files
+// (meta.json, script logs, transcripts) with age (see crypt).
+type UserEncryptionConfig struct {
+	// Enabled encrypts run files when agency writes them (default false).
+	Enabled bool `json:"enabled"`
+
+	// Identity is the age identity file used to decrypt, and whose
+	// recipient is used to encrypt ("" = none; "~/" is expanded). It is
+	// needed to read encrypted files even after Enabled is turned off.
+	Identity string `json:"identity"`
+}
+
+// UserDigestConfig contains settings for `agency digest`.
+type UserDigestConfig struct {
+	// WebhookURL is the incoming webhook (e.g. Slack) that `agency digest
+	// --post` posts to ("" = none). It must be an http(s) URL.
+	WebhookURL string `json:"webhook_url"`
+}
+
+// UserSchedulingConfig holds the limits `agency run` waits for before
+// creating a run's worktree (see schedule.Limits). 0 disables a limit.
+type UserSchedulingConfig struct {
+	// MaxSessions caps the live agency tmux sessions across all repos.
+	MaxSessions int `json:"max_sessions"`
+
+	// MinFreeDiskMB is the free space, in MiB, the data dir's filesystem
+	// must keep.
+	MinFreeDiskMB int `json:"min_free_disk_mb"`
+
+	// MaxLoad caps the 1-minute load average.
+	MaxLoad float64 `json:"max_load"`
+}
+
+// UserLSConfig contains defaults for `agency ls` visibility.
+type UserLSConfig struct {
+	// Archived includes archived runs by default (default false).
+	Archived bool `json:"archived"`
+
+	// Broken includes broken runs by default (default true).
+	Broken bool `json:"broken"`
+}
+
+// DefaultUserConfig returns the built-in user config defaults.
+func DefaultUserConfig() UserConfig {
+	return UserConfig{
+		Version: 1,
+		LS: UserLSConfig{
+			Archived: false,
+			Broken:   true,
+		},
+		Attach: UserAttachConfig{
+			Status: true,
+			Layout: "window",
+		},
+		Repos: UserReposConfig{
+			CapabilitiesTTLHours: 24,
+		},
+		Network: UserNetworkConfig{
+			TimeoutSeconds: 120,
+			Retries:        2,
+		},
+	}
+}
+
+// UserConfigPath returns the path to the user config file.
+func UserConfigPath(configDir string) string {
+	return filepath.Join(configDir, UserConfigFileName)
+}
+
+// LoadUserConfig reads ${configDir}/config.json and overlays it on the defaults.
+// Returns DefaultUserConfig() if the file does not exist.
+// Returns E_INVALID_USER_CONFIG if the file is unreadable, not valid JSON, or has wrong types.
+func LoadUserConfig(filesystem fs.FS, configDir string) (UserConfig, error) {
+	cfg := DefaultUserConfig()
+	path := UserConfigPath(configDir)
+
+	data, err := filesystem.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return UserConfig{}, errors.WrapWithDetails(errors.EInvalidUserConfig, "failed to read user config", err,
+			map[string]string{"path": path})
+	}
+	return ParseUserConfig(data, path)
+}
+
+// ParseUserConfig parses the contents of a user config file read from path
+// and overlays it on the defaults.
+// Returns E_INVALID_USER_CONFIG if data is not valid JSON or has wrong types.
+func ParseUserConfig(data []byte, path string) (UserConfig, error) {
+	cfg := DefaultUserConfig()
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return UserConfig{}, errors.NewWithDetails(errors.EInvalidUserConfig, "invalid json: "+err.Error(),
+			map[string]string{"path": path})
+	}
+
+	invalid := func(msg string) error {
+		return errors.NewWithDetails(errors.EInvalidUserConfig, msg, map[string]string{"path": path})
+	}
+
+	// Parse version - optional, must be 1 if present
+	if rawVersion, ok := raw["version"]; ok {
+		var version int
+		if err := json.Unmarshal(rawVersion, &version); err != nil {
+			return UserConfig{}, invalid("version must be an integer")
+		}
+		if version != 1 {
+			return UserConfig{}, invalid("version must be 1")
+		}
+		cfg.Version = version
+	}
+
+	// Parse ls - optional, must be object if present
+	if rawLS, ok := raw["ls"]; ok {
+		var lsMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawLS, &lsMap); err != nil {
+			return UserConfig{}, invalid("ls must be an object")
+		}
+
+		if rawArchived, ok := lsMap["archived"]; ok {
+			if err := json.Unmarshal(rawArchived, &cfg.LS.Archived); err != nil {
+				return UserConfig{}, invalid("ls.archived must be a boolean")
+			}
+		}
+
+		if rawBroken, ok := lsMap["broken"]; ok {
+			if err := json.Unmarshal(rawBroken, &cfg.LS.Broken); err != nil {
+				return UserConfig{}, invalid("ls.broken must be a boolean")
+			}
+		}
+	}
+
+	// Parse plain - optional boolean
+	if rawPlain, ok := raw["plain"]; ok {
+		if err := json.Unmarshal(rawPlain, &cfg.Plain); err != nil {
+			return UserConfig{}, invalid("plain must be a boolean")
+		}
+	}
+
+	// Parse attach - optional, must be object if present
+	if rawAttach, ok := raw["attach"]; ok {
+		var attachMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawAttach, &attachMap); err != nil {
+			return UserConfig{}, invalid("attach must be an object")
+		}
+
+		if rawStatus, ok := attachMap["status"]; ok {
+			if err := json.Unmarshal(rawStatus, &cfg.Attach.Status); err != nil {
+				return UserConfig{}, invalid("attach.status must be a boolean")
+			}
+		}
+
+		if rawFormat, ok := attachMap["status_format"]; ok {
+			if err := json.Unmarshal(rawFormat, &cfg.Attach.StatusFormat); err != nil {
+				return UserConfig{}, invalid("attach.status_format must be a string")
+			}
+			if _, err := core.FormatStatusLine(cfg.Attach.StatusFormat, nil); err != nil {
+				return UserConfig{}, invalid("attach.status_format: " + err.Error())
+			}
+		}
+
+		if rawWindow, ok := attachMap["window"]; ok {
+			if err := json.Unmarshal(rawWindow, &cfg.Attach.Window); err != nil {
+				return UserConfig{}, invalid("attach.window must be a boolean")
+			}
+		}
+
+		if rawLayout, ok := attachMap["layout"]; ok {
+			if err := json.Unmarshal(rawLayout, &cfg.Attach.Layout); err != nil || !isAttachLayout(cfg.Attach.Layout) {
+				return UserConfig{}, invalid("attach.layout must be one of " + strings.Join(AttachLayouts, ", "))
+			}
+		}
+
+		if rawRefreshEnv, ok := attachMap["refresh_env"]; ok {
+			if err := json.Unmarshal(rawRefreshEnv, &cfg.Attach.RefreshEnv); err != nil {
+				return UserConfig{}, invalid("attach.refresh_env must be a boolean")
+			}
+		}
+	}
+
+	// Parse repos - optional, must be object if present
+	if rawRepos, ok := raw["repos"]; ok {
+		var reposMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawRepos, &reposMap); err != nil {
+			return UserConfig{}, invalid("repos must be an object")
+		}
+
+		if msg := parseNonNegativeInt(reposMap, "repos", "capabilities_ttl_hours", &cfg.Repos.CapabilitiesTTLHours); msg != "" {
+			return UserConfig{}, invalid(msg)
+		}
+	}
+
+	// Parse network - optional, must be object if present
+	if rawNetwork, ok := raw["network"]; ok {
+		var networkMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawNetwork, &networkMap); err != nil {
+			return UserConfig{}, invalid("network must be an object")
+		}
+
+		if msg := parseNonNegativeInt(networkMap, "network", "timeout_seconds", &cfg.Network.TimeoutSeconds); msg != "" {
+			return UserConfig{}, invalid(msg)
+		}
+		if msg := parseNonNegativeInt(networkMap, "network", "retries", &cfg.Network.Retries); msg != "" {
+			return UserConfig{}, invalid(msg)
+		}
+	}
+
+	// Parse encryption - optional, must be object if present
+	if rawEncryption, ok := raw["encryption"]; ok {
+		var encryptionMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawEncryption, &encryptionMap); err != nil {
+			return UserConfig{}, invalid("encryption must be an object")
+		}
+
+		if rawEnabled, ok := encryptionMap["enabled"]; ok {
+			if err := json.Unmarshal(rawEnabled, &cfg.Encryption.Enabled); err != nil {
+				return UserConfig{}, invalid("encryption.enabled must be a boolean")
+			}
+		}
+		if rawIdentity, ok := encryptionMap["identity"]; ok {
+			if err := json.Unmarshal(rawIdentity, &cfg.Encryption.Identity); err != nil {
+				return UserConfig{}, invalid("encryption.identity must be a string")
+			}
+		}
+		if cfg.Encryption.Enabled && cfg.Encryption.Identity == "" {
+			return UserConfig{}, invalid("encryption.enabled needs encryption.identity (an age identity file)")
+		}
+	}
+
+	// Parse digest - optional, must be object if present
+	if rawDigest, ok := raw["digest"]; ok {
+		var digestMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawDigest, &digestMap); err != nil {
+			return UserConfig{}, invalid("digest must be an object")
+		}
+
+		if rawURL, ok := digestMap["webhook_url"]; ok {
+			if err := json.Unmarshal(rawURL, &cfg.Digest.WebhookURL); err != nil {
+				return UserConfig{}, invalid("digest.webhook_url must be a string")
+			}
+			if cfg.Digest.WebhookURL != "" && !isWebhookURL(cfg.Digest.WebhookURL) {
+				return UserConfig{}, invalid("digest.webhook_url must be an http(s) URL")
+			}
+		}
+	}
+
+	// Parse scheduling - optional, must be object if present
+	if rawScheduling, ok := raw["scheduling"]; ok {
+		var schedulingMap map[string]json.RawMessage
+		if err := json.Unmarshal(rawScheduling, &schedulingMap); err != nil {
+			return UserConfig{}, invalid("scheduling must be an object")
+		}
+
+		if msg := parseNonNegativeInt(schedulingMap, "scheduling", "max_sessions", &cfg.Scheduling.MaxSessions); msg != "" {
+			return UserConfig{}, invalid(msg)
+		}
+		if msg := parseNonNegativeInt(schedulingMap, "scheduling", "min_free_disk_mb", &cfg.Scheduling.MinFreeDiskMB); msg != "" {
+			return UserConfig{}, invalid(msg)
+		}
+		if rawLoad, ok := schedulingMap["max_load"]; ok {
+			if err := json.Unmarshal(rawLoad, &cfg.Scheduling.MaxLoad); err != nil {
+				return UserConfig{}, invalid("scheduling.max_load must be a number")
+			}
+			if cfg.Scheduling.MaxLoad < 0 {
+				return UserConfig{}, invalid("scheduling.max_load must not be negative")
+			}
+		}
+	}
+
+	// Parse statuses - optional, must be object if present
+	if rawStatuses, ok := raw["statuses"]; ok {
+		statuses, msg := parseStatuses(rawStatuses)
+		if msg != "" {
+			return UserConfig{}, invalid(msg)
+		}
+		cfg.Statuses = statuses
+	}
+
+	return cfg, nil
+}
+
+// isWebhookURL reports whether s is an absolute http or https URL with a host.
+func isWebhookURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// parseNonNegativeInt sets *dst to section.key from m if present.
+// Returns a validation message if it is not a non-negative integer.
+func parseNonNegativeInt(m map[string]json.RawMessage, section, key string, dst *int) string {
+	rawValue, ok := m[key]
+	if !ok {
+		return ""
+	}
+	if err := json.Unmarshal(rawValue, dst); err != nil {
+		return section + "." + key + " must be an integer"
+	}
+	if *dst < 0 {
+		return section + "." + key + " must not be negative"
+	}
+	return ""
+}
+
+// parseStatuses parses the "statuses" mapping. Keys must be derived status
+// names; each value an object with optional label, emoji, and color strings.
+// Returns a validation message on failure.
+func parseStatuses(data json.RawMessage) (map[string]StatusDisplay, string) {
+	var rawMap map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rawMap); err != nil {
+		return nil, "statuses must be an object"
+	}
+
+	known := make(map[string]bool, len(status.Statuses))
+	for _, s := range status.Statuses {
+		known[s] = true
+	}
+	names := make([]string, 0, len(rawMap))
+	for name := range rawMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make(map[string]StatusDisplay, len(rawMap))
+	for _, name := range names {
+		if !known[name] {
+			return nil, "statuses: unknown status " + strconv.Quote(name) + " (valid: " + strings.Join(status.Statuses, ", ") + ")"
+		}
+		key := "statuses[" + strconv.Quote(name) + "]"
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(rawMap[name], &fields); err != nil {
+			return nil, key + " must be an object"
+		}
+		var d StatusDisplay
+		for field, rawValue := range fields {
+			var target *string
+			switch field {
+			case "label":
+				target = &d.Label
+			case "emoji":
+				target = &d.Emoji
+			case "color":
+				target = &d.Color
+			default:
+				return nil, key + ": unknown key " + strconv.Quote(field)
+			}
+			if err := json.Unmarshal(rawValue, target); err != nil {
+				return nil, key + "." + field + " must be a string"
+			}
+			if strings.IndexFunc(*target, unicode.IsControl) >= 0 {
+				return nil, key + "." + field + " must not contain control characters"
+			}
+		}
+		if d.Label != strings.TrimSpace(d.Label) {
+			return nil, key + ".label must not start or end with whitespace"
+		}
+		if d.Color != "" && !isStatusColor(d.Color) {
+			return nil, key + ".color must be one of " + strings.Join(StatusColors, ", ")
+		}
+		statuses[name] = d
+	}
+	return statuses, ""
+}
+
+func isAttachLayout(layout string) bool {
+	for _, l := range AttachLayouts {
+		if l == layout {
+			return true
+		}
+	}
+	return false
+}
+
+func isStatusColor(color string) bool {
+	for _, c := range StatusColors {
+		if c == color {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// userConfigKey is a user config key that `agency config` can get and set.
+type userConfigKey struct {
+	name string
+	get  func(UserConfig) string
+
+	// parse converts a `config set` value to the JSON value to store.
+	parse func(key, value string) (any, error)
+}
+
+// boolKey is a boolean user config key.
+func boolKey(name string, get func(UserConfig) bool) userConfigKey {
+	return userConfigKey{
+		name: name,
+		get:  func(c UserConfig) string { return strconv.FormatBool(get(c)) },
+		parse: func(key, value string) (any, error) {
+			b, ok := parseConfigBool(value)
+			if !ok {
+				return nil, errors.New(errors.EUsage, key+" must be a boolean (true/false), got "+strconv.Quote(value))
+			}
+			return b, nil
+		},
+	}
+}
+
+// intKey is a non-negative integer user config key.
+func intKey(name string, get func(UserConfig) int) userConfigKey {
+	return userConfigKey{
+		name: name,
+		get:  func(c UserConfig) string { return strconv.Itoa(get(c)) },
+		parse: func(key, value string) (any, error) {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, errors.New(errors.EUsage, key+" must be a non-negative integer, got "+strconv.Quote(value))
+			}
+			return n, nil
+		},
+	}
+}
+
+// floatKey is a non-negative number user config key.
+func floatKey(name string, get func(UserConfig) float64) userConfigKey {
+	return userConfigKey{
+		name: name,
+		get:  func(c UserConfig) string { return strconv.FormatFloat(get(c), 'g', -1, 64) },
+		parse: func(key, value string) (any, error) {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+				return nil, errors.New(errors.EUsage, key+" must be a non-negative number, got "+strconv.Quote(value))
+			}
+			return f, nil
+		},
+	}
+}
+
+// stringKey is a string user config key; ParseUserConfig validates the value.
+func stringKey(name string, get func(UserConfig) string) userConfigKey {
+	return userConfigKey{
+		name:  name,
+		get:   get,
+		parse: func(_, value string) (any, error) { return value, nil },
+	}
+}
+
+// userConfigKeys lists the settable keys in `agency config list` order.
+var userConfigKeys = []userConfigKey{
+	boolKey("ls.archived", func(c UserConfig) bool { return c.LS.Archived }),
+	boolKey("ls.broken", func(c UserConfig) bool { return c.LS.Broken }),
+	boolKey("plain", func(c UserConfig) bool { return c.Plain }),
+	boolKey("attach.status", func(c UserConfig) bool { return c.Attach.Status }),
+	stringKey("attach.status_format", func(c UserConfig) string { return c.Attach.StatusFormat }),
+	boolKey("attach.window", func(c UserConfig) bool { return c.Attach.Window }),
+	stringKey("attach.layout", func(c UserConfig) string { return c.Attach.Layout }),
+	boolKey("attach.refresh_env", func(c UserConfig) bool { return c.Attach.RefreshEnv }),
+	intKey("repos.capabilities_ttl_hours", func(c UserConfig) int { return c.Repos.CapabilitiesTTLHours }),
+	intKey("network.timeout_seconds", func(c UserConfig) int { return c.Network.TimeoutSeconds }),
+	intKey("network.retries", func(c UserConfig) int { return c.Network.Retries }),
+	boolKey("encryption.enabled", func(c UserConfig) bool { return c.Encryption.Enabled }),
+	stringKey("encryption.identity", func(c UserConfig) string { return c.Encryption.Identity }),
+	stringKey("digest.webhook_url", func(c UserConfig) string { return c.Digest.WebhookURL }),
+	intKey("scheduling.max_sessions", func(c UserConfig) int { return c.Scheduling.MaxSessions }),
+	intKey("scheduling.min_free_disk_mb", func(c UserConfig) int { return c.Scheduling.MinFreeDiskMB }),
+	floatKey("scheduling.max_load", func(c UserConfig) float64 { return c.Scheduling.MaxLoad }),
+}
+
+// UserConfigKeys returns the keys `agency config set` accepts, in list order.
+func UserConfigKeys() []string {
+	names := make([]string, len(userConfigKeys))
+	for i, k := range userConfigKeys {
+		names[i] = k.name
+	}
+	return names
+}
+
+// IsUserConfigKey reports whether key is a user config key.
+func IsUserConfigKey(key string) bool {
+	_, ok := findUserConfigKey(key)
+	return ok
+}
+
+func findUserConfigKey(key string) (userConfigKey, bool) {
+	for _, k := range userConfigKeys {
+		if k.name == key {
+			return k, true
+		}
+	}
+	return userConfigKey{}, false
+}
+
+// UserConfigValue returns key's value in cfg as `agency config get` prints it.
+// Returns false for unknown keys.
+func UserConfigValue(cfg UserConfig, key string) (string, bool) {
+	k, ok := findUserConfigKey(key)
+	if !ok {
+		return "", false
+	}
+	return k.get(cfg), true
+}
+
+// parseConfigBool parses a boolean the way git config does: true/false,
+// yes/no, on/off, 1/0 (case-insensitive).
+func parseConfigBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, true
+	case "false", "no", "off", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// UserConfigFileKeys returns the keys set in the user config file (as
+// opposed to left at their defaults). A missing file sets none.
+// Returns E_INVALID_USER_CONFIG if the file cannot be read or parsed.
+func UserConfigFileKeys(filesystem fs.FS, configDir string) (map[string]bool, error) {
+	raw, err := readUserConfigRaw(filesystem, configDir)
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]bool{}
+	for _, k := range userConfigKeys {
+		if _, ok := lookupPath(raw, k.name); ok {
+			set[k.name] = true
+		}
+	}
+	return set, nil
+}
+
+// SetUserConfigValue sets key to value in ${configDir}/config.json, creating
+// the file if needed and keeping the other settings (including ones this
+// build does not know). The file is written atomically.
+//
+// Error codes:
+//   - E_USAGE: unknown key, or value is not a valid boolean or number
+//   - E_INVALID_USER_CONFIG: the existing file is invalid, or value is not
+//     valid for key (e.g. an unknown placeholder in attach.status_format)
+func SetUserConfigValue(filesystem fs.FS, configDir, key, value string) error {
+	k, ok := findUserConfigKey(key)
+	if !ok {
+		return unknownUserConfigKey(key)
+	}
+	v, err := k.parse(key, value)
+	if err != nil {
+		return err
+	}
+
+	raw, err := readUserConfigRaw(filesystem, configDir)
+	if err != nil {
+		return err
+	}
+	if _, ok := raw["version"]; !ok {
+		raw["version"] = 1
+	}
+	setPath(raw, key, v)
+
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to encode user config", err)
+	}
+	data = append(data, '\n')
+	path := UserConfigPath(configDir)
+	if _, err := ParseUserConfig(data, path); err != nil {
+		return err
+	}
+	if err := filesystem.MkdirAll(configDir, 0o755); err != nil {
+		return errors.WrapWithDetails(errors.EInternal, "failed to create config dir", err,
+			map[string]string{"path": configDir})
+	}
+	if err := fs.WriteFileAtomic(filesystem, path, data, 0o644); err != nil {
+		return errors.WrapWithDetails(errors.EInternal, "failed to write user config", err,
+			map[string]string{"path": path})
+	}
+	return nil
+}
+
+// unknownUserConfigKey is the E_USAGE error for a key `agency config` does
+// not know.
+func unknownUserConfigKey(key string) error {
+	return errors.WithHints(errors.New(errors.EUsage, "unknown config key: "+key),
+		"known keys: "+strings.Join(UserConfigKeys(), ", "))
+}
+
+// readUserConfigRaw reads the user config file as generic JSON after
+// validating it. A missing file yields an empty object.
+func readUserConfigRaw(filesystem fs.FS, configDir string) (map[string]any, error) {
+	path := UserConfigPath(configDir)
+	data, err := filesystem.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]any{}, nil
+		}
+		return nil, errors.WrapWithDetails(errors.EInvalidUserConfig, "failed to read user config", err,
+			map[string]string{"path": path})
+	}
+	if _, err := ParseUserConfig(data, path); err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.NewWithDetails(errors.EInvalidUserConfig, "invalid json: "+err.Error(),
+			map[string]string{"path": path})
+	}
+	if raw == nil {
+		raw = map[string]any{}
+	}
+	return raw, nil
+}
+
+// lookupPath finds a dotted key ("ls.archived") in nested JSON objects.
+func lookupPath(raw map[string]any, key string) (any, bool) {
+	parts := strings.Split(key, ".")
+	cur := raw
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+	v, ok := cur[parts[len(parts)-1]]
+	return v, ok
+}
+
+// setPath sets a dotted key in nested JSON objects, creating them as needed.
+func setPath(raw map[string]any, key string, value any) {
+	parts := strings.Split(key, ".")
+	cur := raw
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			cur[p] = next
+		}
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = value
+}
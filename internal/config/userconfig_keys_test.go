@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestSetUserConfigValue(t *testing.T) {
+	mem := fs.NewMemFS()
+
+	// Creates the file with a version
+	if err := SetUserConfigValue(mem, "/config", "ls.archived", "yes"); err != nil {
+		t.Fatalf("SetUserConfigValue() error = %v", err)
+	}
+	cfg, err := LoadUserConfig(mem, "/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.LS.Archived || !cfg.LS.Broken {
+		t.Errorf("cfg.LS = %+v, want archived and the broken default", cfg.LS)
+	}
+
+	// Keeps keys this build does not know
+	if err := mem.WriteFile("/config/config.json", []byte(`{"version": 1, "future": {"x": 1}, "ls": {"broken": false}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetUserConfigValue(mem, "/config", "plain", "ON"); err != nil {
+		t.Fatalf("SetUserConfigValue() error = %v", err)
+	}
+	data, _ := mem.ReadFile("/config/config.json")
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw["future"] == nil || raw["plain"] != true || raw["ls"].(map[string]any)["broken"] != false {
+		t.Errorf("config.json = %s, want future kept, plain true, ls.broken false", data)
+	}
+
+	// String keys are stored as given
+	if err := SetUserConfigValue(mem, "/config", "attach.status_format", "{title} [{status}]"); err != nil {
+		t.Fatalf("SetUserConfigValue() error = %v", err)
+	}
+	cfg, err = LoadUserConfig(mem, "/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := UserConfigValue(cfg, "attach.status_format"); got != "{title} [{status}]" || !cfg.Attach.Status {
+		t.Errorf("attach = %+v, want the format and the status default", cfg.Attach)
+	}
+
+	// Number keys are stored as JSON numbers
+	if err := SetUserConfigValue(mem, "/config", "scheduling.max_load", "3.5"); err != nil {
+		t.Fatalf("SetUserConfigValue() error = %v", err)
+	}
+	cfg, err = LoadUserConfig(mem, "/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := UserConfigValue(cfg, "scheduling.max_load"); got != "3.5" {
+		t.Errorf("scheduling.max_load = %q, want 3.5", got)
+	}
+
+	keys, err := UserConfigFileKeys(mem, "/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !keys["plain"] || !keys["ls.broken"] || keys["ls.archived"] {
+		t.Errorf("UserConfigFileKeys() = %v, want plain and ls.broken", keys)
+	}
+}
+
+func TestSetUserConfigValue_Invalid(t *testing.T) {
+	mem := fs.NewMemFS()
+	tests := []struct {
+		key, value string
+		code       errors.Code
+	}{
+		{"ls.nope", "true", errors.EUsage},
+		{"plain", "maybe", errors.EUsage},
+		{"repos.capabilities_ttl_hours", "-1", errors.EUsage},
+		{"repos.capabilities_ttl_hours", "1d", errors.EUsage},
+		{"scheduling.max_load", "-1", errors.EUsage},
+		{"scheduling.max_load", "NaN", errors.EUsage},
+		{"attach.status_format", "{run_id} {date}", errors.EInvalidUserConfig},
+		{"attach.layout", "grid", errors.EInvalidUserConfig},
+	}
+	for _, tt := range tests {
+		if err := SetUserConfigValue(mem, "/config", tt.key, tt.value); errors.GetCode(err) != tt.code {
+			t.Errorf("SetUserConfigValue(%q, %q) code = %q, want %q", tt.key, tt.value, errors.GetCode(err), tt.code)
+		}
+	}
+
+	// An invalid file is not overwritten
+	if err := mem.MkdirAll("/config", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.WriteFile("/config/config.json", []byte(`{"plain": "yes"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetUserConfigValue(mem, "/config", "plain", "true"); errors.GetCode(err) != errors.EInvalidUserConfig {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EInvalidUserConfig)
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+)
+
+func TestLoadUserConfig_MissingFileUsesDefaults(t *testing.T) {
+	stub := newStubFS()
+	cfg, err := LoadUserConfig(stub, "/config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, DefaultUserConfig()) {
+		t.Errorf("cfg = %+v, want defaults %+v", cfg, DefaultUserConfig())
+	}
+}
+
+func TestLoadUserConfig_Overrides(t *testing.T) {
+	stub := newStubFS()
+	stub.files["/config/config.json"] = []byte(`{"version": 1, "ls": {"archived": true}, "plain": true}`)
+
+	cfg, err := LoadUserConfig(stub, "/config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.LS.Archived {
+		t.Error("ls.archived should be true")
+	}
+	if !cfg.LS.Broken {
+		t.Error("ls.broken should keep default true when omitted")
+	}
+	if !cfg.Plain {
+		t.Error("plain should be true")
+	}
+}
+
+func TestLoadUserConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid json", `{"ls": {`},
+		{"wrong version", `{"version": 2}`},
+		{"ls not object", `{"ls": true}`},
+		{"archived not bool", `{"ls": {"archived": "yes"}}`},
+		{"broken not bool", `{"ls": {"broken": 1}}`},
+		{"plain not bool", `{"plain": "yes"}`},
+		{"attach not object", `{"attach": "tmux"}`},
+		{"attach.status not bool", `{"attach": {"status": "on"}}`},
+		{"attach.status_format not string", `{"attach": {"status_format": 1}}`},
+		{"attach.status_format unknown placeholder", `{"attach": {"status_format": "{nope}"}}`},
+		{"attach.window not bool", `{"attach": {"window": "yes"}}`},
+		{"attach.refresh_env not bool", `{"attach": {"refresh_env": 1}}`},
+		{"attach.layout unknown", `{"attach": {"layout": "grid"}}`},
+		{"repos not object", `{"repos": 24}`},
+		{"repos.capabilities_ttl_hours not integer", `{"repos": {"capabilities_ttl_hours": "1d"}}`},
+		{"repos.capabilities_ttl_hours negative", `{"repos": {"capabilities_ttl_hours": -1}}`},
+		{"network not object", `{"network": "fast"}`},
+		{"network.timeout_seconds not integer", `{"network": {"timeout_seconds": "2m"}}`},
+		{"network.retries negative", `{"network": {"retries": -1}}`},
+		{"encryption not object", `{"encryption": true}`},
+		{"encryption.enabled not bool", `{"encryption": {"enabled": "yes", "identity": "~/age.key"}}`},
+		{"encryption.identity not string", `{"encryption": {"identity": 1}}`},
+		{"encryption.enabled without identity", `{"encryption": {"enabled": true}}`},
+		{"digest not object", `{"digest": "x"}`},
+		{"digest.webhook_url not a URL", `{"digest": {"webhook_url": "hooks.slack.com/x"}}`},
+		{"scheduling not object", `{"scheduling": 4}`},
+		{"scheduling.max_sessions negative", `{"scheduling": {"max_sessions": -1}}`},
+		{"scheduling.min_free_disk_mb not integer", `{"scheduling": {"min_free_disk_mb": "10G"}}`},
+		{"scheduling.max_load not number", `{"scheduling": {"max_load": "high"}}`},
+		{"scheduling.max_load negative", `{"scheduling": {"max_load": -0.5}}`},
+		{"statuses not object", `{"statuses": ["active"]}`},
+		{"statuses unknown status", `{"statuses": {"in-progress": {"label": "x"}}}`},
+		{"statuses entry not object", `{"statuses": {"active": "in-progress"}}`},
+		{"statuses unknown key", `{"statuses": {"active": {"name": "in-progress"}}}`},
+		{"statuses label not string", `{"statuses": {"active": {"label": 1}}}`},
+		{"statuses label control character", `{"statuses": {"active": {"label": "in\nprogress"}}}`},
+		{"statuses unknown color", `{"statuses": {"active": {"color": "orange"}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubFS()
+			stub.files["/config/config.json"] = []byte(tt.content)
+
+			_, err := LoadUserConfig(stub, "/config")
+			if errors.GetCode(err) != errors.EInvalidUserConfig {
+				t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EInvalidUserConfig)
+			}
+		})
+	}
+}
+
+func TestLoadUserConfig_Statuses(t *testing.T) {
+	stub := newStubFS()
+	stub.files["/config/config.json"] = []byte(`{"statuses": {
+		"active (pr)": {"label": "in-progress", "emoji": "🔨", "color": "yellow"},
+		"ready for review": {"label": "review"},
+		"merged": {"color": "green"}
+	}}`)
+
+	cfg, err := LoadUserConfig(stub, "/config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]StatusDisplay{
+		"active (pr)":      {Label: "in-progress", Emoji: "🔨", Color: "yellow"},
+		"ready for review": {Label: "review"},
+		"merged":           {Color: "green"},
+	}
+	if !reflect.DeepEqual(cfg.Statuses, want) {
+		t.Errorf("Statuses = %+v, want %+v", cfg.Statuses, want)
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"unicode"
 
 	"github.com/NielsdaWheelz/agency/internal/errors"
@@ -59,6 +60,10 @@ func ValidateAgencyConfig(cfg AgencyConfig) (AgencyConfig, error) {
 		}
 	}
 
+	if err := validateHooks(cfg.Hooks); err != nil {
+		return cfg, err
+	}
+
 	// Resolve runner command
 	resolved, err := resolveRunner(cfg)
 	if err != nil {
@@ -69,11 +74,16 @@ func ValidateAgencyConfig(cfg AgencyConfig) (AgencyConfig, error) {
 	return cfg, nil
 }
 
-// resolveRunner determines the runner command based on config.
+// resolveRunner determines the default runner command based on config.
 // Returns E_RUNNER_NOT_CONFIGURED if resolution fails.
 func resolveRunner(cfg AgencyConfig) (string, error) {
-	name := cfg.Defaults.Runner
+	return ResolveRunnerCmd(cfg, cfg.Defaults.Runner)
+}
 
+// ResolveRunnerCmd resolves the command for runner name: runners.<name> if
+// set, else claude/codex from PATH.
+// Returns E_RUNNER_NOT_CONFIGURED if resolution fails.
+func ResolveRunnerCmd(cfg AgencyConfig, name string) (string, error) {
 	// If runners map has an entry for this name, use it
 	if cfg.Runners != nil {
 		if cmd, ok := cfg.Runners[name]; ok {
@@ -92,6 +102,29 @@ func resolveRunner(cfg AgencyConfig) (string, error) {
 		"runner \""+name+"\" not configured; set runners."+name+" or choose claude/codex")
 }
 
+// validateHooks rejects unknown hook points and empty script paths.
+func validateHooks(hooks map[string]string) error {
+	for name, script := range hooks {
+		if !isHookPoint(name) {
+			return errors.New(errors.EInvalidAgencyJSON,
+				"unknown hook hooks."+name+"; valid hooks: "+strings.Join(HookPoints, ", "))
+		}
+		if strings.TrimSpace(script) == "" {
+			return errors.New(errors.EInvalidAgencyJSON, "hooks."+name+" must be a non-empty string")
+		}
+	}
+	return nil
+}
+
+func isHookPoint(name string) bool {
+	for _, p := range HookPoints {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
 // containsWhitespace returns true if s contains any whitespace character.
 func containsWhitespace(s string) bool {
 	for _, r := range s {
@@ -178,6 +211,10 @@ func ValidateForS1(cfg AgencyConfig) (AgencyConfig, error) {
 		}
 	}
 
+	if err := validateHooks(cfg.Hooks); err != nil {
+		return cfg, err
+	}
+
 	// Resolve runner command
 	resolved, err := resolveRunner(cfg)
 	if err != nil {
@@ -3,7 +3,10 @@ package core
 // BranchName returns "agency/<slug>-<shortid>".
 // slug max len must be 30 (call Slugify(title, 30)).
 func BranchName(title, runID string) string {
-	slug := Slugify(title, 30)
-	shortID := ShortID(runID)
-	return "agency/" + slug + "-" + shortID
+	return SlugRules{}.BranchName(title, runID)
+}
+
+// BranchName returns "agency/<slug>-<shortid>" with the slug built under r.
+func (r SlugRules) BranchName(title, runID string) string {
+	return "agency/" + r.Slug(title) + "-" + ShortID(runID)
 }
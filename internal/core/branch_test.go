@@ -37,7 +37,7 @@ func TestBranchName(t *testing.T) {
 			name:   "invalid runID format",
 			title:  "test",
 			runID:  "invalid",
-			expect: "agency/test-xxxx",
+			expect: "agency/test-f123",
 		},
 	}
 
@@ -50,3 +50,14 @@ func TestBranchName(t *testing.T) {
 		})
 	}
 }
+
+func TestBranchName_CallerSuppliedRunIDs(t *testing.T) {
+	a := BranchName("nightly deps bump", "job-4821")
+	b := BranchName("nightly deps bump", "ci_build_17")
+	if a == b {
+		t.Errorf("BranchName() = %q for both run ids, want distinct branches", a)
+	}
+	if again := BranchName("nightly deps bump", "job-4821"); again != a {
+		t.Errorf("BranchName() = %q, then %q; want stable", a, again)
+	}
+}
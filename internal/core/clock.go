@@ -0,0 +1,18 @@
+package core
+
+import "time"
+
+// Clock supplies the wall-clock time and new run ids. The command layer
+// takes both from one Clock so tests can run commands deterministically.
+type Clock struct {
+	// Now returns the current time.
+	Now func() time.Time
+
+	// NewRunID returns a new run id for a run created at now.
+	NewRunID func(now time.Time) (string, error)
+}
+
+// SystemClock returns the production clock: time.Now and NewRunID.
+func SystemClock() Clock {
+	return Clock{Now: time.Now, NewRunID: NewRunID}
+}
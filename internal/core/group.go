@@ -0,0 +1,27 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MaxGroupNameLen is the longest allowed run group name.
+const MaxGroupNameLen = 63
+
+// groupNamePattern allows names like "payments-refactor" or "q3.migration".
+var groupNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+
+// ValidateGroupName checks a run group name: 1-63 letters, digits, '.', '_'
+// and '-', starting and ending with a letter or digit.
+func ValidateGroupName(name string) error {
+	if name == "" {
+		return fmt.Errorf("group name must not be empty")
+	}
+	if len(name) > MaxGroupNameLen {
+		return fmt.Errorf("group name %q is longer than %d characters", name, MaxGroupNameLen)
+	}
+	if !groupNamePattern.MatchString(name) {
+		return fmt.Errorf("group name %q must contain only letters, digits, '.', '_' and '-', and start and end with a letter or digit", name)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateGroupName(t *testing.T) {
+	for _, name := range []string{"payments-refactor", "q3.migration", "a", "v2_rollout"} {
+		if err := ValidateGroupName(name); err != nil {
+			t.Errorf("ValidateGroupName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "-lead", "trail.", "has space", "a/b", strings.Repeat("x", 64)} {
+		if err := ValidateGroupName(name); err == nil {
+			t.Errorf("ValidateGroupName(%q) = nil, want error", name)
+		}
+	}
+}
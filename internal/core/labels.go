@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Label limits.
+const (
+	MaxLabelKeyLen   = 63
+	MaxLabelValueLen = 256
+)
+
+// labelKeyPattern allows keys like "ticket", "team.owner", "ci/job-id".
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+
+// ParseLabel parses "key=value". The value may be empty but must not contain
+// newlines; the key must match labelKeyPattern.
+func ParseLabel(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("label %q must be key=value", s)
+	}
+	if err := validateLabelKey(key); err != nil {
+		return "", "", err
+	}
+	if len(value) > MaxLabelValueLen {
+		return "", "", fmt.Errorf("label %q value is longer than %d characters", key, MaxLabelValueLen)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return "", "", fmt.Errorf("label %q value must not contain newlines", key)
+	}
+	return key, value, nil
+}
+
+// ParseLabels parses repeated key=value arguments into a map.
+// Returns nil for no labels. Repeating a key is an error.
+func ParseLabels(args []string) (map[string]string, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, err := ParseLabel(arg)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := labels[key]; dup {
+			return nil, fmt.Errorf("label %q given more than once", key)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// LabelSelector matches runs by label: "key=value" requires an exact value,
+// a bare "key" requires only that the label is present.
+type LabelSelector struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+// ParseLabelSelector parses "key=value" or "key".
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	if strings.Contains(s, "=") {
+		key, value, err := ParseLabel(s)
+		if err != nil {
+			return LabelSelector{}, err
+		}
+		return LabelSelector{Key: key, Value: value, HasValue: true}, nil
+	}
+	if err := validateLabelKey(s); err != nil {
+		return LabelSelector{}, err
+	}
+	return LabelSelector{Key: s}, nil
+}
+
+// Matches reports whether labels satisfy the selector.
+func (sel LabelSelector) Matches(labels map[string]string) bool {
+	v, ok := labels[sel.Key]
+	if !ok {
+		return false
+	}
+	return !sel.HasValue || v == sel.Value
+}
+
+// FormatLabels renders labels as "k1=v1,k2=v2" sorted by key.
+func FormatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + labels[k]
+	}
+	return strings.Join(parts, ",")
+}
+
+func validateLabelKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("label key must not be empty")
+	}
+	if len(key) > MaxLabelKeyLen {
+		return fmt.Errorf("label key %q is longer than %d characters", key, MaxLabelKeyLen)
+	}
+	if !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("label key %q must contain only letters, digits, '.', '_', '-' and '/', and start and end with a letter or digit", key)
+	}
+	return nil
+}
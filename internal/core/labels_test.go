@@ -0,0 +1,78 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	got, err := ParseLabels([]string{"ticket=JIRA-123", "team.owner=infra", "ci/job=", "note=a=b"})
+	if err != nil {
+		t.Fatalf("ParseLabels: %v", err)
+	}
+	want := map[string]string{"ticket": "JIRA-123", "team.owner": "infra", "ci/job": "", "note": "a=b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLabels = %v, want %v", got, want)
+	}
+
+	if got, err := ParseLabels(nil); err != nil || got != nil {
+		t.Errorf("ParseLabels(nil) = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestParseLabels_Invalid(t *testing.T) {
+	cases := [][]string{
+		{"ticket"},
+		{"=value"},
+		{"-bad=x"},
+		{"bad key=x"},
+		{"k=line1\nline2"},
+		{"k=" + strings.Repeat("v", MaxLabelValueLen+1)},
+		{strings.Repeat("k", MaxLabelKeyLen+1) + "=v"},
+		{"ticket=a", "ticket=b"},
+	}
+	for _, args := range cases {
+		if _, err := ParseLabels(args); err == nil {
+			t.Errorf("ParseLabels(%q) = nil error, want error", args)
+		}
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	labels := map[string]string{"ticket": "JIRA-123", "flaky": ""}
+
+	tests := []struct {
+		sel  string
+		want bool
+	}{
+		{"ticket=JIRA-123", true},
+		{"ticket=JIRA-124", false},
+		{"ticket", true},
+		{"flaky", true},
+		{"flaky=", true},
+		{"missing", false},
+	}
+	for _, tt := range tests {
+		sel, err := ParseLabelSelector(tt.sel)
+		if err != nil {
+			t.Fatalf("ParseLabelSelector(%q): %v", tt.sel, err)
+		}
+		if got := sel.Matches(labels); got != tt.want {
+			t.Errorf("%q.Matches = %v, want %v", tt.sel, got, tt.want)
+		}
+	}
+
+	if _, err := ParseLabelSelector("bad key"); err == nil {
+		t.Error("ParseLabelSelector(bad key) should fail")
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	if got := FormatLabels(map[string]string{"b": "2", "a": "1"}); got != "a=1,b=2" {
+		t.Errorf("FormatLabels = %q, want a=1,b=2", got)
+	}
+	if got := FormatLabels(nil); got != "" {
+		t.Errorf("FormatLabels(nil) = %q, want empty", got)
+	}
+}
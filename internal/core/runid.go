@@ -3,12 +3,21 @@ package core
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 )
 
+// MaxRunIDLen is the maximum length of an externally supplied run_id.
+const MaxRunIDLen = 64
+
+// runIDPattern restricts external run ids to characters that are safe in
+// directory names, git refs, and tmux session names (which reject '.' and ':').
+var runIDPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9_-]*[a-z0-9])?$`)
+
 // NewRunID returns "<yyyymmddhhmmss>-<rand4>" in UTC time.
 // Example: "20260109013207-a3f2"
 // Error only if crypto/rand read fails.
@@ -23,15 +32,30 @@ func NewRunID(now time.Time) (string, error) {
 }
 
 // ShortID returns the 4-char random suffix (after the last '-').
-// If the format is unexpected, return "xxxx".
+// If the format is unexpected (a caller-supplied run_id), it returns the
+// first 4 hex chars of the run_id's SHA-256 instead, so that such runs with
+// the same title still get distinct branch names.
 func ShortID(runID string) string {
 	idx := strings.LastIndex(runID, "-")
-	if idx == -1 || idx+1 >= len(runID) {
-		return "xxxx"
+	if idx == -1 || len(runID)-idx-1 != 4 {
+		sum := sha256.Sum256([]byte(runID))
+		return hex.EncodeToString(sum[:2])
+	}
+	return runID[idx+1:]
+}
+
+// ValidateRunID checks the format of an externally supplied run_id:
+// 1-64 chars of lowercase letters, digits, '-' and '_', starting and ending
+// with a letter or digit. Generated ids always pass.
+func ValidateRunID(runID string) error {
+	if runID == "" {
+		return fmt.Errorf("run_id must not be empty")
+	}
+	if len(runID) > MaxRunIDLen {
+		return fmt.Errorf("run_id %q is longer than %d characters", runID, MaxRunIDLen)
 	}
-	suffix := runID[idx+1:]
-	if len(suffix) != 4 {
-		return "xxxx"
+	if !runIDPattern.MatchString(runID) {
+		return fmt.Errorf("run_id %q must contain only lowercase letters, digits, '-' and '_', and start and end with a letter or digit", runID)
 	}
-	return suffix
+	return nil
 }
@@ -2,6 +2,7 @@ package core
 
 import (
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 )
@@ -59,12 +60,12 @@ func TestShortID(t *testing.T) {
 	}{
 		{"valid format", "20260109013207-a3f2", "a3f2"},
 		{"valid format hex", "20260109013207-beef", "beef"},
-		{"no hyphen", "20260109013207", "xxxx"},
-		{"empty", "", "xxxx"},
-		{"short suffix", "20260109013207-ab", "xxxx"},
-		{"long suffix", "20260109013207-abcde", "xxxx"},
+		{"no hyphen", "20260109013207", "88d9"},
+		{"empty", "", "e3b0"},
+		{"short suffix", "20260109013207-ab", "d3fe"},
+		{"long suffix", "20260109013207-abcde", "e3f5"},
 		{"multiple hyphens", "a-b-c-d3f2", "d3f2"},
-		{"hyphen at end", "test-", "xxxx"},
+		{"hyphen at end", "test-", "e494"},
 	}
 
 	for _, tt := range tests {
@@ -76,3 +77,23 @@ func TestShortID(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateRunID(t *testing.T) {
+	generated, err := NewRunID(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := []string{generated, "job-4821", "ci_build_17", "a", "x1"}
+	for _, id := range valid {
+		if err := ValidateRunID(id); err != nil {
+			t.Errorf("ValidateRunID(%q) = %v, want nil", id, err)
+		}
+	}
+
+	invalid := []string{"", "-", "-abc", "abc-", "Job-1", "a.b", "a:b", "a/b", "a b", strings.Repeat("a", MaxRunIDLen+1)}
+	for _, id := range invalid {
+		if err := ValidateRunID(id); err == nil {
+			t.Errorf("ValidateRunID(%q) = nil, want error", id)
+		}
+	}
+}
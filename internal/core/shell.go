@@ -30,3 +30,13 @@ func BuildRunnerShellScript(worktreePath, runnerCmd string) string {
 	escapedPath := ShellEscapePosix(worktreePath)
 	return "cd " + escapedPath + " && exec " + runnerCmd
 }
+
+// WithGitHubTokenFile prefixes a BuildRunnerShellScript script so the runner
+// gets GITHUB_TOKEN and GH_TOKEN from tokenPath. The token is read inside the
+// shell, so it never appears in argv. Empty tokenPath returns script unchanged.
+func WithGitHubTokenFile(script, tokenPath string) string {
+	if tokenPath == "" {
+		return script
+	}
+	return "GITHUB_TOKEN=\"$(cat " + ShellEscapePosix(tokenPath) + ")\" && GH_TOKEN=\"$GITHUB_TOKEN\" && export GITHUB_TOKEN GH_TOKEN && " + script
+}
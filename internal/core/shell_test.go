@@ -88,3 +88,14 @@ func TestBuildRunnerShellScript(t *testing.T) {
 		})
 	}
 }
+
+func TestWithGitHubTokenFile(t *testing.T) {
+	script := BuildRunnerShellScript("/wt", "claude")
+	if got := WithGitHubTokenFile(script, ""); got != script {
+		t.Errorf("empty token path changed script: %q", got)
+	}
+	want := `GITHUB_TOKEN="$(cat '/runs/a b/github_token')" && GH_TOKEN="$GITHUB_TOKEN" && export GITHUB_TOKEN GH_TOKEN && cd '/wt' && exec claude`
+	if got := WithGitHubTokenFile(script, "/runs/a b/github_token"); got != want {
+		t.Errorf("WithGitHubTokenFile() = %q, want %q", got, want)
+	}
+}
@@ -15,25 +15,86 @@ import (
 // - after truncation, re-trim leading/trailing hyphens and collapse repeats
 // if result empty or maxLen <= 0 => "untitled"
 func Slugify(title string, maxLen int) string {
+	return slugify(title, maxLen, false, "")
+}
+
+// DefaultSlugMaxLen is the slug length limit when a repo sets none.
+const DefaultSlugMaxLen = 30
+
+// SlugExtraChars lists the characters SlugRules.ExtraChars may allow beyond
+// [a-z0-9-]; others are unsafe or awkward in git ref names.
+const SlugExtraChars = "._"
+
+// SlugRules configures how run titles become branch slugs and default titles
+// (agency.json "slug"). The zero value gives the built-in behavior.
+type SlugRules struct {
+	// Prefix is prepended to every slug as "<prefix>-" (e.g. team initials).
+	// It must already be a valid slug under these rules.
+	Prefix string
+
+	// MaxLen caps the whole slug, prefix included (0 = DefaultSlugMaxLen).
+	MaxLen int
+
+	// PreserveCase keeps uppercase letters instead of lowercasing.
+	PreserveCase bool
+
+	// ExtraChars are characters from SlugExtraChars kept as-is.
+	ExtraChars string
+}
+
+// Slug returns the slug for title under r.
+func (r SlugRules) Slug(title string) string {
+	maxLen := r.MaxLen
+	if maxLen <= 0 {
+		maxLen = DefaultSlugMaxLen
+	}
+	if r.Prefix == "" {
+		return slugify(title, maxLen, r.PreserveCase, r.ExtraChars)
+	}
+	return r.Prefix + "-" + slugify(title, maxLen-len(r.Prefix)-1, r.PreserveCase, r.ExtraChars)
+}
+
+// DefaultTitle returns the title used when a run has none:
+// "untitled-<shortid>", or "<prefix>-untitled-<shortid>".
+func (r SlugRules) DefaultTitle(runID string) string {
+	title := "untitled-" + ShortID(runID)
+	if r.Prefix != "" {
+		title = r.Prefix + "-" + title
+	}
+	return title
+}
+
+// slugify implements Slugify with optional case preservation and extra
+// allowed characters. Runs of separators (hyphens and extra chars) collapse
+// to their first character and are trimmed from both ends.
+func slugify(title string, maxLen int, preserveCase bool, extra string) string {
 	if maxLen <= 0 {
 		return "untitled"
 	}
+	if !preserveCase {
+		title = strings.ToLower(title)
+	}
 
 	var b strings.Builder
-	for _, r := range strings.ToLower(title) {
+	for _, r := range title {
 		switch {
 		case r >= 'a' && r <= 'z':
 			b.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
 		case r >= '0' && r <= '9':
 			b.WriteRune(r)
+		case r < unicode.MaxASCII && strings.ContainsRune(extra, r):
+			b.WriteRune(r)
 		case unicode.IsSpace(r) || r == '_' || r == '-':
 			b.WriteRune('-')
 		// drop all other chars
 		}
 	}
 
-	result := collapseHyphens(b.String())
-	result = strings.Trim(result, "-")
+	seps := "-" + extra
+	result := collapseSeparators(b.String(), seps)
+	result = strings.Trim(result, seps)
 
 	// truncate
 	if len(result) > maxLen {
@@ -41,8 +102,8 @@ func Slugify(title string, maxLen int) string {
 	}
 
 	// re-trim after truncation
-	result = collapseHyphens(result)
-	result = strings.Trim(result, "-")
+	result = collapseSeparators(result, seps)
+	result = strings.Trim(result, seps)
 
 	if result == "" {
 		return "untitled"
@@ -50,19 +111,20 @@ func Slugify(title string, maxLen int) string {
 	return result
 }
 
-// collapseHyphens replaces multiple consecutive hyphens with a single hyphen.
-func collapseHyphens(s string) string {
+// collapseSeparators replaces each run of consecutive separator characters
+// with its first character (e.g. "a--b" => "a-b", "a.-b" => "a.b").
+func collapseSeparators(s, seps string) string {
 	var b strings.Builder
-	prevHyphen := false
+	prevSep := false
 	for _, r := range s {
-		if r == '-' {
-			if !prevHyphen {
+		if strings.ContainsRune(seps, r) {
+			if !prevSep {
 				b.WriteRune(r)
-				prevHyphen = true
+				prevSep = true
 			}
 		} else {
 			b.WriteRune(r)
-			prevHyphen = false
+			prevSep = false
 		}
 	}
 	return b.String()
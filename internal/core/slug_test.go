@@ -103,3 +103,36 @@ func TestSlugify_EdgeCases(t *testing.T) {
 		t.Errorf("Slugify result should not end with hyphen: %q", got)
 	}
 }
+
+func TestSlugRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  SlugRules
+		title  string
+		expect string
+	}{
+		{"zero value matches Slugify", SlugRules{}, "Fix bug #123 (urgent!!!)", "fix-bug-123-urgent"},
+		{"prefix", SlugRules{Prefix: "nw"}, "Fix login", "nw-fix-login"},
+		{"prefix counts toward max length", SlugRules{Prefix: "nw", MaxLen: 12}, "implement feature x", "nw-implement"},
+		{"custom max length", SlugRules{MaxLen: 40}, "this is a very long title that exceeds thirty characters", "this-is-a-very-long-title-that-exceeds-t"},
+		{"preserve case", SlugRules{PreserveCase: true}, "Fix JIRA-123 Login", "Fix-JIRA-123-Login"},
+		{"extra chars kept", SlugRules{ExtraChars: "._"}, "bump v1.2_final", "bump-v1.2_final"},
+		{"extra chars collapse and trim", SlugRules{ExtraChars: "."}, "..a...b -.c.", "a.b-c"},
+		{"empty title with prefix", SlugRules{Prefix: "nw"}, "!!!", "nw-untitled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.Slug(tt.title); got != tt.expect {
+				t.Errorf("Slug(%q) = %q, want %q", tt.title, got, tt.expect)
+			}
+		})
+	}
+
+	rules := SlugRules{Prefix: "nw"}
+	if got := rules.BranchName("Fix login", "20260109013207-a3f2"); got != "agency/nw-fix-login-a3f2" {
+		t.Errorf("BranchName() = %q", got)
+	}
+	if got := rules.DefaultTitle("20260109013207-a3f2"); got != "nw-untitled-a3f2" {
+		t.Errorf("DefaultTitle() = %q", got)
+	}
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultStatusLineFormat is the tmux status line agency attach shows for a
+// run when the user config sets no attach.status_format.
+const DefaultStatusLineFormat = "{run_id} {title} [{status}] {pr}"
+
+// statusLinePlaceholders are the names a status line format may use.
+var statusLinePlaceholders = []string{"run_id", "title", "status", "pr", "branch", "runner"}
+
+// StatusLinePlaceholders returns the placeholder names of a status line
+// format, in documentation order.
+func StatusLinePlaceholders() []string {
+	return append([]string(nil), statusLinePlaceholders...)
+}
+
+// FormatStatusLine expands a status line format with the placeholder syntax
+// of ParseTemplate. Placeholders missing from vars expand to "" (e.g. {pr}
+// for a run without a PR); the result is trimmed. Unknown names and syntax
+// errors are errors, so FormatStatusLine(format, nil) validates a format.
+func FormatStatusLine(format string, vars map[string]string) (string, error) {
+	out, err := walkTemplate(format, func(name string) (string, error) {
+		for _, known := range statusLinePlaceholders {
+			if name == known {
+				return vars[name], nil
+			}
+		}
+		return "", fmt.Errorf("unknown placeholder {%s} (known: {%s})", name, strings.Join(statusLinePlaceholders, "}, {"))
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,36 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatStatusLine(t *testing.T) {
+	vars := map[string]string{"run_id": "20260110120000-a3f2", "title": "fix login", "status": "active", "branch": "agency/fix-login-a3f2", "runner": "claude"}
+	cases := map[string]string{
+		DefaultStatusLineFormat:      "20260110120000-a3f2 fix login [active]",
+		"{title} ({branch}) {{x}}":   "fix login (agency/fix-login-a3f2) {x}",
+		"{runner}: {status} PR {pr}": "claude: active PR",
+	}
+	for format, want := range cases {
+		got, err := FormatStatusLine(format, vars)
+		if err != nil || got != want {
+			t.Errorf("FormatStatusLine(%q) = %q, %v; want %q", format, got, err, want)
+		}
+	}
+
+	vars["pr"] = "#42"
+	if got, _ := FormatStatusLine(DefaultStatusLineFormat, vars); got != "20260110120000-a3f2 fix login [active] #42" {
+		t.Errorf("with a PR: %q", got)
+	}
+
+	for format, want := range map[string]string{
+		"{date}":    "unknown placeholder {date}",
+		"{title":    "unterminated placeholder",
+		"title } x": "unmatched }",
+	} {
+		if _, err := FormatStatusLine(format, nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("FormatStatusLine(%q) error = %v, want %q", format, err, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Title template placeholders, expanded when a run is created.
+const (
+	TemplateDate   = "date"   // creation date, YYYY-MM-DD (local time)
+	TemplateTicket = "ticket" // the run's "ticket" label
+	TemplateParent = "parent" // the resolved parent branch
+	TemplateRunner = "runner" // the resolved runner name
+)
+
+// templatePlaceholders is the set of known placeholder names.
+var templatePlaceholders = map[string]bool{
+	TemplateDate:   true,
+	TemplateTicket: true,
+	TemplateParent: true,
+	TemplateRunner: true,
+}
+
+// TemplatePlaceholders returns the known placeholder names, sorted.
+func TemplatePlaceholders() []string {
+	names := make([]string, 0, len(templatePlaceholders))
+	for name := range templatePlaceholders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseTemplate returns the placeholders s uses, in order of first use.
+// Placeholders are written {name}; "{{" and "}}" stand for literal braces.
+// Unknown names, empty or unterminated placeholders, and stray "}" are
+// errors.
+func ParseTemplate(s string) ([]string, error) {
+	var used []string
+	seen := map[string]bool{}
+	_, err := walkTemplate(s, func(name string) (string, error) {
+		if !templatePlaceholders[name] {
+			return "", fmt.Errorf("unknown placeholder {%s} (known: {%s})", name, strings.Join(TemplatePlaceholders(), "}, {"))
+		}
+		if !seen[name] {
+			seen[name] = true
+			used = append(used, name)
+		}
+		return "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return used, nil
+}
+
+// ExpandTemplate replaces each placeholder in s with its value in vars and
+// returns the result with the values it used. A placeholder missing from
+// vars is an error, as are the syntax errors of ParseTemplate.
+func ExpandTemplate(s string, vars map[string]string) (string, map[string]string, error) {
+	if _, err := ParseTemplate(s); err != nil {
+		return "", nil, err
+	}
+	used := map[string]string{}
+	out, err := walkTemplate(s, func(name string) (string, error) {
+		value, ok := vars[name]
+		if !ok {
+			return "", fmt.Errorf("no value for placeholder {%s}", name)
+		}
+		used[name] = value
+		return value, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return out, used, nil
+}
+
+// walkTemplate scans s, calling placeholder for each {name} and copying the
+// rest (with "{{" and "}}" unescaped).
+func walkTemplate(s string, placeholder func(name string) (string, error)) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '{' && i+1 < len(s) && s[i+1] == '{':
+			b.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(s) && s[i+1] == '}':
+			b.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(s[i+1:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated placeholder at offset %d (use {{ for a literal brace)", i)
+			}
+			name := s[i+1 : i+1+end]
+			if name == "" {
+				return "", fmt.Errorf("empty placeholder {} at offset %d", i)
+			}
+			value, err := placeholder(name)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += end + 1
+		case c == '}':
+			return "", fmt.Errorf("unmatched } at offset %d (use }} for a literal brace)", i)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
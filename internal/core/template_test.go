@@ -0,0 +1,60 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	vars := map[string]string{"date": "2026-01-10", "ticket": "JIRA-123", "parent": "main", "runner": "claude"}
+	cases := []struct {
+		in, want string
+		used     map[string]string
+	}{
+		{"plain title", "plain title", map[string]string{}},
+		{"{ticket}: fix login", "JIRA-123: fix login", map[string]string{"ticket": "JIRA-123"}},
+		{"{runner} on {parent} {date} {date}", "claude on main 2026-01-10 2026-01-10",
+			map[string]string{"runner": "claude", "parent": "main", "date": "2026-01-10"}},
+		{"literal {{braces}}", "literal {braces}", map[string]string{}},
+	}
+	for _, tc := range cases {
+		got, used, err := ExpandTemplate(tc.in, vars)
+		if err != nil {
+			t.Errorf("ExpandTemplate(%q) error: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want || !reflect.DeepEqual(used, tc.used) {
+			t.Errorf("ExpandTemplate(%q) = %q, %v; want %q, %v", tc.in, got, used, tc.want, tc.used)
+		}
+	}
+}
+
+func TestExpandTemplate_Errors(t *testing.T) {
+	cases := map[string]string{
+		"{branch} fix": "unknown placeholder {branch}",
+		"fix {date":    "unterminated placeholder",
+		"fix {} now":   "empty placeholder",
+		"fix } now":    "unmatched }",
+		"{ticket} fix": "no value for placeholder {ticket}",
+	}
+	for in, want := range cases {
+		_, _, err := ExpandTemplate(in, map[string]string{"date": "2026-01-10"})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ExpandTemplate(%q) error = %v, want %q", in, err, want)
+		}
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	used, err := ParseTemplate("{ticket} {date} {ticket} {{x}}")
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	if want := []string{"ticket", "date"}; !reflect.DeepEqual(used, want) {
+		t.Errorf("ParseTemplate = %v, want %v", used, want)
+	}
+	if _, err := ParseTemplate("{Date}"); err == nil || !strings.Contains(err.Error(), "known: {date}, {parent}, {runner}, {ticket}") {
+		t.Errorf("ParseTemplate({Date}) error = %v, want the known placeholders listed", err)
+	}
+}
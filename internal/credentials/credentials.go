@@ -0,0 +1,266 @@
+// Package credentials mints the GitHub token exported to a run's runner
+// session (agency.json github.credentials), so agents get a token chosen for
+// the run instead of implicitly inheriting the user's full credentials.
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// TokenFileName is the token file in the run dir read by the runner session.
+const TokenFileName = "github_token"
+
+// RefreshMargin is how long before expiry a token is replaced on attach.
+const RefreshMargin = 5 * time.Minute
+
+// DefaultAPIURL is the GitHub REST API used when github.app.api_url is unset.
+const DefaultAPIURL = "https://api.github.com"
+
+// httpClient is used for GitHub App token requests.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Token is a minted GitHub token.
+type Token struct {
+	Value string
+
+	// ExpiresAt is zero for tokens without a known expiry (gh auth token).
+	ExpiresAt time.Time
+}
+
+// Source records how a run's token is minted; it is stored in meta.json as
+// credentials so attach can refresh the token without agency.json.
+func Source(gh config.GitHub, repoKey string) *store.RunMetaCredentials {
+	mode := gh.CredentialMode()
+	if mode == config.CredentialsInherit {
+		return nil
+	}
+	creds := &store.RunMetaCredentials{Mode: mode}
+	if mode == config.CredentialsApp {
+		permissions := gh.App.Permissions
+		if permissions == nil {
+			permissions = config.DefaultAppPermissions
+		}
+		creds.App = &store.RunMetaCredentialsApp{
+			AppID:          gh.App.AppID,
+			InstallationID: gh.App.InstallationID,
+			PrivateKeyPath: gh.App.PrivateKeyPath,
+			Permissions:    permissions,
+			APIURL:         gh.App.APIURL,
+			Repository:     repoName(repoKey),
+		}
+	}
+	return creds
+}
+
+// repoName returns "repo" from a "github:owner/repo" repo key ("" otherwise).
+func repoName(repoKey string) string {
+	rest, ok := strings.CutPrefix(repoKey, "github:")
+	if !ok {
+		return ""
+	}
+	_, name, _ := strings.Cut(rest, "/")
+	return name
+}
+
+// Mint returns a new token for creds.
+//
+// Error codes:
+//   - E_GH_NOT_INSTALLED / E_GH_NOT_AUTHENTICATED: gh mode without a gh login
+//   - E_CREDENTIALS_FAILED: the GitHub App key or token request failed
+func Mint(ctx context.Context, cr exec.CommandRunner, creds *store.RunMetaCredentials, now time.Time) (Token, error) {
+	switch creds.Mode {
+	case config.CredentialsGh:
+		return mintGh(ctx, cr)
+	case config.CredentialsApp:
+		if creds.App == nil {
+			return Token{}, errors.New(errors.ECredentialsFailed, "credentials.app missing from meta.json")
+		}
+		return mintApp(ctx, creds.App, now)
+	}
+	return Token{}, errors.New(errors.ECredentialsFailed, "unknown credential mode "+strconv.Quote(creds.Mode))
+}
+
+// mintGh returns the token of the current gh login.
+func mintGh(ctx context.Context, cr exec.CommandRunner) (Token, error) {
+	result, err := cr.Run(ctx, "gh", []string{"auth", "token"}, exec.RunOpts{})
+	if err != nil {
+		return Token{}, errors.Wrap(errors.EGhNotInstalled, "failed to run gh auth token", err)
+	}
+	token := strings.TrimSpace(result.Stdout)
+	if result.ExitCode != 0 || token == "" {
+		return Token{}, errors.NewWithDetails(errors.EGhNotAuthenticated, "gh auth token failed: "+strings.TrimSpace(result.Stderr),
+			map[string]string{"exit_code": strconv.Itoa(result.ExitCode)})
+	}
+	return Token{Value: token}, nil
+}
+
+// mintApp requests an installation token scoped to app.Repository (if known)
+// and app.Permissions.
+func mintApp(ctx context.Context, app *store.RunMetaCredentialsApp, now time.Time) (Token, error) {
+	jwt, err := appJWT(app, now)
+	if err != nil {
+		return Token{}, err
+	}
+
+	body := map[string]any{"permissions": app.Permissions}
+	if app.Repository != "" {
+		body["repositories"] = []string{app.Repository}
+	}
+	payload, _ := json.Marshal(body)
+
+	apiURL := app.APIURL
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	url := strings.TrimRight(apiURL, "/") + "/app/installations/" + strconv.FormatInt(app.InstallationID, 10) + "/access_tokens"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return Token{}, errors.Wrap(errors.ECredentialsFailed, "invalid github.app.api_url", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Token{}, errors.WrapWithDetails(errors.ECredentialsFailed, "GitHub App token request failed", err, map[string]string{"url": url})
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode != http.StatusCreated {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &apiErr)
+		return Token{}, errors.NewWithDetails(errors.ECredentialsFailed,
+			fmt.Sprintf("GitHub App token request failed: HTTP %d %s", resp.StatusCode, apiErr.Message),
+			map[string]string{"url": url, "status": strconv.Itoa(resp.StatusCode)})
+	}
+
+	var out struct {
+		Token     string `json:"token"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil || out.Token == "" {
+		return Token{}, errors.New(errors.ECredentialsFailed, "GitHub App token response has no token")
+	}
+	expiresAt, _ := time.Parse(time.RFC3339, out.ExpiresAt)
+	return Token{Value: out.Token, ExpiresAt: expiresAt}, nil
+}
+
+// appJWT signs the short-lived RS256 JWT authenticating as the GitHub App.
+func appJWT(app *store.RunMetaCredentialsApp, now time.Time) (string, error) {
+	key, err := readPrivateKey(app.PrivateKeyPath)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]any{
+		// Backdated to allow for clock drift; GitHub caps exp at 10 minutes
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(app.AppID, 10),
+	})
+	signingInput := header + "." + enc.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.Wrap(errors.ECredentialsFailed, "failed to sign GitHub App JWT", err)
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
+
+// readPrivateKey reads a PKCS#1 or PKCS#8 RSA key from path ("~/" = HOME).
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errors.Wrap(errors.ECredentialsFailed, "cannot resolve ~ in github.app.private_key_path", err)
+		}
+		path = filepath.Join(home, rest)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WrapWithDetails(errors.ECredentialsFailed, "cannot read GitHub App private key", err,
+			map[string]string{"path": path})
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.NewWithDetails(errors.ECredentialsFailed, "GitHub App private key is not PEM",
+			map[string]string{"path": path})
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.WrapWithDetails(errors.ECredentialsFailed, "cannot parse GitHub App private key", err,
+			map[string]string{"path": path})
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.NewWithDetails(errors.ECredentialsFailed, "GitHub App private key is not an RSA key",
+			map[string]string{"path": path})
+	}
+	return key, nil
+}
+
+// WriteToken stores token in the run dir (mode 0600) and records its expiry
+// in creds. Returns the token file path.
+func WriteToken(fsys fs.FS, runDir string, creds *store.RunMetaCredentials, token Token, now time.Time) (string, error) {
+	path := filepath.Join(runDir, TokenFileName)
+	if err := fs.WriteFileAtomic(fsys, path, []byte(token.Value+"\n"), 0o600); err != nil {
+		return "", errors.Wrap(errors.ECredentialsFailed, "failed to write token file", err)
+	}
+	creds.TokenPath = path
+	creds.MintedAt = now.UTC().Format(time.RFC3339)
+	creds.ExpiresAt = ""
+	if !token.ExpiresAt.IsZero() {
+		creds.ExpiresAt = token.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return path, nil
+}
+
+// NeedsRefresh reports whether creds' token is missing or expires within
+// RefreshMargin. Tokens without a recorded expiry never need refreshing.
+func NeedsRefresh(fsys fs.FS, creds *store.RunMetaCredentials, now time.Time) bool {
+	if creds.TokenPath == "" {
+		return true
+	}
+	if _, err := fsys.Stat(creds.TokenPath); err != nil {
+		return true
+	}
+	if creds.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, creds.ExpiresAt)
+	return err != nil || !now.Add(RefreshMargin).Before(expiresAt)
+}
@@ -0,0 +1,192 @@
+package credentials
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestSource(t *testing.T) {
+	if got := Source(config.GitHub{}, "github:owner/repo"); got != nil {
+		t.Errorf("inherit: Source() = %+v, want nil", got)
+	}
+
+	got := Source(config.GitHub{
+		Credentials: config.CredentialsApp,
+		App:         config.GitHubApp{AppID: 1, InstallationID: 2, PrivateKeyPath: "/k.pem"},
+	}, "github:owner/repo")
+	if got == nil || got.Mode != config.CredentialsApp || got.App == nil {
+		t.Fatalf("app: Source() = %+v", got)
+	}
+	if got.App.Repository != "repo" {
+		t.Errorf("Repository = %q, want repo", got.App.Repository)
+	}
+	if got.App.Permissions["contents"] != "write" {
+		t.Errorf("Permissions = %v, want defaults", got.App.Permissions)
+	}
+
+	if got := Source(config.GitHub{Credentials: config.CredentialsApp}, "path:abc"); got.App.Repository != "" {
+		t.Errorf("non-github repo: Repository = %q, want empty", got.App.Repository)
+	}
+}
+
+func TestMint_Gh(t *testing.T) {
+	cr := testkit.NewFakeRunner()
+	cr.On("gh", "auth", "token").Stdout("gho_abc\n")
+
+	token, err := Mint(context.Background(), cr, &store.RunMetaCredentials{Mode: config.CredentialsGh}, time.Now())
+	if err != nil {
+		t.Fatalf("Mint() error = %v", err)
+	}
+	if token.Value != "gho_abc" || !token.ExpiresAt.IsZero() {
+		t.Errorf("token = %+v", token)
+	}
+
+	cr = testkit.NewFakeRunner()
+	cr.On("gh", "auth", "token").Exit(1).Stderr("no oauth token\n")
+	if _, err := Mint(context.Background(), cr, &store.RunMetaCredentials{Mode: config.CredentialsGh}, time.Now()); errors.GetCode(err) != errors.EGhNotAuthenticated {
+		t.Errorf("logged out: err = %v, want E_GH_NOT_AUTHENTICATED", err)
+	}
+}
+
+func TestMint_App(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "app.pem")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		if err := verifyJWT(&key.PublicKey, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), now); err != "" {
+			http.Error(w, `{"message":"`+err+`"}`, http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token":"ghs_scoped","expires_at":"2026-01-10T13:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	creds := &store.RunMetaCredentials{Mode: config.CredentialsApp, App: &store.RunMetaCredentialsApp{
+		AppID:          7,
+		InstallationID: 42,
+		PrivateKeyPath: keyPath,
+		Permissions:    map[string]string{"contents": "write"},
+		APIURL:         server.URL,
+		Repository:     "repo",
+	}}
+	token, err := Mint(context.Background(), nil, creds, now)
+	if err != nil {
+		t.Fatalf("Mint() error = %v", err)
+	}
+	if token.Value != "ghs_scoped" || !token.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("token = %+v", token)
+	}
+	if repos, _ := gotBody["repositories"].([]any); len(repos) != 1 || repos[0] != "repo" {
+		t.Errorf("request repositories = %v", gotBody["repositories"])
+	}
+
+	// Token is stored 0600 and its expiry recorded
+	runDir := t.TempDir()
+	path, err := WriteToken(fs.NewRealFS(), runDir, creds, token, now)
+	if err != nil {
+		t.Fatalf("WriteToken() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, err = %v", info.Mode(), err)
+	}
+	if creds.ExpiresAt != "2026-01-10T13:00:00Z" || creds.TokenPath != path {
+		t.Errorf("creds = %+v", creds)
+	}
+
+	// Rejected requests surface the API message
+	creds.App.InstallationID = 43
+	if _, err := Mint(context.Background(), nil, creds, now); errors.GetCode(err) != errors.ECredentialsFailed || !strings.Contains(err.Error(), "HTTP 404 Not Found") {
+		t.Errorf("wrong installation: err = %v", err)
+	}
+}
+
+// verifyJWT checks an RS256 GitHub App JWT; it returns "" if it is valid.
+func verifyJWT(pub *rsa.PublicKey, jwt string, now time.Time) string {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return "malformed jwt"
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "bad signature encoding"
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+		return "bad signature"
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Iss != "7" {
+		return "bad claims"
+	}
+	if claims.Iat > now.Unix() || claims.Exp <= now.Unix() || claims.Exp-claims.Iat > 600 {
+		return "bad token lifetime"
+	}
+	return ""
+}
+
+func TestNeedsRefresh(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	tokenPath := filepath.Join(t.TempDir(), TokenFileName)
+	if err := os.WriteFile(tokenPath, []byte("t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fs.NewRealFS()
+
+	for _, tt := range []struct {
+		name  string
+		creds store.RunMetaCredentials
+		want  bool
+	}{
+		{"no token yet", store.RunMetaCredentials{Mode: "gh"}, true},
+		{"token file gone", store.RunMetaCredentials{Mode: "gh", TokenPath: tokenPath + ".gone"}, true},
+		{"no expiry", store.RunMetaCredentials{Mode: "gh", TokenPath: tokenPath}, false},
+		{"valid", store.RunMetaCredentials{Mode: "app", TokenPath: tokenPath, ExpiresAt: "2026-01-10T12:30:00Z"}, false},
+		{"expiring", store.RunMetaCredentials{Mode: "app", TokenPath: tokenPath, ExpiresAt: "2026-01-10T12:04:00Z"}, true},
+		{"expired", store.RunMetaCredentials{Mode: "app", TokenPath: tokenPath, ExpiresAt: "2026-01-10T11:00:00Z"}, true},
+	} {
+		if got := NeedsRefresh(fsys, &tt.creds, now); got != tt.want {
+			t.Errorf("%s: NeedsRefresh() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package crypt encrypts run files at rest (meta.json, script logs,
+// transcripts) with age (https://age-encryption.org), by running the age
+// command. Files are encrypted in place: an encrypted file keeps its name and
+// is recognized by the age header, so readers decrypt transparently and
+// plaintext files written before encryption was enabled stay readable.
+//
+// Script logs are written in plaintext while their script runs and sealed
+// (see Crypt.Seal) once the command that wrote them finishes.
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// Header is the first line of a binary age file.
+const Header = "age-encryption.org/v1"
+
+// Config is the encryption.* user config.
+type Config struct {
+	// Enabled encrypts run files when agency writes them.
+	Enabled bool
+
+	// Identity is the age identity file: it decrypts files, and its
+	// recipient encrypts them ("" = none).
+	Identity string
+}
+
+// Crypt encrypts and decrypts run files under a Config, running age through
+// a CommandRunner. A nil *Crypt encrypts nothing and cannot decrypt.
+type Crypt struct {
+	cfg Config
+	cr  exec.CommandRunner
+}
+
+// New returns a Crypt for cfg that runs age with cr.
+func New(cr exec.CommandRunner, cfg Config) *Crypt {
+	return &Crypt{cfg: cfg, cr: cr}
+}
+
+// Config returns c's Config (the zero Config for a nil c).
+func (c *Crypt) Config() Config {
+	if c == nil {
+		return Config{}
+	}
+	return c.cfg
+}
+
+// Enabled reports whether run files are encrypted when written.
+func (c *Crypt) Enabled() bool {
+	return c.Config().Enabled
+}
+
+// IsEncrypted reports whether data is an age file.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Header+"\n"))
+}
+
+// IsEncryptedFile reports whether the file at path is an age file.
+func IsEncryptedFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(Header)+1)
+	n, _ := io.ReadFull(f, head)
+	return IsEncrypted(head[:n])
+}
+
+// Encrypt encrypts data to the recipient of the configured identity.
+// Returns E_ENCRYPTION_KEY_MISSING without an identity and
+// E_ENCRYPTION_FAILED if age fails.
+func (c *Crypt) Encrypt(data []byte) ([]byte, error) {
+	identity, err := c.identityFile()
+	if err != nil {
+		return nil, err
+	}
+	return c.runAge(data, "-e", "-i", identity)
+}
+
+// Decrypt returns data decrypted with the configured identity, or data
+// itself if it is not encrypted.
+// Returns E_ENCRYPTION_KEY_MISSING without an identity and
+// E_ENCRYPTION_FAILED if age fails (e.g. the file was encrypted to another key).
+func (c *Crypt) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	identity, err := c.identityFile()
+	if err != nil {
+		return nil, err
+	}
+	return c.runAge(data, "-d", "-i", identity)
+}
+
+// ReadFile reads the file at path, decrypting it if it is encrypted.
+func (c *Crypt) ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(data)
+}
+
+// Seal encrypts the file at path in place (atomically, keeping its mode),
+// unless encryption is disabled or the file is empty or already encrypted.
+// Reports whether the file was encrypted.
+func (c *Crypt) Seal(path string) (bool, error) {
+	if !c.Enabled() {
+		return false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 || IsEncrypted(data) {
+		return false, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	sealed, err := c.Encrypt(data)
+	if err != nil {
+		return false, err
+	}
+	if err := fs.WriteFileAtomic(fs.NewRealFS(), path, sealed, info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Check verifies that files can be encrypted and decrypted: age is
+// installed, the identity is readable, and a probe round-trips. Returns
+// age's version.
+func (c *Crypt) Check(ctx context.Context) (string, error) {
+	if c == nil {
+		return "", errors.New(errors.EEncryptionKeyMissing, "encryption at rest is not configured")
+	}
+	result, err := c.cr.Run(ctx, "age", []string{"--version"}, exec.RunOpts{})
+	if err != nil {
+		return "", errors.WithHints(errors.Wrap(errors.EEncryptionFailed, "age not found on PATH", err),
+			"install age: https://age-encryption.org")
+	}
+	if result.ExitCode != 0 {
+		return "", errors.New(errors.EEncryptionFailed, fmt.Sprintf("age --version failed (exit %d)", result.ExitCode))
+	}
+
+	probe := []byte("agency encryption probe\n")
+	sealed, err := c.Encrypt(probe)
+	if err != nil {
+		return "", err
+	}
+	opened, err := c.Decrypt(sealed)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(opened, probe) {
+		return "", errors.New(errors.EEncryptionFailed, "age round trip returned different data")
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// identityFile returns the configured identity file, checking it is readable.
+func (c *Crypt) identityFile() (string, error) {
+	identity := c.Config().Identity
+	if identity == "" {
+		return "", errors.WithHints(errors.New(errors.EEncryptionKeyMissing, "no age identity configured"),
+			"agency config set encryption.identity <path to an age identity file>")
+	}
+	if strings.HasPrefix(identity, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			identity = filepath.Join(home, identity[2:])
+		}
+	}
+	f, err := os.Open(identity)
+	if err != nil {
+		return "", errors.WithHints(errors.WrapWithDetails(errors.EEncryptionKeyMissing,
+			"age identity is not readable", err, map[string]string{"identity": identity}),
+			"create one with: age-keygen -o "+identity)
+	}
+	f.Close()
+	return identity, nil
+}
+
+// runAge runs age with args, data on stdin, and returns its stdout.
+func (c *Crypt) runAge(data []byte, args ...string) ([]byte, error) {
+	result, err := c.cr.Run(context.Background(), "age", args, exec.RunOpts{Stdin: bytes.NewReader(data)})
+	if err == nil && result.ExitCode != 0 {
+		err = fmt.Errorf("exit status %d", result.ExitCode)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("age %s failed", args[0])
+		if s := strings.TrimSpace(result.Stderr); s != "" {
+			msg += ": " + s
+		}
+		return nil, errors.Wrap(errors.EEncryptionFailed, msg, err)
+	}
+	return []byte(result.Stdout), nil
+}
@@ -0,0 +1,138 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+)
+
+// fakeAge puts an age script on PATH that "encrypts" to base64 behind the
+// age header, and returns a Crypt enabling encryption with a fake identity.
+// (testkit.FakeAge can't be used here: testkit imports store, which imports crypt.)
+func fakeAge(t *testing.T) *Crypt {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$1\" in\n--version) echo v1.1.1 ;;\n-e) echo \"" + Header + "\"; base64 ;;\n-d) tail -n +2 | base64 -d ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(dir, "age"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	identity := filepath.Join(dir, "age.key")
+	if err := os.WriteFile(identity, []byte("AGE-SECRET-KEY-1FAKE\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return New(exec.NewRealRunner(), Config{Enabled: true, Identity: identity})
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	c := fakeAge(t)
+
+	plain := []byte(`{"run_id":"20260110120000-a3f2"}`)
+	sealed, err := c.Encrypt(plain)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !IsEncrypted(sealed) || bytes.Contains(sealed, plain) {
+		t.Fatalf("Encrypt() = %q, want an age file", sealed)
+	}
+	opened, err := c.Decrypt(sealed)
+	if err != nil || !bytes.Equal(opened, plain) {
+		t.Errorf("Decrypt() = %q, %v; want %q", opened, err, plain)
+	}
+
+	// Plaintext passes through
+	if got, err := c.Decrypt(plain); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Decrypt(plaintext) = %q, %v", got, err)
+	}
+}
+
+func TestEncrypt_KeyMissing(t *testing.T) {
+	c := New(exec.NewRealRunner(), Config{Enabled: true})
+	if _, err := c.Encrypt([]byte("x")); errors.GetCode(err) != errors.EEncryptionKeyMissing {
+		t.Errorf("Encrypt() without identity error = %v, want E_ENCRYPTION_KEY_MISSING", err)
+	}
+
+	c = New(exec.NewRealRunner(), Config{Enabled: true, Identity: filepath.Join(t.TempDir(), "missing.key")})
+	if _, err := c.Decrypt([]byte(Header + "\nxxx")); errors.GetCode(err) != errors.EEncryptionKeyMissing {
+		t.Errorf("Decrypt() with unreadable identity error = %v, want E_ENCRYPTION_KEY_MISSING", err)
+	}
+
+	// Without a Crypt nothing can be decrypted
+	var none *Crypt
+	if _, err := none.Decrypt([]byte(Header + "\nxxx")); errors.GetCode(err) != errors.EEncryptionKeyMissing {
+		t.Errorf("nil Decrypt() error = %v, want E_ENCRYPTION_KEY_MISSING", err)
+	}
+}
+
+func TestSeal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta.json")
+	if err := os.WriteFile(path, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Disabled: left alone
+	var none *Crypt
+	if sealed, err := none.Seal(path); sealed || err != nil {
+		t.Fatalf("Seal() while disabled = %t, %v", sealed, err)
+	}
+
+	c := fakeAge(t)
+	if sealed, err := c.Seal(path); !sealed || err != nil {
+		t.Fatalf("Seal() = %t, %v; want sealed", sealed, err)
+	}
+	if !IsEncryptedFile(path) {
+		t.Fatal("file is not encrypted after Seal()")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("mode after Seal() = %v, %v; want 0600", info.Mode(), err)
+	}
+	if data, err := c.ReadFile(path); err != nil || string(data) != "secret\n" {
+		t.Errorf("ReadFile() = %q, %v", data, err)
+	}
+
+	// Already sealed: left alone
+	if sealed, err := c.Seal(path); sealed || err != nil {
+		t.Errorf("second Seal() = %t, %v; want no-op", sealed, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := fakeAge(t)
+	version, err := c.Check(context.Background())
+	if err != nil || version != "v1.1.1" {
+		t.Errorf("Check() = %q, %v; want v1.1.1", version, err)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := c.Check(context.Background()); errors.GetCode(err) != errors.EEncryptionFailed {
+		t.Errorf("Check() without age error = %v, want E_ENCRYPTION_FAILED", err)
+	}
+}
+
+// ageRunner answers every command with age's header, recording the calls.
+type ageRunner struct{ calls []string }
+
+func (r *ageRunner) Run(_ context.Context, name string, args []string, _ exec.RunOpts) (exec.CmdResult, error) {
+	r.calls = append(r.calls, name+" "+args[0])
+	return exec.CmdResult{Stdout: Header + "\nsealed"}, nil
+}
+
+func TestEncrypt_UsesCommandRunner(t *testing.T) {
+	identity := filepath.Join(t.TempDir(), "age.key")
+	if err := os.WriteFile(identity, []byte("AGE-SECRET-KEY-1FAKE\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cr := &ageRunner{}
+	sealed, err := New(cr, Config{Enabled: true, Identity: identity}).Encrypt([]byte("x"))
+	if err != nil || !IsEncrypted(sealed) {
+		t.Fatalf("Encrypt() = %q, %v", sealed, err)
+	}
+	if len(cr.calls) != 1 || cr.calls[0] != "age -e" {
+		t.Errorf("calls = %q, want [age -e]", cr.calls)
+	}
+}
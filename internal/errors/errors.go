@@ -26,6 +26,7 @@ const (
 	// Tool/prerequisite error codes
 	EGitNotInstalled     Code = "E_GIT_NOT_INSTALLED"
 	ETmuxNotInstalled    Code = "E_TMUX_NOT_INSTALLED"
+	ETmuxUnsupported     Code = "E_TMUX_UNSUPPORTED"
 	EGhNotInstalled      Code = "E_GH_NOT_INSTALLED"
 	EGhNotAuthenticated  Code = "E_GH_NOT_AUTHENTICATED"
 	EScriptNotFound      Code = "E_SCRIPT_NOT_FOUND"
@@ -57,6 +58,115 @@ const (
 	// Slice 2 observability error codes
 	ERunIDAmbiguous Code = "E_RUN_ID_AMBIGUOUS" // id prefix matches >1 run
 	ERunBroken      Code = "E_RUN_BROKEN"       // run exists but meta.json is unreadable/invalid
+
+	// Lifecycle error codes
+	ERepoLocked    Code = "E_REPO_LOCKED"    // repo lock held by another agency process
+	EArchiveFailed Code = "E_ARCHIVE_FAILED" // worktree could not be removed during archive
+
+	// Bulk operation error codes
+	EBulkFailed Code = "E_BULK_FAILED" // one or more runs in a bulk operation failed
+
+	// User config error codes
+	EInvalidUserConfig Code = "E_INVALID_USER_CONFIG" // ${AGENCY_CONFIG_DIR}/config.json is invalid
+
+	// Doctor error codes
+	EDataDirUnhealthy Code = "E_DATA_DIR_UNHEALTHY" // a data dir health check failed
+
+	// Rename error codes
+	EBranchExists    Code = "E_BRANCH_EXISTS"    // target branch name is already taken
+	EPRExists        Code = "E_PR_EXISTS"        // operation would detach the run from its open PR
+	EWorktreeMissing Code = "E_WORKTREE_MISSING" // run is archived; its worktree no longer exists
+
+	// Branch guard error codes
+	EGitHookExists Code = "E_GIT_HOOK_EXISTS" // a post-checkout hook not installed by agency is in the way
+
+	// Quota error codes
+	EWorktreeQuotaExceeded Code = "E_WORKTREE_QUOTA_EXCEEDED" // repo worktrees exceed worktrees.max_total_bytes
+
+	// Worktree path error codes
+	EWorktreePathExists  Code = "E_WORKTREE_PATH_EXISTS"   // worktree path (or a case variant) already exists
+	EWorktreePathTooLong Code = "E_WORKTREE_PATH_TOO_LONG" // worktree file paths exceed worktrees.max_path_length
+
+	// Adopt error codes
+	EBranchNotFound Code = "E_BRANCH_NOT_FOUND" // local branch does not exist
+	EBranchManaged  Code = "E_BRANCH_MANAGED"   // branch already belongs to a run
+
+	// Run scope error codes
+	ERepoNotFound Code = "E_REPO_NOT_FOUND" // --repo matches no repo with agency data
+
+	// Lint error codes
+	EMetaInvalid Code = "E_META_INVALID" // meta.json has error-severity lint problems
+
+	// Data dir error codes
+	EInvalidDataDir     Code = "E_INVALID_DATA_DIR"      // agency.json data_dir override is unusable
+	EDataDirVersionSkew Code = "E_DATA_DIR_VERSION_SKEW" // data dir format is not supported by this build
+	ELegacyMigration    Code = "E_LEGACY_MIGRATION"      // a legacy conductor data dir could not be migrated
+
+	// Setup env error codes
+	ESetupEnvNotFound Code = "E_SETUP_ENV_NOT_FOUND" // run has no captured setup_env.json
+
+	// Sandbox error codes
+	ESandboxViolation Code = "E_SANDBOX_VIOLATION" // enforced setup changed files outside the worktree
+
+	// Log error codes
+	ELogNotFound Code = "E_LOG_NOT_FOUND" // run has no log with the requested name
+
+	// Credential error codes
+	ECredentialsFailed Code = "E_CREDENTIALS_FAILED" // the run's GitHub token could not be minted or stored
+
+	// GitHub API error codes
+	EGhAPIFailed   Code = "E_GH_API_FAILED"   // a gh api call failed
+	EGhRateLimited Code = "E_GH_RATE_LIMITED" // GitHub API rate limit exhausted and nothing cached
+
+	// Checkpoint error codes
+	ECheckpointNotFound Code = "E_CHECKPOINT_NOT_FOUND" // run has no checkpoint with the requested number
+	ECheckpointFailed   Code = "E_CHECKPOINT_FAILED"    // a checkpoint could not be taken or restored
+
+	// Shared data dir error codes
+	EPermissionDenied Code = "E_PERMISSION_DENIED" // another user's run or repo dir is not writable for this user
+
+	// Read-only mode error codes
+	EReadOnly Code = "E_READ_ONLY" // command would modify state under --read-only / AGENCY_READ_ONLY
+
+	// Schema error codes
+	ESchemaViolation Code = "E_SCHEMA_VIOLATION" // a file does not match its JSON schema (agency schema --validate)
+
+	// Network error codes
+	ENetworkFailed Code = "E_NETWORK_FAILED" // a git/gh network command timed out or kept failing after its retries
+
+	// Invocation guard error codes
+	ERunningAsRoot Code = "E_RUNNING_AS_ROOT" // run as root on a data dir or repo owned by another user (--allow-root overrides)
+	ECwdInDataDir  Code = "E_CWD_IN_DATA_DIR" // a writing command was run from inside the data dir, outside a run's worktree
+
+	// Encryption at rest error codes
+	EEncryptionKeyMissing Code = "E_ENCRYPTION_KEY_MISSING" // encryption.identity is unset or unreadable, but a file must be encrypted or decrypted
+	EEncryptionFailed     Code = "E_ENCRYPTION_FAILED"      // the age command is missing or failed to encrypt or decrypt
+
+	// Init error codes
+	ECloneFailed Code = "E_CLONE_FAILED" // init --from-url could not clone the repo into its directory
+
+	// Cleanup error codes
+	EPRNotMerged Code = "E_PR_NOT_MERGED" // cleanup without --force on a run whose PR is missing or not merged
+
+	// Profile error codes
+	ENoProfile Code = "E_NO_PROFILE" // profile report found no profile recorded with --profile
+
+	// Relocate error codes
+	ESessionsRunning Code = "E_SESSIONS_RUNNING" // runner tmux sessions still run in the data dir being relocated
+	ERelocateFailed  Code = "E_RELOCATE_FAILED"  // the data dir could not be moved, or a run's meta.json not rewritten
+
+	// Digest error codes
+	EWebhookFailed Code = "E_WEBHOOK_FAILED" // posting the digest to digest.webhook_url failed
+
+	// Verify error codes
+	EVerifyFailed Code = "E_VERIFY_FAILED" // scripts.verify failed or timed out for the run
+
+	// VCS error codes
+	EJjNotInstalled Code = "E_JJ_NOT_INSTALLED" // the repo is a jj repo but jj cannot be run
+	EVCSUnsupported Code = "E_VCS_UNSUPPORTED"  // jj repo without a colocated git store
+
+	// Scheduling error codes
+	EScheduleBlocked Code = "E_SCHEDULE_BLOCKED" // a scheduling limit is reached and --no-wait was given
 )
 
 // AgencyError is the standard error type for agency errors.
@@ -65,6 +175,7 @@ type AgencyError struct {
 	Msg     string
 	Cause   error
 	Details map[string]string // optional structured context
+	Hints   []string          // optional next steps for the user (see WithHints)
 }
 
 // Error returns the stable error format: "CODE: message".
@@ -141,20 +252,15 @@ func ExitCode(err error) int {
 	return 1
 }
 
-// Print writes the error to w in the stable stderr format:
+// Print writes the error to w in the stable stderr format (see Render):
 //
 //	error_code: <CODE>
 //	<message>
+//	cause: <cause>   (one line per link in the cause chain)
+//	hint: <hint>     (one line per hint)
 func Print(w io.Writer, err error) {
 	if err == nil {
 		return
 	}
-	var ae *AgencyError
-	if errors.As(err, &ae) {
-		fmt.Fprintf(w, "error_code: %s\n", ae.Code)
-		fmt.Fprintln(w, ae.Msg)
-	} else {
-		// Fallback for non-AgencyError errors (should not happen in practice)
-		fmt.Fprintln(w, err.Error())
-	}
+	fmt.Fprint(w, Render(err).Human())
 }
@@ -2,7 +2,10 @@ package errors
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -209,3 +212,66 @@ func TestAsAgencyError(t *testing.T) {
 		}
 	})
 }
+
+func TestHintsSurviveWrap(t *testing.T) {
+	inner := WithHints(NewWithDetails(EInvalidDataDir, "data_dir is inside the repo", map[string]string{"data_dir": "/r/d"}),
+		"move data_dir outside the repo")
+	outer := WithHints(WrapWithDetails(ENoRepo, "cannot resolve dirs", inner, map[string]string{"data_dir": "/outer"}),
+		"run agency doctor", "move data_dir outside the repo")
+
+	want := []string{"run agency doctor", "move data_dir outside the repo"}
+	if got := Hints(outer); !reflect.DeepEqual(got, want) {
+		t.Errorf("Hints() = %q, want %q", got, want)
+	}
+	if got := Details(outer); got["data_dir"] != "/outer" {
+		t.Errorf("Details() = %v, want the outer data_dir", got)
+	}
+
+	// WithHints copies rather than mutating
+	base := New(EUsage, "bad")
+	_ = WithHints(base, "x")
+	if ae, _ := AsAgencyError(base); len(ae.Hints) != 0 {
+		t.Errorf("WithHints mutated its argument: %v", ae.Hints)
+	}
+	plain := fmt.Errorf("plain")
+	if WithHints(plain, "x") != plain {
+		t.Error("WithHints changed a non-AgencyError")
+	}
+}
+
+func TestRender(t *testing.T) {
+	err := WithHints(Wrap(EPersistFailed, "failed to write repo.json",
+		Wrap(EInternal, "atomic write failed", fmt.Errorf("open /d/repo.json: permission denied"))),
+		"check permissions on the data dir")
+
+	r := Render(err)
+	wantHuman := "error_code: E_PERSIST_FAILED\n" +
+		"failed to write repo.json\n" +
+		"cause: E_INTERNAL: atomic write failed\n" +
+		"cause: open /d/repo.json: permission denied\n" +
+		"hint: check permissions on the data dir\n"
+	if r.Human() != wantHuman {
+		t.Errorf("Human() = %q, want %q", r.Human(), wantHuman)
+	}
+	var buf bytes.Buffer
+	Print(&buf, err)
+	if buf.String() != wantHuman {
+		t.Errorf("Print() = %q, want Render().Human()", buf.String())
+	}
+
+	data, jsonErr := r.JSON()
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	var env JSONEnvelope
+	if jsonErr := json.Unmarshal(data, &env); jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if env.SchemaVersion != "1.0" || env.Error.Code != EPersistFailed || len(env.Error.Causes) != 2 || len(env.Error.Hints) != 1 {
+		t.Errorf("JSON envelope = %s", data)
+	}
+
+	if got := Render(fmt.Errorf("boom")).Human(); got != "boom\n" {
+		t.Errorf("non-AgencyError Human() = %q, want %q", got, "boom\n")
+	}
+}
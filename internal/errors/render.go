@@ -0,0 +1,133 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// WithHints returns err with hints appended to its outermost AgencyError
+// (a copy; err is not modified). Errors that are not AgencyErrors are
+// returned unchanged.
+func WithHints(err error, hints ...string) error {
+	var ae *AgencyError
+	if len(hints) == 0 || !errors.As(err, &ae) {
+		return err
+	}
+	cp := *ae
+	cp.Hints = append(append([]string(nil), ae.Hints...), hints...)
+	return &cp
+}
+
+// Hints returns the hints of every AgencyError in err's cause chain,
+// outermost first, without duplicates. Hints survive Wrap: a hint attached
+// deep in a call stack is still shown for the wrapping error.
+func Hints(err error) []string {
+	var hints []string
+	seen := make(map[string]bool)
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ae, ok := e.(*AgencyError); ok {
+			for _, h := range ae.Hints {
+				if !seen[h] {
+					seen[h] = true
+					hints = append(hints, h)
+				}
+			}
+		}
+	}
+	return hints
+}
+
+// Details returns the details of every AgencyError in err's cause chain
+// merged into one map; outer errors win on conflicting keys. Nil if none.
+func Details(err error) map[string]string {
+	var merged map[string]string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ae, ok := e.(*AgencyError); ok {
+			for k, v := range ae.Details {
+				if merged == nil {
+					merged = make(map[string]string)
+				}
+				if _, ok := merged[k]; !ok {
+					merged[k] = v
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// Rendered is an error prepared for output, as text (Human) or as the
+// JSON error envelope (JSON).
+type Rendered struct {
+	Code    Code              `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+	Hints   []string          `json:"hints,omitempty"`
+
+	// Causes are the wrapped errors below the outermost AgencyError,
+	// outermost first: "CODE: message" for AgencyErrors, else the error text
+	// (which ends the chain, since it already includes its own causes).
+	Causes []string `json:"causes,omitempty"`
+}
+
+// Render prepares err for output. Code, message, and causes come from the
+// first AgencyError in the chain; details and hints are collected from the
+// whole chain. An error with no AgencyError renders as its text alone.
+func Render(err error) Rendered {
+	var ae *AgencyError
+	if !errors.As(err, &ae) {
+		return Rendered{Message: err.Error()}
+	}
+	r := Rendered{
+		Code:    ae.Code,
+		Message: ae.Msg,
+		Details: Details(err),
+		Hints:   Hints(err),
+	}
+	for e := ae.Cause; e != nil; {
+		inner, ok := e.(*AgencyError)
+		if !ok {
+			r.Causes = append(r.Causes, e.Error())
+			break
+		}
+		r.Causes = append(r.Causes, inner.Error())
+		e = inner.Cause
+	}
+	return r
+}
+
+// Human returns the stable stderr text:
+//
+//	error_code: <CODE>
+//	<message>
+//	cause: <cause>
+//	hint: <hint>
+//
+// Cause and hint lines appear only when present.
+func (r Rendered) Human() string {
+	var b strings.Builder
+	if r.Code != "" {
+		b.WriteString("error_code: " + string(r.Code) + "\n")
+	}
+	b.WriteString(r.Message + "\n")
+	for _, c := range r.Causes {
+		b.WriteString("cause: " + c + "\n")
+	}
+	for _, h := range r.Hints {
+		b.WriteString("hint: " + h + "\n")
+	}
+	return b.String()
+}
+
+// JSONEnvelope is the stable JSON error format:
+// {"schema_version": "1.0", "error": {...}}.
+type JSONEnvelope struct {
+	SchemaVersion string    `json:"schema_version"`
+	Error         *Rendered `json:"error"`
+}
+
+// JSON returns the indented JSON error envelope.
+func (r Rendered) JSON() ([]byte, error) {
+	return json.MarshalIndent(JSONEnvelope{SchemaVersion: "1.0", Error: &r}, "", "  ")
+}
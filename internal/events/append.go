@@ -0,0 +1,74 @@
+// Package events provides the append-only per-run events.jsonl log.
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// SchemaVersion is the events.jsonl line schema version.
+const SchemaVersion = "1.0"
+
+// Event is a single line in <run_dir>/events.jsonl.
+type Event struct {
+	SchemaVersion string         `json:"schema_version"`
+	Timestamp     string         `json:"timestamp"`
+	RepoID        string         `json:"repo_id"`
+	RunID         string         `json:"run_id"`
+	Event         string         `json:"event"`
+	Data          map[string]any `json:"data,omitempty"`
+}
+
+// EventsPath returns the events.jsonl path for a run directory.
+func EventsPath(runDir string) string {
+	return filepath.Join(runDir, "events.jsonl")
+}
+
+// New builds an event stamped with the current schema version and time.
+func New(now time.Time, repoID, runID, event string, data map[string]any) Event {
+	return Event{
+		SchemaVersion: SchemaVersion,
+		Timestamp:     now.UTC().Format(time.RFC3339),
+		RepoID:        repoID,
+		RunID:         runID,
+		Event:         event,
+		Data:          data,
+	}
+}
+
+// AppendEvent appends e as a single JSON line to path, creating the file
+// lazily, and records it as the last event in the run's status.json.
+// Callers treat event emission as best-effort and typically ignore the error.
+func AppendEvent(path string, e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fs.ShareFile(f, filepath.Dir(path))
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	now, err := time.Parse(time.RFC3339, e.Timestamp)
+	if err != nil {
+		now = time.Now()
+	}
+	return UpdateStatus(filepath.Dir(path), e.RepoID, e.RunID, now, func(s *RunStatus) {
+		s.LastEvent = e.Event
+		s.LastEventAt = e.Timestamp
+	})
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendEvent_AppendsLines(t *testing.T) {
+	path := EventsPath(t.TempDir())
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	if err := AppendEvent(path, New(now, "repo1", "run1", "cmd_start", map[string]any{"cmd": "gc"})); err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+	if err := AppendEvent(path, New(now, "repo1", "run1", "cmd_end", nil)); err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("len(lines) = %d, want 2", len(lines))
+	}
+
+	var e Event
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatalf("invalid json line: %v", err)
+	}
+	if e.SchemaVersion != "1.0" || e.Event != "cmd_start" || e.Timestamp != "2026-01-10T12:00:00Z" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if e.Data["cmd"] != "gc" {
+		t.Errorf("data.cmd = %v, want gc", e.Data["cmd"])
+	}
+}
+
+func TestAppendEvent_MissingDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "events.jsonl")
+	if err := AppendEvent(path, New(time.Now(), "r", "r", "x", nil)); err == nil {
+		t.Error("expected error when run dir does not exist")
+	}
+}
+
+func TestAppendEvent_UpdatesStatus(t *testing.T) {
+	runDir := t.TempDir()
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	// Fields written by others (the derived status) are kept
+	if err := UpdateStatus(runDir, "repo1", "run1", now, func(s *RunStatus) { s.DerivedStatus = "active" }); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	later := now.Add(time.Minute)
+	if err := AppendEvent(EventsPath(runDir), New(later, "repo1", "run1", "checkpoint", nil)); err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+
+	data, err := os.ReadFile(StatusPath(runDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s RunStatus
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("invalid status.json: %v", err)
+	}
+	want := RunStatus{
+		SchemaVersion: StatusSchemaVersion,
+		RepoID:        "repo1",
+		RunID:         "run1",
+		DerivedStatus: "active",
+		LastEvent:     "checkpoint",
+		LastEventAt:   "2026-01-10T12:01:00Z",
+		UpdatedAt:     "2026-01-10T12:01:00Z",
+	}
+	if s != want {
+		t.Errorf("status.json = %+v, want %+v", s, want)
+	}
+	if _, err := os.Stat(filepath.Join(runDir, "status.json")); err != nil {
+		t.Errorf("status.json not in the run dir: %v", err)
+	}
+}
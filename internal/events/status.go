@@ -0,0 +1,67 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// StatusSchemaVersion is the schema_version of status.json.
+const StatusSchemaVersion = "1.0"
+
+// StatusFileName is the per-run progress file for external dashboards.
+const StatusFileName = "status.json"
+
+// RunStatus is <run_dir>/status.json: a small summary of a run that
+// dashboards can watch instead of invoking the CLI. It is a read-only
+// contract: agency replaces the file atomically, and fields are only added
+// within a schema_version major.
+type RunStatus struct {
+	SchemaVersion string `json:"schema_version"`
+	RepoID        string `json:"repo_id"`
+	RunID         string `json:"run_id"`
+
+	// DerivedStatus is the status as of UpdatedAt, derived as agency ls
+	// does ("" until a command has derived it).
+	DerivedStatus string `json:"derived_status"`
+
+	// Archived is true once the run's worktree is gone.
+	Archived bool `json:"archived"`
+
+	// LastEvent and LastEventAt are the latest events.jsonl entry.
+	LastEvent   string `json:"last_event,omitempty"`
+	LastEventAt string `json:"last_event_at,omitempty"`
+
+	CreatedAt  string `json:"created_at,omitempty"`
+	LastPushAt string `json:"last_push_at,omitempty"`
+	PRNumber   int    `json:"pr_number,omitempty"`
+
+	// UpdatedAt is when agency last wrote the file.
+	UpdatedAt string `json:"updated_at"`
+}
+
+// StatusPath returns the status.json path for a run directory.
+func StatusPath(runDir string) string {
+	return filepath.Join(runDir, StatusFileName)
+}
+
+// UpdateStatus applies update to the run's status.json (a zero RunStatus if
+// it is missing or unreadable) and replaces the file atomically. The run
+// directory must exist. Concurrent updates are last-writer-wins; every
+// writer rewrites the fields it knows, so the file converges.
+func UpdateStatus(runDir, repoID, runID string, now time.Time, update func(*RunStatus)) error {
+	path := StatusPath(runDir)
+	var s RunStatus
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &s)
+	}
+	update(&s)
+	s.SchemaVersion = StatusSchemaVersion
+	s.RepoID = repoID
+	s.RunID = runID
+	s.UpdatedAt = now.UTC().Format(time.RFC3339)
+	return fs.WriteJSONAtomic(path, s, 0o644)
+}
@@ -0,0 +1,301 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/redact"
+)
+
+// NetworkPolicy bounds the git and gh commands that talk to a remote
+// (see classifyCommand), which occasionally hang or fail transiently.
+type NetworkPolicy struct {
+	// Timeout limits each attempt (0 = no limit beyond ctx).
+	Timeout time.Duration
+
+	// Retries is how many times an idempotent command is run again after a
+	// transient failure: a timeout, a connection error, or an HTTP 5xx.
+	Retries int
+
+	// Backoff is the wait before the first retry; it doubles for each
+	// following one.
+	Backoff time.Duration
+}
+
+// DefaultNetworkPolicy returns the policy used unless the user config
+// overrides it: 2 minute attempts, 2 retries, 1s initial backoff.
+func DefaultNetworkPolicy() NetworkPolicy {
+	return NetworkPolicy{Timeout: 2 * time.Minute, Retries: 2, Backoff: time.Second}
+}
+
+// networkStderrMaxBytes caps the stderr tail kept in E_NETWORK_FAILED details.
+const networkStderrMaxBytes = 512
+
+var (
+	configMu      sync.Mutex
+	networkPolicy = DefaultNetworkPolicy()
+	debugLog      io.Writer
+)
+
+// Configure sets the NetworkPolicy and the debug log (nil = none) of the
+// RealRunners created afterwards. The cli calls it once at startup, from
+// the user config and AGENCY_DEBUG.
+func Configure(policy NetworkPolicy, debug io.Writer) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	networkPolicy = policy
+	debugLog = debug
+}
+
+// configured returns the policy and debug log set by Configure.
+func configured() (NetworkPolicy, io.Writer) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return networkPolicy, debugLog
+}
+
+// classifyCommand reports whether a command talks to a remote, and whether
+// it is idempotent, i.e. safe to run again after a failure:
+//   - git fetch and ls-remote (idempotent); push, pull, and clone
+//   - gh api: GETs and GraphQL queries are idempotent; anything sending
+//     fields or another method (POST by default with -f/-F) is not
+//   - gh auth status (idempotent), and gh subcommands that view or list
+//     (idempotent) or change something
+//
+// Other commands, including gh auth token and gh --version, are local.
+func classifyCommand(name string, args []string) (network, idempotent bool) {
+	switch name {
+	case "git":
+		switch gitSubcommand(args) {
+		case "fetch", "ls-remote":
+			return true, true
+		case "push", "pull", "clone":
+			return true, false
+		}
+	case "gh":
+		if len(args) == 0 {
+			return false, false
+		}
+		switch args[0] {
+		case "api":
+			return true, ghAPIIdempotent(args[1:])
+		case "auth":
+			return len(args) > 1 && args[1] == "status", true
+		case "pr", "issue", "repo", "run", "release", "workflow":
+			if len(args) > 1 {
+				switch args[1] {
+				case "view", "list", "status", "diff", "checks":
+					return true, true
+				}
+			}
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// gitSubcommand returns the git subcommand in args, skipping the global
+// options before it (-C <dir>, -c <key=value>, --git-dir=<dir>, ...).
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-C" || arg == "-c":
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			return arg
+		}
+	}
+	return ""
+}
+
+// ghAPIIdempotent reports whether `gh api <args>` only reads: a GraphQL
+// query (not a mutation), or a REST request without fields, input, or a
+// method other than GET.
+func ghAPIIdempotent(args []string) bool {
+	method := ""
+	hasFields := false
+	graphql := false
+	query := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-X" || arg == "--method":
+			if i+1 < len(args) {
+				method = strings.ToUpper(args[i+1])
+				i++
+			}
+		case strings.HasPrefix(arg, "--method="):
+			method = strings.ToUpper(strings.TrimPrefix(arg, "--method="))
+		case arg == "-f" || arg == "-F" || arg == "--field" || arg == "--raw-field" || arg == "--input":
+			hasFields = true
+			if i+1 < len(args) {
+				if v, ok := strings.CutPrefix(args[i+1], "query="); ok {
+					query = v
+				}
+				i++
+			}
+		case arg == "graphql":
+			graphql = true
+		}
+	}
+	if graphql {
+		return !strings.HasPrefix(strings.TrimSpace(query), "mutation")
+	}
+	return method == "GET" || (method == "" && !hasFields)
+}
+
+// transientPatterns are stderr fragments (lowercased) of failures worth
+// retrying: network trouble and GitHub server errors.
+var transientPatterns = []string{
+	"could not resolve host",
+	"connection timed out",
+	"connection reset",
+	"connection refused",
+	"operation timed out",
+	"i/o timeout",
+	"tls handshake timeout",
+	"temporary failure in name resolution",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"unexpected eof",
+	"http 502",
+	"http 503",
+	"http 504",
+	"502 bad gateway",
+	"503 service unavailable",
+	"504 gateway time",
+}
+
+// transientFailure returns why an attempt is worth retrying, or "" if it
+// succeeded or failed for good.
+func transientFailure(result CmdResult, err error, timedOut bool, timeout time.Duration) string {
+	if timedOut {
+		return "timed out after " + timeout.String()
+	}
+	if err != nil || result.ExitCode == 0 {
+		// Start failures (e.g. binary not found) do not get better
+		return ""
+	}
+	stderr := strings.ToLower(result.Stderr)
+	for _, p := range transientPatterns {
+		if strings.Contains(stderr, p) {
+			return "exit " + strconv.Itoa(result.ExitCode) + ": " + p
+		}
+	}
+	// gh api -i prints the status line on stdout
+	if status, _, _ := strings.Cut(result.Stdout, "\n"); strings.HasPrefix(status, "HTTP/") {
+		if fields := strings.Fields(status); len(fields) >= 2 && strings.HasPrefix(fields[1], "5") {
+			return "HTTP " + fields[1]
+		}
+	}
+	return ""
+}
+
+// runNetwork runs a network command under r's NetworkPolicy: each attempt
+// is limited to Timeout, and idempotent commands are retried up to Retries
+// times after transient failures, with exponential backoff. Every retry is
+// written to the debug log.
+//
+// A command that still times out, or that was retried and still fails
+// transiently, returns E_NETWORK_FAILED with the command, the number of
+// attempts, and the last failure in its details. Other results are
+// returned as runOnce returns them.
+func (r *RealRunner) runNetwork(ctx context.Context, name string, args []string, opts RunOpts, idempotent bool) (CmdResult, error) {
+	policy := r.Network
+	attempts := 1
+	if idempotent && policy.Retries > 0 {
+		attempts += policy.Retries
+	}
+	backoff := policy.Backoff
+	command := commandLabel(name, args)
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if policy.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		}
+		result, err := r.runOnce(attemptCtx, name, args, opts)
+		timedOut := ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded
+		cancel()
+		if ctx.Err() != nil {
+			return result, err
+		}
+
+		reason := transientFailure(result, err, timedOut, policy.Timeout)
+		if reason == "" {
+			return result, err
+		}
+		if attempt >= attempts {
+			if !timedOut && attempt == 1 {
+				// Not retried: the caller sees the failure as before
+				return result, err
+			}
+			return result, networkFailed(command, attempt, reason, result)
+		}
+
+		r.debugf("%s failed (%s); retrying in %s (attempt %d/%d)", command, reason, backoff, attempt+1, attempts)
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-r.after(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// networkFailed builds E_NETWORK_FAILED for a command that exhausted its
+// attempts.
+func networkFailed(command string, attempts int, reason string, last CmdResult) error {
+	details := map[string]string{
+		"command":      command,
+		"attempts":     strconv.Itoa(attempts),
+		"last_failure": reason,
+	}
+	if stderr := strings.TrimSpace(last.Stderr); stderr != "" {
+		if len(stderr) > networkStderrMaxBytes {
+			stderr = stderr[len(stderr)-networkStderrMaxBytes:]
+		}
+		details["stderr"], _ = redact.String(stderr)
+	}
+	return errors.WithHints(errors.NewWithDetails(errors.ENetworkFailed,
+		fmt.Sprintf("%s failed after %d attempt(s): %s", command, attempts, reason), details),
+		"check the network, or raise network.timeout_seconds / network.retries in the user config")
+}
+
+// commandLabel names a command for logs and errors without its arguments,
+// which may hold queries or URLs: "git fetch", "gh api".
+func commandLabel(name string, args []string) string {
+	if name == "git" {
+		if sub := gitSubcommand(args); sub != "" {
+			return "git " + sub
+		}
+		return name
+	}
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return name + " " + args[0]
+	}
+	return name
+}
+
+// debugf writes a line to the debug log, if any.
+func (r *RealRunner) debugf(format string, args ...any) {
+	if r.Debug != nil {
+		fmt.Fprintf(r.Debug, "debug: "+format+"\n", args...)
+	}
+}
+
+// after returns a channel that fires after d (r.sleep in tests).
+func (r *RealRunner) after(d time.Duration) <-chan time.Time {
+	if r.sleep != nil {
+		r.sleep(d)
+		d = 0
+	}
+	return time.After(d)
+}
@@ -0,0 +1,175 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+)
+
+func TestClassifyCommand(t *testing.T) {
+	tests := []struct {
+		name                string
+		args                []string
+		network, idempotent bool
+	}{
+		{"git", []string{"-C", "/repo", "fetch", "origin"}, true, true},
+		{"git", []string{"ls-remote", "origin"}, true, true},
+		{"git", []string{"-C", "/repo", "push", "origin", "main"}, true, false},
+		{"git", []string{"-C", "/repo", "status", "--porcelain"}, false, false},
+		{"gh", []string{"api", "-i", "repos/o/r/pulls/1"}, true, true},
+		{"gh", []string{"api", "graphql", "-f", "query=query { viewer { login } }"}, true, true},
+		{"gh", []string{"api", "graphql", "-f", "query=mutation { addStar }"}, true, false},
+		{"gh", []string{"api", "repos/o/r/issues", "-f", "title=x"}, true, false},
+		{"gh", []string{"api", "-X", "GET", "search/issues", "-f", "q=x"}, true, true},
+		{"gh", []string{"api", "--method=DELETE", "repos/o/r"}, true, false},
+		{"gh", []string{"auth", "status"}, true, true},
+		{"gh", []string{"auth", "token"}, false, true},
+		{"gh", []string{"pr", "view", "12"}, true, true},
+		{"gh", []string{"pr", "create"}, true, false},
+		{"gh", []string{"--version"}, false, false},
+		{"tmux", []string{"ls"}, false, false},
+	}
+	for _, tt := range tests {
+		network, idempotent := classifyCommand(tt.name, tt.args)
+		if network != tt.network || (network && idempotent != tt.idempotent) {
+			t.Errorf("classifyCommand(%s %v) = %t, %t; want %t, %t", tt.name, tt.args, network, idempotent, tt.network, tt.idempotent)
+		}
+	}
+}
+
+func TestTransientFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		result    CmdResult
+		timedOut  bool
+		transient bool
+	}{
+		{"success", CmdResult{}, false, false},
+		{"timeout", CmdResult{ExitCode: -1}, true, true},
+		{"dns", CmdResult{ExitCode: 128, Stderr: "fatal: unable to access: Could not resolve host: github.com"}, false, true},
+		{"hung up", CmdResult{ExitCode: 128, Stderr: "fatal: the remote end hung up unexpectedly"}, false, true},
+		{"gh 502", CmdResult{ExitCode: 1, Stdout: "HTTP/2.0 502 Bad Gateway\n\n"}, false, true},
+		{"gh 404", CmdResult{ExitCode: 1, Stdout: "HTTP/2.0 404 Not Found\n\n", Stderr: "gh: Not Found (HTTP 404)"}, false, false},
+		{"auth", CmdResult{ExitCode: 1, Stderr: "You are not logged into any GitHub hosts"}, false, false},
+	}
+	for _, tt := range tests {
+		got := transientFailure(tt.result, nil, tt.timedOut, time.Second) != ""
+		if got != tt.transient {
+			t.Errorf("%s: transient = %t, want %t", tt.name, got, tt.transient)
+		}
+	}
+}
+
+// fakeGh puts a gh script on PATH that fails with stderr for its first
+// failures calls (counted in a file), then prints "ok".
+func fakeGh(t *testing.T, failures int, stderr string) string {
+	t.Helper()
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "calls")
+	script := `#!/bin/sh
+n=$(cat "` + counter + `" 2>/dev/null || echo 0)
+n=$((n + 1))
+echo $n > "` + counter + `"
+if [ $n -le ` + strconv.Itoa(failures) + ` ]; then
+  echo "` + stderr + `" >&2
+  exit 1
+fi
+echo ok
+`
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return counter
+}
+
+func readCalls(t *testing.T, counter string) string {
+	t.Helper()
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestRealRunner_RetriesTransientFailures(t *testing.T) {
+	counter := fakeGh(t, 2, "dial tcp: i/o timeout")
+
+	var debug bytes.Buffer
+	var waits []time.Duration
+	r := &RealRunner{
+		Network: NetworkPolicy{Timeout: 10 * time.Second, Retries: 2, Backoff: time.Second},
+		Debug:   &debug,
+		sleep:   func(d time.Duration) { waits = append(waits, d) },
+	}
+	result, err := r.Run(context.Background(), "gh", []string{"api", "user"}, RunOpts{})
+	if err != nil || result.ExitCode != 0 || strings.TrimSpace(result.Stdout) != "ok" {
+		t.Fatalf("Run() = %+v, %v; want ok after retries", result, err)
+	}
+	if calls := readCalls(t, counter); calls != "3" {
+		t.Errorf("gh ran %s times, want 3", calls)
+	}
+	if len(waits) != 2 || waits[0] != time.Second || waits[1] != 2*time.Second {
+		t.Errorf("backoff waits = %v, want [1s 2s]", waits)
+	}
+	if !strings.Contains(debug.String(), "debug: gh api failed (exit 1: i/o timeout); retrying in 1s (attempt 2/3)") {
+		t.Errorf("debug log = %q", debug.String())
+	}
+}
+
+func TestRealRunner_RetriesExhausted(t *testing.T) {
+	counter := fakeGh(t, 9, "connection reset by peer")
+
+	r := &RealRunner{
+		Network: NetworkPolicy{Timeout: 10 * time.Second, Retries: 1, Backoff: time.Millisecond},
+		sleep:   func(time.Duration) {},
+	}
+	_, err := r.Run(context.Background(), "gh", []string{"api", "user"}, RunOpts{})
+	ae, ok := errors.AsAgencyError(err)
+	if !ok || ae.Code != errors.ENetworkFailed {
+		t.Fatalf("Run() error = %v, want E_NETWORK_FAILED", err)
+	}
+	if ae.Details["attempts"] != "2" || ae.Details["command"] != "gh api" || !strings.Contains(ae.Details["stderr"], "connection reset") {
+		t.Errorf("details = %v", ae.Details)
+	}
+	if calls := readCalls(t, counter); calls != "2" {
+		t.Errorf("gh ran %s times, want 2", calls)
+	}
+
+	// Not idempotent: run once, and the failure is returned as is
+	if err := os.Remove(counter); err != nil {
+		t.Fatal(err)
+	}
+	result, err := r.Run(context.Background(), "gh", []string{"pr", "create"}, RunOpts{})
+	if err != nil || result.ExitCode != 1 {
+		t.Errorf("Run(gh pr create) = %+v, %v; want exit 1 and no error", result, err)
+	}
+	if calls := readCalls(t, counter); calls != "1" {
+		t.Errorf("gh pr create ran %s times, want 1", calls)
+	}
+}
+
+func TestRealRunner_NetworkTimeout(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte("#!/bin/sh\nexec sleep 10\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	r := &RealRunner{Network: NetworkPolicy{Timeout: 50 * time.Millisecond}}
+	start := time.Now()
+	_, err := r.Run(context.Background(), "gh", []string{"auth", "status"}, RunOpts{})
+	if errors.GetCode(err) != errors.ENetworkFailed {
+		t.Fatalf("Run() error = %v, want E_NETWORK_FAILED", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %s, want the 50ms timeout", elapsed)
+	}
+}
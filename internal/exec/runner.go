@@ -5,9 +5,13 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/profile"
 )
 
 // CmdResult holds the result of a command execution.
@@ -19,8 +23,9 @@ type CmdResult struct {
 
 // RunOpts holds optional parameters for command execution.
 type RunOpts struct {
-	Dir string            // working directory (optional)
-	Env map[string]string // extra environment variables (overlay)
+	Dir   string            // working directory (optional)
+	Env   map[string]string // extra environment variables (overlay)
+	Stdin io.Reader         // standard input (optional; default none)
 }
 
 // CommandRunner is the interface for running external commands.
@@ -33,15 +38,37 @@ type CommandRunner interface {
 }
 
 // RealRunner is the production implementation of CommandRunner using os/exec.
-type RealRunner struct{}
+type RealRunner struct {
+	// Network bounds commands that talk to a remote (see runNetwork).
+	Network NetworkPolicy
+
+	// Debug receives a line for every retry (nil = none).
+	Debug io.Writer
 
-// NewRealRunner creates a new RealRunner.
+	// sleep replaces backoff waits in tests (nil = real waits).
+	sleep func(time.Duration)
+}
+
+// NewRealRunner creates a new RealRunner with the NetworkPolicy and debug
+// log set by Configure (DefaultNetworkPolicy and none if never called).
 func NewRealRunner() *RealRunner {
-	return &RealRunner{}
+	policy, debug := configured()
+	return &RealRunner{Network: policy, Debug: debug}
 }
 
-// Run executes the command and captures stdout/stderr.
+// Run executes the command and captures stdout/stderr. git and gh commands
+// that talk to a remote get r.Network's timeout and retries. Commands are
+// timed for --profile (see profile.Record).
 func (r *RealRunner) Run(ctx context.Context, name string, args []string, opts RunOpts) (CmdResult, error) {
+	defer profile.Track(profile.CommandKind(name), commandLabel(name, args))()
+	if network, idempotent := classifyCommand(name, args); network {
+		return r.runNetwork(ctx, name, args, opts, idempotent)
+	}
+	return r.runOnce(ctx, name, args, opts)
+}
+
+// runOnce executes the command once and captures stdout/stderr.
+func (r *RealRunner) runOnce(ctx context.Context, name string, args []string, opts RunOpts) (CmdResult, error) {
 	cmd := exec.CommandContext(ctx, name, args...)
 
 	var stdout, stderr bytes.Buffer
@@ -52,6 +79,10 @@ func (r *RealRunner) Run(ctx context.Context, name string, args []string, opts R
 		cmd.Dir = opts.Dir
 	}
 
+	if opts.Stdin != nil {
+		cmd.Stdin = opts.Stdin
+	}
+
 	if len(opts.Env) > 0 {
 		cmd.Env = cmd.Environ()
 		for k, v := range opts.Env {
@@ -103,6 +134,8 @@ const (
 // Stdout/stderr are captured in all cases.
 // Stdin is always /dev/null.
 func RunScript(ctx context.Context, name string, args []string, opts ScriptOpts) (CmdResult, error) {
+	defer profile.Track(profile.KindScript, filepath.Base(name))()
+
 	// Apply timeout if specified
 	if opts.Timeout > 0 {
 		var cancel context.CancelFunc
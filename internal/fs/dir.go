@@ -0,0 +1,192 @@
+package fs
+
+import (
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CopyDir recursively copies src to dst. Regular files and directories keep
+// their mode bits, symlinks are recreated (not followed), and other file
+// types are rejected. Directories are created owner-writable and only get
+// their source modes once the walk is done (deepest first), so read-only
+// source directories (e.g. a Go module cache) can still be filled.
+func (r *RealFS) CopyDir(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "copydir", Path: src, Err: fmt.Errorf("not a directory")}
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return &os.PathError{Op: "copydir", Path: dst, Err: os.ErrExist}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	type dirMode struct {
+		path string
+		perm os.FileMode
+	}
+	var dirs []dirMode // in walk order: parents before children
+
+	err = filepath.WalkDir(src, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			if err := os.Mkdir(target, 0o700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{target, mode.Perm()})
+			return nil
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case mode.IsRegular():
+			return copyFile(path, target, mode.Perm())
+		default:
+			return &os.PathError{Op: "copydir", Path: path, Err: fmt.Errorf("unsupported file type %s", mode.Type())}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Mkdir is subject to umask and the walk needs write access; apply the
+	// source permissions now, children before their parents.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies a single regular file, creating dst with perm.
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, perm)
+}
+
+// DirSize sums the sizes of regular files under path without following symlinks.
+func (r *RealFS) DirSize(path string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(path, func(_ string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// ReplaceDir swaps src into dst with two renames: an existing dst is first
+// renamed aside to its backup sibling (see replaceBackup), then src is
+// renamed into place, then the backup is removed. If the second rename fails
+// the original dst is restored. Removal of the backup is best-effort: dst is
+// already replaced by then.
+//
+// Between the two renames dst does not exist. If the process dies there, the
+// next ReplaceDir of dst (or RecoverReplacedDir) puts the backup back first.
+func (r *RealFS) ReplaceDir(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "replacedir", Path: src, Err: fmt.Errorf("not a directory")}
+	}
+	if err := RecoverReplacedDir(dst); err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(dst); os.IsNotExist(err) {
+		return os.Rename(src, dst)
+	} else if err != nil {
+		return err
+	}
+
+	backup := replaceBackup(dst)
+	if err := os.Rename(dst, backup); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		if rerr := os.Rename(backup, dst); rerr != nil {
+			return fmt.Errorf("replace %s: %w (restore failed: %v; original kept at %s)", dst, err, rerr, backup)
+		}
+		return err
+	}
+	_ = os.RemoveAll(backup)
+	return nil
+}
+
+// RecoverReplacedDir cleans up after a ReplaceDir of dst that was
+// interrupted. If dst is missing but its backup exists, the swap died
+// between its renames and the backup is renamed back to dst. If both exist,
+// the swap finished and the leftover backup is removed.
+func RecoverReplacedDir(dst string) error {
+	backup := replaceBackup(dst)
+	if _, err := os.Lstat(backup); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); os.IsNotExist(err) {
+		return os.Rename(backup, dst)
+	} else if err != nil {
+		return err
+	}
+	return os.RemoveAll(backup)
+}
+
+// replaceBackup is the hidden sibling ReplaceDir moves dst aside to. The
+// name is fixed so an interrupted swap can be found again.
+func replaceBackup(dst string) string {
+	dst = filepath.Clean(dst)
+	return filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".agency-replaced")
+}
@@ -0,0 +1,202 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, "sub", "deep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"a.txt":              "hello",
+		"sub/b.txt":          "world!",
+		"sub/deep/script.sh": "#!/bin/sh\n",
+	}
+	for rel, content := range files {
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(root, "sub/deep/script.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRealFS_CopyDir(t *testing.T) {
+	base := t.TempDir()
+	src := filepath.Join(base, "src")
+	dst := filepath.Join(base, "dst")
+	writeTree(t, src)
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("a.txt", filepath.Join(src, "link")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := NewRealFS()
+	if err := fs.CopyDir(src, dst); err != nil {
+		t.Fatalf("CopyDir failed: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dst, "sub", "b.txt"))
+	if err != nil || string(got) != "world!" {
+		t.Errorf("sub/b.txt = %q, %v", got, err)
+	}
+	info, err := os.Stat(filepath.Join(dst, "sub", "deep", "script.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Errorf("script mode = %o, want 755", info.Mode().Perm())
+	}
+	if runtime.GOOS != "windows" {
+		link, err := os.Readlink(filepath.Join(dst, "link"))
+		if err != nil || link != "a.txt" {
+			t.Errorf("link = %q, %v; want a.txt", link, err)
+		}
+	}
+
+	// Copying onto an existing destination is refused.
+	if err := fs.CopyDir(src, dst); !os.IsExist(err) {
+		t.Errorf("CopyDir onto existing dst: err = %v, want exist error", err)
+	}
+}
+
+func TestRealFS_CopyDir_ReadOnlySource(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+	base := t.TempDir()
+	src := filepath.Join(base, "src")
+	dst := filepath.Join(base, "dst")
+	writeTree(t, src)
+	for _, dir := range []string{"sub/deep", "sub", "."} {
+		if err := os.Chmod(filepath.Join(src, dir), 0o555); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		// Let t.TempDir remove both trees
+		for _, root := range []string{src, dst} {
+			for _, dir := range []string{".", "sub", "sub/deep"} {
+				_ = os.Chmod(filepath.Join(root, dir), 0o755)
+			}
+		}
+	})
+
+	if err := NewRealFS().CopyDir(src, dst); err != nil {
+		t.Fatalf("CopyDir failed: %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dst, "sub", "deep", "script.sh")); err != nil || string(got) != "#!/bin/sh\n" {
+		t.Errorf("sub/deep/script.sh = %q, %v", got, err)
+	}
+	for _, dir := range []string{".", "sub", "sub/deep"} {
+		info, err := os.Stat(filepath.Join(dst, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o555 {
+			t.Errorf("%s mode = %o, want 555", dir, info.Mode().Perm())
+		}
+	}
+}
+
+func TestRealFS_DirSize(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root)
+
+	size, err := NewRealFS().DirSize(root)
+	if err != nil {
+		t.Fatalf("DirSize failed: %v", err)
+	}
+	if want := int64(len("hello") + len("world!") + len("#!/bin/sh\n")); size != want {
+		t.Errorf("DirSize = %d, want %d", size, want)
+	}
+
+	if _, err := NewRealFS().DirSize(filepath.Join(root, "missing")); !os.IsNotExist(err) {
+		t.Errorf("DirSize(missing): err = %v, want not-exist", err)
+	}
+}
+
+func TestRealFS_ReplaceDir(t *testing.T) {
+	base := t.TempDir()
+	dst := filepath.Join(base, "current")
+	next := filepath.Join(base, "next")
+	writeTree(t, dst)
+	if err := os.MkdirAll(next, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(next, "new.txt"), []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewRealFS()
+	if err := fs.ReplaceDir(next, dst); err != nil {
+		t.Fatalf("ReplaceDir failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dst, "a.txt")); !os.IsNotExist(err) {
+		t.Error("old contents should be gone")
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "new.txt")); string(got) != "new" {
+		t.Errorf("new.txt = %q, want new", got)
+	}
+	if _, err := os.Stat(next); !os.IsNotExist(err) {
+		t.Error("source dir should be consumed")
+	}
+	entries, _ := os.ReadDir(base)
+	if len(entries) != 1 {
+		t.Errorf("expected only the replaced dir in %s, got %d entries", base, len(entries))
+	}
+
+	// Replacing a missing destination is a plain rename.
+	fresh := filepath.Join(base, "fresh")
+	if err := os.MkdirAll(fresh, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(base, "target")
+	if err := fs.ReplaceDir(fresh, target); err != nil {
+		t.Fatalf("ReplaceDir to missing dst failed: %v", err)
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		t.Errorf("target should be a directory: %v", err)
+	}
+}
+
+func TestRealFS_ReplaceDir_RecoversInterruptedSwap(t *testing.T) {
+	base := t.TempDir()
+	dst := filepath.Join(base, "current")
+	writeTree(t, dst)
+
+	// Simulate a crash between the two renames: dst is only at its backup
+	if err := os.Rename(dst, replaceBackup(dst)); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecoverReplacedDir(dst); err != nil {
+		t.Fatalf("RecoverReplacedDir failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "a.txt")); err != nil {
+		t.Errorf("dst not restored from backup: %v", err)
+	}
+
+	// A backup left next to a replaced dst is stale and removed
+	if err := os.MkdirAll(replaceBackup(dst), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	next := filepath.Join(base, "next")
+	if err := os.MkdirAll(next, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRealFS().ReplaceDir(next, dst); err != nil {
+		t.Fatalf("ReplaceDir failed: %v", err)
+	}
+	entries, _ := os.ReadDir(base)
+	if len(entries) != 1 {
+		t.Errorf("expected only the replaced dir in %s, got %d entries", base, len(entries))
+	}
+}
@@ -20,6 +20,18 @@ type FS interface {
 	// CreateTemp creates a temp file and returns the path and a WriteCloser.
 	// The caller is responsible for closing the writer and removing the file.
 	CreateTemp(dir, pattern string) (path string, w io.WriteCloser, err error)
+
+	// CopyDir recursively copies src to dst, preserving file modes and
+	// symlinks. dst must not exist; its parent must exist.
+	CopyDir(src, dst string) error
+	// DirSize returns the total size in bytes of regular files under path.
+	// Symlinks are not followed.
+	DirSize(path string) (int64, error)
+	// ReplaceDir replaces dst with the directory src (which is consumed).
+	// src should be on the same filesystem as dst, typically a sibling. If
+	// dst does not exist, src is simply renamed into place. The swap is two
+	// renames, not one atomic step; see RealFS.ReplaceDir for recovery.
+	ReplaceDir(src, dst string) error
 }
 
 // RealFS is the production implementation of FS using the os package.
@@ -0,0 +1,404 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an in-memory FS for tests. Paths are cleaned before use and the
+// root ("/" or ".") always exists. Symlinks are not modelled.
+// MemFS is safe for concurrent use.
+type MemFS struct {
+	mu      sync.Mutex
+	files   map[string]*memFile
+	dirs    map[string]os.FileMode
+	tempSeq int
+	now     func() time.Time
+}
+
+type memFile struct {
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{
+		files: make(map[string]*memFile),
+		dirs:  map[string]os.FileMode{"/": 0o755, ".": 0o755},
+		now:   time.Now,
+	}
+}
+
+// Paths returns all file and directory paths, sorted. Intended for assertions.
+func (m *MemFS) Paths() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []string
+	for p := range m.files {
+		out = append(out, p)
+	}
+	for p := range m.dirs {
+		if p != "/" && p != "." {
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mkdirAll(filepath.Clean(path), perm)
+}
+
+func (m *MemFS) mkdirAll(path string, perm os.FileMode) error {
+	if _, ok := m.dirs[path]; ok {
+		return nil
+	}
+	if _, ok := m.files[path]; ok {
+		return &os.PathError{Op: "mkdir", Path: path, Err: fmt.Errorf("not a directory")}
+	}
+	if parent := filepath.Dir(path); parent != path {
+		if err := m.mkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+	m.dirs[path] = perm.Perm()
+	return nil
+}
+
+func (m *MemFS) ReadFile(path string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	f, ok := m.files[path]
+	if !ok {
+		if _, isDir := m.dirs[path]; isDir {
+			return nil, &os.PathError{Op: "read", Path: path, Err: fmt.Errorf("is a directory")}
+		}
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return bytes.Clone(f.data), nil
+}
+
+func (m *MemFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	if err := m.checkParent("open", path); err != nil {
+		return err
+	}
+	if _, isDir := m.dirs[path]; isDir {
+		return &os.PathError{Op: "open", Path: path, Err: fmt.Errorf("is a directory")}
+	}
+	if f, ok := m.files[path]; ok {
+		f.data = bytes.Clone(data)
+		f.modTime = m.now()
+		return nil
+	}
+	m.files[path] = &memFile{data: bytes.Clone(data), mode: perm.Perm(), modTime: m.now()}
+	return nil
+}
+
+func (m *MemFS) Stat(path string) (iofs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	if f, ok := m.files[path]; ok {
+		return memFileInfo{name: filepath.Base(path), size: int64(len(f.data)), mode: f.mode, modTime: f.modTime}, nil
+	}
+	if perm, ok := m.dirs[path]; ok {
+		return memFileInfo{name: filepath.Base(path), mode: os.ModeDir | perm}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+}
+
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	if err := m.checkParent("rename", newpath); err != nil {
+		return err
+	}
+	if f, ok := m.files[oldpath]; ok {
+		if _, isDir := m.dirs[newpath]; isDir {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fmt.Errorf("is a directory")}
+		}
+		delete(m.files, oldpath)
+		m.files[newpath] = f
+		return nil
+	}
+	if _, ok := m.dirs[oldpath]; !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	if _, ok := m.files[newpath]; ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fmt.Errorf("not a directory")}
+	}
+	if _, ok := m.dirs[newpath]; ok {
+		if len(m.children(newpath)) > 0 {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrExist}
+		}
+		delete(m.dirs, newpath)
+	}
+	m.moveTree(oldpath, newpath)
+	return nil
+}
+
+func (m *MemFS) Remove(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	if _, ok := m.files[path]; ok {
+		delete(m.files, path)
+		return nil
+	}
+	if _, ok := m.dirs[path]; ok {
+		if len(m.children(path)) > 0 {
+			return &os.PathError{Op: "remove", Path: path, Err: fmt.Errorf("directory not empty")}
+		}
+		delete(m.dirs, path)
+		return nil
+	}
+	return &os.PathError{Op: "remove", Path: path, Err: os.ErrNotExist}
+}
+
+func (m *MemFS) Chmod(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	if f, ok := m.files[path]; ok {
+		f.mode = perm.Perm()
+		return nil
+	}
+	if _, ok := m.dirs[path]; ok {
+		m.dirs[path] = perm.Perm()
+		return nil
+	}
+	return &os.PathError{Op: "chmod", Path: path, Err: os.ErrNotExist}
+}
+
+func (m *MemFS) CreateTemp(dir, pattern string) (string, io.WriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tempSeq++
+	name := pattern + strconv.Itoa(m.tempSeq)
+	if i := strings.LastIndex(pattern, "*"); i >= 0 {
+		name = pattern[:i] + strconv.Itoa(m.tempSeq) + pattern[i+1:]
+	}
+	path := filepath.Join(filepath.Clean(dir), name)
+	if err := m.checkParent("createtemp", path); err != nil {
+		return "", nil, err
+	}
+	m.files[path] = &memFile{mode: 0o600, modTime: m.now()}
+	return path, &memWriter{fs: m, path: path}, nil
+}
+
+func (m *MemFS) CopyDir(src, dst string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	src, dst = filepath.Clean(src), filepath.Clean(dst)
+	if _, ok := m.dirs[src]; !ok {
+		return &os.PathError{Op: "copydir", Path: src, Err: os.ErrNotExist}
+	}
+	if m.exists(dst) {
+		return &os.PathError{Op: "copydir", Path: dst, Err: os.ErrExist}
+	}
+	if err := m.checkParent("copydir", dst); err != nil {
+		return err
+	}
+	for p, perm := range m.dirs {
+		if rel, ok := relUnder(src, p); ok {
+			m.dirs[filepath.Join(dst, rel)] = perm
+		}
+	}
+	for p, f := range m.files {
+		if rel, ok := relUnder(src, p); ok {
+			m.files[filepath.Join(dst, rel)] = &memFile{data: bytes.Clone(f.data), mode: f.mode, modTime: f.modTime}
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) DirSize(path string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	if f, ok := m.files[path]; ok {
+		return int64(len(f.data)), nil
+	}
+	if _, ok := m.dirs[path]; !ok {
+		return 0, &os.PathError{Op: "lstat", Path: path, Err: os.ErrNotExist}
+	}
+	var total int64
+	for p, f := range m.files {
+		if _, ok := relUnder(path, p); ok {
+			total += int64(len(f.data))
+		}
+	}
+	return total, nil
+}
+
+func (m *MemFS) ReplaceDir(src, dst string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	src, dst = filepath.Clean(src), filepath.Clean(dst)
+	if _, ok := m.dirs[src]; !ok {
+		return &os.PathError{Op: "replacedir", Path: src, Err: os.ErrNotExist}
+	}
+	if _, inside := relUnder(dst, src); inside {
+		return &os.LinkError{Op: "replacedir", Old: src, New: dst, Err: fmt.Errorf("source is inside destination")}
+	}
+	if err := m.checkParent("replacedir", dst); err != nil {
+		return err
+	}
+	m.removeTree(dst)
+	m.moveTree(src, dst)
+	return nil
+}
+
+// exists reports whether path is a file or directory. Caller holds mu.
+func (m *MemFS) exists(path string) bool {
+	_, isFile := m.files[path]
+	_, isDir := m.dirs[path]
+	return isFile || isDir
+}
+
+// checkParent returns ErrNotExist unless path's parent is a directory. Caller holds mu.
+func (m *MemFS) checkParent(op, path string) error {
+	if _, ok := m.dirs[filepath.Dir(path)]; !ok {
+		return &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	return nil
+}
+
+// children returns the paths strictly under dir. Caller holds mu.
+func (m *MemFS) children(dir string) []string {
+	var out []string
+	for p := range m.files {
+		if rel, ok := relUnder(dir, p); ok && rel != "." {
+			out = append(out, p)
+		}
+	}
+	for p := range m.dirs {
+		if rel, ok := relUnder(dir, p); ok && rel != "." {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// moveTree renames the tree at oldpath (inclusive) to newpath. Caller holds mu.
+func (m *MemFS) moveTree(oldpath, newpath string) {
+	dirs := make(map[string]os.FileMode)
+	for p, perm := range m.dirs {
+		if rel, ok := relUnder(oldpath, p); ok {
+			delete(m.dirs, p)
+			dirs[filepath.Join(newpath, rel)] = perm
+		}
+	}
+	files := make(map[string]*memFile)
+	for p, f := range m.files {
+		if rel, ok := relUnder(oldpath, p); ok {
+			delete(m.files, p)
+			files[filepath.Join(newpath, rel)] = f
+		}
+	}
+	for p, perm := range dirs {
+		m.dirs[p] = perm
+	}
+	for p, f := range files {
+		m.files[p] = f
+	}
+}
+
+// removeTree deletes path and everything under it. Caller holds mu.
+func (m *MemFS) removeTree(path string) {
+	for p := range m.dirs {
+		if _, ok := relUnder(path, p); ok {
+			delete(m.dirs, p)
+		}
+	}
+	for p := range m.files {
+		if _, ok := relUnder(path, p); ok {
+			delete(m.files, p)
+		}
+	}
+}
+
+// relUnder returns p relative to root if p is root or inside it.
+func relUnder(root, p string) (string, bool) {
+	if p == root {
+		return ".", true
+	}
+	prefix := root
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	if !strings.HasPrefix(p, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(p, prefix), true
+}
+
+// memWriter appends to a MemFS file; data is visible immediately.
+type memWriter struct {
+	fs     *MemFS
+	path   string
+	closed bool
+}
+
+func (w *memWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	w.fs.mu.Lock()
+	defer w.fs.mu.Unlock()
+	f, ok := w.fs.files[w.path]
+	if !ok {
+		return 0, &os.PathError{Op: "write", Path: w.path, Err: os.ErrNotExist}
+	}
+	f.data = append(f.data, p...)
+	f.modTime = w.fs.now()
+	return len(p), nil
+}
+
+func (w *memWriter) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+	return nil
+}
+
+// memFileInfo implements iofs.FileInfo for MemFS entries.
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() os.FileMode  { return i.mode }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memFileInfo) Sys() any           { return nil }
+
+// Compile-time interface checks.
+var (
+	_ FS = (*RealFS)(nil)
+	_ FS = (*MemFS)(nil)
+)
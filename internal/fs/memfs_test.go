@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMemFS_ReadWriteStat(t *testing.T) {
+	m := NewMemFS()
+
+	if err := m.WriteFile("/a/b.txt", []byte("x"), 0o644); !os.IsNotExist(err) {
+		t.Errorf("WriteFile without parent: err = %v, want not-exist", err)
+	}
+	if err := m.MkdirAll("/a", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteFile("/a/b.txt", []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.ReadFile("/a/b.txt")
+	if err != nil || string(got) != "hello" {
+		t.Errorf("ReadFile = %q, %v", got, err)
+	}
+	info, err := m.Stat("/a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 5 || info.Mode().Perm() != 0o600 || info.IsDir() {
+		t.Errorf("Stat = size %d mode %v dir %v", info.Size(), info.Mode(), info.IsDir())
+	}
+	if info, err := m.Stat("/a"); err != nil || !info.IsDir() {
+		t.Errorf("Stat(/a) should be a dir: %v", err)
+	}
+	if _, err := m.Stat("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Stat(missing): err = %v, want not-exist", err)
+	}
+	if err := m.Remove("/a"); err == nil {
+		t.Error("Remove of non-empty dir should fail")
+	}
+}
+
+func TestMemFS_WriteFileAtomic(t *testing.T) {
+	m := NewMemFS()
+	if err := m.MkdirAll("/data", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(m, "/data/meta.json", []byte(`{"v":1}`), 0o644); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+	want := []string{"/data", "/data/meta.json"}
+	if got := m.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths = %v, want %v (temp file leaked?)", got, want)
+	}
+	if info, _ := m.Stat("/data/meta.json"); info.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+}
+
+func TestMemFS_DirOperations(t *testing.T) {
+	m := NewMemFS()
+	for _, dir := range []string{"/src/sub", "/old/keep"} {
+		if err := m.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = m.WriteFile("/src/a", []byte("abc"), 0o644)
+	_ = m.WriteFile("/src/sub/b", []byte("de"), 0o755)
+	_ = m.WriteFile("/old/keep/c", []byte("zzzz"), 0o644)
+
+	if size, err := m.DirSize("/src"); err != nil || size != 5 {
+		t.Errorf("DirSize(/src) = %d, %v; want 5", size, err)
+	}
+
+	if err := m.CopyDir("/src", "/copy"); err != nil {
+		t.Fatalf("CopyDir failed: %v", err)
+	}
+	if got, _ := m.ReadFile("/copy/sub/b"); string(got) != "de" {
+		t.Errorf("/copy/sub/b = %q", got)
+	}
+	if err := m.CopyDir("/src", "/copy"); !os.IsExist(err) {
+		t.Errorf("CopyDir onto existing dst: err = %v, want exist error", err)
+	}
+
+	// Copies are independent of the source.
+	_ = m.WriteFile("/src/a", []byte("changed"), 0o644)
+	if got, _ := m.ReadFile("/copy/a"); string(got) != "abc" {
+		t.Errorf("/copy/a = %q, want abc", got)
+	}
+
+	if err := m.ReplaceDir("/copy", "/old"); err != nil {
+		t.Fatalf("ReplaceDir failed: %v", err)
+	}
+	want := []string{"/old", "/old/a", "/old/sub", "/old/sub/b", "/src", "/src/a", "/src/sub", "/src/sub/b"}
+	if got := m.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths after ReplaceDir = %v, want %v", got, want)
+	}
+	if err := m.ReplaceDir("/old/sub", "/old"); err == nil {
+		t.Error("ReplaceDir with src inside dst should fail")
+	}
+}
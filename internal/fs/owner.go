@@ -0,0 +1,41 @@
+package fs
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// OwnerOf returns the uid owning the file described by info, or false if the
+// platform does not report one.
+func OwnerOf(info os.FileInfo) (int, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(st.Uid), true
+}
+
+// ExistingOwner returns the uid owning path or, if path does not exist yet,
+// its nearest existing ancestor (whose owner is who path would belong to if
+// created by that user). Returns false if no owner is known.
+func ExistingOwner(path string) (int, bool) {
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil {
+			return OwnerOf(info)
+		}
+		if dir == filepath.Dir(dir) {
+			return 0, false
+		}
+	}
+}
+
+// UserName returns the login name of uid, or "uid <n>" if it is unknown.
+func UserName(uid int) string {
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "uid " + strconv.Itoa(uid)
+}
@@ -0,0 +1,50 @@
+package fs
+
+import (
+	"os"
+)
+
+// SharedDirMode is the mode of dirs agency creates in a shared data dir:
+// group read/write/search, and setgid so entries inherit the dir's group.
+const SharedDirMode = os.ModeSetgid | 0o770
+
+// IsShared reports whether dataDir is set up to be used by several users,
+// which agency takes to be the case when the data dir is group-writable
+// (e.g. chgrp team + chmod 2770 on a network volume).
+func IsShared(dataDir string) bool {
+	info, err := os.Stat(dataDir)
+	return err == nil && info.IsDir() && info.Mode().Perm()&0o020 != 0
+}
+
+// ShareDir gives dir SharedDirMode if dataDir is shared, so other users of
+// the data dir can create and replace files in it. Dirs are created 0700 or
+// 0755 (and filtered by the umask), which would otherwise lock them out.
+// Only the dir's owner can change its mode; for anyone else this fails.
+func ShareDir(dataDir, dir string) error {
+	if !IsShared(dataDir) {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&SharedDirMode == SharedDirMode {
+		return nil
+	}
+	return os.Chmod(dir, SharedDirMode)
+}
+
+// ShareFile adds group write to an existing file in a group-writable dir,
+// for files other users append to (events.jsonl). Best-effort: a file owned
+// by someone else is left alone.
+func ShareFile(f *os.File, dir string) {
+	dirInfo, err := os.Stat(dir)
+	if err != nil || dirInfo.Mode().Perm()&0o020 == 0 {
+		return
+	}
+	info, err := f.Stat()
+	if err != nil || info.Mode().Perm()&0o020 != 0 {
+		return
+	}
+	_ = f.Chmod(info.Mode().Perm() | 0o060)
+}
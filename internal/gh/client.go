@@ -0,0 +1,198 @@
+// Package gh wraps the gh CLI for GitHub API access with on-disk response
+// caching and batched queries, so operations across many runs stay within
+// GitHub's rate limits.
+package gh
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// Client issues GitHub API requests through `gh api`.
+// REST GETs are cached under <cacheDir>/gh and revalidated with If-None-Match;
+// GitHub does not count 304 Not Modified responses against the rate limit.
+type Client struct {
+	cr       exec.CommandRunner
+	fsys     fs.FS
+	cacheDir string
+	now      func() time.Time
+}
+
+// NewClient returns a Client caching responses under cacheDir/gh
+// (normally paths.Dirs.CacheDir). An empty cacheDir disables caching.
+func NewClient(cr exec.CommandRunner, fsys fs.FS, cacheDir string) *Client {
+	c := &Client{cr: cr, fsys: fsys, now: time.Now}
+	if cacheDir != "" {
+		c.cacheDir = filepath.Join(cacheDir, "gh")
+	}
+	return c
+}
+
+// Response is the result of a REST GET.
+type Response struct {
+	Body []byte
+
+	// Cached is true if Body came from the on-disk cache (304 or rate limited).
+	Cached bool
+
+	// Stale is true if Body is a cached copy served because the rate limit
+	// was exhausted; it may be out of date.
+	Stale bool
+
+	// RateLimitRemaining is X-RateLimit-Remaining from the response (-1 if absent).
+	RateLimitRemaining int
+}
+
+// cacheEntry is the on-disk form of a cached REST response.
+type cacheEntry struct {
+	Path      string          `json:"path"`
+	ETag      string          `json:"etag"`
+	FetchedAt string          `json:"fetched_at"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// Get fetches a REST API path (e.g. "repos/owner/repo/pulls/12") via
+// `gh api -i`, sending the cached ETag as If-None-Match.
+//
+// When the rate limit is exhausted, a cached copy is returned with Stale set.
+// Returns E_GH_RATE_LIMITED if rate limited with nothing cached, or
+// E_GH_API_FAILED for other failures.
+func (c *Client) Get(ctx context.Context, path string) (Response, error) {
+	cached := c.readCache(path)
+
+	args := []string{"api", "-i"}
+	if cached != nil && cached.ETag != "" {
+		args = append(args, "-H", "If-None-Match: "+cached.ETag)
+	}
+	args = append(args, path)
+
+	result, err := c.cr.Run(ctx, "gh", args, exec.RunOpts{})
+	if err != nil {
+		return Response{}, errors.WrapWithDetails(errors.EGhAPIFailed, "failed to run gh api", err, map[string]string{"path": path})
+	}
+
+	status, headers, body := parseHTTPResponse(result.Stdout)
+	remaining := -1
+	if v, err := strconv.Atoi(headers["x-ratelimit-remaining"]); err == nil {
+		remaining = v
+	}
+
+	switch {
+	case status == 304 && cached != nil:
+		return Response{Body: cached.Body, Cached: true, RateLimitRemaining: remaining}, nil
+
+	case status >= 200 && status < 300 && result.ExitCode == 0:
+		c.writeCache(path, headers["etag"], body)
+		return Response{Body: body, RateLimitRemaining: remaining}, nil
+
+	case isRateLimited(status, headers, result.Stderr):
+		if cached != nil {
+			return Response{Body: cached.Body, Cached: true, Stale: true, RateLimitRemaining: 0}, nil
+		}
+		return Response{}, rateLimitError(path, headers)
+	}
+
+	msg := strings.TrimSpace(result.Stderr)
+	if msg == "" {
+		msg = "HTTP " + strconv.Itoa(status)
+	}
+	return Response{}, errors.NewWithDetails(errors.EGhAPIFailed, "gh api "+path+" failed: "+msg,
+		map[string]string{"path": path, "status": strconv.Itoa(status)})
+}
+
+// parseHTTPResponse splits `gh api -i` output into status code, lowercased
+// headers, and body. A missing or malformed status line yields status 0.
+func parseHTTPResponse(out string) (int, map[string]string, []byte) {
+	out = strings.ReplaceAll(out, "\r\n", "\n")
+	head, body, _ := strings.Cut(out, "\n\n")
+
+	lines := strings.Split(head, "\n")
+	status := 0
+	if fields := strings.Fields(lines[0]); len(fields) >= 2 && strings.HasPrefix(fields[0], "HTTP/") {
+		status, _ = strconv.Atoi(fields[1])
+	}
+
+	headers := make(map[string]string)
+	for _, line := range lines[1:] {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			headers[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+		}
+	}
+	return status, headers, []byte(body)
+}
+
+// isRateLimited reports whether a response is GitHub's primary or secondary rate limit.
+func isRateLimited(status int, headers map[string]string, stderr string) bool {
+	if status == 429 {
+		return true
+	}
+	if status == 403 && headers["x-ratelimit-remaining"] == "0" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(stderr), "rate limit")
+}
+
+// rateLimitError builds E_GH_RATE_LIMITED, including the reset time when known.
+func rateLimitError(path string, headers map[string]string) error {
+	details := map[string]string{"path": path}
+	msg := "GitHub API rate limit exceeded"
+	if reset, err := strconv.ParseInt(headers["x-ratelimit-reset"], 10, 64); err == nil {
+		resetAt := time.Unix(reset, 0).UTC().Format(time.RFC3339)
+		details["reset_at"] = resetAt
+		msg += "; resets at " + resetAt
+	}
+	return errors.NewWithDetails(errors.EGhRateLimited, msg, details)
+}
+
+// cachePath returns the cache file for an API path.
+func (c *Client) cachePath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(c.cacheDir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// readCache returns the cached entry for path, or nil if absent or unreadable.
+func (c *Client) readCache(path string) *cacheEntry {
+	if c.cacheDir == "" {
+		return nil
+	}
+	data, err := c.fsys.ReadFile(c.cachePath(path))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Path != path {
+		return nil
+	}
+	return &entry
+}
+
+// writeCache stores a response body (best-effort; caching never fails a request).
+// Responses without an ETag or with a non-JSON body are not cached.
+func (c *Client) writeCache(path, etag string, body []byte) {
+	if c.cacheDir == "" || etag == "" || !json.Valid(body) {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{
+		Path:      path,
+		ETag:      etag,
+		FetchedAt: c.now().UTC().Format(time.RFC3339),
+		Body:      body,
+	})
+	if err != nil {
+		return
+	}
+	if err := c.fsys.MkdirAll(c.cacheDir, 0o700); err != nil {
+		return
+	}
+	_ = fs.WriteFileAtomic(c.fsys, c.cachePath(path), data, 0o600)
+}
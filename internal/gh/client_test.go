@@ -0,0 +1,89 @@
+package gh
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+const prPath = "repos/acme/widgets/pulls/12"
+
+func httpOut(status, headers, body string) string {
+	return "HTTP/2.0 " + status + "\r\n" + headers + "\r\n" + body
+}
+
+func TestGet_CachesAndRevalidatesWithETag(t *testing.T) {
+	f := testkit.NewFakeRunner()
+	f.On("gh", "api", "-i", prPath).
+		Stdout(httpOut("200 OK", "Etag: \"abc\"\r\nX-Ratelimit-Remaining: 4999\r\n", `{"state":"open"}`))
+	f.On("gh", "api", "-i", "-H", `If-None-Match: "abc"`, prPath).
+		Stdout(httpOut("304 Not Modified", "Etag: \"abc\"\r\n", "")).Exit(1)
+
+	c := NewClient(f, fs.NewRealFS(), t.TempDir())
+
+	resp, err := c.Get(context.Background(), prPath)
+	if err != nil {
+		t.Fatalf("first Get() error = %v", err)
+	}
+	if resp.Cached || string(resp.Body) != `{"state":"open"}` || resp.RateLimitRemaining != 4999 {
+		t.Errorf("first Get() = %+v", resp)
+	}
+
+	resp, err = c.Get(context.Background(), prPath)
+	if err != nil {
+		t.Fatalf("second Get() error = %v", err)
+	}
+	if !resp.Cached || resp.Stale || string(resp.Body) != `{"state":"open"}` {
+		t.Errorf("second Get() = %+v, want cached body", resp)
+	}
+}
+
+func TestGet_RateLimited(t *testing.T) {
+	limited := httpOut("403 Forbidden", "X-Ratelimit-Remaining: 0\r\nX-Ratelimit-Reset: 1767225600\r\n", `{"message":"API rate limit exceeded"}`)
+
+	t.Run("no cache", func(t *testing.T) {
+		f := testkit.NewFakeRunner()
+		f.OnPrefix("gh", "api").Stdout(limited).Exit(1)
+
+		_, err := NewClient(f, fs.NewRealFS(), t.TempDir()).Get(context.Background(), prPath)
+		if errors.GetCode(err) != errors.EGhRateLimited {
+			t.Fatalf("code = %q, want %q", errors.GetCode(err), errors.EGhRateLimited)
+		}
+		if !strings.Contains(err.Error(), "2026-01-01T00:00:00Z") {
+			t.Errorf("error should include reset time: %v", err)
+		}
+	})
+
+	t.Run("stale cache", func(t *testing.T) {
+		f := testkit.NewFakeRunner()
+		f.OnPrefix("gh", "api").Stdout(httpOut("200 OK", "ETag: W/\"v1\"\r\n", `{"state":"open"}`))
+		f.OnPrefix("gh", "api").Stdout(limited).Exit(1)
+
+		c := NewClient(f, fs.NewRealFS(), t.TempDir())
+		if _, err := c.Get(context.Background(), prPath); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := c.Get(context.Background(), prPath)
+		if err != nil {
+			t.Fatalf("Get() error = %v, want stale cache", err)
+		}
+		if !resp.Stale || string(resp.Body) != `{"state":"open"}` {
+			t.Errorf("Get() = %+v, want stale cached body", resp)
+		}
+	})
+}
+
+func TestGet_Failure(t *testing.T) {
+	f := testkit.NewFakeRunner()
+	f.OnPrefix("gh", "api").Stdout(httpOut("404 Not Found", "", `{"message":"Not Found"}`)).
+		Stderr("gh: Not Found (HTTP 404)").Exit(1)
+
+	_, err := NewClient(f, fs.NewRealFS(), "").Get(context.Background(), prPath)
+	if errors.GetCode(err) != errors.EGhAPIFailed {
+		t.Fatalf("code = %q, want %q", errors.GetCode(err), errors.EGhAPIFailed)
+	}
+}
@@ -0,0 +1,151 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+)
+
+// MaxPRsPerQuery caps the pull requests fetched in one GraphQL request,
+// keeping each query well under GitHub's node limit.
+const MaxPRsPerQuery = 100
+
+// PRRef identifies a pull request.
+type PRRef struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// PRState is the subset of pull request fields agency tracks.
+type PRState struct {
+	Number      int    `json:"number"`
+	State       string `json:"state"` // OPEN, CLOSED, or MERGED
+	IsDraft     bool   `json:"isDraft"`
+	MergedAt    string `json:"mergedAt"`
+	URL         string `json:"url"`
+	HeadRefName string `json:"headRefName"`
+}
+
+const prStateFragment = "fragment pr on PullRequest { number state isDraft mergedAt url headRefName }"
+
+// PRStates fetches the state of many pull requests, possibly across repos,
+// with one GraphQL request per MaxPRsPerQuery PRs instead of one call per run.
+// PRs that do not exist (or are not visible) are absent from the result.
+//
+// Returns E_GH_RATE_LIMITED if GitHub reports the rate limit exhausted, or
+// E_GH_API_FAILED for other failures.
+func (c *Client) PRStates(ctx context.Context, refs []PRRef) (map[PRRef]PRState, error) {
+	refs = sortedUniqueRefs(refs)
+	states := make(map[PRRef]PRState, len(refs))
+
+	for start := 0; start < len(refs); start += MaxPRsPerQuery {
+		end := start + MaxPRsPerQuery
+		if end > len(refs) {
+			end = len(refs)
+		}
+		if err := c.fetchPRStates(ctx, refs[start:end], states); err != nil {
+			return nil, err
+		}
+	}
+	return states, nil
+}
+
+// fetchPRStates runs one GraphQL query for batch and adds the results to states.
+// Each repo is aliased r<i> and each PR p<number> within it.
+func (c *Client) fetchPRStates(ctx context.Context, batch []PRRef, states map[PRRef]PRState) error {
+	type repoKey struct{ owner, repo string }
+	var repos []repoKey
+	byRepo := make(map[repoKey][]int)
+	for _, ref := range batch {
+		k := repoKey{ref.Owner, ref.Repo}
+		if _, ok := byRepo[k]; !ok {
+			repos = append(repos, k)
+		}
+		byRepo[k] = append(byRepo[k], ref.Number)
+	}
+
+	var q strings.Builder
+	q.WriteString("query {")
+	for i, k := range repos {
+		q.WriteString(" r" + strconv.Itoa(i) + ": repository(owner: " + strconv.Quote(k.owner) + ", name: " + strconv.Quote(k.repo) + ") {")
+		for _, n := range byRepo[k] {
+			num := strconv.Itoa(n)
+			q.WriteString(" p" + num + ": pullRequest(number: " + num + ") { ...pr }")
+		}
+		q.WriteString(" }")
+	}
+	q.WriteString(" } " + prStateFragment)
+
+	result, err := c.cr.Run(ctx, "gh", []string{"api", "graphql", "-f", "query=" + q.String()}, exec.RunOpts{})
+	if err != nil {
+		return errors.Wrap(errors.EGhAPIFailed, "failed to run gh api graphql", err)
+	}
+
+	var resp struct {
+		Data   map[string]map[string]*PRState `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if jsonErr := json.Unmarshal([]byte(result.Stdout), &resp); jsonErr != nil {
+		if isRateLimited(0, nil, result.Stderr) {
+			return rateLimitError("graphql", nil)
+		}
+		msg := strings.TrimSpace(result.Stderr)
+		if msg == "" {
+			msg = jsonErr.Error()
+		}
+		return errors.New(errors.EGhAPIFailed, "gh api graphql failed: "+msg)
+	}
+	for _, e := range resp.Errors {
+		if e.Type == "RATE_LIMITED" {
+			return rateLimitError("graphql", nil)
+		}
+		// NOT_FOUND errors accompany null nodes for missing repos/PRs; skip them
+		if e.Type != "NOT_FOUND" {
+			return errors.New(errors.EGhAPIFailed, "gh api graphql failed: "+e.Message)
+		}
+	}
+
+	for i, k := range repos {
+		prs := resp.Data["r"+strconv.Itoa(i)]
+		for _, n := range byRepo[k] {
+			if pr := prs["p"+strconv.Itoa(n)]; pr != nil {
+				states[PRRef{Owner: k.owner, Repo: k.repo, Number: n}] = *pr
+			}
+		}
+	}
+	return nil
+}
+
+// sortedUniqueRefs returns refs deduplicated and sorted by owner, repo, number,
+// so queries (and batch boundaries) are deterministic.
+func sortedUniqueRefs(refs []PRRef) []PRRef {
+	seen := make(map[PRRef]bool, len(refs))
+	out := make([]PRRef, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Number <= 0 || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		out = append(out, ref)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Owner != b.Owner {
+			return a.Owner < b.Owner
+		}
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		return a.Number < b.Number
+	})
+	return out
+}
@@ -0,0 +1,79 @@
+package gh
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestPRStates_BatchesAcrossRepos(t *testing.T) {
+	f := testkit.NewFakeRunner()
+	f.OnPrefix("gh", "api", "graphql").Stdout(`{"data": {
+		"r0": {"p3": {"number": 3, "state": "MERGED", "mergedAt": "2026-01-02T00:00:00Z"}},
+		"r1": {"p7": {"number": 7, "state": "OPEN", "isDraft": true}, "p9": null}
+	}, "errors": [{"type": "NOT_FOUND", "message": "Could not resolve to a PullRequest with the number of 9."}]}`)
+
+	refs := []PRRef{
+		{Owner: "acme", Repo: "widgets", Number: 7},
+		{Owner: "acme", Repo: "gadgets", Number: 3},
+		{Owner: "acme", Repo: "widgets", Number: 9},
+		{Owner: "acme", Repo: "widgets", Number: 7},
+	}
+	states, err := NewClient(f, nil, "").PRStates(context.Background(), refs)
+	if err != nil {
+		t.Fatalf("PRStates() error = %v", err)
+	}
+
+	calls := f.CallsTo("gh")
+	if len(calls) != 1 {
+		t.Fatalf("gh called %d times, want 1", len(calls))
+	}
+	query := calls[0].Args[len(calls[0].Args)-1]
+	for _, want := range []string{
+		`r0: repository(owner: "acme", name: "gadgets") { p3: pullRequest(number: 3)`,
+		`r1: repository(owner: "acme", name: "widgets") { p7: pullRequest(number: 7) { ...pr } p9:`,
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query missing %q:\n%s", want, query)
+		}
+	}
+
+	if len(states) != 2 {
+		t.Fatalf("len(states) = %d, want 2: %+v", len(states), states)
+	}
+	if s := states[PRRef{"acme", "gadgets", 3}]; s.State != "MERGED" {
+		t.Errorf("gadgets#3 state = %q, want MERGED", s.State)
+	}
+	if s := states[PRRef{"acme", "widgets", 7}]; s.State != "OPEN" || !s.IsDraft {
+		t.Errorf("widgets#7 = %+v, want open draft", s)
+	}
+}
+
+func TestPRStates_ChunksLargeBatches(t *testing.T) {
+	f := testkit.NewFakeRunner()
+	f.OnPrefix("gh", "api", "graphql").Stdout(`{"data": {}}`)
+
+	var refs []PRRef
+	for n := 1; n <= MaxPRsPerQuery+1; n++ {
+		refs = append(refs, PRRef{Owner: "acme", Repo: "widgets", Number: n})
+	}
+	if _, err := NewClient(f, nil, "").PRStates(context.Background(), refs); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.CallsTo("gh")); n != 2 {
+		t.Errorf("gh called %d times, want 2", n)
+	}
+}
+
+func TestPRStates_RateLimited(t *testing.T) {
+	f := testkit.NewFakeRunner()
+	f.OnPrefix("gh", "api", "graphql").Stdout(`{"errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]}`).Exit(1)
+
+	_, err := NewClient(f, nil, "").PRStates(context.Background(), []PRRef{{"acme", "widgets", 1}})
+	if errors.GetCode(err) != errors.EGhRateLimited {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EGhRateLimited)
+	}
+}
@@ -4,6 +4,7 @@ package git
 import (
 	"context"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/NielsdaWheelz/agency/internal/errors"
@@ -237,6 +238,23 @@ func BranchExists(ctx context.Context, cr exec.CommandRunner, repoRoot, branch s
 	return result.ExitCode == 0, nil
 }
 
+// HooksDir returns the absolute directory git runs hooks from for the repo,
+// honoring core.hooksPath. Uses `git rev-parse --git-path hooks`.
+func HooksDir(ctx context.Context, cr exec.CommandRunner, repoRoot string) (string, error) {
+	result, err := cr.Run(ctx, "git", []string{"rev-parse", "--git-path", "hooks"}, exec.RunOpts{Dir: repoRoot})
+	if err != nil {
+		return "", errors.Wrap(errors.EInternal, "failed to run git rev-parse --git-path hooks", err)
+	}
+	dir := strings.TrimSpace(result.Stdout)
+	if result.ExitCode != 0 || dir == "" {
+		return "", errors.New(errors.EInternal, "git rev-parse --git-path hooks failed: "+strings.TrimSpace(result.Stderr))
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoRoot, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
 // GetOriginURL retrieves the origin remote URL using `git remote get-url origin`.
 // Returns the URL if origin exists, or empty string if missing.
 // Never returns an error; failures result in empty string.
@@ -250,3 +268,160 @@ func GetOriginURL(ctx context.Context, cr exec.CommandRunner, repoRoot string) s
 	}
 	return strings.TrimSpace(result.Stdout)
 }
+
+// Clone clones url into dir (which must not exist or be empty).
+// Uses `git clone -- <url> <dir>` via CommandRunner.
+// Returns E_CLONE_FAILED with git's stderr if the clone fails.
+func Clone(ctx context.Context, cr exec.CommandRunner, url, dir string) error {
+	result, err := cr.Run(ctx, "git", []string{"clone", "--", url, dir}, exec.RunOpts{Dir: filepath.Dir(dir)})
+	if err != nil {
+		return errors.Wrap(errors.ECloneFailed, "failed to run git clone", err)
+	}
+	if result.ExitCode != 0 {
+		return errors.NewWithDetails(errors.ECloneFailed, "git clone failed: "+strings.TrimSpace(result.Stderr),
+			map[string]string{"url": url, "dir": dir})
+	}
+	return nil
+}
+
+// CountCommitsSince returns the number of commits reachable from ref but not from sha.
+// Uses `git rev-list --count <sha>..<ref>` via CommandRunner.
+//
+// Returns (n, nil) on success.
+// Returns (0, error) if the command fails to run, exits non-zero (e.g. unknown sha),
+// or produces unparseable output.
+func CountCommitsSince(ctx context.Context, cr exec.CommandRunner, dir, sha, ref string) (int, error) {
+	result, err := cr.Run(ctx, "git", []string{"rev-list", "--count", sha + ".." + ref}, exec.RunOpts{Dir: dir})
+	if err != nil {
+		return 0, errors.Wrap(errors.EInternal, "failed to run git rev-list --count", err)
+	}
+	if result.ExitCode != 0 {
+		return 0, errors.New(errors.EInternal, "git rev-list --count failed: "+strings.TrimSpace(result.Stderr))
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(result.Stdout))
+	if err != nil {
+		return 0, errors.Wrap(errors.EInternal, "unexpected git rev-list --count output", err)
+	}
+	return n, nil
+}
+
+// AheadBehind returns how many commits head has that base lacks (ahead) and
+// how many base has that head lacks (behind).
+// Uses `git rev-list --left-right --count <base>...<head>` via CommandRunner.
+func AheadBehind(ctx context.Context, cr exec.CommandRunner, dir, base, head string) (ahead, behind int, err error) {
+	result, err := cr.Run(ctx, "git", []string{"rev-list", "--left-right", "--count", base + "..." + head}, exec.RunOpts{Dir: dir})
+	if err != nil {
+		return 0, 0, errors.Wrap(errors.EInternal, "failed to run git rev-list --left-right --count", err)
+	}
+	if result.ExitCode != 0 {
+		return 0, 0, errors.New(errors.EInternal, "git rev-list --left-right --count failed: "+strings.TrimSpace(result.Stderr))
+	}
+
+	fields := strings.Fields(result.Stdout)
+	if len(fields) != 2 {
+		return 0, 0, errors.New(errors.EInternal, "unexpected git rev-list --left-right --count output: "+strings.TrimSpace(result.Stdout))
+	}
+	behind, err1 := strconv.Atoi(fields[0])
+	ahead, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil {
+		return 0, 0, errors.New(errors.EInternal, "unexpected git rev-list --left-right --count output: "+strings.TrimSpace(result.Stdout))
+	}
+	return ahead, behind, nil
+}
+
+// DiffStat is the size of a diff, as summarized by git diff --shortstat.
+type DiffStat struct {
+	FilesChanged int `json:"files_changed"`
+	Insertions   int `json:"insertions"`
+	Deletions    int `json:"deletions"`
+}
+
+// DiffShortStat returns the size of the changes head made since it forked
+// from base (the merge base), ignoring later commits on base.
+// Uses `git diff --shortstat <base>...<head>` via CommandRunner.
+func DiffShortStat(ctx context.Context, cr exec.CommandRunner, dir, base, head string) (DiffStat, error) {
+	result, err := cr.Run(ctx, "git", []string{"diff", "--shortstat", base + "..." + head}, exec.RunOpts{Dir: dir})
+	if err != nil {
+		return DiffStat{}, errors.Wrap(errors.EInternal, "failed to run git diff --shortstat", err)
+	}
+	if result.ExitCode != 0 {
+		return DiffStat{}, errors.New(errors.EInternal, "git diff --shortstat failed: "+strings.TrimSpace(result.Stderr))
+	}
+
+	// " 3 files changed, 10 insertions(+), 2 deletions(-)"; parts with a
+	// zero count are left out, and an empty diff prints nothing
+	var stat DiffStat
+	for _, part := range strings.Split(strings.TrimSpace(result.Stdout), ",") {
+		fields := strings.Fields(part)
+		if len(fields) < 2 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return DiffStat{}, errors.New(errors.EInternal, "unexpected git diff --shortstat output: "+strings.TrimSpace(result.Stdout))
+		}
+		switch {
+		case strings.HasPrefix(fields[1], "file"):
+			stat.FilesChanged = n
+		case strings.HasPrefix(fields[1], "insertion"):
+			stat.Insertions = n
+		case strings.HasPrefix(fields[1], "deletion"):
+			stat.Deletions = n
+		}
+	}
+	return stat, nil
+}
+
+// BranchHeads returns the commit SHA of every local branch, keyed by branch
+// name without refs/heads/. Uses `git for-each-ref refs/heads` via CommandRunner.
+func BranchHeads(ctx context.Context, cr exec.CommandRunner, dir string) (map[string]string, error) {
+	result, err := cr.Run(ctx, "git", []string{"for-each-ref", "--format=%(objectname) %(refname)", "refs/heads"}, exec.RunOpts{Dir: dir})
+	if err != nil {
+		return nil, errors.Wrap(errors.EInternal, "failed to run git for-each-ref", err)
+	}
+	if result.ExitCode != 0 {
+		return nil, errors.New(errors.EInternal, "git for-each-ref failed: "+strings.TrimSpace(result.Stderr))
+	}
+
+	heads := make(map[string]string)
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		sha, ref, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok {
+			heads[strings.TrimPrefix(ref, "refs/heads/")] = sha
+		}
+	}
+	return heads, nil
+}
+
+// Worktree is one entry of `git worktree list --porcelain`.
+type Worktree struct {
+	// Path is the absolute worktree path.
+	Path string
+
+	// Branch is the checked-out branch without refs/heads/ (empty if detached).
+	Branch string
+}
+
+// ListWorktrees returns all worktrees of the repo, the main worktree first.
+// Uses `git worktree list --porcelain` via CommandRunner.
+func ListWorktrees(ctx context.Context, cr exec.CommandRunner, repoRoot string) ([]Worktree, error) {
+	result, err := cr.Run(ctx, "git", []string{"worktree", "list", "--porcelain"}, exec.RunOpts{Dir: repoRoot})
+	if err != nil {
+		return nil, errors.Wrap(errors.EInternal, "failed to run git worktree list", err)
+	}
+	if result.ExitCode != 0 {
+		return nil, errors.New(errors.EInternal, "git worktree list failed: "+strings.TrimSpace(result.Stderr))
+	}
+
+	var worktrees []Worktree
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			worktrees = append(worktrees, Worktree{Path: strings.TrimPrefix(line, "worktree ")})
+		case strings.HasPrefix(line, "branch ") && len(worktrees) > 0:
+			worktrees[len(worktrees)-1].Branch = strings.TrimPrefix(strings.TrimPrefix(line, "branch "), "refs/heads/")
+		}
+	}
+	return worktrees, nil
+}
@@ -3,6 +3,7 @@ package git
 import (
 	"context"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/NielsdaWheelz/agency/internal/errors"
@@ -544,3 +545,155 @@ func TestGetOriginURL_Missing(t *testing.T) {
 		t.Errorf("GetOriginURL = %q, want empty for missing origin", url)
 	}
 }
+
+func TestCountCommitsSince(t *testing.T) {
+	ctx := context.Background()
+	cr := newStubRunner()
+	dir := "/worktree"
+
+	cr.On("git", []string{"rev-list", "--count", "abc1234..HEAD"}, dir, exec.CmdResult{
+		Stdout:   "3\n",
+		ExitCode: 0,
+	})
+
+	n, err := CountCommitsSince(ctx, cr, dir, "abc1234", "HEAD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("n = %d, want 3", n)
+	}
+}
+
+func TestCountCommitsSince_UnknownSHA(t *testing.T) {
+	ctx := context.Background()
+	cr := newStubRunner()
+
+	cr.On("git", []string{"rev-list", "--count", "deadbee..HEAD"}, "/worktree", exec.CmdResult{
+		Stderr:   "fatal: bad revision 'deadbee..HEAD'",
+		ExitCode: 128,
+	})
+
+	if _, err := CountCommitsSince(ctx, cr, "/worktree", "deadbee", "HEAD"); err == nil {
+		t.Fatal("expected error for unknown sha")
+	}
+}
+
+func TestAheadBehind(t *testing.T) {
+	ctx := context.Background()
+	cr := newStubRunner()
+
+	cr.On("git", []string{"rev-list", "--left-right", "--count", "1111...2222"}, "/worktree", exec.CmdResult{
+		Stdout: "1\t4\n",
+	})
+
+	ahead, behind, err := AheadBehind(ctx, cr, "/worktree", "1111", "2222")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ahead != 4 || behind != 1 {
+		t.Errorf("AheadBehind() = %d, %d; want 4, 1", ahead, behind)
+	}
+}
+
+func TestDiffShortStat(t *testing.T) {
+	ctx := context.Background()
+	cr := newStubRunner()
+
+	cr.On("git", []string{"diff", "--shortstat", "main...agency/a"}, "/worktree", exec.CmdResult{
+		Stdout: " 3 files changed, 10 insertions(+), 2 deletions(-)\n",
+	})
+	cr.On("git", []string{"diff", "--shortstat", "main...agency/b"}, "/worktree", exec.CmdResult{
+		Stdout: " 1 file changed, 1 deletion(-)\n",
+	})
+	cr.On("git", []string{"diff", "--shortstat", "main...agency/c"}, "/worktree", exec.CmdResult{})
+
+	tests := []struct {
+		head string
+		want DiffStat
+	}{
+		{"agency/a", DiffStat{FilesChanged: 3, Insertions: 10, Deletions: 2}},
+		{"agency/b", DiffStat{FilesChanged: 1, Deletions: 1}},
+		{"agency/c", DiffStat{}},
+	}
+	for _, tt := range tests {
+		got, err := DiffShortStat(ctx, cr, "/worktree", "main", tt.head)
+		if err != nil {
+			t.Fatalf("DiffShortStat(%s) error = %v", tt.head, err)
+		}
+		if got != tt.want {
+			t.Errorf("DiffShortStat(%s) = %+v, want %+v", tt.head, got, tt.want)
+		}
+	}
+
+	cr.On("git", []string{"diff", "--shortstat", "main...gone"}, "/worktree", exec.CmdResult{ExitCode: 128, Stderr: "fatal: bad revision"})
+	if _, err := DiffShortStat(ctx, cr, "/worktree", "main", "gone"); err == nil {
+		t.Error("expected error for unknown branch")
+	}
+}
+
+func TestBranchHeads(t *testing.T) {
+	ctx := context.Background()
+	cr := newStubRunner()
+
+	cr.On("git", []string{"for-each-ref", "--format=%(objectname) %(refname)", "refs/heads"}, "/repo", exec.CmdResult{
+		Stdout: "1111 refs/heads/main\n2222 refs/heads/agency/fix-login-a3f2\n",
+	})
+
+	heads, err := BranchHeads(ctx, cr, "/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(heads) != 2 || heads["main"] != "1111" || heads["agency/fix-login-a3f2"] != "2222" {
+		t.Errorf("BranchHeads() = %v", heads)
+	}
+}
+
+func TestListWorktrees(t *testing.T) {
+	ctx := context.Background()
+	cr := newStubRunner()
+
+	cr.On("git", []string{"worktree", "list", "--porcelain"}, "/repo", exec.CmdResult{
+		Stdout: "worktree /repo\nHEAD 1111\nbranch refs/heads/main\n\n" +
+			"worktree /wt/feature\nHEAD 2222\nbranch refs/heads/feature/x\n\n" +
+			"worktree /wt/detached\nHEAD 3333\ndetached\n\n",
+	})
+
+	got, err := ListWorktrees(ctx, cr, "/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Worktree{
+		{Path: "/repo", Branch: "main"},
+		{Path: "/wt/feature", Branch: "feature/x"},
+		{Path: "/wt/detached"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d worktrees, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("worktree %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	ctx := context.Background()
+	cr := newStubRunner()
+
+	cr.On("git", []string{"clone", "--", "git@github.com:owner/repo.git", "/work/repo"}, "/work", exec.CmdResult{})
+	if err := Clone(ctx, cr, "git@github.com:owner/repo.git", "/work/repo"); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+
+	cr.On("git", []string{"clone", "--", "git@github.com:owner/gone.git", "/work/gone"}, "/work", exec.CmdResult{
+		ExitCode: 128,
+		Stderr:   "ERROR: Repository not found.\nfatal: Could not read from remote repository.\n",
+	})
+	err := Clone(ctx, cr, "git@github.com:owner/gone.git", "/work/gone")
+	ae, ok := errors.AsAgencyError(err)
+	if !ok || ae.Code != errors.ECloneFailed || !strings.Contains(ae.Msg, "Repository not found") || ae.Details["dir"] != "/work/gone" {
+		t.Errorf("Clone() error = %v, want E_CLONE_FAILED with git's stderr", err)
+	}
+}
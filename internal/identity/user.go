@@ -0,0 +1,31 @@
+package identity
+
+import (
+	"os"
+	"os/user"
+)
+
+// CurrentUser returns the name agency records as the author of runs and
+// repo locks: $AGENCY_USER if set (for shared accounts), else the OS login
+// name, else $USER / $USERNAME. Returns "" if none is known.
+func CurrentUser() string {
+	if name := os.Getenv("AGENCY_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// Hostname returns the machine's host name, or "" if it is unknown.
+func Hostname() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return host
+}
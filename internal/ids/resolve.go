@@ -106,6 +106,34 @@ func ResolveRunRef(input string, refs []RunRef) (RunRef, error) {
 	}
 }
 
+// ResolveRunRefPreferRepo resolves like ResolveRunRef, but when the input is
+// ambiguous it prefers candidates from repoID (typically the repo containing
+// cwd). If exactly one candidate belongs to repoID it wins; if several do,
+// ErrAmbiguous lists only those. With repoID "" or no candidate in repoID the
+// result is the same as ResolveRunRef.
+func ResolveRunRefPreferRepo(input string, refs []RunRef, repoID string) (RunRef, error) {
+	ref, err := ResolveRunRef(input, refs)
+	amb, ok := err.(*ErrAmbiguous)
+	if !ok || repoID == "" {
+		return ref, err
+	}
+
+	var inRepo []RunRef
+	for _, c := range amb.Candidates {
+		if c.RepoID == repoID {
+			inRepo = append(inRepo, c)
+		}
+	}
+	switch len(inRepo) {
+	case 0:
+		return RunRef{}, err
+	case 1:
+		return inRepo[0], nil
+	default:
+		return RunRef{}, &ErrAmbiguous{Input: amb.Input, Candidates: inRepo}
+	}
+}
+
 // sortCandidates sorts candidates deterministically:
 // by RunID ascending, then by RepoID ascending.
 func sortCandidates(refs []RunRef) {
@@ -261,3 +261,43 @@ func TestSortCandidates(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveRunRefPreferRepo(t *testing.T) {
+	refs := []RunRef{
+		{RepoID: "r1", RunID: "20260110-a3f2"},
+		{RepoID: "r2", RunID: "20260110-a3aa"},
+		{RepoID: "r2", RunID: "20260110-b111"},
+		{RepoID: "r2", RunID: "20260110-b222"},
+		{RepoID: "r3", RunID: "20260110-c000"},
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		repoID    string
+		wantRunID string
+		wantCands int // > 0 expects *ErrAmbiguous with this many candidates
+	}{
+		{"current repo breaks tie", "20260110-a3", "r1", "20260110-a3f2", 0},
+		{"other repo breaks tie", "20260110-a3", "r2", "20260110-a3aa", 0},
+		{"no repo stays ambiguous", "20260110-a3", "", "", 2},
+		{"repo without candidates stays ambiguous", "20260110-a3", "r3", "", 2},
+		{"ambiguous within repo lists only repo candidates", "20260110-", "r2", "", 3},
+		{"unique match ignores repo", "20260110-c", "r1", "20260110-c000", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveRunRefPreferRepo(tt.input, refs, tt.repoID)
+			if tt.wantCands > 0 {
+				var amb *ErrAmbiguous
+				if !errors.As(err, &amb) || len(amb.Candidates) != tt.wantCands {
+					t.Fatalf("err = %v, want ErrAmbiguous with %d candidates", err, tt.wantCands)
+				}
+				return
+			}
+			if err != nil || got.RunID != tt.wantRunID {
+				t.Fatalf("got %+v, %v; want %s", got, err, tt.wantRunID)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package ids
+
+import (
+	"sort"
+	"strings"
+)
+
+// MinShortIDLen is the shortest display id; 8 characters of a generated
+// run_id are its date (yyyymmdd).
+const MinShortIDLen = 8
+
+// ShortIDs returns a display id for each of runIDs: the shortest prefix of at
+// least MinShortIDLen characters that no other run id starts with, like git's
+// abbreviated SHAs. ResolveRunRef resolves each short id to its run as long as
+// it is given the same set of runs.
+//
+// Run ids that are a prefix of another run id, or that occur in several repos,
+// have no unique prefix and are displayed in full. A prefix never ends in '-'
+// or '_'; it is extended by one character instead.
+func ShortIDs(runIDs []string) map[string]string {
+	sorted := append([]string(nil), runIDs...)
+	sort.Strings(sorted)
+
+	short := make(map[string]string, len(sorted))
+	for i, id := range sorted {
+		// In sorted order the longest shared prefix is with a neighbor
+		n := 0
+		if i > 0 {
+			n = max(n, commonPrefixLen(id, sorted[i-1]))
+		}
+		if i+1 < len(sorted) {
+			n = max(n, commonPrefixLen(id, sorted[i+1]))
+		}
+
+		n = max(n+1, MinShortIDLen)
+		for n < len(id) && strings.ContainsRune("-_", rune(id[n-1])) {
+			n++
+		}
+		if n >= len(id) {
+			short[id] = id
+			continue
+		}
+		short[id] = id[:n]
+	}
+	return short
+}
+
+// commonPrefixLen returns the length of the longest common prefix of a and b.
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
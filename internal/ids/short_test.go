@@ -0,0 +1,53 @@
+package ids
+
+import "testing"
+
+func TestShortIDs(t *testing.T) {
+	runIDs := []string{
+		"20260110120000-a3f2",
+		"20260110120000-a3ff",
+		"20260110123000-b4c1",
+		"20260111090000-c5d2",
+		"20260215080000-d6e3",
+		"20260215080000-d6e3", // same run_id in a second repo
+		"fix",
+		"fix-auth",
+	}
+	want := map[string]string{
+		"20260110120000-a3f2": "20260110120000-a3f2",
+		"20260110120000-a3ff": "20260110120000-a3ff",
+		"20260110123000-b4c1": "20260110123",
+		"20260111090000-c5d2": "20260111",
+		"20260215080000-d6e3": "20260215080000-d6e3",
+		"fix":                 "fix",
+		"fix-auth":            "fix-auth",
+	}
+
+	got := ShortIDs(runIDs)
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("ShortIDs()[%q] = %q, want %q", id, got[id], w)
+		}
+	}
+
+	// Prefixes never end in a separator
+	if got := ShortIDs([]string{"20260110-a3f2", "20260110-b4c1"}); got["20260110-a3f2"] != "20260110-a" {
+		t.Errorf("separator: got %q, want %q", got["20260110-a3f2"], "20260110-a")
+	}
+
+	// Every short id resolves to its own run
+	refs := make([]RunRef, len(runIDs))
+	for i, id := range runIDs {
+		refs[i] = RunRef{RepoID: "r1", RunID: id}
+	}
+	refs[5].RepoID = "r2"
+	for id, s := range got {
+		if id == "20260215080000-d6e3" {
+			continue // ambiguous across repos by design
+		}
+		ref, err := ResolveRunRef(s, refs)
+		if err != nil || ref.RunID != id {
+			t.Errorf("ResolveRunRef(%q) = %v, %v; want %q", s, ref.RunID, err, id)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package lock
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,9 @@ import (
 	"path/filepath"
 	"syscall"
 	"time"
+
+	agencyfs "github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/identity"
 )
 
 // LockInfo contains the metadata stored in a lock file.
@@ -16,6 +20,12 @@ type LockInfo struct {
 	PID       int       `json:"pid"`
 	CreatedAt time.Time `json:"created_at"`
 	Cmd       string    `json:"cmd,omitempty"`
+
+	// User and Host identify who holds the lock in a data dir shared by
+	// several users or machines (empty in locks written before they were
+	// recorded).
+	User string `json:"user,omitempty"`
+	Host string `json:"host,omitempty"`
 }
 
 // ErrLocked indicates a non-stale lock is held by someone else.
@@ -27,30 +37,57 @@ type ErrLocked struct {
 
 func (e *ErrLocked) Error() string {
 	if e.Info != nil {
-		return fmt.Sprintf("repo %s is locked by pid %d since %s (lock file: %s)",
-			e.RepoID, e.Info.PID, e.Info.CreatedAt.Format(time.RFC3339), e.Path)
+		holder := fmt.Sprintf("pid %d", e.Info.PID)
+		if e.Info.User != "" || e.Info.Host != "" {
+			holder += " (" + e.Info.User
+			if e.Info.Host != "" {
+				holder += "@" + e.Info.Host
+			}
+			holder += ")"
+		}
+		return fmt.Sprintf("repo %s is locked by %s since %s (lock file: %s)",
+			e.RepoID, holder, e.Info.CreatedAt.Format(time.RFC3339), e.Path)
 	}
 	return fmt.Sprintf("repo %s is locked (lock file: %s)", e.RepoID, e.Path)
 }
 
+// DefaultStaleAfter is the v1 staleness window for repo locks.
+const DefaultStaleAfter = 2 * time.Hour
+
+// DefaultWait is how long LockWait callers wait for a held repo lock by
+// default.
+const DefaultWait = time.Minute
+
+// waitPollInterval is how often LockWait retries a held lock.
+const waitPollInterval = 50 * time.Millisecond
+
 // RepoLock provides repo-level locking for mutating commands.
 type RepoLock struct {
 	DataDir    string
 	StaleAfter time.Duration
 	Now        func() time.Time
 	IsPIDAlive func(pid int) bool
+
+	// User and Host are recorded in the locks this RepoLock takes. A lock
+	// recorded on another host is only stale by age: its pid means nothing
+	// here, which matters when the data dir is on a network filesystem.
+	User string
+	Host string
 }
 
 // NewRepoLock returns a RepoLock with v1 defaults:
 // - StaleAfter: 2h
 // - Now: time.Now
 // - IsPIDAlive: platform impl (best-effort)
+// - User, Host: identity.CurrentUser, identity.Hostname
 func NewRepoLock(dataDir string) RepoLock {
 	return RepoLock{
 		DataDir:    dataDir,
-		StaleAfter: 2 * time.Hour,
+		StaleAfter: DefaultStaleAfter,
 		Now:        time.Now,
 		IsPIDAlive: isPIDAlive,
+		User:       identity.CurrentUser(),
+		Host:       identity.Hostname(),
 	}
 }
 
@@ -72,15 +109,20 @@ func (l RepoLock) Lock(repoID string, cmd string) (unlock func() error, err erro
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return nil, fmt.Errorf("failed to create lock directory: %w", err)
 		}
+		_ = agencyfs.ShareDir(l.DataDir, dir)
 
 		// Try to create lock file with O_EXCL for atomic acquisition
-		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		// Readable by everyone, so other users of a shared data dir can see
+		// who holds the lock
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
 		if err == nil {
 			// Successfully created lock file - write info and return
 			info := LockInfo{
 				PID:       os.Getpid(),
 				CreatedAt: l.Now(),
 				Cmd:       cmd,
+				User:      l.User,
+				Host:      l.Host,
 			}
 			data, _ := json.Marshal(info)
 			if _, writeErr := f.Write(data); writeErr != nil {
@@ -145,6 +187,76 @@ func (l RepoLock) Lock(repoID string, cmd string) (unlock func() error, err erro
 	return nil, &ErrLocked{RepoID: repoID, Path: lockPath}
 }
 
+// LockWait is Lock that waits up to timeout for a lock held by someone else
+// to be released, retrying every 50ms. It returns the last *ErrLocked if the
+// lock is still held after timeout, and ctx.Err() if ctx is done first.
+// Other failures are returned at once.
+func (l RepoLock) LockWait(ctx context.Context, repoID, cmd string, timeout time.Duration) (unlock func() error, err error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		unlock, err := l.Lock(repoID, cmd)
+		if _, locked := err.(*ErrLocked); !locked || !time.Now().Before(deadline) {
+			return unlock, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// LockState describes an existing repo lock file.
+type LockState struct {
+	// Path is the lock file path.
+	Path string
+
+	// Info is the lock file contents (nil if unreadable).
+	Info *LockInfo
+
+	// Since is when the lock was taken: Info.CreatedAt, or the lock file's
+	// mtime if it is unreadable.
+	Since time.Time
+
+	// Stale is true if Lock would take the lock over (holder pid gone, or
+	// older than StaleAfter).
+	Stale bool
+}
+
+// State returns the repo's lock file state, or nil if the repo is not locked.
+// It never takes or removes the lock.
+func (l RepoLock) State(repoID string) (*LockState, error) {
+	lockPath := l.lockPath(repoID)
+	stat, err := os.Stat(lockPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	state := &LockState{Path: lockPath, Since: stat.ModTime()}
+	if info, err := l.readLockInfo(lockPath); err == nil {
+		state.Info = info
+		state.Since = info.CreatedAt
+		state.Stale = l.isStale(info)
+	} else {
+		state.Stale = l.Now().Sub(stat.ModTime()) > l.StaleAfter
+	}
+	return state, nil
+}
+
+// ForceUnlock removes the repo's lock file whoever holds it. Removing a lock
+// held by a live process lets a second mutating command run concurrently, so
+// callers should confirm first. Removing a missing lock is not an error.
+func (l RepoLock) ForceUnlock(repoID string) error {
+	err := os.Remove(l.lockPath(repoID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // readLockInfo reads and parses the lock file.
 func (l RepoLock) readLockInfo(path string) (*LockInfo, error) {
 	data, err := os.ReadFile(path)
@@ -160,8 +272,8 @@ func (l RepoLock) readLockInfo(path string) (*LockInfo, error) {
 
 // isStale returns true if the lock should be considered stale.
 func (l RepoLock) isStale(info *LockInfo) bool {
-	// Stale if pid is not alive
-	if !l.IsPIDAlive(info.PID) {
+	// Stale if pid is not alive (only checkable on the holder's host)
+	if (info.Host == "" || info.Host == l.Host) && !l.IsPIDAlive(info.PID) {
 		return true
 	}
 	// Stale if created_at is older than stale_after
@@ -1,6 +1,7 @@
 package lock
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -58,13 +59,13 @@ func TestRepoLock_WritesLockFile(t *testing.T) {
 		t.Errorf("Cmd = %q, want %q", info.Cmd, "push")
 	}
 
-	// Verify permissions are 0600
+	// Verify permissions are 0644 (other users may read the holder)
 	stat, err := os.Stat(lockPath)
 	if err != nil {
 		t.Fatalf("failed to stat lock file: %v", err)
 	}
-	if stat.Mode().Perm() != 0600 {
-		t.Errorf("lock file permissions = %o, want 0600", stat.Mode().Perm())
+	if stat.Mode().Perm()&^0o022 != 0o644&^0o022 {
+		t.Errorf("lock file permissions = %o, want 0644", stat.Mode().Perm())
 	}
 }
 
@@ -156,6 +157,50 @@ func TestRepoLock_StaleByDeadPIDSteals(t *testing.T) {
 	}
 }
 
+func TestRepoLock_OtherHostOnlyStaleByAge(t *testing.T) {
+	dataDir := t.TempDir()
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	// A lock taken on another machine sharing the data dir: its pid is not
+	// alive here, but that says nothing about the holder
+	holder := RepoLock{
+		DataDir:    dataDir,
+		StaleAfter: 2 * time.Hour,
+		Now:        stubNow(now),
+		IsPIDAlive: stubPIDAlive(true),
+		User:       "alice",
+		Host:       "host-a",
+	}
+	if _, err := holder.Lock("shared-repo", "run"); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	l := RepoLock{
+		DataDir:    dataDir,
+		StaleAfter: 2 * time.Hour,
+		Now:        stubNow(now.Add(time.Hour)),
+		IsPIDAlive: stubPIDAlive(false),
+		User:       "bob",
+		Host:       "host-b",
+	}
+	_, err := l.Lock("shared-repo", "kill")
+	errLocked, ok := err.(*ErrLocked)
+	if !ok {
+		t.Fatalf("Lock() error = %v, want *ErrLocked", err)
+	}
+	if errLocked.Info == nil || errLocked.Info.User != "alice" || errLocked.Info.Host != "host-a" {
+		t.Errorf("Info = %+v, want alice@host-a", errLocked.Info)
+	}
+
+	// Past the staleness window it is taken over
+	l.Now = stubNow(now.Add(3 * time.Hour))
+	unlock, err := l.Lock("shared-repo", "kill")
+	if err != nil {
+		t.Fatalf("Lock() after StaleAfter error = %v", err)
+	}
+	_ = unlock()
+}
+
 func TestRepoLock_StaleByAgeSteals(t *testing.T) {
 	dataDir := t.TempDir()
 	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
@@ -414,6 +459,8 @@ func TestErrLocked_Error(t *testing.T) {
 				PID:       12345,
 				CreatedAt: time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC),
 				Cmd:       "push",
+				User:      "alice",
+				Host:      "devbox",
 			},
 			Path: "/data/repos/test-repo/.lock",
 		}
@@ -422,7 +469,7 @@ func TestErrLocked_Error(t *testing.T) {
 			t.Error("error message should not be empty")
 		}
 		// Should contain key information
-		if !containsAll(msg, "test-repo", "12345", "/data/repos/test-repo/.lock") {
+		if !containsAll(msg, "test-repo", "12345", "alice@devbox", "/data/repos/test-repo/.lock") {
 			t.Errorf("error message missing expected info: %s", msg)
 		}
 	})
@@ -443,6 +490,88 @@ func TestErrLocked_Error(t *testing.T) {
 	})
 }
 
+func TestRepoLock_StateAndForceUnlock(t *testing.T) {
+	dataDir := t.TempDir()
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	alive := true
+	l := RepoLock{
+		DataDir:    dataDir,
+		StaleAfter: 2 * time.Hour,
+		Now:        stubNow(now),
+		IsPIDAlive: func(int) bool { return alive },
+	}
+
+	state, err := l.State("test-repo")
+	if err != nil || state != nil {
+		t.Fatalf("State() unlocked = %+v, %v; want nil, nil", state, err)
+	}
+
+	if _, err := l.Lock("test-repo", "mv"); err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+	state, err = l.State("test-repo")
+	if err != nil || state == nil {
+		t.Fatalf("State() locked = %+v, %v", state, err)
+	}
+	if state.Info == nil || state.Info.Cmd != "mv" || state.Info.PID != os.Getpid() || !state.Since.Equal(now) || state.Stale {
+		t.Errorf("State() = %+v, info %+v", state, state.Info)
+	}
+
+	alive = false
+	if state, _ := l.State("test-repo"); state == nil || !state.Stale {
+		t.Errorf("State() with dead holder = %+v, want stale", state)
+	}
+
+	if err := l.ForceUnlock("test-repo"); err != nil {
+		t.Fatalf("ForceUnlock() error = %v", err)
+	}
+	if state, _ := l.State("test-repo"); state != nil {
+		t.Errorf("State() after ForceUnlock = %+v, want nil", state)
+	}
+	if err := l.ForceUnlock("test-repo"); err != nil {
+		t.Errorf("ForceUnlock() on unlocked repo = %v, want nil", err)
+	}
+}
+
+func TestRepoLock_LockWait(t *testing.T) {
+	dataDir := t.TempDir()
+	l := RepoLock{
+		DataDir:    dataDir,
+		StaleAfter: 2 * time.Hour,
+		Now:        time.Now,
+		IsPIDAlive: stubPIDAlive(true),
+	}
+
+	unlock, err := l.Lock("test-repo", "run")
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+
+	// Still held after the timeout
+	if _, err := l.LockWait(context.Background(), "test-repo", "run", 100*time.Millisecond); err == nil {
+		t.Fatal("LockWait() should fail while the lock is held")
+	} else if _, ok := err.(*ErrLocked); !ok {
+		t.Fatalf("LockWait() error = %T, want *ErrLocked", err)
+	}
+
+	// Released while waiting
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = unlock()
+	}()
+	unlock2, err := l.LockWait(context.Background(), "test-repo", "run", 5*time.Second)
+	if err != nil {
+		t.Fatalf("LockWait() error = %v, want the released lock", err)
+	}
+	defer unlock2()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.LockWait(ctx, "test-repo", "run", 5*time.Second); err != context.Canceled {
+		t.Errorf("LockWait() with canceled ctx = %v, want context.Canceled", err)
+	}
+}
+
 // containsAll returns true if s contains all substrings.
 func containsAll(s string, subs ...string) bool {
 	for _, sub := range subs {
@@ -0,0 +1,86 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+)
+
+// RepoDataDir returns the data directory for a repo whose agency.json sets
+// data_dir to override ("" = not set).
+//
+// Resolution order:
+//  1. AGENCY_DATA_DIR env var (if set), so one invocation can still be redirected
+//  2. override, after ValidateDataDir
+//  3. dirs.DataDir
+func RepoDataDir(env Env, dirs Dirs, repoRoot, override string, allowInRepo bool) (string, error) {
+	if override == "" || env.Get("AGENCY_DATA_DIR") != "" {
+		return dirs.DataDir, nil
+	}
+	if err := ValidateDataDir(override, repoRoot, allowInRepo); err != nil {
+		return "", err
+	}
+	return filepath.Clean(override), nil
+}
+
+// ValidateDataDir checks a per-repo data dir override. The directory must be
+// an absolute path and must not be inside repoRoot (where `git clean` or a
+// commit could sweep up agency state) unless allowInRepo. Nothing is created
+// or written, so read commands can resolve a data dir on a read-only mount;
+// write commands also call EnsureDataDir.
+//
+// Returns E_INVALID_DATA_DIR describing the first failed check.
+func ValidateDataDir(dir, repoRoot string, allowInRepo bool) error {
+	details := map[string]string{"data_dir": dir}
+	if !filepath.IsAbs(dir) {
+		return errors.NewWithDetails(errors.EInvalidDataDir, "data_dir must be an absolute path: "+dir, details)
+	}
+	dir = filepath.Clean(dir)
+
+	if repoRoot != "" && !allowInRepo && isWithin(evalSymlinks(repoRoot), evalSymlinks(dir)) {
+		return errors.WithHints(errors.NewWithDetails(errors.EInvalidDataDir, "data_dir is inside the repo working tree: "+dir, details),
+			"move data_dir outside the repo or set allow_data_dir_in_repo")
+	}
+	return nil
+}
+
+// EnsureDataDir creates a data dir override if missing and checks that it is
+// writable, so a write command fails up front rather than halfway.
+//
+// Returns E_INVALID_DATA_DIR if it cannot be created or written.
+func EnsureDataDir(dir string) error {
+	dir = filepath.Clean(dir)
+	details := map[string]string{"data_dir": dir}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return errors.WrapWithDetails(errors.EInvalidDataDir, "data_dir cannot be created: "+dir, err, details)
+	}
+	f, err := os.CreateTemp(dir, ".agency-write-check-*")
+	if err != nil {
+		return errors.WrapWithDetails(errors.EInvalidDataDir, "data_dir is not writable: "+dir, err, details)
+	}
+	name := f.Name()
+	_ = f.Close()
+	_ = os.Remove(name)
+	return nil
+}
+
+// evalSymlinks resolves symlinks in path, falling back to the deepest existing
+// ancestor so paths that do not exist yet still compare correctly.
+func evalSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path
+	}
+	return filepath.Join(evalSymlinks(parent), filepath.Base(path))
+}
+
+// isWithin reports whether path is dir or below it.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
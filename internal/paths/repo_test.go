@@ -0,0 +1,83 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+)
+
+func TestRepoDataDir(t *testing.T) {
+	repoRoot := t.TempDir()
+	shared := filepath.Join(t.TempDir(), "shared", "agency")
+	dirs := Dirs{DataDir: "/global/agency"}
+
+	got, err := RepoDataDir(mapEnv{}, dirs, repoRoot, "", false)
+	if err != nil || got != dirs.DataDir {
+		t.Errorf("no override: got %q, %v; want %q", got, err, dirs.DataDir)
+	}
+
+	got, err = RepoDataDir(mapEnv{}, dirs, repoRoot, shared+"/", false)
+	if err != nil || got != shared {
+		t.Errorf("override: got %q, %v; want %q", got, err, shared)
+	}
+	if _, err := os.Stat(shared); !os.IsNotExist(err) {
+		t.Errorf("resolving the override created it: %v", err)
+	}
+
+	got, err = RepoDataDir(mapEnv{"AGENCY_DATA_DIR": "/env/agency"}, Dirs{DataDir: "/env/agency"}, repoRoot, shared, false)
+	if err != nil || got != "/env/agency" {
+		t.Errorf("AGENCY_DATA_DIR should win: got %q, %v", got, err)
+	}
+}
+
+func TestValidateDataDir(t *testing.T) {
+	repoRoot := t.TempDir()
+
+	tests := []struct {
+		name        string
+		dir         string
+		allowInRepo bool
+		wantErr     bool
+	}{
+		{"relative", "shared/agency", false, true},
+		{"outside repo", filepath.Join(t.TempDir(), "agency"), false, false},
+		{"inside repo", filepath.Join(repoRoot, ".agency"), false, true},
+		{"repo root itself", repoRoot, false, true},
+		{"inside repo allowed", filepath.Join(repoRoot, ".agency"), true, false},
+		{"sibling with shared prefix", repoRoot + "-agency", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDataDir(tt.dir, repoRoot, tt.allowInRepo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateDataDir(%q) error = %v, wantErr %v", tt.dir, err, tt.wantErr)
+			}
+			if err != nil && errors.GetCode(err) != errors.EInvalidDataDir {
+				t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EInvalidDataDir)
+			}
+		})
+	}
+}
+
+func TestEnsureDataDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shared", "agency")
+	if err := EnsureDataDir(dir); err != nil {
+		t.Fatalf("EnsureDataDir: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("data dir not created: %v", err)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	readOnly := filepath.Join(t.TempDir(), "ro")
+	if err := os.Mkdir(readOnly, 0o500); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureDataDir(readOnly); errors.GetCode(err) != errors.EInvalidDataDir {
+		t.Errorf("read-only dir: code = %q, want %q", errors.GetCode(err), errors.EInvalidDataDir)
+	}
+}
@@ -129,3 +129,26 @@ func resolveCacheDirWithOS(env Env, homeDir string, isDarwin bool) string {
 	// 4. Default fallback
 	return filepath.Join(homeDir, ".cache", "agency")
 }
+
+// ResolveLegacyDataDir returns where the data dir of the tool's former name,
+// conductor, would be, for migration by the conductor alias binary:
+//  1. CONDUCTOR_DATA_DIR env var (if set)
+//  2. macOS: ~/Library/Application Support/conductor
+//  3. XDG_DATA_HOME/conductor (if set)
+//  4. ~/.local/share/conductor
+func ResolveLegacyDataDir(env Env, homeDir string) string {
+	return resolveLegacyDataDirWithOS(env, homeDir, IsDarwin())
+}
+
+func resolveLegacyDataDirWithOS(env Env, homeDir string, isDarwin bool) string {
+	if v := env.Get("CONDUCTOR_DATA_DIR"); v != "" {
+		return v
+	}
+	if isDarwin {
+		return filepath.Join(homeDir, "Library", "Application Support", "conductor")
+	}
+	if v := env.Get("XDG_DATA_HOME"); v != "" {
+		return filepath.Join(v, "conductor")
+	}
+	return filepath.Join(homeDir, ".local", "share", "conductor")
+}
@@ -250,3 +250,22 @@ func TestResolveDirs_EmptyEnvVarIgnored(t *testing.T) {
 		t.Errorf("DataDir = %q, want %q (empty env var should be ignored)", dirs.DataDir, want)
 	}
 }
+
+func TestResolveLegacyDataDir(t *testing.T) {
+	home := filepath.FromSlash("/home/testuser")
+	tests := []struct {
+		env      mapEnv
+		isDarwin bool
+		want     string
+	}{
+		{mapEnv{"CONDUCTOR_DATA_DIR": "/old", "AGENCY_DATA_DIR": "/new"}, true, "/old"},
+		{mapEnv{}, true, filepath.FromSlash("/home/testuser/Library/Application Support/conductor")},
+		{mapEnv{"XDG_DATA_HOME": "/xdg"}, false, filepath.FromSlash("/xdg/conductor")},
+		{mapEnv{}, false, filepath.FromSlash("/home/testuser/.local/share/conductor")},
+	}
+	for _, tt := range tests {
+		if got := resolveLegacyDataDirWithOS(tt.env, home, tt.isDarwin); got != tt.want {
+			t.Errorf("resolveLegacyDataDirWithOS(%v, darwin=%v) = %q, want %q", tt.env, tt.isDarwin, got, tt.want)
+		}
+	}
+}
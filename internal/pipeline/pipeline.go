@@ -1,19 +1,23 @@
 // Package pipeline provides the run pipeline orchestrator for agency slice 1.
-// The pipeline executes steps in a fixed order, short-circuits on first error,
-// and preserves AgencyError codes.
+// The pipeline executes a list of steps in order (DefaultSteps for `agency
+// run`), short-circuits on first error, and preserves AgencyError codes.
 package pipeline
 
 import (
 	"context"
 	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/config"
 	"github.com/NielsdaWheelz/agency/internal/core"
 	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/vcs"
 )
 
 // RunPipelineOpts contains the inputs for running a pipeline.
 type RunPipelineOpts struct {
 	// Title is the run title (may be empty; defaults applied in later PRs).
+	// It may contain core template placeholders such as {date}, expanded
+	// by LoadAgencyConfig.
 	Title string
 
 	// Runner is the runner name (may be empty; defaults applied in later PRs).
@@ -24,6 +28,50 @@ type RunPipelineOpts struct {
 
 	// Attach indicates whether to attach to tmux after creation (used in later PRs).
 	Attach bool
+
+	// RunID is an externally supplied run_id (empty = generate one).
+	// Must pass core.ValidateRunID; uniqueness is checked by CheckRepoSafe.
+	RunID string
+
+	// Labels are stored verbatim under meta.labels (already validated).
+	Labels map[string]string
+
+	// Group is stored under meta.group and indexed in groups.json
+	// (already validated; "" = no group).
+	Group string
+
+	// TaskID is stored under meta.task_id ("" = not part of a fan-out).
+	TaskID string
+
+	// Dir is the directory repo discovery starts from (empty = process cwd).
+	// Set by the global -C/--repo flag.
+	Dir string
+
+	// Deadline time-boxes the run (0 = agency.json defaults.deadline).
+	Deadline time.Duration
+
+	// NoDeadline ignores defaults.deadline (--deadline none).
+	NoDeadline bool
+
+	// DeadlineKill kills the tmux session at the deadline
+	// (also enabled by defaults.deadline_kill).
+	DeadlineKill bool
+
+	// SparseProfile selects agency.json worktrees.sparse_profiles.<name>
+	// ("" = worktrees.sparse_checkout, "none" = full checkout).
+	SparseProfile string
+
+	// NoSetup skips RunSetup and its pre_run_setup/post_run_setup hooks
+	// (agency run --no-setup). Only honored by RunSteps.
+	NoSetup bool
+
+	// ForceSetup runs setup even when agency.json setup_cache has a
+	// matching entry (agency run --force-setup).
+	ForceSetup bool
+
+	// NoTmux skips StartTmux and its pre_start_tmux hook
+	// (agency run --no-tmux). Only honored by RunSteps.
+	NoTmux bool
 }
 
 // Warning represents a non-fatal warning emitted during pipeline execution.
@@ -43,32 +91,69 @@ type PipelineState struct {
 	Runner string
 	Parent string
 	Attach bool
+	Labels map[string]string
+	Group  string
+	TaskID string
+	Dir    string
+
+	// From opts; LoadAgencyConfig applies defaults.deadline*
+	Deadline     time.Duration // 0 = not time-boxed
+	NoDeadline   bool
+	DeadlineKill bool
+
+	// From opts; recorded under meta.skipped_steps (Skip* values)
+	SkippedSteps []string
+
+	// From opts; LoadAgencyConfig resolves it into SparseCheckout
+	SparseProfile string
+
+	// From opts; bypasses the setup cache lookup
+	ForceSetup bool
 
 	// Generated immediately
 	RunID string
 
+	// NewRunID returns another generated run_id, for steps that find RunID
+	// taken by a concurrent run (nil when RunID was supplied by opts).
+	NewRunID func() (string, error)
+
 	// Populated by CheckRepoSafe
 	RepoRoot  string
 	RepoID    string
 	RepoKey   string
 	OriginURL string
 	DataDir   string
+	VCS       vcs.Kind // git, or jj for jj repos with a colocated git store
 
 	// Populated by LoadAgencyConfig
 	ResolvedRunnerCmd string
 	SetupScript       string
 	ParentBranch      string // resolved from config if Parent was empty
+	Hooks             map[string]string
+	Logs              config.Logs       // script log size limit
+	Sandbox           config.Sandbox    // setup script write guard
+	SetupCache        config.SetupCache // setup_cache inputs and cached paths
+	Slug              core.SlugRules    // branch slug + default title rules
+	SparseCheckout    []string          // worktrees.sparse_checkout or the SparseProfile's patterns
+	Submodules        bool              // worktrees.submodules: init submodules after checkout
+	MaxPathLength     int               // worktrees.max_path_length (0 = platform default)
+	GitHub            config.GitHub     // github.credentials for the runner session
+	TitleTemplate     string            // Title before placeholder expansion ("" = no placeholders)
+	TemplateVars      map[string]string // placeholder values used to expand TitleTemplate
 
 	// Populated by CreateWorktree
-	Branch       string
-	WorktreePath string
+	Branch           string
+	WorktreePath     string
+	WorktreeDuration time.Duration // git worktree add + sparse checkout + submodules
+	SubmodulePaths   []string      // the worktree's submodules (gitlinks)
+	SubmoduleLog     string        // git submodule update output, copied into setup.log
 
 	// Accumulated warnings (non-fatal)
 	Warnings []Warning
 }
 
 // RunService defines the step implementations for the run pipeline.
-// Each method corresponds to a pipeline step executed in order.
+// Each method corresponds to a pipeline step (see DefaultSteps).
 // Implementations are injected to allow testing without real git/tmux/fs.
 type RunService interface {
 	// CheckRepoSafe verifies repo safety (clean working tree, parent branch exists, etc.)
@@ -88,19 +173,25 @@ type RunService interface {
 
 	// StartTmux creates the tmux session with the runner command
 	StartTmux(ctx context.Context, st *PipelineState) error
+
+	// RunHook runs the script configured for hook (a config.Hook* point).
+	// Only called for hooks present in st.Hooks.
+	RunHook(ctx context.Context, st *PipelineState, hook string) error
 }
 
-// Pipeline orchestrates the execution of run steps in a fixed order.
+// Pipeline orchestrates the execution of run steps.
 type Pipeline struct {
-	svc     RunService
-	nowFunc func() time.Time
+	svc      RunService
+	nowFunc  func() time.Time
+	newRunID func(now time.Time) (string, error)
 }
 
 // NewPipeline creates a pipeline with the given service implementation.
 func NewPipeline(svc RunService) *Pipeline {
 	return &Pipeline{
-		svc:     svc,
-		nowFunc: time.Now,
+		svc:      svc,
+		nowFunc:  time.Now,
+		newRunID: core.NewRunID,
 	}
 }
 
@@ -109,66 +200,194 @@ func (p *Pipeline) SetNowFunc(fn func() time.Time) {
 	p.nowFunc = fn
 }
 
-// Run executes the pipeline steps in fixed order:
+// SetClock overrides both the time source and run_id generation.
+func (p *Pipeline) SetClock(c core.Clock) {
+	p.nowFunc = c.Now
+	p.newRunID = c.NewRunID
+}
+
+// Step is one pipeline step. Run usually calls one RunService method
+// (e.g. svc.CreateWorktree).
+type Step struct {
+	// Name identifies the step in E_INTERNAL error details.
+	Name string
+
+	Run func(ctx context.Context, svc RunService, st *PipelineState) error
+}
+
+// Standard steps, one per RunService method.
+var (
+	CheckRepoSafeStep = Step{Name: StepCheckRepoSafe, Run: func(ctx context.Context, svc RunService, st *PipelineState) error {
+		return svc.CheckRepoSafe(ctx, st)
+	}}
+	LoadAgencyConfigStep = Step{Name: StepLoadAgencyConfig, Run: func(ctx context.Context, svc RunService, st *PipelineState) error {
+		return svc.LoadAgencyConfig(ctx, st)
+	}}
+	CreateWorktreeStep = Step{Name: StepCreateWorktree, Run: func(ctx context.Context, svc RunService, st *PipelineState) error {
+		return svc.CreateWorktree(ctx, st)
+	}}
+	WriteMetaStep = Step{Name: StepWriteMeta, Run: func(ctx context.Context, svc RunService, st *PipelineState) error {
+		return svc.WriteMeta(ctx, st)
+	}}
+	RunSetupStep = Step{Name: StepRunSetup, Run: func(ctx context.Context, svc RunService, st *PipelineState) error {
+		return svc.RunSetup(ctx, st)
+	}}
+	StartTmuxStep = Step{Name: StepStartTmux, Run: func(ctx context.Context, svc RunService, st *PipelineState) error {
+		return svc.StartTmux(ctx, st)
+	}}
+)
+
+// HookStep returns a step running hook (a config.Hook* point) if it is
+// configured in st.Hooks, and doing nothing otherwise.
+func HookStep(hook string) Step {
+	return Step{
+		Name: StepRunHook + ":" + hook,
+		Run: func(ctx context.Context, svc RunService, st *PipelineState) error {
+			if st.Hooks[hook] == "" {
+				return nil
+			}
+			return svc.RunHook(ctx, st, hook)
+		},
+	}
+}
+
+// PrepareSteps returns the steps with no side effects, run by Prepare.
+func PrepareSteps() []Step {
+	return []Step{CheckRepoSafeStep, LoadAgencyConfigStep}
+}
+
+// DefaultSteps returns the `agency run` flow:
 //  1. CheckRepoSafe
 //  2. LoadAgencyConfig
 //  3. CreateWorktree
 //  4. WriteMeta
+//     hooks: post_create_worktree, pre_run_setup
 //  5. RunSetup
+//     hooks: post_run_setup, pre_start_tmux
 //  6. StartTmux
 //
+// Alternate flows compose their own list from the same steps.
+func DefaultSteps() []Step {
+	return append(PrepareSteps(),
+		CreateWorktreeStep,
+		WriteMetaStep,
+		HookStep(config.HookPostCreateWorktree),
+		HookStep(config.HookPreRunSetup),
+		RunSetupStep,
+		HookStep(config.HookPostRunSetup),
+		HookStep(config.HookPreStartTmux),
+		StartTmuxStep,
+	)
+}
+
+// RunSteps returns DefaultSteps minus the steps opts asks to skip:
+// NoSetup drops RunSetup with the pre_run_setup and post_run_setup hooks,
+// NoTmux drops StartTmux with the pre_start_tmux hook.
+func RunSteps(opts RunPipelineOpts) []Step {
+	skip := map[string]bool{}
+	if opts.NoSetup {
+		skip[StepRunSetup] = true
+		skip[HookStep(config.HookPreRunSetup).Name] = true
+		skip[HookStep(config.HookPostRunSetup).Name] = true
+	}
+	if opts.NoTmux {
+		skip[StepStartTmux] = true
+		skip[HookStep(config.HookPreStartTmux).Name] = true
+	}
+	var steps []Step
+	for _, step := range DefaultSteps() {
+		if !skip[step.Name] {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// skippedSteps lists the Skip* values for the steps opts asks to skip.
+func skippedSteps(opts RunPipelineOpts) []string {
+	var skipped []string
+	if opts.NoSetup {
+		skipped = append(skipped, SkipSetup)
+	}
+	if opts.NoTmux {
+		skipped = append(skipped, SkipTmux)
+	}
+	return skipped
+}
+
+// Run executes RunSteps(opts) and returns the run_id (even on error, once
+// one has been assigned). See Execute for the error semantics.
+func (p *Pipeline) Run(ctx context.Context, opts RunPipelineOpts) (string, error) {
+	st, err := p.Execute(ctx, opts, RunSteps(opts))
+	if st == nil {
+		return "", err
+	}
+	return st.RunID, err
+}
+
+// Prepare executes PrepareSteps and returns the resulting state.
+// `agency run --dry-run` calls it alone. The state is nil only if no run_id
+// could be assigned; otherwise it is returned even on error.
+func (p *Pipeline) Prepare(ctx context.Context, opts RunPipelineOpts) (*PipelineState, error) {
+	return p.Execute(ctx, opts, PrepareSteps())
+}
+
+// Execute initializes the state from opts and runs steps in order.
+//
 // Behavior:
-//   - Generates run_id immediately and stores it in state
+//   - Uses opts.RunID (validated) or generates run_id immediately and stores it in state;
+//     a generated run_id may be replaced by a step on collision (see PipelineState.NewRunID)
 //   - Executes steps in order; short-circuits on first error
 //   - If error is *AgencyError, preserves code/message/details exactly
 //   - If error is not *AgencyError, wraps into *AgencyError with:
 //     Code = E_INTERNAL, Message = "internal error", Cause = original error,
-//     Details = map[string]string{"step": "<StepName>"}
-//   - Returns runID even on error (after run_id generation)
-func (p *Pipeline) Run(ctx context.Context, opts RunPipelineOpts) (string, error) {
+//     Details = map[string]string{"step": "<Step.Name>"}
+//   - Returns the state even on error; it is nil only if no run_id could be assigned
+func (p *Pipeline) Execute(ctx context.Context, opts RunPipelineOpts, steps []Step) (*PipelineState, error) {
 	// Initialize state with opts
 	st := &PipelineState{
 		Title:  opts.Title,
 		Runner: opts.Runner,
 		Parent: opts.Parent,
 		Attach: opts.Attach,
+		Labels: opts.Labels,
+		Group:  opts.Group,
+		TaskID: opts.TaskID,
+		Dir:    opts.Dir,
+
+		Deadline:     opts.Deadline,
+		NoDeadline:   opts.NoDeadline,
+		DeadlineKill: opts.DeadlineKill,
+
+		SkippedSteps:  skippedSteps(opts),
+		SparseProfile: opts.SparseProfile,
+		ForceSetup:    opts.ForceSetup,
 	}
 
-	// Generate run_id immediately
-	now := p.nowFunc()
-	runID, err := core.NewRunID(now)
-	if err != nil {
-		// Extremely rare: crypto/rand failure
-		return "", errors.Wrap(errors.EInternal, "failed to generate run_id", err)
-	}
-	st.RunID = runID
-
-	// Execute steps in fixed order
-	if err := p.svc.CheckRepoSafe(ctx, st); err != nil {
-		return st.RunID, wrapStepError(err, StepCheckRepoSafe)
-	}
-
-	if err := p.svc.LoadAgencyConfig(ctx, st); err != nil {
-		return st.RunID, wrapStepError(err, StepLoadAgencyConfig)
-	}
-
-	if err := p.svc.CreateWorktree(ctx, st); err != nil {
-		return st.RunID, wrapStepError(err, StepCreateWorktree)
+	// Use the supplied run_id or generate one immediately
+	if opts.RunID != "" {
+		if err := core.ValidateRunID(opts.RunID); err != nil {
+			return nil, errors.Wrap(errors.EUsage, "invalid --run-id", err)
+		}
+		st.RunID = opts.RunID
+	} else {
+		runID, err := p.newRunID(p.nowFunc())
+		if err != nil {
+			// Extremely rare: crypto/rand failure
+			return nil, errors.Wrap(errors.EInternal, "failed to generate run_id", err)
+		}
+		st.RunID = runID
+		st.NewRunID = func() (string, error) {
+			return p.newRunID(p.nowFunc())
+		}
 	}
 
-	if err := p.svc.WriteMeta(ctx, st); err != nil {
-		return st.RunID, wrapStepError(err, StepWriteMeta)
+	for _, step := range steps {
+		if err := step.Run(ctx, p.svc, st); err != nil {
+			return st, wrapStepError(err, step.Name)
+		}
 	}
-
-	if err := p.svc.RunSetup(ctx, st); err != nil {
-		return st.RunID, wrapStepError(err, StepRunSetup)
-	}
-
-	if err := p.svc.StartTmux(ctx, st); err != nil {
-		return st.RunID, wrapStepError(err, StepStartTmux)
-	}
-
-	return st.RunID, nil
+	return st, nil
 }
 
 // wrapStepError ensures the error is an *AgencyError.
@@ -201,4 +420,11 @@ const (
 	StepWriteMeta        = "WriteMeta"
 	StepRunSetup         = "RunSetup"
 	StepStartTmux        = "StartTmux"
+	StepRunHook          = "RunHook"
+)
+
+// Values recorded in meta.skipped_steps for steps left out by RunSteps.
+const (
+	SkipSetup = "setup"
+	SkipTmux  = "tmux"
 )
@@ -3,9 +3,13 @@ package pipeline
 import (
 	"context"
 	stderrors "errors"
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/core"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 )
 
@@ -25,6 +29,10 @@ type mockRunService struct {
 	runSetupErr         error
 	startTmuxErr        error
 
+	// Hooks copied into state by LoadAgencyConfig, and per-hook errors
+	hooks    map[string]string
+	hookErrs map[string]error
+
 	// Track which methods were called
 	called []string
 }
@@ -34,8 +42,9 @@ func (m *mockRunService) CheckRepoSafe(_ context.Context, _ *PipelineState) erro
 	return m.checkRepoSafeErr
 }
 
-func (m *mockRunService) LoadAgencyConfig(_ context.Context, _ *PipelineState) error {
+func (m *mockRunService) LoadAgencyConfig(_ context.Context, st *PipelineState) error {
 	m.called = append(m.called, StepLoadAgencyConfig)
+	st.Hooks = m.hooks
 	return m.loadAgencyConfigErr
 }
 
@@ -59,6 +68,11 @@ func (m *mockRunService) StartTmux(_ context.Context, _ *PipelineState) error {
 	return m.startTmuxErr
 }
 
+func (m *mockRunService) RunHook(_ context.Context, _ *PipelineState, hook string) error {
+	m.called = append(m.called, StepRunHook+":"+hook)
+	return m.hookErrs[hook]
+}
+
 // TestShortCircuitPreservesErrorCode tests that the pipeline short-circuits
 // on first step error and preserves AgencyError codes.
 func TestShortCircuitPreservesErrorCode(t *testing.T) {
@@ -306,6 +320,9 @@ func (m *stateCapturingMock) CreateWorktree(_ context.Context, _ *PipelineState)
 func (m *stateCapturingMock) WriteMeta(_ context.Context, _ *PipelineState) error { return nil }
 func (m *stateCapturingMock) RunSetup(_ context.Context, _ *PipelineState) error  { return nil }
 func (m *stateCapturingMock) StartTmux(_ context.Context, _ *PipelineState) error { return nil }
+func (m *stateCapturingMock) RunHook(_ context.Context, _ *PipelineState, _ string) error {
+	return nil
+}
 
 // TestOptsPassedToState tests that RunPipelineOpts are correctly copied
 // into the pipeline state.
@@ -362,10 +379,13 @@ func (m *optCapturingMock) CheckRepoSafe(_ context.Context, st *PipelineState) e
 	return nil
 }
 func (m *optCapturingMock) LoadAgencyConfig(_ context.Context, _ *PipelineState) error { return nil }
-func (m *optCapturingMock) CreateWorktree(_ context.Context, _ *PipelineState) error  { return nil }
-func (m *optCapturingMock) WriteMeta(_ context.Context, _ *PipelineState) error       { return nil }
-func (m *optCapturingMock) RunSetup(_ context.Context, _ *PipelineState) error        { return nil }
-func (m *optCapturingMock) StartTmux(_ context.Context, _ *PipelineState) error       { return nil }
+func (m *optCapturingMock) CreateWorktree(_ context.Context, _ *PipelineState) error   { return nil }
+func (m *optCapturingMock) WriteMeta(_ context.Context, _ *PipelineState) error        { return nil }
+func (m *optCapturingMock) RunSetup(_ context.Context, _ *PipelineState) error         { return nil }
+func (m *optCapturingMock) StartTmux(_ context.Context, _ *PipelineState) error        { return nil }
+func (m *optCapturingMock) RunHook(_ context.Context, _ *PipelineState, _ string) error {
+	return nil
+}
 
 // TestStepsExecuteInOrder tests that steps execute in the expected fixed order.
 func TestStepsExecuteInOrder(t *testing.T) {
@@ -398,6 +418,115 @@ func TestStepsExecuteInOrder(t *testing.T) {
 	}
 }
 
+// TestHooksRunAtPipelinePoints tests that configured hooks fire in order
+// around RunSetup and unconfigured hooks are skipped.
+func TestHooksRunAtPipelinePoints(t *testing.T) {
+	mock := &mockRunService{
+		hooks: map[string]string{
+			config.HookPostCreateWorktree: "scripts/copy_env.sh",
+			config.HookPostRunSetup:       "scripts/post.sh",
+			config.HookPreStartTmux:       "scripts/pre_tmux.sh",
+		},
+	}
+
+	p := NewPipeline(mock)
+	p.SetNowFunc(fixedTime)
+
+	if _, err := p.Run(context.Background(), RunPipelineOpts{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		StepCheckRepoSafe,
+		StepLoadAgencyConfig,
+		StepCreateWorktree,
+		StepWriteMeta,
+		StepRunHook + ":" + config.HookPostCreateWorktree,
+		StepRunSetup,
+		StepRunHook + ":" + config.HookPostRunSetup,
+		StepRunHook + ":" + config.HookPreStartTmux,
+		StepStartTmux,
+	}
+	if !reflect.DeepEqual(mock.called, expected) {
+		t.Errorf("called = %v, want %v", mock.called, expected)
+	}
+}
+
+// TestRunSkipsSteps tests that NoSetup/NoTmux drop the steps and their
+// hooks and record the skips in state.
+func TestRunSkipsSteps(t *testing.T) {
+	mock := &mockRunService{
+		hooks: map[string]string{
+			config.HookPostCreateWorktree: "scripts/copy_env.sh",
+			config.HookPreRunSetup:        "scripts/pre.sh",
+			config.HookPostRunSetup:       "scripts/post.sh",
+			config.HookPreStartTmux:       "scripts/pre_tmux.sh",
+		},
+	}
+
+	p := NewPipeline(mock)
+	p.SetNowFunc(fixedTime)
+
+	opts := RunPipelineOpts{NoSetup: true, NoTmux: true}
+	st, err := p.Execute(context.Background(), opts, RunSteps(opts))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		StepCheckRepoSafe,
+		StepLoadAgencyConfig,
+		StepCreateWorktree,
+		StepWriteMeta,
+		StepRunHook + ":" + config.HookPostCreateWorktree,
+	}
+	if !reflect.DeepEqual(mock.called, expected) {
+		t.Errorf("called = %v, want %v", mock.called, expected)
+	}
+	if want := []string{SkipSetup, SkipTmux}; !reflect.DeepEqual(st.SkippedSteps, want) {
+		t.Errorf("SkippedSteps = %v, want %v", st.SkippedSteps, want)
+	}
+
+	// Skipping only tmux keeps setup and its hooks
+	mock.called = nil
+	opts = RunPipelineOpts{NoTmux: true}
+	if _, err := p.Execute(context.Background(), opts, RunSteps(opts)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = append(expected,
+		StepRunHook+":"+config.HookPreRunSetup,
+		StepRunSetup,
+		StepRunHook+":"+config.HookPostRunSetup,
+	)
+	if !reflect.DeepEqual(mock.called, expected) {
+		t.Errorf("called = %v, want %v", mock.called, expected)
+	}
+}
+
+// TestHookFailureShortCircuits tests that a failing hook stops the pipeline
+// and non-AgencyErrors are wrapped with the hook name in details.
+func TestHookFailureShortCircuits(t *testing.T) {
+	mock := &mockRunService{
+		hooks:    map[string]string{config.HookPreRunSetup: "scripts/pre.sh"},
+		hookErrs: map[string]error{config.HookPreRunSetup: stderrors.New("boom")},
+	}
+
+	p := NewPipeline(mock)
+	p.SetNowFunc(fixedTime)
+
+	_, err := p.Run(context.Background(), RunPipelineOpts{})
+	if errors.GetCode(err) != errors.EInternal {
+		t.Fatalf("expected E_INTERNAL, got %v", err)
+	}
+	ae, _ := errors.AsAgencyError(err)
+	if ae.Details["step"] != StepRunHook+":"+config.HookPreRunSetup {
+		t.Errorf("step detail = %q", ae.Details["step"])
+	}
+	if last := mock.called[len(mock.called)-1]; last != StepRunHook+":"+config.HookPreRunSetup {
+		t.Errorf("last call = %q, want the failing hook", last)
+	}
+}
+
 // TestMiddleStepFailure tests that failure in a middle step short-circuits correctly.
 func TestMiddleStepFailure(t *testing.T) {
 	mock := &mockRunService{
@@ -430,3 +559,132 @@ func TestMiddleStepFailure(t *testing.T) {
 		t.Errorf("expected %d steps called, got %d: %v", len(expected), len(mock.called), mock.called)
 	}
 }
+
+// TestSuppliedRunID tests that an externally supplied run_id is used verbatim.
+func TestSuppliedRunID(t *testing.T) {
+	stateCapturer := &stateCapturingMock{err: errors.New(errors.EParentDirty, "dirty")}
+	p := NewPipeline(stateCapturer)
+	p.SetNowFunc(fixedTime)
+
+	runID, _ := p.Run(context.Background(), RunPipelineOpts{RunID: "job-4821"})
+
+	if runID != "job-4821" || stateCapturer.capturedRunID != "job-4821" {
+		t.Errorf("runID = %q, state runID = %q, want job-4821", runID, stateCapturer.capturedRunID)
+	}
+}
+
+// TestSuppliedRunIDInvalid tests that a malformed run_id fails before any step runs.
+func TestSuppliedRunIDInvalid(t *testing.T) {
+	mock := &mockRunService{}
+	p := NewPipeline(mock)
+
+	runID, err := p.Run(context.Background(), RunPipelineOpts{RunID: "Bad.ID"})
+
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("error code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+	if runID != "" {
+		t.Errorf("runID = %q, want empty", runID)
+	}
+	if len(mock.called) != 0 {
+		t.Errorf("no steps should run, got %v", mock.called)
+	}
+}
+
+// TestExecuteCustomSteps tests that alternate flows can run a subset of the
+// standard steps plus their own, with the same short-circuit and wrapping.
+func TestExecuteCustomSteps(t *testing.T) {
+	mock := &mockRunService{hooks: map[string]string{config.HookPreStartTmux: "scripts/pre_tmux.sh"}}
+	p := NewPipeline(mock)
+	p.SetNowFunc(fixedTime)
+
+	custom := Step{Name: "Reuse", Run: func(_ context.Context, _ RunService, st *PipelineState) error {
+		mock.called = append(mock.called, "Reuse")
+		st.WorktreePath = "/existing"
+		return nil
+	}}
+	steps := []Step{LoadAgencyConfigStep, custom, HookStep(config.HookPostRunSetup), HookStep(config.HookPreStartTmux), StartTmuxStep}
+
+	st, err := p.Execute(context.Background(), RunPipelineOpts{}, steps)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{StepLoadAgencyConfig, "Reuse", StepRunHook + ":" + config.HookPreStartTmux, StepStartTmux}
+	if !reflect.DeepEqual(mock.called, want) {
+		t.Errorf("called = %v, want %v", mock.called, want)
+	}
+	if st.RunID == "" || st.WorktreePath != "/existing" {
+		t.Errorf("state = %+v", st)
+	}
+
+	// A failing custom step short-circuits and is named in E_INTERNAL details
+	mock.called = nil
+	failing := Step{Name: "Reuse", Run: func(context.Context, RunService, *PipelineState) error {
+		return stderrors.New("boom")
+	}}
+	_, err = p.Execute(context.Background(), RunPipelineOpts{}, []Step{failing, StartTmuxStep})
+	ae, ok := errors.AsAgencyError(err)
+	if !ok || ae.Code != errors.EInternal || ae.Details["step"] != "Reuse" {
+		t.Errorf("err = %v, want E_INTERNAL with step Reuse", err)
+	}
+	if len(mock.called) != 0 {
+		t.Errorf("steps after the failure ran: %v", mock.called)
+	}
+}
+
+// TestSetClockGeneratesRunID tests that SetClock supplies the run_id.
+func TestSetClockGeneratesRunID(t *testing.T) {
+	p := NewPipeline(&mockRunService{})
+	p.SetClock(core.Clock{
+		Now: fixedTime,
+		NewRunID: func(now time.Time) (string, error) {
+			return now.Format("20060102150405") + "-0001", nil
+		},
+	})
+
+	runID, err := p.Run(context.Background(), RunPipelineOpts{Title: "test"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := fixedTime().Format("20060102150405") + "-0001"; runID != want {
+		t.Errorf("runID = %q, want %q", runID, want)
+	}
+}
+
+// TestNewRunIDOnlyForGeneratedRunID tests that steps can replace a generated
+// run_id, but not a supplied one.
+func TestNewRunIDOnlyForGeneratedRunID(t *testing.T) {
+	n := 0
+	p := NewPipeline(&mockRunService{})
+	p.SetClock(core.Clock{
+		Now: fixedTime,
+		NewRunID: func(now time.Time) (string, error) {
+			n++
+			return fmt.Sprintf("%s-%04d", now.Format("20060102150405"), n), nil
+		},
+	})
+	regenerate := Step{Name: "Regenerate", Run: func(_ context.Context, _ RunService, st *PipelineState) error {
+		if st.NewRunID == nil {
+			return nil
+		}
+		runID, err := st.NewRunID()
+		st.RunID = runID
+		return err
+	}}
+
+	st, err := p.Execute(context.Background(), RunPipelineOpts{}, []Step{regenerate})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := fixedTime().Format("20060102150405") + "-0002"; st.RunID != want {
+		t.Errorf("runID = %q, want %q", st.RunID, want)
+	}
+
+	st, err = p.Execute(context.Background(), RunPipelineOpts{RunID: "job-4821"}, []Step{regenerate})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if st.RunID != "job-4821" || st.NewRunID != nil {
+		t.Errorf("supplied run_id: runID = %q, NewRunID set = %t", st.RunID, st.NewRunID != nil)
+	}
+}
@@ -0,0 +1,201 @@
+// Package profile records where a single agency invocation spends its time,
+// for the hidden --profile flag: a CPU and a heap profile (runtime/pprof),
+// and a trace of the external commands it ran and the run directories it
+// scanned. Everything is written to one directory per invocation, which
+// agency profile report summarizes.
+package profile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is the trace.json schema version.
+const SchemaVersion = "1.0"
+
+// File names in a profile directory.
+const (
+	CPUFileName   = "cpu.pprof"
+	HeapFileName  = "heap.pprof"
+	TraceFileName = "trace.json"
+)
+
+// Span kinds recorded in the trace.
+const (
+	KindGit    = "git"
+	KindTmux   = "tmux"
+	KindGH     = "gh"
+	KindExec   = "exec" // any other external command
+	KindScript = "script"
+	KindScan   = "scan" // filesystem scans of the data dir
+)
+
+// Span is one timed operation: an external command or a scan.
+type Span struct {
+	Kind string `json:"kind"`
+
+	// Name identifies the operation without its arguments, e.g.
+	// "git rev-parse" or "scan all runs".
+	Name string `json:"name"`
+
+	// StartMs is the offset from the start of the invocation.
+	StartMs    int64 `json:"start_ms"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// Trace is the trace.json file of a profile directory.
+type Trace struct {
+	SchemaVersion string   `json:"schema_version"`
+	Command       string   `json:"command"`
+	Argv          []string `json:"argv"`
+	StartedAt     string   `json:"started_at"`
+	DurationMs    int64    `json:"duration_ms"`
+	Spans         []Span   `json:"spans"`
+}
+
+// Session is a profile being recorded.
+type Session struct {
+	Dir string
+
+	trace Trace
+	start time.Time
+	cpu   *os.File
+}
+
+var (
+	mu     sync.Mutex
+	active *Session
+)
+
+// Start creates dir and starts recording into it: the CPU profile, and the
+// spans passed to Record from then on. Only one session can be active.
+func Start(dir, command string, argv []string) (*Session, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	cpu, err := os.Create(filepath.Join(dir, CPUFileName))
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return nil, err
+	}
+	now := time.Now()
+	s := &Session{
+		Dir: dir,
+		trace: Trace{
+			SchemaVersion: SchemaVersion,
+			Command:       command,
+			Argv:          argv,
+			StartedAt:     now.UTC().Format(time.RFC3339),
+		},
+		start: now,
+		cpu:   cpu,
+	}
+	mu.Lock()
+	active = s
+	mu.Unlock()
+	return s, nil
+}
+
+// Stop ends the session: it stops the CPU profile and writes the heap
+// profile and trace.json.
+func (s *Session) Stop() error {
+	mu.Lock()
+	if active == s {
+		active = nil
+	}
+	s.trace.DurationMs = time.Since(s.start).Milliseconds()
+	trace := s.trace
+	mu.Unlock()
+
+	pprof.StopCPUProfile()
+	err := s.cpu.Close()
+
+	heap, herr := os.Create(filepath.Join(s.Dir, HeapFileName))
+	if herr == nil {
+		runtime.GC()
+		herr = pprof.WriteHeapProfile(heap)
+		if cerr := heap.Close(); herr == nil {
+			herr = cerr
+		}
+	}
+	if err == nil {
+		err = herr
+	}
+
+	sort.SliceStable(trace.Spans, func(i, j int) bool { return trace.Spans[i].StartMs < trace.Spans[j].StartMs })
+	data, jerr := json.MarshalIndent(trace, "", "  ")
+	if jerr == nil {
+		jerr = os.WriteFile(filepath.Join(s.Dir, TraceFileName), append(data, '\n'), 0o600)
+	}
+	if err == nil {
+		err = jerr
+	}
+	return err
+}
+
+// Enabled reports whether a session is recording.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return active != nil
+}
+
+// Record adds a span that started at start and ends now. It does nothing
+// without an active session.
+func Record(kind, name string, start time.Time) {
+	end := time.Now()
+	mu.Lock()
+	defer mu.Unlock()
+	if active == nil {
+		return
+	}
+	active.trace.Spans = append(active.trace.Spans, Span{
+		Kind:       kind,
+		Name:       name,
+		StartMs:    start.Sub(active.start).Milliseconds(),
+		DurationMs: end.Sub(start).Milliseconds(),
+	})
+}
+
+// Track starts a span and returns the function that ends it:
+//
+//	defer profile.Track(profile.KindScan, "scan all runs")()
+func Track(kind, name string) func() {
+	start := time.Now()
+	return func() { Record(kind, name, start) }
+}
+
+// CommandKind returns the span kind of an external command.
+func CommandKind(name string) string {
+	switch filepath.Base(name) {
+	case "git":
+		return KindGit
+	case "tmux":
+		return KindTmux
+	case "gh":
+		return KindGH
+	}
+	return KindExec
+}
+
+// ReadTrace reads the trace.json of a profile directory.
+func ReadTrace(dir string) (*Trace, error) {
+	data, err := os.ReadFile(filepath.Join(dir, TraceFileName))
+	if err != nil {
+		return nil, err
+	}
+	var t Trace
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,87 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "p")
+	s, err := Start(dir, "ls", []string{"agency", "ls"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if !Enabled() {
+		t.Error("Enabled() = false during a session")
+	}
+	Track(KindScan, "scan all runs")()
+	Record(KindGit, "git status", time.Now().Add(-30*time.Millisecond))
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if Enabled() {
+		t.Error("Enabled() = true after Stop")
+	}
+	Record(KindGit, "git log", time.Now()) // dropped: no session
+
+	for _, name := range []string{CPUFileName, HeapFileName, TraceFileName} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	trace, err := ReadTrace(dir)
+	if err != nil {
+		t.Fatalf("ReadTrace() error = %v", err)
+	}
+	if trace.Command != "ls" || len(trace.Spans) != 2 {
+		t.Fatalf("trace = %+v", trace)
+	}
+	if trace.Spans[0].Name != "git status" || trace.Spans[0].DurationMs < 30 {
+		t.Errorf("spans[0] = %+v, want git status first (earliest start)", trace.Spans[0])
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	trace := &Trace{
+		Command:    "ls",
+		DurationMs: 1000,
+		Spans: []Span{
+			{Kind: KindGit, Name: "git rev-parse", DurationMs: 100},
+			{Kind: KindScan, Name: "scan all runs", DurationMs: 250},
+			{Kind: KindGit, Name: "git rev-list", DurationMs: 300},
+			{Kind: KindTmux, Name: "tmux list-sessions", DurationMs: 50},
+		},
+	}
+	s := Summarize(trace, 2)
+	want := []KindTotal{{KindGit, 2, 400}, {KindScan, 1, 250}, {KindTmux, 1, 50}}
+	if len(s.Kinds) != len(want) {
+		t.Fatalf("kinds = %+v", s.Kinds)
+	}
+	for i := range want {
+		if s.Kinds[i] != want[i] {
+			t.Errorf("kinds[%d] = %+v, want %+v", i, s.Kinds[i], want[i])
+		}
+	}
+	if s.OtherMs != 300 {
+		t.Errorf("other_ms = %d, want 300", s.OtherMs)
+	}
+	if len(s.Slowest) != 2 || s.Slowest[0].Name != "git rev-list" || s.Slowest[1].Name != "scan all runs" {
+		t.Errorf("slowest = %+v", s.Slowest)
+	}
+}
+
+func TestCommandKind(t *testing.T) {
+	for name, want := range map[string]string{
+		"git":          KindGit,
+		"/usr/bin/git": KindGit,
+		"tmux":         KindTmux,
+		"gh":           KindGH,
+		"claude":       KindExec,
+	} {
+		if got := CommandKind(name); got != want {
+			t.Errorf("CommandKind(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
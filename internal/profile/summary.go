@@ -0,0 +1,72 @@
+package profile
+
+import "sort"
+
+// KindTotal is the time spent in one kind of span.
+type KindTotal struct {
+	Kind    string `json:"kind"`
+	Count   int    `json:"count"`
+	TotalMs int64  `json:"total_ms"`
+}
+
+// Summary is where the time of one profiled invocation went.
+type Summary struct {
+	Command    string   `json:"command"`
+	Argv       []string `json:"argv"`
+	StartedAt  string   `json:"started_at"`
+	DurationMs int64    `json:"duration_ms"`
+
+	// Kinds are the span totals by kind, largest first.
+	Kinds []KindTotal `json:"kinds"`
+
+	// OtherMs is the wall time not covered by spans: agency's own work
+	// (see cpu.pprof). 0 when spans overlap by more than that.
+	OtherMs int64 `json:"other_ms"`
+
+	// Slowest are the longest spans, longest first.
+	Slowest []Span `json:"slowest"`
+}
+
+// Summarize totals t's spans by kind and picks its n slowest spans.
+func Summarize(t *Trace, n int) Summary {
+	s := Summary{
+		Command:    t.Command,
+		Argv:       t.Argv,
+		StartedAt:  t.StartedAt,
+		DurationMs: t.DurationMs,
+		Kinds:      []KindTotal{},
+		Slowest:    []Span{},
+	}
+	byKind := map[string]*KindTotal{}
+	var busy int64
+	for _, span := range t.Spans {
+		k := byKind[span.Kind]
+		if k == nil {
+			k = &KindTotal{Kind: span.Kind}
+			byKind[span.Kind] = k
+		}
+		k.Count++
+		k.TotalMs += span.DurationMs
+		busy += span.DurationMs
+	}
+	for _, k := range byKind {
+		s.Kinds = append(s.Kinds, *k)
+	}
+	sort.Slice(s.Kinds, func(i, j int) bool {
+		if s.Kinds[i].TotalMs != s.Kinds[j].TotalMs {
+			return s.Kinds[i].TotalMs > s.Kinds[j].TotalMs
+		}
+		return s.Kinds[i].Kind < s.Kinds[j].Kind
+	})
+	if busy < t.DurationMs {
+		s.OtherMs = t.DurationMs - busy
+	}
+
+	slowest := append([]Span(nil), t.Spans...)
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].DurationMs > slowest[j].DurationMs })
+	if len(slowest) > n {
+		slowest = slowest[:n]
+	}
+	s.Slowest = append(s.Slowest, slowest...)
+	return s
+}
@@ -0,0 +1,108 @@
+// Package redact masks secrets in text that leaves the machine, such as the
+// logs and env captures collected by `agency bundle`.
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Marker replaces every redacted value.
+const Marker = "[REDACTED]"
+
+// pemBlock matches a PEM private key, header to footer.
+var pemBlock = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+
+// urlCredentials matches the user:password@ part of a URL.
+var urlCredentials = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/\s:@]+:[^/\s@]+@`)
+
+// authHeader matches the credential of an HTTP Authorization header or a
+// bare "Bearer <token>".
+var authHeader = regexp.MustCompile(`(?i)\b(authorization:\s*(?:bearer|basic|token)\s+|bearer\s+)[A-Za-z0-9._~+/=-]{8,}`)
+
+// tokenPatterns match well-known token formats wherever they appear.
+var tokenPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{20,}`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{20,}`),
+	regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`),
+}
+
+// jsonPair matches a "key": "value" pair with a string value.
+var jsonPair = regexp.MustCompile(`"([^"\\]*)"(\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+
+// assignment matches KEY=value, KEY: value, and --key=value, with the value
+// optionally quoted.
+var assignment = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_.-]*)(\s*[=:]\s*)(["']?)([^\s"',;]+)`)
+
+// secretKey matches key names whose values are secrets.
+var secretKey = regexp.MustCompile(`(?i)(token|secret|passw(or)?d|api[_-]?key|private[_-]?key|access[_-]?key|session[_-]?key|credentials?$)`)
+
+// notSecretSuffixes exempt keys that name where a secret lives rather than
+// the secret itself (e.g. GITHUB_TOKEN_PATH).
+var notSecretSuffixes = []string{"path", "file", "dir", "url", "mode", "_at", "_count", "_ttl"}
+
+// IsSecretKey reports whether a value stored under key (an env var name or
+// JSON property) should be redacted.
+func IsSecretKey(key string) bool {
+	if !secretKey.MatchString(key) {
+		return false
+	}
+	lower := strings.ToLower(key)
+	for _, suffix := range notSecretSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns s with secrets replaced by Marker, and the number of values
+// it replaced. It redacts private keys, URL credentials, Authorization
+// headers, well-known token formats, and the values of secret-looking keys
+// in JSON, env files, and command lines. Redaction is best effort: it errs
+// towards masking, but cannot recognize every secret.
+func String(s string) (string, int) {
+	n := 0
+	replace := func(re *regexp.Regexp, repl func(m []string) string) {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			out := repl(re.FindStringSubmatch(match))
+			if out != match {
+				n++
+			}
+			return out
+		})
+	}
+
+	replace(pemBlock, func([]string) string { return Marker })
+	replace(urlCredentials, func(m []string) string { return m[1] + Marker + "@" })
+	replace(authHeader, func(m []string) string { return m[1] + Marker })
+	for _, re := range tokenPatterns {
+		replace(re, func([]string) string { return Marker })
+	}
+	replace(jsonPair, func(m []string) string {
+		if !IsSecretKey(m[1]) || m[3] == "" || m[3] == Marker {
+			return m[0]
+		}
+		return `"` + m[1] + `"` + m[2] + `"` + Marker + `"`
+	})
+	replace(assignment, func(m []string) string {
+		if !IsSecretKey(m[1]) || strings.HasPrefix(m[4], Marker) {
+			return m[0]
+		}
+		return m[1] + m[2] + m[3] + Marker
+	})
+	return s, n
+}
+
+// Bytes is String for byte slices.
+func Bytes(b []byte) ([]byte, int) {
+	s, n := String(string(b))
+	if n == 0 {
+		return b, 0
+	}
+	return []byte(s), n
+}
@@ -0,0 +1,176 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// CompareSchemaVersion is the schema_version of compare --json output.
+const CompareSchemaVersion = "1.0"
+
+// CompareRun is one side of agency compare output (human and JSON).
+type CompareRun struct {
+	RunID        string `json:"run_id"`
+	RepoID       string `json:"repo_id"`
+	Title        string `json:"title"`
+	Runner       string `json:"runner"`
+	Branch       string `json:"branch"`
+	ParentBranch string `json:"parent_branch"`
+	CreatedAt    string `json:"created_at"`
+
+	// Archived is true if the worktree is gone.
+	Archived bool `json:"archived"`
+
+	// Setup is the setup script result (null if setup never ran).
+	Setup *CompareSetup `json:"setup"`
+
+	// Verify summarizes verify.jsonl (null if the run was never verified).
+	Verify *CompareVerify `json:"verify"`
+
+	// Diff is the branch's changes since it forked from the parent branch
+	// (null if the worktree is gone or either branch is missing).
+	Diff *CompareDiff `json:"diff"`
+
+	// ReportBytes is the size of .agency/report.md (0 if missing or archived).
+	ReportBytes int `json:"report_bytes"`
+}
+
+// CompareSetup is a run's setup script result.
+type CompareSetup struct {
+	// Status is "ok", "failed", "timed_out", or "skipped" (run --no-setup).
+	Status string `json:"status"`
+
+	ExitCode   int   `json:"exit_code"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// CompareVerify summarizes a run's verify attempts.
+type CompareVerify struct {
+	Attempts int `json:"attempts"`
+	Passed   int `json:"passed"`
+
+	// LastOK, LastAt, LastDurationMs, and LastSummary describe the latest attempt.
+	LastOK         bool   `json:"last_ok"`
+	LastAt         string `json:"last_at"`
+	LastDurationMs int64  `json:"last_duration_ms"`
+	LastSummary    string `json:"last_summary,omitempty"`
+}
+
+// CompareDiff is the size of a branch's changes vs its parent branch.
+type CompareDiff struct {
+	Commits      int `json:"commits"`
+	FilesChanged int `json:"files_changed"`
+	Insertions   int `json:"insertions"`
+	Deletions    int `json:"deletions"`
+}
+
+// CompareJSONEnvelope is the stable JSON output format for compare --json.
+type CompareJSONEnvelope struct {
+	SchemaVersion string        `json:"schema_version"`
+	Data          [2]CompareRun `json:"data"`
+}
+
+// WriteCompareJSON writes two runs' comparison as JSON (data[0] is run_a).
+func WriteCompareJSON(w io.Writer, a, b CompareRun) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(CompareJSONEnvelope{SchemaVersion: CompareSchemaVersion, Data: [2]CompareRun{a, b}})
+}
+
+// WriteCompareHuman writes two runs side by side: one row per field, with
+// a column per run. plain writes "<field>_a: value" and "<field>_b: value"
+// lines instead of the table. Unknown values are shown as "-".
+func WriteCompareHuman(w io.Writer, a, b CompareRun, plain bool) error {
+	rows := [][3]string{
+		{"run_id", a.RunID, b.RunID},
+		{"title", a.Title, b.Title},
+		{"runner", a.Runner, b.Runner},
+		{"branch", a.Branch, b.Branch},
+		{"created", a.CreatedAt, b.CreatedAt},
+		{"setup", formatCompareSetup(a.Setup), formatCompareSetup(b.Setup)},
+		{"verify", formatCompareVerify(a.Verify), formatCompareVerify(b.Verify)},
+		{"diff", formatCompareDiff(a.Diff), formatCompareDiff(b.Diff)},
+		{"report", formatCompareReport(a), formatCompareReport(b)},
+	}
+	for i := range rows {
+		for j := 1; j < 3; j++ {
+			if rows[i][j] == "" {
+				rows[i][j] = "-"
+			}
+		}
+	}
+
+	if plain {
+		for _, row := range rows {
+			fmt.Fprintf(w, "%s_a: %s\n", row[0], row[1])
+			if _, err := fmt.Fprintf(w, "%s_b: %s\n", row[0], row[2]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tA\tB")
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", row[0], row[1], row[2])
+	}
+	return tw.Flush()
+}
+
+// formatCompareSetup renders a setup result, e.g. "ok 12.3s" or
+// "FAIL exit 1 4.1s".
+func formatCompareSetup(s *CompareSetup) string {
+	if s == nil {
+		return ""
+	}
+	var text string
+	switch s.Status {
+	case "skipped":
+		return "skipped"
+	case "ok":
+		text = "ok"
+	case "timed_out":
+		text = "FAIL timed out"
+	default:
+		text = fmt.Sprintf("FAIL exit %d", s.ExitCode)
+	}
+	if s.DurationMs > 0 {
+		text += " " + formatCheckDuration(s.DurationMs)
+	}
+	return text
+}
+
+// formatCompareVerify renders the latest verify result and the pass count,
+// e.g. "ok 40s (2/3 passed)".
+func formatCompareVerify(v *CompareVerify) string {
+	if v == nil {
+		return ""
+	}
+	text := checkResult(v.LastOK)
+	if v.LastDurationMs > 0 {
+		text += " " + formatCheckDuration(v.LastDurationMs)
+	}
+	return fmt.Sprintf("%s (%d/%d passed)", text, v.Passed, v.Attempts)
+}
+
+// formatCompareDiff renders a diff size, e.g. "+120 -30, 4 file(s), 2 commit(s)".
+func formatCompareDiff(d *CompareDiff) string {
+	if d == nil {
+		return ""
+	}
+	return fmt.Sprintf("+%d -%d, %d file(s), %d commit(s)", d.Insertions, d.Deletions, d.FilesChanged, d.Commits)
+}
+
+// formatCompareReport renders a report size ("" for archived runs).
+func formatCompareReport(r CompareRun) string {
+	if r.Archived {
+		return ""
+	}
+	if r.ReportBytes == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d bytes", r.ReportBytes)
+}
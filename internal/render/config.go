@@ -0,0 +1,43 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// ConfigSchemaVersion is the schema_version of config list --json output.
+const ConfigSchemaVersion = "1.0"
+
+// ConfigSetting is one effective setting shown by agency config list.
+type ConfigSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+
+	// Origin is where the value comes from: default, user, repo, or env.
+	Origin string `json:"origin"`
+}
+
+// ConfigJSONEnvelope is the stable JSON output format for config list --json.
+type ConfigJSONEnvelope struct {
+	SchemaVersion string          `json:"schema_version"`
+	Data          []ConfigSetting `json:"data"`
+}
+
+// WriteConfigJSON writes settings as JSON.
+func WriteConfigJSON(w io.Writer, settings []ConfigSetting) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ConfigJSONEnvelope{SchemaVersion: ConfigSchemaVersion, Data: settings})
+}
+
+// WriteConfigHuman writes settings as "origin  key=value" lines, like
+// git config --list --show-origin.
+func WriteConfigHuman(w io.Writer, settings []ConfigSetting) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range settings {
+		fmt.Fprintf(tw, "%s\t%s=%s\n", s.Origin, s.Key, s.Value)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,146 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DigestRun is one run in an agency digest.
+type DigestRun struct {
+	RunID    string
+	Repo     string // repo_key, or repo_id if repo.json is missing
+	Title    string
+	PRNumber int // 0 if no PR
+	PRURL    string
+	Detail   string // e.g. a failed run's setup outcome ("" if none)
+}
+
+// Digest is the input to the digest writers: run activity since Since.
+// A run can be in several sections (created and merged in the window).
+type Digest struct {
+	GeneratedAt    time.Time
+	Since          time.Time
+	Created        []DigestRun
+	Merged         []DigestRun
+	Failed         []DigestRun
+	AwaitingReview []DigestRun
+}
+
+// digestSection is one titled list of a digest, in output order.
+type digestSection struct {
+	Title string
+	Runs  []DigestRun
+}
+
+func (d Digest) sections() []digestSection {
+	return []digestSection{
+		{"created", d.Created},
+		{"merged", d.Merged},
+		{"failed", d.Failed},
+		{"awaiting review", d.AwaitingReview},
+	}
+}
+
+// RunCount returns the number of distinct runs in the digest.
+func (d Digest) RunCount() int {
+	seen := map[string]bool{}
+	for _, s := range d.sections() {
+		for _, run := range s.Runs {
+			seen[run.Repo+"/"+run.RunID] = true
+		}
+	}
+	return len(seen)
+}
+
+// digestCounts is the one-line summary: "2 created · 1 merged · ...".
+func digestCounts(d Digest) string {
+	parts := make([]string, 0, 4)
+	for _, s := range d.sections() {
+		parts = append(parts, fmt.Sprintf("%d %s", len(s.Runs), s.Title))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// digestWindow describes the digest window, e.g. "since 2026-01-19 12:00 UTC".
+func digestWindow(d Digest) string {
+	return "since " + d.Since.UTC().Format("2006-01-02 15:04 MST")
+}
+
+// WriteDigestMarkdown writes the digest as Markdown. Empty sections are
+// left out.
+func WriteDigestMarkdown(w io.Writer, d Digest) error {
+	var b strings.Builder
+	b.WriteString("# agency digest\n\n")
+	fmt.Fprintf(&b, "_%s · %s_\n", digestWindow(d), digestCounts(d))
+	if d.RunCount() == 0 {
+		b.WriteString("\nno run activity.\n")
+	}
+
+	for _, s := range d.sections() {
+		if len(s.Runs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", s.Title, len(s.Runs))
+		for _, run := range s.Runs {
+			fmt.Fprintf(&b, "- **%s** (`%s`, %s)", markdownEscape(run.Title), run.RunID, markdownEscape(run.Repo))
+			if run.PRNumber != 0 {
+				if run.PRURL != "" {
+					fmt.Fprintf(&b, " — [#%d](%s)", run.PRNumber, run.PRURL)
+				} else {
+					fmt.Fprintf(&b, " — #%d", run.PRNumber)
+				}
+			}
+			if run.Detail != "" {
+				fmt.Fprintf(&b, " — %s", markdownEscape(run.Detail))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteDigestSlack writes the digest in Slack's mrkdwn format, for posting
+// to a channel. Empty sections are left out.
+func WriteDigestSlack(w io.Writer, d Digest) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*agency digest* %s\n%s\n", digestWindow(d), digestCounts(d))
+	if d.RunCount() == 0 {
+		b.WriteString("\nno run activity.\n")
+	}
+
+	for _, s := range d.sections() {
+		if len(s.Runs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n*%s (%d)*\n", s.Title, len(s.Runs))
+		for _, run := range s.Runs {
+			fmt.Fprintf(&b, "• %s (`%s`, %s)", slackEscape(run.Title), run.RunID, slackEscape(run.Repo))
+			if run.PRNumber != 0 {
+				if run.PRURL != "" {
+					fmt.Fprintf(&b, " — <%s|#%d>", run.PRURL, run.PRNumber)
+				} else {
+					fmt.Fprintf(&b, " — #%d", run.PRNumber)
+				}
+			}
+			if run.Detail != "" {
+				fmt.Fprintf(&b, " — %s", slackEscape(run.Detail))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// slackReplacer escapes the characters Slack's mrkdwn treats as control
+// characters.
+var slackReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackEscape(s string) string {
+	return slackReplacer.Replace(s)
+}
@@ -0,0 +1,82 @@
+// Package render provides output formatting for agency commands.
+// This file implements --format go-template output.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+)
+
+// formatFuncs are helper functions available to --format templates.
+var formatFuncs = template.FuncMap{
+	// json renders a value as compact JSON (e.g. {{json .Meta}}).
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	},
+	// default returns def if v is nil (including nil pointers) or an empty string.
+	"default": func(def string, v any) any {
+		rv := reflect.ValueOf(v)
+		for rv.IsValid() && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return def
+			}
+			rv = rv.Elem()
+		}
+		if !rv.IsValid() || (rv.Kind() == reflect.String && rv.String() == "") {
+			return def
+		}
+		return rv.Interface()
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ParseFormat parses a --format go-template.
+// Templates are executed against the same structs used for --json output
+// (RunSummary for ls, RunDetail for show), using Go field names (e.g. {{.RunID}}).
+// Returns E_USAGE if the template is invalid.
+func ParseFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Option("missingkey=error").Parse(format)
+	if err != nil {
+		return nil, errors.Wrap(errors.EUsage, "invalid --format template", err)
+	}
+	return tmpl, nil
+}
+
+// WriteLSFormat executes tmpl once per summary, each followed by a newline.
+func WriteLSFormat(w io.Writer, tmpl *template.Template, summaries []RunSummary) error {
+	for i := range summaries {
+		if err := executeFormatLine(w, tmpl, summaries[i].withUnixTimestamps()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteShowFormat executes tmpl against the run detail, followed by a newline.
+func WriteShowFormat(w io.Writer, tmpl *template.Template, detail *RunDetail) error {
+	return executeFormatLine(w, tmpl, detail.withUnixTimestamps())
+}
+
+// executeFormatLine renders a single template execution into w.
+// Output is buffered so a failing template does not emit a partial line.
+func executeFormatLine(w io.Writer, tmpl *template.Template, data any) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return errors.Wrap(errors.EUsage, "failed to execute --format template", err)
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
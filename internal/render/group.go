@@ -0,0 +1,86 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// GroupSummary is one run group in agency group ls output (human and JSON).
+type GroupSummary struct {
+	// Group is the group name.
+	Group string `json:"group"`
+
+	// CreatedAt is when the group was created, from groups.json (null if not indexed).
+	CreatedAt *string `json:"created_at"`
+
+	// CreatedAtUnix is CreatedAt as Unix epoch seconds.
+	CreatedAtUnix *int64 `json:"created_at_unix"`
+
+	// RunIDs are the member runs (meta.group), sorted.
+	RunIDs []string `json:"run_ids"`
+
+	// Statuses counts member runs by derived status.
+	Statuses map[string]int `json:"statuses"`
+}
+
+// GroupsSchemaVersion is the schema_version of group ls --json output.
+// 1.1 added created_at_unix.
+const GroupsSchemaVersion = "1.1"
+
+// GroupsJSONEnvelope is the stable JSON output format for group ls --json.
+type GroupsJSONEnvelope struct {
+	SchemaVersion string         `json:"schema_version"`
+	Data          []GroupSummary `json:"data"`
+}
+
+// WriteGroupsJSON writes group summaries as JSON.
+func WriteGroupsJSON(w io.Writer, groups []GroupSummary) error {
+	data := make([]GroupSummary, len(groups))
+	for i, g := range groups {
+		data[i] = g.withUnixTimestamps()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(GroupsJSONEnvelope{SchemaVersion: GroupsSchemaVersion, Data: data})
+}
+
+// WriteGroupsHuman writes a GROUP / RUNS / STATUS table; STATUS lists the
+// status counts, most common first (e.g. "2 active, 1 ready for review").
+func WriteGroupsHuman(w io.Writer, groups []GroupSummary) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tRUNS\tSTATUS")
+	for _, g := range groups {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", g.Group, len(g.RunIDs), FormatStatusCounts(g.Statuses))
+	}
+	return tw.Flush()
+}
+
+// FormatStatusCounts renders status counts as "2 active, 1 idle", most
+// common first, ties by name; "-" if there are none.
+func FormatStatusCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "-"
+	}
+	statuses := make([]string, 0, len(counts))
+	for s := range counts {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if counts[statuses[i]] != counts[statuses[j]] {
+			return counts[statuses[i]] > counts[statuses[j]]
+		}
+		return statuses[i] < statuses[j]
+	})
+	parts := make([]string, len(statuses))
+	for i, s := range statuses {
+		parts[i] = fmt.Sprintf("%d %s", counts[s], s)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/checkpoint"
+	"github.com/NielsdaWheelz/agency/internal/errors"
 	"github.com/NielsdaWheelz/agency/internal/store"
 )
 
@@ -33,9 +35,18 @@ type RunSummary struct {
 	// CreatedAt is the creation timestamp in RFC3339Nano (null for broken runs).
 	CreatedAt *time.Time `json:"created_at"`
 
+	// CreatedAtUnix is CreatedAt as Unix epoch seconds.
+	CreatedAtUnix *int64 `json:"created_at_unix"`
+
+	// CreatedBy is the user who created the run (null if not recorded).
+	CreatedBy *string `json:"created_by"`
+
 	// LastPushAt is the last push timestamp (null if not pushed).
 	LastPushAt *time.Time `json:"last_push_at"`
 
+	// LastPushAtUnix is LastPushAt as Unix epoch seconds.
+	LastPushAtUnix *int64 `json:"last_push_at_unix"`
+
 	// TmuxActive indicates whether the tmux session exists.
 	TmuxActive bool `json:"tmux_active"`
 
@@ -51,28 +62,94 @@ type RunSummary struct {
 	// PRURL is the GitHub PR URL (null if no PR).
 	PRURL *string `json:"pr_url"`
 
+	// Labels are the run's meta.labels ({} if none).
+	Labels map[string]string `json:"labels"`
+
 	// DerivedStatus is the human-readable status string.
 	DerivedStatus string `json:"derived_status"`
 
+	// AttentionReason explains a derived "needs attention" status
+	// (e.g. "deadline exceeded"; null otherwise).
+	AttentionReason *string `json:"attention_reason"`
+
+	// ReportStale is true iff the branch has commits newer than the report covers.
+	ReportStale bool `json:"report_stale"`
+
+	// NoChanges is true iff the run has no commits beyond its parent branch
+	// and its report is below the non-empty threshold.
+	NoChanges bool `json:"no_changes"`
+
+	// Ahead is the number of branch commits not on the parent branch
+	// (null unless ls --commits, or if the worktree or either branch is gone).
+	Ahead *int `json:"ahead"`
+
+	// Behind is the number of parent branch commits not on the branch
+	// (null under the same conditions as Ahead).
+	Behind *int `json:"behind"`
+
+	// Lock is the run's repo lock while a mutating command holds it (null
+	// if the repo is not locked).
+	Lock *LockJSON `json:"lock"`
+
 	// Broken indicates whether meta.json is unreadable/invalid.
 	Broken bool `json:"broken"`
 }
 
+// LSSchemaVersion is the schema_version of ls --json output.
+// 1.1 added total, offset, limit, and warnings; 1.2 added the _unix
+// timestamps; 1.3 added lock; 1.4 added created_by, lock.user, and lock.host.
+const LSSchemaVersion = "1.4"
+
 // LSJSONEnvelope is the stable JSON output format for ls --json.
 type LSJSONEnvelope struct {
 	SchemaVersion string       `json:"schema_version"`
 	Data          []RunSummary `json:"data"`
+
+	// Total is the number of matching runs before --offset/--limit.
+	Total int `json:"total"`
+
+	// Offset is the number of matching runs skipped before Data.
+	Offset int `json:"offset"`
+
+	// Limit is the page size; null if unlimited.
+	Limit *int `json:"limit"`
+
+	// Warnings lists directories that could not be scanned; runs under
+	// them are missing from Data.
+	Warnings []store.ScanWarning `json:"warnings"`
+}
+
+// LSPage describes which slice of the matching runs an ls page holds.
+type LSPage struct {
+	// Total is the number of matching runs before paging.
+	Total int
+
+	// Offset is the number of matching runs skipped.
+	Offset int
+
+	// Limit is the page size; 0 means unlimited.
+	Limit int
 }
 
 // WriteLSJSON writes the ls output as JSON to the given writer.
-func WriteLSJSON(w io.Writer, summaries []RunSummary) error {
+func WriteLSJSON(w io.Writer, summaries []RunSummary, page LSPage, warnings []store.ScanWarning) error {
 	env := LSJSONEnvelope{
-		SchemaVersion: "1.0",
-		Data:          summaries,
+		SchemaVersion: LSSchemaVersion,
+		Data:          make([]RunSummary, len(summaries)),
+		Total:         page.Total,
+		Offset:        page.Offset,
+		Warnings:      warnings,
 	}
-	// Use empty slice if nil for valid JSON array output
-	if env.Data == nil {
-		env.Data = []RunSummary{}
+	if page.Limit > 0 {
+		limit := page.Limit
+		env.Limit = &limit
+	}
+	for i, s := range summaries {
+		env.Data[i] = s.withUnixTimestamps()
+	}
+	// Use empty slices if nil for valid JSON array output
+	if env.Warnings == nil {
+		env.Warnings = []store.ScanWarning{}
 	}
 
 	enc := json.NewEncoder(w)
@@ -80,6 +157,18 @@ func WriteLSJSON(w io.Writer, summaries []RunSummary) error {
 	return enc.Encode(env)
 }
 
+// WriteLSStream writes ls output as newline-delimited JSON: one compact
+// RunSummary object per line, with no envelope.
+func WriteLSStream(w io.Writer, summaries []RunSummary) error {
+	enc := json.NewEncoder(w)
+	for _, s := range summaries {
+		if err := enc.Encode(s.withUnixTimestamps()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ============================================================================
 // Show command JSON types
 // ============================================================================
@@ -90,6 +179,12 @@ type RunDetail struct {
 	// Meta is the raw parsed meta.json object; null if broken.
 	Meta *store.RunMeta `json:"meta"`
 
+	// CreatedAtUnix is meta.created_at as Unix epoch seconds (null if broken).
+	CreatedAtUnix *int64 `json:"created_at_unix"`
+
+	// LastPushAtUnix is meta.last_push_at as Unix epoch seconds (null if not pushed).
+	LastPushAtUnix *int64 `json:"last_push_at_unix"`
+
 	// RepoID is the repo identifier from directory name (canonical).
 	RepoID string `json:"repo_id"`
 
@@ -108,6 +203,15 @@ type RunDetail struct {
 	// Paths contains resolved filesystem paths.
 	Paths PathsJSON `json:"paths"`
 
+	// Notes contains the run's notes in the order they were recorded.
+	Notes []store.RunNote `json:"notes"`
+
+	// Checkpoints are the run's worktree checkpoints, oldest first.
+	Checkpoints []checkpoint.Checkpoint `json:"checkpoints"`
+
+	// VerifyHistory is the run's verify attempts from verify.jsonl, oldest first.
+	VerifyHistory []store.VerifyAttempt `json:"verify_history"`
+
 	// Broken indicates whether meta.json is unreadable/invalid.
 	Broken bool `json:"broken"`
 }
@@ -117,12 +221,28 @@ type DerivedJSON struct {
 	// DerivedStatus is the human-readable status string.
 	DerivedStatus string `json:"derived_status"`
 
+	// AttentionReason explains a derived "needs attention" status
+	// (e.g. "deadline exceeded"; null otherwise).
+	AttentionReason *string `json:"attention_reason"`
+
+	// Reasons lists the predicates behind DerivedStatus, in the order they
+	// were evaluated (e.g. "pr_number set", "tmux session missing").
+	Reasons []string `json:"reasons"`
+
+	// NoChanges is true iff the run has no commits beyond its parent branch
+	// and its report is below the non-empty threshold.
+	NoChanges bool `json:"no_changes"`
+
 	// TmuxActive is true iff the tmux session exists.
 	TmuxActive bool `json:"tmux_active"`
 
 	// WorktreePresent is true iff the worktree path exists on disk.
 	WorktreePresent bool `json:"worktree_present"`
 
+	// Lock is the run's repo lock while a mutating command holds it (null
+	// if the repo is not locked).
+	Lock *LockJSON `json:"lock"`
+
 	// Report contains report file info.
 	Report ReportJSON `json:"report"`
 
@@ -140,6 +260,12 @@ type ReportJSON struct {
 
 	// Path is the absolute path to the report file.
 	Path string `json:"path"`
+
+	// Commit is the commit SHA covered by the report (null if not recorded).
+	Commit *string `json:"commit"`
+
+	// Stale is true iff the branch has commits newer than Commit.
+	Stale bool `json:"stale"`
 }
 
 // LogsJSON contains script log paths for show --json.
@@ -152,6 +278,9 @@ type LogsJSON struct {
 
 	// ArchiveLogPath is the path to archive.log.
 	ArchiveLogPath string `json:"archive_log_path"`
+
+	// Bytes is the on-disk size of all of the run's logs (post-compression).
+	Bytes int64 `json:"bytes"`
 }
 
 // PathsJSON contains resolved filesystem paths for show --json.
@@ -172,17 +301,49 @@ type PathsJSON struct {
 	TranscriptPath string `json:"transcript_path"`
 }
 
+// ShowSchemaVersion is the schema_version of show --json output.
+// 1.1 added created_at_unix and last_push_at_unix; 1.2 added derived.lock;
+// 1.3 added checkpoints; 1.4 added derived.lock.user and derived.lock.host;
+// 1.5 added derived.reasons; 1.6 added verify_history.
+const ShowSchemaVersion = "1.6"
+
 // ShowJSONEnvelope is the stable JSON output format for show --json.
 type ShowJSONEnvelope struct {
 	SchemaVersion string     `json:"schema_version"`
 	Data          *RunDetail `json:"data"` // nullable on error
+
+	// Error is set when show fails before it has run data.
+	Error *errors.Rendered `json:"error,omitempty"`
+}
+
+// WriteShowJSONError writes the show --json envelope for a failed lookup:
+// null data and the rendered error.
+func WriteShowJSONError(w io.Writer, err error) error {
+	rendered := errors.Render(err)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ShowJSONEnvelope{SchemaVersion: ShowSchemaVersion, Error: &rendered})
 }
 
 // WriteShowJSON writes the show output as JSON to the given writer.
 func WriteShowJSON(w io.Writer, detail *RunDetail) error {
+	// Use empty slice if nil for valid JSON array output
+	if detail != nil && detail.Notes == nil {
+		detail.Notes = []store.RunNote{}
+	}
+	if detail != nil && detail.Checkpoints == nil {
+		detail.Checkpoints = []checkpoint.Checkpoint{}
+	}
+	if detail != nil && detail.VerifyHistory == nil {
+		detail.VerifyHistory = []store.VerifyAttempt{}
+	}
+	if detail != nil && detail.Derived.Reasons == nil {
+		detail.Derived.Reasons = []string{}
+	}
+
 	env := ShowJSONEnvelope{
-		SchemaVersion: "1.0",
-		Data:          detail,
+		SchemaVersion: ShowSchemaVersion,
+		Data:          detail.withUnixTimestamps(),
 	}
 
 	enc := json.NewEncoder(w)
@@ -0,0 +1,73 @@
+package render
+
+import (
+	"fmt"
+	"time"
+)
+
+// LockJSON describes the repo lock held by a mutating agency command (mv,
+// adopt, lint --fix, gc, ...). Locks are per repo, so every run in the repo
+// reports the same lock.
+type LockJSON struct {
+	// PID is the holder's process id (null if the lock file is unreadable).
+	PID *int `json:"pid"`
+
+	// Cmd is the holder's command, e.g. "mv" (null if unrecorded or unreadable).
+	Cmd *string `json:"cmd"`
+
+	// User is the user running the holder (null if unrecorded or unreadable).
+	User *string `json:"user"`
+
+	// Host is the machine the holder runs on (null if unrecorded or
+	// unreadable). PID only identifies a process on that host.
+	Host *string `json:"host"`
+
+	// CreatedAt is when the lock was taken (the lock file's mtime if unreadable).
+	CreatedAt time.Time `json:"created_at"`
+
+	// CreatedAtUnix is CreatedAt as Unix epoch seconds.
+	CreatedAtUnix *int64 `json:"created_at_unix"`
+
+	// AgeSeconds is how long the lock has been held.
+	AgeSeconds int64 `json:"age_seconds"`
+
+	// Stale is true if the holder is gone or the lock is older than the
+	// staleness window; the next mutating command takes it over.
+	Stale bool `json:"stale"`
+
+	// Path is the lock file path.
+	Path string `json:"path"`
+}
+
+// FormatLock renders a lock as "mv pid 4242 (alice@devbox), 3 mins ago",
+// with a " (stale)" suffix for stale locks.
+func FormatLock(l *LockJSON, now time.Time) string {
+	holder := "unknown holder"
+	if l.PID != nil {
+		holder = fmt.Sprintf("pid %d", *l.PID)
+		if l.Cmd != nil {
+			holder = *l.Cmd + " " + holder
+		}
+		if who := lockHolder(l); who != "" {
+			holder += " (" + who + ")"
+		}
+	}
+	s := holder + ", " + formatRelativeTime(l.CreatedAt, now)
+	if l.Stale {
+		s += " (stale)"
+	}
+	return s
+}
+
+// lockHolder renders the lock's user and host as "user@host", "user", or
+// "@host" ("" if neither was recorded).
+func lockHolder(l *LockJSON) string {
+	who := ""
+	if l.User != nil {
+		who = *l.User
+	}
+	if l.Host != nil {
+		who += "@" + *l.Host
+	}
+	return who
+}
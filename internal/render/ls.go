@@ -9,9 +9,14 @@ import (
 
 // Constants for human output formatting.
 const (
-	// TitleMaxLen is the maximum display length for title in human output.
+	// TitleMaxLen is the maximum display width (terminal columns) for
+	// title in human output.
 	TitleMaxLen = 50
 
+	// TitleMinLen is the width WriteLSHumanFit never shrinks the title
+	// column below, even if rows then overflow the terminal.
+	TitleMinLen = 12
+
 	// TitleBroken is displayed for broken runs.
 	TitleBroken = "<broken>"
 
@@ -27,40 +32,80 @@ type RunSummaryHumanRow struct {
 	Runner        string
 	CreatedAt     string
 	Status        string
+	StatusColor   string // ANSI color code for Status ("" = none)
+	Commits       string // "+ahead -behind"; empty unless ls --commits
 	PR            string
 }
 
 // WriteLSHuman writes the ls output in human-readable format.
 // Fields are separated by whitespace columns for easy scanning.
 func WriteLSHuman(w io.Writer, rows []RunSummaryHumanRow) error {
+	return WriteLSHumanFit(w, rows, 0)
+}
+
+// WriteLSHumanFit is WriteLSHuman for a terminal maxWidth columns wide (0 =
+// no limit): when rows would be wider, the title column shrinks (down to
+// TitleMinLen) and titles are truncated to it.
+func WriteLSHumanFit(w io.Writer, rows []RunSummaryHumanRow, maxWidth int) error {
 	if len(rows) == 0 {
 		return nil
 	}
 
-	// Calculate column widths
+	// The COMMITS column is shown only when some row has counts
+	// (ls --commits); runs without counts show "-"
+	showCommits := false
+	for _, row := range rows {
+		if row.Commits != "" {
+			showCommits = true
+		}
+	}
+	if showCommits {
+		for i := range rows {
+			if rows[i].Commits == "" {
+				rows[i].Commits = "-"
+			}
+		}
+	}
+
+	// Calculate column widths, shrinking the title to fit the terminal
 	widths := columnWidths(rows)
+	if maxWidth > 0 {
+		if over := widths.total() - maxWidth; over > 0 && widths.title > TitleMinLen {
+			widths.title = max(widths.title-over, TitleMinLen)
+			for i := range rows {
+				rows[i].Title = TruncateWidth(rows[i].Title, widths.title)
+			}
+		}
+	}
 
 	// Write header
+	commitsHeader := ""
+	if showCommits {
+		commitsHeader = "COMMITS"
+	}
 	header := formatRow(
 		"RUN_ID", widths.runID,
 		"TITLE", widths.title,
 		"RUNNER", widths.runner,
 		"CREATED", widths.createdAt,
 		"STATUS", widths.status,
+		commitsHeader, widths.commits,
 		"PR", widths.pr,
 	)
 	if _, err := fmt.Fprintln(w, header); err != nil {
 		return err
 	}
 
-	// Write rows
+	// Write rows (the status column is padded by display width: labels
+	// from the user config may hold emoji and color)
 	for _, row := range rows {
 		line := formatRow(
 			row.RunID, widths.runID,
 			row.Title, widths.title,
 			row.Runner, widths.runner,
 			row.CreatedAt, widths.createdAt,
-			row.Status, widths.status,
+			padColumn(row.Status, row.StatusColor, widths.status), widths.status,
+			row.Commits, widths.commits,
 			row.PR, widths.pr,
 		)
 		if _, err := fmt.Fprintln(w, line); err != nil {
@@ -78,6 +123,7 @@ type colWidths struct {
 	runner    int
 	createdAt int
 	status    int
+	commits   int // 0 hides the column
 	pr        int
 }
 
@@ -96,17 +142,23 @@ func columnWidths(rows []RunSummaryHumanRow) colWidths {
 		if len(row.RunID) > widths.runID {
 			widths.runID = len(row.RunID)
 		}
-		if len(row.Title) > widths.title {
-			widths.title = len(row.Title)
+		if w := DisplayWidth(row.Title); w > widths.title {
+			widths.title = w
 		}
-		if len(row.Runner) > widths.runner {
-			widths.runner = len(row.Runner)
+		if w := DisplayWidth(row.Runner); w > widths.runner {
+			widths.runner = w
 		}
 		if len(row.CreatedAt) > widths.createdAt {
 			widths.createdAt = len(row.CreatedAt)
 		}
-		if len(row.Status) > widths.status {
-			widths.status = len(row.Status)
+		if w := DisplayWidth(row.Status); w > widths.status {
+			widths.status = w
+		}
+		if row.Commits != "" && widths.commits < len("COMMITS") {
+			widths.commits = len("COMMITS")
+		}
+		if len(row.Commits) > widths.commits {
+			widths.commits = len(row.Commits)
 		}
 		if len(row.PR) > widths.pr {
 			widths.pr = len(row.PR)
@@ -116,20 +168,37 @@ func columnWidths(rows []RunSummaryHumanRow) colWidths {
 	return widths
 }
 
-// formatRow formats a row with the given column values and widths.
-func formatRow(runID string, runIDW int, title string, titleW int, runner string, runnerW int, created string, createdW int, status string, statusW int, pr string, prW int) string {
-	return fmt.Sprintf("%-*s  %-*s  %-*s  %-*s  %-*s  %s",
-		runIDW, runID,
-		titleW, title,
-		runnerW, runner,
-		createdW, created,
-		statusW, status,
-		pr,
-	)
+// total returns the width of the widest row: the columns and the two
+// spaces between each.
+func (c colWidths) total() int {
+	total := c.runID + c.title + c.runner + c.createdAt + c.status + c.pr + 5*2
+	if c.commits > 0 {
+		total += c.commits + 2
+	}
+	return total
 }
 
-// FormatHumanRow converts a RunSummary to a RunSummaryHumanRow for display.
-func FormatHumanRow(s RunSummary, now time.Time) RunSummaryHumanRow {
+// formatRow formats a row with the given column values and widths, padding
+// each column by display width (fmt's %-*s counts runes, so wide
+// characters would misalign it). The commits column is omitted when
+// commitsW is 0.
+func formatRow(runID string, runIDW int, title string, titleW int, runner string, runnerW int, created string, createdW int, status string, statusW int, commits string, commitsW int, pr string, prW int) string {
+	cols := []string{
+		padColumn(runID, "", runIDW),
+		padColumn(title, "", titleW),
+		padColumn(runner, "", runnerW),
+		padColumn(created, "", createdW),
+		padColumn(status, "", statusW),
+	}
+	if commitsW > 0 {
+		cols = append(cols, padColumn(commits, "", commitsW))
+	}
+	return strings.Join(append(cols, pr), "  ")
+}
+
+// FormatHumanRow converts a RunSummary to a RunSummaryHumanRow for display,
+// naming its status with vocab.
+func FormatHumanRow(s RunSummary, now time.Time, vocab StatusVocabulary) RunSummaryHumanRow {
 	row := RunSummaryHumanRow{
 		RunID: s.RunID,
 	}
@@ -154,7 +223,22 @@ func FormatHumanRow(s RunSummary, now time.Time) RunSummaryHumanRow {
 	}
 
 	// Format status with archived suffix
-	row.Status = formatStatus(s.DerivedStatus, s.Archived)
+	row.Status = formatStatus(vocab.Label(s.DerivedStatus), s.Archived)
+	row.StatusColor = vocab.colorCode(s.DerivedStatus)
+	if s.ReportStale {
+		row.Status += " (report stale)"
+	}
+	if s.NoChanges {
+		row.Status += " (no changes)"
+	}
+	if s.Lock != nil {
+		row.Status += " (locked: " + FormatLock(s.Lock, now) + ")"
+	}
+
+	// Format ahead/behind counts (ls --commits)
+	if s.Ahead != nil && s.Behind != nil {
+		row.Commits = fmt.Sprintf("+%d -%d", *s.Ahead, *s.Behind)
+	}
 
 	// Format PR
 	if s.PRNumber != nil {
@@ -164,14 +248,10 @@ func FormatHumanRow(s RunSummary, now time.Time) RunSummaryHumanRow {
 	return row
 }
 
-// truncateTitle truncates the title to TitleMaxLen, adding ellipsis if needed.
+// truncateTitle truncates the title to TitleMaxLen columns, adding ellipsis
+// if needed.
 func truncateTitle(title string) string {
-	// Count runes for proper Unicode handling
-	runes := []rune(title)
-	if len(runes) <= TitleMaxLen {
-		return title
-	}
-	return string(runes[:TitleMaxLen-1]) + "…"
+	return TruncateWidth(title, TitleMaxLen)
 }
 
 // formatStatus adds "(archived)" suffix if archived.
@@ -223,21 +303,18 @@ func formatRelativeTime(t time.Time, now time.Time) string {
 }
 
 // FormatHumanRows converts a slice of RunSummary to RunSummaryHumanRow.
-func FormatHumanRows(summaries []RunSummary, now time.Time) []RunSummaryHumanRow {
+func FormatHumanRows(summaries []RunSummary, now time.Time, vocab StatusVocabulary) []RunSummaryHumanRow {
 	rows := make([]RunSummaryHumanRow, len(summaries))
 	for i, s := range summaries {
-		rows[i] = FormatHumanRow(s, now)
+		rows[i] = FormatHumanRow(s, now, vocab)
 	}
 	return rows
 }
 
-// TruncateForDisplay is a helper to safely truncate any string for display.
+// TruncateForDisplay is a helper to safely truncate any string for display
+// to maxLen terminal columns (see TruncateWidth).
 func TruncateForDisplay(s string, maxLen int) string {
-	runes := []rune(s)
-	if len(runes) <= maxLen {
-		return s
-	}
-	return string(runes[:maxLen-1]) + "…"
+	return TruncateWidth(s, maxLen)
 }
 
 // JoinStrings joins non-empty strings with the given separator.
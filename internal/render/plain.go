@@ -0,0 +1,59 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Plain output is strictly line-oriented "key: value" text: no column
+// alignment, banners, or truncation, so screen readers and dumb terminals
+// read every line the same way. agency emits no colors or spinners, so
+// plain mode only changes layout.
+
+// writeSection starts a human output section: a blank line (unless first)
+// followed by a "=== name ===" banner. Plain output keeps the blank line as
+// the only separator.
+func writeSection(w io.Writer, name string, first, plain bool) {
+	if !first {
+		fmt.Fprintln(w)
+	}
+	if !plain {
+		fmt.Fprintf(w, "=== %s ===\n", name)
+	}
+}
+
+// WriteLSPlain writes ls output as one "key: value" block per run, blocks
+// separated by blank lines. Titles are not truncated; empty fields are omitted.
+// Statuses use vocab's labels, without emoji or color.
+func WriteLSPlain(w io.Writer, summaries []RunSummary, now time.Time, vocab StatusVocabulary) error {
+	vocab.Plain = true
+	for i, s := range summaries {
+		row := FormatHumanRow(s, now, vocab)
+		if !s.Broken && s.Title != "" {
+			row.Title = s.Title
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fields := []struct{ key, value string }{
+			{"run_id", row.RunID},
+			{"title", row.Title},
+			{"runner", row.Runner},
+			{"created", row.CreatedAt},
+			{"status", row.Status},
+			{"commits", row.Commits},
+			{"pr", row.PR},
+		}
+		for _, f := range fields {
+			if f.value == "" {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s: %s\n", f.key, f.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/status"
+)
+
+// ReportRun is one run in an agency report digest.
+type ReportRun struct {
+	RunID    string
+	Repo     string // repo_key, or repo_id if repo.json is missing
+	Title    string
+	Branch   string
+	Status   string // derived status
+	PRNumber int    // 0 if no PR
+	PRURL    string
+	Setup    string // setup outcome, e.g. "ok" or "failed (exit 1)"
+	Verify   string // verify outcome, e.g. "ran 2026-01-10" or "not run"
+	Summary  string // first paragraph of report.md ("" if none)
+}
+
+// ReportDigest is the input to the report writers: runs grouped by repo, then status.
+type ReportDigest struct {
+	GeneratedAt time.Time
+	Since       *time.Time // nil if no --since window
+	RunCount    int
+	Repos       []ReportRepo
+}
+
+// ReportRepo holds one repo's runs grouped by status.
+type ReportRepo struct {
+	Name   string
+	Groups []ReportStatusGroup
+}
+
+// ReportStatusGroup holds the runs sharing a derived status.
+type ReportStatusGroup struct {
+	Status string
+	Runs   []ReportRun
+}
+
+// reportStatusOrder lists statuses in digest order: finished work first, then
+// work needing review or attention, then work in progress.
+var reportStatusOrder = []string{
+	status.StatusMerged,
+	status.StatusReadyForReview,
+	status.StatusNeedsAttention,
+	status.StatusFailed,
+	status.StatusActivePR,
+	status.StatusIdlePR,
+	status.StatusActive,
+	status.StatusIdle,
+	status.StatusAbandoned,
+}
+
+// NewReportDigest groups runs by repo (sorted by name) and status (in
+// reportStatusOrder; unknown statuses last). Runs keep their input order
+// within a group.
+func NewReportDigest(runs []ReportRun, generatedAt time.Time, since *time.Time) ReportDigest {
+	rank := make(map[string]int, len(reportStatusOrder))
+	for i, s := range reportStatusOrder {
+		rank[s] = i
+	}
+	statusRank := func(s string) int {
+		if r, ok := rank[s]; ok {
+			return r
+		}
+		return len(reportStatusOrder)
+	}
+
+	byRepo := make(map[string]map[string][]ReportRun)
+	for _, run := range runs {
+		if byRepo[run.Repo] == nil {
+			byRepo[run.Repo] = make(map[string][]ReportRun)
+		}
+		byRepo[run.Repo][run.Status] = append(byRepo[run.Repo][run.Status], run)
+	}
+
+	digest := ReportDigest{GeneratedAt: generatedAt, Since: since, RunCount: len(runs)}
+	for _, name := range sortedKeysOf(byRepo) {
+		repo := ReportRepo{Name: name}
+		statuses := sortedKeysOf(byRepo[name])
+		sort.SliceStable(statuses, func(i, j int) bool { return statusRank(statuses[i]) < statusRank(statuses[j]) })
+		for _, s := range statuses {
+			repo.Groups = append(repo.Groups, ReportStatusGroup{Status: s, Runs: byRepo[name][s]})
+		}
+		digest.Repos = append(digest.Repos, repo)
+	}
+	return digest
+}
+
+func sortedKeysOf[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reportHeadline is the one-line description under the digest title.
+func reportHeadline(d ReportDigest) string {
+	line := fmt.Sprintf("generated %s · %d run(s)", d.GeneratedAt.UTC().Format("2006-01-02 15:04 MST"), d.RunCount)
+	if d.Since != nil {
+		line += " with activity since " + d.Since.UTC().Format("2006-01-02")
+	}
+	return line
+}
+
+// WriteReportMarkdown writes the digest as Markdown.
+func WriteReportMarkdown(w io.Writer, d ReportDigest) error {
+	var b strings.Builder
+	b.WriteString("# agency report\n\n")
+	fmt.Fprintf(&b, "_%s_\n", reportHeadline(d))
+	if len(d.Repos) == 0 {
+		b.WriteString("\nno runs.\n")
+	}
+
+	for _, repo := range d.Repos {
+		fmt.Fprintf(&b, "\n## %s\n", markdownEscape(repo.Name))
+		for _, group := range repo.Groups {
+			fmt.Fprintf(&b, "\n### %s (%d)\n\n", group.Status, len(group.Runs))
+			for _, run := range group.Runs {
+				fmt.Fprintf(&b, "- **%s** (`%s`)", markdownEscape(run.Title), run.RunID)
+				if run.PRNumber != 0 {
+					if run.PRURL != "" {
+						fmt.Fprintf(&b, " — [#%d](%s)", run.PRNumber, run.PRURL)
+					} else {
+						fmt.Fprintf(&b, " — #%d", run.PRNumber)
+					}
+				}
+				b.WriteString("\n")
+				fmt.Fprintf(&b, "  - setup: %s · verify: %s\n", run.Setup, run.Verify)
+				if run.Summary != "" {
+					fmt.Fprintf(&b, "  - %s\n", markdownEscape(run.Summary))
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownReplacer escapes characters that would change inline Markdown rendering.
+var markdownReplacer = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`,
+)
+
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"headline": reportHeadline,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>agency report</title>
+</head>
+<body>
+<h1>agency report</h1>
+<p><em>{{headline .}}</em></p>
+{{- if not .Repos}}
+<p>no runs.</p>
+{{- end}}
+{{- range .Repos}}
+<h2>{{.Name}}</h2>
+{{- range .Groups}}
+<h3>{{.Status}} ({{len .Runs}})</h3>
+<ul>
+{{- range .Runs}}
+<li><strong>{{.Title}}</strong> (<code>{{.RunID}}</code>)
+{{- if .PRNumber}} — {{if .PRURL}}<a href="{{.PRURL}}">#{{.PRNumber}}</a>{{else}}#{{.PRNumber}}{{end}}{{end}}
+<ul>
+<li>setup: {{.Setup}} · verify: {{.Verify}}</li>
+{{- if .Summary}}
+<li>{{.Summary}}</li>
+{{- end}}
+</ul>
+</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteReportHTML writes the digest as a standalone HTML page.
+func WriteReportHTML(w io.Writer, d ReportDigest) error {
+	return reportHTMLTemplate.Execute(w, d)
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// RunCreated is the summary printed after agency run succeeds.
+// This is the public contract for run --json output.
+type RunCreated struct {
+	// RunID is the new run's identifier.
+	RunID string `json:"run_id"`
+
+	// Title is the resolved run title.
+	Title string `json:"title"`
+
+	// Runner is the runner name.
+	Runner string `json:"runner"`
+
+	// Parent is the parent branch the run was created from.
+	Parent string `json:"parent"`
+
+	// Branch is the run's branch.
+	Branch string `json:"branch"`
+
+	// WorktreePath is the absolute path to the run's worktree.
+	WorktreePath string `json:"worktree_path"`
+
+	// TmuxSession is the tmux session name (null if tmux was skipped).
+	TmuxSession *string `json:"tmux_session"`
+
+	// AttachCommand attaches to the session without agency (null if tmux was skipped).
+	AttachCommand *string `json:"attach_command"`
+
+	// SkippedSteps are meta.skipped_steps ([] if none).
+	SkippedSteps []string `json:"skipped_steps"`
+
+	// Warnings are non-fatal pipeline warnings ([] if none).
+	Warnings []RunWarning `json:"warnings"`
+
+	// Next are suggested follow-up commands, most useful first.
+	Next []NextStep `json:"next"`
+}
+
+// RunWarning is a non-fatal warning emitted while creating a run.
+type RunWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NextStep is a suggested follow-up command.
+type NextStep struct {
+	// Command is the full command line, e.g. "agency show <run_id>".
+	Command string `json:"command"`
+
+	// Description says what the command is for.
+	Description string `json:"description"`
+}
+
+// RunCreatedJSONEnvelope is the stable JSON output format for run --json.
+type RunCreatedJSONEnvelope struct {
+	SchemaVersion string     `json:"schema_version"`
+	Data          RunCreated `json:"data"`
+}
+
+// WriteRunCreatedJSON writes the run summary as JSON.
+func WriteRunCreatedJSON(w io.Writer, run RunCreated) error {
+	if run.SkippedSteps == nil {
+		run.SkippedSteps = []string{}
+	}
+	if run.Warnings == nil {
+		run.Warnings = []RunWarning{}
+	}
+	if run.Next == nil {
+		run.Next = []NextStep{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(RunCreatedJSONEnvelope{SchemaVersion: "1.0", Data: run})
+}
@@ -0,0 +1,102 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// SetupEnvJSONEnvelope is the stable JSON output format for show --setup-env --json.
+type SetupEnvJSONEnvelope struct {
+	SchemaVersion string          `json:"schema_version"`
+	Data          *store.SetupEnv `json:"data"` // nullable on error
+}
+
+// WriteSetupEnvJSON writes a captured setup environment as JSON.
+func WriteSetupEnvJSON(w io.Writer, env *store.SetupEnv) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(SetupEnvJSONEnvelope{SchemaVersion: "1.0", Data: env})
+}
+
+// WriteSetupEnvHuman writes a captured setup environment for show --setup-env.
+// plain omits the section banners.
+func WriteSetupEnvHuman(w io.Writer, runID string, env *store.SetupEnv, plain bool) error {
+	writeSection(w, "setup env", true, plain)
+	fmt.Fprintf(w, "run_id: %s\n", runID)
+	fmt.Fprintf(w, "captured_at: %s\n", env.CapturedAt)
+	fmt.Fprintf(w, "hostname: %s\n", env.Hostname)
+	fmt.Fprintf(w, "os: %s/%s\n", env.OS, env.Arch)
+	fmt.Fprintf(w, "script_path: %s\n", orUnavailable(env.ScriptPath))
+	if env.ShellVersion != "" {
+		fmt.Fprintf(w, "shell_version: %s\n", env.ShellVersion)
+	}
+
+	writeSection(w, "tools", false, plain)
+	for _, name := range sortedKeys(env.Tools) {
+		fmt.Fprintf(w, "%s: %s\n", name, orUnavailable(env.Tools[name]))
+	}
+
+	writeSection(w, "env", false, plain)
+	for _, name := range sortedKeys(env.Env) {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", name, env.Env[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSetupEnvDiff writes the differences between two runs' setup environments.
+// hidden is the number of run-specific keys left out of diffs.
+func WriteSetupEnvDiff(w io.Writer, runA, runB string, a, b *store.SetupEnv, diffs []store.SetupEnvDiff, hidden int) error {
+	fmt.Fprintf(w, "a: %s (%s, captured %s)\n", runA, a.Hostname, a.CapturedAt)
+	fmt.Fprintf(w, "b: %s (%s, captured %s)\n", runB, b.Hostname, b.CapturedAt)
+
+	for _, d := range diffs {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, d.Key)
+		fmt.Fprintf(w, "  a: %s\n", diffValue(d.Key, d.A, d.MissingA))
+		fmt.Fprintf(w, "  b: %s\n", diffValue(d.Key, d.B, d.MissingB))
+	}
+
+	fmt.Fprintln(w)
+	summary := fmt.Sprintf("%d difference(s)", len(diffs))
+	if len(diffs) == 0 {
+		summary = "no differences"
+	}
+	if hidden > 0 {
+		summary += fmt.Sprintf(" (%d run-specific key(s) hidden; use --all)", hidden)
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
+
+func diffValue(key, value string, missing bool) string {
+	if missing {
+		return "(unset)"
+	}
+	if strings.HasPrefix(key, "tools.") {
+		return orUnavailable(value)
+	}
+	return value
+}
+
+func orUnavailable(version string) string {
+	if version == "" {
+		return "(unavailable)"
+	}
+	return version
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -6,6 +6,14 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/checkpoint"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/store"
 )
 
 // ShowPathsData holds the paths for --path output.
@@ -26,9 +34,16 @@ type ShowHumanData struct {
 	Title     string
 	Runner    string
 	CreatedAt string // RFC3339
+	CreatedBy string // may be empty
 	RepoID    string
 	RepoKey   string // may be empty
 	OriginURL string // may be empty
+	Labels    map[string]string
+	Group     string // may be empty
+
+	SkippedSteps []string                      // meta.skipped_steps (run --no-setup/--no-tmux)
+	Credentials  *store.RunMetaCredentials     // runner GITHUB_TOKEN source (nil = inherit)
+	Probes       map[string]store.RunMetaProbe // latest agency watch probe results
 
 	// Git/workspace
 	ParentBranch    string
@@ -38,6 +53,8 @@ type ShowHumanData struct {
 	TmuxSessionName string
 	TmuxActive      bool
 
+	Worktree *store.RunMetaWorktree // creation timing + sparse patterns (nil if not recorded)
+
 	// PR (may be zero values)
 	PRNumber   int
 	PRURL      string
@@ -47,20 +64,53 @@ type ShowHumanData struct {
 	ReportPath   string
 	ReportExists bool
 	ReportBytes  int
+	ReportCommit string // may be empty if not recorded
+	ReportStale  bool
+
+	// Setup structured output from setup.json (nil if absent)
+	SetupOutput *store.RunMetaSetup
 
 	// Logs
 	SetupLogPath   string
 	VerifyLogPath  string
 	ArchiveLogPath string
+	LogsBytes      int64 // on-disk size of all logs (post-compression)
+
+	// Notes (in recorded order)
+	Notes []store.RunNote
+
+	// VerifyHistory is the run's verify attempts (oldest first)
+	VerifyHistory []store.VerifyAttempt
+
+	// Checkpoints (oldest first)
+	Checkpoints []checkpoint.Checkpoint
 
 	// Derived
-	DerivedStatus string
-	Archived      bool
+	DerivedStatus   string
+	AttentionReason string   // empty unless agency derived "needs attention"
+	Reasons         []string // predicates behind DerivedStatus (show --explain only)
+	Vocabulary      StatusVocabulary
+	NoChanges       bool // no commits beyond the parent branch and an empty report
+	Archived        bool
+
+	// Deadline is the run's time box (nil if none)
+	Deadline *store.RunMetaDeadline
+
+	// Lock is the repo lock while a mutating command holds it (nil if none)
+	Lock *LockJSON
+	Now  time.Time // reference time for the lock's age
 
 	// Warnings
 	RepoNotFoundWarning     bool
 	WorktreeMissingWarning  bool
 	TmuxUnavailableWarning  bool
+
+	// EnvWarnings are differences between the environment the run's
+	// scripts ran with and agency doctor's (see setup_env.json)
+	EnvWarnings []string
+
+	// Plain omits the "=== section ===" banners (see WriteLSPlain).
+	Plain bool
 }
 
 // WriteShowPaths writes --path output in the locked format.
@@ -112,11 +162,14 @@ func WriteShowHuman(w io.Writer, data ShowHumanData) error {
 	}
 
 	// === HEADER / CORE ===
-	fmt.Fprintln(w, "=== run ===")
+	writeSection(w, "run", true, data.Plain)
 	fmt.Fprintf(w, "run_id: %s\n", data.RunID)
 	fmt.Fprintf(w, "title: %s\n", displayTitle)
 	fmt.Fprintf(w, "runner: %s\n", data.Runner)
 	fmt.Fprintf(w, "created_at: %s\n", data.CreatedAt)
+	if data.CreatedBy != "" {
+		fmt.Fprintf(w, "created_by: %s\n", data.CreatedBy)
+	}
 	fmt.Fprintf(w, "repo_id: %s\n", data.RepoID)
 	if data.RepoKey != "" {
 		fmt.Fprintf(w, "repo_key: %s\n", data.RepoKey)
@@ -124,21 +177,54 @@ func WriteShowHuman(w io.Writer, data ShowHumanData) error {
 	if data.OriginURL != "" {
 		fmt.Fprintf(w, "origin_url: %s\n", data.OriginURL)
 	}
+	if len(data.Labels) > 0 {
+		fmt.Fprintf(w, "labels: %s\n", core.FormatLabels(data.Labels))
+	}
+	if data.Group != "" {
+		fmt.Fprintf(w, "group: %s\n", data.Group)
+	}
+	if len(data.SkippedSteps) > 0 {
+		fmt.Fprintf(w, "skipped_steps: %s\n", strings.Join(data.SkippedSteps, ", "))
+	}
+	if data.Credentials != nil {
+		creds := data.Credentials.Mode
+		if data.Credentials.ExpiresAt != "" {
+			creds += " (token expires " + data.Credentials.ExpiresAt + ")"
+		}
+		fmt.Fprintf(w, "credentials: %s\n", creds)
+	}
+	if len(data.Probes) > 0 {
+		fmt.Fprintf(w, "probes: %s\n", formatProbes(data.Probes))
+	}
 
 	// === GIT/WORKSPACE ===
-	fmt.Fprintln(w)
-	fmt.Fprintln(w, "=== workspace ===")
+	writeSection(w, "workspace", false, data.Plain)
 	fmt.Fprintf(w, "parent_branch: %s\n", data.ParentBranch)
 	fmt.Fprintf(w, "branch: %s\n", data.Branch)
 	fmt.Fprintf(w, "worktree_path: %s\n", data.WorktreePath)
 	fmt.Fprintf(w, "worktree_present: %s\n", yesNo(data.WorktreePresent))
+	if data.Worktree != nil {
+		fmt.Fprintf(w, "worktree_create_ms: %d\n", data.Worktree.DurationMs)
+		if data.Worktree.SparseProfile != "" {
+			fmt.Fprintf(w, "sparse_profile: %s\n", data.Worktree.SparseProfile)
+		}
+		if len(data.Worktree.SparseCheckout) > 0 {
+			fmt.Fprintf(w, "sparse_checkout: %s\n", strings.Join(data.Worktree.SparseCheckout, " "))
+		}
+		if len(data.Worktree.Submodules) > 0 {
+			initialized := "not initialized"
+			if data.Worktree.SubmodulesInitialized {
+				initialized = "initialized"
+			}
+			fmt.Fprintf(w, "submodules: %s (%s)\n", strings.Join(data.Worktree.Submodules, " "), initialized)
+		}
+	}
 	fmt.Fprintf(w, "tmux_session_name: %s\n", data.TmuxSessionName)
 	fmt.Fprintf(w, "tmux_active: %s\n", yesNo(data.TmuxActive))
 
 	// === PR (if present) ===
 	if data.PRNumber != 0 || data.PRURL != "" {
-		fmt.Fprintln(w)
-		fmt.Fprintln(w, "=== pr ===")
+		writeSection(w, "pr", false, data.Plain)
 		if data.PRNumber != 0 {
 			fmt.Fprintf(w, "pr_number: %d\n", data.PRNumber)
 		}
@@ -151,30 +237,92 @@ func WriteShowHuman(w io.Writer, data ShowHumanData) error {
 	}
 
 	// === REPORT ===
-	fmt.Fprintln(w)
-	fmt.Fprintln(w, "=== report ===")
+	writeSection(w, "report", false, data.Plain)
 	fmt.Fprintf(w, "report_path: %s\n", data.ReportPath)
 	fmt.Fprintf(w, "report_exists: %s\n", yesNo(data.ReportExists))
 	fmt.Fprintf(w, "report_bytes: %d\n", data.ReportBytes)
+	if data.ReportCommit != "" {
+		fmt.Fprintf(w, "report_commit: %s\n", data.ReportCommit)
+	}
+	fmt.Fprintf(w, "report_stale: %s\n", yesNo(data.ReportStale))
+
+	// === SETUP (if setup.json reported anything) ===
+	if hasSetupOutput(data.SetupOutput) {
+		writeSection(w, "setup", false, data.Plain)
+		writeSetupOutput(w, data.SetupOutput, data.Plain)
+	}
 
 	// === LOGS ===
-	fmt.Fprintln(w)
-	fmt.Fprintln(w, "=== logs ===")
+	writeSection(w, "logs", false, data.Plain)
 	fmt.Fprintf(w, "setup_log: %s\n", data.SetupLogPath)
+	if setup := data.SetupOutput; setup != nil && setup.CacheKey != "" {
+		if setup.CacheHit {
+			fmt.Fprintf(w, "setup_cache: hit (key %s, from run %s)\n", setup.CacheKey, setup.CacheRunID)
+		} else {
+			fmt.Fprintf(w, "setup_cache: miss (key %s)\n", setup.CacheKey)
+		}
+	}
 	fmt.Fprintf(w, "verify_log: %s\n", data.VerifyLogPath)
 	fmt.Fprintf(w, "archive_log: %s\n", data.ArchiveLogPath)
+	fmt.Fprintf(w, "logs_bytes: %d\n", data.LogsBytes)
+
+	// === VERIFY (if attempted) ===
+	if len(data.VerifyHistory) > 0 {
+		writeSection(w, "verify", false, data.Plain)
+		writeVerifyHistory(w, data.VerifyHistory, data.Plain)
+	}
+
+	// === NOTES (if present) ===
+	if len(data.Notes) > 0 {
+		writeSection(w, "notes", false, data.Plain)
+		for _, note := range data.Notes {
+			// Indent continuation lines so multi-line notes stay grouped
+			text := strings.ReplaceAll(note.Text, "\n", "\n  ")
+			fmt.Fprintf(w, "%s: %s\n", note.Timestamp, text)
+		}
+	}
+
+	// === CHECKPOINTS (if present) ===
+	if len(data.Checkpoints) > 0 {
+		writeSection(w, "checkpoints", false, data.Plain)
+		for _, cp := range data.Checkpoints {
+			fmt.Fprintf(w, "%d: %s\n", cp.Number, formatCheckpoint(cp))
+		}
+	}
 
 	// === DERIVED ===
-	fmt.Fprintln(w)
-	fmt.Fprintln(w, "=== status ===")
-	statusDisplay := formatStatus(data.DerivedStatus, data.Archived)
+	writeSection(w, "status", false, data.Plain)
+	vocab := data.Vocabulary
+	vocab.Plain = vocab.Plain || data.Plain
+	statusDisplay := withColor(vocab.colorCode(data.DerivedStatus), formatStatus(vocab.Label(data.DerivedStatus), data.Archived))
 	fmt.Fprintf(w, "derived_status: %s\n", statusDisplay)
+	if data.AttentionReason != "" {
+		fmt.Fprintf(w, "attention_reason: %s\n", data.AttentionReason)
+	}
+	if len(data.Reasons) > 0 {
+		fmt.Fprintln(w, "reasons:")
+		for _, r := range data.Reasons {
+			fmt.Fprintf(w, "  - %s\n", r)
+		}
+	}
+	if data.NoChanges {
+		fmt.Fprintln(w, "no_changes: yes")
+	}
+	if data.Deadline != nil {
+		deadline := data.Deadline.At
+		if data.Deadline.Kill {
+			deadline += " (kill)"
+		}
+		fmt.Fprintf(w, "deadline: %s\n", deadline)
+	}
+	if data.Lock != nil {
+		fmt.Fprintf(w, "lock: %s\n", FormatLock(data.Lock, data.Now))
+	}
 	fmt.Fprintf(w, "archived: %s\n", yesNo(data.Archived))
 
 	// === WARNINGS ===
-	if data.RepoNotFoundWarning || data.WorktreeMissingWarning || data.TmuxUnavailableWarning {
-		fmt.Fprintln(w)
-		fmt.Fprintln(w, "=== warnings ===")
+	if data.RepoNotFoundWarning || data.WorktreeMissingWarning || data.TmuxUnavailableWarning || data.ReportStale || len(data.EnvWarnings) > 0 {
+		writeSection(w, "warnings", false, data.Plain)
 		if data.RepoNotFoundWarning {
 			fmt.Fprintln(w, "warning: repo not found on disk")
 		}
@@ -184,18 +332,192 @@ func WriteShowHuman(w io.Writer, data ShowHumanData) error {
 		if data.TmuxUnavailableWarning {
 			fmt.Fprintln(w, "warning: tmux unavailable; tmux_active=false")
 		}
+		if data.ReportStale {
+			fmt.Fprintln(w, "warning: report stale; branch has commits newer than report_commit")
+		}
+		for _, warning := range data.EnvWarnings {
+			fmt.Fprintf(w, "warning: %s\n", warning)
+		}
 	}
 
 	return nil
 }
 
+// formatCheckpoint renders a checkpoint as
+// "2026-01-10T12:00:00Z main@1a2b3c4d +changes +3 untracked (message)".
+func formatCheckpoint(cp checkpoint.Checkpoint) string {
+	head := cp.Head
+	if len(head) > 8 {
+		head = head[:8]
+	}
+	s := cp.CreatedAt + " " + cp.Branch + "@" + head
+	if cp.Stash != "" {
+		s += " +changes"
+	}
+	if cp.UntrackedFiles > 0 {
+		s += fmt.Sprintf(" +%d untracked", cp.UntrackedFiles)
+	}
+	if cp.Message != "" {
+		s += " (" + cp.Message + ")"
+	}
+	return s
+}
+
 // ResolveScriptLogPaths resolves the log paths for setup/verify/archive scripts.
-// Uses the canonical s1 log path format: <run_dir>/logs/<script>.log
+// Uses the canonical s1 log path format: <run_dir>/logs/<script>.log, or
+// <script>.log.gz once agency gc has compressed it.
 // Returns absolute paths even if files don't exist (for display purposes).
 func ResolveScriptLogPaths(runDir string) (setup, verify, archive string) {
 	logsDir := filepath.Join(runDir, "logs")
-	setup = filepath.Join(logsDir, "setup.log")
-	verify = filepath.Join(logsDir, "verify.log")
-	archive = filepath.Join(logsDir, "archive.log")
+	setup = store.ResolveLogPath(filepath.Join(logsDir, "setup.log"))
+	verify = store.ResolveLogPath(filepath.Join(logsDir, "verify.log"))
+	archive = store.ResolveLogPath(filepath.Join(logsDir, "archive.log"))
 	return
 }
+
+// hasSetupOutput reports whether setup has structured setup.json output to show.
+func hasSetupOutput(setup *store.RunMetaSetup) bool {
+	return setup != nil && (setup.OutputOk != nil || setup.OutputSummary != "" ||
+		len(setup.OutputChecks) > 0 || len(setup.OutputArtifacts) > 0 || len(setup.OutputWarnings) > 0 ||
+		len(setup.OutputErrors) > 0)
+}
+
+// writeSetupOutput writes setup.json results: ok/summary, a checks table
+// (one "check:" line per check in plain mode), artifacts, warnings, and
+// schema violations.
+func writeSetupOutput(w io.Writer, setup *store.RunMetaSetup, plain bool) {
+	if setup.OutputOk != nil {
+		fmt.Fprintf(w, "setup_ok: %t\n", *setup.OutputOk)
+	}
+	if setup.OutputSummary != "" {
+		fmt.Fprintf(w, "setup_summary: %s\n", setup.OutputSummary)
+	}
+
+	if len(setup.OutputChecks) > 0 {
+		if plain {
+			for _, c := range setup.OutputChecks {
+				line := c.Name + " " + checkResult(c.Ok)
+				if c.DurationMs > 0 {
+					line += " " + formatCheckDuration(c.DurationMs)
+				}
+				if c.Details != "" {
+					line += " - " + c.Details
+				}
+				fmt.Fprintf(w, "check: %s\n", line)
+			}
+		} else {
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "CHECK\tRESULT\tDURATION\tDETAILS")
+			for _, c := range setup.OutputChecks {
+				duration := "-"
+				if c.DurationMs > 0 {
+					duration = formatCheckDuration(c.DurationMs)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Name, checkResult(c.Ok), duration, c.Details)
+			}
+			_ = tw.Flush()
+		}
+	}
+
+	for _, a := range setup.OutputArtifacts {
+		fmt.Fprintf(w, "artifact: %s\n", a)
+	}
+	for _, warning := range setup.OutputWarnings {
+		fmt.Fprintf(w, "setup_warning: %s\n", warning)
+	}
+	if f := setup.OutputFile; f != nil {
+		sum := f.SHA256
+		if len(sum) > 12 {
+			sum = sum[:12]
+		}
+		fmt.Fprintf(w, "setup_output: %s (sha256 %s, modified %s)\n", f.Path, sum, f.ModifiedAt)
+	}
+	for _, e := range setup.OutputErrors {
+		fmt.Fprintf(w, "setup_output_error: %s\n", e)
+	}
+}
+
+// verifyTrendLength is how many recent attempts the verify trend shows.
+const verifyTrendLength = 10
+
+// writeVerifyHistory writes a summary of the verify attempts: pass/fail
+// counts, the trend of the recent attempts (oldest first; P/F in plain
+// mode), whether they are flaky, and the last attempt.
+func writeVerifyHistory(w io.Writer, history []store.VerifyAttempt, plain bool) {
+	passed := 0
+	for _, a := range history {
+		if a.OK {
+			passed++
+		}
+	}
+	fmt.Fprintf(w, "verify_attempts: %d (%d passed, %d failed)\n", len(history), passed, len(history)-passed)
+
+	recent := history
+	if len(recent) > verifyTrendLength {
+		recent = recent[len(recent)-verifyTrendLength:]
+	}
+	var trend strings.Builder
+	recentPassed := 0
+	for _, a := range recent {
+		switch {
+		case a.OK && plain:
+			trend.WriteString("P")
+		case a.OK:
+			trend.WriteString("✓")
+		case plain:
+			trend.WriteString("F")
+		default:
+			trend.WriteString("✗")
+		}
+		if a.OK {
+			recentPassed++
+		}
+	}
+	fmt.Fprintf(w, "verify_trend: %s (last %d, oldest first)\n", trend.String(), len(recent))
+	if recentPassed > 0 && recentPassed < len(recent) {
+		fmt.Fprintln(w, "verify_flaky: yes")
+	}
+
+	last := history[len(history)-1]
+	line := checkResult(last.OK) + " " + last.Timestamp
+	if last.DurationMs > 0 {
+		line += " " + formatCheckDuration(last.DurationMs)
+	}
+	if last.Summary != "" {
+		line += " - " + last.Summary
+	}
+	fmt.Fprintf(w, "verify_last: %s\n", line)
+}
+
+// checkResult renders a check's ok flag.
+func checkResult(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "FAIL"
+}
+
+// formatCheckDuration renders a check duration, e.g. "5.3s" or "250ms".
+func formatCheckDuration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// formatProbes renders probe results by name, e.g.
+// "build FAIL (2 in a row), dev ok".
+func formatProbes(probes map[string]store.RunMetaProbe) string {
+	names := make([]string, 0, len(probes))
+	for name := range probes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		p := probes[name]
+		part := name + " " + checkResult(p.OK)
+		if !p.OK && p.ConsecutiveFailures > 1 {
+			part += fmt.Sprintf(" (%d in a row)", p.ConsecutiveFailures)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
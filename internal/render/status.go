@@ -0,0 +1,66 @@
+package render
+
+import (
+	"github.com/NielsdaWheelz/agency/internal/config"
+)
+
+// StatusVocabulary is how human output names derived statuses: the user
+// config "statuses" mapping. The zero value shows the derived status names.
+// JSON, --format, and stream output never use it.
+type StatusVocabulary struct {
+	// Statuses maps derived statuses to their display (nil = none).
+	Statuses map[string]config.StatusDisplay
+
+	// Plain shows labels only, without emoji or color.
+	Plain bool
+
+	// Color wraps labels in ANSI colors (set only for color terminals).
+	Color bool
+}
+
+// ansiColors maps config.StatusColors to ANSI SGR codes.
+var ansiColors = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"gray":    "90",
+}
+
+// Label returns the display text for status: its label (or the status
+// name), preceded by its emoji unless Plain. It carries no color.
+func (v StatusVocabulary) Label(status string) string {
+	d, ok := v.Statuses[status]
+	if !ok {
+		return status
+	}
+	label := status
+	if d.Label != "" {
+		label = d.Label
+	}
+	if d.Emoji != "" && !v.Plain {
+		label = d.Emoji + " " + label
+	}
+	return label
+}
+
+// colorCode returns the ANSI code for status's color, or "" when it has none
+// or Color is off.
+func (v StatusVocabulary) colorCode(status string) string {
+	if !v.Color || v.Plain {
+		return ""
+	}
+	return ansiColors[v.Statuses[status].Color]
+}
+
+// withColor wraps text in the ANSI color code ("" = none).
+func withColor(code, text string) string {
+	if code == "" {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
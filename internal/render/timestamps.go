@@ -0,0 +1,75 @@
+package render
+
+import "time"
+
+// JSON output carries each timestamp twice: the RFC3339 string under its
+// usual key and Unix epoch seconds under "<key>_unix". The epoch fields are
+// derived here, when an envelope is written, so every command emits them the
+// same way and callers only ever set the RFC3339 value.
+
+// unixTime returns t as Unix epoch seconds (nil if t is nil).
+func unixTime(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	secs := t.Unix()
+	return &secs
+}
+
+// unixRFC3339 returns the RFC3339 timestamp s as Unix epoch seconds (nil if
+// s is empty or not RFC3339).
+func unixRFC3339(s string) *int64 {
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return unixTime(&t)
+}
+
+// withUnixTimestamps returns s with its _unix fields derived from CreatedAt
+// and LastPushAt.
+func (s RunSummary) withUnixTimestamps() RunSummary {
+	s.CreatedAtUnix = unixTime(s.CreatedAt)
+	s.LastPushAtUnix = unixTime(s.LastPushAt)
+	s.Lock = s.Lock.withUnixTimestamps()
+	return s
+}
+
+// withUnixTimestamps returns a copy of d with its _unix fields derived from
+// meta.json (nil if d is nil).
+func (d *RunDetail) withUnixTimestamps() *RunDetail {
+	if d == nil {
+		return nil
+	}
+	out := *d
+	out.CreatedAtUnix, out.LastPushAtUnix = nil, nil
+	if d.Meta != nil {
+		out.CreatedAtUnix = unixRFC3339(d.Meta.CreatedAt)
+		out.LastPushAtUnix = unixRFC3339(d.Meta.LastPushAt)
+	}
+	out.Derived.Lock = d.Derived.Lock.withUnixTimestamps()
+	return &out
+}
+
+// withUnixTimestamps returns g with CreatedAtUnix derived from CreatedAt.
+func (g GroupSummary) withUnixTimestamps() GroupSummary {
+	g.CreatedAtUnix = nil
+	if g.CreatedAt != nil {
+		g.CreatedAtUnix = unixRFC3339(*g.CreatedAt)
+	}
+	return g
+}
+
+// withUnixTimestamps returns a copy of l with CreatedAtUnix derived from
+// CreatedAt (nil if l is nil).
+func (l *LockJSON) withUnixTimestamps() *LockJSON {
+	if l == nil {
+		return nil
+	}
+	out := *l
+	out.CreatedAtUnix = unixTime(&out.CreatedAt)
+	return &out
+}
@@ -0,0 +1,52 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Verify results in VerifyRow.Result.
+const (
+	VerifyPass  = "pass"
+	VerifyFail  = "fail"
+	VerifyError = "error" // verify could not run (no worktree, no scripts.verify, ...)
+)
+
+// VerifyRow is one run in the summary of a bulk verify.
+type VerifyRow struct {
+	RunID      string
+	Repo       string // repo_key, or repo_id if repo.json is missing
+	Result     string // VerifyPass, VerifyFail, or VerifyError
+	DurationMs int64
+	Detail     string // verify summary, or the error for VerifyError
+	LogPath    string // "" if the script did not run
+}
+
+// WriteVerifySummary writes the bulk verify summary table, one row per run
+// in the given order, followed by the totals line.
+func WriteVerifySummary(w io.Writer, rows []VerifyRow) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN_ID\tREPO\tRESULT\tDURATION\tDETAIL\tLOG")
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.Result]++
+		duration, detail, logPath := "-", row.Detail, row.LogPath
+		if row.Result != VerifyError {
+			duration = formatCheckDuration(row.DurationMs)
+		}
+		if detail == "" {
+			detail = "-"
+		}
+		if logPath == "" {
+			logPath = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", row.RunID, row.Repo, row.Result, duration, detail, logPath)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nverified %d run(s): %d passed, %d failed, %d could not run\n",
+		len(rows), counts[VerifyPass], counts[VerifyFail], counts[VerifyError])
+	return err
+}
@@ -0,0 +1,79 @@
+package render
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Ellipsis marks truncated text; it takes one column.
+const Ellipsis = "…"
+
+// DisplayWidth approximates the terminal columns s takes: one per rune,
+// two for wide symbols (emoji, CJK), none for joiners, variation selectors,
+// and combining marks.
+func DisplayWidth(s string) int {
+	if isASCII(s) {
+		return len(s)
+	}
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth returns the terminal columns r takes (see DisplayWidth).
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x200D || (r >= 0xFE00 && r <= 0xFE0F) || (r >= 0x0300 && r <= 0x036F):
+		return 0
+	case r >= 0x1100 && (r <= 0x115F || (r >= 0x2600 && r <= 0x27BF) ||
+		(r >= 0x2E80 && r <= 0xA4CF) || (r >= 0xAC00 && r <= 0xD7A3) ||
+		(r >= 0xF900 && r <= 0xFAFF) || (r >= 0xFF00 && r <= 0xFF60) ||
+		(r >= 0x1F300 && r <= 0x1FAFF) || (r >= 0x20000 && r <= 0x3FFFD)):
+		return 2
+	}
+	return 1
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// TruncateWidth shortens s to at most width terminal columns, ending it
+// with Ellipsis if anything was cut. It never splits a rune, and drops a
+// wide character that would straddle the limit rather than overflow it.
+func TruncateWidth(s string, width int) string {
+	if DisplayWidth(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	limit := width - DisplayWidth(Ellipsis)
+	used := 0
+	for i, r := range s {
+		w := runeWidth(r)
+		if used+w > limit {
+			return s[:i] + Ellipsis
+		}
+		used += w
+	}
+	return s
+}
+
+// padColumn pads text to width display columns and colors it (code "" =
+// none). Text already at least width columns wide, e.g. a padded colored
+// label, is returned as is.
+func padColumn(text, code string, width int) string {
+	pad := width - DisplayWidth(text)
+	if pad < 0 {
+		pad = 0
+	}
+	return withColor(code, text) + strings.Repeat(" ", pad)
+}
@@ -7,18 +7,23 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/config"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	"github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/git"
 	"github.com/NielsdaWheelz/agency/internal/identity"
+	"github.com/NielsdaWheelz/agency/internal/lock"
 	"github.com/NielsdaWheelz/agency/internal/paths"
 	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/vcs"
 )
 
 // CheckRepoSafeOpts holds options for CheckRepoSafe.
 type CheckRepoSafeOpts struct {
 	// ParentBranch is the local branch name to branch from, e.g. "main".
+	// Empty skips the parent branch check (for callers that check it once
+	// agency.json has been loaded).
 	ParentBranch string
 }
 
@@ -36,8 +41,14 @@ type RepoContext struct {
 	// OriginURL is the origin remote URL, or empty string if not present.
 	OriginURL string
 
-	// DataDir is the resolved AGENCY_DATA_DIR.
+	// DataDir is the resolved data dir (AGENCY_DATA_DIR or agency.json data_dir).
 	DataDir string
+
+	// Capabilities are the capabilities recorded in repo.json.
+	Capabilities store.Capabilities
+
+	// VCS is the version control the repo is managed with (see vcs.Detect).
+	VCS vcs.Kind
 }
 
 // osEnv implements paths.Env using os.Getenv.
@@ -55,43 +66,27 @@ func (osEnv) Get(key string) string {
 //  2. Compute repo_id using S0 rules
 //  3. Read origin URL (best-effort)
 //  4. Write/update repo.json (last_seen_at, origin_url)
-//  5. Run gates (empty repo, dirty, parent branch)
+//  5. Run gates (empty repo, dirty, parent branch) with the repo's VCS
 //
 // Error codes:
 //   - E_NO_REPO: not inside a git repository
+//   - E_VCS_UNSUPPORTED: jj repo without a colocated git store
+//   - E_JJ_NOT_INSTALLED: jj repo, but jj cannot be run
 //   - E_EMPTY_REPO: repo has no commits (fresh git init)
-//   - E_PARENT_DIRTY: working tree has uncommitted changes
-//   - E_PARENT_BRANCH_NOT_FOUND: local parent branch does not exist
+//   - E_PARENT_DIRTY: working tree has uncommitted changes (jj: conflicts)
+//   - E_PARENT_BRANCH_NOT_FOUND: local parent branch (jj: bookmark) does not exist
 func CheckRepoSafe(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string, opts CheckRepoSafeOpts) (*RepoContext, error) {
-	// 1. Resolve repo root from cwd
-	repoRoot, err := git.GetRepoRoot(ctx, cr, cwd)
+	// 1-5. Resolve repo context and update repo.json
+	rc, err := ResolveRepo(ctx, cr, fsys, cwd)
 	if err != nil {
-		// E_NO_REPO is already set by GetRepoRoot
-		return nil, err
-	}
-
-	// 2. Read origin URL (best-effort, never fails)
-	originURL := git.GetOriginURL(ctx, cr, repoRoot.Path)
-
-	// 3. Compute repo_id using S0 rules
-	repoIdentity := identity.DeriveRepoIdentity(repoRoot.Path, originURL)
-
-	// 4. Resolve data directory
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, errors.Wrap(errors.EInternal, "failed to get home directory", err)
-	}
-	dirs := paths.ResolveDirs(osEnv{}, homeDir)
-
-	// 5. Write/update repo.json
-	if err := updateRepoJSON(fsys, dirs.DataDir, repoRoot.Path, repoIdentity, originURL); err != nil {
 		return nil, err
 	}
 
 	// 6. Run gates
+	v := vcs.ForKind(cr, rc.VCS, rc.RepoRoot)
 
 	// 6a. Empty repo check
-	hasCommits, err := git.HasCommits(ctx, cr, repoRoot.Path)
+	hasCommits, err := v.HasCommits(ctx, rc.RepoRoot)
 	if err != nil {
 		return nil, err
 	}
@@ -100,16 +95,22 @@ func CheckRepoSafe(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd s
 	}
 
 	// 6b. Parent working tree dirty check
-	isClean, err := git.IsClean(ctx, cr, repoRoot.Path)
+	isClean, err := v.IsClean(ctx, rc.RepoRoot)
 	if err != nil {
 		return nil, err
 	}
+	if !isClean && v.Kind() == vcs.JJ {
+		return nil, errors.New(errors.EParentDirty, "working-copy commit has unresolved conflicts; resolve them first")
+	}
 	if !isClean {
 		return nil, errors.New(errors.EParentDirty, "working tree has uncommitted changes; commit or stash them first")
 	}
 
 	// 6c. Local parent branch existence check
-	branchExists, err := git.BranchExists(ctx, cr, repoRoot.Path, opts.ParentBranch)
+	if opts.ParentBranch == "" {
+		return rc, nil
+	}
+	branchExists, err := v.BranchExists(ctx, rc.RepoRoot, opts.ParentBranch)
 	if err != nil {
 		return nil, err
 	}
@@ -121,24 +122,123 @@ func CheckRepoSafe(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd s
 		)
 	}
 
+	return rc, nil
+}
+
+// ResolveRepo resolves repo root + repo_id from cwd and updates repo.json,
+// without running any safety gates. Capabilities older than the user
+// config's repos.capabilities_ttl_hours are detected again.
+//
+// Error codes:
+//   - E_NO_REPO: not inside a git repository
+//   - E_VCS_UNSUPPORTED: jj repo without a colocated git store
+//   - E_PERSIST_FAILED: repo.json could not be written
+//   - E_REPO_LOCKED: another command held the repo lock for lock.DefaultWait
+func ResolveRepo(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string) (*RepoContext, error) {
+	return resolveRepo(ctx, cr, fsys, cwd, false)
+}
+
+// RefreshRepo is ResolveRepo that detects the repo's capabilities again
+// whatever their age.
+//
+// Error codes:
+//   - E_NO_REPO: not inside a git repository
+//   - E_PERSIST_FAILED: repo.json could not be written
+//   - E_REPO_LOCKED: another command held the repo lock for lock.DefaultWait
+func RefreshRepo(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string) (*RepoContext, error) {
+	return resolveRepo(ctx, cr, fsys, cwd, true)
+}
+
+func resolveRepo(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string, refresh bool) (*RepoContext, error) {
+	// 1. Resolve repo root from cwd
+	v := vcs.Detect(cr, cwd)
+	repoRoot, err := v.RepoRoot(ctx, cwd)
+	if err != nil {
+		// E_NO_REPO is already set by GetRepoRoot
+		return nil, err
+	}
+
+	// 2. Read origin URL (best-effort, never fails)
+	originURL := git.GetOriginURL(ctx, cr, repoRoot)
+
+	// 3. Compute repo_id using S0 rules
+	repoIdentity := identity.DeriveRepoIdentity(repoRoot, originURL)
+
+	// 4. Resolve data directory
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, errors.Wrap(errors.EInternal, "failed to get home directory", err)
+	}
+	dirs := paths.ResolveDirs(osEnv{}, homeDir)
+	dataDir, err := config.RepoDataDir(fsys, osEnv{}, dirs, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Write/update repo.json
+	ttl := capabilitiesTTL(fsys, dirs.ConfigDir)
+	if refresh {
+		ttl = -1
+	}
+	capabilities, err := updateRepoJSON(ctx, cr, fsys, dataDir, repoRoot, repoIdentity, originURL, ttl)
+	if err != nil {
+		return nil, err
+	}
+
 	return &RepoContext{
-		RepoRoot:  repoRoot.Path,
-		RepoID:    repoIdentity.RepoID,
-		RepoKey:   repoIdentity.RepoKey,
-		OriginURL: originURL,
-		DataDir:   dirs.DataDir,
+		RepoRoot:     repoRoot,
+		RepoID:       repoIdentity.RepoID,
+		RepoKey:      repoIdentity.RepoKey,
+		OriginURL:    originURL,
+		DataDir:      dataDir,
+		Capabilities: capabilities,
+		VCS:          v.Kind(),
 	}, nil
 }
 
-// updateRepoJSON creates or updates repo.json atomically.
+// capabilitiesTTL returns the user config's repos.capabilities_ttl_hours
+// (its default if the user config cannot be read).
+func capabilitiesTTL(fsys fs.FS, configDir string) time.Duration {
+	cfg, err := config.LoadUserConfig(fsys, configDir)
+	if err != nil {
+		cfg = config.DefaultUserConfig()
+	}
+	return time.Duration(cfg.Repos.CapabilitiesTTLHours) * time.Hour
+}
+
+// updateRepoJSON creates or updates repo.json atomically and returns the
+// recorded capabilities. gh_authed is detected again when it is older than
+// ttl (ttl < 0: always; 0: only if never detected).
+// The read-modify-write holds the repo lock (waiting up to lock.DefaultWait),
+// so concurrent runs in the repo do not lose each other's updates. The
+// `gh auth status` probe runs before the lock is taken, so a slow gh does
+// not hold up other commands in the repo.
 // This function reuses the S0 store package and follows its schema.
-func updateRepoJSON(fsys fs.FS, dataDir, repoRoot string, repoIdentity identity.RepoIdentity, originURL string) error {
+func updateRepoJSON(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, dataDir, repoRoot string, repoIdentity identity.RepoIdentity, originURL string, ttl time.Duration) (store.Capabilities, error) {
 	st := store.NewStore(fsys, dataDir, time.Now)
 
+	// Probe gh first if the recorded result (read unlocked) is stale
+	var probed *store.Capabilities
+	if ttl < 0 || ghAuthStale(st, repoIdentity.RepoID, ttl) {
+		probed = &store.Capabilities{
+			GhAuthed:  ghAuthenticated(ctx, cr, repoIdentity),
+			CheckedAt: st.Now().UTC().Format(time.RFC3339),
+		}
+	}
+
+	unlock, err := lock.NewRepoLock(dataDir).LockWait(ctx, repoIdentity.RepoID, "update repo.json", lock.DefaultWait)
+	if err != nil {
+		if _, ok := err.(*lock.ErrLocked); ok {
+			return store.Capabilities{}, errors.Wrap(errors.ERepoLocked, err.Error(), err)
+		}
+		return store.Capabilities{}, errors.Wrap(errors.EPersistFailed, "failed to acquire repo lock", err)
+	}
+	defer func() { _ = unlock() }()
+
 	// Load existing repo record (if any)
 	existingRec, exists, err := st.LoadRepoRecord(repoIdentity.RepoID)
 	if err != nil {
-		return errors.Wrap(errors.EPersistFailed, "failed to load repo.json", err)
+		return store.Capabilities{}, errors.Wrap(errors.EPersistFailed, "failed to load repo.json", err)
 	}
 
 	var existingPtr *store.RepoRecord
@@ -154,15 +254,18 @@ func updateRepoJSON(fsys fs.FS, dataDir, repoRoot string, repoIdentity identity.
 		agencyJSONPath = existingRec.AgencyJSONPath
 	}
 
-	// Preserve existing capabilities or set minimal defaults
+	// Origin capabilities are always current; gh_authed is kept until stale
 	capabilities := store.Capabilities{
 		GitHubOrigin: repoIdentity.GitHubFlowAvailable,
 		OriginHost:   repoIdentity.Origin.Host,
-		GhAuthed:     false, // Not checking gh auth in this function
 	}
 	if exists {
-		// Preserve gh_authed from existing record
 		capabilities.GhAuthed = existingRec.Capabilities.GhAuthed
+		capabilities.CheckedAt = existingRec.Capabilities.CheckedAt
+	}
+	if probed != nil {
+		capabilities.GhAuthed = probed.GhAuthed
+		capabilities.CheckedAt = probed.CheckedAt
 	}
 
 	rec := st.UpsertRepoRecord(existingPtr, store.BuildRepoRecordInput{
@@ -178,8 +281,28 @@ func updateRepoJSON(fsys fs.FS, dataDir, repoRoot string, repoIdentity identity.
 
 	// Save repo record atomically
 	if err := st.SaveRepoRecord(rec); err != nil {
-		return errors.Wrap(errors.EPersistFailed, "failed to write repo.json", err)
+		return store.Capabilities{}, errors.Wrap(errors.EPersistFailed, "failed to write repo.json", err)
 	}
 
-	return nil
+	return capabilities, nil
+}
+
+// ghAuthStale reports whether repo.json's gh_authed needs detecting again
+// (true if repo.json is missing or unreadable).
+func ghAuthStale(st *store.Store, repoID string, ttl time.Duration) bool {
+	rec, exists, err := st.LoadRepoRecord(repoID)
+	if err != nil || !exists {
+		return true
+	}
+	return rec.Capabilities.Stale(st.Now(), ttl)
+}
+
+// ghAuthenticated reports whether `gh auth status` succeeds. It is only
+// checked for GitHub origins; gh is not needed otherwise.
+func ghAuthenticated(ctx context.Context, cr exec.CommandRunner, repoIdentity identity.RepoIdentity) bool {
+	if !repoIdentity.GitHubFlowAvailable {
+		return false
+	}
+	result, err := cr.Run(ctx, "gh", []string{"auth", "status"}, exec.RunOpts{})
+	return err == nil && result.ExitCode == 0
 }
@@ -7,10 +7,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+	"github.com/NielsdaWheelz/agency/internal/vcs"
 )
 
 // Integration tests for CheckRepoSafe.
@@ -545,3 +549,141 @@ func TestCheckRepoSafe_RepoJSONUpdatedOnSecondCall(t *testing.T) {
 		t.Error("updated_at should be set on second call")
 	}
 }
+
+// ghRunner runs git for real and answers `gh auth status` with ghExit.
+type ghRunner struct {
+	agencyexec.CommandRunner
+	ghExit  int
+	ghCalls int
+
+	// lockedDuringGh records whether a repo lock was held while gh ran.
+	dataDir        string
+	lockedDuringGh bool
+}
+
+func (r *ghRunner) Run(ctx context.Context, name string, args []string, opts agencyexec.RunOpts) (agencyexec.CmdResult, error) {
+	if name == "gh" {
+		r.ghCalls++
+		if locks, _ := filepath.Glob(filepath.Join(r.dataDir, "repos", "*", ".lock")); len(locks) > 0 {
+			r.lockedDuringGh = true
+		}
+		return agencyexec.CmdResult{ExitCode: r.ghExit}, nil
+	}
+	return r.CommandRunner.Run(ctx, name, args, opts)
+}
+
+func TestResolveRepo_CapabilitiesTTL(t *testing.T) {
+	repoRoot, cleanup := setupTempRepo(t)
+	defer cleanup()
+	if err := runGit(repoRoot, "remote", "add", "origin", "git@github.com:test/repo.git"); err != nil {
+		t.Fatalf("failed to add origin: %v", err)
+	}
+	dataDir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+
+	ctx := context.Background()
+	cr := &ghRunner{CommandRunner: agencyexec.NewRealRunner(), dataDir: dataDir}
+	fsys := fs.NewRealFS()
+
+	// First resolve detects: gh is not logged in yet
+	cr.ghExit = 1
+	rc, err := ResolveRepo(ctx, cr, fsys, repoRoot)
+	if err != nil {
+		t.Fatalf("ResolveRepo() error = %v", err)
+	}
+	if cr.ghCalls != 1 || rc.Capabilities.GhAuthed || !rc.Capabilities.GitHubOrigin || rc.Capabilities.CheckedAt == "" {
+		t.Fatalf("first resolve: gh calls %d, capabilities %+v", cr.ghCalls, rc.Capabilities)
+	}
+
+	// After `gh auth login`, fresh capabilities are still trusted
+	cr.ghExit = 0
+	if rc, err = ResolveRepo(ctx, cr, fsys, repoRoot); err != nil || cr.ghCalls != 1 || rc.Capabilities.GhAuthed {
+		t.Fatalf("second resolve: err %v, gh calls %d, capabilities %+v", err, cr.ghCalls, rc.Capabilities)
+	}
+
+	// Past the TTL they are detected again
+	st := store.NewStore(fsys, dataDir, time.Now)
+	rec, _, err := st.LoadRepoRecord(rc.RepoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Capabilities.CheckedAt = time.Now().Add(-25 * time.Hour).UTC().Format(time.RFC3339)
+	if err := st.SaveRepoRecord(rec); err != nil {
+		t.Fatal(err)
+	}
+	if rc, err = ResolveRepo(ctx, cr, fsys, repoRoot); err != nil || cr.ghCalls != 2 || !rc.Capabilities.GhAuthed {
+		t.Fatalf("stale resolve: err %v, gh calls %d, capabilities %+v", err, cr.ghCalls, rc.Capabilities)
+	}
+
+	// RefreshRepo always detects
+	cr.ghExit = 1
+	if rc, err = RefreshRepo(ctx, cr, fsys, repoRoot); err != nil || cr.ghCalls != 3 || rc.Capabilities.GhAuthed {
+		t.Fatalf("refresh: err %v, gh calls %d, capabilities %+v", err, cr.ghCalls, rc.Capabilities)
+	}
+	if rec, _, _ = st.LoadRepoRecord(rc.RepoID); rec.Capabilities != rc.Capabilities {
+		t.Errorf("repo.json capabilities = %+v, want %+v", rec.Capabilities, rc.Capabilities)
+	}
+	if cr.lockedDuringGh {
+		t.Error("gh auth status ran while the repo lock was held")
+	}
+}
+
+// jjRunner runs jj commands on a FakeRunner and everything else for real,
+// for jj repos in environments without jj.
+type jjRunner struct {
+	jj *testkit.FakeRunner
+}
+
+func (r jjRunner) Run(ctx context.Context, name string, args []string, opts agencyexec.RunOpts) (agencyexec.CmdResult, error) {
+	if name == "jj" {
+		return r.jj.Run(ctx, name, args, opts)
+	}
+	return agencyexec.NewRealRunner().Run(ctx, name, args, opts)
+}
+
+func TestCheckRepoSafe_JJColocated(t *testing.T) {
+	repoRoot, cleanup := setupTempRepo(t)
+	defer cleanup()
+	t.Setenv("AGENCY_DATA_DIR", t.TempDir())
+	t.Setenv("AGENCY_CONFIG_DIR", t.TempDir())
+
+	// jj leaves git's HEAD detached and the working copy's changes
+	// uncommitted as far as git is concerned
+	branch := getCurrentBranch(t, repoRoot)
+	if err := runGit(repoRoot, "checkout", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repoRoot, ".jj"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, ".jj", ".gitignore"), []byte("/*\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, "wip.txt"), []byte("in @\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	newJJ := func(conflict string) *testkit.FakeRunner {
+		fake := testkit.NewFakeRunner()
+		fake.On("jj", "log", "--no-graph", "--limit", "1", "-r", "::@- ~ root()", "-T", `commit_id ++ "\n"`).Stdout("abc123\n")
+		fake.On("jj", "log", "--no-graph", "-r", "@", "-T", `if(conflict, "conflict")`).Stdout(conflict)
+		fake.On("jj", "log", "--no-graph", "--limit", "1", "-r", `bookmarks(exact:"`+branch+`")`, "-T", `commit_id ++ "\n"`).Stdout("abc123\n")
+		return fake
+	}
+
+	ctx := context.Background()
+	rc, err := CheckRepoSafe(ctx, jjRunner{newJJ("")}, fs.NewRealFS(), repoRoot, CheckRepoSafeOpts{ParentBranch: branch})
+	if err != nil {
+		t.Fatalf("CheckRepoSafe() error = %v", err)
+	}
+	if rc.VCS != vcs.JJ {
+		t.Errorf("VCS = %q, want %q", rc.VCS, vcs.JJ)
+	}
+
+	// A conflicted working-copy commit is the jj equivalent of a dirty tree
+	_, err = CheckRepoSafe(ctx, jjRunner{newJJ("conflict")}, fs.NewRealFS(), repoRoot, CheckRepoSafeOpts{ParentBranch: branch})
+	if errors.GetCode(err) != errors.EParentDirty {
+		t.Errorf("conflicted: code = %q, want %q", errors.GetCode(err), errors.EParentDirty)
+	}
+}
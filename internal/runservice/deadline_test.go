@@ -0,0 +1,37 @@
+package runservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestScheduleDeadlineKill(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	withDeadline := func(at time.Time, kill bool) *store.RunMeta {
+		meta := testkit.NewRunMeta("abc123", "20260110120000-a3f2", "/wt", now)
+		meta.Deadline = &store.RunMetaDeadline{At: at.Format(time.RFC3339), Kill: kill}
+		return meta
+	}
+
+	cr := testkit.NewFakeRunner()
+	ScheduleDeadlineKill(context.Background(), cr, "agency_20260110120000-a3f2", withDeadline(now.Add(2*time.Hour), true), now)
+	if !cr.Called("tmux", "run-shell", "-b", "sleep 7201; tmux kill-session -t '=agency_20260110120000-a3f2'") {
+		t.Errorf("kill not scheduled; calls = %v", cr.Calls())
+	}
+
+	for name, meta := range map[string]*store.RunMeta{
+		"no deadline": testkit.NewRunMeta("abc123", "20260110120000-a3f2", "/wt", now),
+		"flag only":   withDeadline(now.Add(2*time.Hour), false),
+		"past":        withDeadline(now.Add(-time.Minute), true),
+	} {
+		cr := testkit.NewFakeRunner()
+		ScheduleDeadlineKill(context.Background(), cr, "agency_x", meta, now)
+		if calls := cr.Calls(); len(calls) != 0 {
+			t.Errorf("%s: unexpected calls %v", name, calls)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package runservice
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+)
+
+// limitedLog is a script log writer that keeps the log body under maxBytes.
+//
+// In rotate mode a full log is renamed to <path>.1 (replacing any previous
+// one) and a fresh log is started. In truncate mode output is dropped from the
+// head of the log so the most recent output is kept. Either way the header is
+// rewritten to note the limit and what was discarded.
+//
+// Write is safe for concurrent use (stdout and stderr share one writer).
+type limitedLog struct {
+	mu       sync.Mutex
+	path     string
+	header   string
+	maxBytes int64
+	overflow string
+
+	f         *os.File
+	body      int64 // bytes written after the header in the current file
+	rotations int
+	dropped   int64 // bytes dropped from the head (truncate mode)
+}
+
+// openLimitedLog creates or truncates the log at path and writes header,
+// followed by the limit line and the "# ---" separator.
+func openLimitedLog(path, header string, maxBytes int64, overflow string) (*limitedLog, error) {
+	l := &limitedLog{path: path, header: header, maxBytes: maxBytes, overflow: overflow}
+	if err := l.reopen(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// reopen truncates the current file (or creates it) and writes the header.
+func (l *limitedLog) reopen() error {
+	if l.f == nil {
+		f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		l.f = f
+	} else {
+		if err := l.f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	l.body = 0
+	_, err := io.WriteString(l.f, l.headerText())
+	return err
+}
+
+func (l *limitedLog) headerText() string {
+	s := l.header
+	s += fmt.Sprintf("# log_limit: %d bytes (%s)\n", l.maxBytes, l.overflow)
+	if l.rotations > 0 {
+		s += fmt.Sprintf("# rotated: %d time(s); previous output in %s\n", l.rotations, filepath.Base(l.path)+".1")
+	}
+	if l.dropped > 0 {
+		s += fmt.Sprintf("# truncated: dropped %d bytes from the head of this log\n", l.dropped)
+	}
+	return s + "# ---\n\n"
+}
+
+// Write implements io.Writer. It always reports len(p) written unless the
+// underlying file fails, so a noisy script is never killed by a short write.
+func (l *limitedLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return 0, os.ErrClosed
+	}
+
+	if l.overflow == config.LogOverflowTruncate {
+		return len(p), l.writeTruncating(p)
+	}
+
+	n := len(p)
+	for len(p) > 0 {
+		room := l.maxBytes - l.body
+		if room <= 0 {
+			if err := l.rotate(); err != nil {
+				return 0, err
+			}
+			room = l.maxBytes
+		}
+		chunk := p
+		if int64(len(chunk)) > room {
+			chunk = chunk[:room]
+		}
+		if _, err := l.f.Write(chunk); err != nil {
+			return 0, err
+		}
+		l.body += int64(len(chunk))
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+// rotate moves the full log to <path>.1 and starts a new one.
+func (l *limitedLog) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.f = nil
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	l.rotations++
+	return l.reopen()
+}
+
+// writeTruncating appends p and, once the body exceeds maxBytes, rewrites the
+// log keeping only the newest half of the limit.
+func (l *limitedLog) writeTruncating(p []byte) error {
+	keep := l.maxBytes / 2
+	if int64(len(p)) > keep {
+		// Only the tail of a huge write can survive anyway
+		l.dropped += l.body + int64(len(p)) - keep
+		p = p[int64(len(p))-keep:]
+		if err := l.reopen(); err != nil {
+			return err
+		}
+	}
+	if _, err := l.f.Write(p); err != nil {
+		return err
+	}
+	l.body += int64(len(p))
+	if l.body <= l.maxBytes {
+		return nil
+	}
+
+	end, err := l.f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	tail := make([]byte, keep)
+	if _, err := l.f.ReadAt(tail, end-keep); err != nil {
+		return err
+	}
+	l.dropped += l.body - keep
+	if err := l.reopen(); err != nil {
+		return err
+	}
+	if _, err := l.f.Write(tail); err != nil {
+		return err
+	}
+	l.body = keep
+	return nil
+}
+
+// Close closes the underlying file.
+func (l *limitedLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
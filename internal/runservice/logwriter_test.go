@@ -0,0 +1,95 @@
+package runservice
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+)
+
+// logBody splits a log at the "# ---" separator into header and body.
+func logBody(t *testing.T, path string) (header, body string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(string(data), "# ---\n\n", 2)
+	if len(parts) != 2 {
+		t.Fatalf("log has no separator:\n%s", data)
+	}
+	return parts[0], parts[1]
+}
+
+func TestLimitedLog_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "setup.log")
+	l, err := openLimitedLog(path, "# agency setup log\n", 100, config.LogOverflowRotate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := []byte(strings.Repeat("x", 29) + "\n")
+	for i := 0; i < 10; i++ {
+		if n, err := l.Write(line); err != nil || n != len(line) {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	header, body := logBody(t, path)
+	if !strings.Contains(header, "# log_limit: 100 bytes (rotate)") {
+		t.Errorf("header missing limit:\n%s", header)
+	}
+	if !strings.Contains(header, "# rotated: 2 time(s); previous output in setup.log.1") {
+		t.Errorf("header missing rotation note:\n%s", header)
+	}
+	if len(body) != 100 {
+		t.Errorf("current body = %d bytes, want 100", len(body))
+	}
+	_, prev := logBody(t, path+".1")
+	if len(prev) != 100 {
+		t.Errorf("rotated body = %d bytes, want 100", len(prev))
+	}
+}
+
+func TestLimitedLog_Truncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "setup.log")
+	l, err := openLimitedLog(path, "# agency setup log\n", 100, config.LogOverflowTruncate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all bytes.Buffer
+	for i := 0; i < 20; i++ {
+		line := []byte(strings.Repeat(string(rune('a'+i)), 9) + "\n")
+		all.Write(line)
+		if _, err := l.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A single write larger than the limit keeps only its tail
+	huge := []byte(strings.Repeat("z", 300))
+	all.Write(huge)
+	if _, err := l.Write(huge); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	header, body := logBody(t, path)
+	if len(body) > 100 || !strings.HasSuffix(all.String(), body) {
+		t.Fatalf("body should be a <=100 byte tail of the output, got %d bytes: %q", len(body), body)
+	}
+	dropped := all.Len() - len(body)
+	if !strings.Contains(header, "# truncated: dropped "+strconv.Itoa(dropped)+" bytes from the head of this log") {
+		t.Errorf("header missing truncation note (dropped %d):\n%s", dropped, header)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Error("truncate mode must not rotate")
+	}
+}
@@ -0,0 +1,96 @@
+package runservice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/archive"
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/worktree"
+)
+
+// maxQuotaSuggestions caps the archive-eligible runs listed in a quota error.
+const maxQuotaSuggestions = 5
+
+// CheckWorktreeQuota fails with E_WORKTREE_QUOTA_EXCEEDED if the repo's
+// worktrees already use more than cfg.Worktrees.MaxTotalBytes (0 = unlimited).
+// The error lists the largest archive-eligible (merged or abandoned) runs and
+// how to reclaim their space with `agency gc`. Runs before any side effects.
+// c decrypts meta.json encrypted at rest (see store.ScanRunsForRepo).
+func CheckWorktreeQuota(fsys fs.FS, dataDir, repoID string, cfg config.AgencyConfig, c *crypt.Crypt) error {
+	maxBytes := cfg.Worktrees.MaxTotalBytes
+	if maxBytes <= 0 {
+		return nil
+	}
+
+	usage, total, err := worktree.DiskUsage(fsys, dataDir, repoID)
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to measure worktree disk usage", err)
+	}
+	if total <= maxBytes {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "worktrees for this repo use %s, over worktrees.max_total_bytes (%s)",
+		core.FormatBytes(uint64(total)), core.FormatBytes(uint64(maxBytes)))
+
+	eligible := archiveEligibleUsage(dataDir, repoID, usage, c)
+	if len(eligible) == 0 {
+		b.WriteString("\nno merged or abandoned runs to archive; free space by removing runs you no longer need")
+	} else {
+		b.WriteString("\nlargest archive-eligible runs:")
+		for _, e := range eligible {
+			fmt.Fprintf(&b, "\n  %s  %s  %s", e.usage.RunID, core.FormatBytes(uint64(e.usage.Bytes)), e.reason)
+		}
+		if days := cfg.Retention.AutoArchiveAfterDays; days > 0 {
+			fmt.Fprintf(&b, "\nrun 'agency gc' to list runs past the %dd retention and 'agency gc --auto' to archive them", days)
+		} else {
+			b.WriteString("\nset retention.auto_archive_after_days in agency.json, then run 'agency gc --auto' to archive them")
+		}
+	}
+	b.WriteString("\nor raise worktrees.max_total_bytes in agency.json")
+
+	return errors.NewWithDetails(errors.EWorktreeQuotaExceeded, b.String(), map[string]string{
+		"used_bytes": strconv.FormatInt(total, 10),
+		"max_bytes":  strconv.FormatInt(maxBytes, 10),
+	})
+}
+
+// eligibleUsage is a worktree that retention would archive.
+type eligibleUsage struct {
+	usage  worktree.RunUsage
+	reason string
+}
+
+// archiveEligibleUsage returns the largest (usage is sorted largest first)
+// worktrees belonging to merged or abandoned runs, up to maxQuotaSuggestions.
+func archiveEligibleUsage(dataDir, repoID string, usage []worktree.RunUsage, c *crypt.Crypt) []eligibleUsage {
+	records, err := store.ScanRunsForRepo(dataDir, repoID, c)
+	if err != nil {
+		return nil
+	}
+	reasons := make(map[string]string, len(records))
+	for _, rec := range records {
+		if reason := archive.EligibleReason(rec.Meta); reason != "" {
+			reasons[rec.RunID] = reason
+		}
+	}
+
+	var out []eligibleUsage
+	for _, u := range usage {
+		if reason := reasons[u.RunID]; reason != "" {
+			out = append(out, eligibleUsage{usage: u, reason: reason})
+			if len(out) == maxQuotaSuggestions {
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package runservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+	"github.com/NielsdaWheelz/agency/internal/worktree"
+)
+
+func TestCheckWorktreeQuota(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	repoID := "abc123"
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	seed := func(runID string, size int, mutate func(*store.RunMeta)) {
+		t.Helper()
+		wt := worktree.WorktreePath(dataDir, repoID, runID)
+		if err := os.MkdirAll(wt, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(wt, "data.bin"), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		meta := testkit.NewRunMeta(repoID, runID, wt, created)
+		if mutate != nil {
+			mutate(meta)
+		}
+		testkit.WriteRun(t, dataDir, meta)
+	}
+	seed("20260110120000-aaaa", 3000, nil)
+	seed("20260110120000-bbbb", 2000, func(m *store.RunMeta) {
+		m.Archive = &store.RunMetaArchive{MergedAt: "2026-01-12T12:00:00Z"}
+	})
+	seed("20260110120000-cccc", 1000, func(m *store.RunMeta) {
+		m.Flags = &store.RunMetaFlags{Abandoned: true}
+	})
+
+	var cfg config.AgencyConfig
+	if err := CheckWorktreeQuota(fs.NewRealFS(), dataDir, repoID, cfg, nil); err != nil {
+		t.Fatalf("unlimited quota: error = %v", err)
+	}
+	cfg.Worktrees.MaxTotalBytes = 6000
+	if err := CheckWorktreeQuota(fs.NewRealFS(), dataDir, repoID, cfg, nil); err != nil {
+		t.Fatalf("at quota: error = %v", err)
+	}
+
+	cfg.Worktrees.MaxTotalBytes = 5000
+	err := CheckWorktreeQuota(fs.NewRealFS(), dataDir, repoID, cfg, nil)
+	if errors.GetCode(err) != errors.EWorktreeQuotaExceeded {
+		t.Fatalf("over quota: code = %q, want %q (err=%v)", errors.GetCode(err), errors.EWorktreeQuotaExceeded, err)
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"20260110120000-bbbb  2.0 KiB  merged\n  20260110120000-cccc  1000 B  abandoned",
+		"set retention.auto_archive_after_days",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error missing %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "20260110120000-aaaa") {
+		t.Errorf("active run listed as archive-eligible:\n%s", msg)
+	}
+
+	cfg.Retention.AutoArchiveAfterDays = 14
+	if err := CheckWorktreeQuota(fs.NewRealFS(), dataDir, repoID, cfg, nil); !strings.Contains(err.Error(), "'agency gc --auto'") {
+		t.Errorf("error should suggest agency gc --auto:\n%v", err)
+	}
+}
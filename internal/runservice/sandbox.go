@@ -0,0 +1,179 @@
+package runservice
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+)
+
+// maxSandboxEntries caps how many paths one sandbox snapshot records, so an
+// enforced setup never stalls walking a huge watched tree.
+const maxSandboxEntries = 200000
+
+// maxMetaSandboxViolations caps the violations recorded in meta.json; the
+// sandbox log lists all of them.
+const maxMetaSandboxViolations = 100
+
+// applySandboxEnv points HOME, TMPDIR and the XDG base dirs at .agency/tmp in
+// the worktree, so tools that write caches and config land inside it.
+func applySandboxEnv(env map[string]string, worktreePath string) error {
+	tmpDir := filepath.Join(worktreePath, ".agency", "tmp")
+	home := filepath.Join(tmpDir, "home")
+	for _, dir := range []string{"config", "cache", "share", "state"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0o700); err != nil {
+			return err
+		}
+	}
+	env["HOME"] = home
+	env["TMPDIR"] = tmpDir
+	env["XDG_CONFIG_HOME"] = filepath.Join(home, "config")
+	env["XDG_CACHE_HOME"] = filepath.Join(home, "cache")
+	env["XDG_DATA_HOME"] = filepath.Join(home, "share")
+	env["XDG_STATE_HOME"] = filepath.Join(home, "state")
+	return nil
+}
+
+// sandboxRoots returns the paths checked for changes: the repo root (the
+// main checkout) and sandbox.watch, with "~/" expanded to the real HOME.
+// The real HOME itself is not watched by default: the user's shells and
+// editors write there concurrently.
+func sandboxRoots(repoRoot, realHome string, cfg config.Sandbox) []string {
+	roots := []string{repoRoot}
+	for _, p := range cfg.Watch {
+		if strings.HasPrefix(p, "~/") {
+			if realHome == "" {
+				continue
+			}
+			p = filepath.Join(realHome, p[2:])
+		}
+		roots = append(roots, p)
+	}
+	for i := range roots {
+		roots[i] = resolveSandboxPath(roots[i])
+	}
+	return roots
+}
+
+// resolveSandboxPath resolves symlinks in path so roots and excludes compare
+// equal (e.g. /var vs /private/var on macOS); missing paths are kept as is.
+func resolveSandboxPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// fileStamp is what a snapshot records per path.
+type fileStamp struct {
+	Size    int64
+	ModTime time.Time
+	Mode    fs.FileMode
+}
+
+// sandboxSnapshot maps paths under the watched roots to their stamps.
+type sandboxSnapshot struct {
+	Files map[string]fileStamp
+
+	// Truncated lists roots whose walk stopped at maxSandboxEntries.
+	Truncated []string
+}
+
+// takeSandboxSnapshot records every path under the watched roots, skipping
+// excluded paths (the worktree, the data dir, the repo's .git). Symlinks are
+// not followed; unreadable paths are skipped.
+func takeSandboxSnapshot(roots []string, excludes []string) sandboxSnapshot {
+	snap := sandboxSnapshot{Files: make(map[string]fileStamp)}
+	excluded := func(path string) bool {
+		for _, ex := range excludes {
+			if path == ex || strings.HasPrefix(path, ex+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+	record := func(path string, info fs.FileInfo) {
+		snap.Files[path] = fileStamp{Size: info.Size(), ModTime: info.ModTime(), Mode: info.Mode()}
+	}
+
+	for _, root := range roots {
+		if excluded(root) {
+			continue
+		}
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if excluded(path) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if len(snap.Files) >= maxSandboxEntries {
+				snap.Truncated = append(snap.Truncated, root)
+				return fs.SkipAll
+			}
+			if info, err := d.Info(); err == nil {
+				record(path, info)
+			}
+			return nil
+		})
+	}
+	return snap
+}
+
+// sandboxViolation is one path that changed while setup ran.
+type sandboxViolation struct {
+	Path   string
+	Change string // "created", "modified", or "deleted"
+}
+
+func (v sandboxViolation) String() string {
+	return v.Change + ": " + v.Path
+}
+
+// diffSandboxSnapshots returns the paths created, modified or deleted between
+// two snapshots, sorted by path. Directory timestamps are ignored: adding or
+// removing an entry shows up as that entry's own change.
+func diffSandboxSnapshots(before, after sandboxSnapshot) []sandboxViolation {
+	var violations []sandboxViolation
+	for path, a := range after.Files {
+		b, ok := before.Files[path]
+		switch {
+		case !ok:
+			violations = append(violations, sandboxViolation{Path: path, Change: "created"})
+		case b.Mode != a.Mode:
+			violations = append(violations, sandboxViolation{Path: path, Change: "modified"})
+		case !a.Mode.IsDir() && (b.Size != a.Size || !b.ModTime.Equal(a.ModTime)):
+			violations = append(violations, sandboxViolation{Path: path, Change: "modified"})
+		}
+	}
+	for path := range before.Files {
+		if _, ok := after.Files[path]; !ok {
+			violations = append(violations, sandboxViolation{Path: path, Change: "deleted"})
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return violations
+}
+
+// writeSandboxReport writes the full violation list to path.
+func writeSandboxReport(path string, roots []string, violations []sandboxViolation, now time.Time) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# agency setup sandbox report\n")
+	fmt.Fprintf(&b, "# timestamp: %s\n", now.UTC().Format(time.RFC3339))
+	for _, root := range roots {
+		fmt.Fprintf(&b, "# watched: %s\n", root)
+	}
+	fmt.Fprintf(&b, "# %d path(s) outside the worktree changed during setup\n", len(violations))
+	for _, v := range violations {
+		fmt.Fprintln(&b, v.String())
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
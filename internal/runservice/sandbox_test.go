@@ -0,0 +1,138 @@
+package runservice
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+func TestSandboxSnapshotDiff(t *testing.T) {
+	root := t.TempDir()
+	mustWrite := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite("keep.txt", "a")
+	mustWrite("edit.txt", "a")
+	mustWrite("gone.txt", "a")
+	mustWrite("skip/ignored.txt", "a")
+
+	roots := []string{root}
+	excludes := []string{filepath.Join(root, "skip")}
+	before := takeSandboxSnapshot(roots, excludes)
+
+	mustWrite("edit.txt", "ab")
+	mustWrite("sub/new.txt", "a")
+	mustWrite("skip/ignored.txt", "changed")
+	if err := os.Remove(filepath.Join(root, "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := diffSandboxSnapshots(before, takeSandboxSnapshot(roots, excludes))
+	want := []string{
+		"modified: " + filepath.Join(root, "edit.txt"),
+		"deleted: " + filepath.Join(root, "gone.txt"),
+		"created: " + filepath.Join(root, "sub"),
+		"created: " + filepath.Join(root, "sub", "new.txt"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("violations = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("violation %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSandboxRoots(t *testing.T) {
+	cfg := config.Sandbox{Watch: []string{"~/.npmrc", "/opt/shared"}}
+	got := sandboxRoots("/repo", "/home/u", cfg)
+	want := []string{"/repo", "/home/u/.npmrc", "/opt/shared"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("roots = %v, want %v", got, want)
+	}
+}
+
+func TestService_RunSetup_SandboxViolation(t *testing.T) {
+	repoRoot, dataDir, cleanup := setupTempRepo(t)
+	defer cleanup()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	resolvedRepoRoot, _ := filepath.EvalSymlinks(repoRoot)
+	svc := New()
+	ctx := context.Background()
+
+	st := &pipeline.PipelineState{
+		RunID:        "20260110120000-sbox",
+		Title:        "Sandbox Test",
+		RepoRoot:     resolvedRepoRoot,
+		RepoID:       "abcd1234ef567890",
+		DataDir:      dataDir,
+		ParentBranch: "main",
+		Runner:       "claude",
+	}
+	if err := svc.CreateWorktree(ctx, st); err != nil {
+		t.Fatalf("CreateWorktree failed: %v", err)
+	}
+	st.ResolvedRunnerCmd = "claude"
+	st.SetupScript = "scripts/agency_setup.sh"
+	st.Sandbox = config.Sandbox{Enforce: true}
+	if err := svc.WriteMeta(ctx, st); err != nil {
+		t.Fatalf("WriteMeta failed: %v", err)
+	}
+
+	// HOME and TMPDIR writes stay in the worktree; the repo root write leaks
+	scriptsDir := filepath.Join(st.WorktreePath, "scripts")
+	if err := os.MkdirAll(scriptsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+echo cached > "$HOME/.toolrc"
+echo tmp > "$TMPDIR/scratch"
+echo leak > "$AGENCY_REPO_ROOT/leak.txt"
+`
+	if err := os.WriteFile(filepath.Join(scriptsDir, "agency_setup.sh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := svc.RunSetup(ctx, st)
+	if errors.GetCode(err) != errors.ESandboxViolation {
+		t.Fatalf("RunSetup error = %v, want E_SANDBOX_VIOLATION", err)
+	}
+	leak := filepath.Join(resolvedRepoRoot, "leak.txt")
+	if !strings.Contains(err.Error(), "created: "+leak) {
+		t.Errorf("error = %q, want it to name %s", err.Error(), leak)
+	}
+	if _, err := os.Stat(filepath.Join(st.WorktreePath, ".agency", "tmp", "home", ".toolrc")); err != nil {
+		t.Errorf("HOME was not redirected into .agency/tmp: %v", err)
+	}
+
+	meta, err := store.NewStore(svc.fsys, dataDir, svc.nowFunc).ReadMeta(st.RepoID, st.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Flags == nil || !meta.Flags.SetupFailed {
+		t.Error("setup_failed should be set")
+	}
+	if !meta.Setup.Sandboxed || len(meta.Setup.SandboxViolations) != 1 || meta.Setup.SandboxViolations[0] != "created: "+leak {
+		t.Errorf("setup = %+v", meta.Setup)
+	}
+	report, err := os.ReadFile(meta.Setup.SandboxReportPath)
+	if err != nil || !strings.Contains(string(report), "created: "+leak) {
+		t.Errorf("report = %q, err = %v", report, err)
+	}
+}
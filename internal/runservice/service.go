@@ -5,22 +5,32 @@ package runservice
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	stderrors "errors"
 	"fmt"
 	"os"
 	osexec "os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/NielsdaWheelz/agency/internal/config"
 	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/credentials"
+	"github.com/NielsdaWheelz/agency/internal/crypt"
 	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
 	"github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/identity"
+	"github.com/NielsdaWheelz/agency/internal/lock"
 	"github.com/NielsdaWheelz/agency/internal/pipeline"
 	"github.com/NielsdaWheelz/agency/internal/repo"
+	"github.com/NielsdaWheelz/agency/internal/schema"
 	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/vcs"
 	"github.com/NielsdaWheelz/agency/internal/worktree"
 )
 
@@ -29,6 +39,7 @@ type Service struct {
 	cr      exec.CommandRunner
 	fsys    fs.FS
 	nowFunc func() time.Time
+	crypt   *crypt.Crypt
 }
 
 // New creates a new Service with production dependencies.
@@ -54,12 +65,27 @@ func (s *Service) SetNowFunc(fn func() time.Time) {
 	s.nowFunc = fn
 }
 
+// SetCrypt sets the encryption at rest of the run files the service writes
+// and reads (nil, the default, is none).
+func (s *Service) SetCrypt(c *crypt.Crypt) {
+	s.crypt = c
+}
+
+// newStore returns the store of dataDir, encrypting run files with s.crypt.
+func (s *Service) newStore(dataDir string) *store.Store {
+	return store.NewStore(s.fsys, dataDir, s.nowFunc).WithCrypt(s.crypt)
+}
+
 // CheckRepoSafe verifies repo safety (clean working tree, parent branch exists, etc.).
 func (s *Service) CheckRepoSafe(ctx context.Context, st *pipeline.PipelineState) error {
-	// Get current working directory
-	cwd, err := os.Getwd()
-	if err != nil {
-		return errors.Wrap(errors.EInternal, "failed to get current directory", err)
+	// Start repo discovery from the -C/--repo dir, or the current working directory
+	cwd := st.Dir
+	if cwd == "" {
+		var err error
+		cwd, err = os.Getwd()
+		if err != nil {
+			return errors.Wrap(errors.EInternal, "failed to get current directory", err)
+		}
 	}
 
 	// Determine parent branch: use from opts if provided, otherwise will be resolved from config later
@@ -100,7 +126,8 @@ func (s *Service) CheckRepoSafe(ctx context.Context, st *pipeline.PipelineState)
 		st.RepoKey = result.RepoKey
 		st.OriginURL = result.OriginURL
 		st.DataDir = result.DataDir
-		return nil
+		st.VCS = result.VCS
+		return s.claimRunID(ctx, st, false)
 	}
 
 	// Parent not provided - do basic repo checks without parent validation
@@ -115,6 +142,77 @@ func (s *Service) CheckRepoSafe(ctx context.Context, st *pipeline.PipelineState)
 	st.RepoKey = result.RepoKey
 	st.OriginURL = result.OriginURL
 	st.DataDir = result.DataDir
+	st.VCS = result.VCS
+	return s.claimRunID(ctx, st, false)
+}
+
+// maxRunIDAttempts bounds how many generated run_ids claimRunID tries.
+const maxRunIDAttempts = 8
+
+// claimRunID checks that st.RunID is free: no repo has a run dir for it and,
+// with checkNames (once LoadAgencyConfig has resolved the title and slug
+// rules), neither its worktree path nor its branch exists. A generated
+// run_id that is taken, e.g. by a concurrent run, is replaced by a new one
+// (st.NewRunID); a supplied one fails.
+func (s *Service) claimRunID(ctx context.Context, st *pipeline.PipelineState, checkNames bool) error {
+	for attempt := 1; ; attempt++ {
+		err := CheckRunIDAvailable(st.DataDir, st.RunID)
+		if err == nil && checkNames && st.NewRunID != nil {
+			err = s.checkRunNamesFree(ctx, st)
+		}
+		if err == nil || st.NewRunID == nil || attempt == maxRunIDAttempts {
+			return err
+		}
+		runID, genErr := st.NewRunID()
+		if genErr != nil {
+			return errors.Wrap(errors.EInternal, "failed to generate run_id", genErr)
+		}
+		st.RunID = runID
+	}
+}
+
+// checkRunNamesFree fails if the worktree path or branch CreateWorktree would
+// use for st.RunID already exists.
+func (s *Service) checkRunNamesFree(ctx context.Context, st *pipeline.PipelineState) error {
+	_, branch, worktreePath := worktree.Names(worktree.CreateOpts{
+		RunID:   st.RunID,
+		Title:   st.Title,
+		RepoID:  st.RepoID,
+		DataDir: st.DataDir,
+		Slug:    st.Slug,
+	})
+	if _, err := s.fsys.Stat(worktreePath); err == nil {
+		return errors.NewWithDetails(errors.EWorktreePathExists,
+			"worktree path for run_id "+st.RunID+" already exists",
+			map[string]string{"run_id": st.RunID, "worktree_path": worktreePath})
+	}
+	exists, err := branchExists(ctx, s.cr, st, branch)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.NewWithDetails(errors.EWorktreeCreateFailed,
+			"branch "+branch+" already exists",
+			map[string]string{"run_id": st.RunID, "branch": branch})
+	}
+	return nil
+}
+
+// CheckRunIDAvailable fails with E_RUN_DIR_EXISTS if any repo already has a
+// run dir for runID. Run ids resolve globally, so uniqueness is checked across
+// all repos, not just the current one. This runs before any side effects.
+func CheckRunIDAvailable(dataDir, runID string) error {
+	matches, err := filepath.Glob(filepath.Join(dataDir, "repos", "*", "runs", runID))
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to check run_id uniqueness", err)
+	}
+	if len(matches) > 0 {
+		return errors.NewWithDetails(
+			errors.ERunDirExists,
+			"run_id "+runID+" already exists",
+			map[string]string{"run_id": runID, "run_dir": matches[0]},
+		)
+	}
 	return nil
 }
 
@@ -141,34 +239,12 @@ func checkRepoSafeWithoutParent(ctx context.Context, cr exec.CommandRunner, fsys
 	return checkRepoContextOnly(ctx, cr, fsys, cwd)
 }
 
-// checkRepoContextOnly resolves repo context without running all gates.
-// This is used when parent branch will be validated later.
+// checkRepoContextOnly resolves repo context and runs every gate but the
+// parent branch check, which LoadAgencyConfig runs once the parent is known.
+// (Substituting the current branch does not work for a detached HEAD, which
+// is how jj leaves a colocated git store.)
 func checkRepoContextOnly(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, cwd string) (*repo.RepoContext, error) {
-	// Import the packages we need and run the checks inline
-	// Since this is getting complex, let me just call the actual CheckRepoSafe
-	// with a branch we know exists - the current HEAD.
-
-	// Get current branch
-	result, err := cr.Run(ctx, "git", []string{"branch", "--show-current"}, exec.RunOpts{Dir: cwd})
-	if err != nil {
-		return nil, errors.Wrap(errors.ENoRepo, "failed to get current branch", err)
-	}
-
-	currentBranch := result.Stdout
-	if len(currentBranch) > 0 && currentBranch[len(currentBranch)-1] == '\n' {
-		currentBranch = currentBranch[:len(currentBranch)-1]
-	}
-
-	// Fallback to a common default if no branch (detached HEAD, etc.)
-	if currentBranch == "" {
-		currentBranch = "main"
-	}
-
-	// Now call the full CheckRepoSafe with the current branch
-	// This validates everything except the *actual* parent branch the user wants
-	return repo.CheckRepoSafe(ctx, cr, fsys, cwd, repo.CheckRepoSafeOpts{
-		ParentBranch: currentBranch,
-	})
+	return repo.CheckRepoSafe(ctx, cr, fsys, cwd, repo.CheckRepoSafeOpts{})
 }
 
 // LoadAgencyConfig loads and validates agency.json, populates runner/setup info.
@@ -185,28 +261,9 @@ func (s *Service) LoadAgencyConfig(ctx context.Context, st *pipeline.PipelineSta
 		runnerName = cfg.Defaults.Runner
 	}
 
-	// If a non-default runner is requested, we need to resolve it
-	// ValidateForS1 already resolved the default runner, but if user specified
-	// a different one, we need to check if it's configured
-	resolvedRunnerCmd := cfg.ResolvedRunnerCmd
-	if runnerName != cfg.Defaults.Runner {
-		// Check if the requested runner is configured
-		if cfg.Runners != nil {
-			if cmd, ok := cfg.Runners[runnerName]; ok {
-				resolvedRunnerCmd = cmd
-			} else if runnerName == "claude" || runnerName == "codex" {
-				// Standard runners fallback to PATH
-				resolvedRunnerCmd = runnerName
-			} else {
-				return errors.New(errors.ERunnerNotConfigured,
-					"runner \""+runnerName+"\" not configured; set runners."+runnerName+" or choose claude/codex")
-			}
-		} else if runnerName == "claude" || runnerName == "codex" {
-			resolvedRunnerCmd = runnerName
-		} else {
-			return errors.New(errors.ERunnerNotConfigured,
-				"runner \""+runnerName+"\" not configured; set runners."+runnerName+" or choose claude/codex")
-		}
+	resolvedRunnerCmd, err := config.ResolveRunnerCmd(cfg, runnerName)
+	if err != nil {
+		return err
 	}
 
 	// Resolve parent branch
@@ -218,7 +275,7 @@ func (s *Service) LoadAgencyConfig(ctx context.Context, st *pipeline.PipelineSta
 	// If parent branch wasn't checked in CheckRepoSafe (was deferred), validate it now
 	if st.Parent == "" {
 		// Need to validate the resolved parent branch exists
-		exists, err := branchExists(ctx, s.cr, st.RepoRoot, parentBranch)
+		exists, err := branchExists(ctx, s.cr, st, parentBranch)
 		if err != nil {
 			return err
 		}
@@ -231,34 +288,113 @@ func (s *Service) LoadAgencyConfig(ctx context.Context, st *pipeline.PipelineSta
 		}
 	}
 
+	// Refuse before creating anything if the repo's worktrees are over quota
+	if err := CheckWorktreeQuota(s.fsys, st.DataDir, st.RepoID, cfg, s.crypt); err != nil {
+		return err
+	}
+
+	// Apply the default deadline unless the caller set or disabled one
+	// (defaults.deadline was validated when agency.json was parsed)
+	if st.NoDeadline {
+		st.Deadline = 0
+		st.DeadlineKill = false
+	} else if st.Deadline == 0 && cfg.Defaults.Deadline != "" {
+		st.Deadline, _ = core.ParseAge(cfg.Defaults.Deadline)
+	}
+	if st.Deadline > 0 && cfg.Defaults.DeadlineKill {
+		st.DeadlineKill = true
+	}
+
 	// Populate state
 	st.Runner = runnerName // Store the resolved runner name (may differ from CLI input)
 	st.ResolvedRunnerCmd = resolvedRunnerCmd
 	st.SetupScript = cfg.Scripts.Setup
 	st.ParentBranch = parentBranch
+	st.Hooks = cfg.Hooks
+	st.Logs = cfg.Logs
+	st.Sandbox = cfg.Sandbox
+	st.SetupCache = cfg.SetupCache
+	st.Slug = cfg.Slug.Rules()
+	sparse, err := resolveSparseCheckout(cfg.Worktrees, st.SparseProfile)
+	if err != nil {
+		return err
+	}
+	st.SparseCheckout = sparse
+	st.Submodules = cfg.Worktrees.Submodules
+	st.MaxPathLength = cfg.Worktrees.MaxPathLength
+	st.GitHub = cfg.GitHub
 
-	return nil
+	// Expand title placeholders now that runner and parent are resolved
+	return s.expandTitle(st)
 }
 
-// branchExists checks if a local branch exists.
-func branchExists(ctx context.Context, cr exec.CommandRunner, repoRoot, branch string) (bool, error) {
-	ref := "refs/heads/" + branch
-	result, err := cr.Run(ctx, "git", []string{"show-ref", "--verify", ref}, exec.RunOpts{Dir: repoRoot})
+// expandTitle expands the placeholders in st.Title ({date}, {ticket},
+// {parent}, {runner}), keeping the template and the values used for
+// meta.json. {ticket} is the run's "ticket" label and requires one.
+func (s *Service) expandTitle(st *pipeline.PipelineState) error {
+	if !strings.ContainsAny(st.Title, "{}") {
+		return nil
+	}
+	vars := map[string]string{
+		core.TemplateDate:   s.nowFunc().Format("2006-01-02"),
+		core.TemplateParent: st.ParentBranch,
+		core.TemplateRunner: st.Runner,
+	}
+	if ticket := st.Labels["ticket"]; ticket != "" {
+		vars[core.TemplateTicket] = ticket
+	}
+	title, used, err := core.ExpandTemplate(st.Title, vars)
 	if err != nil {
-		return false, errors.Wrap(errors.EInternal, "failed to check branch existence", err)
+		e := errors.Wrap(errors.EUsage, "invalid --title", err)
+		if _, ok := vars[core.TemplateTicket]; !ok && strings.Contains(st.Title, "{"+core.TemplateTicket+"}") {
+			return errors.WithHints(e, "add --label ticket=<id> to use {ticket}")
+		}
+		return e
 	}
-	return result.ExitCode == 0, nil
+	st.TitleTemplate = st.Title
+	st.TemplateVars = used
+	st.Title = strings.TrimSpace(title)
+	return nil
+}
+
+// branchExists checks if a local branch (jj: bookmark) exists in the run's repo.
+func branchExists(ctx context.Context, cr exec.CommandRunner, st *pipeline.PipelineState, branch string) (bool, error) {
+	return vcs.ForKind(cr, st.VCS, st.RepoRoot).BranchExists(ctx, st.RepoRoot, branch)
 }
 
 // CreateWorktree creates the git worktree and .agency/ directories.
+// Concurrent runs in the repo are serialized by the repo lock from claiming
+// the run_id until git worktree add has taken its worktree path and branch,
+// waiting up to lock.DefaultWait for the lock.
+//
+// Error codes (besides worktree.Create's):
+//   - E_REPO_LOCKED: the repo lock was still held after lock.DefaultWait
 func (s *Service) CreateWorktree(ctx context.Context, st *pipeline.PipelineState) error {
+	unlock, err := lock.NewRepoLock(st.DataDir).LockWait(ctx, st.RepoID, "run", lock.DefaultWait)
+	if err != nil {
+		return repoLockError(err)
+	}
+	defer func() { _ = unlock() }()
+
+	if err := s.claimRunID(ctx, st, true); err != nil {
+		return err
+	}
+	if err := vcs.ForKind(s.cr, st.VCS, st.RepoRoot).PrepareWorktree(ctx, st.RepoRoot); err != nil {
+		return err
+	}
+
+	start := time.Now()
 	result, err := worktree.Create(ctx, s.cr, s.fsys, worktree.CreateOpts{
-		RunID:        st.RunID,
-		Title:        st.Title,
-		RepoRoot:     st.RepoRoot,
-		RepoID:       st.RepoID,
-		ParentBranch: st.ParentBranch,
-		DataDir:      st.DataDir,
+		RunID:          st.RunID,
+		Title:          st.Title,
+		RepoRoot:       st.RepoRoot,
+		RepoID:         st.RepoID,
+		ParentBranch:   st.ParentBranch,
+		DataDir:        st.DataDir,
+		Slug:           st.Slug,
+		SparseCheckout: st.SparseCheckout,
+		Submodules:     st.Submodules,
+		MaxPathLength:  st.MaxPathLength,
 	})
 	if err != nil {
 		return err
@@ -267,6 +403,9 @@ func (s *Service) CreateWorktree(ctx context.Context, st *pipeline.PipelineState
 	// Populate state
 	st.Branch = result.Branch
 	st.WorktreePath = result.WorktreePath
+	st.WorktreeDuration = time.Since(start)
+	st.SubmodulePaths = result.Submodules
+	st.SubmoduleLog = result.SubmoduleLog
 
 	// If title was empty, use the resolved title for later use
 	if st.Title == "" {
@@ -284,6 +423,34 @@ func (s *Service) CreateWorktree(ctx context.Context, st *pipeline.PipelineState
 	return nil
 }
 
+// resolveSparseCheckout returns the sparse-checkout patterns for profile:
+// "" uses worktrees.sparse_checkout, "none" a full checkout, and any other
+// name must be defined in worktrees.sparse_profiles (E_USAGE otherwise).
+func resolveSparseCheckout(wt config.Worktrees, profile string) ([]string, error) {
+	switch profile {
+	case "":
+		return wt.SparseCheckout, nil
+	case config.SparseProfileNone:
+		return nil, nil
+	}
+	if patterns, ok := wt.SparseProfiles[profile]; ok {
+		return patterns, nil
+	}
+	names := make([]string, 0, len(wt.SparseProfiles))
+	for name := range wt.SparseProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	available := "none defined in agency.json worktrees.sparse_profiles"
+	if len(names) > 0 {
+		available = "available: " + strings.Join(names, ", ")
+	}
+	return nil, errors.WithHints(
+		errors.NewWithDetails(errors.EUsage, fmt.Sprintf("unknown sparse profile %q", profile),
+			map[string]string{"profile": profile}),
+		available)
+}
+
 // WriteMeta writes the initial meta.json for the run.
 // Creates the run directory with exclusive semantics, creates the logs subdirectory,
 // and writes meta.json atomically with required fields.
@@ -323,7 +490,7 @@ func (s *Service) WriteMeta(ctx context.Context, st *pipeline.PipelineState) err
 	}
 
 	// Create a store for the run operations
-	st2 := store.NewStore(s.fsys, st.DataDir, s.nowFunc)
+	st2 := s.newStore(st.DataDir)
 
 	// Create run directory (exclusive semantics) + logs subdirectory
 	_, err = st2.EnsureRunDir(st.RepoID, st.RunID)
@@ -343,12 +510,46 @@ func (s *Service) WriteMeta(ctx context.Context, st *pipeline.PipelineState) err
 		st.WorktreePath,
 		s.nowFunc(),
 	)
+	meta.CreatedBy = identity.CurrentUser()
+	meta.Labels = st.Labels
+	meta.Group = st.Group
+	meta.TaskID = st.TaskID
+	meta.SkippedSteps = st.SkippedSteps
+	if st.TitleTemplate != "" {
+		meta.Template = &store.RunMetaTemplate{Title: st.TitleTemplate, Vars: st.TemplateVars}
+	}
+	meta.Credentials = credentials.Source(st.GitHub, st.RepoKey)
+	meta.Worktree = &store.RunMetaWorktree{
+		DurationMs:     st.WorktreeDuration.Milliseconds(),
+		SparseCheckout: st.SparseCheckout,
+		SparseProfile:  st.SparseProfile,
+		Submodules:     st.SubmodulePaths,
+	}
+	if len(st.SubmodulePaths) > 0 {
+		meta.Worktree.SubmodulesInitialized = st.Submodules
+	}
+	if st.Deadline > 0 {
+		meta.Deadline = &store.RunMetaDeadline{
+			At:   s.nowFunc().Add(st.Deadline).UTC().Format(time.RFC3339),
+			Kill: st.DeadlineKill,
+		}
+	}
 
 	// Write meta.json atomically
 	if err := st2.WriteInitialMeta(st.RepoID, st.RunID, meta); err != nil {
 		return err
 	}
 
+	// meta.group is canonical; a stale groups.json index is only a warning
+	if st.Group != "" {
+		if err := st2.AddRunToGroup(st.Group, st.RepoID, st.RunID); err != nil {
+			st.Warnings = append(st.Warnings, pipeline.Warning{
+				Code:    "W_GROUP_INDEX",
+				Message: "failed to update groups.json: " + err.Error(),
+			})
+		}
+	}
+
 	return nil
 }
 
@@ -357,12 +558,17 @@ const SetupTimeout = 10 * time.Minute
 
 // RunSetup executes the setup script with timeout.
 // Runs the configured setup script via `sh -lc <setup_script>` in the worktree.
-// Captures stdout/stderr to logs/setup.log (truncated on each attempt).
+// Captures stdout/stderr to logs/setup.log (truncated on each attempt, size-limited
+// per agency.json logs).
 // Updates meta.json with setup evidence (flags.setup_failed, setup.* fields).
 // Optionally parses .agency/out/setup.json for structured output.
+// The environment and tool versions are captured to setup_env.json first.
+// With agency.json setup_cache, a run whose inputs match an earlier
+// successful setup restores that run's cached paths instead of running the
+// script (unless st.ForceSetup), and a successful setup saves its paths.
 func (s *Service) RunSetup(ctx context.Context, st *pipeline.PipelineState) error {
 	// Build paths
-	st2 := store.NewStore(s.fsys, st.DataDir, s.nowFunc)
+	st2 := s.newStore(st.DataDir)
 	logsDir := st2.RunLogsDir(st.RepoID, st.RunID)
 	logPath := filepath.Join(logsDir, "setup.log")
 
@@ -379,17 +585,97 @@ func (s *Service) RunSetup(ctx context.Context, st *pipeline.PipelineState) erro
 	// Build environment variables
 	env := buildSetupEnv(st, logsDir)
 
+	// Under sandbox.enforce, HOME and TMPDIR point into the worktree and the
+	// watched paths outside it are snapshotted around the script
+	var roots []string
+	var excludes []string
+	var before sandboxSnapshot
+	if st.Sandbox.Enforce {
+		if err := applySandboxEnv(env, st.WorktreePath); err != nil {
+			return errors.WrapWithDetails(
+				errors.EInternal,
+				"failed to create sandbox home",
+				err,
+				map[string]string{"worktree_path": st.WorktreePath},
+			)
+		}
+		realHome, _ := os.UserHomeDir()
+		roots = sandboxRoots(st.RepoRoot, realHome, st.Sandbox)
+		excludes = []string{
+			resolveSandboxPath(st.WorktreePath),
+			resolveSandboxPath(st.DataDir),
+			resolveSandboxPath(filepath.Join(st.RepoRoot, ".git")),
+		}
+	}
+
+	// Capture the environment for `agency show --setup-env` / `agency diff-env`
+	// (best-effort; a failed capture must not block setup)
+	setupEnv := CaptureSetupEnv(ctx, s.cr, s.nowFunc(), st.WorktreePath, env, st.ResolvedRunnerCmd)
+	setupEnv.CredentialMode = st.GitHub.CredentialMode()
+	_ = st2.WriteSetupEnv(st.RepoID, st.RunID, store.SetupEnvScript, setupEnv)
+
+	// Start from an empty .agency/out so a setup.json left by an earlier
+	// attempt is never parsed as this attempt's result
+	previousOutput, err := worktree.RotateOutputDir(st.WorktreePath, "setup", s.nowFunc())
+	if err != nil {
+		return errors.WrapWithDetails(
+			errors.EInternal,
+			"failed to clear .agency/out before setup",
+			err,
+			map[string]string{"output_dir": worktree.OutputDir(st.WorktreePath)},
+		)
+	}
+
+	// Reuse an earlier run's setup when the setup_cache inputs match
+	var cacheKey string
+	if len(st.SetupCache.Inputs) > 0 {
+		cacheKey = setupCacheKey(st.WorktreePath, st.SetupScript, st.SetupCache.Inputs)
+		if !st.ForceSetup {
+			hit, err := s.useSetupCache(st, st2, cacheKey, logPath)
+			if err != nil {
+				st.Warnings = append(st.Warnings, pipeline.Warning{
+					Code:    "W_SETUP_CACHE",
+					Message: fmt.Sprintf("setup cache entry %s could not be restored, running setup: %v", cacheKey, err),
+				})
+			} else if hit {
+				return nil
+			}
+		}
+	}
+
 	// Execute setup script
-	result := executeSetupScript(ctx, st.SetupScript, st.WorktreePath, env, logPath, SetupTimeout)
+	if st.Sandbox.Enforce {
+		before = takeSandboxSnapshot(roots, excludes)
+	}
+	result := executeScript(ctx, "setup", st.SetupScript, st.WorktreePath, env, logPath, st.Logs, SetupTimeout, submoduleLogHeader(st.SubmoduleLog))
+
+	var violations []sandboxViolation
+	if st.Sandbox.Enforce {
+		after := takeSandboxSnapshot(roots, excludes)
+		violations = diffSandboxSnapshots(before, after)
+		for _, root := range after.Truncated {
+			st.Warnings = append(st.Warnings, pipeline.Warning{
+				Code:    "W_SANDBOX_PARTIAL",
+				Message: fmt.Sprintf("sandbox check of %s stopped after %d paths; changes beyond them were not checked", root, maxSandboxEntries),
+			})
+		}
+	}
 
 	// Parse optional setup.json if it exists
-	setupJSONPath := filepath.Join(st.WorktreePath, ".agency", "out", "setup.json")
+	setupJSONPath := filepath.Join(worktree.OutputDir(st.WorktreePath), "setup.json")
 	structuredOutput := parseSetupJSON(s.fsys, setupJSONPath)
+	outputErrors := validateSetupJSON(s.fsys, setupJSONPath)
+	if len(outputErrors) > 0 {
+		st.Warnings = append(st.Warnings, pipeline.Warning{
+			Code:    "W_SETUP_OUTPUT_INVALID",
+			Message: fmt.Sprintf("setup.json does not match its schema (%d problem(s)); first: %s", len(outputErrors), outputErrors[0]),
+		})
+	}
 
 	// Determine if setup failed
 	setupFailed := result.Failed
 	if !setupFailed && structuredOutput != nil && structuredOutput.Ok != nil && !*structuredOutput.Ok {
-		// setup.json says ok=false, override success
+		// setup.json says ok=false (or a v2 check failed), override success
 		setupFailed = true
 	}
 
@@ -400,16 +686,40 @@ func (s *Service) RunSetup(ctx context.Context, st *pipeline.PipelineState) erro
 		DurationMs: result.DurationMs,
 		TimedOut:   result.TimedOut,
 		LogPath:    logPath,
+		Sandboxed:  st.Sandbox.Enforce,
+		CacheKey:   cacheKey,
+
+		OutputErrors:       outputErrors,
+		PreviousOutputPath: previousOutput,
+	}
+
+	sandboxReportPath := filepath.Join(logsDir, "setup_sandbox.log")
+	if len(violations) > 0 {
+		setupFailed = true
+		for i, v := range violations {
+			if i == maxMetaSandboxViolations {
+				break
+			}
+			setupMeta.SandboxViolations = append(setupMeta.SandboxViolations, v.String())
+		}
+		if err := writeSandboxReport(sandboxReportPath, roots, violations, s.nowFunc()); err == nil {
+			setupMeta.SandboxReportPath = sandboxReportPath
+		}
 	}
 
 	// Add structured output fields if present
 	if structuredOutput != nil {
 		setupMeta.OutputOk = structuredOutput.Ok
 		setupMeta.OutputSummary = structuredOutput.Summary
+		setupMeta.OutputSchemaVersion = structuredOutput.SchemaVersion
+		setupMeta.OutputChecks = structuredOutput.Checks
+		setupMeta.OutputArtifacts = structuredOutput.Artifacts
+		setupMeta.OutputWarnings = structuredOutput.Warnings
+		setupMeta.OutputFile = structuredOutput.File
 	}
 
 	// Update meta.json atomically (read-modify-write)
-	err := st2.UpdateMeta(st.RepoID, st.RunID, func(meta *store.RunMeta) {
+	err = st2.UpdateMeta(st.RepoID, st.RunID, func(meta *store.RunMeta) {
 		meta.Setup = setupMeta
 		if setupFailed {
 			if meta.Flags == nil {
@@ -433,6 +743,21 @@ func (s *Service) RunSetup(ctx context.Context, st *pipeline.PipelineState) erro
 			},
 		)
 	}
+	if len(violations) > 0 {
+		msg := fmt.Sprintf("setup script changed %d path(s) outside the worktree: %s", len(violations), violations[0])
+		if len(violations) > 1 {
+			msg += fmt.Sprintf(" (and %d more)", len(violations)-1)
+		}
+		details := map[string]string{
+			"command":   "sh -lc " + st.SetupScript,
+			"exit_code": fmt.Sprintf("%d", result.ExitCode),
+			"log_path":  logPath,
+		}
+		if setupMeta.SandboxReportPath != "" {
+			details["report_path"] = setupMeta.SandboxReportPath
+		}
+		return errors.NewWithDetails(errors.ESandboxViolation, msg, details)
+	}
 	if setupFailed {
 		msg := "setup script failed"
 		if structuredOutput != nil && structuredOutput.Ok != nil && !*structuredOutput.Ok {
@@ -440,6 +765,9 @@ func (s *Service) RunSetup(ctx context.Context, st *pipeline.PipelineState) erro
 			if structuredOutput.Summary != "" {
 				msg += ": " + structuredOutput.Summary
 			}
+			if failed := structuredOutput.failedChecks(); len(failed) > 0 {
+				msg += " (failed checks: " + strings.Join(failed, ", ") + ")"
+			}
 		}
 		return errors.NewWithDetails(
 			errors.EScriptFailed,
@@ -452,6 +780,112 @@ func (s *Service) RunSetup(ctx context.Context, st *pipeline.PipelineState) erro
 		)
 	}
 
+	// Save the setup_cache paths for later runs (best-effort)
+	if cacheKey != "" {
+		cacheDir := SetupCacheDir(st.DataDir, st.RepoID)
+		if err := saveSetupCache(s.fsys, st.DataDir, cacheDir, cacheKey, st.RunID, s.nowFunc(), st.SetupCache.Paths, st.WorktreePath, setupMeta); err != nil {
+			st.Warnings = append(st.Warnings, pipeline.Warning{
+				Code:    "W_SETUP_CACHE",
+				Message: fmt.Sprintf("failed to save setup cache entry %s: %v", cacheKey, err),
+			})
+		}
+	}
+
+	return nil
+}
+
+// useSetupCache restores the setup cache entry for key into the worktree
+// and records the hit in meta.json and setup.log. Returns false if there is
+// no entry, and an error if the entry could not be restored.
+func (s *Service) useSetupCache(st *pipeline.PipelineState, st2 *store.Store, key, logPath string) (bool, error) {
+	cacheDir := SetupCacheDir(st.DataDir, st.RepoID)
+	entry := readSetupCacheEntry(cacheDir, key)
+	if entry == nil {
+		return false, nil
+	}
+	if err := restoreSetupCache(s.fsys, cacheDir, entry, st.WorktreePath); err != nil {
+		return false, err
+	}
+
+	msg := fmt.Sprintf("# agency setup log\n# timestamp: %s\n# setup skipped (cache hit): key %s from run %s; restored %s\n",
+		s.nowFunc().UTC().Format(time.RFC3339), key, entry.RunID, strings.Join(entry.Paths, ", "))
+	_ = os.WriteFile(logPath, []byte(msg), 0o644)
+
+	setupMeta := &store.RunMetaSetup{
+		Command:    "sh -lc " + st.SetupScript,
+		ExitCode:   0,
+		LogPath:    logPath,
+		CacheKey:   key,
+		CacheHit:   true,
+		CacheRunID: entry.RunID,
+	}
+	if prev := entry.Setup; prev != nil {
+		setupMeta.OutputOk = prev.OutputOk
+		setupMeta.OutputSummary = prev.OutputSummary
+		setupMeta.OutputSchemaVersion = prev.OutputSchemaVersion
+		setupMeta.OutputChecks = prev.OutputChecks
+		setupMeta.OutputArtifacts = prev.OutputArtifacts
+		setupMeta.OutputWarnings = prev.OutputWarnings
+		setupMeta.OutputErrors = prev.OutputErrors
+	}
+	if err := st2.UpdateMeta(st.RepoID, st.RunID, func(meta *store.RunMeta) {
+		meta.Setup = setupMeta
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// HookTimeout is the timeout for each agency.json hook script.
+const HookTimeout = 5 * time.Minute
+
+// RunHook executes the script configured for hook via `sh -lc` in the worktree,
+// with the same AGENCY_* environment as setup plus AGENCY_HOOK=<hook>.
+// Output goes to logs/hook_<hook>.log and the outcome is appended to events.jsonl.
+// A non-zero exit or timeout fails the run.
+func (s *Service) RunHook(ctx context.Context, st *pipeline.PipelineState, hook string) error {
+	script := st.Hooks[hook]
+	if script == "" {
+		return nil
+	}
+
+	st2 := s.newStore(st.DataDir)
+	logsDir := st2.RunLogsDir(st.RepoID, st.RunID)
+	logPath := filepath.Join(logsDir, "hook_"+hook+".log")
+	if err := s.fsys.MkdirAll(logsDir, 0o700); err != nil {
+		return errors.WrapWithDetails(
+			errors.EInternal,
+			"failed to ensure logs directory exists",
+			err,
+			map[string]string{"logs_dir": logsDir},
+		)
+	}
+
+	env := buildSetupEnv(st, logsDir)
+	env["AGENCY_HOOK"] = hook
+	_ = st2.WriteSetupEnv(st.RepoID, st.RunID, "hook_"+hook, CaptureSetupEnv(ctx, s.cr, s.nowFunc(), st.WorktreePath, env, ""))
+
+	result := executeScript(ctx, "hook "+hook, script, st.WorktreePath, env, logPath, st.Logs, HookTimeout, "")
+
+	_ = events.AppendEvent(events.EventsPath(st2.RunDir(st.RepoID, st.RunID)), events.New(s.nowFunc(), st.RepoID, st.RunID, "hook", map[string]any{
+		"hook":        hook,
+		"exit_code":   result.ExitCode,
+		"duration_ms": result.DurationMs,
+		"timed_out":   result.TimedOut,
+	}))
+
+	details := map[string]string{
+		"hook":     hook,
+		"command":  "sh -lc " + script,
+		"log_path": logPath,
+	}
+	if result.TimedOut {
+		return errors.NewWithDetails(errors.EScriptTimeout, "hook "+hook+" timed out after "+HookTimeout.String(), details)
+	}
+	if result.Failed {
+		details["exit_code"] = fmt.Sprintf("%d", result.ExitCode)
+		return errors.NewWithDetails(errors.EScriptFailed, "hook "+hook+" failed", details)
+	}
 	return nil
 }
 
@@ -463,23 +897,40 @@ type setupResult struct {
 	Failed     bool
 }
 
-// executeSetupScript runs the setup script and captures output to the log file.
-func executeSetupScript(ctx context.Context, script, workDir string, env map[string]string, logPath string, timeout time.Duration) setupResult {
+// submoduleLogHeader turns the output of the worktree's git submodule update
+// into setup.log header lines, so the log shows how submodules were prepared.
+func submoduleLogHeader(submoduleLog string) string {
+	if submoduleLog == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("# submodules:\n")
+	for _, line := range strings.Split(strings.TrimRight(submoduleLog, "\n"), "\n") {
+		b.WriteString("#   " + line + "\n")
+	}
+	return b.String()
+}
+
+// executeScript runs a setup or hook script and captures output to the log file,
+// keeping it within the logs limit (see limitedLog).
+// kind names the script in the log header (e.g. "setup", "hook pre_run_setup");
+// extraHeader is appended to the header as is (lines must start with "# ").
+func executeScript(ctx context.Context, kind, script, workDir string, env map[string]string, logPath string, logs config.Logs, timeout time.Duration, extraHeader string) setupResult {
 	start := time.Now()
 
-	// Create/truncate log file
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	// Create/truncate log file; the header notes the size limit
+	var header strings.Builder
+	fmt.Fprintf(&header, "# agency %s log\n", kind)
+	fmt.Fprintf(&header, "# timestamp: %s\n", start.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&header, "# command: sh -lc %s\n", script)
+	fmt.Fprintf(&header, "# cwd: %s\n", workDir)
+	header.WriteString(extraHeader)
+	maxBytes, overflow := logs.Limit()
+	logFile, err := openLimitedLog(logPath, header.String(), maxBytes, overflow)
 	if err != nil {
 		return setupResult{ExitCode: -1, Failed: true}
 	}
 
-	// Write header to log
-	fmt.Fprintf(logFile, "# agency setup log\n")
-	fmt.Fprintf(logFile, "# timestamp: %s\n", start.UTC().Format(time.RFC3339))
-	fmt.Fprintf(logFile, "# command: sh -lc %s\n", script)
-	fmt.Fprintf(logFile, "# cwd: %s\n", workDir)
-	fmt.Fprintf(logFile, "# ---\n\n")
-
 	// Apply timeout
 	if timeout > 0 {
 		var cancel context.CancelFunc
@@ -491,9 +942,11 @@ func executeSetupScript(ctx context.Context, script, workDir string, env map[str
 	cmd := osexec.CommandContext(ctx, "sh", "-lc", script)
 	cmd.Dir = workDir
 
-	// Set stdout/stderr to log file
+	// Set stdout/stderr to the size-limited log. Output is copied through a
+	// pipe, so don't wait forever on background processes that keep it open.
 	cmd.Stdout = logFile
 	cmd.Stderr = logFile
+	cmd.WaitDelay = 5 * time.Second
 
 	// Open /dev/null for stdin
 	devnull, err := os.Open(os.DevNull)
@@ -589,13 +1042,46 @@ func buildSetupEnv(st *pipeline.PipelineState, logsDir string) map[string]string
 }
 
 // structuredSetupOutput represents the optional .agency/out/setup.json output.
+//
+// Schema v1 ("1.0", or no schema_version) carries only ok and summary.
+// Schema v2 ("2.x") adds named checks, artifacts, and warnings:
+//
+//	{
+//	  "schema_version": "2.0",
+//	  "ok": true,
+//	  "summary": "deps installed, db migrated",
+//	  "checks": [{"name": "deps", "ok": true, "duration_ms": 5300, "details": "312 packages"}],
+//	  "artifacts": ["node_modules/.bin"],
+//	  "warnings": ["lockfile out of date"]
+//	}
+//
+// In v2, an absent ok is derived from the checks (false if any check failed).
 type structuredSetupOutput struct {
-	Ok      *bool
-	Summary string
+	SchemaVersion string
+	Ok            *bool
+	Summary       string
+	Checks        []store.RunMetaSetupCheck
+	Artifacts     []string
+	Warnings      []string
+
+	// File identifies the parsed file version (meta setup.output_file).
+	File *store.RunMetaOutputFile
+}
+
+// failedChecks returns the names of the checks that reported ok=false.
+func (o *structuredSetupOutput) failedChecks() []string {
+	var names []string
+	for _, c := range o.Checks {
+		if !c.Ok {
+			names = append(names, c.Name)
+		}
+	}
+	return names
 }
 
 // parseSetupJSON attempts to parse .agency/out/setup.json if it exists.
-// Returns nil if the file doesn't exist or is invalid JSON.
+// Returns nil if the file doesn't exist or is invalid JSON. v2 fields are
+// read only when schema_version is 2.x; checks without a name are dropped.
 func parseSetupJSON(fsys fs.FS, path string) *structuredSetupOutput {
 	data, err := fsys.ReadFile(path)
 	if err != nil {
@@ -603,19 +1089,82 @@ func parseSetupJSON(fsys fs.FS, path string) *structuredSetupOutput {
 	}
 
 	var raw struct {
-		SchemaVersion string `json:"schema_version"`
-		Ok            *bool  `json:"ok"`
-		Summary       string `json:"summary"`
+		SchemaVersion string                    `json:"schema_version"`
+		Ok            *bool                     `json:"ok"`
+		Summary       string                    `json:"summary"`
+		Checks        []store.RunMetaSetupCheck `json:"checks"`
+		Artifacts     []string                  `json:"artifacts"`
+		Warnings      []string                  `json:"warnings"`
 	}
 
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return nil // invalid JSON, ignore
 	}
 
-	return &structuredSetupOutput{
-		Ok:      raw.Ok,
-		Summary: raw.Summary,
+	out := &structuredSetupOutput{
+		SchemaVersion: raw.SchemaVersion,
+		Ok:            raw.Ok,
+		Summary:       raw.Summary,
+		File:          &store.RunMetaOutputFile{Path: path, SHA256: fmt.Sprintf("%x", sha256.Sum256(data))},
+	}
+	if info, err := fsys.Stat(path); err == nil {
+		out.File.ModifiedAt = info.ModTime().UTC().Format(time.RFC3339)
+	}
+	if raw.SchemaVersion != "2" && !strings.HasPrefix(raw.SchemaVersion, "2.") {
+		return out
+	}
+
+	for _, c := range raw.Checks {
+		if c.Name != "" {
+			out.Checks = append(out.Checks, c)
+		}
+	}
+	out.Artifacts = nonEmpty(raw.Artifacts)
+	out.Warnings = nonEmpty(raw.Warnings)
+	if out.Ok == nil && len(out.Checks) > 0 {
+		ok := len(out.failedChecks()) == 0
+		out.Ok = &ok
+	}
+	return out
+}
+
+// maxMetaOutputErrors caps the setup.json schema violations recorded in
+// meta.json.
+const maxMetaOutputErrors = 20
+
+// validateSetupJSON checks .agency/out/setup.json, if it exists, against the
+// setup schema and returns its violations (nil if it is valid or absent).
+// Invalid output is still read as far as parseSetupJSON can; the violations
+// tell the script author what to fix.
+func validateSetupJSON(fsys fs.FS, path string) []string {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	violations, err := schema.Validate("setup", data)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for i, v := range violations {
+		if i == maxMetaOutputErrors {
+			out = append(out, fmt.Sprintf("... and %d more", len(violations)-i))
+			break
+		}
+		out = append(out, v.String())
+	}
+	return out
+}
+
+// nonEmpty returns the non-blank strings in values (nil if none).
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			out = append(out, v)
+		}
 	}
+	return out
 }
 
 // TmuxSessionPrefix is the prefix for all agency tmux session names.
@@ -629,7 +1178,7 @@ const TmuxSessionPrefix = "agency_"
 // Updates meta.json with tmux_session_name on success or flags.tmux_failed on failure.
 func (s *Service) StartTmux(ctx context.Context, st *pipeline.PipelineState) error {
 	// Check if setup failed - should not start tmux if so
-	st2 := store.NewStore(s.fsys, st.DataDir, s.nowFunc)
+	st2 := s.newStore(st.DataDir)
 	meta, err := st2.ReadMeta(st.RepoID, st.RunID)
 	if err != nil {
 		return err
@@ -666,12 +1215,59 @@ func (s *Service) StartTmux(ctx context.Context, st *pipeline.PipelineState) err
 		)
 	}
 
+	// Mint the run's GitHub token (github.credentials) before the runner starts
+	tokenPath, err := RefreshGitHubToken(ctx, s.cr, s.fsys, st2, meta, s.nowFunc())
+	if err != nil {
+		return err
+	}
+
+	if err := StartRunnerSession(ctx, s.cr, sessionName, st.WorktreePath, st.ResolvedRunnerCmd, tokenPath); err != nil {
+		s.setTmuxFailedFlag(st.DataDir, st.RepoID, st.RunID)
+		return err
+	}
+
+	// Update meta.json with tmux_session_name
+	err = st2.UpdateMeta(st.RepoID, st.RunID, func(m *store.RunMeta) {
+		m.TmuxSessionName = sessionName
+	})
+	if err != nil {
+		// Meta write failed, but tmux session was created
+		// Best effort: try to kill the session
+		s.cr.Run(ctx, "tmux", []string{"kill-session", "-t", sessionName}, exec.RunOpts{})
+		return err
+	}
+
+	ScheduleDeadlineKill(ctx, s.cr, sessionName, meta, s.nowFunc())
+
+	return nil
+}
+
+// ScheduleDeadlineKill arranges for tmux to kill sessionName when the run's
+// deadline passes, if meta.deadline.kill is set. The timer runs inside the
+// tmux server (run-shell -b), so it outlives agency. Best-effort: if it cannot
+// be scheduled, ls/show still flag the run once the deadline passes.
+func ScheduleDeadlineKill(ctx context.Context, cr exec.CommandRunner, sessionName string, meta *store.RunMeta, now time.Time) {
+	if meta.Deadline == nil || !meta.Deadline.Kill {
+		return
+	}
+	at, err := time.Parse(time.RFC3339, meta.Deadline.At)
+	if err != nil || !now.Before(at) {
+		return
+	}
+	secs := int64(at.Sub(now).Seconds()) + 1
+	script := fmt.Sprintf("sleep %d; tmux kill-session -t %s", secs, core.ShellEscapePosix("="+sessionName))
+	_, _ = cr.Run(ctx, "tmux", []string{"run-shell", "-b", script}, exec.RunOpts{})
+}
+
+// StartRunnerSession creates a detached tmux session running runnerCmd in
+// worktreePath. The caller is responsible for collision checks and meta updates.
+func StartRunnerSession(ctx context.Context, cr exec.CommandRunner, sessionName, worktreePath, runnerCmd, tokenPath string) error {
 	// Build the pane command
-	paneCmd := core.BuildRunnerShellScript(st.WorktreePath, st.ResolvedRunnerCmd)
+	paneCmd := core.WithGitHubTokenFile(core.BuildRunnerShellScript(worktreePath, runnerCmd), tokenPath)
 
 	// Create the tmux session detached
 	// Use: tmux new-session -d -s <session> -- sh -lc '<pane_cmd>'
-	newSessionResult, err := s.cr.Run(ctx, "tmux", []string{
+	result, err := cr.Run(ctx, "tmux", []string{
 		"new-session",
 		"-d",
 		"-s", sessionName,
@@ -679,42 +1275,55 @@ func (s *Service) StartTmux(ctx context.Context, st *pipeline.PipelineState) err
 		"sh", "-lc", paneCmd,
 	}, exec.RunOpts{})
 	if err != nil {
-		// tmux command failed to run
-		s.setTmuxFailedFlag(st.DataDir, st.RepoID, st.RunID)
 		return errors.Wrap(errors.ETmuxFailed, "failed to create tmux session", err)
 	}
-	if newSessionResult.ExitCode != 0 {
-		// tmux command returned non-zero
-		s.setTmuxFailedFlag(st.DataDir, st.RepoID, st.RunID)
+	if result.ExitCode != 0 {
 		return errors.NewWithDetails(
 			errors.ETmuxFailed,
-			"tmux new-session failed: "+newSessionResult.Stderr,
+			"tmux new-session failed: "+result.Stderr,
 			map[string]string{
 				"session":   sessionName,
-				"exit_code": fmt.Sprintf("%d", newSessionResult.ExitCode),
-				"stderr":    newSessionResult.Stderr,
+				"exit_code": fmt.Sprintf("%d", result.ExitCode),
+				"stderr":    result.Stderr,
 			},
 		)
 	}
+	return nil
+}
 
-	// Update meta.json with tmux_session_name
-	err = st2.UpdateMeta(st.RepoID, st.RunID, func(m *store.RunMeta) {
-		m.TmuxSessionName = sessionName
-	})
-	if err != nil {
-		// Meta write failed, but tmux session was created
-		// Best effort: try to kill the session
-		s.cr.Run(ctx, "tmux", []string{"kill-session", "-t", sessionName}, exec.RunOpts{})
-		return err
+// RefreshGitHubToken mints a new GitHub token for a run with
+// meta.credentials if its token file is missing or about to expire, and
+// records it in meta.json. Returns the token file path ("" for runs that
+// inherit credentials). meta.Credentials is updated in place.
+func RefreshGitHubToken(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, st *store.Store, meta *store.RunMeta, now time.Time) (string, error) {
+	creds := meta.Credentials
+	if creds == nil {
+		return "", nil
+	}
+	if !credentials.NeedsRefresh(fsys, creds, now) {
+		return creds.TokenPath, nil
 	}
 
-	return nil
+	token, err := credentials.Mint(ctx, cr, creds, now)
+	if err != nil {
+		return "", err
+	}
+	path, err := credentials.WriteToken(fsys, st.RunDir(meta.RepoID, meta.RunID), creds, token, now)
+	if err != nil {
+		return "", err
+	}
+	if err := st.UpdateMeta(meta.RepoID, meta.RunID, func(m *store.RunMeta) {
+		m.Credentials = creds
+	}); err != nil {
+		return "", err
+	}
+	return path, nil
 }
 
 // setTmuxFailedFlag updates meta.json to set flags.tmux_failed=true.
 // Called when tmux session creation fails.
 func (s *Service) setTmuxFailedFlag(dataDir, repoID, runID string) {
-	st2 := store.NewStore(s.fsys, dataDir, s.nowFunc)
+	st2 := s.newStore(dataDir)
 	_ = st2.UpdateMeta(repoID, runID, func(m *store.RunMeta) {
 		if m.Flags == nil {
 			m.Flags = &store.RunMetaFlags{}
@@ -722,3 +1331,13 @@ func (s *Service) setTmuxFailedFlag(dataDir, repoID, runID string) {
 		m.Flags.TmuxFailed = true
 	})
 }
+
+// repoLockError maps a RepoLock failure to E_REPO_LOCKED when another
+// command still holds the lock, E_INTERNAL otherwise.
+func repoLockError(err error) error {
+	if _, ok := err.(*lock.ErrLocked); ok {
+		return errors.WithHints(errors.Wrap(errors.ERepoLocked, err.Error(), err),
+			"retry when the other command is done, or run agency unlock if it crashed")
+	}
+	return errors.Wrap(errors.EInternal, "failed to acquire repo lock", err)
+}
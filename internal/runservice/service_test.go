@@ -2,16 +2,24 @@ package runservice
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/core"
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
 )
 
 // setupTempRepo creates a temp repo with agency.json and one commit.
@@ -236,6 +244,99 @@ func TestService_CheckRepoSafe_DirtyRepo(t *testing.T) {
 	}
 }
 
+func TestService_CheckRepoSafe_RunIDCollision(t *testing.T) {
+	repoRoot, dataDir, cleanup := setupTempRepo(t)
+	defer cleanup()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	// An existing run with the same id in another repo still collides:
+	// run ids resolve globally.
+	existing := filepath.Join(dataDir, "repos", "otherrepo", "runs", "job-4821")
+	if err := os.MkdirAll(existing, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldWd, _ := os.Getwd()
+	os.Chdir(repoRoot)
+	defer os.Chdir(oldWd)
+
+	svc := New()
+	st := &pipeline.PipelineState{Parent: "main", RunID: "job-4821"}
+	err := svc.CheckRepoSafe(context.Background(), st)
+	if errors.GetCode(err) != errors.ERunDirExists {
+		t.Fatalf("error code = %q, want %q (err=%v)", errors.GetCode(err), errors.ERunDirExists, err)
+	}
+
+	st = &pipeline.PipelineState{Parent: "main", RunID: "job-4822"}
+	if err := svc.CheckRepoSafe(context.Background(), st); err != nil {
+		t.Fatalf("unused run_id should pass: %v", err)
+	}
+}
+
+// TestPipeline_ConcurrentRuns runs several pipelines at once against one repo,
+// all starting from the same generated run_id and title: each must end up with
+// its own run_id, branch, and worktree.
+func TestPipeline_ConcurrentRuns(t *testing.T) {
+	repoRoot, dataDir, cleanup := setupTempRepo(t)
+	defer cleanup()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	const n = 6
+	var mu sync.Mutex
+	generated := 0
+	clock := core.Clock{
+		Now: time.Now,
+		NewRunID: func(now time.Time) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			generated++
+			if generated <= n {
+				// Every pipeline starts with the same run_id
+				return "20260110120000-0000", nil
+			}
+			return fmt.Sprintf("20260110120000-%04x", generated), nil
+		},
+	}
+
+	steps := append(pipeline.PrepareSteps(), pipeline.CreateWorktreeStep, pipeline.WriteMetaStep)
+	states := make([]*pipeline.PipelineState, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := pipeline.NewPipeline(New())
+			p.SetClock(clock)
+			states[i], errs[i] = p.Execute(context.Background(), pipeline.RunPipelineOpts{Title: "stress", Dir: repoRoot}, steps)
+		}(i)
+	}
+	wg.Wait()
+
+	runIDs := map[string]bool{}
+	branches := map[string]bool{}
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("pipeline %d failed: %v", i, errs[i])
+		}
+		st := states[i]
+		if runIDs[st.RunID] || branches[st.Branch] {
+			t.Fatalf("pipeline %d reused run_id %s / branch %s", i, st.RunID, st.Branch)
+		}
+		runIDs[st.RunID] = true
+		branches[st.Branch] = true
+		if _, err := os.Stat(filepath.Join(dataDir, "repos", st.RepoID, "runs", st.RunID, "meta.json")); err != nil {
+			t.Errorf("pipeline %d: meta.json missing: %v", i, err)
+		}
+		if _, err := os.Stat(st.WorktreePath); err != nil {
+			t.Errorf("pipeline %d: worktree missing: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "repos", states[0].RepoID, ".lock")); !os.IsNotExist(err) {
+		t.Errorf("repo lock left behind: %v", err)
+	}
+}
+
 func TestService_LoadAgencyConfig(t *testing.T) {
 	repoRoot, dataDir, cleanup := setupTempRepo(t)
 	defer cleanup()
@@ -272,6 +373,72 @@ func TestService_LoadAgencyConfig(t *testing.T) {
 	}
 }
 
+func TestService_LoadAgencyConfig_TitleTemplate(t *testing.T) {
+	repoRoot, dataDir, cleanup := setupTempRepo(t)
+	defer cleanup()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	resolvedRepoRoot, _ := filepath.EvalSymlinks(repoRoot)
+	svc := NewWithDeps(agencyexec.NewRealRunner(), fs.NewRealFS())
+	svc.SetNowFunc(func() time.Time { return time.Date(2026, 1, 10, 12, 0, 0, 0, time.Local) })
+	ctx := context.Background()
+
+	st := &pipeline.PipelineState{
+		RepoRoot: resolvedRepoRoot,
+		DataDir:  dataDir,
+		Title:    "{ticket}: {runner} on {parent} ({date})",
+		Labels:   map[string]string{"ticket": "JIRA-123"},
+	}
+	if err := svc.LoadAgencyConfig(ctx, st); err != nil {
+		t.Fatalf("LoadAgencyConfig failed: %v", err)
+	}
+	if want := "JIRA-123: claude on main (2026-01-10)"; st.Title != want {
+		t.Errorf("Title = %q, want %q", st.Title, want)
+	}
+	if st.TitleTemplate != "{ticket}: {runner} on {parent} ({date})" || len(st.TemplateVars) != 4 || st.TemplateVars["ticket"] != "JIRA-123" {
+		t.Errorf("TitleTemplate = %q, TemplateVars = %v", st.TitleTemplate, st.TemplateVars)
+	}
+
+	// {ticket} needs a ticket label
+	st = &pipeline.PipelineState{RepoRoot: resolvedRepoRoot, DataDir: dataDir, Title: "{ticket} fix"}
+	err := svc.LoadAgencyConfig(ctx, st)
+	if errors.GetCode(err) != errors.EUsage || !strings.Contains(strings.Join(errors.Hints(err), "\n"), "--label ticket=") {
+		t.Errorf("LoadAgencyConfig error = %v, want E_USAGE with a --label hint", err)
+	}
+}
+
+func TestResolveSparseCheckout(t *testing.T) {
+	wt := config.Worktrees{
+		SparseCheckout: []string{"/*", "!/assets/"},
+		SparseProfiles: map[string][]string{"api": {"/services/api/"}, "web": {"/web/"}},
+	}
+
+	for _, tt := range []struct {
+		profile string
+		want    []string
+	}{
+		{"", []string{"/*", "!/assets/"}},
+		{config.SparseProfileNone, nil},
+		{"api", []string{"/services/api/"}},
+	} {
+		got, err := resolveSparseCheckout(wt, tt.profile)
+		if err != nil {
+			t.Fatalf("resolveSparseCheckout(%q) error = %v", tt.profile, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolveSparseCheckout(%q) = %v, want %v", tt.profile, got, tt.want)
+		}
+	}
+
+	_, err := resolveSparseCheckout(wt, "mobile")
+	if errors.GetCode(err) != errors.EUsage {
+		t.Fatalf("unknown profile: code = %s, want %s", errors.GetCode(err), errors.EUsage)
+	}
+	if hints := errors.Hints(err); len(hints) != 1 || hints[0] != "available: api, web" {
+		t.Errorf("hints = %v", hints)
+	}
+}
+
 func TestService_WriteMeta_Success(t *testing.T) {
 	repoRoot, dataDir, cleanup := setupTempRepo(t)
 	defer cleanup()
@@ -564,6 +731,21 @@ exit 0
 	if !strings.Contains(string(metaContent), `"command"`) {
 		t.Error("meta.json should contain command field")
 	}
+
+	// Verify the setup environment was captured
+	setupEnv, err := store.NewStore(fs.NewRealFS(), dataDir, nil).ReadSetupEnv(repoID, runID)
+	if err != nil || setupEnv == nil {
+		t.Fatalf("ReadSetupEnv() = %v, %v; want a capture", setupEnv, err)
+	}
+	if setupEnv.Env["AGENCY_RUN_ID"] != runID || setupEnv.OS == "" {
+		t.Errorf("setup env = %+v, want AGENCY_RUN_ID and os", setupEnv)
+	}
+	if _, ok := setupEnv.Tools["git"]; !ok {
+		t.Errorf("setup env tools = %v, want git", setupEnv.Tools)
+	}
+	if setupEnv.ScriptPath == "" || setupEnv.Tools["sh"] == "" {
+		t.Errorf("setup env = %+v, want the script's PATH and shell", setupEnv)
+	}
 }
 
 func TestService_RunSetup_ScriptFailed(t *testing.T) {
@@ -739,6 +921,81 @@ exit 0
 	if !strings.Contains(string(metaContent), `"output_summary": "test failure"`) {
 		t.Error("meta.json should contain output_summary")
 	}
+	if !strings.Contains(string(metaContent), `"sha256": "`) {
+		t.Error("meta.json should record the parsed setup.json version")
+	}
+
+	// A retry that writes no setup.json must not pick up the stale one
+	if err := os.WriteFile(filepath.Join(scriptsDir, "agency_setup.sh"), []byte("#!/bin/bash\nexit 0\n"), 0755); err != nil {
+		t.Fatalf("failed to write setup script: %v", err)
+	}
+	if err := svc.RunSetup(ctx, st); err != nil {
+		t.Fatalf("retry RunSetup() error = %v", err)
+	}
+	meta, err := store.NewStore(fs.NewRealFS(), dataDir, time.Now).ReadMeta(repoID, runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Setup.OutputOk != nil || meta.Setup.OutputFile != nil {
+		t.Errorf("retry parsed stale output: %+v", meta.Setup)
+	}
+	prev := meta.Setup.PreviousOutputPath
+	if !strings.HasPrefix(prev, filepath.Join(st.WorktreePath, ".agency", "out-history")+string(filepath.Separator)) || !strings.HasSuffix(prev, "-setup") {
+		t.Errorf("PreviousOutputPath = %q", prev)
+	}
+	if _, err := os.Stat(filepath.Join(prev, "setup.json")); err != nil {
+		t.Errorf("stale setup.json not kept in history: %v", err)
+	}
+}
+
+func TestParseSetupJSON_Versions(t *testing.T) {
+	dir := t.TempDir()
+	parse := func(content string) *structuredSetupOutput {
+		t.Helper()
+		path := filepath.Join(dir, "setup.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return parseSetupJSON(fs.NewRealFS(), path)
+	}
+
+	// v1 ignores v2 fields
+	v1 := parse(`{"schema_version": "1.0", "ok": true, "summary": "done", "checks": [{"name": "deps", "ok": false}]}`)
+	if v1 == nil || v1.Ok == nil || !*v1.Ok || v1.Summary != "done" || v1.Checks != nil {
+		t.Errorf("v1 = %+v", v1)
+	}
+
+	v2 := parse(`{
+		"schema_version": "2.0",
+		"summary": "deps installed",
+		"checks": [
+			{"name": "deps", "ok": true, "duration_ms": 5300, "details": "312 packages"},
+			{"name": "", "ok": false},
+			{"name": "migrate", "ok": false, "details": "db unreachable"}
+		],
+		"artifacts": ["node_modules/.bin", ""],
+		"warnings": ["lockfile out of date"]
+	}`)
+	if v2 == nil {
+		t.Fatal("v2 = nil")
+	}
+	if v2.Ok == nil || *v2.Ok {
+		t.Errorf("v2 ok should be derived false from failed check, got %v", v2.Ok)
+	}
+	if len(v2.Checks) != 2 || v2.Checks[0].DurationMs != 5300 || v2.Checks[1].Details != "db unreachable" {
+		t.Errorf("v2 checks = %+v", v2.Checks)
+	}
+	if got := v2.failedChecks(); len(got) != 1 || got[0] != "migrate" {
+		t.Errorf("failedChecks() = %v", got)
+	}
+	if len(v2.Artifacts) != 1 || len(v2.Warnings) != 1 {
+		t.Errorf("v2 artifacts = %v, warnings = %v", v2.Artifacts, v2.Warnings)
+	}
+
+	// explicit ok wins over checks
+	if v2ok := parse(`{"schema_version": "2.1", "ok": true, "checks": [{"name": "lint", "ok": false}]}`); v2ok.Ok == nil || !*v2ok.Ok {
+		t.Errorf("explicit ok should win, got %v", v2ok.Ok)
+	}
 }
 
 func TestService_RunSetup_SetupJsonMalformed(t *testing.T) {
@@ -818,6 +1075,83 @@ exit 0
 	if strings.Contains(string(metaContent), `"output_ok"`) {
 		t.Error("meta.json should not contain output_ok for malformed JSON")
 	}
+	// ...but the syntax error is reported
+	if !strings.Contains(string(metaContent), "invalid JSON") {
+		t.Errorf("meta.json should record the syntax error in output_errors:\n%s", metaContent)
+	}
+	if len(st.Warnings) == 0 || st.Warnings[len(st.Warnings)-1].Code != "W_SETUP_OUTPUT_INVALID" {
+		t.Errorf("warnings = %+v, want W_SETUP_OUTPUT_INVALID", st.Warnings)
+	}
+}
+
+func TestService_RunSetup_SetupJsonSchemaViolation(t *testing.T) {
+	repoRoot, dataDir, cleanup := setupTempRepo(t)
+	defer cleanup()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	resolvedRepoRoot, _ := filepath.EvalSymlinks(repoRoot)
+	svc := New()
+	ctx := context.Background()
+	runID := "20260110120000-schm"
+	repoID := "abcd1234ef567890"
+
+	st := &pipeline.PipelineState{
+		RunID:        runID,
+		Title:        "Setup Schema Test",
+		RepoRoot:     resolvedRepoRoot,
+		RepoID:       repoID,
+		DataDir:      dataDir,
+		ParentBranch: "main",
+		Runner:       "claude",
+	}
+	if err := svc.CreateWorktree(ctx, st); err != nil {
+		t.Fatalf("CreateWorktree failed: %v", err)
+	}
+	st.ResolvedRunnerCmd = "claude"
+	st.SetupScript = "scripts/agency_setup.sh"
+	if err := svc.WriteMeta(ctx, st); err != nil {
+		t.Fatalf("WriteMeta failed: %v", err)
+	}
+
+	// A v2 setup.json with a check whose ok is a string
+	setupScript := `#!/bin/sh
+cat > "$AGENCY_OUTPUT_DIR/setup.json" <<'JSON'
+{
+  "schema_version": "2.0",
+  "ok": true,
+  "checks": [{"name": "deps", "ok": "yes"}]
+}
+JSON
+`
+	scriptsDir := filepath.Join(st.WorktreePath, "scripts")
+	if err := os.MkdirAll(scriptsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(scriptsDir, "agency_setup.sh"), []byte(setupScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.RunSetup(ctx, st); err != nil {
+		t.Fatalf("RunSetup failed: %v", err)
+	}
+
+	meta, err := store.NewStore(svc.fsys, dataDir, svc.nowFunc).ReadMeta(repoID, runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "line 4, column 37: /checks/0/ok: expected boolean, got string"
+	if meta.Setup == nil || len(meta.Setup.OutputErrors) != 1 || meta.Setup.OutputErrors[0] != want {
+		t.Errorf("output_errors = %v, want [%s]", meta.Setup.OutputErrors, want)
+	}
+	var found bool
+	for _, w := range st.Warnings {
+		if w.Code == "W_SETUP_OUTPUT_INVALID" && strings.Contains(w.Message, want) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("warnings = %+v, want W_SETUP_OUTPUT_INVALID naming the violation", st.Warnings)
+	}
 }
 
 func TestService_StartTmux_Success(t *testing.T) {
@@ -1065,3 +1399,120 @@ func TestService_StartTmux_SessionExists(t *testing.T) {
 		t.Errorf("error code = %q, want %q", code, errors.ETmuxSessionExists)
 	}
 }
+
+func TestService_RunHook(t *testing.T) {
+	dataDir := t.TempDir()
+	worktree := t.TempDir()
+	scriptsDir := filepath.Join(worktree, "scripts")
+	if err := os.MkdirAll(scriptsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	hookScript := `#!/bin/sh
+echo "hook=$AGENCY_HOOK run=$AGENCY_RUN_ID"
+cp "$AGENCY_REPO_ROOT/.env" "$AGENCY_WORKSPACE_ROOT/.env"
+`
+	if err := os.WriteFile(filepath.Join(scriptsDir, "copy_env.sh"), []byte(hookScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(scriptsDir, "fail.sh"), []byte("#!/bin/sh\nexit 3\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	repoRoot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoRoot, ".env"), []byte("SECRET=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewWithDeps(agencyexec.NewRealRunner(), fs.NewRealFS())
+	st := &pipeline.PipelineState{
+		RunID:        "20260110120000-hook",
+		RepoID:       "abcd1234ef567890",
+		RepoRoot:     repoRoot,
+		DataDir:      dataDir,
+		WorktreePath: worktree,
+		Hooks: map[string]string{
+			config.HookPostCreateWorktree: "scripts/copy_env.sh",
+			config.HookPreStartTmux:       "scripts/fail.sh",
+		},
+	}
+	ctx := context.Background()
+
+	if err := svc.RunHook(ctx, st, config.HookPostCreateWorktree); err != nil {
+		t.Fatalf("RunHook: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(worktree, ".env")); err != nil {
+		t.Errorf("hook did not copy .env: %v", err)
+	}
+	runDir := filepath.Join(dataDir, "repos", st.RepoID, "runs", st.RunID)
+	log, err := os.ReadFile(filepath.Join(runDir, "logs", "hook_post_create_worktree.log"))
+	if err != nil {
+		t.Fatalf("read hook log: %v", err)
+	}
+	if !strings.Contains(string(log), "hook=post_create_worktree run=20260110120000-hook") {
+		t.Errorf("hook log missing env output:\n%s", log)
+	}
+	if !strings.Contains(string(log), "# log_limit: 10485760 bytes (rotate)") {
+		t.Errorf("hook log header missing default limit:\n%s", log)
+	}
+
+	err = svc.RunHook(ctx, st, config.HookPreStartTmux)
+	if errors.GetCode(err) != errors.EScriptFailed {
+		t.Fatalf("expected E_SCRIPT_FAILED, got %v", err)
+	}
+	ae, _ := errors.AsAgencyError(err)
+	if ae.Details["hook"] != config.HookPreStartTmux || ae.Details["exit_code"] != "3" {
+		t.Errorf("details = %v", ae.Details)
+	}
+
+	evs, err := os.ReadFile(filepath.Join(runDir, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	if n := strings.Count(string(evs), `"event":"hook"`); n != 2 {
+		t.Errorf("hook events = %d, want 2:\n%s", n, evs)
+	}
+
+	// Unconfigured hooks are a no-op.
+	if err := svc.RunHook(ctx, st, config.HookPreRunSetup); err != nil {
+		t.Errorf("unconfigured hook: %v", err)
+	}
+}
+
+func TestRefreshGitHubToken(t *testing.T) {
+	dataDir := testkit.DataDir(t)
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	meta := testkit.NewRunMeta("abcd1234ef567890", "20260110120000-a3f2", t.TempDir(), now)
+	meta.Credentials = &store.RunMetaCredentials{Mode: config.CredentialsGh}
+	testkit.WriteRun(t, dataDir, meta)
+
+	cr := testkit.NewFakeRunner()
+	cr.On("gh", "auth", "token").Stdout("gho_abc\n")
+	st := store.NewStore(fs.NewRealFS(), dataDir, func() time.Time { return now })
+
+	path, err := RefreshGitHubToken(context.Background(), cr, fs.NewRealFS(), st, meta, now)
+	if err != nil {
+		t.Fatalf("RefreshGitHubToken() error = %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "gho_abc\n" {
+		t.Errorf("token file = %q, err = %v", data, err)
+	}
+	stored, err := st.ReadMeta(meta.RepoID, meta.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Credentials == nil || stored.Credentials.TokenPath != path || stored.Credentials.MintedAt != "2026-01-10T12:00:00Z" {
+		t.Errorf("meta credentials = %+v", stored.Credentials)
+	}
+
+	// A current token is reused without calling gh again
+	if again, err := RefreshGitHubToken(context.Background(), cr, fs.NewRealFS(), st, stored, now); err != nil || again != path {
+		t.Errorf("second refresh = %q, %v", again, err)
+	}
+	if n := len(cr.CallsTo("gh")); n != 1 {
+		t.Errorf("gh called %d times, want 1", n)
+	}
+
+	// Runs that inherit credentials get no token file
+	if path, err := RefreshGitHubToken(context.Background(), cr, fs.NewRealFS(), st, testkit.NewRunMeta("abcd1234ef567890", "x", "/wt", now), now); path != "" || err != nil {
+		t.Errorf("inherit: = %q, %v", path, err)
+	}
+}
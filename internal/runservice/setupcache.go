@@ -0,0 +1,223 @@
+package runservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// setupCacheVersion is hashed into every key, so changing what a key covers
+// invalidates old entries.
+const setupCacheVersion = "1"
+
+// SetupCacheKeep is how many setup cache entries are kept per repo; the
+// oldest are pruned when a new one is saved.
+const SetupCacheKeep = 5
+
+// setupCacheEntry is <cache_dir>/<key>/entry.json. The cached paths are
+// stored under <cache_dir>/<key>/files/.
+type setupCacheEntry struct {
+	Key       string `json:"key"`
+	RunID     string `json:"run_id"`
+	CreatedAt string `json:"created_at"`
+
+	// Paths are the setup_cache.paths that existed after setup and were saved.
+	Paths []string `json:"paths"`
+
+	// Setup is the evidence of the setup that populated the entry.
+	Setup *store.RunMetaSetup `json:"setup"`
+}
+
+// SetupCacheDir returns the setup cache dir of a repo.
+func SetupCacheDir(dataDir, repoID string) string {
+	return filepath.Join(dataDir, "repos", repoID, "setup_cache")
+}
+
+// setupCacheKey hashes the setup script and the setup_cache inputs (path
+// and contents) in the worktree. A missing file hashes differently from any
+// content, so adding or removing an input changes the key.
+func setupCacheKey(worktreePath, script string, inputs []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "agency setup cache %s\n", setupCacheVersion)
+	for _, p := range append([]string{script}, inputs...) {
+		fmt.Fprintf(h, "%s\n", p)
+		f, err := os.Open(filepath.Join(worktreePath, filepath.FromSlash(p)))
+		if err != nil {
+			fmt.Fprintf(h, "<missing>\n")
+			continue
+		}
+		n, _ := io.Copy(h, f)
+		f.Close()
+		fmt.Fprintf(h, "\n<%d bytes>\n", n)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// readSetupCacheEntry returns the cache entry for key, or nil if there is
+// none (or it is unreadable, which is treated as a miss).
+func readSetupCacheEntry(cacheDir, key string) *setupCacheEntry {
+	data, err := os.ReadFile(filepath.Join(cacheDir, key, "entry.json"))
+	if err != nil {
+		return nil
+	}
+	var entry setupCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil
+	}
+	return &entry
+}
+
+// restoreSetupCache copies an entry's cached paths into the worktree,
+// replacing whatever the checkout put there.
+func restoreSetupCache(fsys fs.FS, cacheDir string, entry *setupCacheEntry, worktreePath string) error {
+	for _, p := range entry.Paths {
+		src := filepath.Join(cacheDir, entry.Key, "files", filepath.FromSlash(p))
+		dst := filepath.Join(worktreePath, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		tmp := dst + ".agency-cache"
+		if err := os.RemoveAll(tmp); err != nil {
+			return err
+		}
+		isDir, err := copyCachePath(fsys, src, tmp)
+		if err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("restore %s: %w", p, err)
+		}
+		if isDir {
+			err = fsys.ReplaceDir(tmp, dst)
+		} else {
+			err = fsys.Rename(tmp, dst)
+		}
+		if err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("restore %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// saveSetupCache stores the worktree's setup_cache paths under key, with
+// setup as the entry's evidence. An entry another run saved for the same key
+// in the meantime is kept. Older entries beyond SetupCacheKeep are pruned.
+func saveSetupCache(fsys fs.FS, dataDir, cacheDir, key, runID string, now time.Time, paths []string, worktreePath string, setup *store.RunMetaSetup) error {
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		return err
+	}
+	if err := fs.ShareDir(dataDir, cacheDir); err != nil {
+		return err
+	}
+	final := filepath.Join(cacheDir, key)
+	if _, err := os.Stat(final); err == nil {
+		return nil
+	}
+
+	tmp := filepath.Join(cacheDir, ".tmp-"+key+"-"+runID)
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	entry := setupCacheEntry{
+		Key:       key,
+		RunID:     runID,
+		CreatedAt: now.UTC().Format(time.RFC3339),
+		Paths:     []string{},
+		Setup:     setup,
+	}
+	for _, p := range paths {
+		src := filepath.Join(worktreePath, filepath.FromSlash(p))
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			continue
+		}
+		dst := filepath.Join(tmp, "files", filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+			return err
+		}
+		if _, err := copyCachePath(fsys, src, dst); err != nil {
+			return fmt.Errorf("save %s: %w", p, err)
+		}
+		entry.Paths = append(entry.Paths, p)
+	}
+	if err := os.MkdirAll(tmp, 0o700); err != nil {
+		return err
+	}
+	if err := fs.WriteJSONAtomic(filepath.Join(tmp, "entry.json"), entry, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		if _, statErr := os.Stat(final); statErr == nil {
+			return nil
+		}
+		return err
+	}
+	_ = fs.ShareDir(dataDir, final)
+
+	pruneSetupCache(cacheDir, SetupCacheKeep)
+	return nil
+}
+
+// pruneSetupCache removes all but the keep most recently saved entries.
+func pruneSetupCache(cacheDir string, keep int) {
+	dirEntries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+	type saved struct {
+		name    string
+		modTime time.Time
+	}
+	var entries []saved
+	for _, d := range dirEntries {
+		if !d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, saved{d.Name(), info.ModTime()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.After(entries[j].modTime)
+	})
+	for i := keep; i < len(entries); i++ {
+		_ = os.RemoveAll(filepath.Join(cacheDir, entries[i].name))
+	}
+}
+
+// copyCachePath copies a file or dir (symlinks are kept as links) and
+// reports whether it was a dir.
+func copyCachePath(fsys fs.FS, src, dst string) (bool, error) {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case info.IsDir():
+		return true, fsys.CopyDir(src, dst)
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(src)
+		if err != nil {
+			return false, err
+		}
+		return false, os.Symlink(link, dst)
+	default:
+		data, err := fsys.ReadFile(src)
+		if err != nil {
+			return false, err
+		}
+		return false, fsys.WriteFile(dst, data, info.Mode().Perm())
+	}
+}
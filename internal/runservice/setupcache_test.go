@@ -0,0 +1,209 @@
+package runservice
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetupCacheKey(t *testing.T) {
+	wt := t.TempDir()
+	inputs := []string{"package-lock.json", "go.sum"}
+
+	missing := setupCacheKey(wt, "setup.sh", inputs)
+	writeTestFile(t, filepath.Join(wt, "package-lock.json"), "{}")
+	base := setupCacheKey(wt, "setup.sh", inputs)
+	if base == missing {
+		t.Error("adding an input file should change the key")
+	}
+	if len(base) != 16 {
+		t.Errorf("key = %q, want 16 hex chars", base)
+	}
+	if again := setupCacheKey(wt, "setup.sh", inputs); again != base {
+		t.Errorf("key is not stable: %q vs %q", again, base)
+	}
+
+	writeTestFile(t, filepath.Join(wt, "package-lock.json"), `{"lockfileVersion": 3}`)
+	if setupCacheKey(wt, "setup.sh", inputs) == base {
+		t.Error("changing an input file should change the key")
+	}
+	if setupCacheKey(wt, "other.sh", inputs) == setupCacheKey(wt, "setup.sh", inputs) {
+		t.Error("changing the setup script should change the key")
+	}
+}
+
+func TestSetupCache_SaveRestore(t *testing.T) {
+	dataDir := t.TempDir()
+	cacheDir := SetupCacheDir(dataDir, "abcd1234ef567890")
+	fsys := fs.NewRealFS()
+
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "node_modules", "left-pad", "index.js"), "module.exports = 1")
+	writeTestFile(t, filepath.Join(src, ".venv", "marker"), "venv")
+	setup := &store.RunMetaSetup{ExitCode: 0, OutputSummary: "deps installed"}
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	paths := []string{"node_modules", ".venv", "vendor"}
+	if err := saveSetupCache(fsys, dataDir, cacheDir, "k1", "run-a", now, paths, src, setup); err != nil {
+		t.Fatalf("saveSetupCache: %v", err)
+	}
+
+	entry := readSetupCacheEntry(cacheDir, "k1")
+	if entry == nil {
+		t.Fatal("entry not found after save")
+	}
+	if entry.RunID != "run-a" || strings.Join(entry.Paths, ",") != "node_modules,.venv" {
+		t.Errorf("entry = %+v, want run-a with the existing paths only", entry)
+	}
+	if entry.Setup == nil || entry.Setup.OutputSummary != "deps installed" {
+		t.Errorf("entry setup = %+v", entry.Setup)
+	}
+	if readSetupCacheEntry(cacheDir, "k2") != nil {
+		t.Error("unknown key should miss")
+	}
+
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(dst, "node_modules", "stale.js"), "stale")
+	if err := restoreSetupCache(fsys, cacheDir, entry, dst); err != nil {
+		t.Fatalf("restoreSetupCache: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "node_modules", "left-pad", "index.js")); err != nil || string(data) != "module.exports = 1" {
+		t.Errorf("restored file = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "node_modules", "stale.js")); !os.IsNotExist(err) {
+		t.Error("restore should replace the existing dir")
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".venv", "marker")); err != nil {
+		t.Errorf(".venv not restored: %v", err)
+	}
+}
+
+func TestPruneSetupCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"a", "b", "c", ".tmp-x"} {
+		dir := filepath.Join(cacheDir, name)
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(dir, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruneSetupCache(cacheDir, 2)
+
+	for name, want := range map[string]bool{"a": false, "b": true, "c": true, ".tmp-x": true} {
+		_, err := os.Stat(filepath.Join(cacheDir, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestService_RunSetup_SetupCache(t *testing.T) {
+	repoRoot, dataDir, cleanup := setupTempRepo(t)
+	defer cleanup()
+	t.Setenv("AGENCY_DATA_DIR", dataDir)
+
+	resolvedRepoRoot, _ := filepath.EvalSymlinks(repoRoot)
+	svc := New()
+	ctx := context.Background()
+	repoID := "abcd1234ef567890"
+
+	// The script counts its runs in the data dir and populates deps/
+	counter := filepath.Join(dataDir, "setup-runs")
+	script := `#!/bin/sh
+echo run >> "` + counter + `"
+mkdir -p deps
+echo installed > deps/lib.txt
+`
+	runSetup := func(runID, title string, force bool) *store.RunMeta {
+		t.Helper()
+		st := &pipeline.PipelineState{
+			RunID:        runID,
+			Title:        title,
+			RepoRoot:     resolvedRepoRoot,
+			RepoID:       repoID,
+			DataDir:      dataDir,
+			ParentBranch: "main",
+			Runner:       "claude",
+		}
+		if err := svc.CreateWorktree(ctx, st); err != nil {
+			t.Fatalf("CreateWorktree failed: %v", err)
+		}
+		st.ResolvedRunnerCmd = "claude"
+		st.SetupScript = "scripts/agency_setup.sh"
+		st.SetupCache = config.SetupCache{Inputs: []string{"deps.lock"}, Paths: []string{"deps"}}
+		st.ForceSetup = force
+		if err := svc.WriteMeta(ctx, st); err != nil {
+			t.Fatalf("WriteMeta failed: %v", err)
+		}
+		writeTestFile(t, filepath.Join(st.WorktreePath, "deps.lock"), "v1")
+		writeTestFile(t, filepath.Join(st.WorktreePath, "scripts", "agency_setup.sh"), script)
+		if err := os.Chmod(filepath.Join(st.WorktreePath, "scripts", "agency_setup.sh"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := svc.RunSetup(ctx, st); err != nil {
+			t.Fatalf("RunSetup(%s) failed: %v", runID, err)
+		}
+		for _, w := range st.Warnings {
+			if w.Code == "W_SETUP_CACHE" {
+				t.Errorf("RunSetup(%s) warning: %s", runID, w.Message)
+			}
+		}
+		if data, err := os.ReadFile(filepath.Join(st.WorktreePath, "deps", "lib.txt")); err != nil || string(data) != "installed\n" {
+			t.Errorf("run %s deps/lib.txt = %q, %v", runID, data, err)
+		}
+		meta, err := store.NewStore(svc.fsys, dataDir, svc.nowFunc).ReadMeta(repoID, runID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return meta
+	}
+	setupRuns := func() int {
+		data, _ := os.ReadFile(counter)
+		return strings.Count(string(data), "run")
+	}
+
+	first := runSetup("20260110120000-cach", "cache one", false)
+	if setupRuns() != 1 || first.Setup.CacheHit || first.Setup.CacheKey == "" {
+		t.Fatalf("first run: setup runs = %d, setup = %+v; want a miss that ran setup", setupRuns(), first.Setup)
+	}
+
+	second := runSetup("20260110120001-cach", "cache two", false)
+	if setupRuns() != 1 {
+		t.Errorf("second run ran setup; want a cache hit")
+	}
+	if !second.Setup.CacheHit || second.Setup.CacheKey != first.Setup.CacheKey || second.Setup.CacheRunID != "20260110120000-cach" {
+		t.Errorf("second run setup = %+v, want a hit from the first run", second.Setup)
+	}
+	log, _ := os.ReadFile(second.Setup.LogPath)
+	if !strings.Contains(string(log), "setup skipped (cache hit)") {
+		t.Errorf("setup.log = %q, want the cache hit recorded", log)
+	}
+
+	forced := runSetup("20260110120002-cach", "cache three", true)
+	if setupRuns() != 2 || forced.Setup.CacheHit {
+		t.Errorf("--force-setup: setup runs = %d, setup = %+v; want setup to run", setupRuns(), forced.Setup)
+	}
+}
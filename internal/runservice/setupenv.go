@@ -0,0 +1,107 @@
+package runservice
+
+import (
+	"context"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// SetupEnvSchemaVersion is the schema_version written to setup_env.json.
+const SetupEnvSchemaVersion = "1.0"
+
+// toolVersionTimeout bounds each tool version probe.
+const toolVersionTimeout = 5 * time.Second
+
+// setupEnvTools are the tools whose versions are captured before setup.
+var setupEnvTools = []struct {
+	name string
+	args []string
+}{
+	{"git", []string{"--version"}},
+	{"tmux", []string{"-V"}},
+	{"gh", []string{"--version"}},
+}
+
+// CaptureSetupEnv builds the setup_env.json snapshot for a script about to
+// run in dir with env, the way scripts run: `sh -lc`. The PATH inside the
+// script is read from such a shell, since login files may change it. Probes
+// are best-effort: a missing tool is recorded with an empty version. agency
+// doctor captures the same for comparison, with no env and no runner.
+func CaptureSetupEnv(ctx context.Context, cr exec.CommandRunner, now time.Time, dir string, env map[string]string, runnerCmd string) store.SetupEnv {
+	captured := make(map[string]string, len(env)+2)
+	for _, kv := range os.Environ() {
+		if k, v, _ := strings.Cut(kv, "="); strings.HasPrefix(k, "AGENCY_") {
+			captured[k] = v
+		}
+	}
+	for k, v := range env {
+		captured[k] = v
+	}
+	for _, k := range []string{"PATH", "SHELL"} {
+		captured[k] = os.Getenv(k)
+	}
+
+	tools := make(map[string]string, len(setupEnvTools)+1)
+	for _, tool := range setupEnvTools {
+		tools[tool.name] = toolVersion(ctx, cr, tool.name, tool.args)
+	}
+	// scripts run via `sh -lc`; which shell that is (dash, bash, ...) often matters
+	tools["sh"] = resolveBinary("sh")
+	if runnerCmd != "" {
+		// Runners may be interactive, so record where the binary resolves instead of running it
+		tools["runner"] = resolveBinary(runnerCmd)
+	}
+
+	hostname, _ := os.Hostname()
+	setupEnv := store.SetupEnv{
+		SchemaVersion: SetupEnvSchemaVersion,
+		CapturedAt:    now.UTC().Format(time.RFC3339),
+		Hostname:      hostname,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		Env:           captured,
+		Tools:         tools,
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, toolVersionTimeout)
+	defer cancel()
+	result, err := cr.Run(probeCtx, "sh", []string{"-lc", `printf '%s' "$PATH"`}, exec.RunOpts{Dir: dir, Env: env})
+	if err == nil && result.ExitCode == 0 {
+		setupEnv.ScriptPath = result.Stdout
+	}
+	if tools["sh"] != "" {
+		setupEnv.ShellVersion = toolVersion(ctx, cr, tools["sh"], []string{"--version"})
+	}
+	return setupEnv
+}
+
+// resolveBinary returns the symlink-resolved path of name on PATH, or "".
+func resolveBinary(name string) string {
+	path, err := osexec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// toolVersion returns the first line of a tool's version output, or "" if it fails.
+func toolVersion(ctx context.Context, cr exec.CommandRunner, name string, args []string) string {
+	ctx, cancel := context.WithTimeout(ctx, toolVersionTimeout)
+	defer cancel()
+	result, err := cr.Run(ctx, name, args, exec.RunOpts{})
+	if err != nil || result.ExitCode != 0 {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(result.Stdout), "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,175 @@
+package runservice
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/worktree"
+)
+
+// VerifyTimeout is the timeout for the verify script.
+const VerifyTimeout = 30 * time.Minute
+
+// VerifyOpts contains the inputs for verifying an existing run.
+type VerifyOpts struct {
+	// Script is scripts.verify from agency.json.
+	Script string
+
+	// RepoRoot is the repo checkout the run belongs to (AGENCY_REPO_ROOT;
+	// empty if unknown).
+	RepoRoot string
+
+	// OriginURL is the repo's origin URL (AGENCY_ORIGIN_URL; may be empty).
+	OriginURL string
+
+	// Logs is the logs config from agency.json (verify.log size limit).
+	Logs config.Logs
+
+	// Timeout overrides VerifyTimeout when non-zero.
+	Timeout time.Duration
+}
+
+// VerifyResult is the outcome of one verify script run.
+type VerifyResult struct {
+	// Attempt is the attempt recorded in verify.jsonl.
+	Attempt store.VerifyAttempt
+
+	ExitCode int
+	TimedOut bool
+	LogPath  string
+}
+
+// RunVerify executes scripts.verify for an existing run via `sh -lc` in its
+// worktree, with the same AGENCY_* environment as setup plus the run's PR.
+// Output goes to logs/verify.log. A .agency/out/verify.json written by the
+// script (setup.json format) decides the outcome and summary; without one,
+// the exit code does. A timeout is a failure.
+//
+// The attempt is appended to verify.jsonl (setting last_verify_at) and a
+// verify event to events.jsonl. A passing verification records the
+// worktree's HEAD as meta.report_commit. A failed verification is not an error: it
+// is reported in the result. Returns E_WORKTREE_MISSING if the worktree is
+// gone, and persistence errors.
+func RunVerify(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, st *store.Store, meta *store.RunMeta, opts VerifyOpts) (VerifyResult, error) {
+	if info, err := fsys.Stat(meta.WorktreePath); err != nil || !info.IsDir() {
+		return VerifyResult{}, errors.NewWithDetails(
+			errors.EWorktreeMissing,
+			"worktree no longer exists; verify needs the run's checkout",
+			map[string]string{"worktree_path": meta.WorktreePath},
+		)
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = VerifyTimeout
+	}
+
+	logsDir := st.RunLogsDir(meta.RepoID, meta.RunID)
+	logPath := filepath.Join(logsDir, "verify.log")
+	if err := fsys.MkdirAll(logsDir, 0o700); err != nil {
+		return VerifyResult{}, errors.WrapWithDetails(
+			errors.EInternal,
+			"failed to ensure logs directory exists",
+			err,
+			map[string]string{"logs_dir": logsDir},
+		)
+	}
+
+	env := buildSetupEnv(&pipeline.PipelineState{
+		RunID:        meta.RunID,
+		Title:        meta.Title,
+		RepoRoot:     opts.RepoRoot,
+		WorktreePath: meta.WorktreePath,
+		Branch:       meta.Branch,
+		ParentBranch: meta.ParentBranch,
+		OriginURL:    opts.OriginURL,
+		Runner:       meta.Runner,
+	}, logsDir)
+	env["AGENCY_PR_URL"] = meta.PRURL
+	if meta.PRNumber > 0 {
+		env["AGENCY_PR_NUMBER"] = strconv.Itoa(meta.PRNumber)
+	}
+
+	// Never read a verify.json left by an earlier attempt (or by setup)
+	if _, err := worktree.RotateOutputDir(meta.WorktreePath, "verify", st.Now()); err != nil {
+		return VerifyResult{}, errors.WrapWithDetails(
+			errors.EInternal,
+			"failed to prepare .agency/out",
+			err,
+			map[string]string{"output_dir": worktree.OutputDir(meta.WorktreePath)},
+		)
+	}
+	_ = st.WriteSetupEnv(meta.RepoID, meta.RunID, "verify", CaptureSetupEnv(ctx, cr, st.Now(), meta.WorktreePath, env, ""))
+
+	result := executeScript(ctx, "verify", opts.Script, meta.WorktreePath, env, logPath, opts.Logs, timeout, "")
+
+	ok := !result.Failed
+	var summary string
+	switch {
+	case result.TimedOut:
+		summary = "timed out after " + timeout.String()
+	case result.Failed && result.ExitCode < 0:
+		summary = "failed to start"
+	case result.Failed:
+		summary = fmt.Sprintf("exit %d", result.ExitCode)
+	}
+	if out := parseSetupJSON(fsys, filepath.Join(worktree.OutputDir(meta.WorktreePath), "verify.json")); out != nil && !result.TimedOut {
+		if out.Ok != nil {
+			ok = *out.Ok
+		}
+		if out.Summary != "" {
+			summary = out.Summary
+		}
+	}
+
+	attempt, err := st.AppendVerifyAttempt(meta.RepoID, meta.RunID, store.VerifyAttempt{
+		OK:         ok,
+		DurationMs: result.DurationMs,
+		Summary:    summary,
+	})
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	if ok {
+		if err := recordReportCommit(ctx, cr, st, meta); err != nil {
+			return VerifyResult{}, err
+		}
+	}
+
+	_ = events.AppendEvent(events.EventsPath(st.RunDir(meta.RepoID, meta.RunID)), events.New(st.Now(), meta.RepoID, meta.RunID, "verify", map[string]any{
+		"ok":          ok,
+		"exit_code":   result.ExitCode,
+		"duration_ms": result.DurationMs,
+		"timed_out":   result.TimedOut,
+	}))
+
+	return VerifyResult{
+		Attempt:  attempt,
+		ExitCode: result.ExitCode,
+		TimedOut: result.TimedOut,
+		LogPath:  logPath,
+	}, nil
+}
+
+// recordReportCommit sets meta.report_commit to the worktree's HEAD, the
+// commit a passing verify covers. Nothing is recorded if HEAD cannot be read.
+func recordReportCommit(ctx context.Context, cr exec.CommandRunner, st *store.Store, meta *store.RunMeta) error {
+	result, err := cr.Run(ctx, "git", []string{"rev-parse", "--verify", "HEAD"}, exec.RunOpts{Dir: meta.WorktreePath})
+	if err != nil || result.ExitCode != 0 {
+		return nil
+	}
+	head := strings.TrimSpace(result.Stdout)
+	return st.UpdateMeta(meta.RepoID, meta.RunID, func(m *store.RunMeta) {
+		m.ReportCommit = head
+	})
+}
@@ -0,0 +1,65 @@
+package scaffold
+
+import (
+	"strings"
+)
+
+// Branch guard modes.
+const (
+	// BranchGuardWarn prints a warning when an agency/* branch is checked out
+	// in the main working copy.
+	BranchGuardWarn = "warn"
+	// BranchGuardBlock additionally switches back to the previous HEAD.
+	BranchGuardBlock = "block"
+)
+
+// BranchGuardMarker identifies a post-checkout hook installed by agency.
+const BranchGuardMarker = "# agency-branch-guard"
+
+// branchGuardHook is the post-checkout hook body; @MODE@ is the installed mode.
+// Git has no pre-checkout hook, so blocking means checking the previous HEAD
+// back out. Linked worktrees (where runs live) have their own git dir and are
+// never guarded. AGENCY_BRANCH_GUARD=off|warn|block overrides the mode.
+const branchGuardHook = `#!/bin/sh
+` + BranchGuardMarker + ` mode=@MODE@
+# installed by 'agency branch-guard'; remove with 'agency branch-guard --uninstall'
+mode="${AGENCY_BRANCH_GUARD:-@MODE@}"
+[ "$3" = "1" ] || exit 0
+[ "$mode" = "off" ] && exit 0
+
+git_dir=$(cd "$(git rev-parse --git-dir)" && pwd -P) || exit 0
+common_dir=$(cd "$(git rev-parse --git-common-dir)" && pwd -P) || exit 0
+[ "$git_dir" = "$common_dir" ] || exit 0
+
+branch=$(git symbolic-ref --short -q HEAD) || exit 0
+case "$branch" in
+agency/*) ;;
+*) exit 0 ;;
+esac
+
+if [ "$mode" = "block" ]; then
+	echo "agency: refusing to check out $branch in the main working copy; it belongs to an agency run" >&2
+	echo "agency: work on it in the run's worktree (agency ls / agency attach), or set AGENCY_BRANCH_GUARD=warn" >&2
+	AGENCY_BRANCH_GUARD=off git checkout -q - >&2 || AGENCY_BRANCH_GUARD=off git checkout -q "$1" >&2
+	exit 1
+fi
+echo "agency: warning: $branch belongs to an agency run; work on it in the run's worktree (agency ls / agency attach)" >&2
+exit 0
+`
+
+// BranchGuardHook returns the post-checkout hook script for mode
+// (BranchGuardWarn or BranchGuardBlock).
+func BranchGuardHook(mode string) string {
+	return strings.ReplaceAll(branchGuardHook, "@MODE@", mode)
+}
+
+// BranchGuardMode returns the mode of an installed hook, or "" if content
+// is not a hook installed by agency.
+func BranchGuardMode(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if rest, ok := strings.CutPrefix(line, BranchGuardMarker+" mode="); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	return ""
+}
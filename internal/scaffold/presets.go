@@ -0,0 +1,90 @@
+package scaffold
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+
+	agencyfs "github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// presetFiles holds the stub presets: presets/<name>/<script>.sh replaces
+// the stub of the same name. Scripts a preset does not provide keep the
+// default stub. Adding a directory adds a preset.
+//
+//go:embed presets
+var presetFiles embed.FS
+
+// PresetNone selects the default (empty) stubs.
+const PresetNone = "none"
+
+// presetMarkers maps files found at a repo root to the preset they imply,
+// in detection priority order.
+var presetMarkers = []struct {
+	file   string
+	preset string
+}{
+	{"go.mod", "go"},
+	{"Cargo.toml", "rust"},
+	{"package.json", "node"},
+	{"pyproject.toml", "python"},
+}
+
+// Presets returns the names of the embedded presets, sorted.
+func Presets() []string {
+	entries, err := presetFiles.ReadDir("presets")
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsPreset reports whether name is an embedded preset or PresetNone.
+func IsPreset(name string) bool {
+	if name == PresetNone {
+		return true
+	}
+	for _, p := range Presets() {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectPreset returns the preset implied by the first marker file
+// (go.mod, Cargo.toml, package.json, pyproject.toml) present at repoRoot,
+// and the marker it found. Returns PresetNone and "" if there is none.
+func DetectPreset(fsys agencyfs.FS, repoRoot string) (preset, marker string) {
+	for _, m := range presetMarkers {
+		if _, err := fsys.Stat(filepath.Join(repoRoot, m.file)); err == nil {
+			return m.preset, m.file
+		}
+	}
+	return PresetNone, ""
+}
+
+// PresetStubs returns the stub scripts for preset: DefaultStubs with each
+// script the preset provides swapped in. PresetNone returns DefaultStubs.
+func PresetStubs(preset string) []StubScript {
+	stubs := DefaultStubs()
+	if preset == PresetNone {
+		return stubs
+	}
+	for i, stub := range stubs {
+		content, err := fs.ReadFile(presetFiles, path.Join("presets", preset, path.Base(stub.RelPath)))
+		if err == nil {
+			stubs[i].Content = string(content)
+		}
+	}
+	return stubs
+}
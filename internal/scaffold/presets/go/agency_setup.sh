@@ -0,0 +1,4 @@
+#!/usr/bin/env bash
+set -euo pipefail
+# agency preset (go): download module dependencies
+go mod download
@@ -0,0 +1,6 @@
+#!/usr/bin/env bash
+set -euo pipefail
+# agency preset (go): build, vet, and test every package
+go build ./...
+go vet ./...
+go test ./...
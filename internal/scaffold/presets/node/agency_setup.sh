@@ -0,0 +1,12 @@
+#!/usr/bin/env bash
+set -euo pipefail
+# agency preset (node): install dependencies from the lockfile
+if [ -f pnpm-lock.yaml ]; then
+  pnpm install --frozen-lockfile
+elif [ -f yarn.lock ]; then
+  yarn install --frozen-lockfile
+elif [ -f package-lock.json ]; then
+  npm ci
+else
+  npm install
+fi
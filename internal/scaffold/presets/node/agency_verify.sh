@@ -0,0 +1,10 @@
+#!/usr/bin/env bash
+set -euo pipefail
+# agency preset (node): run the package's test script
+if [ -f pnpm-lock.yaml ]; then
+  pnpm test
+elif [ -f yarn.lock ]; then
+  yarn test
+else
+  npm test
+fi
@@ -0,0 +1,8 @@
+#!/usr/bin/env bash
+set -euo pipefail
+# agency preset (python): create .venv and install the project into it
+python3 -m venv .venv
+.venv/bin/python -m pip install --upgrade pip
+if ! .venv/bin/python -m pip install -e '.[dev]'; then
+  .venv/bin/python -m pip install -e .
+fi
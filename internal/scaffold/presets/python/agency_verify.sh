@@ -0,0 +1,4 @@
+#!/usr/bin/env bash
+set -euo pipefail
+# agency preset (python): run the test suite in .venv
+.venv/bin/python -m pytest
@@ -0,0 +1,4 @@
+#!/usr/bin/env bash
+set -euo pipefail
+# agency preset (rust): fetch crate dependencies
+cargo fetch
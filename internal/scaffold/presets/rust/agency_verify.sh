@@ -0,0 +1,5 @@
+#!/usr/bin/env bash
+set -euo pipefail
+# agency preset (rust): build and test the workspace
+cargo build --all-targets
+cargo test
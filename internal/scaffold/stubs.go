@@ -51,11 +51,11 @@ type CreateStubsResult struct {
 	Skipped []string // relative paths of scripts that already existed
 }
 
-// CreateStubs creates stub scripts under repoRoot if they don't exist.
-// Never overwrites existing scripts. Sets mode 0755 on created scripts.
-func CreateStubs(fsys fs.FS, repoRoot string) (CreateStubsResult, error) {
+// CreateStubs creates stubs (DefaultStubs or PresetStubs) under repoRoot
+// if they don't exist. Never overwrites existing scripts. Sets mode 0755 on
+// created scripts.
+func CreateStubs(fsys fs.FS, repoRoot string, stubs []StubScript) (CreateStubsResult, error) {
 	result := CreateStubsResult{}
-	stubs := DefaultStubs()
 
 	// Ensure scripts/ directory exists
 	scriptsDir := filepath.Join(repoRoot, "scripts")
@@ -1,6 +1,12 @@
 // Package scaffold provides helpers for creating agency.json and stub scripts.
 package scaffold
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 // AgencyJSONTemplate is the exact template for agency.json per L0 spec.
 // This must match the constitution exactly.
 const AgencyJSONTemplate = `{
@@ -20,3 +26,55 @@ const AgencyJSONTemplate = `{
   }
 }
 `
+
+// AgencyJSONOptions are the choices made in the `agency init` wizard.
+type AgencyJSONOptions struct {
+	ParentBranch string
+	Runner       string
+
+	// GitHubFlow false writes "github": {"flow": false}, so doctor skips
+	// the gh checks.
+	GitHubFlow bool
+}
+
+// DefaultAgencyJSONOptions renders exactly AgencyJSONTemplate.
+var DefaultAgencyJSONOptions = AgencyJSONOptions{ParentBranch: "main", Runner: "claude", GitHubFlow: true}
+
+// RenderAgencyJSON returns agency.json for opts, laid out like
+// AgencyJSONTemplate. A runner other than claude/codex is added to
+// runners as a command of the same name.
+func RenderAgencyJSON(opts AgencyJSONOptions) string {
+	runners := []string{"claude", "codex"}
+	if opts.Runner != "claude" && opts.Runner != "codex" {
+		runners = append(runners, opts.Runner)
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n  \"version\": 1,\n  \"defaults\": {\n")
+	fmt.Fprintf(&b, "    \"parent_branch\": %s,\n", jsonString(opts.ParentBranch))
+	fmt.Fprintf(&b, "    \"runner\": %s\n", jsonString(opts.Runner))
+	b.WriteString("  },\n  \"scripts\": {\n" +
+		"    \"setup\": \"scripts/agency_setup.sh\",\n" +
+		"    \"verify\": \"scripts/agency_verify.sh\",\n" +
+		"    \"archive\": \"scripts/agency_archive.sh\"\n" +
+		"  },\n  \"runners\": {\n")
+	for i, name := range runners {
+		sep := ","
+		if i == len(runners)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&b, "    %s: %s%s\n", jsonString(name), jsonString(name), sep)
+	}
+	b.WriteString("  }")
+	if !opts.GitHubFlow {
+		b.WriteString(",\n  \"github\": {\n    \"flow\": false\n  }")
+	}
+	b.WriteString("\n}\n")
+	return b.String()
+}
+
+// jsonString quotes s as a JSON string.
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
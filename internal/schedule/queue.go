@@ -0,0 +1,339 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/identity"
+)
+
+// Entry states.
+const (
+	// StateQueued is a run waiting for its turn or for a limit.
+	StateQueued = "queued"
+
+	// StateStarting is a run admitted by the limits and being set up; it
+	// counts as a tmux session until its entry is removed.
+	StateStarting = "starting"
+)
+
+// DefaultPollInterval is how often a queued run checks the limits again.
+const DefaultPollInterval = 5 * time.Second
+
+// Admission lock timing: how often a held lock is retried, and the age
+// after which a lock is removed whatever its holder (it is only held while
+// one run is checked against the limits).
+const (
+	lockPollInterval = 50 * time.Millisecond
+	lockStaleAfter   = time.Minute
+)
+
+// Entry is a run in the queue, stored at ${AGENCY_DATA_DIR}/queue/<run_id>.json.
+type Entry struct {
+	RunID    string    `json:"run_id"`
+	RepoID   string    `json:"repo_id"`
+	Title    string    `json:"title,omitempty"`
+	State    string    `json:"state"`
+	QueuedAt time.Time `json:"queued_at"`
+
+	// Reason is why a queued run is waiting (e.g. "8/8 agency tmux sessions").
+	Reason string `json:"reason,omitempty"`
+
+	// PID and Host identify the agency process holding the entry; entries
+	// of processes that are gone are removed by the next Entries call.
+	PID  int    `json:"pid"`
+	Host string `json:"host"`
+}
+
+// Request identifies the run asking to start.
+type Request struct {
+	RunID  string
+	RepoID string
+	Title  string
+}
+
+// Queue orders the runs waiting for the limits, first in, first out. Every
+// limit is a property of this machine, so entries written by other hosts
+// sharing the data dir are ignored.
+type Queue struct {
+	DataDir      string
+	Probe        Probe
+	PollInterval time.Duration
+	Now          func() time.Time
+	IsPIDAlive   func(pid int) bool
+	PID          int
+	Host         string
+}
+
+// NewQueue returns the queue of dataDir measuring resources with probe.
+func NewQueue(dataDir string, probe Probe) Queue {
+	return Queue{
+		DataDir:      dataDir,
+		Probe:        probe,
+		PollInterval: DefaultPollInterval,
+		Now:          time.Now,
+		IsPIDAlive:   isPIDAlive,
+		PID:          os.Getpid(),
+		Host:         identity.Hostname(),
+	}
+}
+
+// Dir returns the queue directory.
+func (q Queue) Dir() string {
+	return filepath.Join(q.DataDir, "queue")
+}
+
+// Wait returns once req may start under limits: no run queued before it is
+// still waiting, and no limit is reached. Until then req is held in the
+// queue in StateQueued, and notify is called with the reason whenever it
+// changes. Once admitted, the entry moves to StateStarting; the caller must
+// call release once the run's tmux session exists (or the run has failed).
+//
+// With noWait, Wait does not queue: it returns E_SCHEDULE_BLOCKED if req
+// cannot start right away. If ctx is done while waiting, ctx.Err() is
+// returned. Either way the entry is removed.
+func (q Queue) Wait(ctx context.Context, req Request, limits Limits, noWait bool, notify func(reason string)) (release func(), err error) {
+	entry := Entry{
+		RunID:    req.RunID,
+		RepoID:   req.RepoID,
+		Title:    req.Title,
+		State:    StateQueued,
+		QueuedAt: q.Now().UTC(),
+		PID:      q.PID,
+		Host:     q.Host,
+	}
+	path := filepath.Join(q.Dir(), req.RunID+".json")
+	release = func() { _ = os.Remove(path) }
+	if err := q.write(path, entry); err != nil {
+		return nil, err
+	}
+
+	for {
+		admitted, reason, err := q.admit(ctx, path, &entry, limits)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		if admitted {
+			return release, nil
+		}
+		if noWait {
+			release()
+			return nil, errors.WithHints(
+				errors.NewWithDetails(errors.EScheduleBlocked, "cannot start the run now: "+reason,
+					map[string]string{"run_id": req.RunID, "reason": reason}),
+				"run again without --no-wait to queue until it can start",
+			)
+		}
+		if reason != entry.Reason {
+			entry.Reason = reason
+			if err := q.write(path, entry); err != nil {
+				release()
+				return nil, err
+			}
+			if notify != nil {
+				notify(reason)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		case <-time.After(q.PollInterval):
+		}
+	}
+}
+
+// admit moves entry, stored at path, to StateStarting if it may start now,
+// and otherwise returns why not. It holds the queue's admission lock, so two
+// runs cannot both see, and take, the last slot.
+func (q Queue) admit(ctx context.Context, path string, entry *Entry, limits Limits) (bool, string, error) {
+	unlock, err := q.lock(ctx)
+	if err != nil {
+		return false, "", err
+	}
+	defer unlock()
+
+	reason, err := q.blocked(ctx, *entry, limits)
+	if err != nil || reason != "" {
+		return false, reason, err
+	}
+	entry.State, entry.Reason = StateStarting, ""
+	if err := q.write(path, *entry); err != nil {
+		return false, "", err
+	}
+	return true, "", nil
+}
+
+// lock takes the admission lock, ${AGENCY_DATA_DIR}/queue/.lock, waiting
+// while another process holds it. A lock whose holder on this host is gone,
+// or older than lockStaleAfter, is removed.
+func (q Queue) lock(ctx context.Context) (unlock func(), err error) {
+	if err := os.MkdirAll(q.Dir(), 0o755); err != nil {
+		return nil, errors.WrapWithDetails(errors.EInternal, "failed to create run queue dir", err,
+			map[string]string{"path": q.Dir()})
+	}
+	path := filepath.Join(q.Dir(), ".lock")
+	holder := lockHolder{PID: q.PID, Host: q.Host}
+	data, _ := json.Marshal(holder)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = f.Write(data)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(path)
+				return nil, errors.WrapWithDetails(errors.EInternal, "failed to write run queue lock", err,
+					map[string]string{"path": path})
+			}
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, errors.WrapWithDetails(errors.EInternal, "failed to create run queue lock", err,
+				map[string]string{"path": path})
+		}
+		if q.lockStale(path) {
+			_ = os.Remove(path)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// lockHolder is the content of the admission lock.
+type lockHolder struct {
+	PID  int    `json:"pid"`
+	Host string `json:"host"`
+}
+
+// lockStale reports whether the admission lock at path was left behind.
+func (q Queue) lockStale(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false // released since
+	}
+	if q.Now().Sub(info.ModTime()) > lockStaleAfter {
+		return true
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var h lockHolder
+	if err := json.Unmarshal(data, &h); err != nil {
+		return false // being written
+	}
+	return h.Host == q.Host && !q.IsPIDAlive(h.PID)
+}
+
+// blocked returns why entry cannot start now ("" if it can).
+func (q Queue) blocked(ctx context.Context, entry Entry, limits Limits) (string, error) {
+	entries, err := q.Entries()
+	if err != nil {
+		return "", err
+	}
+	ahead, starting := 0, 0
+	for _, e := range entries {
+		switch {
+		case e.RunID == entry.RunID:
+		case e.State == StateStarting:
+			starting++
+		case e.QueuedAt.Before(entry.QueuedAt) || (e.QueuedAt.Equal(entry.QueuedAt) && e.RunID < entry.RunID):
+			ahead++
+		}
+	}
+	if ahead > 0 {
+		return pluralRuns(ahead) + " queued ahead", nil
+	}
+	return limits.blocked(ctx, q.Probe, q.DataDir, starting)
+}
+
+// Entries returns this host's queue entries, oldest first. Entries of
+// agency processes that are gone are removed.
+func (q Queue) Entries() ([]Entry, error) {
+	dirEntries, err := os.ReadDir(q.Dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WrapWithDetails(errors.EInternal, "failed to read run queue", err,
+			map[string]string{"path": q.Dir()})
+	}
+	var entries []Entry
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(q.Dir(), de.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // removed since ReadDir
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil || e.Host != q.Host {
+			continue
+		}
+		if !q.IsPIDAlive(e.PID) {
+			_ = os.Remove(path)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].QueuedAt.Equal(entries[j].QueuedAt) {
+			return entries[i].QueuedAt.Before(entries[j].QueuedAt)
+		}
+		return entries[i].RunID < entries[j].RunID
+	})
+	return entries, nil
+}
+
+// write stores entry at path.
+func (q Queue) write(path string, entry Entry) error {
+	if err := os.MkdirAll(q.Dir(), 0o755); err != nil {
+		return errors.WrapWithDetails(errors.EInternal, "failed to create run queue dir", err,
+			map[string]string{"path": q.Dir()})
+	}
+	if err := fs.WriteJSONAtomic(path, entry, 0o644); err != nil {
+		return errors.WrapWithDetails(errors.EInternal, "failed to write run queue entry", err,
+			map[string]string{"path": path})
+	}
+	return nil
+}
+
+// pluralRuns renders n runs, e.g. "1 run" or "3 runs".
+func pluralRuns(n int) string {
+	if n == 1 {
+		return "1 run"
+	}
+	return strconv.Itoa(n) + " runs"
+}
+
+// isPIDAlive reports whether a process with pid exists (signal 0).
+func isPIDAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
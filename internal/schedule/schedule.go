@@ -0,0 +1,175 @@
+// Package schedule holds new runs back until the machine has room for them.
+// Limits on live agency tmux sessions, free disk, and load average are
+// checked before a run creates its worktree; runs that must wait are queued
+// first in, first out under ${AGENCY_DATA_DIR}/queue (see Queue).
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+)
+
+// TmuxSessionPrefix is the prefix of the tmux sessions agency creates
+// (runservice.TmuxSessionPrefix).
+const TmuxSessionPrefix = "agency_"
+
+// Limits are the conditions a run waits for before it starts. A zero field
+// sets no limit.
+type Limits struct {
+	// MaxSessions caps the live agency tmux sessions across all repos,
+	// counting runs already admitted but still being set up.
+	MaxSessions int
+
+	// MinFreeDiskMB is the free space, in MiB, the data dir's filesystem
+	// (where worktrees are created) must have.
+	MinFreeDiskMB int
+
+	// MaxLoad caps the 1-minute load average.
+	MaxLoad float64
+}
+
+// IsZero reports whether l sets no limit.
+func (l Limits) IsZero() bool {
+	return l.MaxSessions == 0 && l.MinFreeDiskMB == 0 && l.MaxLoad == 0
+}
+
+// Probe measures the resources Limits constrain.
+type Probe struct {
+	// Sessions returns the number of live agency tmux sessions.
+	Sessions func(ctx context.Context) (int, error)
+
+	// FreeBytes returns the bytes available on the filesystem holding path.
+	FreeBytes func(path string) (uint64, error)
+
+	// LoadAvg returns the 1-minute load average.
+	LoadAvg func(ctx context.Context) (float64, error)
+}
+
+// NewProbe returns a Probe measuring this machine: tmux list-sessions,
+// statfs(2), and /proc/loadavg (`sysctl -n vm.loadavg` where there is no
+// /proc, as on macOS).
+func NewProbe(cr exec.CommandRunner) Probe {
+	return Probe{
+		Sessions:  func(ctx context.Context) (int, error) { return countSessions(ctx, cr), nil },
+		FreeBytes: statFreeBytes,
+		LoadAvg:   func(ctx context.Context) (float64, error) { return loadAvg(ctx, cr) },
+	}
+}
+
+// blocked returns why l keeps a run from starting now ("" if nothing does).
+// starting is the number of runs admitted whose sessions may not exist yet.
+// Only the resources l limits are measured.
+func (l Limits) blocked(ctx context.Context, p Probe, dataDir string, starting int) (string, error) {
+	if l.MaxSessions > 0 {
+		sessions, err := p.Sessions(ctx)
+		if err != nil {
+			return "", err
+		}
+		if n := sessions + starting; n >= l.MaxSessions {
+			return fmt.Sprintf("%d/%d agency tmux sessions", n, l.MaxSessions), nil
+		}
+	}
+	if l.MinFreeDiskMB > 0 {
+		dir := existingAncestor(dataDir)
+		free, err := p.FreeBytes(dir)
+		if err != nil {
+			return "", errors.WrapWithDetails(errors.EInternal, "failed to read free disk space", err,
+				map[string]string{"path": dir})
+		}
+		if mb := free >> 20; mb < uint64(l.MinFreeDiskMB) {
+			return fmt.Sprintf("%d MiB free on %s, need %d MiB", mb, dir, l.MinFreeDiskMB), nil
+		}
+	}
+	if l.MaxLoad > 0 {
+		load, err := p.LoadAvg(ctx)
+		if err != nil {
+			return "", err
+		}
+		if load >= l.MaxLoad {
+			return fmt.Sprintf("load average %s, limit %s", formatLoad(load), formatLoad(l.MaxLoad)), nil
+		}
+	}
+	return "", nil
+}
+
+// countSessions returns the number of live agency tmux sessions; 0 if tmux
+// is not installed or no server is running.
+func countSessions(ctx context.Context, cr exec.CommandRunner) int {
+	result, err := cr.Run(ctx, "tmux", []string{"list-sessions", "-F", "#{session_name}"}, exec.RunOpts{})
+	if err != nil || result.ExitCode != 0 {
+		return 0
+	}
+	n := 0
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), TmuxSessionPrefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// statFreeBytes returns the bytes available to unprivileged users on the
+// filesystem holding path.
+func statFreeBytes(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
+
+// loadAvg returns the 1-minute load average.
+// Returns E_INTERNAL if neither /proc/loadavg nor sysctl reports it.
+func loadAvg(ctx context.Context, cr exec.CommandRunner) (float64, error) {
+	if data, err := os.ReadFile("/proc/loadavg"); err == nil {
+		if load, ok := parseLoadAvg(string(data)); ok {
+			return load, nil
+		}
+	}
+	result, err := cr.Run(ctx, "sysctl", []string{"-n", "vm.loadavg"}, exec.RunOpts{})
+	if err == nil && result.ExitCode == 0 {
+		if load, ok := parseLoadAvg(result.Stdout); ok {
+			return load, nil
+		}
+	}
+	return 0, errors.WithHints(
+		errors.New(errors.EInternal, "failed to read the load average (no /proc/loadavg or sysctl vm.loadavg)"),
+		"unset the load limit: agency config set scheduling.max_load 0",
+	)
+}
+
+// parseLoadAvg parses the first number of /proc/loadavg ("0.52 0.58 0.59
+// 1/467 12345") or `sysctl -n vm.loadavg` ("{ 1.23 1.45 1.67 }").
+func parseLoadAvg(s string) (float64, bool) {
+	for _, field := range strings.Fields(s) {
+		if field == "{" {
+			continue
+		}
+		load, err := strconv.ParseFloat(field, 64)
+		return load, err == nil
+	}
+	return 0, false
+}
+
+// existingAncestor returns dir, or its nearest ancestor that exists (the
+// data dir may not have been created yet).
+func existingAncestor(dir string) string {
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || filepath.Dir(d) == d {
+			return d
+		}
+	}
+}
+
+// formatLoad renders a load average, e.g. "3.5".
+func formatLoad(load float64) string {
+	return strconv.FormatFloat(load, 'f', -1, 64)
+}
@@ -0,0 +1,227 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// fakeProbe reports fixed usage.
+func fakeProbe(sessions int, freeMB uint64, load float64) Probe {
+	return Probe{
+		Sessions:  func(context.Context) (int, error) { return sessions, nil },
+		FreeBytes: func(string) (uint64, error) { return freeMB << 20, nil },
+		LoadAvg:   func(context.Context) (float64, error) { return load, nil },
+	}
+}
+
+// testQueue returns a queue in a temp data dir in which every pid is alive.
+func testQueue(t *testing.T, probe Probe) Queue {
+	t.Helper()
+	q := NewQueue(t.TempDir(), probe)
+	q.PollInterval = time.Millisecond
+	q.IsPIDAlive = func(int) bool { return true }
+	q.Host = "host-a"
+	return q
+}
+
+func TestLimitsBlocked(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   Limits
+		starting int
+		want     string
+	}{
+		{"no limits", Limits{}, 0, ""},
+		{"sessions below limit", Limits{MaxSessions: 4}, 0, ""},
+		{"starting runs count as sessions", Limits{MaxSessions: 4}, 1, "4/4 agency tmux sessions"},
+		{"disk", Limits{MinFreeDiskMB: 2048}, 0, "1024 MiB free on "},
+		{"load", Limits{MaxLoad: 2.5}, 0, "load average 3.5, limit 2.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.limits.blocked(context.Background(), fakeProbe(3, 1024, 3.5), t.TempDir(), tt.starting)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("blocked() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLoadAvg(t *testing.T) {
+	for in, want := range map[string]float64{
+		"0.52 0.58 0.59 1/467 12345\n": 0.52,
+		"{ 1.23 1.45 1.67 }\n":         1.23,
+	} {
+		if got, ok := parseLoadAvg(in); !ok || got != want {
+			t.Errorf("parseLoadAvg(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	if _, ok := parseLoadAvg(""); ok {
+		t.Error("parseLoadAvg(\"\") ok")
+	}
+}
+
+func TestCountSessions(t *testing.T) {
+	cr := testkit.NewFakeRunner()
+	cr.TmuxSessions("agency_a", "work", "agency_b")
+	if n := countSessions(context.Background(), cr); n != 2 {
+		t.Errorf("countSessions() = %d, want 2", n)
+	}
+	cr = testkit.NewFakeRunner()
+	cr.TmuxNoServer()
+	if n := countSessions(context.Background(), cr); n != 0 {
+		t.Errorf("countSessions() without a server = %d, want 0", n)
+	}
+}
+
+func TestQueueWait(t *testing.T) {
+	ctx := context.Background()
+	sessions := 2
+	probe := fakeProbe(0, 1<<20, 0)
+	probe.Sessions = func(context.Context) (int, error) { return sessions, nil }
+	q := testQueue(t, probe)
+	limits := Limits{MaxSessions: 2}
+
+	// --no-wait fails at once and leaves no entry
+	_, err := q.Wait(ctx, Request{RunID: "r1"}, limits, true, nil)
+	if errors.GetCode(err) != errors.EScheduleBlocked {
+		t.Fatalf("Wait(no-wait) code = %q, want %q", errors.GetCode(err), errors.EScheduleBlocked)
+	}
+	if entries, _ := q.Entries(); len(entries) != 0 {
+		t.Fatalf("entries after --no-wait = %+v", entries)
+	}
+
+	// Queued until a session ends, reporting why
+	var reasons []string
+	notify := func(reason string) {
+		reasons = append(reasons, reason)
+		entries, _ := q.Entries()
+		if len(entries) != 1 || entries[0].State != StateQueued || entries[0].Reason != reason {
+			t.Errorf("entries while queued = %+v", entries)
+		}
+		sessions = 1
+	}
+	release, err := q.Wait(ctx, Request{RunID: "r1"}, limits, false, notify)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if len(reasons) != 1 || reasons[0] != "2/2 agency tmux sessions" {
+		t.Errorf("reasons = %q", reasons)
+	}
+
+	// An admitted run counts as a session until released
+	entries, _ := q.Entries()
+	if len(entries) != 1 || entries[0].State != StateStarting {
+		t.Fatalf("entries after admission = %+v", entries)
+	}
+	_, err = q.Wait(ctx, Request{RunID: "r2"}, limits, true, nil)
+	if errors.GetCode(err) != errors.EScheduleBlocked {
+		t.Errorf("Wait(r2) code = %q, want %q", errors.GetCode(err), errors.EScheduleBlocked)
+	}
+	release()
+	if release, err := q.Wait(ctx, Request{RunID: "r2"}, limits, true, nil); err != nil {
+		t.Errorf("Wait(r2) after release error = %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestQueueWait_FIFO(t *testing.T) {
+	q := testQueue(t, fakeProbe(0, 1<<20, 0))
+	if err := q.write(filepath.Join(q.Dir(), "older.json"), Entry{RunID: "older", State: StateQueued, QueuedAt: time.Now().Add(-time.Minute), Host: q.Host, PID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := q.Wait(context.Background(), Request{RunID: "newer"}, Limits{MaxSessions: 8}, true, nil)
+	if errors.GetCode(err) != errors.EScheduleBlocked || !strings.Contains(err.Error(), "1 run queued ahead") {
+		t.Errorf("Wait() error = %v, want 1 run queued ahead", err)
+	}
+
+	// Entries of processes that are gone, and of other hosts, do not count
+	q.IsPIDAlive = func(int) bool { return false }
+	if err := q.write(filepath.Join(q.Dir(), "remote.json"), Entry{RunID: "remote", State: StateQueued, QueuedAt: time.Now().Add(-time.Minute), Host: "host-b", PID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	release, err := q.Wait(context.Background(), Request{RunID: "newer"}, Limits{MaxSessions: 8}, true, nil)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	release()
+	if _, err := os.Stat(filepath.Join(q.Dir(), "older.json")); !os.IsNotExist(err) {
+		t.Errorf("stale entry kept: %v", err)
+	}
+}
+
+func TestQueueWait_Canceled(t *testing.T) {
+	q := testQueue(t, fakeProbe(5, 1<<20, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := q.Wait(ctx, Request{RunID: "r1"}, Limits{MaxSessions: 1}, false, func(string) { cancel() })
+	if err != context.Canceled {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+	if entries, _ := q.Entries(); len(entries) != 0 {
+		t.Errorf("entries after cancel = %+v", entries)
+	}
+}
+
+func TestQueueWait_AdmitsOneAtATime(t *testing.T) {
+	q := testQueue(t, fakeProbe(0, 1<<20, 0))
+	const n = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := q.Wait(context.Background(), Request{RunID: fmt.Sprintf("r%d", i)}, Limits{MaxSessions: 1}, true, nil); err == nil {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if admitted != 1 {
+		t.Errorf("admitted = %d, want 1", admitted)
+	}
+}
+
+func TestQueueLock(t *testing.T) {
+	q := testQueue(t, fakeProbe(0, 1<<20, 0))
+	unlock, err := q.lock(context.Background())
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+
+	// A held lock is waited for
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.lock(ctx); err != context.DeadlineExceeded {
+		t.Errorf("lock() while held error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// A lock of a process that is gone is taken over
+	q.IsPIDAlive = func(int) bool { return false }
+	unlock2, err := q.lock(context.Background())
+	if err != nil {
+		t.Fatalf("lock() over a stale lock error = %v", err)
+	}
+	unlock2()
+	unlock()
+	if _, err := os.Stat(filepath.Join(q.Dir(), ".lock")); !os.IsNotExist(err) {
+		t.Errorf("lock file kept after unlock: %v", err)
+	}
+}
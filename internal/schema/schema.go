@@ -0,0 +1,529 @@
+// Package schema embeds the JSON Schemas of agency's config files, run
+// metadata, script outputs, and --json envelopes, and validates documents
+// against them.
+//
+// The schemas (draft 2020-12) live in schemas/<name>.schema.json. The
+// validator implements the keywords they use: type, const, enum, anyOf,
+// $ref, properties, required, additionalProperties, items, minItems,
+// minLength, maxLength, pattern, minimum, and maximum. Other keywords
+// (e.g. description) are ignored.
+package schema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+//go:embed schemas/*.schema.json
+var files embed.FS
+
+// names lists the published schemas in display order.
+var names = []string{"agency", "meta", "setup", "verify", "status", "ls", "show"}
+
+// Names returns the names of the published schemas.
+func Names() []string {
+	return append([]string(nil), names...)
+}
+
+// Normalize maps a schema name as typed by the user ("agency.json",
+// "setup.json", "ls") to its canonical name, or "" if there is no such
+// schema.
+func Normalize(name string) string {
+	name = strings.TrimSuffix(name, ".json")
+	for _, n := range names {
+		if n == name {
+			return n
+		}
+	}
+	return ""
+}
+
+// Source returns the JSON Schema document of a schema.
+func Source(name string) ([]byte, error) {
+	n := Normalize(name)
+	if n == "" {
+		return nil, fmt.Errorf("unknown schema %q (known: %s)", name, strings.Join(names, ", "))
+	}
+	return files.ReadFile("schemas/" + fileName(n))
+}
+
+func fileName(name string) string {
+	return name + ".schema.json"
+}
+
+// Violation is one way a document does not match its schema.
+type Violation struct {
+	// Pointer is the JSON pointer of the offending value ("" = the document).
+	Pointer string
+
+	// Line and Column locate the offending value in the document (1-based;
+	// for a missing property, the object that lacks it).
+	Line   int
+	Column int
+
+	// Message describes the problem, e.g. "expected boolean, got string".
+	Message string
+}
+
+// String formats the violation as "line 3, column 14: /checks/0/ok: <message>".
+func (v Violation) String() string {
+	ptr := v.Pointer
+	if ptr == "" {
+		ptr = "(root)"
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %s", v.Line, v.Column, ptr, v.Message)
+}
+
+// Validate checks data against the named schema and returns its violations,
+// ordered by position (none if it is valid). Data that is not JSON yields a
+// single violation at the syntax error. The error is non-nil only for an
+// unknown schema name.
+func Validate(name string, data []byte) ([]Violation, error) {
+	n := Normalize(name)
+	if n == "" {
+		return nil, fmt.Errorf("unknown schema %q (known: %s)", name, strings.Join(names, ", "))
+	}
+	root, err := loadFile(fileName(n))
+	if err != nil {
+		return nil, err
+	}
+
+	var syntax any
+	if err := json.Unmarshal(data, &syntax); err != nil {
+		offset := len(data)
+		if se, ok := err.(*json.SyntaxError); ok {
+			offset = int(se.Offset)
+			if offset > 0 {
+				offset--
+			}
+		}
+		line, col := lineColumn(data, offset)
+		return []Violation{{Line: line, Column: col, Message: "invalid JSON: " + err.Error()}}, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	v := &validator{data: data, offsets: locate(data)}
+	if err := v.validate(fileName(n), root, doc, ""); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(v.out, func(i, j int) bool {
+		a, b := v.out[i], v.out[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.out, nil
+}
+
+// schemaFiles caches the parsed schema documents by file name.
+var (
+	schemaMu    sync.Mutex
+	schemaFiles = map[string]map[string]any{}
+	patterns    = map[string]*regexp.Regexp{}
+)
+
+func loadFile(file string) (map[string]any, error) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	if doc, ok := schemaFiles[file]; ok {
+		return doc, nil
+	}
+	data, err := files.ReadFile("schemas/" + file)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", file, err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", file, err)
+	}
+	schemaFiles[file] = doc
+	return doc, nil
+}
+
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	if re, ok := patterns[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	patterns[expr] = re
+	return re, nil
+}
+
+// validator collects the violations of one document.
+type validator struct {
+	data    []byte
+	offsets map[string]int
+	out     []Violation
+}
+
+func (v *validator) fail(ptr, format string, args ...any) {
+	line, col := lineColumn(v.data, v.offsets[ptr])
+	v.out = append(v.out, Violation{Pointer: ptr, Line: line, Column: col, Message: fmt.Sprintf(format, args...)})
+}
+
+// validate checks value (at ptr) against the schema node from file. Errors
+// are problems with the schemas themselves, not the document.
+func (v *validator) validate(file string, node map[string]any, value any, ptr string) error {
+	if ref, ok := node["$ref"].(string); ok {
+		refFile, target, err := resolveRef(file, ref)
+		if err != nil {
+			return err
+		}
+		if err := v.validate(refFile, target, value, ptr); err != nil {
+			return err
+		}
+	}
+
+	if t, ok := node["type"]; ok && !matchesType(t, value) {
+		v.fail(ptr, "expected %s, got %s", typeList(t), typeOf(value))
+		return nil
+	}
+	if c, ok := node["const"]; ok && !equal(c, value) {
+		v.fail(ptr, "must be %s", marshal(c))
+	}
+	if enum, ok := node["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			vals := make([]string, len(enum))
+			for i, e := range enum {
+				vals[i] = marshal(e)
+			}
+			v.fail(ptr, "must be one of %s", strings.Join(vals, ", "))
+		}
+	}
+	if anyOf, ok := node["anyOf"].([]any); ok {
+		if err := v.validateAnyOf(file, anyOf, value, ptr); err != nil {
+			return err
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		return v.validateObject(file, node, val, ptr)
+	case []any:
+		if items, ok := node["items"].(map[string]any); ok {
+			for i, item := range val {
+				if err := v.validate(file, items, item, ptr+"/"+strconv.Itoa(i)); err != nil {
+					return err
+				}
+			}
+		}
+		if min, ok := node["minItems"].(float64); ok && float64(len(val)) < min {
+			v.fail(ptr, "must have at least %s item(s)", formatNumber(min))
+		}
+	case string:
+		n := float64(utf8.RuneCountInString(val))
+		if min, ok := node["minLength"].(float64); ok && n < min {
+			if min == 1 {
+				v.fail(ptr, "must not be empty")
+			} else {
+				v.fail(ptr, "must be at least %s characters", formatNumber(min))
+			}
+		}
+		if max, ok := node["maxLength"].(float64); ok && n > max {
+			v.fail(ptr, "must be at most %s characters", formatNumber(max))
+		}
+		if expr, ok := node["pattern"].(string); ok {
+			re, err := compilePattern(expr)
+			if err != nil {
+				return fmt.Errorf("schema %s: pattern %q: %w", file, expr, err)
+			}
+			if !re.MatchString(val) {
+				v.fail(ptr, "%s does not match pattern %s", marshal(val), expr)
+			}
+		}
+	case json.Number:
+		f, _ := val.Float64()
+		if min, ok := node["minimum"].(float64); ok && f < min {
+			v.fail(ptr, "must be >= %s", formatNumber(min))
+		}
+		if max, ok := node["maximum"].(float64); ok && f > max {
+			v.fail(ptr, "must be <= %s", formatNumber(max))
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateObject(file string, node map[string]any, obj map[string]any, ptr string) error {
+	if required, ok := node["required"].([]any); ok {
+		for _, r := range required {
+			if name, _ := r.(string); name != "" {
+				if _, present := obj[name]; !present {
+					v.fail(ptr, "missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	props, _ := node["properties"].(map[string]any)
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		childPtr := ptr + "/" + escapePointer(k)
+		if prop, ok := props[k].(map[string]any); ok {
+			if err := v.validate(file, prop, obj[k], childPtr); err != nil {
+				return err
+			}
+			continue
+		}
+		switch extra := node["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				v.fail(childPtr, "unknown property %q", k)
+			}
+		case map[string]any:
+			if err := v.validate(file, extra, obj[k], childPtr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateAnyOf passes if value matches any branch. Otherwise it reports the
+// violations of the one branch whose type matched (e.g. the object branch of
+// "null or object"), or a generic violation if that is ambiguous.
+func (v *validator) validateAnyOf(file string, branches []any, value any, ptr string) error {
+	var candidates [][]Violation
+	for _, b := range branches {
+		branch, ok := b.(map[string]any)
+		if !ok {
+			continue
+		}
+		sub := &validator{data: v.data, offsets: v.offsets}
+		if err := sub.validate(file, branch, value, ptr); err != nil {
+			return err
+		}
+		if len(sub.out) == 0 {
+			return nil
+		}
+		first := sub.out[0]
+		if len(sub.out) == 1 && first.Pointer == ptr && strings.HasPrefix(first.Message, "expected ") {
+			continue
+		}
+		candidates = append(candidates, sub.out)
+	}
+	if len(candidates) == 1 {
+		v.out = append(v.out, candidates[0]...)
+		return nil
+	}
+	v.fail(ptr, "%s matches none of the allowed schemas", typeOf(value))
+	return nil
+}
+
+// resolveRef resolves a $ref ("#/$defs/x", "other.schema.json", or
+// "other.schema.json#/$defs/x") relative to file.
+func resolveRef(file, ref string) (string, map[string]any, error) {
+	refFile, fragment, _ := strings.Cut(ref, "#")
+	if refFile == "" {
+		refFile = file
+	}
+	doc, err := loadFile(refFile)
+	if err != nil {
+		return "", nil, err
+	}
+	node := doc
+	for _, part := range strings.Split(strings.TrimPrefix(fragment, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		next, ok := node[unescapePointer(part)].(map[string]any)
+		if !ok {
+			return "", nil, fmt.Errorf("schema %s: unresolvable $ref %q", file, ref)
+		}
+		node = next
+	}
+	return refFile, node, nil
+}
+
+// matchesType reports whether value has the schema type t (a name or a
+// list of names).
+func matchesType(t any, value any) bool {
+	switch t := t.(type) {
+	case string:
+		return isType(t, value)
+	case []any:
+		for _, name := range t {
+			if s, ok := name.(string); ok && isType(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func isType(name string, value any) bool {
+	switch val := value.(type) {
+	case nil:
+		return name == "null"
+	case bool:
+		return name == "boolean"
+	case string:
+		return name == "string"
+	case []any:
+		return name == "array"
+	case map[string]any:
+		return name == "object"
+	case json.Number:
+		if name == "number" {
+			return true
+		}
+		f, err := val.Float64()
+		return name == "integer" && err == nil && f == math.Trunc(f)
+	}
+	return false
+}
+
+// typeOf names the JSON type of a decoded value.
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "number"
+}
+
+func typeList(t any) string {
+	if list, ok := t.([]any); ok {
+		parts := make([]string, 0, len(list))
+		for _, name := range list {
+			parts = append(parts, fmt.Sprint(name))
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// equal compares a schema value (numbers as float64) with a document value
+// (numbers as json.Number).
+func equal(schemaValue, value any) bool {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		s, isNum := schemaValue.(float64)
+		return err == nil && isNum && f == s
+	}
+	return marshal(schemaValue) == marshal(value)
+}
+
+func marshal(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// locate maps the JSON pointer of every value in data to its byte offset.
+// data must be valid JSON.
+func locate(data []byte) map[string]int {
+	offsets := map[string]int{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var walk func(ptr string) bool
+	walk = func(ptr string) bool {
+		offsets[ptr] = skipSeparators(data, int(dec.InputOffset()))
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return false
+				}
+				name, _ := key.(string)
+				if !walk(ptr + "/" + escapePointer(name)) {
+					return false
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if !walk(ptr + "/" + strconv.Itoa(i)) {
+					return false
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err == nil
+	}
+	walk("")
+	return offsets
+}
+
+// skipSeparators advances offset past whitespace, commas, and colons to the
+// start of the next value.
+func skipSeparators(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// lineColumn converts a byte offset into a 1-based line and column (in runes).
+func lineColumn(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	before := data[:offset]
+	line := 1 + bytes.Count(before, []byte("\n"))
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, 1 + utf8.RuneCount(before[lineStart:])
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+}
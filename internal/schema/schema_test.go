@@ -0,0 +1,297 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/config"
+	"github.com/NielsdaWheelz/agency/internal/events"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestValidate_Locations(t *testing.T) {
+	doc := `{
+  "schema_version": "2.0",
+  "ok": "yes",
+  "checks": [
+    {"name": "deps", "ok": true},
+    {"ok": true, "duration_ms": -5}
+  ],
+  "warnings": ["a", 3]
+}`
+	violations, err := Validate("setup.json", []byte(doc))
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	want := []string{
+		`line 3, column 9: /ok: expected boolean, got string`,
+		`line 6, column 5: /checks/1: missing required property "name"`,
+		`line 6, column 33: /checks/1/duration_ms: must be >= 0`,
+		`line 8, column 21: /warnings/1: expected string, got number`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("violations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidate_Keywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		doc    string
+		want   string
+	}{
+		{"valid", "agency", `{"version": 1, "defaults": {"parent_branch": "main", "runner": "claude"}, "scripts": {"setup": "s.sh"}}`, ""},
+		{"const", "agency", `{"version": 2, "defaults": {"parent_branch": "main", "runner": "claude"}, "scripts": {"setup": "s.sh"}}`, "/version: must be 1"},
+		{"enum", "agency", `{"version": 1, "defaults": {"parent_branch": "main", "runner": "claude"}, "scripts": {"setup": "s.sh"}, "logs": {"overflow": "drop"}}`, `/logs/overflow: must be one of "rotate", "truncate"`},
+		{"unknown hook", "agency", `{"version": 1, "defaults": {"parent_branch": "main", "runner": "claude"}, "scripts": {"setup": "s.sh"}, "hooks": {"pre_push": "x.sh"}}`, `/hooks/pre_push: unknown property "pre_push"`},
+		{"pattern", "agency", `{"version": 1, "defaults": {"parent_branch": "main", "runner": "claude"}, "scripts": {"setup": "s.sh"}, "runners": {"x": "a b"}}`, `/runners/x: "a b" does not match pattern ^\S+$`},
+		{"integer", "agency", `{"version": 1.5, "defaults": {"parent_branch": "main", "runner": "claude"}, "scripts": {"setup": "s.sh"}}`, "/version: must be 1"},
+		{"minLength", "setup", `{"checks": [{"name": "", "ok": true}]}`, "/checks/0/name: must not be empty"},
+		{"nullable", "ls", `{"schema_version": "1.4", "data": [], "total": 0, "offset": 0, "limit": "ten", "warnings": []}`, "/limit: expected integer or null, got string"},
+		{"anyOf branch", "show", `{"schema_version": "1.4", "data": {"meta": null}}`, `/data: missing required property "repo_id"`},
+		{"anyOf type", "show", `{"schema_version": "1.4", "data": 7}`, "/data: number matches none of the allowed schemas"},
+		{"cross-file ref", "meta", `{"schema_version": "1.0", "run_id": "r", "repo_id": "x", "title": "", "runner": "", "runner_cmd": "", "parent_branch": "", "branch": "", "worktree_path": "", "created_at": "", "setup": {"exit_code": 0, "output_checks": [{"name": "a"}]}}`, `/setup/output_checks/0: missing required property "ok"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := Validate(tt.schema, []byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if tt.want == "" {
+				if len(violations) > 0 {
+					t.Errorf("violations = %v, want none", violations)
+				}
+				return
+			}
+			for _, v := range violations {
+				if strings.HasSuffix(v.String(), ": "+tt.want) || strings.Contains(v.String(), " "+tt.want) {
+					return
+				}
+			}
+			t.Errorf("violations = %v, want %q", violations, tt.want)
+		})
+	}
+}
+
+func TestValidate_SyntaxError(t *testing.T) {
+	violations, err := Validate("setup", []byte("{\n  \"ok\": true,\n  \"summary\": oops\n}"))
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(violations) != 1 {
+		t.Fatalf("violations = %v, want one syntax error", violations)
+	}
+	v := violations[0]
+	if v.Line != 3 || v.Column != 14 || !strings.Contains(v.Message, "invalid JSON") {
+		t.Errorf("violation = %s, want invalid JSON at line 3, column 14", v)
+	}
+}
+
+func TestValidate_UnknownSchema(t *testing.T) {
+	if _, err := Validate("nope", []byte("{}")); err == nil {
+		t.Error("expected an error for an unknown schema")
+	}
+	if _, err := Source("nope"); err == nil {
+		t.Error("expected an error for an unknown schema")
+	}
+	for _, name := range Names() {
+		data, err := Source(name + ".json")
+		if err != nil {
+			t.Fatalf("Source(%s) error = %v", name, err)
+		}
+		if !json.Valid(data) {
+			t.Errorf("schema %s is not valid JSON", name)
+		}
+	}
+}
+
+// TestAgencySchema_ConfigTestdata checks the agency schema against the
+// loader: every fixture the loader accepts must validate, and every
+// wrong_types fixture must not.
+func TestAgencySchema_ConfigTestdata(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("..", "config", "testdata", "*.json"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no config fixtures: %v", err)
+	}
+	for _, fixture := range fixtures {
+		name := filepath.Base(fixture)
+		data, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo := t.TempDir()
+		if err := os.WriteFile(filepath.Join(repo, "agency.json"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, loadErr := config.LoadAndValidateForS1(fs.NewRealFS(), repo)
+
+		violations, err := Validate("agency", data)
+		if err != nil {
+			t.Fatalf("%s: Validate() error = %v", name, err)
+		}
+		if loadErr == nil && len(violations) > 0 {
+			t.Errorf("%s: loader accepts it but schema reports %v", name, violations)
+		}
+		if strings.HasPrefix(name, "wrong_types") && len(violations) == 0 {
+			t.Errorf("%s: loader rejects it (%v) but schema accepts it", name, loadErr)
+		}
+	}
+}
+
+// TestSchemas_CoverGoTypes checks that every JSON field of the Go types
+// behind each schema is described by it.
+func TestSchemas_CoverGoTypes(t *testing.T) {
+	for name, typ := range map[string]reflect.Type{
+		"agency": reflect.TypeOf(config.AgencyConfig{}),
+		"meta":   reflect.TypeOf(store.RunMeta{}),
+		"status": reflect.TypeOf(events.RunStatus{}),
+		"ls":     reflect.TypeOf(render.LSJSONEnvelope{}),
+		"show":   reflect.TypeOf(render.ShowJSONEnvelope{}),
+	} {
+		file := fileName(name)
+		root, err := loadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkCovers(t, name, file, root, typ, "")
+	}
+}
+
+func checkCovers(t *testing.T, schemaName, file string, node map[string]any, typ reflect.Type, path string) {
+	t.Helper()
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+		if items, ok := node["items"].(map[string]any); ok {
+			node = items
+		}
+	}
+	if typ.Kind() != reflect.Struct || typ == reflect.TypeOf(time.Time{}) {
+		return
+	}
+	file, node = objectNode(t, file, node)
+
+	props, _ := node["properties"].(map[string]any)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		prop, ok := props[tag].(map[string]any)
+		if !ok {
+			t.Errorf("schema %s: %s/%s (%s.%s) is not described", schemaName, path, tag, typ.Name(), field.Name)
+			continue
+		}
+		checkCovers(t, schemaName, file, prop, field.Type, path+"/"+tag)
+	}
+}
+
+// objectNode follows $refs and picks the object branch of an anyOf.
+func objectNode(t *testing.T, file string, node map[string]any) (string, map[string]any) {
+	t.Helper()
+	for {
+		if ref, ok := node["$ref"].(string); ok {
+			var err error
+			file, node, err = resolveRef(file, ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if anyOf, ok := node["anyOf"].([]any); ok {
+			for _, b := range anyOf {
+				branch := b.(map[string]any)
+				if branch["type"] != "null" {
+					node = branch
+				}
+			}
+			continue
+		}
+		return file, node
+	}
+}
+
+func TestSchemas_ValidateRenderedOutput(t *testing.T) {
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	meta := testkit.NewRunMeta("abcd1234ef567890", "20260110120000-a3f2", "/data/repos/abcd1234ef567890/worktrees/20260110120000-a3f2", created)
+	ok := true
+	meta.Labels = map[string]string{"ticket": "ENG-1"}
+	meta.Setup = &store.RunMetaSetup{
+		Command:      "sh -lc scripts/agency_setup.sh",
+		DurationMs:   1200,
+		LogPath:      "/data/repos/abcd1234ef567890/runs/20260110120000-a3f2/logs/setup.log",
+		OutputOk:     &ok,
+		OutputChecks: []store.RunMetaSetupCheck{{Name: "deps", Ok: true, DurationMs: 900}},
+		OutputFile:   &store.RunMetaOutputFile{Path: "/x/setup.json", SHA256: strings.Repeat("ab", 32), ModifiedAt: "2026-01-10T12:00:01Z"},
+	}
+	meta.Deadline = &store.RunMetaDeadline{At: "2026-01-10T14:00:00Z", Kill: true}
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValid(t, "meta", metaJSON)
+
+	statusJSON, err := json.Marshal(events.RunStatus{SchemaVersion: events.StatusSchemaVersion, RepoID: meta.RepoID, RunID: meta.RunID,
+		DerivedStatus: "active", LastEvent: "checkpoint", LastEventAt: "2026-01-10T12:05:00Z", CreatedAt: meta.CreatedAt, UpdatedAt: "2026-01-10T12:05:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValid(t, "status", statusJSON)
+
+	pr := 42
+	lsJSON := &bytes.Buffer{}
+	summary := render.RunSummary{
+		RunID:         meta.RunID,
+		RepoID:        meta.RepoID,
+		Title:         meta.Title,
+		CreatedAt:     &created,
+		PRNumber:      &pr,
+		DerivedStatus: "active",
+		Lock:          &render.LockJSON{CreatedAt: created, AgeSeconds: 5, Path: "/data/locks/x.lock"},
+	}
+	if err := render.WriteLSJSON(lsJSON, []render.RunSummary{summary, {RunID: "x", RepoID: "y", Title: "<broken>", Broken: true}}, render.LSPage{Total: 2, Limit: 10}, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertValid(t, "ls", lsJSON.Bytes())
+
+	showJSON := &bytes.Buffer{}
+	detail := &render.RunDetail{Meta: meta, RepoID: meta.RepoID, Derived: render.DerivedJSON{DerivedStatus: "active"}}
+	if err := render.WriteShowJSON(showJSON, detail); err != nil {
+		t.Fatal(err)
+	}
+	assertValid(t, "show", showJSON.Bytes())
+
+	showErr := &bytes.Buffer{}
+	if err := render.WriteShowJSONError(showErr, fmt.Errorf("boom")); err != nil {
+		t.Fatal(err)
+	}
+	assertValid(t, "show", showErr.Bytes())
+}
+
+func assertValid(t *testing.T, name string, data []byte) {
+	t.Helper()
+	violations, err := Validate(name, data)
+	if err != nil {
+		t.Fatalf("Validate(%s) error = %v", name, err)
+	}
+	for _, v := range violations {
+		t.Errorf("%s: %s", name, v)
+	}
+}
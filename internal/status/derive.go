@@ -2,12 +2,37 @@
 // No filesystem, tmux, or network calls are made in this package.
 package status
 
-import "github.com/NielsdaWheelz/agency/internal/store"
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
 
 // ReportNonemptyThresholdBytes is the minimum byte count for a report to be considered non-empty.
 // Reports below this threshold are assumed to be template-only or effectively empty.
 const ReportNonemptyThresholdBytes = 64
 
+// ReviewPolicy configures the report predicate for "ready for review".
+// The zero value is the default: report size >= ReportNonemptyThresholdBytes.
+type ReviewPolicy struct {
+	// ReportMinBytes overrides ReportNonemptyThresholdBytes when > 0.
+	ReportMinBytes int
+
+	// RequireReadyFlag replaces the size check with the report.md front
+	// matter flag (ready: true).
+	RequireReadyFlag bool
+}
+
+// ReportThreshold returns the effective non-empty threshold.
+func (p ReviewPolicy) ReportThreshold() int {
+	if p.ReportMinBytes > 0 {
+		return p.ReportMinBytes
+	}
+	return ReportNonemptyThresholdBytes
+}
+
 // Derived status string constants (user-visible contract, must remain stable across v1.x).
 const (
 	StatusBroken           = "broken"
@@ -22,6 +47,27 @@ const (
 	StatusIdle             = "idle"
 )
 
+// Statuses lists every derived status, in precedence order.
+var Statuses = []string{
+	StatusBroken,
+	StatusMerged,
+	StatusAbandoned,
+	StatusFailed,
+	StatusNeedsAttention,
+	StatusReadyForReview,
+	StatusActivePR,
+	StatusActive,
+	StatusIdlePR,
+	StatusIdle,
+}
+
+// ReasonDeadlineExceeded is Derived.AttentionReason for a run past its deadline.
+const ReasonDeadlineExceeded = "deadline exceeded"
+
+// ReasonProbeFailing prefixes Derived.AttentionReason for a run flagged by
+// failing agency watch probes ("probe failing: <names>").
+const ReasonProbeFailing = "probe failing: "
+
 // Snapshot contains local-only inputs for status derivation.
 // These values must be computed by the caller from filesystem and tmux state.
 type Snapshot struct {
@@ -34,6 +80,26 @@ type Snapshot struct {
 	// ReportBytes is the size of .agency/report.md in bytes.
 	// Set to 0 if the file is missing or unreadable.
 	ReportBytes int
+
+	// ReportStale is true iff the branch has commits newer than the commit
+	// recorded as covered by the report (footer or meta.report_commit).
+	// False when no commit is recorded or freshness could not be determined.
+	ReportStale bool
+
+	// ReportReadyFlag is true iff report.md front matter sets ready: true.
+	ReportReadyFlag bool
+
+	// Policy is the repo's review policy (agency.json review.*).
+	Policy ReviewPolicy
+
+	// DeadlineExceeded is true iff the run's meta.deadline has passed.
+	// Callers leave it false for archived runs.
+	DeadlineExceeded bool
+
+	// NoCommits is true iff the branch tip equals its merge-base with the
+	// parent branch (the run committed nothing). Callers may leave it false
+	// when the report is non-empty, since NoChanges needs both.
+	NoCommits bool
 }
 
 // Derived contains the computed status values.
@@ -45,8 +111,26 @@ type Derived struct {
 	// Archived is true iff the worktree is not present.
 	Archived bool
 
-	// ReportNonempty is true iff ReportBytes >= ReportNonemptyThresholdBytes.
+	// ReportNonempty is true iff ReportBytes >= the policy threshold
+	// (ReportNonemptyThresholdBytes by default).
 	ReportNonempty bool
+
+	// ReportStale mirrors Snapshot.ReportStale.
+	ReportStale bool
+
+	// AttentionReason explains a "needs attention" status when agency
+	// derived it (ReasonDeadlineExceeded) or probes set it
+	// (ReasonProbeFailing); empty otherwise.
+	AttentionReason string
+
+	// NoChanges is true iff an open run has no commits and the report is
+	// below the non-empty threshold: a likely dead end to clean up.
+	NoChanges bool
+
+	// Reasons lists, in precedence order, the predicates that decided
+	// DerivedStatus (e.g. "pr_number set", "report >= 64 bytes",
+	// "tmux session missing"). Never empty.
+	Reasons []string
 }
 
 // Derive computes the derived status from meta and local snapshot.
@@ -61,7 +145,7 @@ func Derive(meta *store.RunMeta, in Snapshot) Derived {
 
 	// Compute presence-derived fields (independent of meta)
 	archived := !in.WorktreePresent
-	reportNonempty := reportBytes >= ReportNonemptyThresholdBytes
+	reportNonempty := reportBytes >= in.Policy.ReportThreshold()
 
 	// Handle broken runs (nil meta)
 	if meta == nil {
@@ -69,22 +153,104 @@ func Derive(meta *store.RunMeta, in Snapshot) Derived {
 			DerivedStatus:  StatusBroken,
 			Archived:       archived,
 			ReportNonempty: reportNonempty,
+			ReportStale:    in.ReportStale,
+			Reasons:        []string{"meta.json unreadable"},
 		}
 	}
 
+	// A stale report does not count towards ready-for-review
+	reportReady := reportNonempty
+	if in.Policy.RequireReadyFlag {
+		reportReady = in.ReportReadyFlag
+	}
+	reportReady = reportReady && !in.ReportStale
+
 	// Compute derived status using precedence rules
-	status := deriveStatus(meta, in.TmuxActive, reportNonempty)
+	status := deriveStatus(meta, in.TmuxActive, reportReady, in.DeadlineExceeded)
+
+	var reason string
+	if status == StatusNeedsAttention {
+		if !isNeedsAttention(meta) {
+			reason = ReasonDeadlineExceeded
+		} else if names := flaggedProbes(meta); len(names) > 0 {
+			reason = ReasonProbeFailing + strings.Join(names, ", ")
+		}
+	}
 
 	return Derived{
-		DerivedStatus:  status,
-		Archived:       archived,
-		ReportNonempty: reportNonempty,
+		DerivedStatus:   status,
+		Archived:        archived,
+		ReportNonempty:  reportNonempty,
+		ReportStale:     in.ReportStale,
+		AttentionReason: reason,
+		NoChanges:       in.NoCommits && !reportNonempty && !isMerged(meta),
+		Reasons:         statusReasons(meta, in, status, reportNonempty),
+	}
+}
+
+// statusReasons explains status, which deriveStatus chose for meta and in:
+// the flag behind a terminal or failure status, otherwise each
+// ready-for-review predicate and, below it, the tmux session state.
+func statusReasons(meta *store.RunMeta, in Snapshot, status string, reportNonempty bool) []string {
+	var reasons []string
+	switch status {
+	case StatusMerged:
+		reasons = append(reasons, "archive.merged_at set")
+	case StatusAbandoned:
+		reasons = append(reasons, "flags.abandoned set")
+	case StatusFailed:
+		reasons = append(reasons, "flags.setup_failed set")
+	case StatusNeedsAttention:
+		if isNeedsAttention(meta) {
+			reasons = append(reasons, "flags.needs_attention set")
+			for _, name := range flaggedProbes(meta) {
+				reasons = append(reasons, "probe "+name+" failing")
+			}
+		}
+		if in.DeadlineExceeded {
+			reasons = append(reasons, "deadline exceeded")
+		}
+	default:
+		if hasPRNumber(meta) {
+			reasons = append(reasons, "pr_number set")
+		} else {
+			reasons = append(reasons, "pr_number unset")
+		}
+		if hasLastPushAt(meta) {
+			reasons = append(reasons, "last_push_at set")
+		} else {
+			reasons = append(reasons, "last_push_at unset")
+		}
+		switch {
+		case in.Policy.RequireReadyFlag && in.ReportReadyFlag:
+			reasons = append(reasons, "report marked ready")
+		case in.Policy.RequireReadyFlag:
+			reasons = append(reasons, "report not marked ready")
+		case reportNonempty:
+			reasons = append(reasons, fmt.Sprintf("report >= %d bytes", in.Policy.ReportThreshold()))
+		default:
+			reasons = append(reasons, fmt.Sprintf("report < %d bytes", in.Policy.ReportThreshold()))
+		}
+		if in.ReportStale {
+			reasons = append(reasons, "report stale")
+		}
+		if status != StatusReadyForReview {
+			if in.TmuxActive {
+				reasons = append(reasons, "tmux session running")
+			} else {
+				reasons = append(reasons, "tmux session missing")
+			}
+		}
 	}
+	if !in.WorktreePresent {
+		reasons = append(reasons, "worktree missing (archived)")
+	}
+	return reasons
 }
 
 // deriveStatus implements the precedence rules for status derivation.
 // Precondition: meta is non-nil.
-func deriveStatus(meta *store.RunMeta, tmuxActive bool, reportNonempty bool) string {
+func deriveStatus(meta *store.RunMeta, tmuxActive bool, reportReady bool, deadlineExceeded bool) string {
 	// 1) Terminal outcome always wins
 	if isMerged(meta) {
 		return StatusMerged
@@ -97,12 +263,12 @@ func deriveStatus(meta *store.RunMeta, tmuxActive bool, reportNonempty bool) str
 	if isSetupFailed(meta) {
 		return StatusFailed
 	}
-	if isNeedsAttention(meta) {
+	if isNeedsAttention(meta) || deadlineExceeded {
 		return StatusNeedsAttention
 	}
 
 	// 3) Ready for review (all predicates must be true)
-	if isReadyForReview(meta, reportNonempty) {
+	if isReadyForReview(meta, reportReady) {
 		return StatusReadyForReview
 	}
 
@@ -140,6 +306,19 @@ func isNeedsAttention(meta *store.RunMeta) bool {
 	return meta.Flags != nil && meta.Flags.NeedsAttention
 }
 
+// flaggedProbes returns the sorted names of probes holding
+// flags.needs_attention.
+func flaggedProbes(meta *store.RunMeta) []string {
+	var names []string
+	for name, p := range meta.Probes {
+		if p.Flagged {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // hasPRNumber returns true if pr_number is set (non-zero).
 func hasPRNumber(meta *store.RunMeta) bool {
 	return meta.PRNumber != 0
@@ -153,7 +332,7 @@ func hasLastPushAt(meta *store.RunMeta) bool {
 // isReadyForReview returns true if all ready-for-review predicates are met:
 // - pr_number is set
 // - last_push_at is set
-// - report is non-empty (>= 64 bytes)
-func isReadyForReview(meta *store.RunMeta, reportNonempty bool) bool {
-	return hasPRNumber(meta) && hasLastPushAt(meta) && reportNonempty
+// - report is ready per the review policy (default: >= 64 bytes) and not stale
+func isReadyForReview(meta *store.RunMeta, reportReady bool) bool {
+	return hasPRNumber(meta) && hasLastPushAt(meta) && reportReady
 }
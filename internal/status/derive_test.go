@@ -1,6 +1,7 @@
 package status
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -248,6 +249,17 @@ func TestDerive(t *testing.T) {
 			wantArchived:       false,
 			wantReportNonempty: true,
 		},
+		{
+			name: "NOT ready_for_review: report stale",
+			meta: mkMeta(func(m *store.RunMeta) {
+				m.PRNumber = 456
+				m.LastPushAt = "2026-01-10T13:00:00Z"
+			}),
+			snapshot:           Snapshot{TmuxActive: false, WorktreePresent: true, ReportBytes: 1000, ReportStale: true},
+			wantDerivedStatus:  StatusIdlePR,
+			wantArchived:       false,
+			wantReportNonempty: true,
+		},
 		{
 			name: "NOT ready_for_review: missing pr_number",
 			meta: mkMeta(func(m *store.RunMeta) {
@@ -502,6 +514,102 @@ func TestReportNonemptyThresholdConstant(t *testing.T) {
 	}
 }
 
+// TestDeriveReviewPolicy verifies the configurable ready-for-review predicate.
+func TestDeriveReviewPolicy(t *testing.T) {
+	pushed := mkMeta(func(m *store.RunMeta) {
+		m.PRNumber = 12
+		m.LastPushAt = "2026-01-10T13:00:00Z"
+	})
+
+	tests := []struct {
+		name         string
+		snapshot     Snapshot
+		wantStatus   string
+		wantNonempty bool
+	}{
+		{"default threshold", Snapshot{WorktreePresent: true, ReportBytes: 64}, StatusReadyForReview, true},
+		{"raised threshold not met", Snapshot{WorktreePresent: true, ReportBytes: 100, Policy: ReviewPolicy{ReportMinBytes: 200}}, StatusIdlePR, false},
+		{"raised threshold met", Snapshot{WorktreePresent: true, ReportBytes: 200, Policy: ReviewPolicy{ReportMinBytes: 200}}, StatusReadyForReview, true},
+		{"lowered threshold", Snapshot{WorktreePresent: true, ReportBytes: 10, Policy: ReviewPolicy{ReportMinBytes: 8}}, StatusReadyForReview, true},
+		{"flag required, missing", Snapshot{WorktreePresent: true, ReportBytes: 5000, Policy: ReviewPolicy{RequireReadyFlag: true}}, StatusIdlePR, true},
+		{"flag required, set", Snapshot{WorktreePresent: true, ReportBytes: 10, ReportReadyFlag: true, Policy: ReviewPolicy{RequireReadyFlag: true}}, StatusReadyForReview, false},
+		{"flag set but stale", Snapshot{WorktreePresent: true, ReportReadyFlag: true, ReportStale: true, Policy: ReviewPolicy{RequireReadyFlag: true}}, StatusIdlePR, false},
+		{"flag ignored by default", Snapshot{WorktreePresent: true, ReportBytes: 10, ReportReadyFlag: true}, StatusIdlePR, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Derive(pushed, tt.snapshot)
+			if got.DerivedStatus != tt.wantStatus {
+				t.Errorf("DerivedStatus = %q, want %q", got.DerivedStatus, tt.wantStatus)
+			}
+			if got.ReportNonempty != tt.wantNonempty {
+				t.Errorf("ReportNonempty = %v, want %v", got.ReportNonempty, tt.wantNonempty)
+			}
+		})
+	}
+}
+
+func TestDeriveDeadlineExceeded(t *testing.T) {
+	tests := []struct {
+		name       string
+		meta       *store.RunMeta
+		wantStatus string
+		wantReason string
+	}{
+		{"active run", mkMeta(nil), StatusNeedsAttention, ReasonDeadlineExceeded},
+		{"already flagged", mkMeta(func(m *store.RunMeta) {
+			m.Flags = &store.RunMetaFlags{NeedsAttention: true}
+		}), StatusNeedsAttention, ""},
+		{"flagged by probes", mkMeta(func(m *store.RunMeta) {
+			m.Flags = &store.RunMetaFlags{NeedsAttention: true}
+			m.Probes = map[string]store.RunMetaProbe{"dev": {Flagged: true}, "build": {Flagged: true}, "lint": {OK: true}}
+		}), StatusNeedsAttention, "probe failing: build, dev"},
+		{"setup failed wins", mkMeta(func(m *store.RunMeta) {
+			m.Flags = &store.RunMetaFlags{SetupFailed: true}
+		}), StatusFailed, ""},
+		{"merged wins", mkMeta(func(m *store.RunMeta) {
+			m.Archive = &store.RunMetaArchive{MergedAt: "2026-01-11T12:00:00Z"}
+		}), StatusMerged, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Derive(tt.meta, Snapshot{TmuxActive: true, WorktreePresent: true, DeadlineExceeded: true})
+			if got.DerivedStatus != tt.wantStatus {
+				t.Errorf("DerivedStatus = %q, want %q", got.DerivedStatus, tt.wantStatus)
+			}
+			if got.AttentionReason != tt.wantReason {
+				t.Errorf("AttentionReason = %q, want %q", got.AttentionReason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestDeriveNoChanges(t *testing.T) {
+	merged := mkMeta(func(m *store.RunMeta) {
+		m.Archive = &store.RunMetaArchive{MergedAt: "2026-01-11T12:00:00Z"}
+	})
+	tests := []struct {
+		name     string
+		meta     *store.RunMeta
+		snapshot Snapshot
+		want     bool
+	}{
+		{"no commits, empty report", mkMeta(nil), Snapshot{WorktreePresent: true, NoCommits: true, ReportBytes: 10}, true},
+		{"no commits, written report", mkMeta(nil), Snapshot{WorktreePresent: true, NoCommits: true, ReportBytes: 64}, false},
+		{"commits, empty report", mkMeta(nil), Snapshot{WorktreePresent: true, ReportBytes: 0}, false},
+		{"raised threshold", mkMeta(nil), Snapshot{WorktreePresent: true, NoCommits: true, ReportBytes: 100, Policy: ReviewPolicy{ReportMinBytes: 200}}, true},
+		{"merged", merged, Snapshot{WorktreePresent: true, NoCommits: true}, false},
+		{"broken", nil, Snapshot{WorktreePresent: true, NoCommits: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Derive(tt.meta, tt.snapshot).NoChanges; got != tt.want {
+				t.Errorf("NoChanges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestStatusStringConstants verifies status strings match expected values.
 func TestStatusStringConstants(t *testing.T) {
 	// These are user-visible contracts and must remain stable
@@ -538,3 +646,40 @@ func TestStatusStringConstants(t *testing.T) {
 		}
 	}
 }
+
+func TestDeriveReasons(t *testing.T) {
+	pushed := mkMeta(func(m *store.RunMeta) {
+		m.PRNumber = 42
+		m.LastPushAt = "2026-01-10T13:00:00Z"
+	})
+	tests := []struct {
+		name     string
+		meta     *store.RunMeta
+		snapshot Snapshot
+		want     []string
+	}{
+		{"broken", nil, Snapshot{}, []string{"meta.json unreadable"}},
+		{"idle", mkMeta(nil), Snapshot{WorktreePresent: true},
+			[]string{"pr_number unset", "last_push_at unset", "report < 64 bytes", "tmux session missing"}},
+		{"ready for review", pushed, Snapshot{WorktreePresent: true, ReportBytes: 100},
+			[]string{"pr_number set", "last_push_at set", "report >= 64 bytes"}},
+		{"stale report keeps it active", pushed, Snapshot{WorktreePresent: true, TmuxActive: true, ReportBytes: 100, ReportStale: true},
+			[]string{"pr_number set", "last_push_at set", "report >= 64 bytes", "report stale", "tmux session running"}},
+		{"front matter policy", pushed, Snapshot{WorktreePresent: true, Policy: ReviewPolicy{RequireReadyFlag: true}},
+			[]string{"pr_number set", "last_push_at set", "report not marked ready", "tmux session missing"}},
+		{"needs attention", mkMeta(func(m *store.RunMeta) {
+			m.Flags = &store.RunMetaFlags{NeedsAttention: true}
+		}), Snapshot{WorktreePresent: true, DeadlineExceeded: true}, []string{"flags.needs_attention set", "deadline exceeded"}},
+		{"merged and archived", mkMeta(func(m *store.RunMeta) {
+			m.Archive = &store.RunMetaArchive{MergedAt: "2026-01-11T12:00:00Z"}
+		}), Snapshot{}, []string{"archive.merged_at set", "worktree missing (archived)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Derive(tt.meta, tt.snapshot).Reasons
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("Reasons = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
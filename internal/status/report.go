@@ -0,0 +1,105 @@
+package status
+
+import (
+	"regexp"
+	"strings"
+)
+
+// reportCommitFooterRe matches the report freshness footer:
+//
+//	<!-- agency:report-commit <sha> -->
+//
+// The sha must be 7-40 lowercase hex characters.
+var reportCommitFooterRe = regexp.MustCompile(`<!--\s*agency:report-commit\s+([0-9a-f]{7,40})\s*-->`)
+
+// ParseReportCommit extracts the commit SHA recorded in a report.md footer.
+// If multiple footers are present, the last one wins.
+// Returns "" if no footer is found.
+func ParseReportCommit(content string) string {
+	matches := reportCommitFooterRe.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1][1]
+}
+
+// ReportCommit returns the commit SHA covered by the report.
+// The report footer takes precedence over meta.report_commit, since the footer
+// is written alongside the report content itself.
+// Returns "" if neither source records a commit.
+func ReportCommit(metaReportCommit, reportContent string) string {
+	if sha := ParseReportCommit(reportContent); sha != "" {
+		return sha
+	}
+	return strings.TrimSpace(metaReportCommit)
+}
+
+// ParseReportReady reports whether report.md starts with a front matter block
+// (delimited by "---" lines) containing "ready: true".
+func ParseReportReady(content string) bool {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return false
+	}
+	ready := false
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "---" {
+			return ready
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "ready" {
+			ready = strings.Trim(strings.TrimSpace(value), `"'`) == "true"
+		}
+	}
+	// Unterminated front matter is not front matter
+	return false
+}
+
+// ReportSummary returns the first prose paragraph of report.md, skipping front
+// matter, headings, and HTML comments (such as the report-commit footer), with
+// lines joined by spaces. Paragraphs longer than maxLen runes are truncated
+// with "…" (maxLen <= 0 disables truncation). Returns "" if there is no prose.
+func ReportSummary(content string, maxLen int) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	// Skip a terminated front matter block
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	var para []string
+	inComment := false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case inComment:
+			inComment = !strings.Contains(line, "-->")
+			continue
+		case strings.HasPrefix(line, "<!--"):
+			inComment = !strings.Contains(line, "-->")
+			continue
+		case line == "" || strings.HasPrefix(line, "#"):
+			if len(para) > 0 {
+				return truncateRunes(strings.Join(para, " "), maxLen)
+			}
+			continue
+		}
+		para = append(para, line)
+	}
+	return truncateRunes(strings.Join(para, " "), maxLen)
+}
+
+// truncateRunes shortens s to at most maxLen runes, ending in "…".
+func truncateRunes(s string, maxLen int) string {
+	r := []rune(s)
+	if maxLen <= 0 || len(r) <= maxLen {
+		return s
+	}
+	return strings.TrimSpace(string(r[:maxLen-1])) + "…"
+}
@@ -0,0 +1,92 @@
+package status
+
+import "testing"
+
+func TestParseReportCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no footer", "# title\n\nsome text\n", ""},
+		{"footer", "# title\n<!-- agency:report-commit abc1234 -->\n", "abc1234"},
+		{"full sha", "<!-- agency:report-commit 0123456789abcdef0123456789abcdef01234567 -->", "0123456789abcdef0123456789abcdef01234567"},
+		{"extra whitespace", "<!--   agency:report-commit   abc1234   -->", "abc1234"},
+		{"last footer wins", "<!-- agency:report-commit aaaaaaa -->\n<!-- agency:report-commit bbbbbbb -->\n", "bbbbbbb"},
+		{"too short", "<!-- agency:report-commit abc -->", ""},
+		{"uppercase rejected", "<!-- agency:report-commit ABC1234 -->", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseReportCommit(tt.content); got != tt.want {
+				t.Errorf("ParseReportCommit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportCommit_FooterWinsOverMeta(t *testing.T) {
+	got := ReportCommit("1111111", "<!-- agency:report-commit 2222222 -->")
+	if got != "2222222" {
+		t.Errorf("ReportCommit() = %q, want %q", got, "2222222")
+	}
+
+	got = ReportCommit("1111111", "no footer")
+	if got != "1111111" {
+		t.Errorf("ReportCommit() = %q, want %q", got, "1111111")
+	}
+
+	got = ReportCommit("", "no footer")
+	if got != "" {
+		t.Errorf("ReportCommit() = %q, want empty", got)
+	}
+}
+
+func TestParseReportReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"no front matter", "# title\nready: true\n", false},
+		{"ready true", "---\nready: true\n---\n# title\n", true},
+		{"quoted", "---\nowner: me\nready: \"true\"\n---\n", true},
+		{"crlf", "---\r\nready: true\r\n---\r\n", true},
+		{"ready false", "---\nready: false\n---\n", false},
+		{"unterminated", "---\nready: true\n# title\n", false},
+		{"after front matter", "---\ntitle: x\n---\nready: true\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseReportReady(tt.content); got != tt.want {
+				t.Errorf("ParseReportReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		maxLen  int
+		want    string
+	}{
+		{"empty", "", 0, ""},
+		{"headings only", "# report\n\n## summary\n", 0, ""},
+		{"first paragraph", "# report\n\nfixed the login\nredirect loop.\n\nsecond para\n", 0, "fixed the login redirect loop."},
+		{"front matter", "---\nready: true\n---\n# report\nadds retries\n", 0, "adds retries"},
+		{"comment", "<!-- agency:report-commit abc1234 -->\n<!--\nnote\n-->\nbody\n", 0, "body"},
+		{"truncated", "abcdefghij\n", 5, "abcd…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReportSummary(tt.content, tt.maxLen); got != tt.want {
+				t.Errorf("ReportSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
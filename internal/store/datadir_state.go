@@ -0,0 +1,118 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// DataFormat is the data dir layout version written by this build.
+// Bump it for changes older builds would misread (renamed files, changed
+// meta.json semantics), and raise MinDataFormat when this build can no
+// longer read older layouts.
+const DataFormat = 1
+
+// MinDataFormat is the oldest data dir layout this build can read and write.
+const MinDataFormat = 1
+
+// DataDirStateFileName is the top-level data dir state file.
+const DataDirStateFileName = "state.json"
+
+// DataDirState is ${AGENCY_DATA_DIR}/state.json: which layout the data dir
+// uses and which agency build last wrote it.
+type DataDirState struct {
+	SchemaVersion string `json:"schema_version"`
+
+	// DataFormat is the data dir layout version (see DataFormat).
+	DataFormat int `json:"data_format"`
+
+	// LastWrittenBy is the agency version that last opened the data dir for writing.
+	LastWrittenBy string `json:"last_written_by"`
+
+	// UpdatedAt is when LastWrittenBy was recorded (RFC3339 UTC).
+	UpdatedAt string `json:"updated_at"`
+}
+
+// DataDirStatePath returns the path to state.json.
+func (s *Store) DataDirStatePath() string {
+	return filepath.Join(s.DataDir, DataDirStateFileName)
+}
+
+// ReadDataDirState reads state.json. Returns nil, nil if it does not exist
+// (data dirs written before state.json existed use DataFormat 1).
+// Returns E_STORE_CORRUPT if the file is unreadable or invalid.
+func (s *Store) ReadDataDirState() (*DataDirState, error) {
+	path := s.DataDirStatePath()
+	data, err := s.FS.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WrapWithDetails(errors.EStoreCorrupt, "failed to read state.json", err, map[string]string{"path": path})
+	}
+
+	var state DataDirState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, errors.WrapWithDetails(errors.EStoreCorrupt, "invalid json in state.json", err, map[string]string{"path": path})
+	}
+	if state.DataFormat <= 0 {
+		return nil, errors.NewWithDetails(errors.EStoreCorrupt, "state.json: missing or invalid data_format", map[string]string{"path": path})
+	}
+	return &state, nil
+}
+
+// StampDataDirState records this build as the last writer of the data dir,
+// creating the data dir if needed. Call it only after CheckDataDirState passed.
+func (s *Store) StampDataDirState(version string) error {
+	state := DataDirState{
+		SchemaVersion: SchemaVersion,
+		DataFormat:    DataFormat,
+		LastWrittenBy: version,
+		UpdatedAt:     s.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if err := s.FS.MkdirAll(s.DataDir, 0o700); err != nil {
+		return errors.Wrap(errors.EPersistFailed, "failed to create data dir", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to marshal state.json", err)
+	}
+	if err := fs.WriteFileAtomic(s.FS, s.DataDirStatePath(), append(data, '\n'), 0o644); err != nil {
+		return errors.Wrap(errors.EPersistFailed, "failed to write state.json", err)
+	}
+	return nil
+}
+
+// CheckDataDirState returns E_DATA_DIR_VERSION_SKEW if state records a data
+// format outside [MinDataFormat, DataFormat]. A nil state is compatible.
+func CheckDataDirState(dataDir string, state *DataDirState, version string) error {
+	if state == nil || (state.DataFormat >= MinDataFormat && state.DataFormat <= DataFormat) {
+		return nil
+	}
+
+	writer := state.LastWrittenBy
+	if writer == "" {
+		writer = "unknown"
+	}
+	details := map[string]string{
+		"data_dir":        dataDir,
+		"data_format":     strconv.Itoa(state.DataFormat),
+		"supported":       fmt.Sprintf("%d-%d", MinDataFormat, DataFormat),
+		"last_written_by": writer,
+	}
+
+	var msg string
+	if state.DataFormat > DataFormat {
+		msg = fmt.Sprintf("data dir %s uses data format %d (last written by agency %s), newer than this agency %s supports (%d); "+
+			"upgrade agency, or pass --force-read-only to inspect it with read-only commands", dataDir, state.DataFormat, writer, version, DataFormat)
+	} else {
+		msg = fmt.Sprintf("data dir %s uses data format %d (last written by agency %s), older than this agency %s supports (%d+); "+
+			"use agency %s to finish or archive its runs, or pass --force-read-only to inspect it with read-only commands", dataDir, state.DataFormat, writer, version, MinDataFormat, writer)
+	}
+	return errors.NewWithDetails(errors.EDataDirVersionSkew, msg, details)
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestDataDirState_RoundTrip(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "data")
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	st := NewStore(fs.NewRealFS(), dataDir, func() time.Time { return now })
+
+	if state, err := st.ReadDataDirState(); err != nil || state != nil {
+		t.Fatalf("ReadDataDirState() on missing file = %+v, %v; want nil, nil", state, err)
+	}
+	if err := st.StampDataDirState("v1.2.3"); err != nil {
+		t.Fatalf("StampDataDirState() error = %v", err)
+	}
+	state, err := st.ReadDataDirState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DataDirState{SchemaVersion: SchemaVersion, DataFormat: DataFormat, LastWrittenBy: "v1.2.3", UpdatedAt: "2026-01-10T12:00:00Z"}
+	if *state != want {
+		t.Errorf("state = %+v, want %+v", *state, want)
+	}
+
+	if err := os.WriteFile(st.DataDirStatePath(), []byte(`{"schema_version": "1.0"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.ReadDataDirState(); errors.GetCode(err) != errors.EStoreCorrupt {
+		t.Errorf("missing data_format: code = %q, want %q", errors.GetCode(err), errors.EStoreCorrupt)
+	}
+}
+
+func TestCheckDataDirState(t *testing.T) {
+	if err := CheckDataDirState("/data", nil, "dev"); err != nil {
+		t.Errorf("nil state: %v", err)
+	}
+	if err := CheckDataDirState("/data", &DataDirState{DataFormat: DataFormat}, "dev"); err != nil {
+		t.Errorf("current format: %v", err)
+	}
+
+	err := CheckDataDirState("/data", &DataDirState{DataFormat: DataFormat + 1, LastWrittenBy: "v2.0.0"}, "v1.0.0")
+	if errors.GetCode(err) != errors.EDataDirVersionSkew {
+		t.Fatalf("newer format: code = %q, want %q", errors.GetCode(err), errors.EDataDirVersionSkew)
+	}
+	if !strings.Contains(err.Error(), "upgrade agency") || !strings.Contains(err.Error(), "--force-read-only") {
+		t.Errorf("newer format error should explain what to do: %v", err)
+	}
+
+	err = CheckDataDirState("/data", &DataDirState{DataFormat: MinDataFormat - 1}, "v1.0.0")
+	if errors.GetCode(err) != errors.EDataDirVersionSkew {
+		t.Errorf("older format: code = %q, want %q", errors.GetCode(err), errors.EDataDirVersionSkew)
+	}
+}
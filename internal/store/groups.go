@@ -0,0 +1,118 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// Groups represents the groups.json file: named groups of runs across repos.
+// Each run's meta.json group field is canonical; groups.json indexes the
+// members so groups can be listed without reading every run.
+type Groups struct {
+	SchemaVersion string                `json:"schema_version"`
+	Groups        map[string]GroupEntry `json:"groups"`
+}
+
+// GroupEntry is one group in groups.json.
+type GroupEntry struct {
+	// CreatedAt is when the first run joined the group (RFC3339 UTC).
+	CreatedAt string `json:"created_at"`
+
+	// Runs are the members, sorted by repo_id then run_id.
+	Runs []GroupRun `json:"runs"`
+}
+
+// GroupRun identifies a group member.
+type GroupRun struct {
+	RepoID string `json:"repo_id"`
+	RunID  string `json:"run_id"`
+}
+
+// GroupsPath returns the path to groups.json.
+func (s *Store) GroupsPath() string {
+	return filepath.Join(s.DataDir, "groups.json")
+}
+
+// LoadGroups reads groups.json from the data directory.
+// If the file is missing, returns an empty index.
+// Returns E_STORE_CORRUPT if the JSON is invalid or schema_version is unsupported.
+func (s *Store) LoadGroups() (Groups, error) {
+	data, err := s.FS.ReadFile(s.GroupsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Groups{SchemaVersion: SchemaVersion, Groups: make(map[string]GroupEntry)}, nil
+		}
+		return Groups{}, errors.Wrap(errors.EStoreCorrupt, "failed to read groups.json", err)
+	}
+
+	var g Groups
+	if err := json.Unmarshal(data, &g); err != nil {
+		return Groups{}, errors.Wrap(errors.EStoreCorrupt, "invalid json in groups.json", err)
+	}
+	if g.SchemaVersion != SchemaVersion {
+		return Groups{}, errors.New(errors.EStoreCorrupt, "groups.json: unsupported schema_version: "+g.SchemaVersion)
+	}
+	if g.Groups == nil {
+		g.Groups = make(map[string]GroupEntry)
+	}
+	return g, nil
+}
+
+// SaveGroups writes groups.json atomically.
+func (s *Store) SaveGroups(g Groups) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to marshal groups.json", err)
+	}
+	if err := fs.WriteFileAtomic(s.FS, s.GroupsPath(), append(data, '\n'), 0o644); err != nil {
+		return errors.WrapWithDetails(errors.EPersistFailed, "failed to write groups.json", err,
+			map[string]string{"groups_path": s.GroupsPath()})
+	}
+	return nil
+}
+
+// AddRunToGroup records repoID/runID as a member of group in groups.json,
+// removing it from any other group (a run belongs to at most one group).
+// Groups left empty are dropped.
+func (s *Store) AddRunToGroup(group, repoID, runID string) error {
+	g, err := s.LoadGroups()
+	if err != nil {
+		return err
+	}
+	member := GroupRun{RepoID: repoID, RunID: runID}
+
+	for name, entry := range g.Groups {
+		runs := entry.Runs[:0]
+		for _, r := range entry.Runs {
+			if r != member {
+				runs = append(runs, r)
+			}
+		}
+		entry.Runs = runs
+		if len(runs) == 0 && name != group {
+			delete(g.Groups, name)
+			continue
+		}
+		g.Groups[name] = entry
+	}
+
+	entry, ok := g.Groups[group]
+	if !ok {
+		entry.CreatedAt = s.Now().UTC().Format("2006-01-02T15:04:05Z")
+	}
+	entry.Runs = append(entry.Runs, member)
+	sort.Slice(entry.Runs, func(i, j int) bool {
+		if entry.Runs[i].RepoID != entry.Runs[j].RepoID {
+			return entry.Runs[i].RepoID < entry.Runs[j].RepoID
+		}
+		return entry.Runs[i].RunID < entry.Runs[j].RunID
+	})
+	g.Groups[group] = entry
+
+	return s.SaveGroups(g)
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestAddRunToGroup(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	st := NewStore(fs.NewRealFS(), t.TempDir(), func() time.Time { return now })
+
+	g, err := st.LoadGroups()
+	if err != nil {
+		t.Fatalf("LoadGroups (missing file): %v", err)
+	}
+	if len(g.Groups) != 0 {
+		t.Fatalf("expected no groups, got %v", g.Groups)
+	}
+
+	for _, add := range []struct{ group, repoID, runID string }{
+		{"payments", "repo2", "run-b"},
+		{"payments", "repo1", "run-a"},
+		{"payments", "repo1", "run-a"}, // idempotent
+		{"auth", "repo1", "run-c"},
+	} {
+		if err := st.AddRunToGroup(add.group, add.repoID, add.runID); err != nil {
+			t.Fatalf("AddRunToGroup(%v): %v", add, err)
+		}
+	}
+
+	g, err = st.LoadGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payments := g.Groups["payments"]
+	want := []GroupRun{{RepoID: "repo1", RunID: "run-a"}, {RepoID: "repo2", RunID: "run-b"}}
+	if len(payments.Runs) != 2 || payments.Runs[0] != want[0] || payments.Runs[1] != want[1] {
+		t.Errorf("payments runs = %v, want %v", payments.Runs, want)
+	}
+	if payments.CreatedAt != "2026-01-10T12:00:00Z" {
+		t.Errorf("created_at = %q", payments.CreatedAt)
+	}
+
+	// Moving the only member of auth drops the group
+	if err := st.AddRunToGroup("payments", "repo1", "run-c"); err != nil {
+		t.Fatal(err)
+	}
+	g, err = st.LoadGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.Groups["auth"]; ok {
+		t.Errorf("empty group auth should be dropped: %v", g.Groups)
+	}
+	if n := len(g.Groups["payments"].Runs); n != 3 {
+		t.Errorf("payments has %d runs, want 3", n)
+	}
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/core"
+)
+
+// Lint severities.
+const (
+	LintError = "error" // meta.json violates the contract; commands may misbehave
+	LintWarn  = "warn"  // unusual but workable (e.g. adopted branch names)
+)
+
+// LintIssue is a single meta.json problem found by LintMeta.
+type LintIssue struct {
+	// Field is the meta.json field (dotted for nested fields, e.g. "archive.merged_at").
+	Field    string `json:"field"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+
+	// Fix repairs the field in place; nil if the problem needs a human.
+	Fix func(*RunMeta) `json:"-"`
+}
+
+var (
+	repoIDPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+	prURLPattern  = regexp.MustCompile(`/pull/([0-9]+)/?$`)
+)
+
+// LintMeta validates meta.json contents beyond JSON parsing for the run stored
+// under repos/<repoID>/runs/<runID> in dataDir. Issues are returned in field order.
+func LintMeta(meta *RunMeta, dataDir, repoID, runID string) []LintIssue {
+	var issues []LintIssue
+	add := func(field, severity, msg string, fix func(*RunMeta)) {
+		issues = append(issues, LintIssue{Field: field, Severity: severity, Message: msg, Fix: fix})
+	}
+
+	// schema_version
+	switch meta.SchemaVersion {
+	case MetaSchemaVersion:
+	case "":
+		add("schema_version", LintWarn, "missing; assuming "+MetaSchemaVersion, func(m *RunMeta) { m.SchemaVersion = MetaSchemaVersion })
+	default:
+		add("schema_version", LintError, "unknown schema_version "+strconv.Quote(meta.SchemaVersion)+" (this build knows "+MetaSchemaVersion+")", nil)
+	}
+
+	// identity must match the directory layout
+	if meta.RunID != runID {
+		add("run_id", LintError, "run_id "+strconv.Quote(meta.RunID)+" does not match run dir "+runID, nil)
+	} else if err := core.ValidateRunID(runID); err != nil {
+		add("run_id", LintWarn, err.Error(), nil)
+	}
+	if meta.RepoID != repoID {
+		add("repo_id", LintError, "repo_id "+strconv.Quote(meta.RepoID)+" does not match repo dir "+repoID, nil)
+	} else if !repoIDPattern.MatchString(repoID) {
+		add("repo_id", LintWarn, "repo_id is not 16 lowercase hex characters", nil)
+	}
+
+	if meta.Runner == "" {
+		add("runner", LintError, "missing", nil)
+	}
+	if meta.RunnerCmd == "" {
+		add("runner_cmd", LintError, "missing", nil)
+	}
+	if meta.ParentBranch == "" {
+		add("parent_branch", LintWarn, "missing", nil)
+	}
+
+	// branch: agency/<slug>-<shortid> unless adopted
+	switch {
+	case meta.Branch == "":
+		add("branch", LintError, "missing", nil)
+	case !strings.HasPrefix(meta.Branch, "agency/") || !strings.HasSuffix(meta.Branch, "-"+core.ShortID(runID)):
+		add("branch", LintWarn, "branch "+meta.Branch+" does not match agency/<slug>-"+core.ShortID(runID)+" (adopted?)", nil)
+	}
+
+	// worktree_path
+	expected := filepath.Join(dataDir, "repos", repoID, "worktrees", runID)
+	switch {
+	case meta.WorktreePath == "":
+		add("worktree_path", LintError, "missing", nil)
+	case !filepath.IsAbs(meta.WorktreePath):
+		add("worktree_path", LintError, "not an absolute path: "+meta.WorktreePath, nil)
+	case filepath.Clean(meta.WorktreePath) != meta.WorktreePath:
+		add("worktree_path", LintWarn, "not a clean path: "+meta.WorktreePath, func(m *RunMeta) { m.WorktreePath = filepath.Clean(m.WorktreePath) })
+	case meta.WorktreePath != expected && !withinDir(filepath.Join(dataDir, "repos"), meta.WorktreePath):
+		add("worktree_path", LintWarn, "outside the data dir (adopted worktree?): "+meta.WorktreePath, nil)
+	}
+
+	// timestamps
+	lintTimestamp(add, "created_at", meta.CreatedAt, true, func(m *RunMeta) *string { return &m.CreatedAt })
+	lintTimestamp(add, "last_push_at", meta.LastPushAt, false, func(m *RunMeta) *string { return &m.LastPushAt })
+	lintTimestamp(add, "last_verify_at", meta.LastVerifyAt, false, func(m *RunMeta) *string { return &m.LastVerifyAt })
+	if meta.Archive != nil {
+		lintTimestamp(add, "archive.archived_at", meta.Archive.ArchivedAt, false, func(m *RunMeta) *string { return &m.Archive.ArchivedAt })
+		lintTimestamp(add, "archive.merged_at", meta.Archive.MergedAt, false, func(m *RunMeta) *string { return &m.Archive.MergedAt })
+	}
+
+	if meta.TmuxSessionName != "" && meta.TmuxSessionName != "agency_"+runID {
+		add("tmux_session_name", LintWarn, "expected agency_"+runID+", got "+meta.TmuxSessionName, nil)
+	}
+
+	// PR fields
+	urlNumber := 0
+	if m := prURLPattern.FindStringSubmatch(meta.PRURL); m != nil {
+		urlNumber, _ = strconv.Atoi(m[1])
+	}
+	switch {
+	case meta.PRURL != "" && urlNumber == 0:
+		add("pr_url", LintError, "not a pull request URL: "+meta.PRURL, nil)
+	case meta.PRURL != "" && meta.PRNumber == 0:
+		n := urlNumber
+		add("pr_number", LintWarn, "missing; pr_url points at #"+strconv.Itoa(n), func(m *RunMeta) { m.PRNumber = n })
+	case meta.PRURL != "" && meta.PRNumber != urlNumber:
+		add("pr_number", LintError, "#"+strconv.Itoa(meta.PRNumber)+" does not match pr_url "+meta.PRURL, nil)
+	case meta.PRURL == "" && meta.PRNumber != 0:
+		add("pr_url", LintWarn, "missing for PR #"+strconv.Itoa(meta.PRNumber), nil)
+	}
+	if meta.PRNumber < 0 {
+		add("pr_number", LintError, "negative PR number", nil)
+	}
+	if meta.Archive != nil && meta.Archive.MergedAt != "" && meta.PRNumber == 0 && meta.PRURL == "" {
+		add("archive.merged_at", LintWarn, "merged_at is set but the run has no PR", nil)
+	}
+
+	return issues
+}
+
+// lintTimestamp checks that value is RFC3339. Non-UTC timestamps are fixable
+// by normalizing to UTC.
+func lintTimestamp(add func(field, severity, msg string, fix func(*RunMeta)), field, value string, required bool, ptr func(*RunMeta) *string) {
+	if value == "" {
+		if required {
+			add(field, LintError, "missing", nil)
+		}
+		return
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		add(field, LintError, "not an RFC3339 timestamp: "+value, nil)
+		return
+	}
+	if utc := t.UTC().Format(time.RFC3339); utc != value {
+		add(field, LintWarn, "not in UTC: "+value, func(m *RunMeta) { *ptr(m) = utc })
+	}
+}
+
+// withinDir reports whether path is dir or below it.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
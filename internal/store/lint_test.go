@@ -0,0 +1,68 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLintMeta(t *testing.T) {
+	const (
+		dataDir = "/data"
+		repoID  = "abcd1234ef567890"
+		runID   = "20260110120000-a3f2"
+	)
+	worktree := "/data/repos/" + repoID + "/worktrees/" + runID
+	valid := func() *RunMeta {
+		m := NewRunMeta(runID, repoID, "fix it", "claude", "claude", "main", "agency/fix-it-a3f2", worktree, time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+		m.PRNumber = 42
+		m.PRURL = "https://github.com/o/r/pull/42"
+		return m
+	}
+
+	if issues := LintMeta(valid(), dataDir, repoID, runID); len(issues) != 0 {
+		t.Fatalf("valid meta has issues: %+v", issues)
+	}
+
+	tests := []struct {
+		name     string
+		mutate   func(*RunMeta)
+		field    string
+		severity string
+		fixable  bool
+	}{
+		{"unknown schema", func(m *RunMeta) { m.SchemaVersion = "2.0" }, "schema_version", LintError, false},
+		{"missing schema", func(m *RunMeta) { m.SchemaVersion = "" }, "schema_version", LintWarn, true},
+		{"run_id mismatch", func(m *RunMeta) { m.RunID = "other" }, "run_id", LintError, false},
+		{"bad created_at", func(m *RunMeta) { m.CreatedAt = "2026-01-10 12:00" }, "created_at", LintError, false},
+		{"non-utc push", func(m *RunMeta) { m.LastPushAt = "2026-01-10T13:00:00+01:00" }, "last_push_at", LintWarn, true},
+		{"adopted branch", func(m *RunMeta) { m.Branch = "feature/manual" }, "branch", LintWarn, false},
+		{"relative worktree", func(m *RunMeta) { m.WorktreePath = "worktrees/x" }, "worktree_path", LintError, false},
+		{"unclean worktree", func(m *RunMeta) { m.WorktreePath = worktree + "/" }, "worktree_path", LintWarn, true},
+		{"external worktree", func(m *RunMeta) { m.WorktreePath = "/src/linked" }, "worktree_path", LintWarn, false},
+		{"pr number mismatch", func(m *RunMeta) { m.PRNumber = 7 }, "pr_number", LintError, false},
+		{"pr number missing", func(m *RunMeta) { m.PRNumber = 0 }, "pr_number", LintWarn, true},
+		{"pr url not a PR", func(m *RunMeta) { m.PRURL = "https://github.com/o/r" }, "pr_url", LintError, false},
+		{"bad merged_at", func(m *RunMeta) { m.Archive = &RunMetaArchive{MergedAt: "yesterday"} }, "archive.merged_at", LintError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := valid()
+			tt.mutate(meta)
+			issues := LintMeta(meta, dataDir, repoID, runID)
+			if len(issues) != 1 {
+				t.Fatalf("got %d issues, want 1: %+v", len(issues), issues)
+			}
+			got := issues[0]
+			if got.Field != tt.field || got.Severity != tt.severity || (got.Fix != nil) != tt.fixable {
+				t.Fatalf("issue = {%s %s fixable=%v %q}, want {%s %s fixable=%v}",
+					got.Field, got.Severity, got.Fix != nil, got.Message, tt.field, tt.severity, tt.fixable)
+			}
+			if got.Fix != nil {
+				got.Fix(meta)
+				if after := LintMeta(meta, dataDir, repoID, runID); len(after) != 0 {
+					t.Errorf("issues after fix: %+v", after)
+				}
+			}
+		})
+	}
+}
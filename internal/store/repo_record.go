@@ -3,6 +3,8 @@ package store
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	"github.com/NielsdaWheelz/agency/internal/fs"
@@ -13,6 +15,20 @@ type Capabilities struct {
 	GitHubOrigin bool   `json:"github_origin"`
 	OriginHost   string `json:"origin_host"`
 	GhAuthed     bool   `json:"gh_authed"`
+
+	// CheckedAt is when the capabilities were detected (RFC3339; empty in
+	// records written before it was recorded).
+	CheckedAt string `json:"checked_at,omitempty"`
+}
+
+// Stale reports whether c should be detected again: it has no CheckedAt, or
+// was checked more than ttl before now. ttl <= 0 never expires a CheckedAt.
+func (c Capabilities) Stale(now time.Time, ttl time.Duration) bool {
+	checked, err := time.Parse(time.RFC3339, c.CheckedAt)
+	if err != nil {
+		return true
+	}
+	return ttl > 0 && now.Sub(checked) > ttl
 }
 
 // RepoRecord represents the repo.json file for a repository.
@@ -29,6 +45,11 @@ type RepoRecord struct {
 	Capabilities     Capabilities `json:"capabilities"`
 	CreatedAt        string       `json:"created_at"`
 	UpdatedAt        string       `json:"updated_at"`
+
+	// DoctorEnv is the script environment the last successful agency doctor
+	// saw, which agency show compares runs' setup_env.json against (nil if
+	// none).
+	DoctorEnv *SetupEnv `json:"doctor_env,omitempty"`
 }
 
 // BuildRepoRecordInput contains the input for building a RepoRecord.
@@ -41,6 +62,9 @@ type BuildRepoRecordInput struct {
 	OriginURL        string
 	OriginHost       string
 	Capabilities     Capabilities
+
+	// DoctorEnv replaces the record's doctor environment (nil = keep it).
+	DoctorEnv *SetupEnv
 }
 
 // LoadRepoRecord reads repo.json for the given repoID.
@@ -96,10 +120,14 @@ func (s *Store) UpsertRepoRecord(existing *RepoRecord, input BuildRepoRecordInpu
 	if existing != nil {
 		// Preserve original creation time
 		rec.CreatedAt = existing.CreatedAt
+		rec.DoctorEnv = existing.DoctorEnv
 	} else {
 		// New record
 		rec.CreatedAt = now
 	}
+	if input.DoctorEnv != nil {
+		rec.DoctorEnv = input.DoctorEnv
+	}
 
 	return rec
 }
@@ -112,6 +140,8 @@ func (s *Store) SaveRepoRecord(rec RepoRecord) error {
 	if err := s.FS.MkdirAll(repoDir, 0755); err != nil {
 		return errors.Wrap(errors.EStoreCorrupt, "failed to create repo directory", err)
 	}
+	_ = fs.ShareDir(s.DataDir, filepath.Dir(repoDir))
+	_ = fs.ShareDir(s.DataDir, repoDir)
 
 	// Marshal with indentation for human readability
 	data, err := json.MarshalIndent(rec, "", "  ")
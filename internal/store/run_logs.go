@@ -0,0 +1,223 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+)
+
+// CompressedLogExt is appended to a script log compressed by agency gc.
+const CompressedLogExt = ".gz"
+
+// LogFile is one script log in a run's logs dir.
+type LogFile struct {
+	// Name is the log name without CompressedLogExt (e.g. "setup.log").
+	Name string
+
+	// Path is the file on disk (ending in CompressedLogExt if compressed).
+	Path string
+
+	// Bytes is the size on disk (post-compression).
+	Bytes int64
+
+	Compressed bool
+
+	// Sealed is true if the log is encrypted at rest (see crypt).
+	Sealed bool
+}
+
+// isLogName reports whether name is a script log: <name>.log or a rotated
+// <name>.log.N.
+func isLogName(name string) bool {
+	return strings.HasSuffix(name, ".log") || strings.Contains(name, ".log.")
+}
+
+// ListLogs returns the script logs in logsDir, sorted by name. If both a
+// plain and a compressed copy exist, the plain one (written after
+// compression) wins. A missing logs dir has no logs.
+func ListLogs(logsDir string) ([]LogFile, error) {
+	entries, err := os.ReadDir(logsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	byName := make(map[string]LogFile)
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), CompressedLogExt)
+		compressed := name != e.Name()
+		if !isLogName(name) {
+			continue
+		}
+		if prev, ok := byName[name]; ok && !prev.Compressed {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(logsDir, e.Name())
+		byName[name] = LogFile{
+			Name:       name,
+			Path:       path,
+			Bytes:      info.Size(),
+			Compressed: compressed,
+			Sealed:     crypt.IsEncryptedFile(path),
+		}
+	}
+
+	logs := make([]LogFile, 0, len(byName))
+	for _, l := range byName {
+		logs = append(logs, l)
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].Name < logs[j].Name })
+	return logs, nil
+}
+
+// LogsBytes returns the on-disk size of the script logs in logsDir
+// (0 if it cannot be read).
+func LogsBytes(logsDir string) int64 {
+	logs, _ := ListLogs(logsDir)
+	var total int64
+	for _, l := range logs {
+		total += l.Bytes
+	}
+	return total
+}
+
+// ResolveLogPath returns path, or its compressed copy if only that exists.
+func ResolveLogPath(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		if _, err := os.Stat(path + CompressedLogExt); err == nil {
+			return path + CompressedLogExt
+		}
+	}
+	return path
+}
+
+// OpenLog opens the script log at path (as named before compression),
+// transparently decrypting it with c if it is encrypted at rest and
+// decompressing it if gc compressed it.
+func OpenLog(path string, c *crypt.Crypt) (io.ReadCloser, error) {
+	path = ResolveLogPath(path)
+	var src io.ReadCloser
+	if crypt.IsEncryptedFile(path) {
+		data, err := c.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		src = io.NopCloser(bytes.NewReader(data))
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		src = f
+	}
+	if !strings.HasSuffix(path, CompressedLogExt) {
+		return src, nil
+	}
+	zr, err := gzip.NewReader(src)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	return &gzipLog{Reader: zr, src: src}, nil
+}
+
+type gzipLog struct {
+	*gzip.Reader
+	src io.Closer
+}
+
+func (g *gzipLog) Close() error {
+	g.Reader.Close()
+	return g.src.Close()
+}
+
+// CompressLogs gzips every uncompressed script log in logsDir, replacing it
+// with <name>.gz. Encrypted logs are skipped, as they do not compress.
+// Returns the on-disk size of the compressed logs before and after. A log
+// that fails to compress is left as is and the others are still compressed;
+// the failures are returned joined, with the totals of those that succeeded.
+func CompressLogs(logsDir string) (before, after int64, err error) {
+	logs, err := ListLogs(logsDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	var errs []error
+	for _, l := range logs {
+		if l.Compressed || l.Sealed {
+			continue
+		}
+		size, err := compressLog(l.Path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", l.Name, err))
+			continue
+		}
+		before += l.Bytes
+		after += size
+	}
+	return before, after, stderrors.Join(errs...)
+}
+
+// compressLog writes path.gz via a temp file and rename, then removes path.
+// Returns the compressed size.
+func compressLog(path string) (int64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".agency-tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	zw := gzip.NewWriter(tmp)
+	if _, err := io.Copy(zw, src); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return 0, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path+CompressedLogExt); err != nil {
+		return 0, err
+	}
+	success = true
+	return info.Size(), os.Remove(path)
+}
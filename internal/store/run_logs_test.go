@@ -0,0 +1,94 @@
+package store
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressLogs_ContinuesPastFailure(t *testing.T) {
+	logsDir := t.TempDir()
+	for name, content := range map[string]string{"a.log": "first\n", "b.log": "second\n"} {
+		if err := os.WriteFile(filepath.Join(logsDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A directory in the way of a.log.gz makes a.log fail to compress
+	if err := os.Mkdir(filepath.Join(logsDir, "a.log.gz"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	before, after, err := CompressLogs(logsDir)
+	if err == nil || !strings.Contains(err.Error(), "a.log") {
+		t.Fatalf("CompressLogs() error = %v, want a.log's failure", err)
+	}
+	if before != int64(len("second\n")) || after <= 0 {
+		t.Errorf("CompressLogs() = %d, %d; want b.log's sizes", before, after)
+	}
+	if _, err := os.Stat(filepath.Join(logsDir, "a.log")); err != nil {
+		t.Errorf("a.log should be left as is: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(logsDir, "b.log.gz")); err != nil {
+		t.Errorf("b.log should still be compressed: %v", err)
+	}
+}
+
+func TestCompressLogs(t *testing.T) {
+	logsDir := t.TempDir()
+	setup := strings.Repeat("installing dependencies\n", 1000)
+	files := map[string]string{
+		"setup.log":   setup,
+		"setup.log.1": "older output\n",
+		"notes.txt":   "not a log\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(logsDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before, after, err := CompressLogs(logsDir)
+	if err != nil {
+		t.Fatalf("CompressLogs() error = %v", err)
+	}
+	if before != int64(len(setup)+len("older output\n")) || after <= 0 || after >= before {
+		t.Errorf("CompressLogs() = %d, %d; want the uncompressed total and a smaller size", before, after)
+	}
+
+	logs, err := ListLogs(logsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].Name != "setup.log" || !logs[0].Compressed || logs[1].Name != "setup.log.1" {
+		t.Fatalf("ListLogs() = %+v, want setup.log and setup.log.1, compressed", logs)
+	}
+	if _, err := os.Stat(filepath.Join(logsDir, "setup.log")); !os.IsNotExist(err) {
+		t.Errorf("setup.log still exists after compression (err=%v)", err)
+	}
+	if got := LogsBytes(logsDir); got != after {
+		t.Errorf("LogsBytes() = %d, want %d", got, after)
+	}
+
+	r, err := OpenLog(filepath.Join(logsDir, "setup.log"), nil)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != setup {
+		t.Errorf("OpenLog() read %d bytes (err=%v), want the original content", len(got), err)
+	}
+
+	// Already compressed logs are skipped; a newer plain log wins
+	if before, _, _ := CompressLogs(logsDir); before != 0 {
+		t.Errorf("second CompressLogs() compressed %d bytes, want 0", before)
+	}
+	if err := os.WriteFile(filepath.Join(logsDir, "setup.log"), []byte("rerun\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if path := ResolveLogPath(filepath.Join(logsDir, "setup.log")); filepath.Base(path) != "setup.log" {
+		t.Errorf("ResolveLogPath() = %s, want the plain log", path)
+	}
+}
@@ -3,12 +3,16 @@ package store
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/NielsdaWheelz/agency/internal/errors"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 )
 
+// MetaSchemaVersion is the meta.json schema_version written by this build.
+const MetaSchemaVersion = "1.0"
+
 // RunMeta represents the metadata for a run, persisted to meta.json.
 // This is the public contract per the constitution.
 type RunMeta struct {
@@ -42,10 +46,37 @@ type RunMeta struct {
 	// CreatedAt is the creation timestamp in RFC3339 UTC format.
 	CreatedAt string `json:"created_at"`
 
+	// CreatedBy is the user who created or adopted the run (see
+	// identity.CurrentUser; absent for runs created before it was recorded).
+	CreatedBy string `json:"created_by,omitempty"`
+
 	// TmuxSessionName is the tmux session name (set only on successful tmux creation).
 	// Omit when writing initial meta (PR-06); set in PR-08.
 	TmuxSessionName string `json:"tmux_session_name,omitempty"`
 
+	// Labels are user-supplied key=value pairs set at creation (e.g. ticket=JIRA-123).
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Group is the named group the run belongs to (agency run --group,
+	// agency group add); indexed in groups.json.
+	Group string `json:"group,omitempty"`
+
+	// TaskID links the runs agency run --runners launched for one task, one
+	// per runner (agency ls --task, agency compare --task).
+	TaskID string `json:"task_id,omitempty"`
+
+	// SkippedSteps lists creation steps left out on purpose (agency run
+	// --no-setup / --no-tmux): "setup", "tmux".
+	SkippedSteps []string `json:"skipped_steps,omitempty"`
+
+	// Worktree records how agency run created the worktree (absent for
+	// adopted runs and runs created before it was recorded).
+	Worktree *RunMetaWorktree `json:"worktree,omitempty"`
+
+	// Credentials records the GitHub token given to the runner session
+	// (agency.json github.credentials; absent = inherit).
+	Credentials *RunMetaCredentials `json:"credentials,omitempty"`
+
 	// Flags contains optional boolean flags for run state.
 	Flags *RunMetaFlags `json:"flags,omitempty"`
 
@@ -61,11 +92,61 @@ type RunMeta struct {
 	// LastPushAt is the timestamp of the last push (set by push, not in PR-06).
 	LastPushAt string `json:"last_push_at,omitempty"`
 
-	// LastVerifyAt is the timestamp of the last verify (set by merge, not in PR-06).
+	// LastVerifyAt is the timestamp of the last verify attempt (the full
+	// history is in verify.jsonl).
 	LastVerifyAt string `json:"last_verify_at,omitempty"`
 
+	// ReportCommit is the branch commit SHA covered by report.md (set when verify passes).
+	// A report.md footer (<!-- agency:report-commit <sha> -->) takes precedence.
+	ReportCommit string `json:"report_commit,omitempty"`
+
 	// Archive contains archive-related fields (set by merge/clean, not in PR-06).
 	Archive *RunMetaArchive `json:"archive,omitempty"`
+
+	// Aliases records names the run had before `agency mv` (set by mv).
+	Aliases *RunMetaAliases `json:"aliases,omitempty"`
+
+	// Deadline is the run's time box (set by run --deadline or defaults.deadline).
+	Deadline *RunMetaDeadline `json:"deadline,omitempty"`
+
+	// Template records how the title was expanded (set by run when --title
+	// has placeholders).
+	Template *RunMetaTemplate `json:"template,omitempty"`
+
+	// Probes records the latest result of each agency.json probe, keyed by
+	// probe name (set by agency watch).
+	Probes map[string]RunMetaProbe `json:"probes,omitempty"`
+}
+
+// RunMetaTemplate records a title template and its expansion.
+type RunMetaTemplate struct {
+	// Title is --title as given, before expansion.
+	Title string `json:"title"`
+
+	// Vars are the placeholder values used, e.g. {"date": "2026-01-10"}.
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// RunMetaProbe records the latest check of one probe.
+type RunMetaProbe struct {
+	// LastCheckAt is when the probe last finished (RFC3339).
+	LastCheckAt string `json:"last_check_at"`
+
+	// OK is true if the last check exited 0 within its timeout.
+	OK bool `json:"ok"`
+
+	// ExitCode is the last check's exit code (-1 if it did not exit).
+	ExitCode int `json:"exit_code"`
+
+	// TimedOut is true if the last check was killed at its timeout.
+	TimedOut bool `json:"timed_out,omitempty"`
+
+	// ConsecutiveFailures counts failed checks since the last success.
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
+
+	// Flagged is true while this probe's failures hold flags.needs_attention;
+	// its next success clears the flag.
+	Flagged bool `json:"flagged,omitempty"`
 }
 
 // RunMetaFlags contains optional boolean flags for run state.
@@ -83,6 +164,59 @@ type RunMetaFlags struct {
 	Abandoned bool `json:"abandoned,omitempty"`
 }
 
+// RunMetaCredentials records how the runner's GITHUB_TOKEN is minted.
+// The token itself is only stored in the TokenPath file.
+type RunMetaCredentials struct {
+	// Mode is the github.credentials mode: "gh" or "app".
+	Mode string `json:"mode"`
+
+	// TokenPath is the 0600 token file the runner session reads.
+	TokenPath string `json:"token_path,omitempty"`
+
+	// MintedAt is when the current token was minted (RFC3339).
+	MintedAt string `json:"minted_at,omitempty"`
+
+	// ExpiresAt is when the current token expires (RFC3339; empty = unknown).
+	ExpiresAt string `json:"expires_at,omitempty"`
+
+	// App holds the GitHub App settings for mode "app".
+	App *RunMetaCredentialsApp `json:"app,omitempty"`
+}
+
+// RunMetaCredentialsApp is the GitHub App installation minting run tokens.
+type RunMetaCredentialsApp struct {
+	AppID          int64             `json:"app_id"`
+	InstallationID int64             `json:"installation_id"`
+	PrivateKeyPath string            `json:"private_key_path"`
+	Permissions    map[string]string `json:"permissions,omitempty"`
+	APIURL         string            `json:"api_url,omitempty"`
+
+	// Repository is the repo name the token is scoped to ("" = every repo
+	// of the installation, for repos without a github origin).
+	Repository string `json:"repository,omitempty"`
+}
+
+// RunMetaWorktree records worktree creation for a run.
+type RunMetaWorktree struct {
+	// DurationMs is how long git worktree add (and the sparse checkout) took.
+	DurationMs int64 `json:"duration_ms"`
+
+	// SparseCheckout are the worktrees.sparse_checkout patterns applied
+	// (omitted for full checkouts).
+	SparseCheckout []string `json:"sparse_checkout,omitempty"`
+
+	// SparseProfile is the worktrees.sparse_profiles name selected with
+	// agency run --sparse (omitted when none was).
+	SparseProfile string `json:"sparse_profile,omitempty"`
+
+	// Submodules are the worktree's submodule (gitlink) paths.
+	Submodules []string `json:"submodules,omitempty"`
+
+	// SubmodulesInitialized is true if worktrees.submodules ran
+	// git submodule update --init --recursive for them.
+	SubmodulesInitialized bool `json:"submodules_initialized,omitempty"`
+}
+
 // RunMetaSetup contains setup script execution details.
 type RunMetaSetup struct {
 	// Command is the exact command string executed (e.g., "sh -lc scripts/agency_setup.sh").
@@ -105,6 +239,70 @@ type RunMetaSetup struct {
 
 	// OutputSummary is the value of "summary" from .agency/out/setup.json (if present and parsed).
 	OutputSummary string `json:"output_summary,omitempty"`
+
+	// OutputSchemaVersion is the "schema_version" from setup.json (if present and parsed).
+	OutputSchemaVersion string `json:"output_schema_version,omitempty"`
+
+	// OutputChecks are the named checks from a v2 setup.json, in reported order.
+	OutputChecks []RunMetaSetupCheck `json:"output_checks,omitempty"`
+
+	// OutputArtifacts are the artifact paths from a v2 setup.json (relative to the worktree).
+	OutputArtifacts []string `json:"output_artifacts,omitempty"`
+
+	// OutputWarnings are the warnings from a v2 setup.json.
+	OutputWarnings []string `json:"output_warnings,omitempty"`
+
+	// OutputFile identifies the setup.json the output_* fields were parsed from.
+	OutputFile *RunMetaOutputFile `json:"output_file,omitempty"`
+
+	// OutputErrors are the places setup.json does not match its schema
+	// (`agency schema setup`), as "line L, column C: <pointer>: <problem>".
+	OutputErrors []string `json:"output_errors,omitempty"`
+
+	// PreviousOutputPath is where output left in .agency/out before setup ran
+	// was moved (under .agency/out-history/; empty if there was none).
+	PreviousOutputPath string `json:"previous_output_path,omitempty"`
+
+	// Sandboxed is true if setup ran under sandbox.enforce.
+	Sandboxed bool `json:"sandboxed,omitempty"`
+
+	// SandboxViolations are the paths outside the worktree that changed
+	// during a sandboxed setup ("<change>: <path>", capped; see SandboxReportPath).
+	SandboxViolations []string `json:"sandbox_violations,omitempty"`
+
+	// SandboxReportPath is the full sandbox report (only set on violations).
+	SandboxReportPath string `json:"sandbox_report_path,omitempty"`
+
+	// CacheKey is the agency.json setup_cache key of this run's inputs
+	// (empty if setup caching is not configured).
+	CacheKey string `json:"cache_key,omitempty"`
+
+	// CacheHit is true if setup was skipped because an earlier run with the
+	// same CacheKey succeeded; its cached paths were restored instead.
+	CacheHit bool `json:"cache_hit,omitempty"`
+
+	// CacheRunID is the run whose setup populated the cache entry (on a hit).
+	CacheRunID string `json:"cache_run_id,omitempty"`
+}
+
+// RunMetaOutputFile identifies the exact version of a parsed script output file.
+type RunMetaOutputFile struct {
+	// Path is the absolute path of the file.
+	Path string `json:"path"`
+
+	// SHA256 is the hex SHA-256 of the parsed contents.
+	SHA256 string `json:"sha256"`
+
+	// ModifiedAt is the file's mtime in RFC3339 UTC format.
+	ModifiedAt string `json:"modified_at"`
+}
+
+// RunMetaSetupCheck is one named check reported by a v2 setup.json.
+type RunMetaSetupCheck struct {
+	Name       string `json:"name"`
+	Ok         bool   `json:"ok"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Details    string `json:"details,omitempty"`
 }
 
 // RunMetaArchive contains archive-related fields.
@@ -116,6 +314,34 @@ type RunMetaArchive struct {
 	MergedAt string `json:"merged_at,omitempty"`
 }
 
+// RunMetaDeadline records when a time-boxed run is due.
+type RunMetaDeadline struct {
+	// At is the deadline timestamp in RFC3339 UTC format.
+	At string `json:"at"`
+
+	// Kill is true if the tmux session is killed when the deadline passes.
+	Kill bool `json:"kill,omitempty"`
+}
+
+// DeadlineExceeded reports whether the run has a deadline at or before now.
+// An unparseable deadline is treated as not exceeded.
+func (m *RunMeta) DeadlineExceeded(now time.Time) bool {
+	if m.Deadline == nil {
+		return false
+	}
+	at, err := time.Parse(time.RFC3339, m.Deadline.At)
+	return err == nil && !now.Before(at)
+}
+
+// RunMetaAliases contains previous names of a renamed run, oldest first.
+type RunMetaAliases struct {
+	// Titles are previous titles.
+	Titles []string `json:"titles,omitempty"`
+
+	// Branches are previous branch names (only set when the branch was renamed).
+	Branches []string `json:"branches,omitempty"`
+}
+
 // EnsureRunDir creates the run directory with exclusive semantics.
 // Returns the run dir path on success.
 // Fails with E_RUN_DIR_EXISTS if the directory already exists.
@@ -135,6 +361,9 @@ func (s *Store) EnsureRunDir(repoID, runID string) (string, error) {
 			map[string]string{"runs_dir": runsDir},
 		)
 	}
+	_ = fs.ShareDir(s.DataDir, filepath.Dir(s.RepoDir(repoID)))
+	_ = fs.ShareDir(s.DataDir, s.RepoDir(repoID))
+	_ = fs.ShareDir(s.DataDir, runsDir)
 
 	// Create run directory with exclusive semantics using os.Mkdir
 	// This fails if the directory already exists
@@ -153,6 +382,7 @@ func (s *Store) EnsureRunDir(repoID, runID string) (string, error) {
 			map[string]string{"run_dir": runDir},
 		)
 	}
+	_ = fs.ShareDir(s.DataDir, runDir)
 
 	// Create logs subdirectory
 	logsDir := s.RunLogsDir(repoID, runID)
@@ -164,6 +394,7 @@ func (s *Store) EnsureRunDir(repoID, runID string) (string, error) {
 			map[string]string{"logs_dir": logsDir},
 		)
 	}
+	_ = fs.ShareDir(s.DataDir, logsDir)
 
 	return runDir, nil
 }
@@ -174,7 +405,10 @@ func (s *Store) EnsureRunDir(repoID, runID string) (string, error) {
 func (s *Store) WriteInitialMeta(repoID, runID string, meta *RunMeta) error {
 	metaPath := s.RunMetaPath(repoID, runID)
 
-	if err := fs.WriteJSONAtomic(metaPath, meta, 0o644); err != nil {
+	if err := s.writeMetaFile(metaPath, meta); err != nil {
+		if errors.GetCode(err) != "" {
+			return err
+		}
 		return errors.WrapWithDetails(
 			errors.EMetaWriteFailed,
 			"failed to write meta.json atomically",
@@ -202,7 +436,13 @@ func (s *Store) UpdateMeta(repoID, runID string, updateFn func(*RunMeta)) error
 	updateFn(meta)
 
 	// Write back atomically
-	if err := fs.WriteJSONAtomic(metaPath, meta, 0o644); err != nil {
+	if err := s.writeMetaFile(metaPath, meta); err != nil {
+		if errors.GetCode(err) != "" {
+			return err
+		}
+		if os.IsPermission(err) {
+			return PermissionDenied(err, filepath.Dir(metaPath), meta.CreatedBy)
+		}
 		return errors.WrapWithDetails(
 			errors.EMetaWriteFailed,
 			"failed to write meta.json atomically",
@@ -237,6 +477,10 @@ func (s *Store) ReadMeta(repoID, runID string) (*RunMeta, error) {
 		)
 	}
 
+	if data, err = s.Crypt.Decrypt(data); err != nil {
+		return nil, err
+	}
+
 	var meta RunMeta
 	if err := jsonUnmarshal(data, &meta); err != nil {
 		return nil, errors.WrapWithDetails(
@@ -254,7 +498,7 @@ func (s *Store) ReadMeta(repoID, runID string) (*RunMeta, error) {
 // createdAt should be the current time in UTC.
 func NewRunMeta(runID, repoID, title, runner, runnerCmd, parentBranch, branch, worktreePath string, createdAt time.Time) *RunMeta {
 	return &RunMeta{
-		SchemaVersion: "1.0",
+		SchemaVersion: MetaSchemaVersion,
 		RunID:         runID,
 		RepoID:        repoID,
 		Title:         title,
@@ -267,7 +511,40 @@ func NewRunMeta(runID, repoID, title, runner, runnerCmd, parentBranch, branch, w
 	}
 }
 
+// writeMetaFile writes meta to metaPath atomically, encrypted when
+// encryption at rest is enabled (see Store.Crypt).
+func (s *Store) writeMetaFile(metaPath string, meta *RunMeta) error {
+	if !s.Crypt.Enabled() {
+		return fs.WriteJSONAtomic(metaPath, meta, 0o644)
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	sealed, err := s.Crypt.Encrypt(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	return fs.WriteFileAtomic(s.FS, metaPath, sealed, 0o644)
+}
+
 // jsonUnmarshal wraps json.Unmarshal (can be stubbed for testing).
 func jsonUnmarshal(data []byte, v any) error {
 	return json.Unmarshal(data, v)
 }
+
+// PermissionDenied wraps a permission error on dir, a run or repo dir that
+// another user of a shared data dir created, as E_PERMISSION_DENIED.
+// owner is the run's created_by ("" if unknown).
+func PermissionDenied(err error, dir, owner string) error {
+	details := map[string]string{"path": dir}
+	msg := "permission denied writing " + dir
+	if owner != "" {
+		details["owner"] = owner
+		msg += " (created by " + owner + ")"
+	}
+	return errors.WithHints(errors.WrapWithDetails(errors.EPermissionDenied, msg, err, details),
+		"in a shared data dir, make the data dir group-writable before creating runs (chgrp <team> + chmod 2770); "+
+			"the owner can fix existing dirs with chmod -R g+rwX,g+s",
+		"agency doctor lists data dir entries other users cannot write (data_dir_shared)")
+}
@@ -80,6 +80,37 @@ func TestEnsureRunDir_Success(t *testing.T) {
 	}
 }
 
+// TestEnsureRunDir_SharedDataDir verifies dirs other users can write in a
+// group-writable data dir, and private dirs otherwise.
+func TestEnsureRunDir_SharedDataDir(t *testing.T) {
+	for _, tt := range []struct {
+		dataDirMode os.FileMode
+		wantGroup   os.FileMode
+	}{
+		{0o700, 0},
+		{0o2770, 0o070},
+	} {
+		dataDir := t.TempDir()
+		if err := os.Chmod(dataDir, tt.dataDirMode); err != nil {
+			t.Fatal(err)
+		}
+		s := NewStore(fs.NewRealFS(), dataDir, nil)
+		runDir, err := s.EnsureRunDir("repo123", "run456")
+		if err != nil {
+			t.Fatalf("EnsureRunDir() error = %v", err)
+		}
+		for _, dir := range []string{s.RepoDir("repo123"), s.RunsDir("repo123"), runDir, filepath.Join(runDir, "logs")} {
+			info, err := os.Stat(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm() & 0o070; got != tt.wantGroup {
+				t.Errorf("data dir %o: %s group bits = %o, want %o", tt.dataDirMode, dir, got, tt.wantGroup)
+			}
+		}
+	}
+}
+
 // TestEnsureRunDir_Collision verifies E_RUN_DIR_EXISTS on collision.
 func TestEnsureRunDir_Collision(t *testing.T) {
 	dataDir := t.TempDir()
@@ -410,3 +441,22 @@ func TestJSONOmitEmptyFields(t *testing.T) {
 		}
 	}
 }
+
+func TestRunMetaDeadlineExceeded(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	meta := &RunMeta{}
+	if meta.DeadlineExceeded(now) {
+		t.Error("run without deadline reported exceeded")
+	}
+	meta.Deadline = &RunMetaDeadline{At: now.Add(time.Hour).Format(time.RFC3339)}
+	if meta.DeadlineExceeded(now) {
+		t.Error("deadline reported exceeded an hour early")
+	}
+	if !meta.DeadlineExceeded(now.Add(time.Hour)) {
+		t.Error("deadline not reported exceeded at the deadline")
+	}
+	meta.Deadline.At = "soon"
+	if meta.DeadlineExceeded(now) {
+		t.Error("unparseable deadline reported exceeded")
+	}
+}
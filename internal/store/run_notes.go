@@ -0,0 +1,103 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// RunNote is a single timestamped note attached to a run.
+// Notes are persisted one JSON object per line in notes.jsonl.
+type RunNote struct {
+	// Timestamp is the time the note was recorded in RFC3339 UTC format.
+	Timestamp string `json:"timestamp"`
+
+	// Text is the note body (verbatim, may span multiple lines).
+	Text string `json:"text"`
+}
+
+// AppendNote appends a timestamped note to the run's notes.jsonl as a
+// single O_APPEND write, like events.jsonl, so concurrent notes (taken
+// without the repo lock) are not lost. The run directory must already exist.
+// Returns E_USAGE if text is empty, E_PERSIST_FAILED on write errors.
+func (s *Store) AppendNote(repoID, runID, text string) (RunNote, error) {
+	if strings.TrimSpace(text) == "" {
+		return RunNote{}, errors.New(errors.EUsage, "note text is required")
+	}
+
+	notesPath := s.RunNotesPath(repoID, runID)
+
+	note := RunNote{
+		Timestamp: s.Now().UTC().Format(time.RFC3339),
+		Text:      text,
+	}
+	line, err := json.Marshal(note)
+	if err != nil {
+		return RunNote{}, errors.Wrap(errors.EInternal, "failed to encode note", err)
+	}
+
+	if err := appendLine(notesPath, line); err != nil {
+		return RunNote{}, errors.WrapWithDetails(
+			errors.EPersistFailed,
+			"failed to write notes.jsonl",
+			err,
+			map[string]string{"notes_path": notesPath},
+		)
+	}
+
+	return note, nil
+}
+
+// appendLine appends line and a newline to the file at path in one write,
+// creating the file if missing.
+func appendLine(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fs.ShareFile(f, filepath.Dir(path))
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadNotes reads all notes for a run in the order they were recorded.
+// Returns nil (no error) if notes.jsonl does not exist.
+// Malformed lines are skipped so a single bad line does not hide the rest.
+func (s *Store) ReadNotes(repoID, runID string) ([]RunNote, error) {
+	notesPath := s.RunNotesPath(repoID, runID)
+
+	data, err := s.FS.ReadFile(notesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WrapWithDetails(
+			errors.EStoreCorrupt,
+			"failed to read notes.jsonl",
+			err,
+			map[string]string{"notes_path": notesPath},
+		)
+	}
+
+	var notes []RunNote
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var note RunNote
+		if err := json.Unmarshal([]byte(line), &note); err != nil {
+			continue
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, nil
+}
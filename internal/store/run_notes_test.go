@@ -0,0 +1,145 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// TestRunNotesPath verifies notes.jsonl path construction.
+func TestRunNotesPath(t *testing.T) {
+	s := NewStore(nil, "/data/agency", nil)
+	got := s.RunNotesPath("repo123", "run456")
+	want := "/data/agency/repos/repo123/runs/run456/notes.jsonl"
+	if got != want {
+		t.Errorf("RunNotesPath() = %q, want %q", got, want)
+	}
+}
+
+// TestAppendNote_Roundtrip verifies notes are appended in order and read back.
+func TestAppendNote_Roundtrip(t *testing.T) {
+	dataDir := t.TempDir()
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	s := NewStore(fs.NewRealFS(), dataDir, func() time.Time { return now })
+
+	if _, err := s.EnsureRunDir("repo123", "run456"); err != nil {
+		t.Fatalf("EnsureRunDir() error = %v", err)
+	}
+
+	first, err := s.AppendNote("repo123", "run456", "first note")
+	if err != nil {
+		t.Fatalf("AppendNote() error = %v", err)
+	}
+	if first.Timestamp != "2026-01-10T12:00:00Z" {
+		t.Errorf("Timestamp = %q, want %q", first.Timestamp, "2026-01-10T12:00:00Z")
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := s.AppendNote("repo123", "run456", "second\nmulti-line"); err != nil {
+		t.Fatalf("AppendNote() error = %v", err)
+	}
+
+	notes, err := s.ReadNotes("repo123", "run456")
+	if err != nil {
+		t.Fatalf("ReadNotes() error = %v", err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("len(notes) = %d, want 2", len(notes))
+	}
+	if notes[0].Text != "first note" {
+		t.Errorf("notes[0].Text = %q, want %q", notes[0].Text, "first note")
+	}
+	if notes[1].Text != "second\nmulti-line" {
+		t.Errorf("notes[1].Text = %q, want %q", notes[1].Text, "second\nmulti-line")
+	}
+	if notes[1].Timestamp != "2026-01-10T13:00:00Z" {
+		t.Errorf("notes[1].Timestamp = %q, want %q", notes[1].Timestamp, "2026-01-10T13:00:00Z")
+	}
+}
+
+// TestAppendNote_Concurrent verifies concurrent notes are all kept.
+func TestAppendNote_Concurrent(t *testing.T) {
+	s := NewStore(fs.NewRealFS(), t.TempDir(), time.Now)
+	if _, err := s.EnsureRunDir("repo123", "run456"); err != nil {
+		t.Fatalf("EnsureRunDir() error = %v", err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := s.AppendNote("repo123", "run456", fmt.Sprintf("note %d", i)); err != nil {
+				t.Errorf("AppendNote() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	notes, err := s.ReadNotes("repo123", "run456")
+	if err != nil {
+		t.Fatalf("ReadNotes() error = %v", err)
+	}
+	if len(notes) != n {
+		t.Errorf("len(notes) = %d, want %d", len(notes), n)
+	}
+}
+
+// TestAppendNote_EmptyText verifies empty notes are rejected.
+func TestAppendNote_EmptyText(t *testing.T) {
+	s := NewStore(fs.NewRealFS(), t.TempDir(), time.Now)
+
+	_, err := s.AppendNote("repo123", "run456", "   ")
+	if errors.GetCode(err) != errors.EUsage {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EUsage)
+	}
+}
+
+// TestReadNotes_Missing verifies a missing notes file yields no notes.
+func TestReadNotes_Missing(t *testing.T) {
+	s := NewStore(fs.NewRealFS(), t.TempDir(), nil)
+
+	notes, err := s.ReadNotes("repo123", "run456")
+	if err != nil {
+		t.Fatalf("ReadNotes() error = %v", err)
+	}
+	if notes != nil {
+		t.Errorf("notes = %v, want nil", notes)
+	}
+}
+
+// TestReadNotes_SkipsMalformedLines verifies bad lines do not hide valid notes.
+func TestReadNotes_SkipsMalformedLines(t *testing.T) {
+	dataDir := t.TempDir()
+	s := NewStore(fs.NewRealFS(), dataDir, nil)
+
+	runDir := s.RunDir("repo123", "run456")
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"timestamp":"2026-01-10T12:00:00Z","text":"ok"}
+not json
+{"timestamp":"2026-01-10T13:00:00Z","text":"also ok"}
+`
+	if err := os.WriteFile(filepath.Join(runDir, "notes.jsonl"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	notes, err := s.ReadNotes("repo123", "run456")
+	if err != nil {
+		t.Fatalf("ReadNotes() error = %v", err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("len(notes) = %d, want 2", len(notes))
+	}
+	if notes[1].Text != "also ok" {
+		t.Errorf("notes[1].Text = %q, want %q", notes[1].Text, "also ok")
+	}
+}
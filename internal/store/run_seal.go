@@ -0,0 +1,62 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+)
+
+// TranscriptFileName is a run's transcript in its run dir.
+const TranscriptFileName = "transcript.txt"
+
+// PlaintextRunFiles returns the run files that encryption at rest covers
+// (meta.json, transcript.txt, and the script logs, compressed or not) and
+// that are still unencrypted, sorted. Empty files are left out.
+func PlaintextRunFiles(runDir string) []string {
+	candidates := []string{
+		filepath.Join(runDir, "meta.json"),
+		filepath.Join(runDir, TranscriptFileName),
+	}
+	logsDir := filepath.Join(runDir, "logs")
+	if entries, err := os.ReadDir(logsDir); err == nil {
+		for _, e := range entries {
+			if e.Type().IsRegular() && isLogName(strings.TrimSuffix(e.Name(), CompressedLogExt)) {
+				candidates = append(candidates, filepath.Join(logsDir, e.Name()))
+			}
+		}
+	}
+
+	var plaintext []string
+	for _, path := range candidates {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() == 0 || crypt.IsEncryptedFile(path) {
+			continue
+		}
+		plaintext = append(plaintext, path)
+	}
+	sort.Strings(plaintext)
+	return plaintext
+}
+
+// SealRunFiles encrypts the PlaintextRunFiles of runDir in place when c
+// enables encryption at rest (see crypt.Crypt.Seal). Returns how many files
+// it encrypted; it stops at the first failure.
+func SealRunFiles(runDir string, c *crypt.Crypt) (int, error) {
+	if !c.Enabled() {
+		return 0, nil
+	}
+	sealed := 0
+	for _, path := range PlaintextRunFiles(runDir) {
+		ok, err := c.Seal(path)
+		if err != nil {
+			return sealed, err
+		}
+		if ok {
+			sealed++
+		}
+	}
+	return sealed, nil
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// fakeAge returns a Crypt enabling encryption at rest with an age script on
+// PATH that "encrypts" to base64 behind the age header.
+// (testkit.FakeAge can't be used here: testkit imports store.)
+func fakeAge(t *testing.T) *crypt.Crypt {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$1\" in\n-e) echo \"" + crypt.Header + "\"; base64 ;;\n-d) tail -n +2 | base64 -d ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(dir, "age"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	identity := filepath.Join(dir, "age.key")
+	if err := os.WriteFile(identity, []byte("AGE-SECRET-KEY-1FAKE\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return crypt.New(exec.NewRealRunner(), crypt.Config{Enabled: true, Identity: identity})
+}
+
+func TestSealRunFiles(t *testing.T) {
+	c := fakeAge(t)
+	dataDir := t.TempDir()
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	st := NewStore(fs.NewRealFS(), dataDir, func() time.Time { return now })
+	st.Crypt = c
+	repoID, runID := "abc123", "20260110120000-a3f2"
+	if _, err := st.EnsureRunDir(repoID, runID); err != nil {
+		t.Fatal(err)
+	}
+	meta := NewRunMeta(runID, repoID, "fix bug", "claude", "claude", "main", "agency/fix-bug-a3f2", "/tmp/wt", now)
+	if err := st.WriteInitialMeta(repoID, runID, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	// meta.json is encrypted as it is written
+	runDir := st.RunDir(repoID, runID)
+	if !crypt.IsEncryptedFile(filepath.Join(runDir, "meta.json")) {
+		t.Fatal("meta.json was written in plaintext")
+	}
+	got, err := st.ReadMeta(repoID, runID)
+	if err != nil || got.Title != "fix bug" {
+		t.Fatalf("ReadMeta() = %+v, %v", got, err)
+	}
+	records, err := ScanAllRuns(dataDir, c)
+	if err != nil || len(records) != 1 || records[0].Broken || !records[0].Sealed || records[0].Meta.Title != "fix bug" {
+		t.Fatalf("ScanAllRuns() = %+v, %v; want one sealed, readable run", records, err)
+	}
+
+	// Logs and the transcript are sealed afterwards
+	logsDir := filepath.Join(runDir, "logs")
+	for name, content := range map[string]string{
+		filepath.Join(logsDir, "setup.log"):       "installing\n",
+		filepath.Join(logsDir, "empty.log"):       "",
+		filepath.Join(runDir, TranscriptFileName): "$ make test\n",
+	} {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := PlaintextRunFiles(runDir); len(got) != 2 {
+		t.Fatalf("PlaintextRunFiles() = %v, want setup.log and transcript.txt", got)
+	}
+	if n, err := SealRunFiles(runDir, c); n != 2 || err != nil {
+		t.Fatalf("SealRunFiles() = %d, %v; want 2", n, err)
+	}
+	if got := PlaintextRunFiles(runDir); len(got) != 0 {
+		t.Errorf("PlaintextRunFiles() after sealing = %v", got)
+	}
+
+	logs, err := ListLogs(logsDir)
+	if err != nil || len(logs) != 2 || logs[1].Name != "setup.log" || !logs[1].Sealed {
+		t.Fatalf("ListLogs() = %+v, %v; want setup.log sealed", logs, err)
+	}
+	r, err := OpenLog(filepath.Join(logsDir, "setup.log"), c)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	defer r.Close()
+	if data, err := io.ReadAll(r); err != nil || string(data) != "installing\n" {
+		t.Errorf("OpenLog() read %q, %v", data, err)
+	}
+
+	// Sealed logs are not compressed
+	if before, _, _ := CompressLogs(logsDir); before != 0 {
+		t.Errorf("CompressLogs() compressed %d bytes of sealed logs, want 0", before)
+	}
+}
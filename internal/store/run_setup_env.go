@@ -0,0 +1,165 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// SetupEnv is the environment a run's setup script ran with, captured at setup
+// time in setup_env.json so a setup failure on one machine can be compared
+// against a success on another. The run's other scripts (verify, hooks) are
+// captured the same way under Scripts, and agency doctor records one in
+// repo.json to explain "works in my shell, fails under agency".
+type SetupEnv struct {
+	SchemaVersion string `json:"schema_version"`
+
+	// CapturedAt is when setup started, in RFC3339 UTC format.
+	CapturedAt string `json:"captured_at"`
+
+	Hostname string `json:"hostname,omitempty"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+
+	// Env holds the AGENCY_* variables (and CI) the script sees, set by
+	// agency or inherited, plus the inherited PATH and SHELL.
+	Env map[string]string `json:"env"`
+
+	// ScriptPath is the PATH inside the script, i.e. after `sh -l` read the
+	// login files ("" if it could not be determined).
+	ScriptPath string `json:"script_path,omitempty"`
+
+	// Tools maps a tool name to its version line ("" if it could not be run).
+	Tools map[string]string `json:"tools"`
+
+	// ShellVersion is the --version line of the sh in Tools (empty for
+	// shells without one, such as dash).
+	ShellVersion string `json:"shell_version,omitempty"`
+
+	// CredentialMode is the github.credentials mode of the runner session
+	// ("inherit", "gh" or "app"; empty in captures that predate it).
+	CredentialMode string `json:"credential_mode,omitempty"`
+
+	// Scripts holds the captures of the run's other scripts, keyed by script
+	// ("verify", "hook_<hook point>"; the latest run of each).
+	Scripts map[string]SetupEnv `json:"scripts,omitempty"`
+}
+
+// SetupEnvDiff is one key whose value differs between two SetupEnvs.
+// Keys are "os", "arch", "hostname", "credential_mode", "script_path",
+// "shell_version", "env.<NAME>", or "tools.<name>".
+type SetupEnvDiff struct {
+	Key string
+	A   string
+	B   string
+
+	// MissingA/MissingB report that the key is absent (vs. empty) on that side.
+	MissingA bool
+	MissingB bool
+}
+
+// SetupEnvScript is the script WriteSetupEnv records as the run's setup capture.
+const SetupEnvScript = "setup"
+
+// WriteSetupEnv records env as the capture of script in setup_env.json,
+// atomically: SetupEnvScript replaces the setup capture, any other script
+// its entry in Scripts. The other scripts' entries are kept.
+// Returns E_PERSIST_FAILED on write errors.
+func (s *Store) WriteSetupEnv(repoID, runID, script string, env SetupEnv) error {
+	file, err := s.ReadSetupEnv(repoID, runID)
+	if err != nil || file == nil {
+		// A corrupt file is replaced rather than blocking the script
+		file = &SetupEnv{}
+	}
+	scripts := file.Scripts
+	env.Scripts = nil
+	if script == SetupEnvScript {
+		*file = env
+	} else {
+		if scripts == nil {
+			scripts = map[string]SetupEnv{}
+		}
+		scripts[script] = env
+		if file.SchemaVersion == "" {
+			file.SchemaVersion = env.SchemaVersion
+		}
+	}
+	file.Scripts = scripts
+
+	path := s.RunSetupEnvPath(repoID, runID)
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return errors.Wrap(errors.EInternal, "failed to encode setup_env.json", err)
+	}
+	if err := fs.WriteFileAtomic(s.FS, path, append(data, '\n'), 0o644); err != nil {
+		return errors.WrapWithDetails(
+			errors.EPersistFailed,
+			"failed to write setup_env.json",
+			err,
+			map[string]string{"setup_env_path": path},
+		)
+	}
+	return nil
+}
+
+// ReadSetupEnv reads a run's setup_env.json.
+// Returns nil (no error) if the run has no capture (e.g. setup predates it).
+// CapturedAt is empty if only other scripts were captured so far.
+// Returns E_STORE_CORRUPT if the file is unreadable or invalid.
+func (s *Store) ReadSetupEnv(repoID, runID string) (*SetupEnv, error) {
+	path := s.RunSetupEnvPath(repoID, runID)
+	data, err := s.FS.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WrapWithDetails(errors.EStoreCorrupt, "failed to read setup_env.json", err,
+			map[string]string{"setup_env_path": path})
+	}
+	var env SetupEnv
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, errors.WrapWithDetails(errors.EStoreCorrupt, "invalid setup_env.json", err,
+			map[string]string{"setup_env_path": path})
+	}
+	return &env, nil
+}
+
+// DiffSetupEnv returns the keys whose values differ between a and b, sorted by key.
+func DiffSetupEnv(a, b *SetupEnv) []SetupEnvDiff {
+	var diffs []SetupEnvDiff
+	scalar := func(key, va, vb string) {
+		if va != vb {
+			diffs = append(diffs, SetupEnvDiff{Key: key, A: va, B: vb})
+		}
+	}
+	scalar("arch", a.Arch, b.Arch)
+	scalar("credential_mode", a.CredentialMode, b.CredentialMode)
+	scalar("hostname", a.Hostname, b.Hostname)
+	scalar("os", a.OS, b.OS)
+	scalar("script_path", a.ScriptPath, b.ScriptPath)
+	scalar("shell_version", a.ShellVersion, b.ShellVersion)
+	diffs = append(diffs, diffMaps("env.", a.Env, b.Env)...)
+	diffs = append(diffs, diffMaps("tools.", a.Tools, b.Tools)...)
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Key < diffs[j].Key })
+	return diffs
+}
+
+func diffMaps(prefix string, a, b map[string]string) []SetupEnvDiff {
+	var diffs []SetupEnvDiff
+	for k, va := range a {
+		vb, ok := b[k]
+		if !ok || va != vb {
+			diffs = append(diffs, SetupEnvDiff{Key: prefix + k, A: va, B: vb, MissingB: !ok})
+		}
+	}
+	for k, vb := range b {
+		if _, ok := a[k]; !ok {
+			diffs = append(diffs, SetupEnvDiff{Key: prefix + k, B: vb, MissingA: true})
+		}
+	}
+	return diffs
+}
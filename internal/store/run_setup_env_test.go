@@ -0,0 +1,126 @@
+package store
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestSetupEnv_WriteRead(t *testing.T) {
+	dataDir := t.TempDir()
+	st := NewStore(fs.NewRealFS(), dataDir, time.Now)
+	repoID, runID := "abc123", "20260110120000-a3f2"
+
+	got, err := st.ReadSetupEnv(repoID, runID)
+	if err != nil || got != nil {
+		t.Fatalf("ReadSetupEnv() before capture = %+v, %v; want nil, nil", got, err)
+	}
+
+	if err := fs.NewRealFS().MkdirAll(st.RunDir(repoID, runID), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	want := SetupEnv{
+		SchemaVersion: "1.0",
+		CapturedAt:    "2026-01-10T12:00:00Z",
+		OS:            "linux",
+		Arch:          "amd64",
+		Env:           map[string]string{"AGENCY_RUN_ID": runID},
+		Tools:         map[string]string{"git": "git version 2.43.0"},
+	}
+	if err := st.WriteSetupEnv(repoID, runID, SetupEnvScript, want); err != nil {
+		t.Fatalf("WriteSetupEnv() error = %v", err)
+	}
+	got, err = st.ReadSetupEnv(repoID, runID)
+	if err != nil {
+		t.Fatalf("ReadSetupEnv() error = %v", err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("ReadSetupEnv() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestSetupEnv_WriteOtherScripts(t *testing.T) {
+	st := NewStore(fs.NewRealFS(), t.TempDir(), time.Now)
+	repoID, runID := "abc123", "20260110120000-a3f2"
+	if err := fs.NewRealFS().MkdirAll(st.RunDir(repoID, runID), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	// A hook that fires before setup leaves the setup capture empty
+	hook := SetupEnv{SchemaVersion: "1.0", CapturedAt: "2026-01-10T12:00:00Z", ScriptPath: "/bin"}
+	if err := st.WriteSetupEnv(repoID, runID, "hook_pre_run_setup", hook); err != nil {
+		t.Fatalf("WriteSetupEnv(hook) error = %v", err)
+	}
+	got, err := st.ReadSetupEnv(repoID, runID)
+	if err != nil || got.CapturedAt != "" || got.Scripts["hook_pre_run_setup"].ScriptPath != "/bin" {
+		t.Fatalf("ReadSetupEnv() = %+v, %v; want only the hook", got, err)
+	}
+
+	// Setup replaces its own capture and keeps the other scripts'
+	setup := SetupEnv{SchemaVersion: "1.0", CapturedAt: "2026-01-10T12:01:00Z", ScriptPath: "/usr/bin:/bin"}
+	if err := st.WriteSetupEnv(repoID, runID, SetupEnvScript, setup); err != nil {
+		t.Fatalf("WriteSetupEnv(setup) error = %v", err)
+	}
+	if err := st.WriteSetupEnv(repoID, runID, "verify", SetupEnv{CapturedAt: "2026-01-10T12:05:00Z"}); err != nil {
+		t.Fatalf("WriteSetupEnv(verify) error = %v", err)
+	}
+	got, err = st.ReadSetupEnv(repoID, runID)
+	if err != nil {
+		t.Fatalf("ReadSetupEnv() error = %v", err)
+	}
+	if got.ScriptPath != setup.ScriptPath || len(got.Scripts) != 2 || got.Scripts["hook_pre_run_setup"].ScriptPath != "/bin" {
+		t.Errorf("setup_env.json = %+v, want setup plus hook and verify", got)
+	}
+
+	// A corrupt file is reported on read and replaced on the next write
+	if err := os.WriteFile(st.RunSetupEnvPath(repoID, runID), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.ReadSetupEnv(repoID, runID); errors.GetCode(err) != errors.EStoreCorrupt {
+		t.Errorf("corrupt setup_env.json code = %q, want %q", errors.GetCode(err), errors.EStoreCorrupt)
+	}
+	if err := st.WriteSetupEnv(repoID, runID, SetupEnvScript, setup); err != nil {
+		t.Fatalf("WriteSetupEnv() over corrupt file error = %v", err)
+	}
+	if got, err := st.ReadSetupEnv(repoID, runID); err != nil || got.CapturedAt != setup.CapturedAt || len(got.Scripts) != 0 {
+		t.Errorf("ReadSetupEnv() = %+v, %v; want only setup", got, err)
+	}
+}
+
+func TestDiffSetupEnv(t *testing.T) {
+	a := &SetupEnv{
+		OS:    "linux",
+		Arch:  "amd64",
+		Env:   map[string]string{"PATH": "/usr/bin", "CI": "1", "AGENCY_RUNNER": "claude"},
+		Tools: map[string]string{"git": "git version 2.43.0", "gh": ""},
+	}
+	b := &SetupEnv{
+		OS:    "darwin",
+		Arch:  "amd64",
+		Env:   map[string]string{"PATH": "/opt/homebrew/bin:/usr/bin", "CI": "1"},
+		Tools: map[string]string{"git": "git version 2.39.3", "gh": "", "tmux": "tmux 3.4"},
+	}
+
+	var keys []string
+	for _, d := range DiffSetupEnv(a, b) {
+		keys = append(keys, d.Key)
+		if d.Key == "env.AGENCY_RUNNER" && !d.MissingB {
+			t.Errorf("env.AGENCY_RUNNER should be missing in b: %+v", d)
+		}
+		if d.Key == "tools.tmux" && !d.MissingA {
+			t.Errorf("tools.tmux should be missing in a: %+v", d)
+		}
+	}
+	want := []string{"env.AGENCY_RUNNER", "env.PATH", "os", "tools.git", "tools.tmux"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("diff keys = %v, want %v", keys, want)
+	}
+
+	if diffs := DiffSetupEnv(a, a); len(diffs) != 0 {
+		t.Errorf("DiffSetupEnv(a, a) = %+v, want none", diffs)
+	}
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// VerifyAttempt is one recorded verify attempt of a run.
+// Attempts are persisted one JSON object per line in verify.jsonl, so a run
+// keeps its whole verify history rather than only the latest outcome.
+type VerifyAttempt struct {
+	// Timestamp is when the attempt finished, in RFC3339 UTC format.
+	Timestamp string `json:"timestamp"`
+
+	// OK is true if the verification passed.
+	OK bool `json:"ok"`
+
+	// DurationMs is how long the attempt took, in milliseconds.
+	DurationMs int64 `json:"duration_ms"`
+
+	// Summary is a one-line summary of the outcome (may be empty).
+	Summary string `json:"summary,omitempty"`
+}
+
+// RunVerifyHistoryPath returns the path to a run's verify.jsonl.
+// Format: ${AGENCY_DATA_DIR}/repos/<repo_id>/runs/<run_id>/verify.jsonl
+func (s *Store) RunVerifyHistoryPath(repoID, runID string) string {
+	return filepath.Join(s.RunDir(repoID, runID), "verify.jsonl")
+}
+
+// AppendVerifyAttempt appends an attempt to the run's verify.jsonl and sets
+// last_verify_at in meta.json to its timestamp. An empty Timestamp is set to
+// the store's current time. The run directory must already exist.
+// Returns E_PERSIST_FAILED on read or write errors of verify.jsonl, and the
+// UpdateMeta errors for meta.json.
+func (s *Store) AppendVerifyAttempt(repoID, runID string, attempt VerifyAttempt) (VerifyAttempt, error) {
+	if attempt.Timestamp == "" {
+		attempt.Timestamp = s.Now().UTC().Format(time.RFC3339)
+	}
+	// Keep each line a one-line summary
+	attempt.Summary = strings.Join(strings.Fields(attempt.Summary), " ")
+
+	historyPath := s.RunVerifyHistoryPath(repoID, runID)
+	existing, err := s.FS.ReadFile(historyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return VerifyAttempt{}, errors.WrapWithDetails(
+			errors.EPersistFailed,
+			"failed to read verify.jsonl",
+			err,
+			map[string]string{"verify_history_path": historyPath},
+		)
+	}
+
+	line, err := json.Marshal(attempt)
+	if err != nil {
+		return VerifyAttempt{}, errors.Wrap(errors.EInternal, "failed to encode verify attempt", err)
+	}
+
+	// Rewrite the whole file atomically; verify histories are small
+	var buf bytes.Buffer
+	buf.Write(existing)
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	buf.Write(line)
+	buf.WriteByte('\n')
+
+	if err := fs.WriteFileAtomic(s.FS, historyPath, buf.Bytes(), 0o644); err != nil {
+		return VerifyAttempt{}, errors.WrapWithDetails(
+			errors.EPersistFailed,
+			"failed to write verify.jsonl",
+			err,
+			map[string]string{"verify_history_path": historyPath},
+		)
+	}
+
+	if err := s.UpdateMeta(repoID, runID, func(m *RunMeta) {
+		m.LastVerifyAt = attempt.Timestamp
+	}); err != nil {
+		return VerifyAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+// ReadVerifyHistory reads a run's verify attempts, oldest first.
+// Returns nil (no error) if verify.jsonl does not exist.
+// Malformed lines are skipped so a single bad line does not hide the rest.
+func (s *Store) ReadVerifyHistory(repoID, runID string) ([]VerifyAttempt, error) {
+	historyPath := s.RunVerifyHistoryPath(repoID, runID)
+
+	data, err := s.FS.ReadFile(historyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WrapWithDetails(
+			errors.EStoreCorrupt,
+			"failed to read verify.jsonl",
+			err,
+			map[string]string{"verify_history_path": historyPath},
+		)
+	}
+
+	var history []VerifyAttempt
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var attempt VerifyAttempt
+		if err := json.Unmarshal([]byte(line), &attempt); err != nil || attempt.Timestamp == "" {
+			continue
+		}
+		history = append(history, attempt)
+	}
+
+	return history, nil
+}
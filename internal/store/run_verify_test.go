@@ -0,0 +1,82 @@
+package store
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// TestAppendVerifyAttempt_Roundtrip verifies attempts are appended in order,
+// read back, and update last_verify_at.
+func TestAppendVerifyAttempt_Roundtrip(t *testing.T) {
+	dataDir := t.TempDir()
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	s := NewStore(fs.NewRealFS(), dataDir, func() time.Time { return now })
+
+	if _, err := s.EnsureRunDir("repo123", "run456"); err != nil {
+		t.Fatalf("EnsureRunDir() error = %v", err)
+	}
+	if err := s.WriteInitialMeta("repo123", "run456", NewRunMeta("run456", "repo123", "t", "claude", "claude", "main", "agency/t-run4", "/tmp/wt", now)); err != nil {
+		t.Fatalf("WriteInitialMeta() error = %v", err)
+	}
+
+	first, err := s.AppendVerifyAttempt("repo123", "run456", VerifyAttempt{OK: false, DurationMs: 1200, Summary: "2 tests\nfailed"})
+	if err != nil {
+		t.Fatalf("AppendVerifyAttempt() error = %v", err)
+	}
+	if first.Timestamp != "2026-01-10T12:00:00Z" || first.Summary != "2 tests failed" {
+		t.Errorf("first = %+v, want the current time and a one-line summary", first)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := s.AppendVerifyAttempt("repo123", "run456", VerifyAttempt{OK: true, DurationMs: 900}); err != nil {
+		t.Fatalf("AppendVerifyAttempt() error = %v", err)
+	}
+
+	history, err := s.ReadVerifyHistory("repo123", "run456")
+	if err != nil {
+		t.Fatalf("ReadVerifyHistory() error = %v", err)
+	}
+	if len(history) != 2 || history[0].OK || !history[1].OK || history[1].Timestamp != "2026-01-10T13:00:00Z" {
+		t.Fatalf("history = %+v", history)
+	}
+
+	meta, err := s.ReadMeta("repo123", "run456")
+	if err != nil {
+		t.Fatalf("ReadMeta() error = %v", err)
+	}
+	if meta.LastVerifyAt != "2026-01-10T13:00:00Z" {
+		t.Errorf("LastVerifyAt = %q, want the last attempt", meta.LastVerifyAt)
+	}
+}
+
+// TestReadVerifyHistory_SkipsMalformedLines verifies bad lines do not hide the rest.
+func TestReadVerifyHistory_SkipsMalformedLines(t *testing.T) {
+	s := NewStore(fs.NewRealFS(), t.TempDir(), nil)
+
+	history, err := s.ReadVerifyHistory("repo123", "run456")
+	if err != nil || history != nil {
+		t.Fatalf("ReadVerifyHistory() = %v, %v; want nil, nil without verify.jsonl", history, err)
+	}
+
+	if _, err := s.EnsureRunDir("repo123", "run456"); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"timestamp":"2026-01-10T12:00:00Z","ok":true,"duration_ms":5}
+not json
+{"ok":false}
+{"timestamp":"2026-01-10T13:00:00Z","ok":false,"duration_ms":7,"summary":"lint"}
+`
+	if err := os.WriteFile(s.RunVerifyHistoryPath("repo123", "run456"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	history, err = s.ReadVerifyHistory("repo123", "run456")
+	if err != nil {
+		t.Fatalf("ReadVerifyHistory() error = %v", err)
+	}
+	if len(history) != 2 || history[1].Summary != "lint" {
+		t.Errorf("history = %+v, want the 2 valid attempts", history)
+	}
+}
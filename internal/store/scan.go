@@ -4,9 +4,14 @@ package store
 
 import (
 	"encoding/json"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
+
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+	"github.com/NielsdaWheelz/agency/internal/profile"
 )
 
 // RepoInfo holds minimal repo identity information for joining runs to repos.
@@ -32,6 +37,10 @@ type RunRecord struct {
 	// When true, Meta is nil but RepoID/RunID are still populated from dir names.
 	Broken bool
 
+	// Sealed indicates meta.json is encrypted at rest (see crypt). A sealed
+	// run is Broken if it could not be decrypted, e.g. without the key.
+	Sealed bool
+
 	// Meta is the parsed meta.json. Nil if Broken==true.
 	Meta *RunMeta
 
@@ -94,33 +103,62 @@ func (c *repoJoinCache) get(repoID string) *RepoInfo {
 	return info
 }
 
+// ScanWarning describes a directory that could not be read while scanning.
+// Runs under it are missing from the scan result.
+type ScanWarning struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
 // ScanAllRuns discovers runs across all repos by scanning the filesystem.
 // Returns records sorted by RepoID asc, then RunID asc (stable order).
 // Missing directories result in empty slice (not error).
 // Corrupt meta.json results in a RunRecord with Broken=true.
-func ScanAllRuns(dataDir string) ([]RunRecord, error) {
+// Unreadable repo directories are skipped; only an unreadable repos/ dir
+// is an error. Use ScanAllRunsWithWarnings to learn what was skipped.
+// meta.json encrypted at rest is decrypted with c; without c (nil) such
+// runs are Broken.
+func ScanAllRuns(dataDir string, c *crypt.Crypt) ([]RunRecord, error) {
+	records, _, err := scanAllRuns(dataDir, c)
+	return records, err
+}
+
+// ScanAllRunsWithWarnings is ScanAllRuns that never fails: every directory
+// that cannot be read, including repos/ itself, is skipped and reported.
+func ScanAllRunsWithWarnings(dataDir string, c *crypt.Crypt) ([]RunRecord, []ScanWarning) {
+	records, warnings, err := scanAllRuns(dataDir, c)
+	if err != nil {
+		warnings = append(warnings, scanWarning(filepath.Join(dataDir, "repos"), err))
+	}
+	return records, warnings
+}
+
+func scanAllRuns(dataDir string, c *crypt.Crypt) ([]RunRecord, []ScanWarning, error) {
+	defer profile.Track(profile.KindScan, "scan all runs")()
 	reposDir := filepath.Join(dataDir, "repos")
 
 	// List repo directories
 	entries, err := os.ReadDir(reposDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return nil, nil, nil
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	cache := newRepoJoinCache(dataDir)
 	var records []RunRecord
+	var warnings []ScanWarning
 
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
 		repoID := entry.Name()
-		repoRecords, err := scanRepoRuns(dataDir, repoID, cache)
+		repoRecords, err := scanRepoRuns(dataDir, repoID, cache, c)
 		if err != nil {
 			// Skip repos with errors (e.g., permission denied)
+			warnings = append(warnings, scanWarning(filepath.Join(reposDir, repoID, "runs"), err))
 			continue
 		}
 		records = append(records, repoRecords...)
@@ -134,16 +172,18 @@ func ScanAllRuns(dataDir string) ([]RunRecord, error) {
 		return records[i].RunID < records[j].RunID
 	})
 
-	return records, nil
+	return records, warnings, nil
 }
 
 // ScanRunsForRepo discovers runs for a single repo_id.
 // Returns records sorted by RunID asc (stable order).
 // Missing directories result in empty slice (not error).
 // Corrupt meta.json results in a RunRecord with Broken=true.
-func ScanRunsForRepo(dataDir, repoID string) ([]RunRecord, error) {
+// meta.json encrypted at rest is decrypted with c, as for ScanAllRuns.
+func ScanRunsForRepo(dataDir, repoID string, c *crypt.Crypt) ([]RunRecord, error) {
+	defer profile.Track(profile.KindScan, "scan repo runs")()
 	cache := newRepoJoinCache(dataDir)
-	records, err := scanRepoRuns(dataDir, repoID, cache)
+	records, err := scanRepoRuns(dataDir, repoID, cache, c)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -159,8 +199,28 @@ func ScanRunsForRepo(dataDir, repoID string) ([]RunRecord, error) {
 	return records, nil
 }
 
+// ScanRunsForRepoWithWarnings is ScanRunsForRepo that reports an unreadable
+// runs directory as a warning instead of failing.
+func ScanRunsForRepoWithWarnings(dataDir, repoID string, c *crypt.Crypt) ([]RunRecord, []ScanWarning) {
+	records, err := ScanRunsForRepo(dataDir, repoID, c)
+	if err != nil {
+		return nil, []ScanWarning{scanWarning(filepath.Join(dataDir, "repos", repoID, "runs"), err)}
+	}
+	return records, nil
+}
+
+// scanWarning builds the warning for a directory read failure. The message
+// drops the path, which the warning already carries.
+func scanWarning(path string, err error) ScanWarning {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return ScanWarning{Path: path, Message: err.Error()}
+}
+
 // scanRepoRuns scans runs for a single repo, using the provided cache.
-func scanRepoRuns(dataDir, repoID string, cache *repoJoinCache) ([]RunRecord, error) {
+func scanRepoRuns(dataDir, repoID string, cache *repoJoinCache, c *crypt.Crypt) ([]RunRecord, error) {
 	runsDir := filepath.Join(dataDir, "repos", repoID, "runs")
 
 	entries, err := os.ReadDir(runsDir)
@@ -191,6 +251,10 @@ func scanRepoRuns(dataDir, repoID string, cache *repoJoinCache) ([]RunRecord, er
 
 		// Try to read and parse meta.json
 		data, err := os.ReadFile(metaPath)
+		if err == nil && crypt.IsEncrypted(data) {
+			record.Sealed = true
+			data, err = c.Decrypt(data)
+		}
 		if err != nil {
 			// Missing or unreadable - mark as broken
 			record.Broken = true
@@ -21,7 +21,7 @@ func TestScanAllRuns_ValidAndCorruptMeta(t *testing.T) {
 	// Optionally create repo.json for r1 only
 	createRepoJSON(t, dataDir, "r1", "github:owner/repo1", "git@github.com:owner/repo1.git")
 
-	records, err := ScanAllRuns(dataDir)
+	records, err := ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v, want nil", err)
 	}
@@ -94,7 +94,7 @@ func TestScanAllRuns_MismatchedMetaIdentity(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	records, err := ScanAllRuns(dataDir)
+	records, err := ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v", err)
 	}
@@ -126,7 +126,7 @@ func TestScanAllRuns_EmptyDataDir(t *testing.T) {
 	dataDir := t.TempDir()
 	// Don't create any repos
 
-	records, err := ScanAllRuns(dataDir)
+	records, err := ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v, want nil", err)
 	}
@@ -140,7 +140,7 @@ func TestScanAllRuns_MissingReposDir(t *testing.T) {
 	dataDir := t.TempDir()
 	// dataDir exists but repos/ does not
 
-	records, err := ScanAllRuns(dataDir)
+	records, err := ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v, want nil", err)
 	}
@@ -160,7 +160,7 @@ func TestScanRunsForRepo_ScopesCorrectly(t *testing.T) {
 	createValidMeta(t, dataDir, "r3", "run4")
 
 	// Scan only r1
-	records, err := ScanRunsForRepo(dataDir, "r1")
+	records, err := ScanRunsForRepo(dataDir, "r1", nil)
 	if err != nil {
 		t.Fatalf("ScanRunsForRepo() error = %v", err)
 	}
@@ -181,7 +181,7 @@ func TestScanRunsForRepo_MissingRepo(t *testing.T) {
 	dataDir := t.TempDir()
 	createValidMeta(t, dataDir, "r1", "run1")
 
-	records, err := ScanRunsForRepo(dataDir, "nonexistent")
+	records, err := ScanRunsForRepo(dataDir, "nonexistent", nil)
 	if err != nil {
 		t.Fatalf("ScanRunsForRepo() error = %v, want nil", err)
 	}
@@ -201,7 +201,7 @@ func TestScanAllRuns_DeterministicOrdering(t *testing.T) {
 	createValidMeta(t, dataDir, "a-repo", "a-run")
 	createValidMeta(t, dataDir, "m-repo", "z-run")
 
-	records, err := ScanAllRuns(dataDir)
+	records, err := ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v", err)
 	}
@@ -235,7 +235,7 @@ func TestScanRunsForRepo_DeterministicOrdering(t *testing.T) {
 	createValidMeta(t, dataDir, "r1", "a-run")
 	createValidMeta(t, dataDir, "r1", "m-run")
 
-	records, err := ScanRunsForRepo(dataDir, "r1")
+	records, err := ScanRunsForRepo(dataDir, "r1", nil)
 	if err != nil {
 		t.Fatalf("ScanRunsForRepo() error = %v", err)
 	}
@@ -277,7 +277,7 @@ func TestScanAllRuns_BrokenMetaTypes(t *testing.T) {
 	// Valid for comparison
 	createValidMeta(t, dataDir, "r1", "valid-run")
 
-	records, err := ScanAllRuns(dataDir)
+	records, err := ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v", err)
 	}
@@ -301,6 +301,48 @@ func TestScanAllRuns_BrokenMetaTypes(t *testing.T) {
 	}
 }
 
+// TestScanAllRuns_UnreadableRepoDir verifies one unreadable repo dir is
+// skipped and reported rather than failing the scan.
+func TestScanAllRuns_UnreadableRepoDir(t *testing.T) {
+	dataDir := t.TempDir()
+	createValidMeta(t, dataDir, "r1", "run1")
+
+	// A runs path that is not a directory cannot be read, even as root
+	badRuns := filepath.Join(dataDir, "repos", "r2", "runs")
+	if err := os.MkdirAll(filepath.Dir(badRuns), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(badRuns, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, warnings := ScanAllRunsWithWarnings(dataDir, nil)
+	if len(records) != 1 || records[0].RepoID != "r1" {
+		t.Fatalf("records = %+v, want only r1/run1", records)
+	}
+	if len(warnings) != 1 || warnings[0].Path != badRuns || warnings[0].Message == "" {
+		t.Fatalf("warnings = %+v, want one for %s", warnings, badRuns)
+	}
+
+	if _, err := ScanAllRuns(dataDir, nil); err != nil {
+		t.Errorf("ScanAllRuns() error = %v, want nil", err)
+	}
+
+	records, warnings = ScanRunsForRepoWithWarnings(dataDir, "r2", nil)
+	if len(records) != 0 || len(warnings) != 1 || warnings[0].Path != badRuns {
+		t.Errorf("ScanRunsForRepoWithWarnings() = %+v, %+v; want no records, one warning", records, warnings)
+	}
+
+	// An unreadable repos/ dir is reported too
+	dataDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "repos"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, warnings = ScanAllRunsWithWarnings(dataDir, nil); len(warnings) != 1 {
+		t.Errorf("warnings = %+v, want one for repos/", warnings)
+	}
+}
+
 // TestScanAllRuns_RepoJoinBestEffort verifies repo.json join is best-effort.
 func TestScanAllRuns_RepoJoinBestEffort(t *testing.T) {
 	dataDir := t.TempDir()
@@ -316,7 +358,7 @@ func TestScanAllRuns_RepoJoinBestEffort(t *testing.T) {
 	// Repo without repo.json
 	createValidMeta(t, dataDir, "r3", "run3")
 
-	records, err := ScanAllRuns(dataDir)
+	records, err := ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v", err)
 	}
@@ -364,7 +406,7 @@ func TestScanAllRuns_RepoJoinCaching(t *testing.T) {
 	createValidMeta(t, dataDir, "r1", "run3")
 	createRepoJSON(t, dataDir, "r1", "github:owner/repo1", "")
 
-	records, err := ScanAllRuns(dataDir)
+	records, err := ScanAllRuns(dataDir, nil)
 	if err != nil {
 		t.Fatalf("ScanAllRuns() error = %v", err)
 	}
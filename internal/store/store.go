@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/NielsdaWheelz/agency/internal/crypt"
 	"github.com/NielsdaWheelz/agency/internal/fs"
 )
 
@@ -14,6 +15,7 @@ type Store struct {
 	FS      fs.FS            // filesystem interface for stubbing
 	DataDir string           // resolved AGENCY_DATA_DIR
 	Now     func() time.Time // injectable clock for deterministic tests
+	Crypt   *crypt.Crypt     // encryption at rest of run files (nil = off)
 }
 
 // NewStore creates a new Store with the given dependencies.
@@ -25,6 +27,12 @@ func NewStore(filesystem fs.FS, dataDir string, now func() time.Time) *Store {
 	}
 }
 
+// WithCrypt sets s.Crypt to c and returns s.
+func (s *Store) WithCrypt(c *crypt.Crypt) *Store {
+	s.Crypt = c
+	return s
+}
+
 // RepoIndexPath returns the path to repo_index.json.
 func (s *Store) RepoIndexPath() string {
 	return filepath.Join(s.DataDir, "repo_index.json")
@@ -63,3 +71,15 @@ func (s *Store) RunMetaPath(repoID, runID string) string {
 func (s *Store) RunLogsDir(repoID, runID string) string {
 	return filepath.Join(s.RunDir(repoID, runID), "logs")
 }
+
+// RunNotesPath returns the path to a run's notes.jsonl.
+// Format: ${AGENCY_DATA_DIR}/repos/<repo_id>/runs/<run_id>/notes.jsonl
+func (s *Store) RunNotesPath(repoID, runID string) string {
+	return filepath.Join(s.RunDir(repoID, runID), "notes.jsonl")
+}
+
+// RunSetupEnvPath returns the path to a run's setup_env.json.
+// Format: ${AGENCY_DATA_DIR}/repos/<repo_id>/runs/<run_id>/setup_env.json
+func (s *Store) RunSetupEnvPath(repoID, runID string) string {
+	return filepath.Join(s.RunDir(repoID, runID), "setup_env.json")
+}
@@ -537,3 +537,26 @@ func TestPathNormalization(t *testing.T) {
 		t.Errorf("path not normalized: got %q, want /path/to/repo", entry.Paths[0])
 	}
 }
+
+// TestCapabilitiesStale verifies the capability TTL check.
+func TestCapabilitiesStale(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		checkedAt string
+		ttl       time.Duration
+		want      bool
+	}{
+		{"never checked", "", 24 * time.Hour, true},
+		{"unparsable", "yesterday", 24 * time.Hour, true},
+		{"within ttl", "2026-01-10T00:00:00Z", 24 * time.Hour, false},
+		{"past ttl", "2026-01-09T11:00:00Z", 24 * time.Hour, true},
+		{"no ttl", "2025-01-01T00:00:00Z", 0, false},
+		{"no ttl, never checked", "", 0, true},
+	}
+	for _, tt := range tests {
+		if got := (Capabilities{CheckedAt: tt.checkedAt}).Stale(now, tt.ttl); got != tt.want {
+			t.Errorf("%s: Stale() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package testkit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/crypt"
+)
+
+// fakeAgeScript stands in for age: -e prints the age header and base64 of
+// stdin, -d reverses it.
+const fakeAgeScript = `#!/bin/sh
+case "$1" in
+--version) echo "v1.1.1" ;;
+-e) echo "` + crypt.Header + `"; base64 ;;
+-d) tail -n +2 | base64 -d ;;
+*) echo "age: unexpected arguments: $*" >&2; exit 1 ;;
+esac
+`
+
+// FakeAge puts a fake age command on PATH, writes an identity file, and
+// enables encryption at rest with it in the user config.json of
+// $AGENCY_CONFIG_DIR (replacing the file; a new temp dir if unset), so
+// commands load it. Returns the identity path.
+func FakeAge(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "age"), fakeAgeScript, 0o755)
+	identity := filepath.Join(dir, "age.key")
+	writeFile(t, identity, "AGE-SECRET-KEY-1FAKE\n", 0o600)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	configDir := os.Getenv("AGENCY_CONFIG_DIR")
+	if configDir == "" {
+		configDir = t.TempDir()
+		t.Setenv("AGENCY_CONFIG_DIR", configDir)
+	}
+	cfg, err := json.Marshal(map[string]any{
+		"version":    1,
+		"encryption": map[string]any{"enabled": true, "identity": identity},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(configDir, "config.json"), string(cfg), 0o644)
+	return identity
+}
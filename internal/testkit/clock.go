@@ -0,0 +1,50 @@
+package testkit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/core"
+)
+
+// Clock is a manually advanced clock with sequential run ids, for running
+// commands deterministically. Safe for concurrent use.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+	seq int
+}
+
+// NewClock returns a Clock stopped at now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// NewRunID returns "<yyyymmddhhmmss>-<seq>" for now, with a 4-digit
+// sequence number counting from 0001 instead of a random suffix.
+func (c *Clock) NewRunID(now time.Time) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	return fmt.Sprintf("%s-%04d", now.UTC().Format("20060102150405"), c.seq), nil
+}
+
+// Core returns the clock as a core.Clock.
+func (c *Clock) Core() core.Clock {
+	return core.Clock{Now: c.Now, NewRunID: c.NewRunID}
+}
@@ -0,0 +1,179 @@
+package testkit
+
+import (
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// DefaultAgencyJSON is a minimal valid agency.json using the default script paths.
+const DefaultAgencyJSON = `{
+  "version": 1,
+  "defaults": {
+    "parent_branch": "main",
+    "runner": "claude"
+  },
+  "scripts": {
+    "setup": "scripts/agency_setup.sh",
+    "verify": "scripts/agency_verify.sh",
+    "archive": "scripts/agency_archive.sh"
+  },
+  "runners": {
+    "claude": "claude",
+    "codex": "codex"
+  }
+}
+`
+
+// DefaultScript is the body of every stub script NewRepo writes by default.
+const DefaultScript = "#!/usr/bin/env bash\nexit 0\n"
+
+// RepoOpts configures NewRepo.
+type RepoOpts struct {
+	// AgencyJSON is written to agency.json. Empty means DefaultAgencyJSON;
+	// use NoAgencyJSON to skip the file.
+	AgencyJSON   string
+	NoAgencyJSON bool
+
+	// Scripts maps repo-relative paths to executable script bodies.
+	// Nil means the three default scripts with DefaultScript bodies.
+	Scripts map[string]string
+
+	// Files maps repo-relative paths to extra file contents.
+	Files map[string]string
+
+	// Git runs a real `git init` and commits everything on branch main.
+	// The test is skipped if git is not on PATH. Without Git only an empty
+	// .git directory is created, which is enough for FakeRunner-based tests.
+	Git bool
+	// Origin sets remote.origin.url (requires Git).
+	Origin string
+}
+
+// NewRepo builds a temporary repo and returns its root. It is removed when
+// the test ends.
+func NewRepo(t testing.TB, opts RepoOpts) string {
+	t.Helper()
+	root := t.TempDir()
+
+	if !opts.NoAgencyJSON {
+		content := opts.AgencyJSON
+		if content == "" {
+			content = DefaultAgencyJSON
+		}
+		writeFile(t, filepath.Join(root, "agency.json"), content, 0o644)
+	}
+
+	scripts := opts.Scripts
+	if scripts == nil {
+		scripts = map[string]string{
+			"scripts/agency_setup.sh":   DefaultScript,
+			"scripts/agency_verify.sh":  DefaultScript,
+			"scripts/agency_archive.sh": DefaultScript,
+		}
+	}
+	for rel, body := range scripts {
+		writeFile(t, filepath.Join(root, rel), body, 0o755)
+	}
+	for rel, body := range opts.Files {
+		writeFile(t, filepath.Join(root, rel), body, 0o644)
+	}
+
+	if !opts.Git {
+		if err := os.MkdirAll(filepath.Join(root, ".git"), 0o755); err != nil {
+			t.Fatalf("testkit: create .git: %v", err)
+		}
+		return root
+	}
+
+	if _, err := osexec.LookPath("git"); err != nil {
+		t.Skip("testkit: git not on PATH")
+	}
+	git(t, root, "init", "-q")
+	git(t, root, "checkout", "-q", "-b", "main")
+	git(t, root, "config", "user.email", "test@example.com")
+	git(t, root, "config", "user.name", "agency test")
+	git(t, root, "config", "commit.gpgsign", "false")
+	if opts.Origin != "" {
+		git(t, root, "remote", "add", "origin", opts.Origin)
+	}
+	git(t, root, "add", "-A")
+	git(t, root, "commit", "-q", "--allow-empty", "-m", "initial")
+	return root
+}
+
+// Git runs git in dir and fails the test on error. Returns trimmed stdout.
+func Git(t testing.TB, dir string, args ...string) string {
+	t.Helper()
+	return git(t, dir, args...)
+}
+
+func git(t testing.TB, dir string, args ...string) string {
+	t.Helper()
+	cmd := osexec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("testkit: git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// WriteFile writes content to path (mode 0644), creating its parent dirs,
+// and fails the test on error.
+func WriteFile(t testing.TB, path, content string) {
+	t.Helper()
+	writeFile(t, path, content, 0o644)
+}
+
+func writeFile(t testing.TB, path, content string, perm os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("testkit: mkdir %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatalf("testkit: write %s: %v", path, err)
+	}
+}
+
+// DataDir creates a temporary data dir and points AGENCY_DATA_DIR at it for
+// the duration of the test.
+func DataDir(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("AGENCY_DATA_DIR", dir)
+	return dir
+}
+
+// NewRunMeta returns a valid meta for runID with a tmux session name of
+// agency_<runID> and a branch of agency/test-<runID>.
+func NewRunMeta(repoID, runID, worktreePath string, createdAt time.Time) *store.RunMeta {
+	meta := store.NewRunMeta(runID, repoID, "Test Run "+runID, "claude", "claude", "main", "agency/test-"+runID, worktreePath, createdAt)
+	meta.TmuxSessionName = "agency_" + runID
+	return meta
+}
+
+// WriteRun persists meta under dataDir (creating the run and logs dirs) and
+// returns the run dir. Mutate meta before calling to seed flags, PR data, etc.
+func WriteRun(t testing.TB, dataDir string, meta *store.RunMeta) string {
+	t.Helper()
+	createdAt, err := time.Parse(time.RFC3339, meta.CreatedAt)
+	if err != nil {
+		createdAt = time.Now()
+	}
+	st := store.NewStore(fs.NewRealFS(), dataDir, func() time.Time { return createdAt })
+	runDir, err := st.EnsureRunDir(meta.RepoID, meta.RunID)
+	if err != nil {
+		t.Fatalf("testkit: create run dir: %v", err)
+	}
+	if err := st.WriteInitialMeta(meta.RepoID, meta.RunID, meta); err != nil {
+		t.Fatalf("testkit: write meta: %v", err)
+	}
+	return runDir
+}
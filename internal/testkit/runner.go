@@ -0,0 +1,234 @@
+// Package testkit provides fakes and fixture builders for integration-style
+// tests of agency commands. It must only be imported from _test.go files.
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/exec"
+)
+
+// Call is a single recorded CommandRunner invocation.
+type Call struct {
+	Name string
+	Args []string
+	Dir  string
+	Env  map[string]string
+}
+
+// String renders the call as "name arg1 arg2".
+func (c Call) String() string {
+	return commandKey(c.Name, c.Args)
+}
+
+// Response is what the fake returns for a matched command.
+type Response struct {
+	Result exec.CmdResult
+	Err    error
+	// Delay is slept before responding; a canceled context ends the sleep
+	// early and the call returns ctx.Err().
+	Delay time.Duration
+}
+
+// Stub is a configurable response registered with FakeRunner.On or OnPrefix.
+// Its methods mutate the response in place and return the stub for chaining.
+type Stub struct {
+	resp Response
+}
+
+// Stdout sets the command's stdout.
+func (s *Stub) Stdout(out string) *Stub { s.resp.Result.Stdout = out; return s }
+
+// Stderr sets the command's stderr.
+func (s *Stub) Stderr(out string) *Stub { s.resp.Result.Stderr = out; return s }
+
+// Exit sets the command's exit code.
+func (s *Stub) Exit(code int) *Stub { s.resp.Result.ExitCode = code; return s }
+
+// Fail makes the call return err (an execution failure, not a non-zero exit).
+func (s *Stub) Fail(err error) *Stub { s.resp.Err = err; return s }
+
+// After delays the response by d.
+func (s *Stub) After(d time.Duration) *Stub { s.resp.Delay = d; return s }
+
+type prefixStub struct {
+	name  string
+	args  []string
+	queue []*Stub
+}
+
+// FakeRunner is a scriptable exec.CommandRunner that records every call.
+//
+// Exact matches (On) win over prefix matches (OnPrefix); among prefix
+// matches the longest registered prefix wins. Registering the same command
+// more than once queues responses: each call consumes the next one and the
+// last response repeats. Unmatched commands return Fallback, or an error
+// naming the command if Fallback is nil.
+//
+// FakeRunner is safe for concurrent use.
+type FakeRunner struct {
+	// Fallback is returned for commands with no matching stub.
+	Fallback *Response
+
+	mu       sync.Mutex
+	exact    map[string][]*Stub
+	prefixes []*prefixStub
+	calls    []Call
+}
+
+// NewFakeRunner returns a FakeRunner with no stubs.
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{exact: make(map[string][]*Stub)}
+}
+
+// On registers a response for the exact command name + args.
+// The default response is a successful exit with empty output.
+func (f *FakeRunner) On(name string, args ...string) *Stub {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := &Stub{}
+	key := commandKey(name, args)
+	f.exact[key] = append(f.exact[key], s)
+	return s
+}
+
+// OnPrefix registers a response for any invocation of name whose args start
+// with the given args.
+func (f *FakeRunner) OnPrefix(name string, args ...string) *Stub {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := &Stub{}
+	for _, p := range f.prefixes {
+		if p.name == name && equalArgs(p.args, args) {
+			p.queue = append(p.queue, s)
+			return s
+		}
+	}
+	f.prefixes = append(f.prefixes, &prefixStub{name: name, args: append([]string(nil), args...), queue: []*Stub{s}})
+	return s
+}
+
+// Run implements exec.CommandRunner.
+func (f *FakeRunner) Run(ctx context.Context, name string, args []string, opts exec.RunOpts) (exec.CmdResult, error) {
+	resp, ok := f.record(name, args, opts)
+	if !ok {
+		return exec.CmdResult{}, fmt.Errorf("testkit: unexpected command: %s", commandKey(name, args))
+	}
+	if resp.Delay > 0 {
+		timer := time.NewTimer(resp.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return exec.CmdResult{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return resp.Result, resp.Err
+}
+
+// record stores the call and resolves its response.
+func (f *FakeRunner) record(name string, args []string, opts exec.RunOpts) (Response, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := Call{Name: name, Args: append([]string(nil), args...), Dir: opts.Dir}
+	if len(opts.Env) > 0 {
+		call.Env = make(map[string]string, len(opts.Env))
+		for k, v := range opts.Env {
+			call.Env[k] = v
+		}
+	}
+	f.calls = append(f.calls, call)
+
+	if queue := f.exact[commandKey(name, args)]; len(queue) > 0 {
+		return f.consume(queue, func(q []*Stub) { f.exact[commandKey(name, args)] = q }), true
+	}
+
+	var best *prefixStub
+	for _, p := range f.prefixes {
+		if p.name != name || len(p.args) > len(args) || !equalArgs(p.args, args[:len(p.args)]) {
+			continue
+		}
+		if best == nil || len(p.args) > len(best.args) {
+			best = p
+		}
+	}
+	if best != nil {
+		return f.consume(best.queue, func(q []*Stub) { best.queue = q }), true
+	}
+
+	if f.Fallback != nil {
+		return *f.Fallback, true
+	}
+	return Response{}, false
+}
+
+// consume pops the head of a response queue, keeping the last one sticky.
+func (f *FakeRunner) consume(queue []*Stub, set func([]*Stub)) Response {
+	head := queue[0]
+	if len(queue) > 1 {
+		set(queue[1:])
+	}
+	return head.resp
+}
+
+// Calls returns all recorded calls in order.
+func (f *FakeRunner) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns recorded calls for the given binary name.
+func (f *FakeRunner) CallsTo(name string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []Call
+	for _, c := range f.calls {
+		if c.Name == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Called reports whether the exact command name + args was run.
+func (f *FakeRunner) Called(name string, args ...string) bool {
+	key := commandKey(name, args)
+	for _, c := range f.Calls() {
+		if c.String() == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset clears recorded calls; stubs are kept.
+func (f *FakeRunner) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+func commandKey(name string, args []string) string {
+	return strings.TrimSpace(name + " " + strings.Join(args, " "))
+}
+
+func equalArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Compile-time interface check.
+var _ exec.CommandRunner = (*FakeRunner)(nil)
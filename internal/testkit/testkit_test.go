@@ -0,0 +1,154 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+func TestFakeRunner_ExactPrefixAndFallback(t *testing.T) {
+	f := NewFakeRunner()
+	f.On("git", "status").Stdout("clean\n")
+	f.OnPrefix("git").Exit(2)
+	f.OnPrefix("git", "log").Exit(3)
+	ctx := context.Background()
+
+	if res, err := f.Run(ctx, "git", []string{"status"}, exec.RunOpts{}); err != nil || res.Stdout != "clean\n" {
+		t.Errorf("exact: got %+v, %v", res, err)
+	}
+	if res, _ := f.Run(ctx, "git", []string{"log", "-1"}, exec.RunOpts{}); res.ExitCode != 3 {
+		t.Errorf("longest prefix: exit = %d, want 3", res.ExitCode)
+	}
+	if res, _ := f.Run(ctx, "git", []string{"fetch"}, exec.RunOpts{}); res.ExitCode != 2 {
+		t.Errorf("short prefix: exit = %d, want 2", res.ExitCode)
+	}
+	if _, err := f.Run(ctx, "gh", []string{"pr", "view"}, exec.RunOpts{}); err == nil {
+		t.Error("unstubbed command without Fallback should error")
+	}
+
+	f.Fallback = &Response{Result: exec.CmdResult{ExitCode: 127}}
+	if res, err := f.Run(ctx, "gh", []string{"pr", "view"}, exec.RunOpts{}); err != nil || res.ExitCode != 127 {
+		t.Errorf("fallback: got %+v, %v", res, err)
+	}
+}
+
+func TestFakeRunner_QueuedResponsesAndRecording(t *testing.T) {
+	f := NewFakeRunner()
+	f.On("gh", "pr", "view").Exit(1)
+	f.On("gh", "pr", "view").Stdout("{}")
+	ctx := context.Background()
+
+	var exits []int
+	for i := 0; i < 3; i++ {
+		res, _ := f.Run(ctx, "gh", []string{"pr", "view"}, exec.RunOpts{Dir: "/wt", Env: map[string]string{"K": "v"}})
+		exits = append(exits, res.ExitCode)
+	}
+	if exits[0] != 1 || exits[1] != 0 || exits[2] != 0 {
+		t.Errorf("exits = %v, want [1 0 0] (last response repeats)", exits)
+	}
+
+	calls := f.CallsTo("gh")
+	if len(calls) != 3 || calls[0].Dir != "/wt" || calls[0].Env["K"] != "v" {
+		t.Errorf("recorded calls = %+v", calls)
+	}
+	if !f.Called("gh", "pr", "view") || f.Called("gh", "pr") {
+		t.Error("Called should match exact args only")
+	}
+	f.Reset()
+	if len(f.Calls()) != 0 {
+		t.Error("Reset should clear calls")
+	}
+}
+
+func TestFakeRunner_FailAndDelay(t *testing.T) {
+	f := NewFakeRunner()
+	boom := errors.New("exec: not found")
+	f.On("tmux", "-V").Fail(boom)
+	f.On("git", "fetch").After(time.Hour)
+
+	if _, err := f.Run(context.Background(), "tmux", []string{"-V"}, exec.RunOpts{}); !errors.Is(err, boom) {
+		t.Errorf("err = %v, want %v", err, boom)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := f.Run(ctx, "git", []string{"fetch"}, exec.RunOpts{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("delayed call err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestFakeRunner_TmuxSessions(t *testing.T) {
+	f := NewFakeRunner()
+	f.TmuxSessions("agency_a", "agency_b")
+	ctx := context.Background()
+
+	res, _ := f.Run(ctx, "tmux", []string{"list-sessions", "-F", "#{session_name}"}, exec.RunOpts{})
+	if res.Stdout != "agency_a\nagency_b\n" {
+		t.Errorf("list-sessions stdout = %q", res.Stdout)
+	}
+	if res, _ := f.Run(ctx, "tmux", []string{"has-session", "-t", "agency_a"}, exec.RunOpts{}); res.ExitCode != 0 {
+		t.Error("has-session for known session should succeed")
+	}
+	if res, _ := f.Run(ctx, "tmux", []string{"has-session", "-t", "agency_zzz"}, exec.RunOpts{}); res.ExitCode != 1 {
+		t.Error("has-session for unknown session should exit 1")
+	}
+}
+
+func TestNewRepo_Defaults(t *testing.T) {
+	root := NewRepo(t, RepoOpts{Files: map[string]string{"README.md": "hi"}})
+
+	for _, rel := range []string{".git", "agency.json", "README.md"} {
+		if _, err := os.Stat(filepath.Join(root, rel)); err != nil {
+			t.Errorf("%s missing: %v", rel, err)
+		}
+	}
+	info, err := os.Stat(filepath.Join(root, "scripts", "agency_setup.sh"))
+	if err != nil || info.Mode().Perm()&0o111 == 0 {
+		t.Errorf("setup script should be executable: %v", err)
+	}
+}
+
+func TestNewRepo_Git(t *testing.T) {
+	root := NewRepo(t, RepoOpts{Git: true, Origin: "git@github.com:o/r.git"})
+
+	if got := Git(t, root, "rev-parse", "--abbrev-ref", "HEAD"); got != "main\n" {
+		t.Errorf("branch = %q, want main", got)
+	}
+	if got := Git(t, root, "config", "--get", "remote.origin.url"); got != "git@github.com:o/r.git\n" {
+		t.Errorf("origin = %q", got)
+	}
+	if out := Git(t, root, "status", "--porcelain"); out != "" {
+		t.Errorf("repo should be clean, got %q", out)
+	}
+}
+
+func TestWriteRun(t *testing.T) {
+	dataDir := DataDir(t)
+	if os.Getenv("AGENCY_DATA_DIR") != dataDir {
+		t.Fatal("AGENCY_DATA_DIR not set")
+	}
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	meta := NewRunMeta("repo1", "20260110120000-a3f2", "/wt", created)
+	meta.Flags = &store.RunMetaFlags{NeedsAttention: true}
+
+	runDir := WriteRun(t, dataDir, meta)
+
+	st := store.NewStore(fs.NewRealFS(), dataDir, time.Now)
+	got, err := st.ReadMeta("repo1", "20260110120000-a3f2")
+	if err != nil {
+		t.Fatalf("ReadMeta: %v", err)
+	}
+	if got.TmuxSessionName != "agency_20260110120000-a3f2" || got.Flags == nil || !got.Flags.NeedsAttention {
+		t.Errorf("meta = %+v", got)
+	}
+	if runDir != st.RunDir("repo1", "20260110120000-a3f2") {
+		t.Errorf("runDir = %q", runDir)
+	}
+}
@@ -0,0 +1,63 @@
+package testkit
+
+// Canned tool responses for the git/tmux/gh invocations agency makes.
+
+// Default tool versions reported by AllToolsOK.
+const (
+	GitVersion  = "git version 2.40.0"
+	TmuxVersion = "tmux 3.3a"
+	GhVersion   = "gh version 2.40.0 (2024-01-15)"
+)
+
+// AllToolsOK stubs the prerequisite checks doctor and run perform: repo root
+// discovery, origin lookup, tool versions, and gh auth. An empty originURL
+// simulates a repo without an origin remote.
+func (f *FakeRunner) AllToolsOK(repoRoot, originURL string) {
+	f.On("git", "rev-parse", "--show-toplevel").Stdout(repoRoot + "\n")
+	if originURL == "" {
+		f.On("git", "config", "--get", "remote.origin.url").Exit(1)
+	} else {
+		f.On("git", "config", "--get", "remote.origin.url").Stdout(originURL + "\n")
+	}
+	f.On("git", "--version").Stdout(GitVersion + "\n")
+	f.On("tmux", "-V").Stdout(TmuxVersion + "\n")
+	f.On("tmux", "start-server", ";", "list-sessions")
+	f.On("gh", "--version").Stdout(GhVersion + "\n")
+	f.GhAuthenticated(true)
+}
+
+// GhAuthenticated stubs `gh auth status`.
+func (f *FakeRunner) GhAuthenticated(ok bool) {
+	if ok {
+		f.On("gh", "auth", "status").Stderr("Logged in to github.com\n")
+		return
+	}
+	f.On("gh", "auth", "status").Exit(1).Stderr("You are not logged into any GitHub hosts.\n")
+}
+
+// TmuxSessions simulates a tmux server with exactly the given sessions:
+// list-sessions prints them, has-session/kill-session succeed for them and
+// exit 1 for anything else.
+func (f *FakeRunner) TmuxSessions(names ...string) {
+	if len(names) == 0 {
+		f.TmuxNoServer()
+		return
+	}
+	out := ""
+	for _, n := range names {
+		out += n + "\n"
+		f.On("tmux", "has-session", "-t", n)
+		f.On("tmux", "kill-session", "-t", n)
+	}
+	f.On("tmux", "list-sessions", "-F", "#{session_name}").Stdout(out)
+	f.OnPrefix("tmux", "has-session").Exit(1).Stderr("can't find session\n")
+	f.OnPrefix("tmux", "kill-session").Exit(1).Stderr("can't find session\n")
+}
+
+// TmuxNoServer simulates no running tmux server.
+func (f *FakeRunner) TmuxNoServer() {
+	const noServer = "no server running on /tmp/tmux-1000/default\n"
+	f.On("tmux", "list-sessions", "-F", "#{session_name}").Exit(1).Stderr(noServer)
+	f.OnPrefix("tmux", "has-session").Exit(1).Stderr(noServer)
+	f.OnPrefix("tmux", "kill-session").Exit(1).Stderr(noServer)
+}
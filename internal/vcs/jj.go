@@ -0,0 +1,97 @@
+package vcs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/git"
+)
+
+// jjVCS is a jj (Jujutsu) repo. Only repos with a colocated git store (.git
+// next to .jj) are supported: worktrees are git worktrees of that store.
+type jjVCS struct {
+	cr        exec.CommandRunner
+	root      string // the directory holding .jj
+	colocated bool
+}
+
+func (jjVCS) Kind() Kind { return JJ }
+
+// RepoRoot returns the root of the colocated git store, which is the jj
+// workspace root, as git reports it, so a repo's identity (repo_id) is the
+// same whichever tool created the checkout.
+// Returns E_VCS_UNSUPPORTED if the repo has no colocated git store.
+func (j jjVCS) RepoRoot(ctx context.Context, dir string) (string, error) {
+	if !j.colocated {
+		return "", errors.WithHints(
+			errors.NewWithDetails(errors.EVCSUnsupported, "jj repo has no colocated git store; agency needs .git next to .jj",
+				map[string]string{"repo_root": j.root}),
+			"clone the repo with: jj git clone --colocate <url>",
+		)
+	}
+	root, err := git.GetRepoRoot(ctx, j.cr, dir)
+	return root.Path, err
+}
+
+// HasCommits reports whether any commit other than the root commit is an
+// ancestor of the working-copy commit's parent.
+func (j jjVCS) HasCommits(ctx context.Context, repoRoot string) (bool, error) {
+	out, err := j.run(ctx, repoRoot, "log", "--no-graph", "--limit", "1", "-r", "::@- ~ root()", "-T", `commit_id ++ "\n"`)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// IsClean reports whether the working-copy commit is free of conflicts.
+// jj records working-copy changes in the @ commit as it runs, so unlike git
+// there are no uncommitted changes a run could lose or confuse; a run
+// branches from its parent bookmark either way. Unresolved conflicts are
+// the state worth stopping for.
+func (j jjVCS) IsClean(ctx context.Context, repoRoot string) (bool, error) {
+	out, err := j.run(ctx, repoRoot, "log", "--no-graph", "-r", "@", "-T", `if(conflict, "conflict")`)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) == "", nil
+}
+
+// BranchExists reports whether a local bookmark named branch exists.
+func (j jjVCS) BranchExists(ctx context.Context, repoRoot, branch string) (bool, error) {
+	revset := "bookmarks(exact:" + revsetString(branch) + ")"
+	out, err := j.run(ctx, repoRoot, "log", "--no-graph", "--limit", "1", "-r", revset, "-T", `commit_id ++ "\n"`)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// PrepareWorktree exports jj's bookmarks to git branches, so that
+// `git worktree add` sees a parent bookmark moved since the last export.
+func (j jjVCS) PrepareWorktree(ctx context.Context, repoRoot string) error {
+	_, err := j.run(ctx, repoRoot, "git", "export")
+	return err
+}
+
+// run runs jj in repoRoot and returns its stdout.
+// Returns E_JJ_NOT_INSTALLED if jj cannot be run, and E_INTERNAL if it
+// exits non-zero.
+func (j jjVCS) run(ctx context.Context, repoRoot string, args ...string) (string, error) {
+	result, err := j.cr.Run(ctx, "jj", args, exec.RunOpts{Dir: repoRoot})
+	if err != nil {
+		return "", errors.Wrap(errors.EJjNotInstalled, "failed to run jj; jj repos need jj on PATH", err)
+	}
+	if result.ExitCode != 0 {
+		return "", errors.NewWithDetails(errors.EInternal, "jj "+args[0]+" failed: "+strings.TrimSpace(result.Stderr),
+			map[string]string{"command": "jj " + strings.Join(args, " "), "exit_code": strconv.Itoa(result.ExitCode)})
+	}
+	return result.Stdout, nil
+}
+
+// revsetString quotes s as a revset string literal.
+func revsetString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,106 @@
+// Package vcs abstracts the version control operations run creation needs
+// from a repo (root, cleanliness, branch existence, preparing a worktree),
+// so repos managed with jj (Jujutsu) on a colocated git store work like
+// plain git repos.
+package vcs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/git"
+)
+
+// Kind names a version control system.
+type Kind string
+
+// Supported kinds.
+const (
+	Git Kind = "git"
+	JJ  Kind = "jj"
+)
+
+// VCS is the version control a repo is managed with.
+//
+// Worktrees are always git worktrees of the repo's git store, so the rest of
+// agency (push, PRs, diffs) works unchanged inside them; PrepareWorktree
+// makes the repo's branches visible to git before one is created.
+type VCS interface {
+	// Kind returns the VCS kind.
+	Kind() Kind
+
+	// RepoRoot returns the absolute root of the repo containing dir.
+	// Returns E_NO_REPO if dir is not inside a repo.
+	RepoRoot(ctx context.Context, dir string) (string, error)
+
+	// HasCommits reports whether the repo has at least one commit.
+	HasCommits(ctx context.Context, repoRoot string) (bool, error)
+
+	// IsClean reports whether the repo's working copy is safe to start a run
+	// from (for git: no uncommitted changes).
+	IsClean(ctx context.Context, repoRoot string) (bool, error)
+
+	// BranchExists reports whether a local branch (jj: bookmark) exists.
+	BranchExists(ctx context.Context, repoRoot, branch string) (bool, error)
+
+	// PrepareWorktree makes the repo's branches visible to
+	// `git worktree add`.
+	PrepareWorktree(ctx context.Context, repoRoot string) error
+}
+
+// Detect returns the VCS of the repo containing dir: jj if the nearest
+// directory at or above dir holding a .git or .jj entry holds .jj, git
+// otherwise (including when dir is not inside a repo, so that git reports
+// it as usual).
+func Detect(cr exec.CommandRunner, dir string) VCS {
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		jjInfo, jjErr := os.Stat(filepath.Join(d, ".jj"))
+		if jjErr == nil && jjInfo.IsDir() {
+			_, gitErr := os.Stat(filepath.Join(d, ".git"))
+			return jjVCS{cr: cr, root: d, colocated: gitErr == nil}
+		}
+		if _, err := os.Lstat(filepath.Join(d, ".git")); err == nil {
+			return gitVCS{cr: cr}
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return gitVCS{cr: cr}
+		}
+	}
+}
+
+// ForKind returns the VCS implementation of kind for the repo at repoRoot
+// ("" is git, for state recorded before jj support).
+func ForKind(cr exec.CommandRunner, kind Kind, repoRoot string) VCS {
+	if kind == JJ {
+		return jjVCS{cr: cr, root: repoRoot, colocated: true}
+	}
+	return gitVCS{cr: cr}
+}
+
+// gitVCS is a plain git repo.
+type gitVCS struct {
+	cr exec.CommandRunner
+}
+
+func (gitVCS) Kind() Kind { return Git }
+
+func (g gitVCS) RepoRoot(ctx context.Context, dir string) (string, error) {
+	root, err := git.GetRepoRoot(ctx, g.cr, dir)
+	return root.Path, err
+}
+
+func (g gitVCS) HasCommits(ctx context.Context, repoRoot string) (bool, error) {
+	return git.HasCommits(ctx, g.cr, repoRoot)
+}
+
+func (g gitVCS) IsClean(ctx context.Context, repoRoot string) (bool, error) {
+	return git.IsClean(ctx, g.cr, repoRoot)
+}
+
+func (g gitVCS) BranchExists(ctx context.Context, repoRoot, branch string) (bool, error) {
+	return git.BranchExists(ctx, g.cr, repoRoot, branch)
+}
+
+func (gitVCS) PrepareWorktree(context.Context, string) error { return nil }
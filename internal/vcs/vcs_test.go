@@ -0,0 +1,97 @@
+package vcs
+
+import (
+	"context"
+	stderrors "errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+func TestDetect(t *testing.T) {
+	mkdirs := func(t *testing.T, root string, dirs ...string) {
+		t.Helper()
+		for _, d := range dirs {
+			if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		dirs      []string
+		from      string
+		want      Kind
+		colocated bool
+	}{
+		{"git", []string{".git", "src"}, "src", Git, false},
+		{"jj colocated", []string{".git", ".jj", "src/pkg"}, "src/pkg", JJ, true},
+		{"jj without git store", []string{".jj", "src"}, "src", JJ, false},
+		{"git repo nested in jj repo", []string{".git", ".jj", "vendor/lib/.git"}, "vendor/lib", Git, false},
+		{"jj repo nested in git repo", []string{".git", "sub/.jj", "sub/.git"}, "sub", JJ, true},
+		{"no repo", []string{"src"}, "src", Git, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			mkdirs(t, root, tt.dirs...)
+			v := Detect(testkit.NewFakeRunner(), filepath.Join(root, tt.from))
+			if v.Kind() != tt.want {
+				t.Fatalf("Kind() = %q, want %q", v.Kind(), tt.want)
+			}
+			if jj, ok := v.(jjVCS); ok && jj.colocated != tt.colocated {
+				t.Errorf("colocated = %v, want %v", jj.colocated, tt.colocated)
+			}
+		})
+	}
+}
+
+func TestJJ(t *testing.T) {
+	ctx := context.Background()
+	cr := testkit.NewFakeRunner()
+	j := ForKind(cr, JJ, "/repo")
+
+	cr.On("jj", "log", "--no-graph", "--limit", "1", "-r", `bookmarks(exact:"feat/\"q\"")`, "-T", `commit_id ++ "\n"`).Stdout("")
+	if exists, err := j.BranchExists(ctx, "/repo", `feat/"q"`); err != nil || exists {
+		t.Errorf("BranchExists() = %v, %v; want false", exists, err)
+	}
+
+	cr.On("jj", "log", "--no-graph", "--limit", "1", "-r", "::@- ~ root()", "-T", `commit_id ++ "\n"`).Stdout("")
+	if has, err := j.HasCommits(ctx, "/repo"); err != nil || has {
+		t.Errorf("HasCommits() = %v, %v; want false", has, err)
+	}
+
+	cr.On("jj", "git", "export")
+	if err := j.PrepareWorktree(ctx, "/repo"); err != nil {
+		t.Errorf("PrepareWorktree() error = %v", err)
+	}
+	if !cr.Called("jj", "git", "export") {
+		t.Error("PrepareWorktree did not run jj git export")
+	}
+
+	cr.On("jj", "log", "--no-graph", "-r", "@", "-T", `if(conflict, "conflict")`).Stderr("Error: no jj repo").Exit(1)
+	if _, err := j.IsClean(ctx, "/repo"); errors.GetCode(err) != errors.EInternal {
+		t.Errorf("IsClean() code = %q, want %q", errors.GetCode(err), errors.EInternal)
+	}
+
+	missing := testkit.NewFakeRunner()
+	missing.On("jj", "git", "export").Fail(stderrors.New(`exec: "jj": executable file not found in $PATH`))
+	if err := ForKind(missing, JJ, "/repo").PrepareWorktree(ctx, "/repo"); errors.GetCode(err) != errors.EJjNotInstalled {
+		t.Errorf("jj missing: code = %q, want %q", errors.GetCode(err), errors.EJjNotInstalled)
+	}
+}
+
+func TestJJ_RepoRootNotColocated(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".jj"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Detect(testkit.NewFakeRunner(), root).RepoRoot(context.Background(), root)
+	if errors.GetCode(err) != errors.EVCSUnsupported {
+		t.Errorf("code = %q, want %q", errors.GetCode(err), errors.EVCSUnsupported)
+	}
+}
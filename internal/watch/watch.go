@@ -0,0 +1,186 @@
+// Package watch reports file changes in a directory tree by polling: each
+// Poll walks the tree and compares it with the previous walk. Polling keeps
+// agency free of platform-specific notification APIs and works the same on
+// every filesystem, at the cost of missing changes that are undone between
+// two polls.
+package watch
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Change types.
+const (
+	Created  = "created"
+	Modified = "modified"
+	Deleted  = "deleted"
+)
+
+// Change is one file change seen between two polls.
+type Change struct {
+	// Path is relative to the watched root, with forward slashes.
+	Path string
+
+	// Type is Created, Modified, or Deleted.
+	Type string
+
+	// Time is when the poll that saw the change ran.
+	Time time.Time
+}
+
+// fileState is what a poll remembers about a file.
+type fileState struct {
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// Watcher tracks the files under Root. Directories are walked but not
+// reported; .git (a dir, or a file in linked worktrees) is never walked.
+type Watcher struct {
+	root    string
+	include []string
+	exclude []string
+	files   map[string]fileState
+}
+
+// New returns a Watcher for root. A file is reported if it matches any
+// include pattern (all files if there are none) and no exclude pattern;
+// see Match for the pattern syntax.
+func New(root string, include, exclude []string) *Watcher {
+	return &Watcher{root: root, include: include, exclude: exclude}
+}
+
+// Start records the current state of the tree without reporting it.
+func (w *Watcher) Start() error {
+	files, err := w.scan()
+	if err != nil {
+		return err
+	}
+	w.files = files
+	return nil
+}
+
+// Poll walks the tree and returns the changes since the last Start or Poll,
+// sorted by path.
+func (w *Watcher) Poll(now time.Time) ([]Change, error) {
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for p, cur := range files {
+		prev, ok := w.files[p]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: p, Type: Created, Time: now})
+		case prev != cur:
+			changes = append(changes, Change{Path: p, Type: Modified, Time: now})
+		}
+	}
+	for p := range w.files {
+		if _, ok := files[p]; !ok {
+			changes = append(changes, Change{Path: p, Type: Deleted, Time: now})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	w.files = files
+	return changes, nil
+}
+
+// scan walks the tree, skipping files that vanish mid-walk.
+func (w *Watcher) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(w.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p != w.root {
+				return nil
+			}
+			return err
+		}
+		if p == w.root {
+			return nil
+		}
+		rel, err := filepath.Rel(w.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if w.excluded(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if w.excluded(rel) || !w.included(rel) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		files[rel] = fileState{size: info.Size(), mode: info.Mode(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+func (w *Watcher) included(rel string) bool {
+	if len(w.include) == 0 {
+		return true
+	}
+	for _, pattern := range w.include {
+		if Match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) excluded(rel string) bool {
+	for _, pattern := range w.exclude {
+		if Match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether the slash-separated path rel matches pattern. A
+// pattern without a slash is matched against every path element ("*.go",
+// "node_modules"); one with a slash is matched against the leading elements
+// of rel ("src/*.ts", "docs"), so a directory pattern covers its contents.
+// Invalid patterns match nothing.
+func Match(pattern, rel string) bool {
+	pattern = strings.Trim(pattern, "/")
+	elems := strings.Split(rel, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, elem := range elems {
+			if ok, _ := path.Match(pattern, elem); ok {
+				return true
+			}
+		}
+		return false
+	}
+	depth := strings.Count(pattern, "/") + 1
+	if depth > len(elems) {
+		return false
+	}
+	ok, _ := path.Match(pattern, strings.Join(elems[:depth], "/"))
+	return ok
+}
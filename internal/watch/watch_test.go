@@ -0,0 +1,116 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func changeList(changes []Change) []string {
+	var out []string
+	for _, c := range changes {
+		out = append(out, c.Type+" "+c.Path)
+	}
+	return out
+}
+
+func TestWatcherPoll(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	writeFile(t, root, "keep.go", "package keep")
+	writeFile(t, root, "edit.go", "package edit")
+	writeFile(t, root, "gone.go", "package gone")
+	writeFile(t, root, ".git", "gitdir: /elsewhere")
+	writeFile(t, root, "node_modules/dep/index.js", "x")
+
+	w := New(root, nil, []string{"node_modules"})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// No changes yet
+	changes, err := w.Poll(now)
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("Poll() unchanged = %v, %v", changes, err)
+	}
+
+	writeFile(t, root, "edit.go", "package edit // changed")
+	writeFile(t, root, "src/new.go", "package src")
+	writeFile(t, root, "node_modules/dep/other.js", "y")
+	writeFile(t, root, ".git", "gitdir: /moved")
+	if err := os.Remove(filepath.Join(root, "gone.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err = w.Poll(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"modified edit.go", "deleted gone.go", "created src/new.go"}
+	if got := changeList(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Poll() = %v, want %v", got, want)
+	}
+	if !changes[0].Time.Equal(now) {
+		t.Errorf("change time = %v, want %v", changes[0].Time, now)
+	}
+
+	// Changes are reported once
+	if changes, _ := w.Poll(now); len(changes) != 0 {
+		t.Errorf("second Poll() = %v, want none", changeList(changes))
+	}
+}
+
+func TestWatcherInclude(t *testing.T) {
+	root := t.TempDir()
+	w := New(root, []string{"*.go"}, nil)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "a.go", "")
+	writeFile(t, root, "b.txt", "")
+
+	changes, err := w.Poll(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changeList(changes); !reflect.DeepEqual(got, []string{"created a.go"}) {
+		t.Errorf("Poll() = %v, want [created a.go]", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/agency/main.go", true},
+		{"*.go", "main.go.orig", false},
+		{"node_modules", "web/node_modules/x/index.js", true},
+		{"src/*.ts", "src/app.ts", true},
+		{"src/*.ts", "lib/src/app.ts", false},
+		{"docs", "docs/readme.md", true},
+		{"docs/", "docs/readme.md", true},
+		{"src/api", "src/api/handler.go", true},
+		{"src/api", "src/apiv2/handler.go", false},
+		{"[", "x", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/exec"
+)
+
+// NestedRepos describes the git repositories inside a checkout. Both kinds
+// break the assumption that a worktree holds the whole project: a fresh
+// worktree gets an empty directory for each submodule, and nested repos are
+// not part of the checkout at all (and their changes never show up in the
+// outer repo's dirty checks).
+type NestedRepos struct {
+	// Gitmodules is true if the checkout has a .gitmodules file.
+	Gitmodules bool
+
+	// Submodules are the paths of tracked gitlinks (mode 160000 entries),
+	// i.e. submodules and repos added with `git add <nested repo>`.
+	Submodules []string
+
+	// Nested are untracked, non-ignored directories holding their own .git.
+	Nested []string
+}
+
+// FindNestedRepos inspects the checkout at dir for submodules and nested
+// git repos. Gitlinks come from `git ls-files --stage`; nested repos are the
+// directory entries of `git ls-files --others --exclude-standard`, since git
+// does not descend into untracked repos.
+func FindNestedRepos(ctx context.Context, cr exec.CommandRunner, dir string) (NestedRepos, error) {
+	var nr NestedRepos
+	if _, err := os.Stat(filepath.Join(dir, ".gitmodules")); err == nil {
+		nr.Gitmodules = true
+	}
+
+	staged, err := gitLsFiles(ctx, cr, dir, "--stage")
+	if err != nil {
+		return nr, err
+	}
+	for _, entry := range staged {
+		// "<mode> <object> <stage>\t<path>"
+		info, path, ok := strings.Cut(entry, "\t")
+		if ok && strings.HasPrefix(info, "160000 ") {
+			nr.Submodules = append(nr.Submodules, path)
+		}
+	}
+
+	others, err := gitLsFiles(ctx, cr, dir, "--others", "--exclude-standard")
+	if err != nil {
+		return nr, err
+	}
+	for _, path := range others {
+		if strings.HasSuffix(path, "/") {
+			nr.Nested = append(nr.Nested, strings.TrimSuffix(path, "/"))
+		}
+	}
+	return nr, nil
+}
+
+// gitLsFiles runs `git -C dir ls-files <args> -z` and returns its entries.
+func gitLsFiles(ctx context.Context, cr exec.CommandRunner, dir string, args ...string) ([]string, error) {
+	fullArgs := append([]string{"-C", dir, "ls-files"}, args...)
+	fullArgs = append(fullArgs, "-z")
+	result, err := cr.Run(ctx, "git", fullArgs, exec.RunOpts{})
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("git ls-files %s failed: %s", strings.Join(args, " "), strings.TrimSpace(result.Stderr))
+	}
+	var entries []string
+	for _, entry := range strings.Split(result.Stdout, "\x00") {
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,92 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestSubmodulesAndNestedRepos(t *testing.T) {
+	// Local submodule URLs need the file transport
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	libRoot, _, cleanupLib := setupTempRepo(t)
+	defer cleanupLib()
+	repoRoot, dataDir, cleanup := setupTempRepo(t)
+	defer cleanup()
+
+	if err := runGit(repoRoot, "submodule", "add", libRoot, "vendor/lib"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(repoRoot, "commit", "-m", "add submodule"); err != nil {
+		t.Fatal(err)
+	}
+	// An untracked repo inside the checkout
+	if err := runGit(repoRoot, "init", "tools/scratch"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	cr := agencyexec.NewRealRunner()
+	nested, err := FindNestedRepos(ctx, cr, repoRoot)
+	if err != nil {
+		t.Fatalf("FindNestedRepos() error = %v", err)
+	}
+	want := NestedRepos{Gitmodules: true, Submodules: []string{"vendor/lib"}, Nested: []string{"tools/scratch"}}
+	if !reflect.DeepEqual(nested, want) {
+		t.Errorf("FindNestedRepos() = %+v, want %+v", nested, want)
+	}
+
+	resolvedRepoRoot, _ := filepath.EvalSymlinks(repoRoot)
+	parentBranch := getCurrentBranch(t, repoRoot)
+	create := func(runID string, submodules bool) *CreateResult {
+		t.Helper()
+		result, err := Create(ctx, cr, fs.NewRealFS(), CreateOpts{
+			RunID:        runID,
+			Title:        "Submodules",
+			RepoRoot:     resolvedRepoRoot,
+			RepoID:       "abcd1234ef567890",
+			ParentBranch: parentBranch,
+			DataDir:      dataDir,
+			Submodules:   submodules,
+		})
+		if err != nil {
+			t.Fatalf("Create(submodules=%v) error = %v", submodules, err)
+		}
+		return result
+	}
+
+	// Without worktrees.submodules the submodule stays empty, with a warning
+	result := create("20260110120000-aaaa", false)
+	if !reflect.DeepEqual(result.Submodules, []string{"vendor/lib"}) || result.SubmoduleLog != "" {
+		t.Errorf("Submodules = %v, SubmoduleLog = %q", result.Submodules, result.SubmoduleLog)
+	}
+	if len(result.Warnings) == 0 || result.Warnings[len(result.Warnings)-1].Code != "W_SUBMODULES_NOT_INITIALIZED" {
+		t.Errorf("Warnings = %+v, want W_SUBMODULES_NOT_INITIALIZED", result.Warnings)
+	}
+	if _, err := os.Stat(filepath.Join(result.WorktreePath, "vendor", "lib", "README.md")); !os.IsNotExist(err) {
+		t.Errorf("submodule checked out without worktrees.submodules: %v", err)
+	}
+
+	// With it the submodule is checked out and the command is logged
+	result = create("20260110120000-bbbb", true)
+	if _, err := os.Stat(filepath.Join(result.WorktreePath, "vendor", "lib", "README.md")); err != nil {
+		t.Errorf("submodule not initialized: %v", err)
+	}
+	if !strings.HasPrefix(result.SubmoduleLog, "$ git -C "+result.WorktreePath+" submodule update --init --recursive\n") {
+		t.Errorf("SubmoduleLog = %q", result.SubmoduleLog)
+	}
+	for _, w := range result.Warnings {
+		if w.Code == "W_SUBMODULES_NOT_INITIALIZED" {
+			t.Errorf("unexpected warning: %+v", w)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OutputHistoryKeep is how many earlier output dirs RotateOutputDir keeps
+// under .agency/out-history/.
+const OutputHistoryKeep = 5
+
+// OutputDir returns the script output dir (AGENCY_OUTPUT_DIR) of a worktree.
+func OutputDir(worktreePath string) string {
+	return filepath.Join(worktreePath, ".agency", "out")
+}
+
+// RotateOutputDir gives a script an empty .agency/out so structured output
+// left by an earlier script run (e.g. setup.json from a failed attempt) can
+// never be read as its result. A non-empty .agency/out is moved to
+// .agency/out-history/<yyyymmddThhmmssZ>-<script>/ and recreated; only the
+// newest OutputHistoryKeep entries are kept.
+//
+// Returns the history dir the earlier output was moved to ("" if there was
+// nothing to move).
+func RotateOutputDir(worktreePath, script string, now time.Time) (string, error) {
+	outDir := OutputDir(worktreePath)
+	entries, err := os.ReadDir(outDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", os.MkdirAll(outDir, 0o755)
+	}
+
+	historyRoot := filepath.Join(worktreePath, ".agency", "out-history")
+	if err := os.MkdirAll(historyRoot, 0o755); err != nil {
+		return "", err
+	}
+	name := now.UTC().Format("20060102T150405Z") + "-" + script
+	historyDir := filepath.Join(historyRoot, name)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(historyDir); os.IsNotExist(err) {
+			break
+		}
+		historyDir = filepath.Join(historyRoot, name+"-"+strconv.Itoa(i))
+	}
+	if err := os.Rename(outDir, historyDir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return "", err
+	}
+
+	pruneOutputHistory(historyRoot)
+	return historyDir, nil
+}
+
+// pruneOutputHistory removes all but the newest OutputHistoryKeep entries
+// (best-effort; names sort by time).
+func pruneOutputHistory(historyRoot string) {
+	entries, err := os.ReadDir(historyRoot)
+	if err != nil || len(entries) <= OutputHistoryKeep {
+		return
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-OutputHistoryKeep] {
+		_ = os.RemoveAll(filepath.Join(historyRoot, name))
+	}
+}
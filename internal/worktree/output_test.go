@@ -0,0 +1,57 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotateOutputDir(t *testing.T) {
+	wt := t.TempDir()
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	// Missing or empty output dir: nothing to move, dir is (re)created
+	prev, err := RotateOutputDir(wt, "setup", now)
+	if err != nil || prev != "" {
+		t.Fatalf("RotateOutputDir() empty = %q, %v", prev, err)
+	}
+	if info, err := os.Stat(OutputDir(wt)); err != nil || !info.IsDir() {
+		t.Fatalf("output dir not created: %v", err)
+	}
+
+	// Earlier output moves to a timestamped history dir
+	if err := os.WriteFile(filepath.Join(OutputDir(wt), "setup.json"), []byte(`{"ok": false}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prev, err = RotateOutputDir(wt, "setup", now)
+	if err != nil {
+		t.Fatalf("RotateOutputDir() error = %v", err)
+	}
+	if want := filepath.Join(wt, ".agency", "out-history", "20260110T120000Z-setup"); prev != want {
+		t.Errorf("history dir = %q, want %q", prev, want)
+	}
+	if _, err := os.Stat(filepath.Join(prev, "setup.json")); err != nil {
+		t.Errorf("setup.json not in history: %v", err)
+	}
+	if entries, _ := os.ReadDir(OutputDir(wt)); len(entries) != 0 {
+		t.Errorf("output dir not empty: %v", entries)
+	}
+
+	// Only the newest OutputHistoryKeep entries survive
+	for i := 0; i < OutputHistoryKeep+2; i++ {
+		if err := os.WriteFile(filepath.Join(OutputDir(wt), "setup.json"), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RotateOutputDir(wt, "setup", now.Add(time.Duration(i+1)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(wt, ".agency", "out-history"))
+	if err != nil || len(entries) != OutputHistoryKeep {
+		t.Fatalf("history entries = %d (%v), want %d", len(entries), err, OutputHistoryKeep)
+	}
+	if first := entries[0].Name(); first != "20260110T120300Z-setup" {
+		t.Errorf("oldest kept entry = %q, want 20260110T120300Z-setup", first)
+	}
+}
@@ -0,0 +1,108 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/exec"
+)
+
+// DefaultMaxPathLength returns the path length limit used when agency.json
+// sets no worktrees.max_path_length: the platform's PATH_MAX (1024 on macOS,
+// 4096 elsewhere).
+func DefaultMaxPathLength() int {
+	if runtime.GOOS == "darwin" {
+		return 1024
+	}
+	return 4096
+}
+
+// CheckPath verifies that a worktree for ref can be created at path before
+// `git worktree add` touches anything:
+//   - path must not exist, and no sibling may differ from it only in case
+//     (they are the same directory on case-insensitive filesystems)
+//   - path plus the longest file tracked at ref must fit in maxLen bytes
+//     (0 = DefaultMaxPathLength); tools fail in odd ways on longer paths
+//
+// The longest file comes from `git ls-tree`; if that fails, only path itself
+// is checked.
+//
+// Error codes:
+//   - E_WORKTREE_PATH_EXISTS: path (or a case variant of it) already exists
+//   - E_WORKTREE_PATH_TOO_LONG: a checked-out file would exceed maxLen
+func CheckPath(ctx context.Context, cr exec.CommandRunner, repoRoot, ref, path string, maxLen int) error {
+	if conflict := findConflictingPath(path); conflict != "" {
+		return errors.NewWithDetails(errors.EWorktreePathExists,
+			"worktree path already exists: "+conflict,
+			map[string]string{
+				"worktree_path":    path,
+				"conflicting_path": conflict,
+				"hint":             "remove the directory if it is left over from a deleted run, or use another --run-id",
+			})
+	}
+
+	if maxLen <= 0 {
+		maxLen = DefaultMaxPathLength()
+	}
+	longest := longestTrackedPath(ctx, cr, repoRoot, ref)
+	full := path
+	if longest != "" {
+		full = filepath.Join(path, longest)
+	}
+	if len(full) <= maxLen {
+		return nil
+	}
+	details := map[string]string{
+		"worktree_path": path,
+		"length":        strconv.Itoa(len(full)),
+		"max":           strconv.Itoa(maxLen),
+		"hint":          "use a shorter data_dir (AGENCY_DATA_DIR) or raise worktrees.max_path_length in agency.json",
+	}
+	msg := fmt.Sprintf("worktree path is %d bytes, over the %d byte limit", len(full), maxLen)
+	if longest != "" {
+		details["longest_file"] = longest
+		msg = fmt.Sprintf("worktree file paths would reach %d bytes (%s), over the %d byte limit", len(full), longest, maxLen)
+	}
+	return errors.NewWithDetails(errors.EWorktreePathTooLong, msg, details)
+}
+
+// findConflictingPath returns path if it exists, or an entry of its parent
+// directory whose name equals its base name ignoring case; "" if neither.
+func findConflictingPath(path string) string {
+	if _, err := os.Lstat(path); err == nil {
+		return path
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	base := filepath.Base(path)
+	for _, e := range entries {
+		if strings.EqualFold(e.Name(), base) {
+			return filepath.Join(filepath.Dir(path), e.Name())
+		}
+	}
+	return ""
+}
+
+// longestTrackedPath returns the longest file path tracked at ref, or "" if
+// it cannot be listed.
+func longestTrackedPath(ctx context.Context, cr exec.CommandRunner, repoRoot, ref string) string {
+	result, err := cr.Run(ctx, "git", []string{"-C", repoRoot, "ls-tree", "-r", "-z", "--name-only", ref}, exec.RunOpts{})
+	if err != nil || result.ExitCode != 0 {
+		return ""
+	}
+	var longest string
+	for _, name := range strings.Split(result.Stdout, "\x00") {
+		if len(name) > len(longest) {
+			longest = name
+		}
+	}
+	return longest
+}
@@ -0,0 +1,51 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// RunUsage is the disk usage of one run's worktree.
+type RunUsage struct {
+	RunID string
+	Bytes int64
+}
+
+// DiskUsage returns the size of every worktree under
+// ${AGENCY_DATA_DIR}/repos/<repo_id>/worktrees/, largest first, and their total.
+// A repo without a worktrees dir has no usage.
+func DiskUsage(fsys fs.FS, dataDir, repoID string) ([]RunUsage, int64, error) {
+	root := filepath.Join(dataDir, "repos", repoID, "worktrees")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+
+	var usage []RunUsage
+	var total int64
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		size, err := fsys.DirSize(filepath.Join(root, e.Name()))
+		if err != nil {
+			return nil, 0, err
+		}
+		usage = append(usage, RunUsage{RunID: e.Name(), Bytes: size})
+		total += size
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Bytes != usage[j].Bytes {
+			return usage[i].Bytes > usage[j].Bytes
+		}
+		return usage[i].RunID < usage[j].RunID
+	})
+	return usage, total, nil
+}
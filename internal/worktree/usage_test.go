@@ -0,0 +1,52 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+func TestDiskUsage(t *testing.T) {
+	dataDir := t.TempDir()
+	root := filepath.Join(dataDir, "repos", "abc123", "worktrees")
+
+	if usage, total, err := DiskUsage(fs.NewRealFS(), dataDir, "abc123"); err != nil || usage != nil || total != 0 {
+		t.Fatalf("DiskUsage() without worktrees = %v, %d, %v", usage, total, err)
+	}
+
+	write := func(rel string, size int) {
+		t.Helper()
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("20260110120000-a3f2/README.md", 100)
+	write("20260111090000-b4c1/src/big.bin", 4000)
+	write("20260111090000-b4c1/src/small.txt", 96)
+	if err := os.Symlink("/", filepath.Join(root, "20260110120000-a3f2", "root-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, total, err := DiskUsage(fs.NewRealFS(), dataDir, "abc123")
+	if err != nil {
+		t.Fatalf("DiskUsage() error = %v", err)
+	}
+	if total != 4196 {
+		t.Errorf("total = %d, want 4196", total)
+	}
+	want := []RunUsage{{"20260111090000-b4c1", 4096}, {"20260110120000-a3f2", 100}}
+	if len(usage) != len(want) {
+		t.Fatalf("usage = %v, want %v", usage, want)
+	}
+	for i := range want {
+		if usage[i] != want[i] {
+			t.Errorf("usage[%d] = %v, want %v", i, usage[i], want[i])
+		}
+	}
+}
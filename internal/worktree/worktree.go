@@ -32,6 +32,13 @@ type CreateResult struct {
 	// ResolvedTitle is the title used for slug/template (may differ from input if defaulted).
 	ResolvedTitle string
 
+	// Submodules are the worktree's gitlink paths (see FindNestedRepos).
+	Submodules []string
+
+	// SubmoduleLog is the command and output of `git submodule update`
+	// (empty unless CreateOpts.Submodules), kept as evidence for the setup log.
+	SubmoduleLog string
+
 	// Warnings contains non-fatal warnings (e.g., .agency/ not ignored).
 	Warnings []Warning
 }
@@ -55,6 +62,32 @@ type CreateOpts struct {
 
 	// DataDir is the resolved AGENCY_DATA_DIR.
 	DataDir string
+
+	// Slug holds the repo's slug rules for the branch name and default title
+	// (zero value = built-in rules).
+	Slug core.SlugRules
+
+	// SparseCheckout are gitignore-style sparse-checkout patterns
+	// (agency.json worktrees.sparse_checkout); empty = full checkout.
+	SparseCheckout []string
+
+	// Submodules initializes submodules after checkout
+	// (agency.json worktrees.submodules).
+	Submodules bool
+
+	// MaxPathLength caps worktree file paths (agency.json
+	// worktrees.max_path_length; 0 = DefaultMaxPathLength).
+	MaxPathLength int
+}
+
+// Names returns the title, branch, and worktree path Create would use for opts,
+// without touching git or the filesystem (used by `agency run --dry-run`).
+func Names(opts CreateOpts) (title, branch, worktreePath string) {
+	title = opts.Title
+	if title == "" {
+		title = opts.Slug.DefaultTitle(opts.RunID)
+	}
+	return title, opts.Slug.BranchName(title, opts.RunID), WorktreePath(opts.DataDir, opts.RepoID, opts.RunID)
 }
 
 // Create creates a git worktree and scaffolds the workspace.
@@ -62,43 +95,108 @@ type CreateOpts struct {
 // Operations (in order):
 //  1. Compute branch name from title + run_id
 //  2. Compute worktree path from data_dir + repo_id + run_id
+//     and check it with CheckPath
 //  3. Create branch + worktree via: git worktree add -b <branch> <path> <parent>
-//  4. Create .agency/, .agency/out/, .agency/tmp/ directories
-//  5. Create .agency/report.md if missing (with template)
-//  6. Check if .agency/ is ignored (best-effort warning)
+//     (with SparseCheckout: add --no-checkout, then
+//     git sparse-checkout set --no-cone <patterns> and git read-tree -mu HEAD)
+//  4. With Submodules: git submodule update --init --recursive
+//  5. Create .agency/, .agency/out/, .agency/tmp/ directories
+//  6. Create .agency/report.md if missing (with template)
+//  7. Check if .agency/ is ignored and whether submodules were left
+//     uninitialized (best-effort warnings)
 //
 // Error codes:
-//   - E_WORKTREE_CREATE_FAILED: any git worktree add failure (including collisions)
+//   - E_WORKTREE_PATH_EXISTS: the worktree path (or a case variant) exists
+//   - E_WORKTREE_PATH_TOO_LONG: checked-out paths would exceed MaxPathLength
+//   - E_WORKTREE_CREATE_FAILED: any other git worktree add failure
 func Create(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, opts CreateOpts) (*CreateResult, error) {
-	// 1. Resolve title (default if empty)
-	resolvedTitle := opts.Title
-	if resolvedTitle == "" {
-		shortID := core.ShortID(opts.RunID)
-		resolvedTitle = "untitled-" + shortID
+	// 1-3. Resolve title (default if empty), branch name, and worktree path
+	resolvedTitle, branch, worktreePath := Names(opts)
+	if err := CheckPath(ctx, cr, opts.RepoRoot, opts.ParentBranch, worktreePath, opts.MaxPathLength); err != nil {
+		return nil, err
 	}
 
-	// 2. Compute branch name
-	branch := core.BranchName(resolvedTitle, opts.RunID)
-
-	// 3. Compute worktree path
-	worktreePath := WorktreePath(opts.DataDir, opts.RepoID, opts.RunID)
-
 	// 4. Create worktree + branch in one command
 	// Command: git -C <repo_root> worktree add -b <branch> <worktree_path> <parent_branch>
-	args := []string{
-		"-C", opts.RepoRoot,
-		"worktree", "add",
-		"-b", branch,
-		worktreePath,
-		opts.ParentBranch,
+	args := []string{"-C", opts.RepoRoot, "worktree", "add"}
+	if len(opts.SparseCheckout) > 0 {
+		// Check out only the sparse patterns below, not the whole tree
+		args = append(args, "--no-checkout")
+	}
+	args = append(args, "-b", branch, worktreePath, opts.ParentBranch)
+	if _, err := runWorktreeGit(ctx, cr, args, "git worktree add"); err != nil {
+		return nil, err
 	}
 
+	if len(opts.SparseCheckout) > 0 {
+		sparseArgs := append([]string{"-C", worktreePath, "sparse-checkout", "set", "--no-cone"}, opts.SparseCheckout...)
+		if _, err := runWorktreeGit(ctx, cr, sparseArgs, "git sparse-checkout set"); err != nil {
+			return nil, err
+		}
+		readTreeArgs := []string{"-C", worktreePath, "read-tree", "-mu", "HEAD"}
+		if _, err := runWorktreeGit(ctx, cr, readTreeArgs, "git read-tree"); err != nil {
+			return nil, err
+		}
+	}
+
+	var submoduleLog string
+	if opts.Submodules {
+		submoduleArgs := []string{"-C", worktreePath, "submodule", "update", "--init", "--recursive"}
+		result, err := runWorktreeGit(ctx, cr, submoduleArgs, "git submodule update")
+		if err != nil {
+			return nil, err
+		}
+		submoduleLog = "$ git " + strings.Join(submoduleArgs, " ") + "\n" + result.Stdout + result.Stderr
+	}
+
+	// 5-6. Scaffold workspace directories
+	if err := scaffoldWorkspace(fsys, worktreePath, resolvedTitle); err != nil {
+		return nil, errors.WrapWithDetails(
+			errors.EWorktreeCreateFailed,
+			"failed to scaffold workspace",
+			err,
+			map[string]string{
+				"worktree_path": worktreePath,
+			},
+		)
+	}
+
+	// 7. Check if .agency/ is ignored (best-effort)
+	var warnings []Warning
+	if warn := checkIgnored(ctx, cr, worktreePath); warn != nil {
+		warnings = append(warnings, *warn)
+	}
+	var submodules []string
+	if nested, err := FindNestedRepos(ctx, cr, worktreePath); err == nil {
+		submodules = nested.Submodules
+		if len(submodules) > 0 && !opts.Submodules {
+			warnings = append(warnings, Warning{
+				Code: "W_SUBMODULES_NOT_INITIALIZED",
+				Message: fmt.Sprintf("%d submodule(s) checked out as empty dirs (%s); set worktrees.submodules in agency.json to initialize them",
+					len(submodules), strings.Join(submodules, ", ")),
+			})
+		}
+	}
+
+	return &CreateResult{
+		Branch:        branch,
+		WorktreePath:  worktreePath,
+		ResolvedTitle: resolvedTitle,
+		Submodules:    submodules,
+		SubmoduleLog:  submoduleLog,
+		Warnings:      warnings,
+	}, nil
+}
+
+// runWorktreeGit runs git with args and maps failures to
+// E_WORKTREE_CREATE_FAILED; name labels the command in the message.
+func runWorktreeGit(ctx context.Context, cr exec.CommandRunner, args []string, name string) (exec.CmdResult, error) {
 	result, err := cr.Run(ctx, "git", args, exec.RunOpts{})
 	if err != nil {
 		// Binary not found or execution failure
-		return nil, errors.WrapWithDetails(
+		return result, errors.WrapWithDetails(
 			errors.EWorktreeCreateFailed,
-			"failed to execute git worktree add",
+			"failed to execute "+name,
 			err,
 			map[string]string{
 				"command": "git " + strings.Join(args, " "),
@@ -107,7 +205,7 @@ func Create(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, opts CreateO
 	}
 
 	if result.ExitCode != 0 {
-		// Git worktree add failed (collision, already checked out, etc.)
+		// Git failed (collision, already checked out, etc.)
 		details := map[string]string{
 			"command":   "git " + strings.Join(args, " "),
 			"exit_code": fmt.Sprintf("%d", result.ExitCode),
@@ -130,37 +228,13 @@ func Create(ctx context.Context, cr exec.CommandRunner, fsys fs.FS, opts CreateO
 			details["stdout"] = stdout
 		}
 
-		return nil, errors.NewWithDetails(
+		return result, errors.NewWithDetails(
 			errors.EWorktreeCreateFailed,
-			"git worktree add failed: "+strings.TrimSpace(result.Stderr),
+			name+" failed: "+strings.TrimSpace(result.Stderr),
 			details,
 		)
 	}
-
-	// 5. Scaffold workspace directories
-	if err := scaffoldWorkspace(fsys, worktreePath, resolvedTitle); err != nil {
-		return nil, errors.WrapWithDetails(
-			errors.EWorktreeCreateFailed,
-			"failed to scaffold workspace",
-			err,
-			map[string]string{
-				"worktree_path": worktreePath,
-			},
-		)
-	}
-
-	// 6. Check if .agency/ is ignored (best-effort)
-	var warnings []Warning
-	if warn := checkIgnored(ctx, cr, worktreePath); warn != nil {
-		warnings = append(warnings, *warn)
-	}
-
-	return &CreateResult{
-		Branch:        branch,
-		WorktreePath:  worktreePath,
-		ResolvedTitle: resolvedTitle,
-		Warnings:      warnings,
-	}, nil
+	return result, nil
 }
 
 // WorktreePath returns the worktree path for a run.
@@ -244,6 +244,69 @@ func TestCreate_EmptyTitle(t *testing.T) {
 	}
 }
 
+func TestCreate_SparseCheckout(t *testing.T) {
+	repoRoot, dataDir, cleanup := setupTempRepo(t)
+	defer cleanup()
+
+	// Add a large directory to leave out
+	if err := os.MkdirAll(filepath.Join(repoRoot, "assets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, "assets", "big.bin"), []byte("big"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(repoRoot, "add", "-A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(repoRoot, "commit", "-m", "add assets"); err != nil {
+		t.Fatal(err)
+	}
+
+	resolvedRepoRoot, _ := filepath.EvalSymlinks(repoRoot)
+	parentBranch := getCurrentBranch(t, repoRoot)
+	if parentBranch == "" {
+		parentBranch = "master"
+	}
+
+	result, err := Create(context.Background(), agencyexec.NewRealRunner(), fs.NewRealFS(), CreateOpts{
+		RunID:          "20260110120000-c0de",
+		Title:          "Sparse Run",
+		RepoRoot:       resolvedRepoRoot,
+		RepoID:         "abcd1234ef567890",
+		ParentBranch:   parentBranch,
+		DataDir:        dataDir,
+		SparseCheckout: []string{"/*", "!/assets/"},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(result.WorktreePath, "README.md")); err != nil {
+		t.Errorf("README.md should be checked out: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(result.WorktreePath, "assets")); !os.IsNotExist(err) {
+		t.Errorf("assets/ should be left out, stat err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(result.WorktreePath, ".agency", "report.md")); err != nil {
+		t.Errorf("report.md should be scaffolded: %v", err)
+	}
+
+	// Left-out files are not reported as deleted
+	cmd := exec.Command("git", "-C", result.WorktreePath, "status", "--porcelain", "--untracked-files=no")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git status failed: %v", err)
+	}
+	if len(output) != 0 {
+		t.Errorf("git status should be clean, got: %s", output)
+	}
+
+	// The main worktree keeps its full checkout
+	if _, err := os.Stat(filepath.Join(repoRoot, "assets", "big.bin")); err != nil {
+		t.Errorf("main worktree lost assets/: %v", err)
+	}
+}
+
 func TestCreate_Collision_ReturnsError(t *testing.T) {
 	repoRoot, dataDir, cleanup := setupTempRepo(t)
 	defer cleanup()
@@ -277,7 +340,7 @@ func TestCreate_Collision_ReturnsError(t *testing.T) {
 		t.Fatalf("first Create failed: %v", err)
 	}
 
-	// Second creation with same run_id should fail
+	// Second creation with same run_id should fail before git runs
 	_, err = Create(ctx, cr, fsys, opts)
 	if err == nil {
 		t.Fatal("expected error for collision, got nil")
@@ -285,8 +348,8 @@ func TestCreate_Collision_ReturnsError(t *testing.T) {
 
 	// Verify error code
 	code := errors.GetCode(err)
-	if code != errors.EWorktreeCreateFailed {
-		t.Errorf("error code = %q, want %q", code, errors.EWorktreeCreateFailed)
+	if code != errors.EWorktreePathExists {
+		t.Errorf("error code = %q, want %q", code, errors.EWorktreePathExists)
 	}
 
 	// Verify error has details
@@ -294,15 +357,61 @@ func TestCreate_Collision_ReturnsError(t *testing.T) {
 	if !ok {
 		t.Fatal("expected AgencyError")
 	}
-	if ae.Details == nil {
-		t.Error("expected Details to be set")
+	wantPath := WorktreePath(dataDir, repoID, runID)
+	if ae.Details["conflicting_path"] != wantPath {
+		t.Errorf("conflicting_path = %q, want %q", ae.Details["conflicting_path"], wantPath)
+	}
+}
+
+func TestCheckPath_CaseVariant(t *testing.T) {
+	parent := t.TempDir()
+	if err := os.Mkdir(filepath.Join(parent, "20260110120000-ABCD"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(parent, "20260110120000-abcd")
+	err := CheckPath(context.Background(), agencyexec.NewRealRunner(), parent, "HEAD", path, 0)
+	if errors.GetCode(err) != errors.EWorktreePathExists {
+		t.Fatalf("CheckPath() = %v, want E_WORKTREE_PATH_EXISTS", err)
+	}
+	ae, _ := errors.AsAgencyError(err)
+	if want := filepath.Join(parent, "20260110120000-ABCD"); ae.Details["conflicting_path"] != want {
+		t.Errorf("conflicting_path = %q, want %q", ae.Details["conflicting_path"], want)
+	}
+}
+
+func TestCreate_PathTooLong_ReturnsError(t *testing.T) {
+	repoRoot, dataDir, cleanup := setupTempRepo(t)
+	defer cleanup()
+
+	resolvedRepoRoot, _ := filepath.EvalSymlinks(repoRoot)
+	parentBranch := getCurrentBranch(t, repoRoot)
+	if parentBranch == "" {
+		parentBranch = "master"
+	}
+
+	runID := "20260110120000-1009"
+	repoID := "abcd1234ef567890"
+	wtPath := WorktreePath(dataDir, repoID, runID)
+
+	_, err := Create(context.Background(), agencyexec.NewRealRunner(), fs.NewRealFS(), CreateOpts{
+		RunID:         runID,
+		Title:         "Long Paths",
+		RepoRoot:      resolvedRepoRoot,
+		RepoID:        repoID,
+		ParentBranch:  parentBranch,
+		DataDir:       dataDir,
+		MaxPathLength: len(wtPath) + 3,
+	})
+	if errors.GetCode(err) != errors.EWorktreePathTooLong {
+		t.Fatalf("Create() = %v, want E_WORKTREE_PATH_TOO_LONG", err)
 	}
-	if ae.Details["command"] == "" {
-		t.Error("expected command in details")
+	ae, _ := errors.AsAgencyError(err)
+	if ae.Details["longest_file"] != "README.md" {
+		t.Errorf("longest_file = %q, want README.md", ae.Details["longest_file"])
 	}
-	// Should have stderr (git error message)
-	if ae.Details["stderr"] == "" {
-		t.Error("expected stderr in details")
+	if _, statErr := os.Stat(wtPath); !os.IsNotExist(statErr) {
+		t.Errorf("worktree should not be created, stat err = %v", statErr)
 	}
 }
 
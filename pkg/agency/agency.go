@@ -0,0 +1,90 @@
+// Package agency is the public Go API for embedding agency in other tools.
+//
+// It wraps run creation (the same pipeline as `agency run`), listing runs
+// with their derived status (as `agency ls`), status derivation, and read
+// access to run metadata in the data dir. Everything else in this module
+// lives under internal/ and may change without notice; the types and
+// functions here follow semantic versioning with the CLI.
+//
+// A minimal program:
+//
+//	client, err := agency.New(agency.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	runs, err := client.ListRuns(ctx, agency.ListOptions{})
+//	for _, r := range runs {
+//		fmt.Println(r.RunID, r.Status.Status)
+//	}
+//
+// Errors returned by this package carry the same stable codes as the CLI
+// (e.g. "E_RUN_NOT_FOUND"); use ErrorCode to read them.
+package agency
+
+import (
+	"os"
+
+	"github.com/NielsdaWheelz/agency/internal/commands"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	agencyexec "github.com/NielsdaWheelz/agency/internal/exec"
+	"github.com/NielsdaWheelz/agency/internal/fs"
+)
+
+// Options configures a Client.
+type Options struct {
+	// DataDir is the agency data dir to read runs from. Empty resolves it
+	// like the CLI: AGENCY_DATA_DIR or the platform default, overridden by
+	// the agency.json data_dir of the repo containing Dir.
+	DataDir string
+
+	// Dir is where data dir resolution starts (empty = process cwd).
+	// Ignored when DataDir is set.
+	Dir string
+}
+
+// Client reads and creates agency runs. It is safe for concurrent use;
+// every call reads the data dir afresh.
+type Client struct {
+	dataDir string
+	cr      agencyexec.CommandRunner
+	fsys    fs.FS
+}
+
+// New returns a Client for the data dir selected by opts.
+func New(opts Options) (*Client, error) {
+	fsys := fs.NewRealFS()
+	dataDir := opts.DataDir
+	if dataDir == "" {
+		dir := opts.Dir
+		if dir == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				return nil, errors.Wrap(errors.EInternal, "failed to get working directory", err)
+			}
+			dir = wd
+		}
+		resolved, err := commands.ResolveDataDir(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		dataDir = resolved
+	}
+	return newClient(dataDir, agencyexec.NewRealRunner(), fsys), nil
+}
+
+// newClient builds a Client with explicit dependencies (used by tests).
+func newClient(dataDir string, cr agencyexec.CommandRunner, fsys fs.FS) *Client {
+	return &Client{dataDir: dataDir, cr: cr, fsys: fsys}
+}
+
+// DataDir returns the data dir the client reads runs from.
+func (c *Client) DataDir() string {
+	return c.dataDir
+}
+
+// ErrorCode returns the stable agency error code of err (e.g.
+// "E_RUN_NOT_FOUND", see the README's error codes), or "" if err is nil or
+// did not come from agency.
+func ErrorCode(err error) string {
+	return string(errors.GetCode(err))
+}
@@ -0,0 +1,150 @@
+package agency
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/fs"
+	"github.com/NielsdaWheelz/agency/internal/store"
+	"github.com/NielsdaWheelz/agency/internal/testkit"
+)
+
+// newTestClient writes three runs to a temp data dir: an idle run with a
+// worktree, a merged run, and an archived run (no worktree).
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	dataDir := testkit.DataDir(t)
+	base := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	write := func(runID string, created time.Time, worktree bool, mutate func(*store.RunMeta)) {
+		path := filepath.Join(dataDir, "repos", "repo1", "worktrees", runID)
+		if worktree {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		meta := testkit.NewRunMeta("repo1", runID, path, created)
+		if mutate != nil {
+			mutate(meta)
+		}
+		testkit.WriteRun(t, dataDir, meta)
+	}
+	write("20260110120000-a3f2", base, true, func(m *store.RunMeta) {
+		m.Labels = map[string]string{"ticket": "JIRA-1"}
+		m.Group = "payments"
+	})
+	write("20260110130000-b4c5", base.Add(time.Hour), true, func(m *store.RunMeta) {
+		m.PRNumber = 12
+		m.Archive = &store.RunMetaArchive{MergedAt: "2026-01-11T09:00:00Z"}
+	})
+	write("20260109120000-c6d7", base.Add(-24*time.Hour), false, nil)
+	return newClient(dataDir, testkit.NewFakeRunner(), fs.NewRealFS())
+}
+
+func TestClient_ListRuns(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	runs, err := c.ListRuns(ctx, ListOptions{})
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(runs) != 2 || runs[0].RunID != "20260110130000-b4c5" || runs[1].RunID != "20260110120000-a3f2" {
+		t.Fatalf("runs = %+v", runs)
+	}
+	if runs[0].Status.Status != StatusMerged || runs[0].PRNumber != 12 || runs[0].MergedAt.IsZero() {
+		t.Errorf("merged run = %+v", runs[0])
+	}
+	if runs[1].Status.Status != StatusIdle || runs[1].Labels["ticket"] != "JIRA-1" {
+		t.Errorf("idle run = %+v", runs[1])
+	}
+
+	runs, err = c.ListRuns(ctx, ListOptions{IncludeArchived: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || !runs[2].Status.Archived {
+		t.Errorf("with archived: %+v", runs)
+	}
+
+	runs, err = c.ListRuns(ctx, ListOptions{Group: "payments"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Group != "payments" {
+		t.Errorf("group filter: %+v", runs)
+	}
+}
+
+func TestClient_FindAndGetRun(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	found, err := c.FindRun(ctx, "20260110120000")
+	if err != nil {
+		t.Fatalf("FindRun: %v", err)
+	}
+	if found.RunID != "20260110120000-a3f2" || found.Status.Status != StatusIdle {
+		t.Errorf("found = %+v", found)
+	}
+
+	if _, err := c.FindRun(ctx, "202601101"); ErrorCode(err) != "E_RUN_ID_AMBIGUOUS" {
+		t.Errorf("ambiguous prefix: got %v", err)
+	}
+	if _, err := c.FindRun(ctx, "nope"); ErrorCode(err) != "E_RUN_NOT_FOUND" {
+		t.Errorf("unknown run: got %v", err)
+	}
+
+	run, err := c.GetRun(ctx, "repo1", "20260110120000-a3f2")
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if run.CreatedAt != time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC) || run.Group != "payments" {
+		t.Errorf("run = %+v", run)
+	}
+}
+
+func TestDeriveStatus(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		run  Run
+		in   StatusInput
+		want string
+	}{
+		{"idle", Run{}, StatusInput{WorktreePresent: true}, StatusIdle},
+		{"active pr", Run{PRNumber: 3}, StatusInput{WorktreePresent: true, TmuxActive: true}, StatusActivePR},
+		{"ready for review", Run{PRNumber: 3, LastPushAt: now}, StatusInput{WorktreePresent: true, ReportBytes: 100}, StatusReadyForReview},
+		{"stale report", Run{PRNumber: 3, LastPushAt: now}, StatusInput{WorktreePresent: true, ReportBytes: 100, ReportStale: true}, StatusIdlePR},
+		{"failed", Run{SetupFailed: true}, StatusInput{WorktreePresent: true}, StatusFailed},
+		{"merged wins", Run{SetupFailed: true, MergedAt: now}, StatusInput{}, StatusMerged},
+		{"deadline", Run{Deadline: now.Add(-time.Minute)}, StatusInput{WorktreePresent: true, Now: now}, StatusNeedsAttention},
+		{"deadline not yet", Run{Deadline: now.Add(time.Minute)}, StatusInput{WorktreePresent: true, Now: now}, StatusIdle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeriveStatus(tt.run, tt.in); got.Status != tt.want {
+				t.Errorf("DeriveStatus = %q, want %q", got.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateRun_Validation(t *testing.T) {
+	c := newClient(t.TempDir(), testkit.NewFakeRunner(), fs.NewRealFS())
+	ctx := context.Background()
+
+	if _, err := c.CreateRun(ctx, CreateOptions{}); ErrorCode(err) != "E_USAGE" {
+		t.Errorf("missing RepoDir: got %v", err)
+	}
+	opts := CreateOptions{RepoDir: t.TempDir(), Group: "-bad"}
+	if _, err := c.CreateRun(ctx, opts); ErrorCode(err) != "E_USAGE" {
+		t.Errorf("invalid group: got %v", err)
+	}
+	opts = CreateOptions{RepoDir: t.TempDir(), Labels: map[string]string{"a=b": "c"}}
+	if _, err := c.CreateRun(ctx, opts); ErrorCode(err) != "E_USAGE" {
+		t.Errorf("invalid label: got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package agency
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/commands"
+	"github.com/NielsdaWheelz/agency/internal/core"
+	"github.com/NielsdaWheelz/agency/internal/errors"
+	"github.com/NielsdaWheelz/agency/internal/pipeline"
+	"github.com/NielsdaWheelz/agency/internal/runservice"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// CreateOptions configures CreateRun. Empty fields take the same defaults
+// as `agency run` (agency.json defaults.*).
+type CreateOptions struct {
+	// RepoDir is a directory inside the git repo to create the run in
+	// (required). The repo must contain agency.json.
+	RepoDir string
+
+	Title  string
+	Runner string
+	Parent string
+
+	// RunID is a caller-supplied run_id (empty = generate one).
+	RunID string
+
+	// Labels are stored under meta.labels.
+	Labels map[string]string
+
+	// Group adds the run to a named group.
+	Group string
+
+	// Deadline time-boxes the run (0 = agency.json defaults.deadline);
+	// NoDeadline ignores that default.
+	Deadline     time.Duration
+	NoDeadline   bool
+	DeadlineKill bool
+
+	// NoSetup and NoTmux skip the setup script and the tmux session, as
+	// `agency run --no-setup --no-tmux` does; skips land in meta.skipped_steps.
+	NoSetup bool
+	NoTmux  bool
+
+	// SparseProfile selects agency.json worktrees.sparse_profiles.<name>
+	// ("" = worktrees.sparse_checkout, "none" = full checkout).
+	SparseProfile string
+}
+
+// Warning is a non-fatal problem reported while creating a run.
+type Warning struct {
+	// Code is a stable identifier (e.g. "W_GROUP_INDEX").
+	Code    string
+	Message string
+}
+
+// CreateResult describes a created run.
+type CreateResult struct {
+	Run
+
+	// DataDir is the data dir the run was written to: the one RepoDir
+	// resolves to, which may differ from Client.DataDir.
+	DataDir string
+
+	Warnings []Warning
+}
+
+// CreateRun creates a run like `agency run`: it checks the repo, creates
+// the worktree, writes meta.json, runs setup and hooks, and starts the
+// runner in a detached tmux session. It blocks until setup finishes.
+//
+// On failure after the worktree exists, the returned result still names
+// the run (RunID, WorktreePath) so callers can inspect or clean it up.
+// Errors carry the CLI's codes (E_PARENT_DIRTY, E_SCRIPT_FAILED, ...).
+func (c *Client) CreateRun(ctx context.Context, opts CreateOptions) (CreateResult, error) {
+	if opts.RepoDir == "" {
+		return CreateResult{}, errors.New(errors.EUsage, "RepoDir is required")
+	}
+	// Validate labels with the --label rules
+	args := make([]string, 0, len(opts.Labels))
+	for k, v := range opts.Labels {
+		if strings.Contains(k, "=") {
+			return CreateResult{}, errors.New(errors.EUsage, "invalid label: key "+k+" contains '='")
+		}
+		args = append(args, k+"="+v)
+	}
+	labels, err := core.ParseLabels(args)
+	if err != nil {
+		return CreateResult{}, errors.Wrap(errors.EUsage, "invalid label", err)
+	}
+	if opts.Group != "" {
+		if err := core.ValidateGroupName(opts.Group); err != nil {
+			return CreateResult{}, errors.Wrap(errors.EUsage, "invalid group", err)
+		}
+	}
+
+	crypter := commands.LoadCrypt(c.cr, c.fsys)
+	svc := runservice.NewWithDeps(c.cr, c.fsys)
+	svc.SetCrypt(crypter)
+	p := pipeline.NewPipeline(svc)
+	pipelineOpts := pipeline.RunPipelineOpts{
+		Title:         opts.Title,
+		Runner:        opts.Runner,
+		Parent:        opts.Parent,
+		RunID:         opts.RunID,
+		Labels:        labels,
+		Group:         opts.Group,
+		Dir:           opts.RepoDir,
+		Deadline:      opts.Deadline,
+		NoDeadline:    opts.NoDeadline,
+		DeadlineKill:  opts.DeadlineKill,
+		NoSetup:       opts.NoSetup,
+		NoTmux:        opts.NoTmux,
+		SparseProfile: opts.SparseProfile,
+	}
+	st, err := p.Execute(ctx, pipelineOpts, pipeline.RunSteps(pipelineOpts))
+	if st == nil {
+		return CreateResult{}, err
+	}
+
+	result := CreateResult{
+		Run: Run{
+			RunID:        st.RunID,
+			RepoID:       st.RepoID,
+			Title:        st.Title,
+			Runner:       st.Runner,
+			ParentBranch: st.ParentBranch,
+			Branch:       st.Branch,
+			WorktreePath: st.WorktreePath,
+			Labels:       map[string]string{},
+		},
+		DataDir: st.DataDir,
+	}
+	for _, w := range st.Warnings {
+		result.Warnings = append(result.Warnings, Warning{Code: w.Code, Message: w.Message})
+	}
+	if st.DataDir != "" && st.RepoID != "" {
+		if meta, readErr := store.NewStore(c.fsys, st.DataDir, time.Now).WithCrypt(crypter).ReadMeta(st.RepoID, st.RunID); readErr == nil {
+			result.Run = newRun(meta)
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,254 @@
+package agency
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/commands"
+	"github.com/NielsdaWheelz/agency/internal/render"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// Run is a run's metadata, as recorded in its meta.json.
+// Optional timestamps are the zero time when unset.
+type Run struct {
+	RunID  string
+	RepoID string
+	Title  string
+
+	// Runner is the runner name (e.g. "claude"); RunnerCmd is the shell
+	// command it resolved to.
+	Runner    string
+	RunnerCmd string
+
+	ParentBranch string
+	Branch       string
+	WorktreePath string
+	CreatedAt    time.Time
+
+	// CreatedBy is the user who created the run ("" if not recorded).
+	CreatedBy string
+
+	// TmuxSessionName is empty until the runner session was started.
+	TmuxSessionName string
+
+	// Labels are the key=value labels set at creation (never nil).
+	Labels map[string]string
+
+	// Group is the run's named group ("" if none).
+	Group string
+
+	// PRNumber is 0 and PRURL "" until a PR exists.
+	PRNumber   int
+	PRURL      string
+	LastPushAt time.Time
+
+	// State flags.
+	SetupFailed    bool
+	TmuxFailed     bool
+	NeedsAttention bool
+	Abandoned      bool
+
+	// MergedAt and ArchivedAt are set once the run was merged / archived.
+	MergedAt   time.Time
+	ArchivedAt time.Time
+
+	// Deadline is the run's time box; DeadlineKill kills its session then.
+	Deadline     time.Time
+	DeadlineKill bool
+}
+
+// RunStatus is a run's derived status and the local facts behind it.
+type RunStatus struct {
+	// Status is one of the Status* constants.
+	Status string
+
+	// Archived is true when the worktree no longer exists.
+	Archived bool
+
+	// TmuxActive is true when the run's tmux session exists.
+	TmuxActive bool
+
+	// ReportStale is true when the branch has commits newer than report.md covers.
+	ReportStale bool
+
+	// NoChanges is true when an open run has no commits and an empty report.
+	NoChanges bool
+
+	// AttentionReason explains a StatusNeedsAttention that agency derived
+	// (e.g. "deadline exceeded"); empty otherwise.
+	AttentionReason string
+}
+
+// RunSummary is one entry of ListRuns.
+type RunSummary struct {
+	// Run is the run's metadata; only RunID and RepoID are set when Broken.
+	Run
+
+	// RepoKey identifies the repo by origin (e.g. "github:owner/repo"; "" if unknown).
+	RepoKey string
+
+	// Broken is true when meta.json is missing or unreadable.
+	Broken bool
+
+	Status RunStatus
+}
+
+// ListOptions filters ListRuns.
+type ListOptions struct {
+	// RepoID restricts the listing to one repo (empty = all repos).
+	RepoID string
+
+	// IncludeArchived includes runs whose worktree is gone.
+	IncludeArchived bool
+
+	// IncludeBroken includes runs whose meta.json cannot be read.
+	IncludeBroken bool
+
+	// Group only lists runs in this group (empty = any).
+	Group string
+}
+
+// ListRuns lists runs newest first with their derived status, like
+// `agency ls --all-repos`. Repo directories that cannot be read are skipped.
+func (c *Client) ListRuns(ctx context.Context, opts ListOptions) ([]RunSummary, error) {
+	crypter := commands.LoadCrypt(c.cr, c.fsys)
+	var records []store.RunRecord
+	if opts.RepoID != "" {
+		records, _ = store.ScanRunsForRepoWithWarnings(c.dataDir, opts.RepoID, crypter)
+	} else {
+		records, _ = store.ScanAllRunsWithWarnings(c.dataDir, crypter)
+	}
+
+	filtered := records[:0]
+	for _, rec := range records {
+		if rec.Broken && !opts.IncludeBroken {
+			continue
+		}
+		if opts.Group != "" && (rec.Meta == nil || rec.Meta.Group != opts.Group) {
+			continue
+		}
+		filtered = append(filtered, rec)
+	}
+
+	summaries := commands.SummarizeRuns(ctx, c.cr, c.fsys, c.dataDir, filtered)
+	runs := make([]RunSummary, 0, len(summaries))
+	for i, s := range summaries {
+		if !s.Broken && s.Archived && !opts.IncludeArchived {
+			continue
+		}
+		runs = append(runs, newRunSummary(filtered[i], s))
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		if !runs[i].CreatedAt.Equal(runs[j].CreatedAt) {
+			return runs[i].CreatedAt.After(runs[j].CreatedAt)
+		}
+		return runs[i].RunID > runs[j].RunID
+	})
+	return runs, nil
+}
+
+// FindRun resolves an exact run_id or unique prefix across all repos and
+// returns the run with its derived status. Errors carry E_RUN_NOT_FOUND,
+// E_RUN_ID_AMBIGUOUS, or E_RUN_BROKEN.
+func (c *Client) FindRun(ctx context.Context, runID string) (RunSummary, error) {
+	rec, err := commands.ResolveRun(c.dataDir, runID, "", commands.LoadCrypt(c.cr, c.fsys))
+	if err != nil {
+		return RunSummary{}, err
+	}
+	summaries := commands.SummarizeRuns(ctx, c.cr, c.fsys, c.dataDir, []store.RunRecord{*rec})
+	return newRunSummary(*rec, summaries[0]), nil
+}
+
+// GetRun reads the metadata of one run. Errors carry E_RUN_NOT_FOUND if the
+// run does not exist and E_STORE_CORRUPT if meta.json is invalid.
+func (c *Client) GetRun(ctx context.Context, repoID, runID string) (Run, error) {
+	if err := ctx.Err(); err != nil {
+		return Run{}, err
+	}
+	meta, err := store.NewStore(c.fsys, c.dataDir, time.Now).WithCrypt(commands.LoadCrypt(c.cr, c.fsys)).ReadMeta(repoID, runID)
+	if err != nil {
+		return Run{}, err
+	}
+	return newRun(meta), nil
+}
+
+// newRunSummary converts a scanned record and its ls summary.
+func newRunSummary(rec store.RunRecord, s render.RunSummary) RunSummary {
+	summary := RunSummary{
+		Run:    Run{RunID: rec.RunID, RepoID: rec.RepoID, Labels: map[string]string{}},
+		Broken: rec.Broken,
+		Status: RunStatus{
+			Status:      s.DerivedStatus,
+			Archived:    s.Archived,
+			TmuxActive:  s.TmuxActive,
+			ReportStale: s.ReportStale,
+			NoChanges:   s.NoChanges,
+		},
+	}
+	if s.AttentionReason != nil {
+		summary.Status.AttentionReason = *s.AttentionReason
+	}
+	if s.RepoKey != nil {
+		summary.RepoKey = *s.RepoKey
+	}
+	if rec.Meta != nil {
+		summary.Run = newRun(rec.Meta)
+	}
+	return summary
+}
+
+// newRun converts meta.json contents.
+func newRun(meta *store.RunMeta) Run {
+	run := Run{
+		RunID:           meta.RunID,
+		RepoID:          meta.RepoID,
+		Title:           meta.Title,
+		Runner:          meta.Runner,
+		RunnerCmd:       meta.RunnerCmd,
+		ParentBranch:    meta.ParentBranch,
+		Branch:          meta.Branch,
+		WorktreePath:    meta.WorktreePath,
+		CreatedAt:       parseTime(meta.CreatedAt),
+		CreatedBy:       meta.CreatedBy,
+		TmuxSessionName: meta.TmuxSessionName,
+		Labels:          make(map[string]string, len(meta.Labels)),
+		Group:           meta.Group,
+		PRNumber:        meta.PRNumber,
+		PRURL:           meta.PRURL,
+		LastPushAt:      parseTime(meta.LastPushAt),
+	}
+	for k, v := range meta.Labels {
+		run.Labels[k] = v
+	}
+	if f := meta.Flags; f != nil {
+		run.SetupFailed = f.SetupFailed
+		run.TmuxFailed = f.TmuxFailed
+		run.NeedsAttention = f.NeedsAttention
+		run.Abandoned = f.Abandoned
+	}
+	if a := meta.Archive; a != nil {
+		run.MergedAt = parseTime(a.MergedAt)
+		run.ArchivedAt = parseTime(a.ArchivedAt)
+	}
+	if d := meta.Deadline; d != nil {
+		run.Deadline = parseTime(d.At)
+		run.DeadlineKill = d.Kill
+	}
+	return run
+}
+
+// parseTime parses an RFC3339 meta.json timestamp (zero time if empty or invalid).
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+// formatTime formats a timestamp for meta.json ("" for the zero time).
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,114 @@
+package agency
+
+import (
+	"time"
+
+	"github.com/NielsdaWheelz/agency/internal/status"
+	"github.com/NielsdaWheelz/agency/internal/store"
+)
+
+// Derived status values (RunStatus.Status). These strings are part of the
+// CLI's stable output and do not change within a major version.
+const (
+	StatusBroken         = status.StatusBroken
+	StatusMerged         = status.StatusMerged
+	StatusAbandoned      = status.StatusAbandoned
+	StatusFailed         = status.StatusFailed
+	StatusNeedsAttention = status.StatusNeedsAttention
+	StatusReadyForReview = status.StatusReadyForReview
+	StatusActivePR       = status.StatusActivePR
+	StatusActive         = status.StatusActive
+	StatusIdlePR         = status.StatusIdlePR
+	StatusIdle           = status.StatusIdle
+)
+
+// StatusInput holds the local facts status derivation needs beyond a
+// run's metadata. ListRuns and FindRun gather them from disk and tmux;
+// DeriveStatus lets callers supply their own.
+type StatusInput struct {
+	// TmuxActive is true if the run's tmux session exists.
+	TmuxActive bool
+
+	// WorktreePresent is true if the worktree exists on disk.
+	WorktreePresent bool
+
+	// ReportBytes is the size of .agency/report.md (0 if missing).
+	ReportBytes int
+
+	// ReportStale is true if the branch has commits newer than the report covers.
+	ReportStale bool
+
+	// ReportReadyFlag is true if report.md front matter sets ready: true.
+	ReportReadyFlag bool
+
+	// ReportMinBytes is the report size that counts as non-empty (0 = 64).
+	ReportMinBytes int
+
+	// RequireReadyFlag makes ReportReadyFlag, not ReportBytes, decide
+	// whether the report is ready (agency.json review.readiness "front_matter").
+	RequireReadyFlag bool
+
+	// NoCommits is true if the branch has no commits beyond its parent.
+	NoCommits bool
+
+	// Now is the time deadlines are checked against (zero = time.Now()).
+	Now time.Time
+}
+
+// DeriveStatus computes a run's status from its metadata and in, with the
+// same precedence rules as `agency ls`. It does no I/O.
+func DeriveStatus(run Run, in StatusInput) RunStatus {
+	meta := run.meta()
+	now := in.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	derived := status.Derive(meta, status.Snapshot{
+		TmuxActive:      in.TmuxActive,
+		WorktreePresent: in.WorktreePresent,
+		ReportBytes:     in.ReportBytes,
+		ReportStale:     in.ReportStale,
+		ReportReadyFlag: in.ReportReadyFlag,
+		Policy: status.ReviewPolicy{
+			ReportMinBytes:   in.ReportMinBytes,
+			RequireReadyFlag: in.RequireReadyFlag,
+		},
+		DeadlineExceeded: in.WorktreePresent && meta.DeadlineExceeded(now),
+		NoCommits:        in.NoCommits,
+	})
+	return RunStatus{
+		Status:          derived.DerivedStatus,
+		Archived:        derived.Archived,
+		TmuxActive:      in.TmuxActive,
+		ReportStale:     derived.ReportStale,
+		NoChanges:       derived.NoChanges,
+		AttentionReason: derived.AttentionReason,
+	}
+}
+
+// meta converts run back to the meta.json fields status derivation reads.
+func (r Run) meta() *store.RunMeta {
+	meta := &store.RunMeta{
+		RunID:      r.RunID,
+		RepoID:     r.RepoID,
+		PRNumber:   r.PRNumber,
+		PRURL:      r.PRURL,
+		LastPushAt: formatTime(r.LastPushAt),
+		Flags: &store.RunMetaFlags{
+			SetupFailed:    r.SetupFailed,
+			TmuxFailed:     r.TmuxFailed,
+			NeedsAttention: r.NeedsAttention,
+			Abandoned:      r.Abandoned,
+		},
+	}
+	if !r.MergedAt.IsZero() || !r.ArchivedAt.IsZero() {
+		meta.Archive = &store.RunMetaArchive{
+			MergedAt:   formatTime(r.MergedAt),
+			ArchivedAt: formatTime(r.ArchivedAt),
+		}
+	}
+	if !r.Deadline.IsZero() {
+		meta.Deadline = &store.RunMetaDeadline{At: formatTime(r.Deadline), Kill: r.DeadlineKill}
+	}
+	return meta
+}